@@ -0,0 +1,137 @@
+package authsession
+
+import (
+	"backend/api/server"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leporo/sqlf"
+)
+
+// attemptWindow is the sliding window in
+// which attempts are counted.
+const attemptWindow = 15 * time.Minute
+
+// AttemptKind is the kind of rate
+// limited authentication attempt.
+type AttemptKind string
+
+const (
+	// FailedSignin is a sign in attempt
+	// with wrong credentials.
+	FailedSignin AttemptKind = "failed_signin"
+
+	// EmailSent is a verification, password
+	// reset or magic link email sent.
+	EmailSent AttemptKind = "email_sent"
+)
+
+// attemptLimit is the maximum number of attempts
+// allowed per email & per ip address in the
+// attempt window.
+type attemptLimit struct {
+	email int
+	ip    int
+}
+
+// attemptLimits maps each kind of
+// attempt to its limits.
+var attemptLimits = map[AttemptKind]attemptLimit{
+	FailedSignin: {email: 5, ip: 20},
+	EmailSent:    {email: 3, ip: 10},
+}
+
+// attemptCounts holds the number of attempts
+// in the window along with the time of the
+// oldest attempt.
+type attemptCounts struct {
+	email       int
+	ip          int
+	oldestEmail time.Time
+	oldestIP    time.Time
+}
+
+// retryAfter computes how long to wait before another
+// attempt is allowed. Zero means allowed right away.
+func (c attemptCounts) retryAfter(limit attemptLimit, now time.Time) (wait time.Duration) {
+	if c.email >= limit.email {
+		wait = max(wait, c.oldestEmail.Add(attemptWindow).Sub(now))
+	}
+
+	if c.ip >= limit.ip {
+		wait = max(wait, c.oldestIP.Add(attemptWindow).Sub(now))
+	}
+
+	// round up to whole seconds, so that retrying
+	// after the hinted duration always succeeds
+	if wait > 0 {
+		wait = wait.Truncate(time.Second) + time.Second
+	}
+
+	return
+}
+
+// CheckAttempts reports how long the client must wait before
+// attempting again. Zero means the attempt is allowed.
+func CheckAttempts(ctx context.Context, kind AttemptKind, email, ip string) (wait time.Duration, err error) {
+	now := time.Now()
+	email = strings.ToLower(email)
+
+	stmt := sqlf.PostgreSQL.
+		From("public.auth_attempts").
+		Select("count(*) filter (where email = ?)", email).
+		Select("count(*) filter (where ip = ?)", ip).
+		Select("coalesce(min(created_at) filter (where email = ?), now())", email).
+		Select("coalesce(min(created_at) filter (where ip = ?), now())", ip).
+		Where("kind = ?", string(kind)).
+		Where("created_at > ?", now.Add(-attemptWindow)).
+		Where("(email = ? or ip = ?)", email, ip)
+
+	defer stmt.Close()
+
+	var counts attemptCounts
+
+	if err = server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&counts.email, &counts.ip, &counts.oldestEmail, &counts.oldestIP); err != nil {
+		return
+	}
+
+	wait = counts.retryAfter(attemptLimits[kind], now)
+
+	return
+}
+
+// RecordAttempt records an attempt of the kind
+// for the email & ip address.
+func RecordAttempt(ctx context.Context, kind AttemptKind, email, ip string) (err error) {
+	stmt := sqlf.PostgreSQL.
+		InsertInto("public.auth_attempts").
+		Set("id", uuid.New()).
+		Set("kind", string(kind)).
+		Set("email", strings.ToLower(email)).
+		Set("ip", ip).
+		Set("created_at", time.Now())
+
+	defer stmt.Close()
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// ClearAttempts removes recorded attempts of the
+// kind for the email, like after a successful
+// sign in.
+func ClearAttempts(ctx context.Context, kind AttemptKind, email string) (err error) {
+	stmt := sqlf.PostgreSQL.
+		DeleteFrom("public.auth_attempts").
+		Where("kind = ?", string(kind)).
+		Where("email = ?", strings.ToLower(email))
+
+	defer stmt.Close()
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
@@ -0,0 +1,62 @@
+package authsession
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAttemptsRetryAfter(t *testing.T) {
+	now := time.Now()
+	limit := attemptLimit{email: 5, ip: 20}
+
+	cases := []struct {
+		name     string
+		counts   attemptCounts
+		expected time.Duration
+	}{
+		{
+			name:     "under limits",
+			counts:   attemptCounts{email: 4, ip: 19, oldestEmail: now, oldestIP: now},
+			expected: 0,
+		},
+		{
+			name:     "email limit reached",
+			counts:   attemptCounts{email: 5, ip: 5, oldestEmail: now.Add(-10 * time.Minute), oldestIP: now},
+			expected: 5*time.Minute + time.Second,
+		},
+		{
+			name:     "ip limit reached",
+			counts:   attemptCounts{email: 1, ip: 20, oldestEmail: now, oldestIP: now.Add(-14 * time.Minute)},
+			expected: time.Minute + time.Second,
+		},
+		{
+			name:     "both limits reached waits for the longer",
+			counts:   attemptCounts{email: 5, ip: 20, oldestEmail: now.Add(-14 * time.Minute), oldestIP: now.Add(-5 * time.Minute)},
+			expected: 10*time.Minute + time.Second,
+		},
+		{
+			name:     "oldest attempt just left the window",
+			counts:   attemptCounts{email: 5, oldestEmail: now.Add(-attemptWindow)},
+			expected: 0,
+		},
+	}
+
+	for _, c := range cases {
+		if got := c.counts.retryAfter(limit, now); got != c.expected {
+			t.Errorf("%s: expected %v, but got %v", c.name, c.expected, got)
+		}
+	}
+}
+
+func TestAttemptLimits(t *testing.T) {
+	for _, kind := range []AttemptKind{FailedSignin, EmailSent} {
+		limit, ok := attemptLimits[kind]
+		if !ok {
+			t.Fatalf("Expected limits for %q", kind)
+		}
+
+		if limit.email < 1 || limit.ip < limit.email {
+			t.Errorf("%s: expected per ip limit to be at least per email limit, but got %+v", kind, limit)
+		}
+	}
+}
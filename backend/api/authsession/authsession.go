@@ -144,11 +144,15 @@ func GetAuthSession(ctx context.Context, id uuid.UUID) (authSession AuthSession,
 	return
 }
 
-// Cleanup removes expired auth sessions.
+// Cleanup removes expired auth sessions, expired
+// one time tokens & attempts older than the
+// attempt window.
 func Cleanup(ctx context.Context) (err error) {
+	now := time.Now()
+
 	stmt := sqlf.PostgreSQL.
 		DeleteFrom("public.auth_sessions").
-		Where("rt_expiry_at < ?", time.Now())
+		Where("rt_expiry_at < ?", now)
 
 	defer stmt.Close()
 
@@ -157,6 +161,25 @@ func Cleanup(ctx context.Context) (err error) {
 		return
 	}
 
+	stmtTokens := sqlf.PostgreSQL.
+		DeleteFrom("public.auth_tokens").
+		Where("expires_at < ?", now)
+
+	defer stmtTokens.Close()
+
+	_, err = server.Server.PgPool.Exec(ctx, stmtTokens.String(), stmtTokens.Args()...)
+	if err != nil {
+		return
+	}
+
+	stmtAttempts := sqlf.PostgreSQL.
+		DeleteFrom("public.auth_attempts").
+		Where("created_at < ?", now.Add(-attemptWindow))
+
+	defer stmtAttempts.Close()
+
+	_, err = server.Server.PgPool.Exec(ctx, stmtAttempts.String(), stmtAttempts.Args()...)
+
 	return
 }
 
@@ -0,0 +1,287 @@
+package authsession
+
+import (
+	"backend/api/cipher"
+	"backend/api/server"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/leporo/sqlf"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordProvider is the name of the local
+// email & password credential provider.
+const PasswordProvider = "password"
+
+// MagicLinkProvider is the name of the local
+// email magic link credential provider.
+const MagicLinkProvider = "magic_link"
+
+const (
+	// minPasswordChars is the minimum number
+	// of characters in a password.
+	minPasswordChars = 8
+
+	// maxPasswordBytes is the maximum length
+	// of a password in bytes. bcrypt ignores
+	// anything beyond 72 bytes.
+	maxPasswordBytes = 72
+
+	// passwordHashCost is the bcrypt cost
+	// used for hashing passwords.
+	passwordHashCost = 12
+
+	// oneTimeTokenBytes is the number of random
+	// bytes in a one time token.
+	oneTimeTokenBytes = 32
+)
+
+// ErrInvalidOneTimeToken is returned when a one time
+// token is unknown, expired or already used.
+var ErrInvalidOneTimeToken = errors.New("token is invalid or has expired")
+
+// dummyPasswordHash is compared against when a user
+// has no password, so that sign in attempts for
+// unknown emails take as long as for known ones.
+var dummyPasswordHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("measure-dummy-password"), passwordHashCost)
+	return hash
+})
+
+// TokenPurpose is the purpose a one
+// time token can be used for.
+type TokenPurpose string
+
+const (
+	// VerifyEmail tokens confirm ownership
+	// of a newly signed up email.
+	VerifyEmail TokenPurpose = "verify_email"
+
+	// ResetPassword tokens allow setting
+	// a new password.
+	ResetPassword TokenPurpose = "reset_password"
+
+	// MagicLink tokens sign in a user
+	// without a password.
+	MagicLink TokenPurpose = "magic_link"
+)
+
+// tokenExpiry maps each token purpose to the
+// duration its tokens remain usable for.
+var tokenExpiry = map[TokenPurpose]time.Duration{
+	VerifyEmail:   24 * time.Hour,
+	ResetPassword: time.Hour,
+	MagicLink:     15 * time.Minute,
+}
+
+// ValidatePassword validates the length
+// of a password.
+func ValidatePassword(password string) error {
+	if utf8.RuneCountInString(password) < minPasswordChars {
+		return fmt.Errorf("password must be at least %d characters", minPasswordChars)
+	}
+
+	if len(password) > maxPasswordBytes {
+		return fmt.Errorf("password must not exceed %d bytes", maxPasswordBytes)
+	}
+
+	return nil
+}
+
+// HashPassword validates & hashes a password
+// for storage.
+func HashPassword(password string) (hash string, err error) {
+	if err = ValidatePassword(password); err != nil {
+		return
+	}
+
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashCost)
+	if err != nil {
+		return
+	}
+
+	hash = string(bytes)
+
+	return
+}
+
+// CheckPassword reports if the password matches
+// the hash. An empty hash never matches, but
+// takes as long to check as a real one.
+func CheckPassword(hash, password string) bool {
+	if hash == "" {
+		bcrypt.CompareHashAndPassword(dummyPasswordHash(), []byte(password))
+		return false
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// OneTimeToken represents a single use token
+// sent to a user's email.
+type OneTimeToken struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Purpose   TokenPurpose
+	Value     string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// NewOneTimeToken creates a new one time token
+// for the user & purpose.
+func NewOneTimeToken(userId uuid.UUID, purpose TokenPurpose) (token *OneTimeToken, err error) {
+	expiry, ok := tokenExpiry[purpose]
+	if !ok {
+		err = fmt.Errorf("unknown token purpose %q", purpose)
+		return
+	}
+
+	bytes := make([]byte, oneTimeTokenBytes)
+	if _, err = rand.Read(bytes); err != nil {
+		return
+	}
+
+	now := time.Now()
+
+	token = &OneTimeToken{
+		ID:        uuid.New(),
+		UserID:    userId,
+		Purpose:   purpose,
+		Value:     base64.RawURLEncoding.EncodeToString(bytes),
+		ExpiresAt: now.Add(expiry),
+		CreatedAt: now,
+	}
+
+	return
+}
+
+// hashOneTimeToken computes the hash of the token
+// value. Only the hash is ever stored.
+func hashOneTimeToken(value string) (hash string, err error) {
+	checksum, err := cipher.ComputeSHA2Hash([]byte(value))
+	if err != nil {
+		return
+	}
+
+	hash = *checksum
+
+	return
+}
+
+// Save saves the one time token to database,
+// invalidating any earlier unused tokens of
+// the same purpose for the user.
+func (t *OneTimeToken) Save(ctx context.Context) (err error) {
+	hash, err := hashOneTimeToken(t.Value)
+	if err != nil {
+		return
+	}
+
+	tx, err := server.Server.PgPool.Begin(ctx)
+	if err != nil {
+		return
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err = RevokeOneTimeTokens(ctx, t.UserID, t.Purpose, &tx); err != nil {
+		return
+	}
+
+	stmt := sqlf.PostgreSQL.
+		InsertInto("public.auth_tokens").
+		Set("id", t.ID).
+		Set("user_id", t.UserID).
+		Set("purpose", string(t.Purpose)).
+		Set("token_hash", hash).
+		Set("expires_at", t.ExpiresAt).
+		Set("created_at", t.CreatedAt)
+
+	defer stmt.Close()
+
+	if _, err = tx.Exec(ctx, stmt.String(), stmt.Args()...); err != nil {
+		return
+	}
+
+	return tx.Commit(ctx)
+}
+
+// ConsumeOneTimeToken marks the token as used &
+// returns the id of the user it was issued to.
+func ConsumeOneTimeToken(ctx context.Context, value string, purpose TokenPurpose) (userId uuid.UUID, err error) {
+	hash, err := hashOneTimeToken(value)
+	if err != nil {
+		return
+	}
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.auth_tokens").
+		Set("used_at", time.Now()).
+		Where("token_hash = ?", hash).
+		Where("purpose = ?", string(purpose)).
+		Where("used_at is null").
+		Where("expires_at > ?", time.Now()).
+		Returning("user_id")
+
+	defer stmt.Close()
+
+	if err = server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&userId); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = ErrInvalidOneTimeToken
+		}
+		return
+	}
+
+	return
+}
+
+// RevokeOneTimeTokens marks all unused tokens of the
+// purpose issued to the user as used.
+func RevokeOneTimeTokens(ctx context.Context, userId uuid.UUID, purpose TokenPurpose, tx *pgx.Tx) (err error) {
+	stmt := sqlf.PostgreSQL.
+		Update("public.auth_tokens").
+		Set("used_at", time.Now()).
+		Where("user_id = ?", userId).
+		Where("purpose = ?", string(purpose)).
+		Where("used_at is null")
+
+	defer stmt.Close()
+
+	if tx != nil {
+		_, err = (*tx).Exec(ctx, stmt.String(), stmt.Args()...)
+		return
+	}
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// RemoveUserSessions removes all auth sessions
+// of the user, signing them out everywhere.
+func RemoveUserSessions(ctx context.Context, userId uuid.UUID, tx *pgx.Tx) (err error) {
+	stmt := sqlf.PostgreSQL.
+		DeleteFrom("public.auth_sessions").
+		Where("user_id = ?", userId)
+
+	defer stmt.Close()
+
+	if tx != nil {
+		_, err = (*tx).Exec(ctx, stmt.String(), stmt.Args()...)
+		return
+	}
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
@@ -0,0 +1,115 @@
+package authsession
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestValidatePassword(t *testing.T) {
+	cases := []struct {
+		password string
+		valid    bool
+	}{
+		{"short", false},
+		{"12345678", true},
+		{"pässwörd", true},
+		{strings.Repeat("a", maxPasswordBytes), true},
+		{strings.Repeat("a", maxPasswordBytes+1), false},
+		// 37 two byte runes exceed the byte limit
+		{strings.Repeat("ä", 37), false},
+	}
+
+	for _, c := range cases {
+		err := ValidatePassword(c.password)
+		if c.valid && err != nil {
+			t.Errorf("%q: expected nil error, but got %v", c.password, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("%q: expected error, but got nil", c.password)
+		}
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	password := "correct horse battery staple"
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if hash == password || !strings.HasPrefix(hash, "$2a$") {
+		t.Errorf("Expected bcrypt hash, but got %q", hash)
+	}
+
+	if !CheckPassword(hash, password) {
+		t.Error("Expected password to match its hash")
+	}
+
+	if CheckPassword(hash, "wrong password") {
+		t.Error("Expected wrong password not to match")
+	}
+
+	if CheckPassword("", password) {
+		t.Error("Expected empty hash not to match")
+	}
+
+	if _, err := HashPassword("short"); err == nil {
+		t.Error("Expected error for short password, but got nil")
+	}
+}
+
+func TestNewOneTimeToken(t *testing.T) {
+	userId := uuid.New()
+
+	for purpose, expiry := range tokenExpiry {
+		token, err := NewOneTimeToken(userId, purpose)
+		if err != nil {
+			t.Fatalf("Expected nil error, but got %v", err)
+		}
+
+		if token.UserID != userId || token.Purpose != purpose {
+			t.Errorf("Expected token for %v %q, but got %+v", userId, purpose, token)
+		}
+
+		if got := token.ExpiresAt.Sub(token.CreatedAt); got != expiry {
+			t.Errorf("%s: expected expiry %v, but got %v", purpose, expiry, got)
+		}
+
+		other, err := NewOneTimeToken(userId, purpose)
+		if err != nil {
+			t.Fatalf("Expected nil error, but got %v", err)
+		}
+
+		if token.Value == other.Value {
+			t.Errorf("Expected unique token values, but got %q twice", token.Value)
+		}
+	}
+
+	if _, err := NewOneTimeToken(userId, "unknown"); err == nil {
+		t.Error("Expected error for unknown purpose, but got nil")
+	}
+}
+
+func TestHashOneTimeToken(t *testing.T) {
+	token, err := NewOneTimeToken(uuid.New(), MagicLink)
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	hash, err := hashOneTimeToken(token.Value)
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if hash == token.Value || len(hash) != 64 {
+		t.Errorf("Expected sha256 hex hash, but got %q", hash)
+	}
+
+	again, _ := hashOneTimeToken(token.Value)
+	if hash != again {
+		t.Errorf("Expected stable hash, but got %q and %q", hash, again)
+	}
+}
@@ -0,0 +1,491 @@
+package authsession
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// discoveryPath is the well-known path of
+// the OpenID Connect discovery document.
+const discoveryPath = "/.well-known/openid-configuration"
+
+// jwksRefreshInterval is the minimum interval
+// between successive fetches of the issuer's
+// signing keys.
+const jwksRefreshInterval = time.Minute
+
+// oidcSigningMethods are the id token
+// signing algorithms accepted.
+var oidcSigningMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+
+// OIDCConfig represents the configuration
+// of a generic OpenID Connect provider.
+type OIDCConfig struct {
+	// DiscoveryURL is the issuer url or the
+	// full url of its discovery document.
+	DiscoveryURL string
+
+	// ClientID is the client id registered
+	// with the issuer.
+	ClientID string
+
+	// ClientSecret is the client secret
+	// registered with the issuer.
+	ClientSecret string
+
+	// RedirectURI is the url the issuer
+	// redirects to after authentication.
+	RedirectURI string
+
+	// Scopes are the scopes requested
+	// during authentication.
+	Scopes []string
+
+	// EmailClaim is the id token claim
+	// holding the user's email.
+	EmailClaim string
+
+	// NameClaim is the id token claim
+	// holding the user's name.
+	NameClaim string
+
+	// TrustEmail accepts id tokens without the
+	// email_verified claim, for issuers that only
+	// issue verified emails & omit the claim.
+	TrustEmail bool
+
+	// Client is the http client used to
+	// talk to the issuer.
+	Client *http.Client
+}
+
+// OIDCDiscovery represents the fields of the
+// issuer's discovery document that are used.
+type OIDCDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCToken represents the token response
+// received from the issuer.
+type OIDCToken struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	IDToken          string `json:"id_token"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// OIDCUser represents the user
+// authenticated by the issuer.
+type OIDCUser struct {
+	ID     string `json:"id"`
+	Issuer string `json:"issuer"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+}
+
+// OIDCProvider authenticates users against a
+// generic OpenID Connect issuer using the
+// authorization code flow.
+type OIDCProvider struct {
+	config    OIDCConfig
+	discovery OIDCDiscovery
+	mu        sync.RWMutex
+	keys      map[string]any
+	fetchedAt time.Time
+}
+
+// jwk represents a single key of
+// the issuer's key set.
+type jwk struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// discoveryURL returns the url of the discovery
+// document from an issuer or discovery url.
+func discoveryURL(raw string) string {
+	if strings.HasSuffix(raw, discoveryPath) {
+		return raw
+	}
+
+	return strings.TrimSuffix(raw, "/") + discoveryPath
+}
+
+// NewOIDCProvider creates a new OpenID Connect provider
+// by fetching the issuer's discovery document and
+// signing keys.
+func NewOIDCProvider(ctx context.Context, config OIDCConfig) (provider *OIDCProvider, err error) {
+	if config.DiscoveryURL == "" {
+		err = errors.New("oidc discovery url is required")
+		return
+	}
+
+	if config.ClientID == "" {
+		err = errors.New("oidc client id is required")
+		return
+	}
+
+	if len(config.Scopes) == 0 {
+		config.Scopes = []string{"openid", "email", "profile"}
+	}
+
+	if config.EmailClaim == "" {
+		config.EmailClaim = "email"
+	}
+
+	if config.NameClaim == "" {
+		config.NameClaim = "name"
+	}
+
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	provider = &OIDCProvider{
+		config: config,
+	}
+
+	body, err := provider.get(ctx, discoveryURL(config.DiscoveryURL))
+	if err != nil {
+		return nil, err
+	}
+
+	if err = json.Unmarshal(body, &provider.discovery); err != nil {
+		return nil, err
+	}
+
+	d := provider.discovery
+	if d.Issuer == "" || d.AuthorizationEndpoint == "" || d.TokenEndpoint == "" || d.JWKSURI == "" {
+		return nil, errors.New("oidc discovery document is missing required fields")
+	}
+
+	if err = provider.fetchKeys(ctx); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// get makes a GET request to the issuer.
+func (p *OIDCProvider) get(ctx context.Context, endpoint string) (body []byte, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.config.Client.Do(req)
+	if err != nil {
+		return
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("failed to fetch %q, status code: %d", endpoint, resp.StatusCode)
+		return
+	}
+
+	return io.ReadAll(resp.Body)
+}
+
+// fetchKeys fetches and replaces the
+// issuer's signing keys.
+func (p *OIDCProvider) fetchKeys(ctx context.Context) (err error) {
+	body, err := p.get(ctx, p.discovery.JWKSURI)
+	if err != nil {
+		return
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err = json.Unmarshal(body, &set); err != nil {
+		return
+	}
+
+	keys := make(map[string]any)
+
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			// skip keys that can't
+			// be used for verification
+			fmt.Printf("skipping oidc signing key %q: %v\n", k.Kid, err)
+			continue
+		}
+
+		keys[k.Kid] = key
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.keys = keys
+	p.fetchedAt = time.Now()
+
+	return
+}
+
+// key returns the signing key by id. The keys are
+// refetched once if the key is not found to pick
+// up rotated keys.
+func (p *OIDCProvider) key(ctx context.Context, kid string) (any, error) {
+	lookup := func() (any, bool) {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+
+		// tokens without a key id can only
+		// be verified by a single key
+		if kid == "" && len(p.keys) == 1 {
+			for _, key := range p.keys {
+				return key, true
+			}
+		}
+
+		key, ok := p.keys[kid]
+		return key, ok
+	}
+
+	if key, ok := lookup(); ok {
+		return key, nil
+	}
+
+	p.mu.RLock()
+	stale := time.Since(p.fetchedAt) >= jwksRefreshInterval
+	p.mu.RUnlock()
+
+	if stale {
+		if err := p.fetchKeys(ctx); err != nil {
+			return nil, err
+		}
+
+		if key, ok := lookup(); ok {
+			return key, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no oidc signing key found with id %q", kid)
+}
+
+// publicKey decodes the json web key
+// into a public key.
+func (k jwk) publicKey() (any, error) {
+	decode := func(s string) (*big.Int, error) {
+		b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+		if err != nil {
+			return nil, err
+		}
+		return new(big.Int).SetBytes(b), nil
+	}
+
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+// AuthURL returns the url of the issuer's
+// authorization endpoint to redirect to.
+func (p *OIDCProvider) AuthURL(state, nonce string) string {
+	values := url.Values{}
+	values.Set("response_type", "code")
+	values.Set("client_id", p.config.ClientID)
+	values.Set("redirect_uri", p.config.RedirectURI)
+	values.Set("scope", strings.Join(p.config.Scopes, " "))
+	values.Set("state", state)
+	values.Set("nonce", nonce)
+
+	sep := "?"
+	if strings.Contains(p.discovery.AuthorizationEndpoint, "?") {
+		sep = "&"
+	}
+
+	return p.discovery.AuthorizationEndpoint + sep + values.Encode()
+}
+
+// Exchange exchanges the authorization
+// code for the issuer's tokens.
+func (p *OIDCProvider) Exchange(ctx context.Context, code string) (token OIDCToken, err error) {
+	data := url.Values{}
+	data.Set("grant_type", "authorization_code")
+	data.Set("code", code)
+	data.Set("redirect_uri", p.config.RedirectURI)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.discovery.TokenEndpoint, strings.NewReader(data.Encode()))
+	if err != nil {
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(p.config.ClientID), url.QueryEscape(p.config.ClientSecret))
+
+	resp, err := p.config.Client.Do(req)
+	if err != nil {
+		return
+	}
+
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return
+	}
+
+	if err = json.Unmarshal(body, &token); err != nil {
+		err = fmt.Errorf("failed to parse oidc token response, status code: %d", resp.StatusCode)
+		return
+	}
+
+	if token.Error != "" {
+		err = fmt.Errorf("oidc token exchange failed: %s %s", token.Error, token.ErrorDescription)
+		return
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("oidc token exchange failed, status code: %d", resp.StatusCode)
+		return
+	}
+
+	if token.IDToken == "" {
+		err = errors.New("oidc token response is missing id token")
+		return
+	}
+
+	return
+}
+
+// VerifyIDToken verifies the id token's signature,
+// issuer, audience, expiry & nonce and returns
+// its claims.
+func (p *OIDCProvider) VerifyIDToken(ctx context.Context, raw, nonce string) (claims jwt.MapClaims, err error) {
+	keyFunc := func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.key(ctx, kid)
+	}
+
+	claims = jwt.MapClaims{}
+
+	if _, err = jwt.ParseWithClaims(raw, claims, keyFunc,
+		jwt.WithValidMethods(oidcSigningMethods),
+		jwt.WithIssuer(p.discovery.Issuer),
+		jwt.WithAudience(p.config.ClientID),
+		jwt.WithExpirationRequired(),
+	); err != nil {
+		return nil, err
+	}
+
+	if claimNonce, _ := claims["nonce"].(string); claimNonce != nonce {
+		return nil, errors.New("oidc id token nonce mismatch")
+	}
+
+	return
+}
+
+// User maps the verified id token's
+// claims to the user.
+func (p *OIDCProvider) User(claims jwt.MapClaims) (user OIDCUser, err error) {
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		err = errors.New("oidc id token is missing subject")
+		return
+	}
+
+	email, _ := claims[p.config.EmailClaim].(string)
+	if email == "" {
+		err = fmt.Errorf("oidc id token is missing %q claim", p.config.EmailClaim)
+		return
+	}
+
+	// the email decides the account the user
+	// signs in to, so it must be verified
+	verified, ok := claims["email_verified"].(bool)
+	if !ok && !p.config.TrustEmail {
+		err = errors.New(`oidc id token is missing "email_verified" claim`)
+		return
+	}
+	if ok && !verified {
+		err = fmt.Errorf("oidc email %q is not verified", email)
+		return
+	}
+
+	name, _ := claims[p.config.NameClaim].(string)
+	if name == "" {
+		name, _, _ = strings.Cut(email, "@")
+	}
+
+	user.ID = sub
+	user.Issuer = p.discovery.Issuer
+	user.Name = name
+	user.Email = email
+
+	return
+}
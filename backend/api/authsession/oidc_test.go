@@ -0,0 +1,339 @@
+package authsession
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testClientID     = "measure"
+	testClientSecret = "s3cr3t"
+	testCode         = "auth-code"
+	testNonce        = "n0nc3"
+)
+
+// mockIssuer is a local OpenID Connect issuer
+// serving discovery, key set & token endpoints.
+type mockIssuer struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+	claims jwt.MapClaims
+}
+
+func newMockIssuer(t *testing.T) *mockIssuer {
+	t.Helper()
+
+	m := &mockIssuer{t: t}
+	m.rotate("key-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(discoveryPath, func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(OIDCDiscovery{
+			Issuer:                m.server.URL,
+			AuthorizationEndpoint: m.server.URL + "/authorize",
+			TokenEndpoint:         m.server.URL + "/token",
+			JWKSURI:               m.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []jwk{
+				{
+					Kid: m.kid,
+					Kty: "RSA",
+					Use: "sig",
+					N:   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+					E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+				},
+			},
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		id, secret, ok := r.BasicAuth()
+		if !ok || id != testClientID || secret != testClientSecret {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+
+		if r.PostFormValue("code") != testCode {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		json.NewEncoder(w).Encode(OIDCToken{
+			AccessToken: "access-token",
+			TokenType:   "Bearer",
+			IDToken:     m.sign(m.claims),
+		})
+	})
+
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+
+	m.claims = m.defaultClaims()
+
+	return m
+}
+
+func (m *mockIssuer) rotate(kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		m.t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	m.key = key
+	m.kid = kid
+}
+
+func (m *mockIssuer) defaultClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            m.server.URL,
+		"aud":            testClientID,
+		"sub":            "00u1a2b3c4",
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"iat":            time.Now().Unix(),
+		"nonce":          testNonce,
+		"email":          "ada@example.com",
+		"email_verified": true,
+		"name":           "Ada Lovelace",
+	}
+}
+
+func (m *mockIssuer) sign(claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = m.kid
+
+	signed, err := token.SignedString(m.key)
+	if err != nil {
+		m.t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	return signed
+}
+
+func (m *mockIssuer) provider() *OIDCProvider {
+	m.t.Helper()
+
+	provider, err := NewOIDCProvider(context.Background(), OIDCConfig{
+		DiscoveryURL: m.server.URL,
+		ClientID:     testClientID,
+		ClientSecret: testClientSecret,
+		RedirectURI:  "https://measure.example.com/auth/callback/oidc",
+	})
+	if err != nil {
+		m.t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	return provider
+}
+
+func TestDiscoveryURL(t *testing.T) {
+	expected := "https://idp.example.com/realms/acme" + discoveryPath
+
+	for _, raw := range []string{
+		"https://idp.example.com/realms/acme",
+		"https://idp.example.com/realms/acme/",
+		expected,
+	} {
+		if got := discoveryURL(raw); got != expected {
+			t.Errorf("Expected %q, but got %q", expected, got)
+		}
+	}
+}
+
+func TestOIDCAuthURL(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider := issuer.provider()
+
+	u, err := url.Parse(provider.AuthURL("st4te", testNonce))
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if !strings.HasPrefix(u.String(), issuer.server.URL+"/authorize?") {
+		t.Errorf("Expected authorization endpoint, but got %q", u.String())
+	}
+
+	query := u.Query()
+	expected := map[string]string{
+		"response_type": "code",
+		"client_id":     testClientID,
+		"scope":         "openid email profile",
+		"state":         "st4te",
+		"nonce":         testNonce,
+	}
+
+	for k, v := range expected {
+		if query.Get(k) != v {
+			t.Errorf("Expected %s %q, but got %q", k, v, query.Get(k))
+		}
+	}
+}
+
+func TestOIDCSignin(t *testing.T) {
+	ctx := context.Background()
+	issuer := newMockIssuer(t)
+	provider := issuer.provider()
+
+	token, err := provider.Exchange(ctx, testCode)
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	claims, err := provider.VerifyIDToken(ctx, token.IDToken, testNonce)
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	user, err := provider.User(claims)
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	expected := OIDCUser{
+		ID:     "00u1a2b3c4",
+		Issuer: issuer.server.URL,
+		Name:   "Ada Lovelace",
+		Email:  "ada@example.com",
+	}
+
+	if user != expected {
+		t.Errorf("Expected %+v, but got %+v", expected, user)
+	}
+
+	if _, err := provider.Exchange(ctx, "wrong-code"); err == nil {
+		t.Error("Expected error for invalid code, but got nil")
+	}
+}
+
+func TestOIDCVerifyIDTokenRejects(t *testing.T) {
+	ctx := context.Background()
+	issuer := newMockIssuer(t)
+	provider := issuer.provider()
+
+	cases := map[string]func(jwt.MapClaims){
+		"wrong nonce":    func(c jwt.MapClaims) { c["nonce"] = "other" },
+		"wrong audience": func(c jwt.MapClaims) { c["aud"] = "other-client" },
+		"wrong issuer":   func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" },
+		"expired":        func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() },
+		"missing expiry": func(c jwt.MapClaims) { delete(c, "exp") },
+	}
+
+	for name, mutate := range cases {
+		claims := issuer.defaultClaims()
+		mutate(claims)
+
+		if _, err := provider.VerifyIDToken(ctx, issuer.sign(claims), testNonce); err == nil {
+			t.Errorf("%s: expected error, but got nil", name)
+		}
+	}
+
+	// tokens signed by an unknown
+	// key must be rejected
+	other := *issuer
+	other.rotate("key-1")
+
+	if _, err := provider.VerifyIDToken(ctx, other.sign(issuer.defaultClaims()), testNonce); err == nil {
+		t.Error("Expected error for forged signature, but got nil")
+	}
+}
+
+func TestOIDCKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	issuer := newMockIssuer(t)
+	provider := issuer.provider()
+
+	issuer.rotate("key-2")
+
+	// keys were just fetched, so an
+	// unknown key is not refetched yet
+	if _, err := provider.VerifyIDToken(ctx, issuer.sign(issuer.defaultClaims()), testNonce); err == nil {
+		t.Error("Expected error for unknown key, but got nil")
+	}
+
+	provider.fetchedAt = time.Now().Add(-jwksRefreshInterval)
+
+	if _, err := provider.VerifyIDToken(ctx, issuer.sign(issuer.defaultClaims()), testNonce); err != nil {
+		t.Errorf("Expected rotated key to be fetched, but got %v", err)
+	}
+}
+
+func TestOIDCUserClaims(t *testing.T) {
+	issuer := newMockIssuer(t)
+	provider := issuer.provider()
+
+	claims := issuer.defaultClaims()
+	claims["email_verified"] = false
+
+	if _, err := provider.User(claims); err == nil {
+		t.Error("Expected error for unverified email, but got nil")
+	}
+
+	// a missing claim isn't trusted
+	// unless configured
+	claims = issuer.defaultClaims()
+	delete(claims, "email_verified")
+
+	if _, err := provider.User(claims); err == nil {
+		t.Error("Expected error for missing email_verified claim, but got nil")
+	}
+
+	provider.config.TrustEmail = true
+
+	if _, err := provider.User(claims); err != nil {
+		t.Errorf("Expected nil error for trusted issuer, but got %v", err)
+	}
+
+	claims["email_verified"] = false
+
+	if _, err := provider.User(claims); err == nil {
+		t.Error("Expected error for unverified email of trusted issuer, but got nil")
+	}
+
+	provider.config.TrustEmail = false
+
+	claims = issuer.defaultClaims()
+	delete(claims, "name")
+
+	user, err := provider.User(claims)
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if user.Name != "ada" {
+		t.Errorf("Expected name to fall back to %q, but got %q", "ada", user.Name)
+	}
+
+	// claim mapping
+	provider.config.EmailClaim = "upn"
+	provider.config.NameClaim = "display_name"
+
+	claims = issuer.defaultClaims()
+	claims["upn"] = "ada.lovelace@example.com"
+	claims["display_name"] = "Countess of Lovelace"
+
+	user, err = provider.User(claims)
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if user.Email != "ada.lovelace@example.com" || user.Name != "Countess of Lovelace" {
+		t.Errorf("Expected mapped claims, but got %+v", user)
+	}
+}
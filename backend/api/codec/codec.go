@@ -0,0 +1,191 @@
+package codec
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+const (
+	// EncodingIdentity is the encoding of
+	// uncompressed bodies.
+	EncodingIdentity = "identity"
+
+	// EncodingGzip is the encoding of gzip
+	// compressed bodies.
+	EncodingGzip = "gzip"
+
+	// EncodingZstd is the encoding of zstd
+	// compressed bodies.
+	EncodingZstd = "zstd"
+)
+
+// MaxRatio is the maximum allowed ratio of
+// decompressed to compressed size of a body.
+// Bodies exceeding it are treated as
+// decompression bombs.
+const MaxRatio = 100
+
+// minRatioSize is the decompressed size in bytes
+// after which the ratio is enforced, so that small
+// bodies compressing very well aren't rejected.
+const minRatioSize = 1024 * 1024
+
+// ErrUnsupportedEncoding is returned for content
+// encodings other than identity, gzip & zstd.
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// ErrTooLarge is returned when a body exceeds
+// the maximum allowed size, before or after
+// decompression.
+var ErrTooLarge = errors.New("body exceeds maximum allowed size")
+
+// ErrRatio is returned when a body's compression
+// ratio exceeds the maximum allowed ratio.
+var ErrRatio = fmt.Errorf("body exceeds maximum allowed compression ratio of %d", MaxRatio)
+
+// counter counts bytes read from
+// the underlying reader.
+type counter struct {
+	r io.Reader
+	n int64
+}
+
+func (c *counter) Read(p []byte) (n int, err error) {
+	n, err = c.r.Read(p)
+	c.n += int64(n)
+	return
+}
+
+// Body is a request body decompressed as it is
+// read. Tracks the size of the body before &
+// after decompression.
+type Body struct {
+	// Encoding is the normalized content
+	// encoding of the body.
+	Encoding string
+
+	src     io.ReadCloser
+	wire    *counter
+	decoder io.Reader
+	close   func()
+	limit   int64
+	n       int64
+}
+
+// ParseEncoding normalizes a `Content-Encoding`
+// header value. Returns error for unsupported or
+// multiple encodings.
+func ParseEncoding(header string) (string, error) {
+	switch encoding := strings.ToLower(strings.TrimSpace(header)); encoding {
+	case "", EncodingIdentity:
+		return EncodingIdentity, nil
+	case EncodingGzip, "x-gzip":
+		return EncodingGzip, nil
+	case EncodingZstd:
+		return EncodingZstd, nil
+	default:
+		return "", fmt.Errorf("%w %q", ErrUnsupportedEncoding, header)
+	}
+}
+
+// NewBody wraps a body of a content encoding to
+// decompress it while being read. Reading more
+// than limit bytes, before or after decompression,
+// fails with ErrTooLarge.
+func NewBody(src io.ReadCloser, header string, limit int64) (body *Body, err error) {
+	encoding, err := ParseEncoding(header)
+	if err != nil {
+		return
+	}
+
+	body = &Body{
+		Encoding: encoding,
+		src:      src,
+		wire:     &counter{r: io.LimitReader(src, limit+1)},
+		limit:    limit,
+	}
+
+	switch encoding {
+	case EncodingGzip:
+		reader, gzipErr := gzip.NewReader(body.wire)
+		if gzipErr != nil {
+			return nil, gzipErr
+		}
+		body.decoder = reader
+		body.close = func() { reader.Close() }
+	case EncodingZstd:
+		decoder, zstdErr := zstd.NewReader(body.wire, zstd.WithDecoderConcurrency(1), zstd.WithDecoderMaxMemory(uint64(limit)))
+		if zstdErr != nil {
+			return nil, zstdErr
+		}
+		body.decoder = decoder
+		body.close = decoder.Close
+	default:
+		body.decoder = body.wire
+	}
+
+	return
+}
+
+// Read reads decompressed bytes of the body.
+func (b *Body) Read(p []byte) (n int, err error) {
+	n, err = b.decoder.Read(p)
+	b.n += int64(n)
+
+	// zstd frames declaring more than
+	// limit are rejected by the decoder
+	if errors.Is(err, zstd.ErrWindowSizeExceeded) || errors.Is(err, zstd.ErrDecoderSizeExceeded) {
+		return n, ErrTooLarge
+	}
+
+	if b.wire.n > b.limit || b.n > b.limit {
+		return n, ErrTooLarge
+	}
+
+	if b.IsCompressed() && b.n > minRatioSize && b.n > b.wire.n*MaxRatio {
+		return n, ErrRatio
+	}
+
+	return
+}
+
+// Close closes the decompressor & the
+// underlying body.
+func (b *Body) Close() error {
+	if b.close != nil {
+		b.close()
+	}
+	return b.src.Close()
+}
+
+// IsCompressed checks if the body
+// is compressed.
+func (b *Body) IsCompressed() bool {
+	return b.Encoding != EncodingIdentity
+}
+
+// WireSize provides the count of bytes read
+// of the body, before decompression.
+func (b *Body) WireSize() int64 {
+	return b.wire.n
+}
+
+// Size provides the count of bytes read
+// of the body, after decompression.
+func (b *Body) Size() int64 {
+	return b.n
+}
+
+// Ratio provides the ratio of decompressed
+// to compressed bytes read of the body.
+func (b *Body) Ratio() float64 {
+	if b.wire.n == 0 {
+		return 0
+	}
+	return float64(b.n) / float64(b.wire.n)
+}
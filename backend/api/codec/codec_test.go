@@ -0,0 +1,124 @@
+package codec
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func compress(t *testing.T, encoding string, data []byte) io.ReadCloser {
+	var buf bytes.Buffer
+	switch encoding {
+	case EncodingGzip:
+		writer := gzip.NewWriter(&buf)
+		writer.Write(data)
+		writer.Close()
+	case EncodingZstd:
+		writer, err := zstd.NewWriter(&buf)
+		if err != nil {
+			t.Fatalf("Expected nil error, but got %v", err)
+		}
+		writer.Write(data)
+		writer.Close()
+	default:
+		buf.Write(data)
+	}
+	return io.NopCloser(&buf)
+}
+
+func TestParseEncoding(t *testing.T) {
+	expected := map[string]string{
+		"":         EncodingIdentity,
+		"identity": EncodingIdentity,
+		"gzip":     EncodingGzip,
+		" GZIP ":   EncodingGzip,
+		"x-gzip":   EncodingGzip,
+		"zstd":     EncodingZstd,
+	}
+
+	for header, encoding := range expected {
+		got, err := ParseEncoding(header)
+		if err != nil {
+			t.Errorf("Expected nil error for %q, but got %v", header, err)
+		}
+		if got != encoding {
+			t.Errorf("Expected %q, but got %q", encoding, got)
+		}
+	}
+
+	for _, header := range []string{"br", "deflate", "gzip, zstd"} {
+		if _, err := ParseEncoding(header); !errors.Is(err, ErrUnsupportedEncoding) {
+			t.Errorf("Expected %v for %q, but got %v", ErrUnsupportedEncoding, header, err)
+		}
+	}
+}
+
+func TestBodyRead(t *testing.T) {
+	data := []byte(strings.Repeat(`{"type":"string","string":{"string":"hello"}}`, 100))
+
+	for _, encoding := range []string{EncodingIdentity, EncodingGzip, EncodingZstd} {
+		body, err := NewBody(compress(t, encoding, data), encoding, 1024*1024)
+		if err != nil {
+			t.Fatalf("Expected nil error for %q, but got %v", encoding, err)
+		}
+
+		got, err := io.ReadAll(body)
+		if err != nil {
+			t.Errorf("Expected nil error for %q, but got %v", encoding, err)
+		}
+		body.Close()
+
+		if !bytes.Equal(data, got) {
+			t.Errorf("Expected decompressed body to match for %q", encoding)
+		}
+
+		if body.Size() != int64(len(data)) {
+			t.Errorf("Expected size %d for %q, but got %d", len(data), encoding, body.Size())
+		}
+
+		if encoding == EncodingIdentity {
+			if body.WireSize() != body.Size() {
+				t.Errorf("Expected wire size %d, but got %d", body.Size(), body.WireSize())
+			}
+		} else if body.WireSize() >= body.Size() || body.Ratio() <= 1 {
+			t.Errorf("Expected wire size of %q to be less than %d, but got %d", encoding, body.Size(), body.WireSize())
+		}
+	}
+}
+
+func TestBodyReadLimits(t *testing.T) {
+	data := bytes.Repeat([]byte("a"), 4*1024*1024)
+
+	for _, encoding := range []string{EncodingIdentity, EncodingGzip, EncodingZstd} {
+		body, err := NewBody(compress(t, encoding, data), encoding, 1024)
+		if err != nil {
+			t.Fatalf("Expected nil error for %q, but got %v", encoding, err)
+		}
+
+		if _, err := io.ReadAll(body); !errors.Is(err, ErrTooLarge) {
+			t.Errorf("Expected %v for %q, but got %v", ErrTooLarge, encoding, err)
+		}
+		body.Close()
+	}
+
+	for _, encoding := range []string{EncodingGzip, EncodingZstd} {
+		body, err := NewBody(compress(t, encoding, data), encoding, int64(len(data))*2)
+		if err != nil {
+			t.Fatalf("Expected nil error for %q, but got %v", encoding, err)
+		}
+
+		if _, err := io.ReadAll(body); !errors.Is(err, ErrRatio) {
+			t.Errorf("Expected %v for %q, but got %v", ErrRatio, encoding, err)
+		}
+		body.Close()
+	}
+
+	if _, err := NewBody(io.NopCloser(strings.NewReader("not gzip")), EncodingGzip, 1024); err == nil {
+		t.Error("Expected error for invalid gzip body, but got nil")
+	}
+}
@@ -106,6 +106,14 @@ type Attribute struct {
 	// - 5g
 	// - unknown
 	NetworkGeneration string `json:"network_generation"`
+
+	// UserDefinedAttrs is the app's own key/value
+	// pairs, like an experiment's variant or a
+	// subscription tier.
+	//
+	// Keys must only have lowercase letters,
+	// digits & underscores.
+	UserDefinedAttrs map[string]string `json:"user_defined_attribute"`
 }
 
 // Validate validates an event's attributes.
@@ -128,6 +136,9 @@ func (a Attribute) Validate() error {
 		maxNetworkGenerationChars  = 8
 		maxNetworkProviderChars    = 64
 		maxDeviceLocaleChars       = 64
+		maxUserDefinedAttrs        = 32
+		maxUserDefinedAttrKeyChars = 64
+		maxUserDefinedAttrValChars = 256
 	)
 
 	if len(a.AppVersion) > maxAppVersionChars {
@@ -190,5 +201,34 @@ func (a Attribute) Validate() error {
 	if !slices.Contains(ValidNetworkGenerations, a.NetworkGeneration) {
 		return fmt.Errorf(`%q contains invalid network geenration`, `attributes.network_generation`)
 	}
+	if len(a.UserDefinedAttrs) > maxUserDefinedAttrs {
+		return fmt.Errorf(`%q exceeds maximum allowed count of %d`, `attributes.user_defined_attribute`, maxUserDefinedAttrs)
+	}
+	for key, value := range a.UserDefinedAttrs {
+		if !isUserDefinedAttrKey(key) {
+			return fmt.Errorf(`%q key %q must only contain lowercase letters, digits & underscores`, `attributes.user_defined_attribute`, key)
+		}
+		if len(key) > maxUserDefinedAttrKeyChars {
+			return fmt.Errorf(`%q key %q exceeds maximum allowed characters of %d`, `attributes.user_defined_attribute`, key, maxUserDefinedAttrKeyChars)
+		}
+		if len(value) > maxUserDefinedAttrValChars {
+			return fmt.Errorf(`%q value of %q exceeds maximum allowed characters of %d`, `attributes.user_defined_attribute`, key, maxUserDefinedAttrValChars)
+		}
+	}
 	return nil
 }
+
+// isUserDefinedAttrKey checks if a user defined
+// attribute key is non-empty & only has lowercase
+// letters, digits & underscores.
+func isUserDefinedAttrKey(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, r := range key {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '_' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package event
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAttributeValidateUserDefined(t *testing.T) {
+	var attribute Attribute
+	payload := `{"platform":"android","network_type":"wifi","network_generation":"unknown","user_defined_attribute":{"experiment_variant":"control","subscription_tier":"pro"}}`
+	if err := json.Unmarshal([]byte(payload), &attribute); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if err := attribute.Validate(); err != nil {
+		t.Errorf("Expected nil error, but got %v", err)
+	}
+
+	if got := attribute.UserDefinedAttrs["subscription_tier"]; got != "pro" {
+		t.Errorf("Expected %q, but got %q", "pro", got)
+	}
+
+	tooMany := make(map[string]string)
+	for i := 0; i < 33; i++ {
+		tooMany["key_"+strconv.Itoa(i)] = "value"
+	}
+
+	invalid := []map[string]string{
+		tooMany,
+		{"": "value"},
+		{"Experiment": "control"},
+		{"experiment:variant": "control"},
+		{strings.Repeat("k", 65): "value"},
+		{"experiment_variant": strings.Repeat("v", 257)},
+	}
+
+	for i := range invalid {
+		attribute.UserDefinedAttrs = invalid[i]
+		if err := attribute.Validate(); err == nil {
+			t.Errorf("Expected error for invalid user defined attributes %d, but got nil", i)
+		}
+	}
+}
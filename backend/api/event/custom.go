@@ -0,0 +1,133 @@
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// constants defining maximum limits
+// for various custom event fields.
+const (
+	maxCustomNameChars          = 64
+	maxCustomProperties         = 32
+	maxCustomPropertyKeyChars   = 64
+	maxCustomPropertyValueChars = 256
+)
+
+// Custom represents a product event of
+// the app, like a purchase or a login,
+// with properties of its own.
+//
+// Property values must be either a
+// string, a number or a bool.
+type Custom struct {
+	Name       string         `json:"name" binding:"required"`
+	Properties map[string]any `json:"properties"`
+}
+
+// StringProperties provides the properties
+// having string values.
+func (c Custom) StringProperties() map[string]string {
+	props := make(map[string]string)
+	for key, value := range c.Properties {
+		if s, ok := value.(string); ok {
+			props[key] = s
+		}
+	}
+	return props
+}
+
+// NumberProperties provides the properties
+// having number values.
+func (c Custom) NumberProperties() map[string]float64 {
+	props := make(map[string]float64)
+	for key, value := range c.Properties {
+		if n, ok := toNumber(value); ok {
+			props[key] = n
+		}
+	}
+	return props
+}
+
+// BoolProperties provides the properties
+// having bool values.
+func (c Custom) BoolProperties() map[string]bool {
+	props := make(map[string]bool)
+	for key, value := range c.Properties {
+		if b, ok := value.(bool); ok {
+			props[key] = b
+		}
+	}
+	return props
+}
+
+// Validate validates the custom event
+// for data integrity.
+func (c Custom) Validate() error {
+	if len(c.Name) < 1 {
+		return fmt.Errorf(`%q must not be empty`, `custom.name`)
+	}
+
+	if len(c.Name) > maxCustomNameChars {
+		return fmt.Errorf(`%q exceeds maximum allowed characters of (%d)`, `custom.name`, maxCustomNameChars)
+	}
+
+	if len(c.Properties) > maxCustomProperties {
+		return fmt.Errorf(`%q exceeds maximum allowed count of (%d)`, `custom.properties`, maxCustomProperties)
+	}
+
+	for key, value := range c.Properties {
+		if len(key) < 1 {
+			return fmt.Errorf(`%q must not contain empty keys`, `custom.properties`)
+		}
+
+		if len(key) > maxCustomPropertyKeyChars {
+			return fmt.Errorf(`%q key %q exceeds maximum allowed characters of (%d)`, `custom.properties`, key, maxCustomPropertyKeyChars)
+		}
+
+		switch v := value.(type) {
+		case string:
+			if len(v) > maxCustomPropertyValueChars {
+				return fmt.Errorf(`%q value of %q exceeds maximum allowed characters of (%d)`, `custom.properties`, key, maxCustomPropertyValueChars)
+			}
+		case bool:
+		default:
+			n, ok := toNumber(value)
+			if !ok {
+				return fmt.Errorf(`%q value of %q must be a string, number or bool`, `custom.properties`, key)
+			}
+			if math.IsNaN(n) || math.IsInf(n, 0) {
+				return fmt.Errorf(`%q value of %q must be a finite number`, `custom.properties`, key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// toNumber converts numeric property
+// values to float64.
+func toNumber(value any) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case json.Number:
+		n, err := v.Float64()
+		return n, err == nil
+	default:
+		return 0, false
+	}
+}
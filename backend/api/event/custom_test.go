@@ -0,0 +1,55 @@
+package event
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestCustomValidate(t *testing.T) {
+	var custom Custom
+	payload := `{"name":"purchase_failed","properties":{"sku":"pro_yearly","amount":49.99,"retries":2,"trial":false}}`
+	if err := json.Unmarshal([]byte(payload), &custom); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if err := custom.Validate(); err != nil {
+		t.Errorf("Expected nil error, but got %v", err)
+	}
+
+	if got := custom.StringProperties(); len(got) != 1 || got["sku"] != "pro_yearly" {
+		t.Errorf("Expected string properties %v, but got %v", map[string]string{"sku": "pro_yearly"}, got)
+	}
+
+	if got := custom.NumberProperties(); len(got) != 2 || got["amount"] != 49.99 || got["retries"] != 2 {
+		t.Errorf("Expected number properties %v, but got %v", map[string]float64{"amount": 49.99, "retries": 2}, got)
+	}
+
+	if got := custom.BoolProperties(); len(got) != 1 || got["trial"] {
+		t.Errorf("Expected bool properties %v, but got %v", map[string]bool{"trial": false}, got)
+	}
+
+	tooMany := make(map[string]any)
+	for i := 0; i <= maxCustomProperties; i++ {
+		tooMany[strings.Repeat("k", i+1)] = i
+	}
+
+	invalid := []Custom{
+		{Name: ""},
+		{Name: strings.Repeat("n", maxCustomNameChars+1)},
+		{Name: "login", Properties: tooMany},
+		{Name: "login", Properties: map[string]any{"": "value"}},
+		{Name: "login", Properties: map[string]any{strings.Repeat("k", maxCustomPropertyKeyChars+1): "value"}},
+		{Name: "login", Properties: map[string]any{"method": strings.Repeat("v", maxCustomPropertyValueChars+1)}},
+		{Name: "login", Properties: map[string]any{"methods": []any{"otp", "password"}}},
+		{Name: "login", Properties: map[string]any{"method": nil}},
+		{Name: "login", Properties: map[string]any{"latency": math.Inf(1)}},
+	}
+
+	for i := range invalid {
+		if err := invalid[i].Validate(); err == nil {
+			t.Errorf("Expected error for invalid custom event %d, but got nil", i)
+		}
+	}
+}
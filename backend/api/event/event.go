@@ -72,6 +72,9 @@ const TypeLowMemory = "low_memory"
 const TypeTrimMemory = "trim_memory"
 const TypeCPUUsage = "cpu_usage"
 const TypeNavigation = "navigation"
+const TypeNativeCrash = "native_crash"
+const TypeSpan = "span"
+const TypeCustom = "custom"
 
 const NetworkGeneration2G = "2g"
 const NetworkGeneration3G = "3g"
@@ -177,19 +180,21 @@ type Thread struct {
 type Threads []Thread
 
 type ANR struct {
-	Handled     bool           `json:"handled" binding:"required"`
-	Exceptions  ExceptionUnits `json:"exceptions" binding:"required"`
-	Threads     Threads        `json:"threads" binding:"required"`
-	Fingerprint string         `json:"fingerprint"`
-	Foreground  bool           `json:"foreground" binding:"required"`
+	Handled            bool           `json:"handled" binding:"required"`
+	Exceptions         ExceptionUnits `json:"exceptions" binding:"required"`
+	Threads            Threads        `json:"threads" binding:"required"`
+	Fingerprint        string         `json:"fingerprint"`
+	Foreground         bool           `json:"foreground" binding:"required"`
+	FingerprintVersion int            `json:"fingerprint_version,omitempty"`
 }
 
 type Exception struct {
-	Handled     bool           `json:"handled" binding:"required"`
-	Exceptions  ExceptionUnits `json:"exceptions" binding:"required"`
-	Threads     Threads        `json:"threads" binding:"required"`
-	Fingerprint string         `json:"fingerprint"`
-	Foreground  bool           `json:"foreground" binding:"required"`
+	Handled            bool           `json:"handled" binding:"required"`
+	Exceptions         ExceptionUnits `json:"exceptions" binding:"required"`
+	Threads            Threads        `json:"threads" binding:"required"`
+	Fingerprint        string         `json:"fingerprint"`
+	Foreground         bool           `json:"foreground" binding:"required"`
+	FingerprintVersion int            `json:"fingerprint_version,omitempty"`
 }
 
 type AppExit struct {
@@ -390,6 +395,9 @@ type EventField struct {
 	TrimMemory        *TrimMemory        `json:"trim_memory,omitempty"`
 	CPUUsage          *CPUUsage          `json:"cpu_usage,omitempty"`
 	Navigation        *Navigation        `json:"navigation,omitempty"`
+	NativeCrash       *NativeCrash       `json:"native_crash,omitempty"`
+	Span              *Span              `json:"span,omitempty"`
+	Custom            *Custom            `json:"custom,omitempty"`
 }
 
 // Compute computes the most accurate cold launch timing
@@ -560,12 +568,22 @@ func (e EventField) IsNavigation() bool {
 	return e.Type == TypeNavigation
 }
 
+// IsSpan returns true for span event.
+func (e EventField) IsSpan() bool {
+	return e.Type == TypeSpan
+}
+
+// IsCustom returns true for custom event.
+func (e EventField) IsCustom() bool {
+	return e.Type == TypeCustom
+}
+
 // NeedsSymbolication returns true if the event needs
 // symbolication, false otherwise.
 func (e EventField) NeedsSymbolication() (result bool) {
 	result = false
 
-	if e.IsException() || e.IsANR() {
+	if e.IsException() || e.IsANR() || e.IsNativeCrash() {
 		result = true
 		return
 	}
@@ -625,7 +643,8 @@ func (e *EventField) Validate() error {
 		TypeLifecycleApp, TypeColdLaunch, TypeWarmLaunch,
 		TypeHotLaunch, TypeNetworkChange, TypeHttp,
 		TypeMemoryUsage, TypeLowMemory, TypeTrimMemory,
-		TypeCPUUsage, TypeNavigation,
+		TypeCPUUsage, TypeNavigation, TypeNativeCrash,
+		TypeSpan, TypeCustom,
 	}
 
 	if !slices.Contains(validTypes, e.Type) {
@@ -660,6 +679,12 @@ func (e *EventField) Validate() error {
 		}
 	}
 
+	if e.IsNativeCrash() {
+		if err := e.validateNativeCrash(); err != nil {
+			return err
+		}
+	}
+
 	if e.IsAppExit() {
 		if len(e.AppExit.Reason) < 1 || len(e.AppExit.Importance) < 1 || len(e.AppExit.ProcessName) < 1 {
 			return fmt.Errorf(`%q, %q, %q must not be empty`, `app_exit.reason`, `app_exit.importance`, `app_exit.process_name`)
@@ -903,6 +928,24 @@ func (e *EventField) Validate() error {
 		}
 	}
 
+	if e.IsSpan() {
+		if e.Span == nil {
+			return fmt.Errorf(`%q must not be empty`, `span`)
+		}
+		if err := e.Span.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if e.IsCustom() {
+		if e.Custom == nil {
+			return fmt.Errorf(`%q must not be empty`, `custom`)
+		}
+		if err := e.Custom.Validate(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -996,37 +1039,15 @@ func (e Exception) Stacktrace() string {
 }
 
 // ComputeExceptionFingerprint computes a fingerprint
-// from the exception data.
-func (e *Exception) ComputeExceptionFingerprint() (err error) {
-	if len(e.Exceptions) == 0 {
-		return fmt.Errorf("error computing exception fingerprint: no exceptions found")
+// from the exception data using the fingerprint rules.
+func (e *Exception) ComputeExceptionFingerprint(rules FingerprintRules) (err error) {
+	fingerprint, err := fingerprintExceptionUnits(e.Exceptions, rules)
+	if err != nil {
+		return fmt.Errorf("error computing exception fingerprint: %w", err)
 	}
 
-	// Get the innermost exception
-	innermostException := e.Exceptions[len(e.Exceptions)-1]
-
-	// Get the exception type
-	exceptionType := innermostException.Type
-
-	// Initialize fingerprint data with the exception type
-	fingerprintData := exceptionType
-
-	// Get the method name and file name from the first frame of the innermost exception
-	if len(innermostException.Frames) > 0 {
-		methodName := innermostException.Frames[0].MethodName
-		fileName := innermostException.Frames[0].FileName
-
-		// Include any non-empty information
-		if methodName != "" {
-			fingerprintData += ":" + methodName
-		}
-		if fileName != "" {
-			fingerprintData += ":" + fileName
-		}
-	}
-
-	// Compute the fingerprint
-	e.Fingerprint = computeFingerprint(fingerprintData)
+	e.Fingerprint = fingerprint
+	e.FingerprintVersion = rules.EffectiveVersion()
 
 	return nil
 }
@@ -1121,37 +1142,15 @@ func (a ANR) Stacktrace() string {
 }
 
 // ComputeANRFingerprint computes a fingerprint
-// from the ANR data.
-func (a *ANR) ComputeANRFingerprint() (err error) {
-	if len(a.Exceptions) == 0 {
-		return fmt.Errorf("error computing ANR fingerprint: no exceptions found")
-	}
-
-	// Get the innermost exception
-	innermostException := a.Exceptions[len(a.Exceptions)-1]
-
-	// Get the exception type
-	exceptionType := innermostException.Type
-
-	// Initialize fingerprint data with the exception type
-	fingerprintData := exceptionType
-
-	// Get the method name and file name from the first frame of the innermost exception
-	if len(innermostException.Frames) > 0 {
-		methodName := innermostException.Frames[0].MethodName
-		fileName := innermostException.Frames[0].FileName
-
-		// Include any non-empty information
-		if methodName != "" {
-			fingerprintData += ":" + methodName
-		}
-		if fileName != "" {
-			fingerprintData += ":" + fileName
-		}
+// from the ANR data using the fingerprint rules.
+func (a *ANR) ComputeANRFingerprint(rules FingerprintRules) (err error) {
+	fingerprint, err := fingerprintExceptionUnits(a.Exceptions, rules)
+	if err != nil {
+		return fmt.Errorf("error computing ANR fingerprint: %w", err)
 	}
 
-	// Compute the fingerprint
-	a.Fingerprint = computeFingerprint(fingerprintData)
+	a.Fingerprint = fingerprint
+	a.FingerprintVersion = rules.EffectiveVersion()
 
 	return nil
 }
@@ -0,0 +1,263 @@
+package event
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// FingerprintV1 is the original fingerprinting
+// algorithm. Hashes the innermost exception's type
+// along with the method & file name of its first frame.
+const FingerprintV1 = 1
+
+// FingerprintV2 is the rule based fingerprinting
+// algorithm. Frames, in-app frame count and message
+// inclusion are controlled by FingerprintRules.
+const FingerprintV2 = 2
+
+// FingerprintLatest is the most recent
+// fingerprinting algorithm.
+const FingerprintLatest = FingerprintV2
+
+// maxFingerprintFrames is the maximum number of
+// frames that can contribute to a fingerprint.
+const maxFingerprintFrames = 10
+
+// maxFingerprintPrefixes is the maximum number of
+// frame prefixes allowed in each rule.
+const maxFingerprintPrefixes = 50
+
+// maxFingerprintPrefixChars is the maximum number of
+// characters allowed in a frame prefix.
+const maxFingerprintPrefixChars = 256
+
+// ValidFingerprintVersions defines the
+// supported fingerprinting algorithms.
+var ValidFingerprintVersions = []int{
+	FingerprintV1,
+	FingerprintV2,
+}
+
+var (
+	uuidPattern   = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	hexPattern    = regexp.MustCompile(`(?i)\b0x[0-9a-f]+\b`)
+	numberPattern = regexp.MustCompile(`\d+`)
+)
+
+// FingerprintRules controls how exceptions and
+// ANRs of an app are fingerprinted for grouping.
+type FingerprintRules struct {
+	// Version is the fingerprinting algorithm.
+	Version int `json:"version"`
+
+	// SkipFramePrefixes excludes frames whose
+	// class & method start with any of the prefixes.
+	SkipFramePrefixes []string `json:"skip_frame_prefixes"`
+
+	// InAppFramePrefixes marks frames whose class
+	// & method start with any of the prefixes as
+	// in-app frames.
+	InAppFramePrefixes []string `json:"in_app_frame_prefixes"`
+
+	// InAppFrames is the number of frames
+	// that contribute to the fingerprint.
+	InAppFrames int `json:"in_app_frames"`
+
+	// IncludeMessage includes the message template of
+	// the exception with variable parts stripped.
+	IncludeMessage bool `json:"include_message"`
+}
+
+// DefaultFingerprintRules returns the rules
+// used for apps without any configured rules.
+func DefaultFingerprintRules() FingerprintRules {
+	return FingerprintRules{
+		Version:            FingerprintV1,
+		SkipFramePrefixes:  []string{},
+		InAppFramePrefixes: []string{},
+		InAppFrames:        1,
+	}
+}
+
+// Validate validates the fingerprint rules.
+func (r FingerprintRules) Validate() error {
+	if !slices.Contains(ValidFingerprintVersions, r.Version) {
+		return fmt.Errorf(`"version" must be one of %v`, ValidFingerprintVersions)
+	}
+
+	if r.InAppFrames < 1 || r.InAppFrames > maxFingerprintFrames {
+		return fmt.Errorf(`"in_app_frames" must be between 1 and %d`, maxFingerprintFrames)
+	}
+
+	prefixes := []struct {
+		name   string
+		values []string
+	}{
+		{"skip_frame_prefixes", r.SkipFramePrefixes},
+		{"in_app_frame_prefixes", r.InAppFramePrefixes},
+	}
+
+	for _, p := range prefixes {
+		if len(p.values) > maxFingerprintPrefixes {
+			return fmt.Errorf(`%q cannot have more than %d prefixes`, p.name, maxFingerprintPrefixes)
+		}
+		for _, prefix := range p.values {
+			if strings.TrimSpace(prefix) == "" {
+				return fmt.Errorf(`%q cannot contain empty prefixes`, p.name)
+			}
+			if len(prefix) > maxFingerprintPrefixChars {
+				return fmt.Errorf(`%q cannot contain prefixes longer than %d characters`, p.name, maxFingerprintPrefixChars)
+			}
+		}
+	}
+
+	return nil
+}
+
+// EffectiveVersion returns the fingerprinting algorithm
+// of the rules. Missing versions are treated as the
+// original algorithm to keep existing groups stable.
+func (r FingerprintRules) EffectiveVersion() int {
+	if r.Version == 0 {
+		return FingerprintV1
+	}
+
+	return r.Version
+}
+
+// skips returns true if the frame
+// must be excluded.
+func (r FingerprintRules) skips(f Frame) bool {
+	return hasAnyPrefix(f.CodeInfo(), r.SkipFramePrefixes)
+}
+
+// inApp returns true if the frame
+// belongs to the app.
+func (r FingerprintRules) inApp(f Frame) bool {
+	return hasAnyPrefix(f.CodeInfo(), r.InAppFramePrefixes)
+}
+
+// selectFrames picks the frames contributing to
+// the fingerprint. Prefers in-app frames and falls
+// back to the remaining frames if none are in-app.
+func (r FingerprintRules) selectFrames(frames Frames) (selected Frames) {
+	limit := r.InAppFrames
+	if limit < 1 {
+		limit = 1
+	}
+
+	var remaining Frames
+	for _, f := range frames {
+		if r.skips(f) {
+			continue
+		}
+		remaining = append(remaining, f)
+	}
+
+	if len(r.InAppFramePrefixes) > 0 {
+		for _, f := range remaining {
+			if r.inApp(f) {
+				selected = append(selected, f)
+			}
+		}
+	}
+
+	if len(selected) == 0 {
+		selected = remaining
+	}
+
+	if len(selected) > limit {
+		selected = selected[:limit]
+	}
+
+	return
+}
+
+// NormalizeMessage strips variable parts like UUIDs,
+// hexadecimal addresses & numbers from a message so
+// that similar messages produce the same template.
+func NormalizeMessage(message string) string {
+	message = uuidPattern.ReplaceAllString(message, "<uuid>")
+	message = hexPattern.ReplaceAllString(message, "<hex>")
+	message = numberPattern.ReplaceAllString(message, "<num>")
+
+	return strings.TrimSpace(message)
+}
+
+// fingerprintExceptionUnits computes the fingerprint
+// of the innermost exception unit using the rules.
+func fingerprintExceptionUnits(units ExceptionUnits, rules FingerprintRules) (fingerprint string, err error) {
+	if len(units) == 0 {
+		err = errors.New("no exceptions found")
+		return
+	}
+
+	innermost := units[len(units)-1]
+
+	switch rules.EffectiveVersion() {
+	case FingerprintV1:
+		fingerprint = computeFingerprint(fingerprintDataV1(innermost))
+	case FingerprintV2:
+		fingerprint = computeFingerprint(fingerprintDataV2(innermost, rules))
+	default:
+		err = fmt.Errorf("unknown fingerprint version %d", rules.Version)
+	}
+
+	return
+}
+
+// fingerprintDataV1 prepares the data to hash
+// using the original algorithm.
+func fingerprintDataV1(unit ExceptionUnit) string {
+	// Initialize fingerprint data with the exception type
+	fingerprintData := unit.Type
+
+	// Get the method name and file name from the first frame
+	if len(unit.Frames) > 0 {
+		methodName := unit.Frames[0].MethodName
+		fileName := unit.Frames[0].FileName
+
+		// Include any non-empty information
+		if methodName != "" {
+			fingerprintData += ":" + methodName
+		}
+		if fileName != "" {
+			fingerprintData += ":" + fileName
+		}
+	}
+
+	return fingerprintData
+}
+
+// fingerprintDataV2 prepares the data to hash
+// using the rule based algorithm.
+func fingerprintDataV2(unit ExceptionUnit, rules FingerprintRules) string {
+	// the version is part of the data so that
+	// fingerprints never collide across versions
+	parts := []string{fmt.Sprintf("v%d", FingerprintV2), unit.Type}
+
+	if rules.IncludeMessage {
+		parts = append(parts, NormalizeMessage(unit.Message))
+	}
+
+	for _, f := range rules.selectFrames(unit.Frames) {
+		parts = append(parts, f.CodeInfo()+":"+f.FileName)
+	}
+
+	return strings.Join(parts, "\n")
+}
+
+// hasAnyPrefix returns true if s starts
+// with any of the prefixes.
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
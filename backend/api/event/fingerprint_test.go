@@ -0,0 +1,212 @@
+package event
+
+import (
+	"strings"
+	"testing"
+)
+
+func newTestException(message string, frames ...Frame) Exception {
+	return Exception{
+		Exceptions: ExceptionUnits{
+			{
+				Type:    "java.lang.NullPointerException",
+				Message: message,
+				Frames:  frames,
+			},
+		},
+	}
+}
+
+var (
+	frameUtil = Frame{ClassName: "com.example.util.Strings", MethodName: "trim", FileName: "Strings.kt"}
+	frameMain = Frame{ClassName: "com.example.app.MainActivity", MethodName: "onClick", FileName: "MainActivity.kt"}
+	frameList = Frame{ClassName: "com.example.app.ListAdapter", MethodName: "bind", FileName: "ListAdapter.kt"}
+	frameView = Frame{ClassName: "android.view.View", MethodName: "performClick", FileName: "View.java"}
+)
+
+func TestFingerprintV1IsStable(t *testing.T) {
+	exception := newTestException("boom", Frame{MethodName: "onClick", FileName: "MainActivity.kt"})
+
+	if err := exception.ComputeExceptionFingerprint(FingerprintRules{}); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	expected := "454aa3cb15ac48b72106c2658a3230ff"
+	if exception.Fingerprint != expected {
+		t.Errorf("Expected fingerprint %q, but got %q", expected, exception.Fingerprint)
+	}
+
+	if exception.FingerprintVersion != FingerprintV1 {
+		t.Errorf("Expected fingerprint version %d, but got %d", FingerprintV1, exception.FingerprintVersion)
+	}
+
+	// rules other than the version must not
+	// affect the original algorithm
+	rules := DefaultFingerprintRules()
+	rules.IncludeMessage = true
+	rules.SkipFramePrefixes = []string{"onClick"}
+	if err := exception.ComputeExceptionFingerprint(rules); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if exception.Fingerprint != expected {
+		t.Errorf("Expected fingerprint %q, but got %q", expected, exception.Fingerprint)
+	}
+}
+
+func TestFingerprintVersionsDiffer(t *testing.T) {
+	v1 := newTestException("boom", frameMain)
+	v2 := newTestException("boom", frameMain)
+
+	rules := DefaultFingerprintRules()
+	v1.ComputeExceptionFingerprint(rules)
+
+	rules.Version = FingerprintV2
+	v2.ComputeExceptionFingerprint(rules)
+
+	if v1.Fingerprint == v2.Fingerprint {
+		t.Error("Expected fingerprints to differ across versions")
+	}
+
+	if v2.FingerprintVersion != FingerprintV2 {
+		t.Errorf("Expected fingerprint version %d, but got %d", FingerprintV2, v2.FingerprintVersion)
+	}
+}
+
+func TestFingerprintV2SkipFramePrefixes(t *testing.T) {
+	rules := FingerprintRules{
+		Version:           FingerprintV2,
+		SkipFramePrefixes: []string{"com.example.util."},
+		InAppFrames:       1,
+	}
+
+	a := newTestException("boom", frameUtil, frameMain)
+	b := newTestException("boom", frameUtil, frameList)
+
+	a.ComputeExceptionFingerprint(rules)
+	b.ComputeExceptionFingerprint(rules)
+
+	if a.Fingerprint == b.Fingerprint {
+		t.Error("Expected fingerprints to differ when skipped frames are followed by different frames")
+	}
+
+	rules.SkipFramePrefixes = []string{}
+	a.ComputeExceptionFingerprint(rules)
+	b.ComputeExceptionFingerprint(rules)
+
+	if a.Fingerprint != b.Fingerprint {
+		t.Error("Expected fingerprints to match when only the first frame contributes")
+	}
+}
+
+func TestFingerprintV2InAppFrames(t *testing.T) {
+	rules := FingerprintRules{
+		Version:            FingerprintV2,
+		InAppFramePrefixes: []string{"com.example.app."},
+		InAppFrames:        2,
+	}
+
+	frames := rules.selectFrames(Frames{frameView, frameMain, frameUtil, frameList})
+	if len(frames) != 2 {
+		t.Fatalf("Expected 2 frames, but got %d", len(frames))
+	}
+
+	if frames[0] != frameMain || frames[1] != frameList {
+		t.Errorf("Expected in-app frames, but got %+v", frames)
+	}
+
+	// falls back to remaining frames when
+	// no frame is in-app
+	frames = rules.selectFrames(Frames{frameView, frameUtil})
+	if len(frames) != 2 || frames[0] != frameView {
+		t.Errorf("Expected fallback frames, but got %+v", frames)
+	}
+}
+
+func TestFingerprintV2IncludeMessage(t *testing.T) {
+	rules := FingerprintRules{
+		Version:        FingerprintV2,
+		InAppFrames:    1,
+		IncludeMessage: true,
+	}
+
+	a := newTestException("user 42 not found in 0192a0a6-6b7c-7e3a-9b3c-5c2a7d1f0e11", frameMain)
+	b := newTestException("user 1337 not found in 0192a0b1-2f3e-7c4d-8a5b-6c7d8e9f0a1b", frameMain)
+	c := newTestException("cart is empty", frameMain)
+
+	a.ComputeExceptionFingerprint(rules)
+	b.ComputeExceptionFingerprint(rules)
+	c.ComputeExceptionFingerprint(rules)
+
+	if a.Fingerprint != b.Fingerprint {
+		t.Error("Expected fingerprints to match for the same message template")
+	}
+
+	if a.Fingerprint == c.Fingerprint {
+		t.Error("Expected fingerprints to differ for different message templates")
+	}
+}
+
+func TestFingerprintANR(t *testing.T) {
+	anr := ANR{
+		Exceptions: ExceptionUnits{
+			{Type: "sh.measure.android.anr.AnrError", Frames: Frames{frameMain}},
+		},
+	}
+
+	if err := anr.ComputeANRFingerprint(FingerprintRules{Version: FingerprintV2, InAppFrames: 1}); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if len(anr.Fingerprint) != 32 {
+		t.Errorf("Expected 32 character fingerprint, but got %q", anr.Fingerprint)
+	}
+
+	if err := (&ANR{}).ComputeANRFingerprint(DefaultFingerprintRules()); err == nil {
+		t.Error("Expected error for ANR without exceptions, but got nil")
+	}
+}
+
+func TestNormalizeMessage(t *testing.T) {
+	cases := map[string]string{
+		"Index 5 out of bounds for length 3":                       "Index <num> out of bounds for length <num>",
+		"object at 0x7ffee4b2c8 was released":                      "object at <hex> was released",
+		"session 0192A0A6-6B7C-7E3A-9B3C-5C2A7D1F0E11 expired":     "session <uuid> expired",
+		"  Attempt to invoke virtual method on a null reference  ": "Attempt to invoke virtual method on a null reference",
+	}
+
+	for message, expected := range cases {
+		if got := NormalizeMessage(message); got != expected {
+			t.Errorf("Expected %q, but got %q", expected, got)
+		}
+	}
+}
+
+func TestFingerprintRulesValidate(t *testing.T) {
+	valid := []FingerprintRules{
+		DefaultFingerprintRules(),
+		{Version: FingerprintV2, InAppFrames: 3, SkipFramePrefixes: []string{"java.", "kotlin."}},
+	}
+
+	for _, r := range valid {
+		if err := r.Validate(); err != nil {
+			t.Errorf("Expected nil error for %+v, but got %v", r, err)
+		}
+	}
+
+	invalid := []FingerprintRules{
+		{Version: 0, InAppFrames: 1},
+		{Version: 3, InAppFrames: 1},
+		{Version: FingerprintV2, InAppFrames: 0},
+		{Version: FingerprintV2, InAppFrames: maxFingerprintFrames + 1},
+		{Version: FingerprintV2, InAppFrames: 1, SkipFramePrefixes: []string{" "}},
+		{Version: FingerprintV2, InAppFrames: 1, InAppFramePrefixes: []string{strings.Repeat("a", maxFingerprintPrefixChars+1)}},
+		{Version: FingerprintV2, InAppFrames: 1, InAppFramePrefixes: make([]string, maxFingerprintPrefixes+1)},
+	}
+
+	for _, r := range invalid {
+		if err := r.Validate(); err == nil {
+			t.Errorf("Expected error for %+v, but got nil", r)
+		}
+	}
+}
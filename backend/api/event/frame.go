@@ -4,6 +4,7 @@ import (
 	"backend/api/text"
 	"fmt"
 	"strconv"
+	"strings"
 )
 
 // FramePrefix is the prefix string that
@@ -21,6 +22,35 @@ type Frame struct {
 	FileName   string `json:"file_name"`
 	ClassName  string `json:"class_name"`
 	MethodName string `json:"method_name"`
+
+	// FrameIndex is the position of the
+	// frame in a native stacktrace.
+	FrameIndex int `json:"frame_index,omitempty"`
+
+	// BinaryName is the name of the binary
+	// image of a native frame.
+	BinaryName string `json:"binary_name,omitempty"`
+
+	// BinaryAddress is the load address of the
+	// binary image in hex, or the name of the
+	// symbol if resolved on the device.
+	BinaryAddress string `json:"binary_address,omitempty"`
+
+	// Offset is the decimal offset of the instruction
+	// from the binary address.
+	Offset string `json:"offset,omitempty"`
+
+	// SymbolAddress is the address of the
+	// instruction in hex.
+	SymbolAddress string `json:"symbol_address,omitempty"`
+
+	// InApp is true if the frame originates
+	// from the app's own binary.
+	InApp bool `json:"in_app,omitempty"`
+
+	// BuildID is the build id of the binary
+	// image of a native frame in hex.
+	BuildID string `json:"build_id,omitempty"`
 }
 
 type Frames []Frame
@@ -50,6 +80,10 @@ func (f Frame) FileInfo() string {
 // String provides a serialized
 // version of the frame.
 func (f Frame) String() string {
+	if f.IsNative() {
+		return f.nativeString()
+	}
+
 	codeInfo := f.CodeInfo()
 	fileInfo := f.FileInfo()
 
@@ -59,3 +93,65 @@ func (f Frame) String() string {
 
 	return fmt.Sprintf(`%s%s`, codeInfo, fileInfo)
 }
+
+// IsNative returns true if the frame
+// belongs to a native binary image.
+func (f Frame) IsNative() bool {
+	return f.BinaryName != ""
+}
+
+// ImageOffset returns the offset of the frame's
+// instruction from the load address of its binary
+// image. Returns false if the offset cannot be
+// determined, like when the binary address is a
+// symbol already resolved on the device. Frames
+// without a binary address, like those of native
+// crashes, carry the offset itself.
+func (f Frame) ImageOffset() (offset uint64, ok bool) {
+	if f.BinaryAddress == "" && f.Offset != "" {
+		offset, err := strconv.ParseUint(f.Offset, 10, 64)
+		return offset, err == nil
+	}
+
+	base, err := parseHex(f.BinaryAddress)
+	if err != nil {
+		return
+	}
+
+	if f.Offset != "" {
+		offset, err = strconv.ParseUint(f.Offset, 10, 64)
+		if err != nil {
+			return
+		}
+		return offset, true
+	}
+
+	address, err := parseHex(f.SymbolAddress)
+	if err != nil || address < base {
+		return
+	}
+
+	return address - base, true
+}
+
+// nativeString serializes a native frame
+// in the style of Apple crash reports.
+func (f Frame) nativeString() string {
+	location := text.JoinNonEmptyStrings(" + ", f.BinaryAddress, f.Offset)
+
+	if f.MethodName != "" {
+		location = f.CodeInfo()
+		if fileInfo := f.FileInfo(); fileInfo != "" {
+			location += fmt.Sprintf(` (%s)`, fileInfo)
+		}
+	}
+
+	return text.JoinNonEmptyStrings(" ", f.BinaryName, f.SymbolAddress, location)
+}
+
+// parseHex parses a hexadecimal address
+// with or without the "0x" prefix.
+func parseHex(s string) (uint64, error) {
+	s = strings.TrimPrefix(strings.ToLower(s), "0x")
+	return strconv.ParseUint(s, 16, 64)
+}
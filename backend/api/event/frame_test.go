@@ -0,0 +1,83 @@
+package event
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestFrameString(t *testing.T) {
+	jvm := Frame{
+		ClassName:  "com.example.app.MainActivity",
+		MethodName: "onClick",
+		FileName:   "MainActivity.kt",
+		LineNum:    42,
+	}
+
+	expectedJVM := "com.example.app.MainActivity.onClick(MainActivity.kt:42)"
+	if got := jvm.String(); got != expectedJVM {
+		t.Errorf("Expected %q, but got %q", expectedJVM, got)
+	}
+
+	native := Frame{
+		FrameIndex:    1,
+		BinaryName:    "DemoApp",
+		BinaryAddress: "102a34000",
+		Offset:        "34008",
+		SymbolAddress: "0000000102a3c4d8",
+		InApp:         true,
+	}
+
+	expectedNative := "DemoApp 0000000102a3c4d8 102a34000 + 34008"
+	if got := native.String(); got != expectedNative {
+		t.Errorf("Expected %q, but got %q", expectedNative, got)
+	}
+
+	native.MethodName = "ViewController.crash()"
+	native.FileName = "ViewController.swift"
+	native.LineNum = 27
+
+	expectedSymbolicated := "DemoApp 0000000102a3c4d8 ViewController.crash() (ViewController.swift:27)"
+	if got := native.String(); got != expectedSymbolicated {
+		t.Errorf("Expected %q, but got %q", expectedSymbolicated, got)
+	}
+}
+
+func TestFrameImageOffset(t *testing.T) {
+	cases := []struct {
+		frame  Frame
+		offset uint64
+		ok     bool
+	}{
+		{Frame{BinaryName: "DemoApp", BinaryAddress: "102a34000", Offset: "34008"}, 34008, true},
+		{Frame{BinaryName: "DemoApp", BinaryAddress: "0x102a34000", SymbolAddress: "0000000102a3c4d8"}, 0x84d8, true},
+		{Frame{BinaryName: "UIKitCore", BinaryAddress: "-[UIApplication sendAction:to:from:forEvent:]", Offset: "96"}, 0, false},
+		{Frame{BinaryName: "DemoApp", BinaryAddress: "102a34000", Offset: "-1"}, 0, false},
+		{Frame{BinaryName: "DemoApp", BinaryAddress: "102a34000", SymbolAddress: "102a30000"}, 0, false},
+		{Frame{BinaryName: "/data/app/lib/arm64/libnative.so", Offset: "47264"}, 47264, true},
+	}
+
+	for _, c := range cases {
+		offset, ok := c.frame.ImageOffset()
+		if ok != c.ok || offset != c.offset {
+			t.Errorf("Expected (%d, %v) for %+v, but got (%d, %v)", c.offset, c.ok, c.frame, offset, ok)
+		}
+	}
+}
+
+func TestFrameOmitsNativeFields(t *testing.T) {
+	data, err := json.Marshal(Frame{ClassName: "com.example.app.MainActivity", MethodName: "onClick"})
+	if err != nil {
+		t.Fatalf("Failed to marshal JSON: %v", err)
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to unmarshal JSON: %v", err)
+	}
+
+	for _, key := range []string{"frame_index", "binary_name", "binary_address", "offset", "symbol_address", "in_app", "build_id"} {
+		if _, ok := result[key]; ok {
+			t.Errorf("Expected key %q to be omitted, got %v", key, result)
+		}
+	}
+}
@@ -36,6 +36,7 @@ type EventException struct {
 	Type          string         `json:"type"`
 	Attribute     Attribute      `json:"attribute"`
 	Exception     Exception      `json:"-"`
+	NativeCrash   *NativeCrash   `json:"-"`
 	ExceptionView ExceptionView  `json:"exception"`
 	Attachments   []Attachment   `json:"attachments"`
 	Threads       []ThreadView   `json:"threads"`
@@ -69,10 +70,19 @@ func (e *EventANR) ComputeView() {
 // ComputeView computes a consumer friendly
 // version of the exception.
 func (e *EventException) ComputeView() {
-	e.ExceptionView = ExceptionView{
-		Title:      e.Exception.GetDisplayTitle(),
-		Stacktrace: e.Exception.Stacktrace(),
-		Message:    e.Exception.GetMessage(),
+	if e.NativeCrash != nil {
+		e.Exception = e.NativeCrash.AsException()
+		e.ExceptionView = ExceptionView{
+			Title:      e.NativeCrash.GetDisplayTitle(),
+			Stacktrace: e.NativeCrash.Stacktrace(),
+			Message:    e.NativeCrash.GetMessage(),
+		}
+	} else {
+		e.ExceptionView = ExceptionView{
+			Title:      e.Exception.GetDisplayTitle(),
+			Stacktrace: e.Exception.Stacktrace(),
+			Message:    e.Exception.GetMessage(),
+		}
 	}
 
 	for i := range e.Exception.Threads {
@@ -0,0 +1,302 @@
+package event
+
+import (
+	"backend/api/text"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// constants defining limits for
+// native crash fields.
+const (
+	maxNativeCrashSignalChars       = 32
+	maxNativeCrashSignalCodeChars   = 32
+	maxNativeCrashFaultAddressChars = 32
+	maxNativeCrashAbortMessageChars = 1024
+	maxNativeCrashRegisters         = 64
+)
+
+// nativeFingerprintFrames is the number of app
+// frames that contribute to a native crash's
+// fingerprint.
+const nativeFingerprintFrames = 3
+
+// systemLibraryDirs are the directories of
+// libraries shipped with the Android OS.
+var systemLibraryDirs = []string{
+	"/system/",
+	"/apex/",
+	"/vendor/",
+}
+
+// systemLibraries are the names of well known
+// libraries shipped with the Android OS.
+var systemLibraries = []string{
+	"libc.so",
+	"libm.so",
+	"libdl.so",
+	"liblog.so",
+	"libart.so",
+	"libc++.so",
+	"libandroid_runtime.so",
+	"linker",
+	"linker64",
+	"[vdso]",
+}
+
+// NativeCrash represents a crash of the app's
+// native code, like a C/C++ signal raised by
+// code built with the Android NDK.
+type NativeCrash struct {
+	// Signal is the name of the signal that
+	// terminated the process, like SIGSEGV.
+	Signal string `json:"signal" binding:"required"`
+
+	// SignalCode is the name of the signal's
+	// code, like SEGV_MAPERR.
+	SignalCode string `json:"signal_code"`
+
+	// FaultAddress is the memory address in hex
+	// that caused the fault, if any.
+	FaultAddress string `json:"fault_address"`
+
+	// AbortMessage is the message set by the
+	// app before aborting, if any.
+	AbortMessage string `json:"abort_message"`
+
+	// Frames are the frames of the
+	// crashing thread.
+	Frames Frames `json:"frames" binding:"required"`
+
+	// Threads are the remaining threads of
+	// the process at the time of the crash.
+	Threads Threads `json:"threads"`
+
+	// Registers are the register values of the
+	// crashing thread keyed by register name.
+	Registers map[string]string `json:"registers"`
+
+	// Foreground is true if the crash was
+	// perceived by end user.
+	Foreground bool `json:"foreground" binding:"required"`
+
+	// Fingerprint is the fingerprint for native
+	// crash similarity classification.
+	Fingerprint string `json:"fingerprint"`
+
+	// FingerprintVersion is the fingerprinting
+	// algorithm of the fingerprint.
+	FingerprintVersion int `json:"fingerprint_version,omitempty"`
+}
+
+// IsNativeCrash returns true for native
+// crash event.
+func (e EventField) IsNativeCrash() bool {
+	return e.Type == TypeNativeCrash
+}
+
+// validateNativeCrash validates the
+// native crash for data integrity.
+func (e EventField) validateNativeCrash() error {
+	n := e.NativeCrash
+
+	if n.Signal == "" {
+		return fmt.Errorf(`%q must not be empty`, `native_crash.signal`)
+	}
+	if len(n.Frames) < 1 {
+		return fmt.Errorf(`%q must contain at least one frame`, `native_crash.frames`)
+	}
+	if len(n.Signal) > maxNativeCrashSignalChars {
+		return fmt.Errorf(`%q exceeds maximum allowed characters of (%d)`, `native_crash.signal`, maxNativeCrashSignalChars)
+	}
+	if len(n.SignalCode) > maxNativeCrashSignalCodeChars {
+		return fmt.Errorf(`%q exceeds maximum allowed characters of (%d)`, `native_crash.signal_code`, maxNativeCrashSignalCodeChars)
+	}
+	if len(n.FaultAddress) > maxNativeCrashFaultAddressChars {
+		return fmt.Errorf(`%q exceeds maximum allowed characters of (%d)`, `native_crash.fault_address`, maxNativeCrashFaultAddressChars)
+	}
+	if n.FaultAddress != "" {
+		if _, err := parseHex(n.FaultAddress); err != nil {
+			return fmt.Errorf(`%q must be a hexadecimal address`, `native_crash.fault_address`)
+		}
+	}
+	if len(n.AbortMessage) > maxNativeCrashAbortMessageChars {
+		return fmt.Errorf(`%q exceeds maximum allowed characters of (%d)`, `native_crash.abort_message`, maxNativeCrashAbortMessageChars)
+	}
+	if len(n.Registers) > maxNativeCrashRegisters {
+		return fmt.Errorf(`%q cannot have more than %d registers`, `native_crash.registers`, maxNativeCrashRegisters)
+	}
+
+	return nil
+}
+
+// GetTitle provides the combined native
+// crash's signal and message as a
+// formatted string.
+func (n NativeCrash) GetTitle() string {
+	return makeTitle(n.GetType(), n.GetMessage())
+}
+
+// GetType provides the type of the
+// native crash.
+func (n NativeCrash) GetType() string {
+	return n.Signal
+}
+
+// GetMessage provides the message of the
+// native crash. Prefers the abort message
+// over the signal code & fault address.
+func (n NativeCrash) GetMessage() string {
+	if n.AbortMessage != "" {
+		return n.AbortMessage
+	}
+
+	faultAddress := ""
+	if n.FaultAddress != "" {
+		faultAddress = "fault addr " + n.FaultAddress
+	}
+
+	return text.JoinNonEmptyStrings(", ", n.SignalCode, faultAddress)
+}
+
+// GetFileName provides the file name of
+// the native crash.
+func (n NativeCrash) GetFileName() string {
+	frame := n.crashingFrame()
+	if frame.FileName != "" {
+		return frame.FileName
+	}
+
+	return path.Base(frame.BinaryName)
+}
+
+// GetLineNumber provides the line number of
+// the native crash.
+func (n NativeCrash) GetLineNumber() int {
+	return n.crashingFrame().LineNum
+}
+
+// GetMethodName provides the method name of
+// the native crash.
+func (n NativeCrash) GetMethodName() string {
+	return n.crashingFrame().MethodName
+}
+
+// GetDisplayTitle provides a user friendly display
+// name for the native crash.
+func (n NativeCrash) GetDisplayTitle() string {
+	return n.GetType() + "@" + n.GetFileName()
+}
+
+// Stacktrace writes a formatted stacktrace
+// from the native crash.
+func (n NativeCrash) Stacktrace() string {
+	var b strings.Builder
+
+	b.WriteString(n.GetTitle())
+
+	for _, frame := range n.Frames {
+		b.WriteString("\n")
+		b.WriteString(FramePrefix + frame.String())
+	}
+
+	return b.String()
+}
+
+// AsException represents the native crash as an
+// unhandled exception so that it can be displayed
+// alongside other crashes.
+func (n NativeCrash) AsException() Exception {
+	return Exception{
+		Handled: false,
+		Exceptions: ExceptionUnits{
+			{
+				Type:    n.GetType(),
+				Message: n.GetMessage(),
+				Frames:  n.Frames,
+			},
+		},
+		Threads:            n.Threads,
+		Fingerprint:        n.Fingerprint,
+		Foreground:         n.Foreground,
+		FingerprintVersion: n.FingerprintVersion,
+	}
+}
+
+// ComputeNativeCrashFingerprint computes a fingerprint
+// from the native crash data using the fingerprint rules.
+func (n *NativeCrash) ComputeNativeCrashFingerprint(rules FingerprintRules) (err error) {
+	if len(n.Frames) == 0 {
+		return fmt.Errorf("error computing native crash fingerprint: no frames found")
+	}
+
+	// the signal code is left out as different
+	// codes of the same signal usually share
+	// the same root cause
+	parts := []string{TypeNativeCrash, n.Signal}
+
+	for _, f := range n.fingerprintFrames(rules) {
+		symbol := f.MethodName
+		if symbol == "" {
+			symbol = "+" + f.Offset
+		}
+		parts = append(parts, path.Base(f.BinaryName)+":"+symbol)
+	}
+
+	n.Fingerprint = computeFingerprint(strings.Join(parts, "\n"))
+	n.FingerprintVersion = rules.EffectiveVersion()
+
+	return nil
+}
+
+// fingerprintFrames picks the frames of the crashing
+// thread contributing to the fingerprint. Prefers
+// frames outside of system libraries and falls back
+// to the top frames if all frames are system frames.
+func (n NativeCrash) fingerprintFrames(rules FingerprintRules) (selected Frames) {
+	var remaining Frames
+	for _, f := range n.Frames {
+		if rules.EffectiveVersion() >= FingerprintV2 && rules.skips(f) {
+			continue
+		}
+		remaining = append(remaining, f)
+	}
+
+	for _, f := range remaining {
+		if !isSystemLibrary(f.BinaryName) {
+			selected = append(selected, f)
+		}
+	}
+
+	if len(selected) == 0 {
+		selected = remaining
+	}
+
+	if len(selected) > nativeFingerprintFrames {
+		selected = selected[:nativeFingerprintFrames]
+	}
+
+	return
+}
+
+// crashingFrame returns the topmost frame
+// of the crashing thread.
+func (n NativeCrash) crashingFrame() Frame {
+	if len(n.Frames) == 0 {
+		return Frame{}
+	}
+
+	return n.Frames[0]
+}
+
+// isSystemLibrary returns true if the binary
+// is a library shipped with the Android OS.
+func isSystemLibrary(binary string) bool {
+	if hasAnyPrefix(binary, systemLibraryDirs) {
+		return true
+	}
+
+	return slices.Contains(systemLibraries, path.Base(binary))
+}
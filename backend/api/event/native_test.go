@@ -0,0 +1,171 @@
+package event
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	nativeFrameLibc   = Frame{FrameIndex: 0, BinaryName: "/apex/com.android.runtime/lib64/bionic/libc.so", Offset: "563412", MethodName: "abort"}
+	nativeFrameCrash  = Frame{FrameIndex: 1, BinaryName: "/data/app/com.example.app/lib/arm64/libnative.so", Offset: "47264", BuildID: "a3f1c2"}
+	nativeFrameCaller = Frame{FrameIndex: 2, BinaryName: "/data/app/com.example.app/lib/arm64/libnative.so", Offset: "47112", BuildID: "a3f1c2"}
+)
+
+func newTestNativeCrashEvent() EventField {
+	return EventField{
+		ID:        uuid.New(),
+		AppID:     uuid.New(),
+		SessionID: uuid.New(),
+		Timestamp: time.Now(),
+		Type:      TypeNativeCrash,
+		NativeCrash: &NativeCrash{
+			Signal:       "SIGSEGV",
+			SignalCode:   "SEGV_MAPERR",
+			FaultAddress: "0x0",
+			Frames:       Frames{nativeFrameLibc, nativeFrameCrash, nativeFrameCaller},
+			Registers:    map[string]string{"x0": "0000000000000000"},
+			Foreground:   true,
+		},
+	}
+}
+
+func TestValidateNativeCrash(t *testing.T) {
+	ev := newTestNativeCrashEvent()
+	if err := ev.Validate(); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if !ev.NeedsSymbolication() {
+		t.Error("Expected native crash to need symbolication")
+	}
+
+	cases := []struct {
+		name   string
+		mutate func(n *NativeCrash)
+	}{
+		{"empty signal", func(n *NativeCrash) { n.Signal = "" }},
+		{"no frames", func(n *NativeCrash) { n.Frames = nil }},
+		{"long signal", func(n *NativeCrash) { n.Signal = strings.Repeat("S", maxNativeCrashSignalChars+1) }},
+		{"non hex fault address", func(n *NativeCrash) { n.FaultAddress = "nowhere" }},
+		{"long abort message", func(n *NativeCrash) { n.AbortMessage = strings.Repeat("a", maxNativeCrashAbortMessageChars+1) }},
+		{"too many registers", func(n *NativeCrash) {
+			n.Registers = make(map[string]string)
+			for i := 0; i <= maxNativeCrashRegisters; i++ {
+				n.Registers[strings.Repeat("x", i+1)] = "0"
+			}
+		}},
+	}
+
+	for _, c := range cases {
+		ev := newTestNativeCrashEvent()
+		c.mutate(ev.NativeCrash)
+		if err := ev.Validate(); err == nil {
+			t.Errorf("Expected error for %s, but got nil", c.name)
+		}
+	}
+}
+
+func TestNativeCrashFingerprintSkipsSystemLibraries(t *testing.T) {
+	crash := newTestNativeCrashEvent().NativeCrash
+	if err := crash.ComputeNativeCrashFingerprint(DefaultFingerprintRules()); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	// the same crash aborting through a different
+	// system frame must produce the same fingerprint
+	other := newTestNativeCrashEvent().NativeCrash
+	other.Frames = Frames{nativeFrameCrash, nativeFrameCaller}
+	other.SignalCode = "SEGV_ACCERR"
+	other.ComputeNativeCrashFingerprint(DefaultFingerprintRules())
+
+	if crash.Fingerprint != other.Fingerprint {
+		t.Errorf("Expected fingerprints to match, but got %q and %q", crash.Fingerprint, other.Fingerprint)
+	}
+
+	if crash.FingerprintVersion != FingerprintV1 {
+		t.Errorf("Expected fingerprint version %d, but got %d", FingerprintV1, crash.FingerprintVersion)
+	}
+
+	// a different signal is a different crash
+	other.Signal = "SIGABRT"
+	other.ComputeNativeCrashFingerprint(DefaultFingerprintRules())
+
+	if crash.Fingerprint == other.Fingerprint {
+		t.Error("Expected fingerprints to differ across signals")
+	}
+}
+
+func TestNativeCrashFingerprintPrefersSymbols(t *testing.T) {
+	crash := newTestNativeCrashEvent().NativeCrash
+	crash.ComputeNativeCrashFingerprint(DefaultFingerprintRules())
+	unsymbolicated := crash.Fingerprint
+
+	crash.Frames[1].MethodName = "crash"
+	crash.ComputeNativeCrashFingerprint(DefaultFingerprintRules())
+
+	if crash.Fingerprint == unsymbolicated {
+		t.Error("Expected symbolicated frames to change the fingerprint")
+	}
+
+	// a rebuilt library shifts the offsets
+	// but keeps the symbols
+	crash.Frames[2].MethodName = "main"
+	crash.ComputeNativeCrashFingerprint(DefaultFingerprintRules())
+
+	rebuilt := newTestNativeCrashEvent().NativeCrash
+	rebuilt.Frames[1].MethodName = "crash"
+	rebuilt.Frames[1].Offset = "50000"
+	rebuilt.Frames[2].MethodName = "main"
+	rebuilt.Frames[2].Offset = "49848"
+	rebuilt.ComputeNativeCrashFingerprint(DefaultFingerprintRules())
+
+	if crash.Fingerprint != rebuilt.Fingerprint {
+		t.Errorf("Expected fingerprints to match, but got %q and %q", crash.Fingerprint, rebuilt.Fingerprint)
+	}
+}
+
+func TestNativeCrashFingerprintFallsBackToSystemFrames(t *testing.T) {
+	crash := newTestNativeCrashEvent().NativeCrash
+	crash.Frames = Frames{nativeFrameLibc}
+
+	if err := crash.ComputeNativeCrashFingerprint(DefaultFingerprintRules()); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if crash.Fingerprint == "" {
+		t.Error("Expected fingerprint from system frames")
+	}
+
+	crash.Frames = nil
+	if err := crash.ComputeNativeCrashFingerprint(DefaultFingerprintRules()); err == nil {
+		t.Error("Expected error for native crash without frames")
+	}
+}
+
+func TestNativeCrashAsException(t *testing.T) {
+	crash := newTestNativeCrashEvent().NativeCrash
+	crash.Frames[0].MethodName = ""
+
+	exception := crash.AsException()
+
+	if exception.Handled {
+		t.Error("Expected native crash to be unhandled")
+	}
+	if got := exception.GetType(); got != "SIGSEGV" {
+		t.Errorf("Expected type %q, but got %q", "SIGSEGV", got)
+	}
+	if got := exception.GetMessage(); got != "SEGV_MAPERR, fault addr 0x0" {
+		t.Errorf("Expected message %q, but got %q", "SEGV_MAPERR, fault addr 0x0", got)
+	}
+	if got := crash.GetFileName(); got != "libc.so" {
+		t.Errorf("Expected file name %q, but got %q", "libc.so", got)
+	}
+
+	crash.AbortMessage = "assertion failed"
+	if got := crash.AsException().GetMessage(); got != "assertion failed" {
+		t.Errorf("Expected message %q, but got %q", "assertion failed", got)
+	}
+}
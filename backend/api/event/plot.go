@@ -16,3 +16,21 @@ type SessionInstance struct {
 	Version   string  `json:"version"`
 	Instances *uint64 `json:"instances"`
 }
+
+// SpanInstance represents an entity for
+// plotting span duration percentiles.
+type SpanInstance struct {
+	DateTime string   `json:"datetime"`
+	Version  string   `json:"version"`
+	P50      *float64 `json:"p50"`
+	P90      *float64 `json:"p90"`
+	P99      *float64 `json:"p99"`
+}
+
+// CustomInstance represents an entity for
+// plotting custom event instances.
+type CustomInstance struct {
+	DateTime  string  `json:"datetime"`
+	Version   string  `json:"version"`
+	Instances *uint64 `json:"instances"`
+}
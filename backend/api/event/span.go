@@ -0,0 +1,150 @@
+package event
+
+import (
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"time"
+)
+
+// constants defining maximum limits
+// for various span fields.
+const (
+	maxSpanNameChars           = 128
+	maxSpanStatusMessageChars  = 256
+	maxSpanAttributes          = 64
+	maxSpanAttributeKeyChars   = 128
+	maxSpanAttributeValueChars = 1024
+)
+
+const SpanKindInternal = "internal"
+const SpanKindServer = "server"
+const SpanKindClient = "client"
+const SpanKindProducer = "producer"
+const SpanKindConsumer = "consumer"
+
+// SpanStatusUnset, SpanStatusOk & SpanStatusError
+// mirror the status codes of OpenTelemetry spans.
+const (
+	SpanStatusUnset uint8 = iota
+	SpanStatusOk
+	SpanStatusError
+)
+
+// ValidSpanKinds defines allowed
+// span kind values.
+var ValidSpanKinds = []string{
+	SpanKindInternal,
+	SpanKindServer,
+	SpanKindClient,
+	SpanKindProducer,
+	SpanKindConsumer,
+}
+
+// Span represents a timed operation of the
+// app, like loading a screen or querying a
+// database. Spans sharing a trace id form a
+// trace, linked to each other by parent id.
+type Span struct {
+	TraceID       string            `json:"trace_id" binding:"required"`
+	SpanID        string            `json:"span_id" binding:"required"`
+	ParentID      string            `json:"parent_id"`
+	Name          string            `json:"name" binding:"required"`
+	Kind          string            `json:"kind"`
+	Status        uint8             `json:"status"`
+	StatusMessage string            `json:"status_message"`
+	StartTime     time.Time         `json:"start_time" binding:"required"`
+	EndTime       time.Time         `json:"end_time" binding:"required"`
+	Attributes    map[string]string `json:"attributes"`
+}
+
+// Duration computes the span's duration.
+func (s Span) Duration() time.Duration {
+	return s.EndTime.Sub(s.StartTime)
+}
+
+// IsRoot returns true for spans
+// without a parent.
+func (s Span) IsRoot() bool {
+	return s.ParentID == ""
+}
+
+// Validate validates the span for data
+// integrity.
+func (s Span) Validate() error {
+	if !isHexID(s.TraceID, 16) {
+		return fmt.Errorf(`%q must be a 32 character hex string`, `span.trace_id`)
+	}
+
+	if !isHexID(s.SpanID, 8) {
+		return fmt.Errorf(`%q must be a 16 character hex string`, `span.span_id`)
+	}
+
+	if s.ParentID != "" && !isHexID(s.ParentID, 8) {
+		return fmt.Errorf(`%q must be a 16 character hex string`, `span.parent_id`)
+	}
+
+	if len(s.Name) < 1 {
+		return fmt.Errorf(`%q must not be empty`, `span.name`)
+	}
+
+	if len(s.Name) > maxSpanNameChars {
+		return fmt.Errorf(`%q exceeds maximum allowed characters of (%d)`, `span.name`, maxSpanNameChars)
+	}
+
+	if s.Kind != "" && !slices.Contains(ValidSpanKinds, s.Kind) {
+		return fmt.Errorf(`%q contains invalid span kind`, `span.kind`)
+	}
+
+	if s.Status > SpanStatusError {
+		return fmt.Errorf(`%q contains invalid span status`, `span.status`)
+	}
+
+	if len(s.StatusMessage) > maxSpanStatusMessageChars {
+		return fmt.Errorf(`%q exceeds maximum allowed characters of (%d)`, `span.status_message`, maxSpanStatusMessageChars)
+	}
+
+	if s.StartTime.IsZero() || s.EndTime.IsZero() {
+		return fmt.Errorf(`%q and %q must be valid ISO 8601 timestamps`, `span.start_time`, `span.end_time`)
+	}
+
+	if s.EndTime.Before(s.StartTime) {
+		return fmt.Errorf(`%q must not be before %q`, `span.end_time`, `span.start_time`)
+	}
+
+	if len(s.Attributes) > maxSpanAttributes {
+		return fmt.Errorf(`%q exceeds maximum allowed count of (%d)`, `span.attributes`, maxSpanAttributes)
+	}
+
+	for key, value := range s.Attributes {
+		if len(key) > maxSpanAttributeKeyChars {
+			return fmt.Errorf(`%q key %q exceeds maximum allowed characters of (%d)`, `span.attributes`, key, maxSpanAttributeKeyChars)
+		}
+		if len(value) > maxSpanAttributeValueChars {
+			return fmt.Errorf(`%q value of %q exceeds maximum allowed characters of (%d)`, `span.attributes`, key, maxSpanAttributeValueChars)
+		}
+	}
+
+	return nil
+}
+
+// isHexID returns true if the id is a lowercase
+// hex encoding of n non-zero bytes.
+func isHexID(id string, n int) bool {
+	if len(id) != n*2 {
+		return false
+	}
+
+	b, err := hex.DecodeString(id)
+	if err != nil || hex.EncodeToString(b) != id {
+		return false
+	}
+
+	for _, c := range b {
+		if c != 0 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,94 @@
+package event
+
+import (
+	"backend/api/platform"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestSpanValidate(t *testing.T) {
+	start := time.Now()
+	span := Span{
+		TraceID:   "5b8efff798038103d269b633813fc60c",
+		SpanID:    "eee19b7ec3c1b174",
+		Name:      "checkout",
+		Kind:      SpanKindInternal,
+		Status:    SpanStatusOk,
+		StartTime: start,
+		EndTime:   start.Add(time.Second),
+	}
+
+	if err := span.Validate(); err != nil {
+		t.Errorf("Expected nil error, but got %v", err)
+	}
+
+	if !span.IsRoot() {
+		t.Errorf("Expected root span")
+	}
+
+	if span.Duration() != time.Second {
+		t.Errorf("Expected %v duration, but got %v", time.Second, span.Duration())
+	}
+
+	invalid := []func(s *Span){
+		func(s *Span) { s.TraceID = "00000000000000000000000000000000" },
+		func(s *Span) { s.SpanID = "EEE19B7EC3C1B174" },
+		func(s *Span) { s.ParentID = "eee19b" },
+		func(s *Span) { s.Name = "" },
+		func(s *Span) { s.Kind = "unknown" },
+		func(s *Span) { s.Status = 3 },
+		func(s *Span) { s.EndTime = start.Add(-time.Second) },
+	}
+
+	for i, modify := range invalid {
+		s := span
+		modify(&s)
+		if err := s.Validate(); err == nil {
+			t.Errorf("Expected error for invalid span %d, but got nil", i)
+		}
+	}
+}
+
+func TestSpanEventValidate(t *testing.T) {
+	start := time.Now()
+	ev := EventField{
+		ID:        uuid.New(),
+		AppID:     uuid.New(),
+		SessionID: uuid.New(),
+		Timestamp: start,
+		Type:      TypeSpan,
+		Attribute: Attribute{
+			InstallationID:    uuid.New(),
+			AppVersion:        "1.0.0",
+			AppBuild:          "100",
+			AppUniqueID:       "sh.measure.sample",
+			MeasureSDKVersion: "0.7.0",
+			Platform:          platform.Android,
+			NetworkType:       NetworkTypeUnknown,
+			NetworkGeneration: NetworkGenerationUnknown,
+		},
+	}
+
+	if err := ev.Validate(); err == nil {
+		t.Errorf("Expected error for span event without span, but got nil")
+	}
+
+	ev.Span = &Span{
+		TraceID:   "5b8efff798038103d269b633813fc60c",
+		SpanID:    "eee19b7ec3c1b174",
+		ParentID:  "0102030405060708",
+		Name:      "db_query",
+		StartTime: start,
+		EndTime:   start.Add(20 * time.Millisecond),
+	}
+
+	if err := ev.Validate(); err != nil {
+		t.Errorf("Expected nil error, but got %v", err)
+	}
+
+	if !ev.IsSpan() || ev.Span.IsRoot() {
+		t.Errorf("Expected child span event")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"fmt"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -25,6 +26,11 @@ const MaxPaginationLimit = 1000
 // as default for paginating items.
 const DefaultPaginationLimit = 10
 
+// maxUserDefinedAttrValues is the maximum number
+// of distinct values listed for each user defined
+// attribute key.
+const maxUserDefinedAttrValues = 100
+
 // AppFilter represents various app filtering
 // operations and its parameters to query app's
 // issue journey map, metrics, exceptions and
@@ -93,6 +99,10 @@ type AppFilter struct {
 	// to be matched & filtered on.
 	NetworkTypes []string `form:"network_types"`
 
+	// GroupStatuses is the list of crash or ANR
+	// group statuses to be matched & filtered on.
+	GroupStatuses []string `form:"group_statuses"`
+
 	// Exception indicates the filtering should
 	// only consider exception events, both
 	// handled & unhandled.
@@ -106,6 +116,24 @@ type AppFilter struct {
 	// consider ANR events.
 	ANR bool `form:"anr"`
 
+	// SpanName is the name of the span to
+	// be matched & filtered on.
+	SpanName string `form:"span_name"`
+
+	// CustomEventName is the name of the custom
+	// event to be matched & filtered on.
+	CustomEventName string `form:"custom_event_name"`
+
+	// CustomEventProperties is the list of custom
+	// event properties to be matched & filtered on,
+	// each in `key:value` format.
+	CustomEventProperties []string `form:"custom_event_properties"`
+
+	// UserDefinedAttrs is the list of user defined
+	// attributes to be matched & filtered on, each
+	// in `key:value` format.
+	UserDefinedAttrs []string `form:"user_defined_attributes"`
+
 	// KeyID is the anchor point for keyset
 	// pagination.
 	KeyID string `form:"key_id"`
@@ -132,17 +160,25 @@ type AppFilter struct {
 // used in filtering operations of app's issue journey map,
 // metrics, exceptions and ANRs.
 type FilterList struct {
-	Versions            []string `json:"versions"`
-	VersionCodes        []string `json:"version_codes"`
-	OsNames             []string `json:"os_names"`
-	OsVersions          []string `json:"os_versions"`
-	Countries           []string `json:"countries"`
-	NetworkProviders    []string `json:"network_providers"`
-	NetworkTypes        []string `json:"network_types"`
-	NetworkGenerations  []string `json:"network_generations"`
-	DeviceLocales       []string `json:"locales"`
-	DeviceManufacturers []string `json:"device_manufacturers"`
-	DeviceNames         []string `json:"device_names"`
+	Versions            []string          `json:"versions"`
+	VersionCodes        []string          `json:"version_codes"`
+	OsNames             []string          `json:"os_names"`
+	OsVersions          []string          `json:"os_versions"`
+	Countries           []string          `json:"countries"`
+	NetworkProviders    []string          `json:"network_providers"`
+	NetworkTypes        []string          `json:"network_types"`
+	NetworkGenerations  []string          `json:"network_generations"`
+	DeviceLocales       []string          `json:"locales"`
+	DeviceManufacturers []string          `json:"device_manufacturers"`
+	DeviceNames         []string          `json:"device_names"`
+	UserDefinedAttrs    []UserDefinedAttr `json:"user_defined_attributes"`
+}
+
+// UserDefinedAttr represents a user defined
+// attribute key & its distinct values.
+type UserDefinedAttr struct {
+	Key    string   `json:"key"`
+	Values []string `json:"values"`
 }
 
 // Versions represents a list of
@@ -185,6 +221,22 @@ func (af *AppFilter) Validate() error {
 		return fmt.Errorf("`limit` cannot be more than %d", MaxPaginationLimit)
 	}
 
+	// custom event validations
+	for _, prop := range af.CustomEventProperties {
+		key, _, found := strings.Cut(prop, ":")
+		if !found || strings.TrimSpace(key) == "" {
+			return fmt.Errorf("`custom_event_properties` must be in `key:value` format")
+		}
+	}
+
+	// user defined attribute validations
+	for _, attr := range af.UserDefinedAttrs {
+		key, _, found := strings.Cut(attr, ":")
+		if !found || strings.TrimSpace(key) == "" {
+			return fmt.Errorf("`user_defined_attributes` must be in `key:value` format")
+		}
+	}
+
 	return nil
 }
 
@@ -250,6 +302,18 @@ func (af *AppFilter) Expand() {
 	if len(af.NetworkGenerations) > 0 {
 		af.NetworkGenerations = text.SplitTrimEmpty(af.NetworkGenerations[0], ",")
 	}
+
+	if len(af.GroupStatuses) > 0 {
+		af.GroupStatuses = text.SplitTrimEmpty(af.GroupStatuses[0], ",")
+	}
+
+	if len(af.CustomEventProperties) > 0 {
+		af.CustomEventProperties = text.SplitTrimEmpty(af.CustomEventProperties[0], ",")
+	}
+
+	if len(af.UserDefinedAttrs) > 0 {
+		af.UserDefinedAttrs = text.SplitTrimEmpty(af.UserDefinedAttrs[0], ",")
+	}
 }
 
 // HasTimeRange checks if the time values are
@@ -258,6 +322,36 @@ func (af *AppFilter) HasTimeRange() bool {
 	return !af.From.IsZero() && !af.To.IsZero()
 }
 
+// HasCustomEvent checks if custom event name
+// or properties were requested.
+func (af *AppFilter) HasCustomEvent() bool {
+	return af.CustomEventName != "" || len(af.CustomEventProperties) > 0
+}
+
+// CustomEventPropertyPairs provides the keys and
+// values of the requested custom event properties
+// in the requested order.
+func (af *AppFilter) CustomEventPropertyPairs() (keys, values []string) {
+	for _, prop := range af.CustomEventProperties {
+		key, value, _ := strings.Cut(prop, ":")
+		keys = append(keys, strings.TrimSpace(key))
+		values = append(values, strings.TrimSpace(value))
+	}
+	return
+}
+
+// UserDefinedAttrPairs provides the keys and
+// values of the requested user defined attributes
+// in the requested order.
+func (af *AppFilter) UserDefinedAttrPairs() (keys, values []string) {
+	for _, attr := range af.UserDefinedAttrs {
+		key, value, _ := strings.Cut(attr, ":")
+		keys = append(keys, strings.TrimSpace(key))
+		values = append(values, strings.TrimSpace(value))
+	}
+	return
+}
+
 // HasKeyset checks if key id and key timestamp
 // values are present and valid.
 func (af *AppFilter) HasKeyset() bool {
@@ -365,6 +459,12 @@ func (af *AppFilter) GetGenericFilters(ctx context.Context, fl *FilterList) erro
 	}
 	fl.DeviceNames = append(fl.DeviceNames, deviceNames...)
 
+	userDefinedAttrs, err := af.getUserDefinedAttrs(ctx)
+	if err != nil {
+		return err
+	}
+	fl.UserDefinedAttrs = append(fl.UserDefinedAttrs, userDefinedAttrs...)
+
 	return nil
 }
 
@@ -776,6 +876,57 @@ func (af *AppFilter) getDeviceNames(ctx context.Context) (deviceNames []string,
 	return
 }
 
+// getUserDefinedAttrs finds distinct user defined
+// attribute keys & their distinct values from
+// available events.
+func (af *AppFilter) getUserDefinedAttrs(ctx context.Context) (attrs []UserDefinedAttr, err error) {
+	base := sqlf.
+		From("default.events").
+		Select("arrayJoin(mapKeys(user_defined_attribute)) as key").
+		Select("user_defined_attribute[key] as value").
+		Where("app_id = toUUID(?)", af.AppID)
+
+	if af.Exception {
+		base.Where("type = 'exception'")
+	}
+
+	if af.Crash {
+		base.Where("type = 'exception'")
+		base.Where("`exception.handled` = false")
+	}
+
+	if af.ANR {
+		base.Where("type = 'anr'")
+	}
+
+	stmt := sqlf.
+		With("user_defined_attrs", base).
+		From("user_defined_attrs").
+		Select("key").
+		Select(fmt.Sprintf("arraySort(groupUniqArray(%d)(value))", maxUserDefinedAttrValues)).
+		GroupBy("key").
+		OrderBy("key")
+
+	defer stmt.Close()
+
+	rows, err := server.Server.ChPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	for rows.Next() {
+		var attr UserDefinedAttr
+		if err = rows.Scan(&attr.Key, &attr.Values); err != nil {
+			return
+		}
+		attrs = append(attrs, attr)
+	}
+
+	err = rows.Err()
+
+	return
+}
+
 // GetExcludedVersions computes list of app version
 // and version codes that are excluded from app filter.
 func (af *AppFilter) GetExcludedVersions(ctx context.Context) (versions Versions, err error) {
@@ -16,39 +16,45 @@ import (
 )
 
 type ExceptionGroup struct {
-	ID              uuid.UUID              `json:"id" db:"id"`
-	AppID           uuid.UUID              `json:"app_id" db:"app_id"`
-	Type            string                 `json:"type" db:"type"`
-	Message         string                 `json:"message" db:"message"`
-	MethodName      string                 `json:"method_name" db:"method_name"`
-	FileName        string                 `json:"file_name" db:"file_name"`
-	LineNumber      int                    `json:"line_number" db:"line_number"`
-	Fingerprint     string                 `json:"fingerprint" db:"fingerprint"`
-	Count           int                    `json:"count"`
-	EventIDs        []uuid.UUID            `json:"event_ids,omitempty"`
-	EventExceptions []event.EventException `json:"exception_events,omitempty"`
-	Percentage      float32                `json:"percentage_contribution"`
-	FirstEventTime  time.Time              `json:"-" db:"first_event_timestamp"`
-	CreatedAt       chrono.ISOTime         `json:"created_at" db:"created_at"`
-	UpdatedAt       chrono.ISOTime         `json:"updated_at" db:"updated_at"`
+	ID                 uuid.UUID              `json:"id" db:"id"`
+	AppID              uuid.UUID              `json:"app_id" db:"app_id"`
+	Type               string                 `json:"type" db:"type"`
+	Message            string                 `json:"message" db:"message"`
+	MethodName         string                 `json:"method_name" db:"method_name"`
+	FileName           string                 `json:"file_name" db:"file_name"`
+	LineNumber         int                    `json:"line_number" db:"line_number"`
+	Fingerprint        string                 `json:"fingerprint" db:"fingerprint"`
+	FingerprintVersion int                    `json:"fingerprint_version" db:"fingerprint_version"`
+	Count              int                    `json:"count"`
+	EventIDs           []uuid.UUID            `json:"event_ids,omitempty"`
+	EventExceptions    []event.EventException `json:"exception_events,omitempty"`
+	Percentage         float32                `json:"percentage_contribution"`
+	FirstEventTime     time.Time              `json:"-" db:"first_event_timestamp"`
+	CreatedAt          chrono.ISOTime         `json:"created_at" db:"created_at"`
+	UpdatedAt          chrono.ISOTime         `json:"updated_at" db:"updated_at"`
+	Lifecycle
+	Merging
 }
 
 type ANRGroup struct {
-	ID             uuid.UUID        `json:"id" db:"id"`
-	AppID          uuid.UUID        `json:"app_id" db:"app_id"`
-	Type           string           `json:"type" db:"type"`
-	Message        string           `json:"message" db:"message"`
-	MethodName     string           `json:"method_name" db:"method_name"`
-	FileName       string           `json:"file_name" db:"file_name"`
-	LineNumber     int              `json:"line_number" db:"line_number"`
-	Fingerprint    string           `json:"fingerprint" db:"fingerprint"`
-	Count          int              `json:"count"`
-	EventIDs       []uuid.UUID      `json:"event_ids,omitempty"`
-	EventANRs      []event.EventANR `json:"anr_events,omitempty"`
-	Percentage     float32          `json:"percentage_contribution"`
-	FirstEventTime time.Time        `json:"-" db:"first_event_timestamp"`
-	CreatedAt      chrono.ISOTime   `json:"created_at" db:"created_at"`
-	UpdatedAt      chrono.ISOTime   `json:"updated_at" db:"updated_at"`
+	ID                 uuid.UUID        `json:"id" db:"id"`
+	AppID              uuid.UUID        `json:"app_id" db:"app_id"`
+	Type               string           `json:"type" db:"type"`
+	Message            string           `json:"message" db:"message"`
+	MethodName         string           `json:"method_name" db:"method_name"`
+	FileName           string           `json:"file_name" db:"file_name"`
+	LineNumber         int              `json:"line_number" db:"line_number"`
+	Fingerprint        string           `json:"fingerprint" db:"fingerprint"`
+	FingerprintVersion int              `json:"fingerprint_version" db:"fingerprint_version"`
+	Count              int              `json:"count"`
+	EventIDs           []uuid.UUID      `json:"event_ids,omitempty"`
+	EventANRs          []event.EventANR `json:"anr_events,omitempty"`
+	Percentage         float32          `json:"percentage_contribution"`
+	FirstEventTime     time.Time        `json:"-" db:"first_event_timestamp"`
+	CreatedAt          chrono.ISOTime   `json:"created_at" db:"created_at"`
+	UpdatedAt          chrono.ISOTime   `json:"updated_at" db:"updated_at"`
+	Lifecycle
+	Merging
 }
 
 func (e ExceptionGroup) GetID() uuid.UUID {
@@ -102,6 +108,8 @@ func (e *ExceptionGroup) Insert(ctx context.Context, tx *pgx.Tx) (err error) {
 		return
 	}
 
+	e.ID = id
+
 	stmt := sqlf.PostgreSQL.
 		InsertInto("public.unhandled_exception_groups").
 		Set("id", id).
@@ -112,6 +120,7 @@ func (e *ExceptionGroup) Insert(ctx context.Context, tx *pgx.Tx) (err error) {
 		Set("file_name", e.FileName).
 		Set("line_number", e.LineNumber).
 		Set("fingerprint", e.Fingerprint).
+		Set("fingerprint_version", e.FingerprintVersion).
 		Set("first_event_timestamp", e.FirstEventTime)
 
 	defer stmt.Close()
@@ -177,6 +186,8 @@ func (a *ANRGroup) Insert(ctx context.Context, tx *pgx.Tx) (err error) {
 		return err
 	}
 
+	a.ID = id
+
 	stmt := sqlf.PostgreSQL.
 		InsertInto("public.anr_groups").
 		Set("id", id).
@@ -187,6 +198,7 @@ func (a *ANRGroup) Insert(ctx context.Context, tx *pgx.Tx) (err error) {
 		Set("file_name", a.FileName).
 		Set("line_number", a.LineNumber).
 		Set("fingerprint", a.Fingerprint).
+		Set("fingerprint_version", a.FingerprintVersion).
 		Set("first_event_timestamp", a.FirstEventTime)
 
 	defer stmt.Close()
@@ -258,7 +270,7 @@ func SortANRGroups(groups []ANRGroup) {
 func GetExceptionGroupsFromExceptionIds(ctx context.Context, eventIds []uuid.UUID) (exceptionGroups []ExceptionGroup, err error) {
 	// Get list of fingerprints and event IDs
 	eventDataStmt := sqlf.From(`default.events`).
-		Select(`id, exception.fingerprint`).
+		Select(`id, if(type = 'native_crash', native_crash.fingerprint, exception.fingerprint)`).
 		Where(`id in (?)`, eventIds)
 
 	eventDataRows, err := server.Server.ChPool.Query(ctx, eventDataStmt.String(), eventDataStmt.Args()...)
@@ -298,12 +310,13 @@ func GetExceptionGroupsFromExceptionIds(ctx context.Context, eventIds []uuid.UUI
 		Select(`file_name`).
 		Select(`line_number`).
 		Select(`fingerprint`).
+		Select(`merged_into`).
 		Where(`fingerprint = ANY(?)`, fingerprints)
 
 	defer stmt.Close()
 
 	rows, _ := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
-	exceptionGroups, err = pgx.CollectRows(rows, pgx.RowToStructByNameLax[ExceptionGroup])
+	matchedGroups, err := pgx.CollectRows(rows, pgx.RowToStructByNameLax[ExceptionGroup])
 
 	if err != nil {
 		return nil, err
@@ -312,10 +325,60 @@ func GetExceptionGroupsFromExceptionIds(ctx context.Context, eventIds []uuid.UUI
 		return nil, rows.Err()
 	}
 
+	// Query surviving groups of merged groups
+	var survivorIds []uuid.UUID
+	for i := range matchedGroups {
+		if matchedGroups[i].IsMerged() {
+			survivorIds = append(survivorIds, *matchedGroups[i].MergedInto)
+		}
+	}
+
+	var survivors []ExceptionGroup
+	if len(survivorIds) > 0 {
+		survivorStmt := sqlf.PostgreSQL.
+			From(`public.unhandled_exception_groups`).
+			Select(`id`).
+			Select(`type`).
+			Select(`message`).
+			Select(`method_name`).
+			Select(`file_name`).
+			Select(`line_number`).
+			Select(`fingerprint`).
+			Where(`id = ANY(?)`, survivorIds)
+
+		defer survivorStmt.Close()
+
+		survivorRows, _ := server.Server.PgPool.Query(ctx, survivorStmt.String(), survivorStmt.Args()...)
+		survivors, err = pgx.CollectRows(survivorRows, pgx.RowToStructByNameLax[ExceptionGroup])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Merged groups are represented
+	// by their surviving groups
+	idToIndex := make(map[uuid.UUID]int)
+	for _, g := range append(matchedGroups, survivors...) {
+		if g.IsMerged() {
+			continue
+		}
+		if _, ok := idToIndex[g.ID]; ok {
+			continue
+		}
+		idToIndex[g.ID] = len(exceptionGroups)
+		exceptionGroups = append(exceptionGroups, g)
+	}
+
 	// Add event ids to obtained exception groups
 	fingerprintToGroup := make(map[string]*ExceptionGroup)
-	for i := range exceptionGroups {
-		fingerprintToGroup[exceptionGroups[i].Fingerprint] = &exceptionGroups[i]
+	for _, g := range matchedGroups {
+		id := g.ID
+		if g.IsMerged() {
+			id = *g.MergedInto
+		}
+		if i, ok := idToIndex[id]; ok {
+			fingerprintToGroup[g.Fingerprint] = &exceptionGroups[i]
+		}
 	}
 
 	for eventID, fingerprint := range eventIdToFingerprint {
@@ -373,12 +436,13 @@ func GetANRGroupsFromANRIds(ctx context.Context, eventIds []uuid.UUID) (anrGroup
 		Select(`file_name`).
 		Select(`line_number`).
 		Select(`fingerprint`).
+		Select(`merged_into`).
 		Where(`fingerprint = ANY(?)`, fingerprints)
 
 	defer stmt.Close()
 
 	rows, _ := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
-	anrGroups, err = pgx.CollectRows(rows, pgx.RowToStructByNameLax[ANRGroup])
+	matchedGroups, err := pgx.CollectRows(rows, pgx.RowToStructByNameLax[ANRGroup])
 
 	if err != nil {
 		return nil, err
@@ -387,10 +451,60 @@ func GetANRGroupsFromANRIds(ctx context.Context, eventIds []uuid.UUID) (anrGroup
 		return nil, rows.Err()
 	}
 
+	// Query surviving groups of merged groups
+	var survivorIds []uuid.UUID
+	for i := range matchedGroups {
+		if matchedGroups[i].IsMerged() {
+			survivorIds = append(survivorIds, *matchedGroups[i].MergedInto)
+		}
+	}
+
+	var survivors []ANRGroup
+	if len(survivorIds) > 0 {
+		survivorStmt := sqlf.PostgreSQL.
+			From(`public.anr_groups`).
+			Select(`id`).
+			Select(`type`).
+			Select(`message`).
+			Select(`method_name`).
+			Select(`file_name`).
+			Select(`line_number`).
+			Select(`fingerprint`).
+			Where(`id = ANY(?)`, survivorIds)
+
+		defer survivorStmt.Close()
+
+		survivorRows, _ := server.Server.PgPool.Query(ctx, survivorStmt.String(), survivorStmt.Args()...)
+		survivors, err = pgx.CollectRows(survivorRows, pgx.RowToStructByNameLax[ANRGroup])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Merged groups are represented
+	// by their surviving groups
+	idToIndex := make(map[uuid.UUID]int)
+	for _, g := range append(matchedGroups, survivors...) {
+		if g.IsMerged() {
+			continue
+		}
+		if _, ok := idToIndex[g.ID]; ok {
+			continue
+		}
+		idToIndex[g.ID] = len(anrGroups)
+		anrGroups = append(anrGroups, g)
+	}
+
 	// Add event ids to obtained ANR groups
 	fingerprintToGroup := make(map[string]*ANRGroup)
-	for i := range anrGroups {
-		fingerprintToGroup[anrGroups[i].Fingerprint] = &anrGroups[i]
+	for _, g := range matchedGroups {
+		id := g.ID
+		if g.IsMerged() {
+			id = *g.MergedInto
+		}
+		if i, ok := idToIndex[id]; ok {
+			fingerprintToGroup[g.Fingerprint] = &anrGroups[i]
+		}
 	}
 
 	for eventID, fingerprint := range eventIdToFingerprint {
@@ -403,29 +517,37 @@ func GetANRGroupsFromANRIds(ctx context.Context, eventIds []uuid.UUID) (anrGroup
 }
 
 // NewExceptionGroup constructs a new ExceptionGroup and returns a pointer to it.
-func NewExceptionGroup(appId uuid.UUID, exceptionType, message, methodName, fileName string, lineNumber int, fingerprint string, firstTime time.Time) *ExceptionGroup {
+func NewExceptionGroup(appId uuid.UUID, exceptionType, message, methodName, fileName string, lineNumber int, fingerprint string, fingerprintVersion int, firstTime time.Time) *ExceptionGroup {
 	return &ExceptionGroup{
-		AppID:          appId,
-		Type:           exceptionType,
-		Message:        message,
-		MethodName:     methodName,
-		FileName:       fileName,
-		LineNumber:     lineNumber,
-		Fingerprint:    fingerprint,
-		FirstEventTime: firstTime,
+		AppID:              appId,
+		Type:               exceptionType,
+		Message:            message,
+		MethodName:         methodName,
+		FileName:           fileName,
+		LineNumber:         lineNumber,
+		Fingerprint:        fingerprint,
+		FingerprintVersion: fingerprintVersion,
+		FirstEventTime:     firstTime,
+		Lifecycle: Lifecycle{
+			Status: StatusOpen,
+		},
 	}
 }
 
 // NewANRGroup constructs a new ANRGroup and returns a pointer to it.
-func NewANRGroup(appId uuid.UUID, anrType, message, methodName, fileName string, lineNumber int, fingerprint string, firstTime time.Time) *ANRGroup {
+func NewANRGroup(appId uuid.UUID, anrType, message, methodName, fileName string, lineNumber int, fingerprint string, fingerprintVersion int, firstTime time.Time) *ANRGroup {
 	return &ANRGroup{
-		AppID:          appId,
-		Type:           anrType,
-		Message:        message,
-		MethodName:     methodName,
-		FileName:       fileName,
-		LineNumber:     lineNumber,
-		Fingerprint:    fingerprint,
-		FirstEventTime: firstTime,
+		AppID:              appId,
+		Type:               anrType,
+		Message:            message,
+		MethodName:         methodName,
+		FileName:           fileName,
+		LineNumber:         lineNumber,
+		Fingerprint:        fingerprint,
+		FingerprintVersion: fingerprintVersion,
+		FirstEventTime:     firstTime,
+		Lifecycle: Lifecycle{
+			Status: StatusOpen,
+		},
 	}
 }
@@ -0,0 +1,324 @@
+package group
+
+import (
+	"backend/api/server"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/leporo/sqlf"
+)
+
+// StatusOpen represents a group that
+// needs attention.
+const StatusOpen = "open"
+
+// StatusResolved represents a group that was
+// fixed, optionally in a specific version.
+const StatusResolved = "resolved"
+
+// StatusIgnored represents a group that
+// should not be acted upon.
+const StatusIgnored = "ignored"
+
+// StatusMuted represents a group that is
+// silenced until a point in time.
+const StatusMuted = "muted"
+
+// ValidStatuses defines the statuses
+// a group can be in.
+var ValidStatuses = []string{
+	StatusOpen,
+	StatusResolved,
+	StatusIgnored,
+	StatusMuted,
+}
+
+// StatusExpr computes the effective status of a group.
+// Muted groups whose mute period has lapsed are open.
+const StatusExpr = `case when status = 'muted' and muted_until <= now() then 'open' else status end`
+
+// Lifecycle represents the triage state
+// of a crash or ANR group.
+type Lifecycle struct {
+	Status                string     `json:"status" db:"status"`
+	ResolvedInVersion     *string    `json:"resolved_in_version" db:"resolved_in_version"`
+	ResolvedInVersionCode *string    `json:"resolved_in_version_code" db:"resolved_in_version_code"`
+	MutedUntil            *time.Time `json:"muted_until" db:"muted_until"`
+	RegressedAt           *time.Time `json:"regressed_at" db:"regressed_at"`
+}
+
+// IsResolved returns true if the group
+// is resolved.
+func (l Lifecycle) IsResolved() bool {
+	return l.Status == StatusResolved
+}
+
+// IsSilenced returns true if the group is ignored
+// or muted and notifications should be skipped.
+func (l Lifecycle) IsSilenced() bool {
+	if l.Status == StatusIgnored {
+		return true
+	}
+
+	return l.Status == StatusMuted && l.MutedUntil != nil && l.MutedUntil.After(time.Now())
+}
+
+// Regresses returns true if an event of the given app
+// version should reopen the resolved group. Groups
+// resolved without a version regress on any event,
+// otherwise only events from newer versions regress.
+func (l Lifecycle) Regresses(version, versionCode string) bool {
+	if !l.IsResolved() {
+		return false
+	}
+
+	hasVersion := l.ResolvedInVersion != nil && *l.ResolvedInVersion != ""
+	hasVersionCode := l.ResolvedInVersionCode != nil && *l.ResolvedInVersionCode != ""
+
+	if !hasVersion && !hasVersionCode {
+		return true
+	}
+
+	// prefer version codes as they are
+	// monotonically increasing
+	if hasVersionCode && versionCode != "" {
+		if cmp, ok := compareVersionCodes(versionCode, *l.ResolvedInVersionCode); ok {
+			return cmp > 0
+		}
+	}
+
+	if hasVersion && version != "" {
+		return CompareVersions(version, *l.ResolvedInVersion) > 0
+	}
+
+	return false
+}
+
+// StatusUpdate represents a change in
+// the status of a group.
+type StatusUpdate struct {
+	Status                string     `json:"status" binding:"required"`
+	ResolvedInVersion     string     `json:"resolved_in_version"`
+	ResolvedInVersionCode string     `json:"resolved_in_version_code"`
+	MutedUntil            *time.Time `json:"muted_until"`
+}
+
+// Validate validates the status update.
+func (u StatusUpdate) Validate() error {
+	if !slices.Contains(ValidStatuses, u.Status) {
+		return fmt.Errorf(`"status" must be one of %s`, strings.Join(ValidStatuses, ", "))
+	}
+
+	if u.Status != StatusResolved && (u.ResolvedInVersion != "" || u.ResolvedInVersionCode != "") {
+		return fmt.Errorf(`"resolved_in_version" and "resolved_in_version_code" are only allowed with %q status`, StatusResolved)
+	}
+
+	if u.Status == StatusMuted {
+		if u.MutedUntil == nil {
+			return fmt.Errorf(`"muted_until" is required with %q status`, StatusMuted)
+		}
+		if !u.MutedUntil.After(time.Now()) {
+			return errors.New(`"muted_until" must be in the future`)
+		}
+	} else if u.MutedUntil != nil {
+		return fmt.Errorf(`"muted_until" is only allowed with %q status`, StatusMuted)
+	}
+
+	return nil
+}
+
+// nullable returns nil for empty strings.
+func nullable(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// updateStatus writes the status update of
+// a group to the table.
+func updateStatus(ctx context.Context, table string, appId, groupId uuid.UUID, u StatusUpdate) (err error) {
+	stmt := sqlf.PostgreSQL.
+		Update(table).
+		Set("status", u.Status).
+		Set("resolved_in_version", nullable(u.ResolvedInVersion)).
+		Set("resolved_in_version_code", nullable(u.ResolvedInVersionCode)).
+		Set("muted_until", u.MutedUntil).
+		Set("updated_at", time.Now()).
+		Where("id = ?", groupId).
+		Where("app_id = ?", appId)
+
+	defer stmt.Close()
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// reopen marks a resolved group of the
+// table as open due to a regression.
+func reopen(ctx context.Context, table string, groupId uuid.UUID, tx *pgx.Tx) (regressedAt time.Time, err error) {
+	regressedAt = time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		Update(table).
+		Set("status", StatusOpen).
+		Set("resolved_in_version", nil).
+		Set("resolved_in_version_code", nil).
+		Set("regressed_at", regressedAt).
+		Where("id = ?", groupId)
+
+	defer stmt.Close()
+
+	if tx != nil {
+		_, err = (*tx).Exec(ctx, stmt.String(), stmt.Args()...)
+		return
+	}
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// apply applies the status update to
+// the lifecycle.
+func (l *Lifecycle) apply(u StatusUpdate) {
+	l.Status = u.Status
+	l.ResolvedInVersion = nullable(u.ResolvedInVersion)
+	l.ResolvedInVersionCode = nullable(u.ResolvedInVersionCode)
+	l.MutedUntil = u.MutedUntil
+}
+
+// markRegressed reflects a regression
+// in the lifecycle.
+func (l *Lifecycle) markRegressed(at time.Time) {
+	l.Status = StatusOpen
+	l.ResolvedInVersion = nil
+	l.ResolvedInVersionCode = nil
+	l.RegressedAt = &at
+}
+
+// UpdateStatus updates the status of
+// the ExceptionGroup.
+func (e *ExceptionGroup) UpdateStatus(ctx context.Context, u StatusUpdate) (err error) {
+	if err = updateStatus(ctx, "public.unhandled_exception_groups", e.AppID, e.ID, u); err != nil {
+		return
+	}
+
+	e.apply(u)
+
+	return
+}
+
+// Reopen reopens the resolved ExceptionGroup
+// on regression.
+func (e *ExceptionGroup) Reopen(ctx context.Context, tx *pgx.Tx) (err error) {
+	at, err := reopen(ctx, "public.unhandled_exception_groups", e.ID, tx)
+	if err != nil {
+		return
+	}
+
+	e.markRegressed(at)
+
+	return
+}
+
+// UpdateStatus updates the status of
+// the ANRGroup.
+func (a *ANRGroup) UpdateStatus(ctx context.Context, u StatusUpdate) (err error) {
+	if err = updateStatus(ctx, "public.anr_groups", a.AppID, a.ID, u); err != nil {
+		return
+	}
+
+	a.apply(u)
+
+	return
+}
+
+// Reopen reopens the resolved ANRGroup
+// on regression.
+func (a *ANRGroup) Reopen(ctx context.Context, tx *pgx.Tx) (err error) {
+	at, err := reopen(ctx, "public.anr_groups", a.ID, tx)
+	if err != nil {
+		return
+	}
+
+	a.markRegressed(at)
+
+	return
+}
+
+// compareVersionCodes compares two numeric version
+// codes. Returns false if either is not numeric.
+func compareVersionCodes(a, b string) (cmp int, ok bool) {
+	x, err := strconv.ParseInt(strings.TrimSpace(a), 10, 64)
+	if err != nil {
+		return
+	}
+
+	y, err := strconv.ParseInt(strings.TrimSpace(b), 10, 64)
+	if err != nil {
+		return
+	}
+
+	ok = true
+
+	switch {
+	case x > y:
+		cmp = 1
+	case x < y:
+		cmp = -1
+	}
+
+	return
+}
+
+// CompareVersions compares two dotted version names
+// like "2.3.0" segment by segment. Numeric segments are
+// compared numerically, others lexically. Missing
+// segments are treated as zero. Returns 1 if a is
+// newer, -1 if b is newer and 0 if equal.
+func CompareVersions(a, b string) int {
+	// ignore build metadata & pre-release
+	// suffixes like "2.3.0-beta+42"
+	trim := func(v string) string {
+		v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+		if i := strings.IndexAny(v, "-+ "); i >= 0 {
+			v = v[:i]
+		}
+		return v
+	}
+
+	as := strings.Split(trim(a), ".")
+	bs := strings.Split(trim(b), ".")
+
+	for i := 0; i < max(len(as), len(bs)); i++ {
+		x, y := "0", "0"
+		if i < len(as) && as[i] != "" {
+			x = as[i]
+		}
+		if i < len(bs) && bs[i] != "" {
+			y = bs[i]
+		}
+
+		if cmp, ok := compareVersionCodes(x, y); ok {
+			if cmp != 0 {
+				return cmp
+			}
+			continue
+		}
+
+		if c := strings.Compare(x, y); c != 0 {
+			return c
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,113 @@
+package group
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompareVersions(t *testing.T) {
+	cases := []struct {
+		a, b     string
+		expected int
+	}{
+		{"2.3.0", "2.3.0", 0},
+		{"2.3.1", "2.3.0", 1},
+		{"2.3.0", "2.10.0", -1},
+		{"2.3", "2.3.0", 0},
+		{"2.3.0.1", "2.3", 1},
+		{"v2.4.0", "2.3.9", 1},
+		{"2.3.0-beta", "2.3.0", 0},
+		{"2.3.0b", "2.3.0a", 1},
+	}
+
+	for _, c := range cases {
+		if got := CompareVersions(c.a, c.b); got != c.expected {
+			t.Errorf("Expected %d comparing %q with %q, but got %d", c.expected, c.a, c.b, got)
+		}
+	}
+}
+
+func TestRegresses(t *testing.T) {
+	version := "2.3.0"
+	versionCode := "230"
+	empty := ""
+
+	cases := []struct {
+		name        string
+		lifecycle   Lifecycle
+		version     string
+		versionCode string
+		expected    bool
+	}{
+		{"open group", Lifecycle{Status: StatusOpen}, "2.4.0", "240", false},
+		{"ignored group", Lifecycle{Status: StatusIgnored}, "2.4.0", "240", false},
+		{"resolved without version", Lifecycle{Status: StatusResolved}, "1.0.0", "100", true},
+		{"resolved with empty version", Lifecycle{Status: StatusResolved, ResolvedInVersion: &empty}, "1.0.0", "100", true},
+		{"newer version code", Lifecycle{Status: StatusResolved, ResolvedInVersion: &version, ResolvedInVersionCode: &versionCode}, "2.3.0", "231", true},
+		{"same version code", Lifecycle{Status: StatusResolved, ResolvedInVersion: &version, ResolvedInVersionCode: &versionCode}, "2.3.0", "230", false},
+		{"older version code", Lifecycle{Status: StatusResolved, ResolvedInVersion: &version, ResolvedInVersionCode: &versionCode}, "2.2.0", "220", false},
+		{"newer version name", Lifecycle{Status: StatusResolved, ResolvedInVersion: &version}, "2.3.1", "", true},
+		{"older version name", Lifecycle{Status: StatusResolved, ResolvedInVersion: &version}, "2.2.9", "", false},
+	}
+
+	for _, c := range cases {
+		if got := c.lifecycle.Regresses(c.version, c.versionCode); got != c.expected {
+			t.Errorf("%s: expected %v, but got %v", c.name, c.expected, got)
+		}
+	}
+}
+
+func TestIsSilenced(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	cases := []struct {
+		lifecycle Lifecycle
+		expected  bool
+	}{
+		{Lifecycle{Status: StatusOpen}, false},
+		{Lifecycle{Status: StatusResolved}, false},
+		{Lifecycle{Status: StatusIgnored}, true},
+		{Lifecycle{Status: StatusMuted, MutedUntil: &future}, true},
+		{Lifecycle{Status: StatusMuted, MutedUntil: &past}, false},
+	}
+
+	for _, c := range cases {
+		if got := c.lifecycle.IsSilenced(); got != c.expected {
+			t.Errorf("Expected %v for %+v, but got %v", c.expected, c.lifecycle, got)
+		}
+	}
+}
+
+func TestStatusUpdateValidate(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	valid := []StatusUpdate{
+		{Status: StatusOpen},
+		{Status: StatusIgnored},
+		{Status: StatusResolved},
+		{Status: StatusResolved, ResolvedInVersion: "2.3.0", ResolvedInVersionCode: "230"},
+		{Status: StatusMuted, MutedUntil: &future},
+	}
+
+	for _, u := range valid {
+		if err := u.Validate(); err != nil {
+			t.Errorf("Expected nil error for %+v, but got %v", u, err)
+		}
+	}
+
+	invalid := []StatusUpdate{
+		{Status: "closed"},
+		{Status: StatusOpen, ResolvedInVersion: "2.3.0"},
+		{Status: StatusMuted},
+		{Status: StatusMuted, MutedUntil: &past},
+		{Status: StatusIgnored, MutedUntil: &future},
+	}
+
+	for _, u := range invalid {
+		if err := u.Validate(); err == nil {
+			t.Errorf("Expected error for %+v, but got nil", u)
+		}
+	}
+}
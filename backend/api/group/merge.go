@@ -0,0 +1,299 @@
+package group
+
+import (
+	"backend/api/server"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/leporo/sqlf"
+)
+
+// MaxMergeGroups is the maximum number of groups
+// that can be merged in a single request.
+const MaxMergeGroups = 50
+
+// ErrMergeGroupsNotFound is returned when one or more
+// groups to merge do not exist in the app.
+var ErrMergeGroupsNotFound = errors.New("one or more groups were not found")
+
+// Merging represents the merge state
+// of a crash or ANR group.
+type Merging struct {
+	// MergedInto is the id of the group
+	// this group was merged into.
+	MergedInto *uuid.UUID `json:"merged_into" db:"merged_into"`
+
+	// Fingerprints is the set of fingerprints
+	// whose events belong to the group.
+	Fingerprints []string `json:"fingerprints,omitempty" db:"-"`
+
+	// MergedGroupIDs is the list of groups
+	// merged into the group.
+	MergedGroupIDs []uuid.UUID `json:"merged_group_ids,omitempty" db:"-"`
+}
+
+// IsMerged returns true if the group was
+// merged into another group.
+func (m Merging) IsMerged() bool {
+	return m.MergedInto != nil
+}
+
+// MergeRequest represents a request to merge
+// or unmerge groups.
+type MergeRequest struct {
+	GroupIDs []uuid.UUID `json:"group_ids"`
+}
+
+// Validate validates the merge request against
+// the id of the surviving group. Empty requests
+// are allowed only when unmerging.
+func (r MergeRequest) Validate(survivorId uuid.UUID, allowEmpty bool) error {
+	if len(r.GroupIDs) == 0 {
+		if allowEmpty {
+			return nil
+		}
+		return errors.New(`"group_ids" must contain at least one group id`)
+	}
+
+	if len(r.GroupIDs) > MaxMergeGroups {
+		return fmt.Errorf(`"group_ids" cannot contain more than %d group ids`, MaxMergeGroups)
+	}
+
+	seen := make(map[uuid.UUID]struct{}, len(r.GroupIDs))
+	for _, id := range r.GroupIDs {
+		if id == survivorId {
+			return errors.New(`"group_ids" cannot contain the group being merged into`)
+		}
+		if _, ok := seen[id]; ok {
+			return fmt.Errorf(`"group_ids" contains duplicate group id %q`, id)
+		}
+		seen[id] = struct{}{}
+	}
+
+	return nil
+}
+
+// mergeGroups points the groups of the table to the
+// surviving group in a single transaction.
+func mergeGroups(ctx context.Context, table string, appId, survivorId uuid.UUID, groupIds []uuid.UUID) (err error) {
+	tx, err := server.Server.PgPool.Begin(ctx)
+	if err != nil {
+		return
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err = repointGroups(ctx, &tx, table, appId, survivorId, groupIds); err != nil {
+		return
+	}
+
+	return tx.Commit(ctx)
+}
+
+// repointGroups points the groups of the table to the
+// surviving group. Groups previously merged into the
+// merged groups are moved to the surviving group
+// so that merges never chain.
+func repointGroups(ctx context.Context, tx *pgx.Tx, table string, appId, survivorId uuid.UUID, groupIds []uuid.UUID) (err error) {
+	now := time.Now()
+
+	mergeStmt := sqlf.PostgreSQL.
+		Update(table).
+		Set("merged_into", survivorId).
+		Set("updated_at", now).
+		Where("app_id = ?", appId).
+		Where("id = any(?)", groupIds)
+
+	defer mergeStmt.Close()
+
+	tag, err := (*tx).Exec(ctx, mergeStmt.String(), mergeStmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	if tag.RowsAffected() != int64(len(groupIds)) {
+		return ErrMergeGroupsNotFound
+	}
+
+	flattenStmt := sqlf.PostgreSQL.
+		Update(table).
+		Set("merged_into", survivorId).
+		Set("updated_at", now).
+		Where("app_id = ?", appId).
+		Where("merged_into = any(?)", groupIds)
+
+	defer flattenStmt.Close()
+
+	_, err = (*tx).Exec(ctx, flattenStmt.String(), flattenStmt.Args()...)
+
+	return
+}
+
+// unmergeGroups detaches the groups of the table
+// from the surviving group.
+func unmergeGroups(ctx context.Context, table string, appId, survivorId uuid.UUID, groupIds []uuid.UUID) (err error) {
+	tx, err := server.Server.PgPool.Begin(ctx)
+	if err != nil {
+		return
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err = detachGroups(ctx, &tx, table, appId, survivorId, groupIds); err != nil {
+		return
+	}
+
+	return tx.Commit(ctx)
+}
+
+// detachGroups detaches the groups of the table from
+// the surviving group. Detaches all merged groups if
+// no group ids are provided.
+func detachGroups(ctx context.Context, tx *pgx.Tx, table string, appId, survivorId uuid.UUID, groupIds []uuid.UUID) (err error) {
+	stmt := sqlf.PostgreSQL.
+		Update(table).
+		Set("merged_into", nil).
+		Set("updated_at", time.Now()).
+		Where("app_id = ?", appId).
+		Where("merged_into = ?", survivorId)
+
+	defer stmt.Close()
+
+	if len(groupIds) > 0 {
+		stmt.Where("id = any(?)", groupIds)
+	}
+
+	_, err = (*tx).Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// loadMerged loads the fingerprints and ids of the
+// groups of the table merged into the group.
+func (m *Merging) loadMerged(ctx context.Context, table string, groupId uuid.UUID, fingerprint string) (err error) {
+	stmt := sqlf.PostgreSQL.
+		From(table).
+		Select("id").
+		Select("fingerprint").
+		Where("merged_into = ?", groupId).
+		OrderBy("id")
+
+	defer stmt.Close()
+
+	rows, err := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	m.Fingerprints = []string{fingerprint}
+	m.MergedGroupIDs = nil
+
+	for rows.Next() {
+		var id uuid.UUID
+		var fp string
+		if err = rows.Scan(&id, &fp); err != nil {
+			return
+		}
+
+		m.MergedGroupIDs = append(m.MergedGroupIDs, id)
+		if !slices.Contains(m.Fingerprints, fp) {
+			m.Fingerprints = append(m.Fingerprints, fp)
+		}
+	}
+
+	return rows.Err()
+}
+
+// mergedFingerprints returns the fingerprints of the
+// groups of the table merged into other groups of
+// the app, keyed by the surviving group's id.
+func mergedFingerprints(ctx context.Context, table string, appId uuid.UUID) (fingerprints map[uuid.UUID][]string, err error) {
+	stmt := sqlf.PostgreSQL.
+		From(table).
+		Select("merged_into").
+		Select("fingerprint").
+		Where("app_id = ?", appId).
+		Where("merged_into is not null")
+
+	defer stmt.Close()
+
+	rows, err := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	fingerprints = make(map[uuid.UUID][]string)
+
+	for rows.Next() {
+		var survivorId uuid.UUID
+		var fp string
+		if err = rows.Scan(&survivorId, &fp); err != nil {
+			return
+		}
+
+		fingerprints[survivorId] = append(fingerprints[survivorId], fp)
+	}
+
+	err = rows.Err()
+
+	return
+}
+
+// Merge merges the exception groups into
+// the ExceptionGroup.
+func (e ExceptionGroup) Merge(ctx context.Context, groupIds []uuid.UUID) error {
+	return mergeGroups(ctx, "public.unhandled_exception_groups", e.AppID, e.ID, groupIds)
+}
+
+// Unmerge detaches the exception groups
+// from the ExceptionGroup.
+func (e ExceptionGroup) Unmerge(ctx context.Context, groupIds []uuid.UUID) error {
+	return unmergeGroups(ctx, "public.unhandled_exception_groups", e.AppID, e.ID, groupIds)
+}
+
+// LoadMerged loads the fingerprints and ids of
+// groups merged into the ExceptionGroup.
+func (e *ExceptionGroup) LoadMerged(ctx context.Context) error {
+	return e.loadMerged(ctx, "public.unhandled_exception_groups", e.ID, e.Fingerprint)
+}
+
+// Merge merges the ANR groups into
+// the ANRGroup.
+func (a ANRGroup) Merge(ctx context.Context, groupIds []uuid.UUID) error {
+	return mergeGroups(ctx, "public.anr_groups", a.AppID, a.ID, groupIds)
+}
+
+// Unmerge detaches the ANR groups
+// from the ANRGroup.
+func (a ANRGroup) Unmerge(ctx context.Context, groupIds []uuid.UUID) error {
+	return unmergeGroups(ctx, "public.anr_groups", a.AppID, a.ID, groupIds)
+}
+
+// LoadMerged loads the fingerprints and ids of
+// groups merged into the ANRGroup.
+func (a *ANRGroup) LoadMerged(ctx context.Context) error {
+	return a.loadMerged(ctx, "public.anr_groups", a.ID, a.Fingerprint)
+}
+
+// MergedExceptionFingerprints returns the fingerprints of
+// exception groups merged into other groups of the app,
+// keyed by the surviving group's id.
+func MergedExceptionFingerprints(ctx context.Context, appId uuid.UUID) (map[uuid.UUID][]string, error) {
+	return mergedFingerprints(ctx, "public.unhandled_exception_groups", appId)
+}
+
+// MergedANRFingerprints returns the fingerprints of
+// ANR groups merged into other groups of the app,
+// keyed by the surviving group's id.
+func MergedANRFingerprints(ctx context.Context, appId uuid.UUID) (map[uuid.UUID][]string, error) {
+	return mergedFingerprints(ctx, "public.anr_groups", appId)
+}
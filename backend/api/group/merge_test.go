@@ -0,0 +1,172 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// execution is a statement executed
+// in a transaction.
+type execution struct {
+	sql  string
+	args []any
+}
+
+// mergeTx is a transaction that records executed
+// statements. Each statement affects rows rows.
+type mergeTx struct {
+	pgx.Tx
+	rows       int
+	executions []execution
+}
+
+func (tx *mergeTx) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	// statements are closed & their args
+	// reset after the merge
+	tx.executions = append(tx.executions, execution{sql: sql, args: slices.Clone(args)})
+
+	return pgconn.NewCommandTag(fmt.Sprintf("UPDATE %d", tx.rows)), nil
+}
+
+func TestMergeRequestValidate(t *testing.T) {
+	survivorId := uuid.New()
+	a := uuid.New()
+	b := uuid.New()
+
+	valid := MergeRequest{GroupIDs: []uuid.UUID{a, b}}
+	if err := valid.Validate(survivorId, false); err != nil {
+		t.Errorf("Expected nil error, but got %v", err)
+	}
+
+	if err := (MergeRequest{}).Validate(survivorId, true); err != nil {
+		t.Errorf("Expected nil error for empty unmerge request, but got %v", err)
+	}
+
+	tooMany := MergeRequest{}
+	for range MaxMergeGroups + 1 {
+		tooMany.GroupIDs = append(tooMany.GroupIDs, uuid.New())
+	}
+
+	invalid := []MergeRequest{
+		{},
+		{GroupIDs: []uuid.UUID{a, survivorId}},
+		{GroupIDs: []uuid.UUID{a, b, a}},
+		tooMany,
+	}
+
+	for _, r := range invalid {
+		if err := r.Validate(survivorId, false); err == nil {
+			t.Errorf("Expected error for %d group ids, but got nil", len(r.GroupIDs))
+		}
+	}
+}
+
+func TestIsMerged(t *testing.T) {
+	survivorId := uuid.New()
+
+	if (ExceptionGroup{}).IsMerged() {
+		t.Error("Expected exception group to not be merged")
+	}
+
+	if !(ANRGroup{Merging: Merging{MergedInto: &survivorId}}).IsMerged() {
+		t.Error("Expected ANR group to be merged")
+	}
+}
+
+func TestRepointGroups(t *testing.T) {
+	ctx := context.Background()
+	appId := uuid.New()
+	survivorId := uuid.New()
+	groupIds := []uuid.UUID{uuid.New(), uuid.New()}
+
+	tx := &mergeTx{rows: len(groupIds)}
+	var pgTx pgx.Tx = tx
+
+	if err := repointGroups(ctx, &pgTx, "public.anr_groups", appId, survivorId, groupIds); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if len(tx.executions) != 2 {
+		t.Fatalf("Expected %d statements, but got %d", 2, len(tx.executions))
+	}
+
+	// merged groups point to the survivor
+	merge := tx.executions[0]
+	if !strings.Contains(merge.sql, "merged_into=$1") || !strings.Contains(merge.sql, "id = any($4)") {
+		t.Errorf("Expected merge of group ids, but got %q", merge.sql)
+	}
+	if merge.args[0] != survivorId || merge.args[2] != appId {
+		t.Errorf("Expected merge into %v of app %v, but got %v", survivorId, appId, merge.args)
+	}
+
+	// groups merged into the merged
+	// groups move to the survivor
+	flatten := tx.executions[1]
+	if !strings.Contains(flatten.sql, "merged_into = any($4)") {
+		t.Errorf("Expected flatten of merged groups, but got %q", flatten.sql)
+	}
+	if flatten.args[0] != survivorId || !slices.Equal(flatten.args[3].([]uuid.UUID), groupIds) {
+		t.Errorf("Expected flatten into %v of %v, but got %v", survivorId, groupIds, flatten.args)
+	}
+}
+
+func TestRepointGroupsNotFound(t *testing.T) {
+	ctx := context.Background()
+
+	// one of the groups isn't in the app
+	tx := &mergeTx{rows: 1}
+	var pgTx pgx.Tx = tx
+
+	err := repointGroups(ctx, &pgTx, "public.anr_groups", uuid.New(), uuid.New(), []uuid.UUID{uuid.New(), uuid.New()})
+	if !errors.Is(err, ErrMergeGroupsNotFound) {
+		t.Errorf("Expected %v, but got %v", ErrMergeGroupsNotFound, err)
+	}
+
+	if len(tx.executions) != 1 {
+		t.Errorf("Expected %d statement, but got %d", 1, len(tx.executions))
+	}
+}
+
+func TestDetachGroups(t *testing.T) {
+	ctx := context.Background()
+	appId := uuid.New()
+	survivorId := uuid.New()
+	groupIds := []uuid.UUID{uuid.New()}
+
+	tx := &mergeTx{}
+	var pgTx pgx.Tx = tx
+
+	if err := detachGroups(ctx, &pgTx, "public.unhandled_exception_groups", appId, survivorId, groupIds); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if err := detachGroups(ctx, &pgTx, "public.unhandled_exception_groups", appId, survivorId, nil); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	some := tx.executions[0]
+	if !strings.Contains(some.sql, "merged_into=$1") || some.args[0] != nil {
+		t.Errorf("Expected merged_into to be cleared, but got %q %v", some.sql, some.args)
+	}
+	if !strings.Contains(some.sql, "merged_into = $4") || some.args[3] != survivorId {
+		t.Errorf("Expected only groups merged into %v, but got %q %v", survivorId, some.sql, some.args)
+	}
+	if !strings.Contains(some.sql, "id = any($5)") {
+		t.Errorf("Expected detach of group ids, but got %q", some.sql)
+	}
+
+	// without group ids, all merged
+	// groups are detached
+	all := tx.executions[1]
+	if strings.Contains(all.sql, "any(") {
+		t.Errorf("Expected detach of all merged groups, but got %q", all.sql)
+	}
+}
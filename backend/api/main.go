@@ -9,6 +9,7 @@ import (
 	"backend/api/inet"
 	"backend/api/measure"
 	"backend/api/server"
+	"backend/api/webhook"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
@@ -36,6 +37,22 @@ func main() {
 		}
 	}()
 
+	// process queued event requests
+	// in the background
+	measure.StartIngestWorkers(context.Background(), config.IngestWorkers)
+
+	// symbolicate events whose mapping
+	// files arrived late in the background
+	measure.StartSymbolicationWorker(context.Background())
+
+	// purge data of deleted teams
+	// & apps in the background
+	measure.StartPurgeWorker(context.Background())
+
+	// retry failed webhook deliveries
+	// in the background
+	webhook.StartRetrier(context.Background())
+
 	r := gin.Default()
 
 	closeTracer := config.InitTracer()
@@ -46,6 +63,14 @@ func main() {
 		}
 	}()
 
+	closeMeter := config.InitMeter()
+	// close otel meter
+	defer func() {
+		if err := closeMeter(context.Background()); err != nil {
+			log.Fatalf("Unable to close otel meter: %v", err)
+		}
+	}()
+
 	r.Use(otelgin.Middleware(config.OtelServiceName))
 	r.Use(server.CaptureRequest())
 	r.Use(server.CapturePanic())
@@ -58,8 +83,13 @@ func main() {
 
 	// SDK routes
 	r.PUT("/events", measure.ValidateAPIKey(), measure.PutEvents)
+	r.GET("/events/:id/status", measure.ValidateAPIKey(), measure.GetEventRequestStatus)
 	r.PUT("/builds", measure.ValidateAPIKey(), measure.PutBuild)
 
+	// OTLP/HTTP routes
+	r.POST("/v1/logs", measure.ValidateAPIKey(), measure.PostOTLPLogs)
+	r.POST("/v1/traces", measure.ValidateAPIKey(), measure.PostOTLPTraces)
+
 	cors := cors.New(cors.Config{
 		AllowOrigins:     []string{config.SiteOrigin},
 		AllowMethods:     []string{"GET", "OPTIONS", "PATCH", "DELETE", "PUT"},
@@ -80,6 +110,15 @@ func main() {
 	{
 		auth.POST("github", measure.SigninGitHub)
 		auth.POST("google", measure.SigninGoogle)
+		auth.POST("oidc", measure.SigninOIDC)
+		auth.POST("password/signup", measure.SignupPassword)
+		auth.POST("password/signin", measure.SigninPassword)
+		auth.POST("password/verify", measure.VerifyEmail)
+		auth.POST("password/forgot", measure.ForgotPassword)
+		auth.POST("password/reset", measure.ResetPassword)
+		auth.POST("magic", measure.SendMagicLink)
+		auth.POST("magic/verify", measure.SigninMagicLink)
+		auth.POST("invites/decline", measure.DeclineInvite)
 		auth.POST("refresh", measure.ValidateRefreshToken(), measure.RefreshToken)
 		auth.DELETE("signout", measure.ValidateRefreshToken(), measure.Signout)
 	}
@@ -91,22 +130,51 @@ func main() {
 		apps.GET(":id/filters", measure.GetAppFilters)
 		apps.GET(":id/crashGroups", measure.GetCrashOverview)
 		apps.GET(":id/crashGroups/plots/instances", measure.GetCrashOverviewPlotInstances)
+		apps.PATCH(":id/crashGroups/:crashGroupId", measure.UpdateCrashGroupStatus)
+		apps.POST(":id/crashGroups/:crashGroupId/merge", measure.MergeCrashGroups)
+		apps.POST(":id/crashGroups/:crashGroupId/unmerge", measure.UnmergeCrashGroups)
 		apps.GET(":id/crashGroups/:crashGroupId/crashes", measure.GetCrashDetailCrashes)
 		apps.GET(":id/crashGroups/:crashGroupId/plots/instances", measure.GetCrashDetailPlotInstances)
 		apps.GET(":id/crashGroups/:crashGroupId/plots/journey", measure.GetCrashDetailPlotJourney)
 		apps.GET(":id/anrGroups", measure.GetANROverview)
 		apps.GET(":id/anrGroups/plots/instances", measure.GetANROverviewPlotInstances)
+		apps.PATCH(":id/anrGroups/:anrGroupId", measure.UpdateANRGroupStatus)
+		apps.POST(":id/anrGroups/:anrGroupId/merge", measure.MergeANRGroups)
+		apps.POST(":id/anrGroups/:anrGroupId/unmerge", measure.UnmergeANRGroups)
 		apps.GET(":id/anrGroups/:anrGroupId/anrs", measure.GetANRDetailANRs)
 		apps.GET(":id/anrGroups/:anrGroupId/plots/instances", measure.GetANRDetailPlotInstances)
 		apps.GET(":id/anrGroups/:anrGroupId/plots/journey", measure.GetANRDetailPlotJourney)
 		apps.GET(":id/sessions", measure.GetSessionsOverview)
 		apps.GET(":id/sessions/:sessionId", measure.GetSession)
 		apps.GET(":id/sessions/plots/instances", measure.GetSessionsOverviewPlot)
+		apps.GET(":id/spans", measure.GetSpans)
+		apps.GET(":id/spans/plots/percentiles", measure.GetSpansPlotPercentiles)
+		apps.GET(":id/customEvents", measure.GetCustomEvents)
+		apps.GET(":id/customEvents/plots/instances", measure.GetCustomEventsPlotInstances)
 		apps.GET(":id/alertPrefs", measure.GetAlertPrefs)
 		apps.PATCH(":id/alertPrefs", measure.UpdateAlertPrefs)
 		apps.GET(":id/settings", measure.GetAppSettings)
 		apps.PATCH(":id/settings", measure.UpdateAppSettings)
+		apps.GET(":id/fingerprintRules", measure.GetFingerprintRules)
+		apps.PATCH(":id/fingerprintRules", measure.UpdateFingerprintRules)
 		apps.PATCH(":id/rename", measure.RenameApp)
+		apps.POST(":id/transfer", measure.TransferApp)
+		apps.GET(":id/quota", measure.GetAppQuota)
+		apps.PATCH(":id/quota", measure.UpdateAppQuota)
+		apps.GET(":id/webhooks", measure.GetWebhooks)
+		apps.POST(":id/webhooks", measure.CreateWebhook)
+		apps.PATCH(":id/webhooks/:webhookId", measure.UpdateWebhook)
+		apps.DELETE(":id/webhooks/:webhookId", measure.DeleteWebhook)
+		apps.GET(":id/webhooks/:webhookId/deliveries", measure.GetWebhookDeliveries)
+		apps.POST(":id/webhooks/:webhookId/test", measure.TestWebhook)
+		apps.GET(":id/apiKeys", measure.GetAPIKeys)
+		apps.POST(":id/apiKeys", measure.CreateAPIKey)
+		apps.PATCH(":id/apiKeys/:keyId", measure.UpdateAPIKey)
+		apps.POST(":id/apiKeys/:keyId/revoke", measure.RevokeAPIKey)
+		apps.GET(":id/roles", measure.GetAppRoles)
+		apps.PATCH(":id/roles/:memberId", measure.UpdateAppRole)
+		apps.DELETE(":id/roles/:memberId", measure.DeleteAppRole)
+		apps.DELETE(":id", measure.DeleteApp)
 	}
 
 	teams := r.Group("/teams", measure.ValidateAccessToken())
@@ -115,6 +183,8 @@ func main() {
 		teams.GET("", measure.GetTeams)
 		teams.GET(":id/apps", measure.GetTeamApps)
 		teams.GET(":id/usage", measure.GetUsage)
+		teams.GET(":id/quota", measure.GetTeamQuota)
+		teams.PATCH(":id/quota", measure.UpdateTeamQuota)
 		teams.GET(":id/apps/:appId", measure.GetTeamApp)
 		teams.POST(":id/apps", measure.CreateApp)
 		teams.POST(":id/invite", measure.InviteMembers)
@@ -123,6 +193,23 @@ func main() {
 		teams.GET(":id/authz", measure.GetAuthzRoles)
 		teams.GET(":id/members", measure.GetTeamMembers)
 		teams.DELETE(":id/members/:memberId", measure.RemoveTeamMember)
+		teams.GET(":id/invites", measure.GetTeamInvites)
+		teams.POST(":id/invites/:inviteId/resend", measure.ResendInvite)
+		teams.DELETE(":id/invites/:inviteId", measure.RevokeInvite)
+		teams.GET(":id/auditLogs", measure.GetTeamAuditLogs)
+		teams.DELETE(":id", measure.DeleteTeam)
+	}
+
+	purges := r.Group("/purges", measure.ValidateAccessToken())
+	{
+		purges.GET(":id", measure.GetPurge)
+	}
+
+	tokens := r.Group("/tokens", measure.ValidateAccessToken())
+	{
+		tokens.GET("", measure.GetAccessTokens)
+		tokens.POST("", measure.CreateAccessToken)
+		tokens.DELETE(":id", measure.RevokeAccessToken)
 	}
 
 	r.Run(":8080") // listen and serve on 0.0.0.0:8080
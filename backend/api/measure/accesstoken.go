@@ -0,0 +1,733 @@
+package measure
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"backend/api/chrono"
+	"backend/api/cipher"
+	"backend/api/server"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/leporo/sqlf"
+)
+
+// AccessTokenPrefix is the constant prefix
+// of personal access tokens.
+const AccessTokenPrefix = "msrpat"
+
+// maxAccessTokensPerUser is the maximum number of
+// unrevoked personal access tokens a user can have.
+const maxAccessTokensPerUser = 20
+
+// maxAccessTokenNameChars is the maximum number
+// of characters in a personal access token's name.
+const maxAccessTokenNameChars = 256
+
+// defaultAccessTokenExpiry is the lifetime of a
+// personal access token when none is provided.
+const defaultAccessTokenExpiry = 30 * 24 * time.Hour
+
+// maxAccessTokenExpiry is the longest lifetime
+// of a personal access token.
+const maxAccessTokenExpiry = 365 * 24 * time.Hour
+
+// accessTokenLastUsedInterval is the minimum interval
+// between successive writes of a personal access
+// token's last used timestamp.
+const accessTokenLastUsedInterval = time.Minute
+
+// accessTokenRoutes maps the dashboard routes personal
+// access tokens can call to the scope the token must
+// grant. Tokens are denied on all other routes. Handlers
+// still check the user's role & the token's scopes on
+// the team or app acted upon.
+var accessTokenRoutes = map[string]*scope{
+	"GET /apps/:id/journey":                                   ScopeAppRead,
+	"GET /apps/:id/metrics":                                   ScopeAppRead,
+	"GET /apps/:id/filters":                                   ScopeAppRead,
+	"GET /apps/:id/crashGroups":                               ScopeAppRead,
+	"GET /apps/:id/crashGroups/plots/instances":               ScopeAppRead,
+	"PATCH /apps/:id/crashGroups/:crashGroupId":               ScopeAppAll,
+	"POST /apps/:id/crashGroups/:crashGroupId/merge":          ScopeAppAll,
+	"POST /apps/:id/crashGroups/:crashGroupId/unmerge":        ScopeAppAll,
+	"GET /apps/:id/crashGroups/:crashGroupId/crashes":         ScopeAppRead,
+	"GET /apps/:id/crashGroups/:crashGroupId/plots/instances": ScopeAppRead,
+	"GET /apps/:id/crashGroups/:crashGroupId/plots/journey":   ScopeAppRead,
+	"GET /apps/:id/anrGroups":                                 ScopeAppRead,
+	"GET /apps/:id/anrGroups/plots/instances":                 ScopeAppRead,
+	"PATCH /apps/:id/anrGroups/:anrGroupId":                   ScopeAppAll,
+	"POST /apps/:id/anrGroups/:anrGroupId/merge":              ScopeAppAll,
+	"POST /apps/:id/anrGroups/:anrGroupId/unmerge":            ScopeAppAll,
+	"GET /apps/:id/anrGroups/:anrGroupId/anrs":                ScopeAppRead,
+	"GET /apps/:id/anrGroups/:anrGroupId/plots/instances":     ScopeAppRead,
+	"GET /apps/:id/anrGroups/:anrGroupId/plots/journey":       ScopeAppRead,
+	"GET /apps/:id/sessions":                                  ScopeAppRead,
+	"GET /apps/:id/sessions/:sessionId":                       ScopeAppRead,
+	"GET /apps/:id/sessions/plots/instances":                  ScopeAppRead,
+	"GET /apps/:id/spans":                                     ScopeAppRead,
+	"GET /apps/:id/spans/plots/percentiles":                   ScopeAppRead,
+	"GET /apps/:id/customEvents":                              ScopeAppRead,
+	"GET /apps/:id/customEvents/plots/instances":              ScopeAppRead,
+	"GET /apps/:id/alertPrefs":                                ScopeAppRead,
+	"PATCH /apps/:id/alertPrefs":                              ScopeAppRead,
+	"GET /apps/:id/settings":                                  ScopeAppRead,
+	"PATCH /apps/:id/settings":                                ScopeAppAll,
+	"GET /apps/:id/fingerprintRules":                          ScopeAppRead,
+	"PATCH /apps/:id/fingerprintRules":                        ScopeAppAll,
+	"PATCH /apps/:id/rename":                                  ScopeAppAll,
+	"POST /apps/:id/transfer":                                 ScopeTeamAll,
+	"GET /apps/:id/quota":                                     ScopeAppRead,
+	"PATCH /apps/:id/quota":                                   ScopeBillingAll,
+	"GET /apps/:id/webhooks":                                  ScopeAppRead,
+	"POST /apps/:id/webhooks":                                 ScopeAppAll,
+	"PATCH /apps/:id/webhooks/:webhookId":                     ScopeAppAll,
+	"DELETE /apps/:id/webhooks/:webhookId":                    ScopeAppAll,
+	"GET /apps/:id/webhooks/:webhookId/deliveries":            ScopeAppRead,
+	"POST /apps/:id/webhooks/:webhookId/test":                 ScopeAppAll,
+	"GET /apps/:id/apiKeys":                                   ScopeAppRead,
+	"POST /apps/:id/apiKeys":                                  ScopeAppAll,
+	"PATCH /apps/:id/apiKeys/:keyId":                          ScopeAppAll,
+	"POST /apps/:id/apiKeys/:keyId/revoke":                    ScopeAppAll,
+	"GET /apps/:id/roles":                                     ScopeTeamRead,
+	"PATCH /apps/:id/roles/:memberId":                         ScopeTeamChangeRoleSameOrLower,
+	"DELETE /apps/:id/roles/:memberId":                        ScopeTeamChangeRoleSameOrLower,
+	"DELETE /apps/:id":                                        ScopeTeamAll,
+	"POST /teams":                                             ScopeTeamAll,
+	"GET /teams":                                              ScopeTeamRead,
+	"GET /teams/:id/apps":                                     ScopeAppRead,
+	"GET /teams/:id/usage":                                    ScopeTeamRead,
+	"GET /teams/:id/quota":                                    ScopeTeamRead,
+	"PATCH /teams/:id/quota":                                  ScopeBillingAll,
+	"GET /teams/:id/apps/:appId":                              ScopeAppRead,
+	"POST /teams/:id/apps":                                    ScopeAppAll,
+	"POST /teams/:id/invite":                                  ScopeTeamInviteSameOrLower,
+	"PATCH /teams/:id/rename":                                 ScopeTeamAll,
+	"PATCH /teams/:id/members/:memberId/role":                 ScopeTeamChangeRoleSameOrLower,
+	"GET /teams/:id/authz":                                    ScopeTeamRead,
+	"GET /teams/:id/members":                                  ScopeTeamRead,
+	"DELETE /teams/:id/members/:memberId":                     ScopeTeamChangeRoleSameOrLower,
+	"GET /teams/:id/invites":                                  ScopeTeamRead,
+	"POST /teams/:id/invites/:inviteId/resend":                ScopeTeamInviteSameOrLower,
+	"DELETE /teams/:id/invites/:inviteId":                     ScopeTeamInviteSameOrLower,
+	"GET /teams/:id/auditLogs":                                ScopeAuditRead,
+	"DELETE /teams/:id":                                       ScopeTeamAll,
+	"GET /purges/:id":                                         ScopeTeamRead,
+}
+
+// accessTokenRouteScope returns the scope a personal
+// access token must grant to call the request's route.
+// Returns false if tokens cannot call the route.
+func accessTokenRouteScope(c *gin.Context) (scope, bool) {
+	s, ok := accessTokenRoutes[c.Request.Method+" "+c.FullPath()]
+	if !ok {
+		return scope{}, false
+	}
+
+	return *s, true
+}
+
+var (
+	// ErrAccessTokenExpired is returned when an
+	// expired personal access token is presented.
+	ErrAccessTokenExpired = errors.New("personal access token has expired")
+
+	// ErrAccessTokenRevoked is returned when a revoked
+	// personal access token is presented.
+	ErrAccessTokenRevoked = errors.New("personal access token has been revoked")
+)
+
+// AccessToken is a scoped & expiring personal
+// access token for programmatic access to the
+// dashboard API on behalf of a user.
+type AccessToken struct {
+	id         uuid.UUID
+	userId     uuid.UUID
+	name       string
+	value      string
+	hint       string
+	scopes     []scope
+	expiresAt  time.Time
+	lastUsedAt time.Time
+	revokedAt  time.Time
+	createdAt  time.Time
+}
+
+type AccessTokenPayload struct {
+	Name          string   `json:"name" binding:"required"`
+	Scopes        []string `json:"scopes" binding:"required"`
+	ExpiresInDays *int     `json:"expires_in_days"`
+}
+
+func (a AccessToken) MarshalJSON() ([]byte, error) {
+	tokenMap := make(map[string]any)
+
+	scopes := []string{}
+	for _, s := range a.scopes {
+		scopes = append(scopes, s.String())
+	}
+
+	tokenMap["id"] = a.id
+	tokenMap["name"] = a.name
+	tokenMap["hint"] = a.hint
+	tokenMap["scopes"] = scopes
+	tokenMap["expires_at"] = a.expiresAt.Format(chrono.ISOFormatJS)
+	tokenMap["created_at"] = a.createdAt.Format(chrono.ISOFormatJS)
+
+	// token is only revealed once
+	// at the time of creation
+	if a.value != "" {
+		tokenMap["token"] = a.String()
+	}
+	if a.lastUsedAt.IsZero() {
+		tokenMap["last_used_at"] = nil
+	} else {
+		tokenMap["last_used_at"] = a.lastUsedAt.Format(chrono.ISOFormatJS)
+	}
+	if a.revokedAt.IsZero() {
+		tokenMap["revoked_at"] = nil
+	} else {
+		tokenMap["revoked_at"] = a.revokedAt.Format(chrono.ISOFormatJS)
+	}
+
+	return json.Marshal(tokenMap)
+}
+
+// NewAccessToken creates a new personal
+// access token for the user.
+func NewAccessToken(userId uuid.UUID) (*AccessToken, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, err
+	}
+
+	value := hex.EncodeToString(bytes)
+	now := time.Now()
+
+	return &AccessToken{
+		id:        uuid.New(),
+		userId:    userId,
+		value:     value,
+		hint:      value[:8],
+		expiresAt: now.Add(defaultAccessTokenExpiry),
+		createdAt: now,
+	}, nil
+}
+
+func (a *AccessToken) String() string {
+	checksum, _ := cipher.ComputeChecksum([]byte(a.value))
+	return fmt.Sprintf("%s_%s_%s", AccessTokenPrefix, a.value, *checksum)
+}
+
+// isAccessToken reports if the bearer token
+// looks like a personal access token.
+func isAccessToken(token string) bool {
+	return strings.HasPrefix(token, AccessTokenPrefix+"_")
+}
+
+// hashAccessToken computes the hash of the token's
+// value. Only the hash is ever stored.
+func hashAccessToken(value string) (string, error) {
+	hash, err := cipher.ComputeSHA2Hash([]byte(value))
+	if err != nil {
+		return "", err
+	}
+
+	return *hash, nil
+}
+
+// validate trims & validates the token's name,
+// scopes & expiry from the payload.
+func (a *AccessToken) validate(p AccessTokenPayload) error {
+	name := strings.TrimSpace(p.Name)
+	if name == "" {
+		return errors.New("name cannot be empty")
+	}
+	if len(name) > maxAccessTokenNameChars {
+		return fmt.Errorf("name cannot be longer than %d characters", maxAccessTokenNameChars)
+	}
+
+	if len(p.Scopes) == 0 {
+		return errors.New("scopes cannot be empty")
+	}
+
+	var scopes []scope
+	for _, str := range p.Scopes {
+		s, ok := parseScope(str)
+		if !ok {
+			return fmt.Errorf("unknown scope %q", str)
+		}
+		if !slices.Contains(scopes, s) {
+			scopes = append(scopes, s)
+		}
+	}
+
+	if p.ExpiresInDays != nil {
+		// bound the days before converting to a
+		// duration, large values would overflow
+		days := *p.ExpiresInDays
+		maxDays := int(maxAccessTokenExpiry.Hours() / 24)
+		if days <= 0 {
+			return errors.New("expires_in_days must be greater than 0")
+		}
+		if days > maxDays {
+			return fmt.Errorf("expires_in_days cannot be more than %d", maxDays)
+		}
+		a.expiresAt = a.createdAt.Add(time.Duration(days) * 24 * time.Hour)
+	}
+
+	a.name = name
+	a.scopes = scopes
+
+	return nil
+}
+
+// insert persists the token. Only the hash
+// of the token's value is stored.
+func (a *AccessToken) insert(ctx context.Context) error {
+	hash, err := hashAccessToken(a.value)
+	if err != nil {
+		return err
+	}
+
+	scopes := []string{}
+	for _, s := range a.scopes {
+		scopes = append(scopes, s.String())
+	}
+
+	stmt := sqlf.PostgreSQL.InsertInto("public.personal_access_tokens").
+		Set("id", a.id).
+		Set("user_id", a.userId).
+		Set("name", a.name).
+		Set("hint", a.hint).
+		Set("token_hash", hash).
+		Set("scopes", scopes).
+		Set("expires_at", a.expiresAt).
+		Set("created_at", a.createdAt)
+
+	defer stmt.Close()
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+	return err
+}
+
+// revoke revokes the token right away.
+func (a *AccessToken) revoke(ctx context.Context) error {
+	a.revokedAt = time.Now()
+
+	stmt := sqlf.PostgreSQL.Update("public.personal_access_tokens").
+		Set("revoked_at", a.revokedAt).
+		Where("id = ? and user_id = ?", a.id, a.userId)
+
+	defer stmt.Close()
+
+	_, err := server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+	return err
+}
+
+// markUsed updates the token's last used
+// timestamp, at most once every interval.
+func (a *AccessToken) markUsed(ctx context.Context) error {
+	now := time.Now()
+
+	if !a.lastUsedAt.IsZero() && now.Sub(a.lastUsedAt) < accessTokenLastUsedInterval {
+		return nil
+	}
+
+	stmt := sqlf.PostgreSQL.Update("public.personal_access_tokens").
+		Set("last_used_at", now).
+		Where("id = ?", a.id).
+		Where("(last_used_at is null or last_used_at < ?)", now.Add(-accessTokenLastUsedInterval))
+
+	defer stmt.Close()
+
+	if _, err := server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...); err != nil {
+		return err
+	}
+
+	a.lastUsedAt = now
+
+	return nil
+}
+
+// check returns an error if the token
+// is not usable at time t.
+func (a AccessToken) check(t time.Time) error {
+	if !a.revokedAt.IsZero() {
+		return ErrAccessTokenRevoked
+	}
+
+	if !t.Before(a.expiresAt) {
+		return ErrAccessTokenExpired
+	}
+
+	return nil
+}
+
+// accessTokenCols are the columns selected
+// for reading a personal access token.
+var accessTokenCols = []string{
+	"id",
+	"user_id",
+	"name",
+	"hint",
+	"scopes",
+	"expires_at",
+	"last_used_at",
+	"revoked_at",
+	"created_at",
+}
+
+// scanAccessToken scans a row selected
+// using accessTokenCols.
+func scanAccessToken(row pgx.Row) (*AccessToken, error) {
+	var scopes []string
+	var lastUsedAt pgtype.Timestamptz
+	var revokedAt pgtype.Timestamptz
+
+	a := new(AccessToken)
+
+	if err := row.Scan(&a.id, &a.userId, &a.name, &a.hint, &scopes, &a.expiresAt, &lastUsedAt, &revokedAt, &a.createdAt); err != nil {
+		return nil, err
+	}
+
+	// scopes no longer known are
+	// dropped rather than granted
+	for _, str := range scopes {
+		if s, ok := parseScope(str); ok {
+			a.scopes = append(a.scopes, s)
+		}
+	}
+
+	if lastUsedAt.Valid {
+		a.lastUsedAt = lastUsedAt.Time
+	}
+
+	if revokedAt.Valid {
+		a.revokedAt = revokedAt.Time
+	}
+
+	return a, nil
+}
+
+// getAccessTokens fetches all personal access
+// tokens of a user, most recent first.
+func getAccessTokens(ctx context.Context, userId uuid.UUID) (tokens []AccessToken, err error) {
+	stmt := sqlf.PostgreSQL.Select(strings.Join(accessTokenCols, ",")).
+		From("public.personal_access_tokens").
+		Where("user_id = ?", userId).
+		OrderBy("created_at desc")
+
+	defer stmt.Close()
+
+	rows, err := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		a, err := scanAccessToken(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, *a)
+	}
+
+	err = rows.Err()
+
+	return
+}
+
+// getAccessToken fetches a user's personal access
+// token by id. Returns nil if no token is found.
+func getAccessToken(ctx context.Context, userId, tokenId uuid.UUID) (*AccessToken, error) {
+	stmt := sqlf.PostgreSQL.Select(strings.Join(accessTokenCols, ",")).
+		From("public.personal_access_tokens").
+		Where("id = ? and user_id = ?", tokenId, userId)
+
+	defer stmt.Close()
+
+	a, err := scanAccessToken(server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// DecodeAccessToken validates the token's format
+// & checksum and looks it up by its hash. Returns
+// nil if no token is found and an error if the token
+// is revoked or expired.
+func DecodeAccessToken(ctx context.Context, token string) (*AccessToken, error) {
+	defaultErr := errors.New("invalid personal access token")
+
+	parts := strings.Split(token, "_")
+
+	if len(parts) != 3 || parts[0] != AccessTokenPrefix {
+		return nil, defaultErr
+	}
+
+	value := parts[1]
+	checksum := parts[2]
+
+	computedChecksum, err := cipher.ComputeChecksum([]byte(value))
+	if err != nil {
+		return nil, err
+	}
+
+	if checksum != *computedChecksum {
+		return nil, defaultErr
+	}
+
+	hash, err := hashAccessToken(value)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := sqlf.PostgreSQL.Select(strings.Join(accessTokenCols, ",")).
+		From("public.personal_access_tokens").
+		Where("token_hash = ?", hash)
+
+	defer stmt.Close()
+
+	a, err := scanAccessToken(server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if err := a.check(time.Now()); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// validatePersonalAccessToken authenticates the request
+// using a personal access token. Sets the token's user &
+// scopes on the context. Aborts the request on failure.
+func validatePersonalAccessToken(c *gin.Context, token string) {
+	accessToken, err := DecodeAccessToken(c.Request.Context(), token)
+	if err != nil {
+		if errors.Is(err, ErrAccessTokenExpired) || errors.Is(err, ErrAccessTokenRevoked) {
+			msg := err.Error()
+			fmt.Println(msg)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": msg})
+			return
+		}
+		fmt.Println("personal access token decode failed:", err)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid personal access token"})
+		return
+	}
+
+	if accessToken == nil {
+		msg := "invalid personal access token"
+		fmt.Println(msg)
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": msg})
+		return
+	}
+
+	// deny routes tokens aren't meant for, so that
+	// handlers without scope checks aren't exposed
+	routeScope, ok := accessTokenRouteScope(c)
+	if !ok {
+		msg := "personal access tokens cannot be used on this route"
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": msg})
+		return
+	}
+
+	if !grants(accessToken.scopes, routeScope) {
+		msg := fmt.Sprintf("personal access token is missing the %q scope", routeScope.String())
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": msg})
+		return
+	}
+
+	if err := accessToken.markUsed(c.Request.Context()); err != nil {
+		fmt.Println("failed to update personal access token last used:", err)
+	}
+
+	c.Set("userId", accessToken.userId.String())
+	c.Set("tokenId", accessToken.id.String())
+	c.Set(tokenScopesKey, accessToken.scopes)
+
+	c.Next()
+}
+
+// rejectAccessToken writes the error response and returns
+// true if the request was authenticated using a personal
+// access token. Tokens cannot be used to manage tokens.
+func rejectAccessToken(c *gin.Context) bool {
+	if c.GetString("tokenId") == "" {
+		return false
+	}
+
+	msg := `personal access tokens cannot be used to manage personal access tokens`
+	c.JSON(http.StatusForbidden, gin.H{"error": msg})
+	return true
+}
+
+func GetAccessTokens(c *gin.Context) {
+	ctx := c.Request.Context()
+	userId := c.GetString("userId")
+
+	if rejectAccessToken(c) {
+		return
+	}
+
+	tokens, err := getAccessTokens(ctx, uuid.MustParse(userId))
+	if err != nil {
+		msg := `failed to fetch personal access tokens`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	if tokens == nil {
+		tokens = []AccessToken{}
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+func CreateAccessToken(c *gin.Context) {
+	ctx := c.Request.Context()
+	userId := c.GetString("userId")
+
+	if rejectAccessToken(c) {
+		return
+	}
+
+	var payload AccessTokenPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		msg := `failed to parse personal access token json payload`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	existing, err := getAccessTokens(ctx, uuid.MustParse(userId))
+	if err != nil {
+		msg := `failed to fetch personal access tokens`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	now := time.Now()
+	count := 0
+	for _, t := range existing {
+		if t.check(now) == nil {
+			count++
+		}
+	}
+
+	if count >= maxAccessTokensPerUser {
+		msg := fmt.Sprintf(`user cannot have more than %d active personal access tokens`, maxAccessTokensPerUser)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	accessToken, err := NewAccessToken(uuid.MustParse(userId))
+	if err != nil {
+		msg := `failed to create personal access token`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	if err := accessToken.validate(payload); err != nil {
+		msg := `personal access token validation failed`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg, "details": err.Error()})
+		return
+	}
+
+	if err := accessToken.insert(ctx); err != nil {
+		msg := `failed to create personal access token`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	scopes := []string{}
+	for _, s := range accessToken.scopes {
+		scopes = append(scopes, s.String())
+	}
+
+	newAuditLog(c, auditAccessTokenCreated).
+		on(auditTargetAccessToken, accessToken.id.String()).
+		change(nil, map[string]any{"name": accessToken.name, "scopes": scopes}).
+		record(ctx)
+
+	c.JSON(http.StatusCreated, accessToken)
+}
+
+func RevokeAccessToken(c *gin.Context) {
+	ctx := c.Request.Context()
+	userId := c.GetString("userId")
+
+	if rejectAccessToken(c) {
+		return
+	}
+
+	tokenId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `personal access token id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	accessToken, err := getAccessToken(ctx, uuid.MustParse(userId), tokenId)
+	if err != nil {
+		msg := `failed to fetch personal access token`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+	if accessToken == nil {
+		msg := fmt.Sprintf(`no personal access token found with id %q`, tokenId)
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+		return
+	}
+
+	if !accessToken.revokedAt.IsZero() {
+		msg := fmt.Sprintf(`personal access token %q is already revoked`, tokenId)
+		c.JSON(http.StatusConflict, gin.H{"error": msg})
+		return
+	}
+
+	if err := accessToken.revoke(ctx); err != nil {
+		msg := `failed to revoke personal access token`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	newAuditLog(c, auditAccessTokenRevoked).
+		on(auditTargetAccessToken, tokenId.String()).
+		change(map[string]any{"revoked": false}, map[string]any{"revoked": true}).
+		record(ctx)
+
+	c.JSON(http.StatusOK, accessToken)
+}
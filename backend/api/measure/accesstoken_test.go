@@ -0,0 +1,200 @@
+package measure
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func newTestAccessToken(t *testing.T) *AccessToken {
+	t.Helper()
+
+	accessToken, err := NewAccessToken(uuid.New())
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	return accessToken
+}
+
+func TestAccessTokenValidate(t *testing.T) {
+	accessToken := newTestAccessToken(t)
+	days := 7
+
+	payload := AccessTokenPayload{
+		Name:          " ci ",
+		Scopes:        []string{"app:read", "team:read", "app:read"},
+		ExpiresInDays: &days,
+	}
+
+	if err := accessToken.validate(payload); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if accessToken.name != "ci" {
+		t.Errorf("Expected %q, but got %q", "ci", accessToken.name)
+	}
+
+	if len(accessToken.scopes) != 2 {
+		t.Errorf("Expected duplicate scopes to be dropped, but got %v", accessToken.scopes)
+	}
+
+	if got := accessToken.expiresAt.Sub(accessToken.createdAt); got != 7*24*time.Hour {
+		t.Errorf("Expected expiry of %v, but got %v", 7*24*time.Hour, got)
+	}
+
+	zero, tooLong, overflow := 0, 366, math.MaxInt
+
+	invalid := []AccessTokenPayload{
+		{Name: "", Scopes: []string{"app:read"}},
+		{Name: strings.Repeat("a", maxAccessTokenNameChars+1), Scopes: []string{"app:read"}},
+		{Name: "ci", Scopes: []string{}},
+		{Name: "ci", Scopes: []string{"app:write"}},
+		{Name: "ci", Scopes: []string{"app:read"}, ExpiresInDays: &zero},
+		{Name: "ci", Scopes: []string{"app:read"}, ExpiresInDays: &tooLong},
+		{Name: "ci", Scopes: []string{"app:read"}, ExpiresInDays: &overflow},
+	}
+
+	for _, p := range invalid {
+		if err := newTestAccessToken(t).validate(p); err == nil {
+			t.Errorf("Expected error for %+v, but got nil", p)
+		}
+	}
+}
+
+func TestAccessTokenCheck(t *testing.T) {
+	now := time.Now()
+
+	active := AccessToken{expiresAt: now.Add(time.Hour)}
+	if err := active.check(now); err != nil {
+		t.Errorf("Expected nil error, but got %v", err)
+	}
+
+	expired := AccessToken{expiresAt: now.Add(-time.Hour)}
+	if err := expired.check(now); err != ErrAccessTokenExpired {
+		t.Errorf("Expected %v, but got %v", ErrAccessTokenExpired, err)
+	}
+
+	revoked := AccessToken{expiresAt: now.Add(time.Hour), revokedAt: now}
+	if err := revoked.check(now); err != ErrAccessTokenRevoked {
+		t.Errorf("Expected %v, but got %v", ErrAccessTokenRevoked, err)
+	}
+}
+
+func TestAccessTokenFormat(t *testing.T) {
+	accessToken := newTestAccessToken(t)
+	token := accessToken.String()
+
+	if !isAccessToken(token) {
+		t.Errorf("Expected %q to be a personal access token", token)
+	}
+
+	if isAccessToken("eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9") {
+		t.Error("Expected jwt to not be a personal access token")
+	}
+
+	if !strings.HasPrefix(accessToken.value, accessToken.hint) {
+		t.Errorf("Expected hint %q to prefix the token value", accessToken.hint)
+	}
+}
+
+func TestAccessTokenMarshalJSON(t *testing.T) {
+	accessToken := newTestAccessToken(t)
+	accessToken.scopes = []scope{*ScopeAppRead}
+
+	var got map[string]any
+
+	data, err := json.Marshal(accessToken)
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if got["token"] != accessToken.String() {
+		t.Errorf("Expected token %q, but got %v", accessToken.String(), got["token"])
+	}
+
+	scopes, _ := got["scopes"].([]any)
+	if len(scopes) != 1 || scopes[0] != "app:read" {
+		t.Errorf("Expected scopes [app:read], but got %v", got["scopes"])
+	}
+
+	// stored tokens never reveal
+	// the token value
+	accessToken.value = ""
+
+	data, err = json.Marshal(accessToken)
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	got = nil
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if _, ok := got["token"]; ok {
+		t.Errorf("Expected no token, but got %v", got["token"])
+	}
+}
+
+func TestAccessTokenRouteScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	var got *scope
+	handler := func(c *gin.Context) {
+		got = nil
+		if s, ok := accessTokenRouteScope(c); ok {
+			got = &s
+		}
+	}
+
+	r.GET("/teams", handler)
+	r.POST("/teams", handler)
+	r.GET("/apps/:id/journey", handler)
+	r.GET("/tokens", handler)
+
+	cases := []struct {
+		method   string
+		path     string
+		expected *scope
+	}{
+		{http.MethodGet, "/teams", ScopeTeamRead},
+		{http.MethodPost, "/teams", ScopeTeamAll},
+		{http.MethodGet, "/apps/" + uuid.NewString() + "/journey", ScopeAppRead},
+		{http.MethodGet, "/tokens", nil},
+	}
+
+	for _, tc := range cases {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.method, tc.path, nil))
+
+		if tc.expected == nil {
+			if got != nil {
+				t.Errorf("Expected %s %s to be denied, but got %v", tc.method, tc.path, got)
+			}
+			continue
+		}
+
+		if got == nil || *got != *tc.expected {
+			t.Errorf("Expected scope %v for %s %s, but got %v", tc.expected, tc.method, tc.path, got)
+		}
+	}
+
+	// an app:read token cannot
+	// create or list teams
+	appRead := []scope{*ScopeAppRead}
+	if grants(appRead, *ScopeTeamAll) || grants(appRead, *ScopeTeamRead) {
+		t.Errorf("Expected %v not to grant team scopes", appRead)
+	}
+}
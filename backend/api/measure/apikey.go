@@ -7,6 +7,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
@@ -14,34 +15,89 @@ import (
 	"backend/api/cipher"
 	"backend/api/server"
 
+	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/leporo/sqlf"
 )
 
 const APIKeyPrefix = "msrsh"
 
+// maxAPIKeysPerApp is the maximum number of
+// active api keys an app can have.
+const maxAPIKeysPerApp = 5
+
+// maxAPIKeyNameChars is the maximum number
+// of characters in an api key's name.
+const maxAPIKeyNameChars = 256
+
+// maxAPIKeyOverlap is the longest a revoked
+// api key can keep working to let clients
+// roll over to a new key.
+const maxAPIKeyOverlap = 7 * 24 * time.Hour
+
+// apiKeyLastSeenInterval is the minimum interval
+// between successive writes of an api key's
+// last seen timestamp.
+const apiKeyLastSeenInterval = time.Minute
+
+// ErrAPIKeyRevoked is returned when a
+// revoked api key is presented after its
+// overlap window has elapsed.
+var ErrAPIKeyRevoked = errors.New("api key has been revoked")
+
+// ErrAppDeleted is returned when an api
+// key of a deleted app is presented.
+var ErrAppDeleted = errors.New("app has been deleted")
+
 type APIKey struct {
+	id        uuid.UUID
 	appId     uuid.UUID
+	name      string
 	keyPrefix string
 	keyValue  string
 	checksum  string
 	revoked   bool
+	revokedAt time.Time
+	expiresAt time.Time
+	createdBy *uuid.UUID
 	lastSeen  time.Time
 	createdAt time.Time
 }
 
+type APIKeyPayload struct {
+	Name string `json:"name"`
+}
+
+type APIKeyRevokePayload struct {
+	OverlapHours int `json:"overlap_hours"`
+}
+
 func (a APIKey) MarshalJSON() ([]byte, error) {
 	apiMap := make(map[string]any)
 
+	apiMap["id"] = a.id
+	apiMap["name"] = a.name
 	apiMap["key"] = a.String()
 	apiMap["revoked"] = a.revoked
+	apiMap["created_by"] = a.createdBy
 	apiMap["created_at"] = a.createdAt.Format(chrono.ISOFormatJS)
 	if a.lastSeen.IsZero() {
 		apiMap["last_seen"] = nil
 	} else {
 		apiMap["last_seen"] = a.lastSeen.Format(chrono.ISOFormatJS)
 	}
+	if a.revokedAt.IsZero() {
+		apiMap["revoked_at"] = nil
+	} else {
+		apiMap["revoked_at"] = a.revokedAt.Format(chrono.ISOFormatJS)
+	}
+	if a.expiresAt.IsZero() {
+		apiMap["expires_at"] = nil
+	} else {
+		apiMap["expires_at"] = a.expiresAt.Format(chrono.ISOFormatJS)
+	}
 	return json.Marshal(apiMap)
 }
 
@@ -61,6 +117,7 @@ func NewAPIKey(appId uuid.UUID) (*APIKey, error) {
 	}
 
 	return &APIKey{
+		id:        uuid.New(),
 		appId:     appId,
 		keyPrefix: APIKeyPrefix,
 		keyValue:  byteString,
@@ -70,7 +127,7 @@ func NewAPIKey(appId uuid.UUID) (*APIKey, error) {
 }
 
 func (a *APIKey) saveTx(tx pgx.Tx) error {
-	_, err := tx.Exec(context.Background(), "insert into public.api_keys(app_id, key_prefix, key_value, checksum, created_at) values ($1, $2, $3, $4, $5);", a.appId, a.keyPrefix, a.keyValue, a.checksum, a.createdAt)
+	_, err := tx.Exec(context.Background(), "insert into public.api_keys(id, app_id, name, key_prefix, key_value, checksum, created_by, created_at) values ($1, $2, $3, $4, $5, $6, $7, $8);", a.id, a.appId, a.name, a.keyPrefix, a.keyValue, a.checksum, a.createdBy, a.createdAt)
 
 	if err != nil {
 		return err
@@ -83,7 +140,230 @@ func (a *APIKey) String() string {
 	return fmt.Sprintf("%s_%s_%s", a.keyPrefix, a.keyValue, a.checksum)
 }
 
-func DecodeAPIKey(key string) (*uuid.UUID, error) {
+// active reports if the key is accepted at
+// time t. A revoked key remains active until
+// its overlap window expires.
+func (a APIKey) active(t time.Time) bool {
+	if !a.revoked {
+		return true
+	}
+
+	return !a.expiresAt.IsZero() && t.Before(a.expiresAt)
+}
+
+// validateName trims and validates
+// the api key's name.
+func (a *APIKey) validateName(name string) error {
+	name = strings.TrimSpace(name)
+	if len(name) > maxAPIKeyNameChars {
+		return fmt.Errorf("name cannot be longer than %d characters", maxAPIKeyNameChars)
+	}
+
+	a.name = name
+
+	return nil
+}
+
+// overlap validates the revoke payload and
+// returns the duration the revoked key should
+// keep working for.
+func (p APIKeyRevokePayload) overlap() (time.Duration, error) {
+	if p.OverlapHours < 0 {
+		return 0, errors.New("overlap_hours cannot be negative")
+	}
+
+	overlap := time.Duration(p.OverlapHours) * time.Hour
+	if overlap > maxAPIKeyOverlap {
+		return 0, fmt.Errorf("overlap_hours cannot be more than %d", int(maxAPIKeyOverlap.Hours()))
+	}
+
+	return overlap, nil
+}
+
+// rename updates the api key's name.
+func (a *APIKey) rename(ctx context.Context) error {
+	stmt := sqlf.PostgreSQL.Update("public.api_keys").
+		Set("name", a.name).
+		Where("id = ? and app_id = ?", a.id, a.appId)
+
+	defer stmt.Close()
+
+	_, err := server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+	return err
+}
+
+// revoke revokes the api key. The key keeps
+// working for the overlap duration, if any.
+func (a *APIKey) revoke(ctx context.Context, overlap time.Duration) error {
+	now := time.Now()
+
+	a.revoked = true
+	a.revokedAt = now
+	a.expiresAt = time.Time{}
+
+	var expiresAt *time.Time
+	if overlap > 0 {
+		a.expiresAt = now.Add(overlap)
+		expiresAt = &a.expiresAt
+	}
+
+	stmt := sqlf.PostgreSQL.Update("public.api_keys").
+		Set("revoked", true).
+		Set("revoked_at", now).
+		Set("expires_at", expiresAt).
+		Where("id = ? and app_id = ?", a.id, a.appId)
+
+	defer stmt.Close()
+
+	_, err := server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+	return err
+}
+
+// markSeen updates the api key's last seen
+// timestamp, at most once every interval.
+func (a *APIKey) markSeen(ctx context.Context) error {
+	now := time.Now()
+
+	if !a.lastSeen.IsZero() && now.Sub(a.lastSeen) < apiKeyLastSeenInterval {
+		return nil
+	}
+
+	stmt := sqlf.PostgreSQL.Update("public.api_keys").
+		Set("last_seen", now).
+		Where("id = ?", a.id).
+		Where("(last_seen is null or last_seen < ?)", now.Add(-apiKeyLastSeenInterval))
+
+	defer stmt.Close()
+
+	if _, err := server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...); err != nil {
+		return err
+	}
+
+	a.lastSeen = now
+
+	return nil
+}
+
+// activeAPIKeyJoin joins an app with its most
+// recently minted unrevoked api key.
+const activeAPIKeyJoin = `lateral (select * from public.api_keys where api_keys.app_id = apps.id and api_keys.revoked = false order by api_keys.created_at desc limit 1) as api_keys`
+
+// apiKeyCols are the columns selected
+// for reading an api key.
+var apiKeyCols = []string{
+	"id",
+	"app_id",
+	"name",
+	"key_prefix",
+	"key_value",
+	"checksum",
+	"revoked",
+	"revoked_at",
+	"expires_at",
+	"created_by",
+	"last_seen",
+	"created_at",
+}
+
+// scanAPIKey scans a row selected
+// using apiKeyCols. Columns selected
+// after apiKeyCols are scanned into dest.
+func scanAPIKey(row pgx.Row, dest ...any) (*APIKey, error) {
+	var name pgtype.Text
+	var revoked pgtype.Bool
+	var revokedAt pgtype.Timestamptz
+	var expiresAt pgtype.Timestamptz
+	var lastSeen pgtype.Timestamptz
+
+	a := new(APIKey)
+
+	cols := []any{&a.id, &a.appId, &name, &a.keyPrefix, &a.keyValue, &a.checksum, &revoked, &revokedAt, &expiresAt, &a.createdBy, &lastSeen, &a.createdAt}
+
+	if err := row.Scan(append(cols, dest...)...); err != nil {
+		return nil, err
+	}
+
+	if name.Valid {
+		a.name = name.String
+	}
+
+	if revoked.Valid {
+		a.revoked = revoked.Bool
+	}
+
+	if revokedAt.Valid {
+		a.revokedAt = revokedAt.Time
+	}
+
+	if expiresAt.Valid {
+		a.expiresAt = expiresAt.Time
+	}
+
+	if lastSeen.Valid {
+		a.lastSeen = lastSeen.Time
+	}
+
+	return a, nil
+}
+
+// getAPIKeys fetches all api keys of
+// an app, most recent first.
+func getAPIKeys(ctx context.Context, appId uuid.UUID) (keys []APIKey, err error) {
+	stmt := sqlf.PostgreSQL.Select(strings.Join(apiKeyCols, ",")).
+		From("public.api_keys").
+		Where("app_id = ?", appId).
+		OrderBy("created_at desc")
+
+	defer stmt.Close()
+
+	rows, err := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		a, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, *a)
+	}
+
+	err = rows.Err()
+
+	return
+}
+
+// getAPIKey fetches an app's api key by
+// id. Returns nil if no key is found.
+func getAPIKey(ctx context.Context, appId, keyId uuid.UUID) (*APIKey, error) {
+	stmt := sqlf.PostgreSQL.Select(strings.Join(apiKeyCols, ",")).
+		From("public.api_keys").
+		Where("id = ? and app_id = ?", keyId, appId)
+
+	defer stmt.Close()
+
+	a, err := scanAPIKey(server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// DecodeAPIKey validates the key's format
+// & checksum and looks it up. Returns nil if
+// no key is found, ErrAPIKeyRevoked if
+// the key is no longer active and
+// ErrAppDeleted if the key's app was
+// deleted.
+func DecodeAPIKey(key string) (*APIKey, error) {
 	defaultErr := errors.New("invalid api key")
 
 	if len(key) < 1 {
@@ -113,20 +393,302 @@ func DecodeAPIKey(key string) (*uuid.UUID, error) {
 		return nil, defaultErr
 	}
 
-	stmt := sqlf.PostgreSQL.Select("app_id").
-		From("api_keys").
-		Where("key_value = ? and revoked = ?", nil, nil, nil).
+	// the app's deletion is read along
+	// with the key to avoid another
+	// round trip on every request
+	stmt := sqlf.PostgreSQL.Select(strings.Join(apiKeyCols, ",")).
+		Select("coalesce((select apps.deleted_at is not null from public.apps where apps.id = api_keys.app_id), true)").
+		From("public.api_keys").
+		Where("key_value = ?", value).
 		Limit(1)
 	defer stmt.Close()
 
-	var appId uuid.UUID
+	var deleted bool
 
-	if err := server.Server.PgPool.QueryRow(context.Background(), stmt.String(), value, false, 1).Scan(&appId); err != nil {
+	a, err := scanAPIKey(server.Server.PgPool.QueryRow(context.Background(), stmt.String(), stmt.Args()...), &deleted)
+	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
 		}
 		return nil, err
 	}
 
-	return &appId, nil
+	if !a.active(time.Now()) {
+		return nil, ErrAPIKeyRevoked
+	}
+
+	if deleted {
+		return nil, ErrAppDeleted
+	}
+
+	return a, nil
+}
+
+// getAPIKeyFromParams parses the app and key ids
+// from the route and fetches the key. Writes the
+// error response and returns nil if either fails.
+func getAPIKeyFromParams(c *gin.Context, scope scope) *APIKey {
+	appId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `app id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return nil
+	}
+
+	keyId, err := uuid.Parse(c.Param("keyId"))
+	if err != nil {
+		msg := `api key id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return nil
+	}
+
+	if !authzApp(c, appId, scope) {
+		return nil
+	}
+
+	a, err := getAPIKey(c.Request.Context(), appId, keyId)
+	if err != nil {
+		msg := `failed to fetch api key`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return nil
+	}
+	if a == nil {
+		msg := fmt.Sprintf(`no api key found with id %q`, keyId)
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+		return nil
+	}
+
+	return a
+}
+
+func GetAPIKeys(c *gin.Context) {
+	ctx := c.Request.Context()
+	appId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `app id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if !authzApp(c, appId, *ScopeAppRead) {
+		return
+	}
+
+	keys, err := getAPIKeys(ctx, appId)
+	if err != nil {
+		msg := `failed to fetch api keys`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	if keys == nil {
+		keys = []APIKey{}
+	}
+
+	c.JSON(http.StatusOK, keys)
+}
+
+func CreateAPIKey(c *gin.Context) {
+	ctx := c.Request.Context()
+	userId := c.GetString("userId")
+	appId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `app id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if !authzApp(c, appId, *ScopeAppAll) {
+		return
+	}
+
+	var payload APIKeyPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		msg := `failed to parse api key json payload`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	existing, err := getAPIKeys(ctx, appId)
+	if err != nil {
+		msg := `failed to fetch api keys`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	now := time.Now()
+	count := 0
+	for _, k := range existing {
+		if k.active(now) {
+			count++
+		}
+	}
+
+	if count >= maxAPIKeysPerApp {
+		msg := fmt.Sprintf(`app cannot have more than %d active api keys`, maxAPIKeysPerApp)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	apiKey, err := NewAPIKey(appId)
+	if err != nil {
+		msg := `failed to create api key`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	if err := apiKey.validateName(payload.Name); err != nil {
+		msg := `api key validation failed`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg, "details": err.Error()})
+		return
+	}
+
+	createdBy := uuid.MustParse(userId)
+	apiKey.createdBy = &createdBy
+
+	tx, err := server.Server.PgPool.Begin(ctx)
+	if err != nil {
+		msg := `failed to create api key`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err := apiKey.saveTx(tx); err != nil {
+		msg := `failed to create api key`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		msg := `failed to create api key`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	newAuditLog(c, auditAPIKeyCreated).
+		inAppTeam(appId).
+		on(auditTargetAPIKey, apiKey.id.String()).
+		change(nil, map[string]any{"name": apiKey.name}).
+		record(ctx)
+
+	c.JSON(http.StatusCreated, apiKey)
+}
+
+func UpdateAPIKey(c *gin.Context) {
+	ctx := c.Request.Context()
+	apiKey := getAPIKeyFromParams(c, *ScopeAppAll)
+	if apiKey == nil {
+		return
+	}
+
+	var payload APIKeyPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		msg := `failed to parse api key json payload`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := apiKey.validateName(payload.Name); err != nil {
+		msg := `api key validation failed`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg, "details": err.Error()})
+		return
+	}
+
+	if err := apiKey.rename(ctx); err != nil {
+		msg := `failed to update api key`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	c.JSON(http.StatusOK, apiKey)
+}
+
+func RevokeAPIKey(c *gin.Context) {
+	ctx := c.Request.Context()
+	apiKey := getAPIKeyFromParams(c, *ScopeAppAll)
+	if apiKey == nil {
+		return
+	}
+
+	// body is optional, revoke
+	// immediately when absent
+	var payload APIKeyRevokePayload
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			msg := `failed to parse api key revoke json payload`
+			fmt.Println(msg, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+
+	overlap, err := payload.overlap()
+	if err != nil {
+		msg := `api key validation failed`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg, "details": err.Error()})
+		return
+	}
+
+	if apiKey.revoked {
+		msg := fmt.Sprintf(`api key %q is already revoked`, apiKey.id)
+		c.JSON(http.StatusConflict, gin.H{"error": msg})
+		return
+	}
+
+	keys, err := getAPIKeys(ctx, apiKey.appId)
+	if err != nil {
+		msg := `failed to fetch api keys`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	// revoking the last key would leave
+	// the app unable to ingest anything
+	others := 0
+	for _, k := range keys {
+		if k.id != apiKey.id && !k.revoked {
+			others++
+		}
+	}
+
+	if others == 0 {
+		msg := `app must have at least one unrevoked api key, create a new key before revoking this one`
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := apiKey.revoke(ctx, overlap); err != nil {
+		msg := `failed to revoke api key`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	newAuditLog(c, auditAPIKeyRevoked).
+		inAppTeam(apiKey.appId).
+		on(auditTargetAPIKey, apiKey.id.String()).
+		change(map[string]any{"revoked": false}, map[string]any{"revoked": true}).
+		record(ctx)
+
+	c.JSON(http.StatusOK, apiKey)
 }
@@ -0,0 +1,109 @@
+package measure
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestAPIKeyActive(t *testing.T) {
+	now := time.Now()
+
+	cases := []struct {
+		name     string
+		key      APIKey
+		expected bool
+	}{
+		{"unrevoked", APIKey{}, true},
+		{"revoked without overlap", APIKey{revoked: true, revokedAt: now}, false},
+		{"revoked within overlap", APIKey{revoked: true, revokedAt: now, expiresAt: now.Add(time.Hour)}, true},
+		{"revoked after overlap", APIKey{revoked: true, revokedAt: now.Add(-2 * time.Hour), expiresAt: now.Add(-time.Hour)}, false},
+	}
+
+	for _, c := range cases {
+		if got := c.key.active(now); got != c.expected {
+			t.Errorf("%s: expected %v, but got %v", c.name, c.expected, got)
+		}
+	}
+}
+
+func TestAPIKeyRevokeOverlap(t *testing.T) {
+	overlap, err := APIKeyRevokePayload{}.overlap()
+	if err != nil || overlap != 0 {
+		t.Errorf("Expected no overlap, but got %v, %v", overlap, err)
+	}
+
+	overlap, err = APIKeyRevokePayload{OverlapHours: 24}.overlap()
+	if err != nil || overlap != 24*time.Hour {
+		t.Errorf("Expected %v overlap, but got %v, %v", 24*time.Hour, overlap, err)
+	}
+
+	if _, err := (APIKeyRevokePayload{OverlapHours: -1}).overlap(); err == nil {
+		t.Error("Expected error for negative overlap, but got nil")
+	}
+
+	if _, err := (APIKeyRevokePayload{OverlapHours: 169}).overlap(); err == nil {
+		t.Error("Expected error for overlap longer than a week, but got nil")
+	}
+}
+
+func TestAPIKeyValidateName(t *testing.T) {
+	apiKey := APIKey{}
+
+	if err := apiKey.validateName("  ci  "); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if apiKey.name != "ci" {
+		t.Errorf("Expected %q, but got %q", "ci", apiKey.name)
+	}
+
+	if err := apiKey.validateName(strings.Repeat("a", maxAPIKeyNameChars+1)); err == nil {
+		t.Error("Expected error for long name, but got nil")
+	}
+}
+
+func TestAPIKeyMarshalJSON(t *testing.T) {
+	apiKey, err := NewAPIKey(uuid.New())
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	apiKey.name = "release"
+	apiKey.revoked = true
+	apiKey.revokedAt = apiKey.createdAt
+	apiKey.expiresAt = apiKey.createdAt.Add(time.Hour)
+
+	data, err := json.Marshal(apiKey)
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if got["id"] != apiKey.id.String() {
+		t.Errorf("Expected id %q, but got %v", apiKey.id, got["id"])
+	}
+
+	if got["key"] != apiKey.String() {
+		t.Errorf("Expected key %q, but got %v", apiKey.String(), got["key"])
+	}
+
+	if got["name"] != "release" {
+		t.Errorf("Expected name %q, but got %v", "release", got["name"])
+	}
+
+	if got["expires_at"] == nil || got["revoked_at"] == nil {
+		t.Errorf("Expected revocation timestamps, but got %v", got)
+	}
+
+	if got["last_seen"] != nil {
+		t.Errorf("Expected nil last seen, but got %v", got["last_seen"])
+	}
+}
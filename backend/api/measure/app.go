@@ -98,9 +98,16 @@ func (a App) GetExceptionGroup(ctx context.Context, id uuid.UUID) (exceptionGrou
 		Select(`file_name`).
 		Select(`line_number`).
 		Select("fingerprint").
+		Select("fingerprint_version").
 		Select("first_event_timestamp").
 		Select("created_at").
 		Select("updated_at").
+		Select(group.StatusExpr+" as status").
+		Select("resolved_in_version").
+		Select("resolved_in_version_code").
+		Select("muted_until").
+		Select("regressed_at").
+		Select("merged_into").
 		Where("app_id = ?", a.ID).
 		Where("id = ?", id)
 
@@ -121,10 +128,14 @@ func (a App) GetExceptionGroup(ctx context.Context, id uuid.UUID) (exceptionGrou
 
 	exceptionGroup = &row
 
+	if err = exceptionGroup.LoadMerged(ctx); err != nil {
+		return nil, err
+	}
+
 	// Get list of event IDs
 	eventDataStmt := sqlf.From(`default.events`).
 		Select(`id`).
-		Where(`exception.fingerprint = (?)`, exceptionGroup.Fingerprint)
+		Where(`(exception.fingerprint in ? or native_crash.fingerprint in ?)`, exceptionGroup.Fingerprints, exceptionGroup.Fingerprints)
 
 	eventDataRows, err := server.Server.ChPool.Query(ctx, eventDataStmt.String(), eventDataStmt.Args()...)
 	if err != nil {
@@ -164,9 +175,16 @@ func (a App) GetExceptionGroupByFingerprint(ctx context.Context, fingerprint str
 		Select(`file_name`).
 		Select(`line_number`).
 		Select("fingerprint").
+		Select("fingerprint_version").
 		Select("first_event_timestamp").
 		Select("created_at").
 		Select("updated_at").
+		Select(group.StatusExpr+" as status").
+		Select("resolved_in_version").
+		Select("resolved_in_version_code").
+		Select("muted_until").
+		Select("regressed_at").
+		Select("merged_into").
 		Where("app_id = ?", a.ID).
 		Where("fingerprint = ?", fingerprint)
 
@@ -186,10 +204,20 @@ func (a App) GetExceptionGroupByFingerprint(ctx context.Context, fingerprint str
 
 	exceptionGroup = &row
 
+	// events of merged groups land
+	// in the surviving group
+	if exceptionGroup.IsMerged() {
+		return a.GetExceptionGroup(ctx, *exceptionGroup.MergedInto)
+	}
+
+	if err = exceptionGroup.LoadMerged(ctx); err != nil {
+		return nil, err
+	}
+
 	// Get list of event IDs
 	eventDataStmt := sqlf.From(`default.events`).
 		Select(`id`).
-		Where(`exception.fingerprint = ?`, exceptionGroup.Fingerprint)
+		Where(`(exception.fingerprint in ? or native_crash.fingerprint in ?)`, exceptionGroup.Fingerprints, exceptionGroup.Fingerprints)
 
 	eventDataRows, err := server.Server.ChPool.Query(ctx, eventDataStmt.String(), eventDataStmt.Args()...)
 	if err != nil {
@@ -230,28 +258,46 @@ func (a App) GetExceptionGroupsWithFilter(ctx context.Context, af *filter.AppFil
 		Select(`file_name`).
 		Select(`line_number`).
 		Select("fingerprint").
+		Select("fingerprint_version").
 		Select("first_event_timestamp").
 		Select("created_at").
 		Select("updated_at").
-		Where("app_id = ?", a.ID)
+		Select(group.StatusExpr+" as status").
+		Select("resolved_in_version").
+		Select("resolved_in_version_code").
+		Select("muted_until").
+		Select("regressed_at").
+		Select("merged_into").
+		Where("app_id = ?", a.ID).
+		Where("merged_into is null")
 
 	defer stmt.Close()
 
+	if len(af.GroupStatuses) > 0 {
+		stmt.Where(group.StatusExpr+" = any(?)", af.GroupStatuses)
+	}
+
 	rows, _ := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
 	groups, err = pgx.CollectRows(rows, pgx.RowToStructByNameLax[group.ExceptionGroup])
 	if err != nil {
 		return
 	}
 
+	merged, err := group.MergedExceptionFingerprints(ctx, *a.ID)
+	if err != nil {
+		return
+	}
+
 	var exceptionGroup *group.ExceptionGroup
 	for i := range groups {
 		exceptionGroup = &groups[i]
+		exceptionGroup.Fingerprints = append([]string{exceptionGroup.Fingerprint}, merged[exceptionGroup.ID]...)
 
 		eventDataStmt := sqlf.
 			From("default.events").
 			Select("id").
 			Where("app_id in ?", af.AppID).
-			Where("exception.fingerprint = ?", exceptionGroup.Fingerprint)
+			Where("(exception.fingerprint in ? or native_crash.fingerprint in ?)", exceptionGroup.Fingerprints, exceptionGroup.Fingerprints)
 
 		defer eventDataStmt.Close()
 
@@ -299,6 +345,8 @@ func (a App) GetExceptionGroupsWithFilter(ctx context.Context, af *filter.AppFil
 			eventDataStmt.Where("attribute.network_generation").In(af.NetworkGenerations)
 		}
 
+		filterUserDefinedAttrs(eventDataStmt, af)
+
 		if af.HasTimeRange() {
 			eventDataStmt.Where("timestamp >= ? and timestamp <= ?", af.From, af.To)
 		}
@@ -343,9 +391,16 @@ func (a App) GetANRGroup(ctx context.Context, id uuid.UUID) (anrGroup *group.ANR
 		Select(`file_name`).
 		Select(`line_number`).
 		Select("fingerprint").
+		Select("fingerprint_version").
 		Select("first_event_timestamp").
 		Select("created_at").
 		Select("updated_at").
+		Select(group.StatusExpr+" as status").
+		Select("resolved_in_version").
+		Select("resolved_in_version_code").
+		Select("muted_until").
+		Select("regressed_at").
+		Select("merged_into").
 		Where("app_id = ?", a.ID).
 		Where("id = ?", id)
 
@@ -366,10 +421,14 @@ func (a App) GetANRGroup(ctx context.Context, id uuid.UUID) (anrGroup *group.ANR
 
 	anrGroup = &row
 
+	if err = anrGroup.LoadMerged(ctx); err != nil {
+		return nil, err
+	}
+
 	// Get list of event IDs
 	eventDataStmt := sqlf.From(`default.events`).
 		Select(`id`).
-		Where(`anr.fingerprint = ?`, anrGroup.Fingerprint)
+		Where(`anr.fingerprint in ?`, anrGroup.Fingerprints)
 
 	eventDataRows, err := server.Server.ChPool.Query(ctx, eventDataStmt.String(), eventDataStmt.Args()...)
 	if err != nil {
@@ -409,9 +468,16 @@ func (a App) GetANRGroupByFingerprint(ctx context.Context, fingerprint string) (
 		Select(`file_name`).
 		Select(`line_number`).
 		Select("fingerprint").
+		Select("fingerprint_version").
 		Select("first_event_timestamp").
 		Select("created_at").
 		Select("updated_at").
+		Select(group.StatusExpr+" as status").
+		Select("resolved_in_version").
+		Select("resolved_in_version_code").
+		Select("muted_until").
+		Select("regressed_at").
+		Select("merged_into").
 		Where("app_id = ?", a.ID).
 		Where("fingerprint = ?", fingerprint)
 
@@ -431,10 +497,20 @@ func (a App) GetANRGroupByFingerprint(ctx context.Context, fingerprint string) (
 
 	anrGroup = &row
 
+	// events of merged groups land
+	// in the surviving group
+	if anrGroup.IsMerged() {
+		return a.GetANRGroup(ctx, *anrGroup.MergedInto)
+	}
+
+	if err = anrGroup.LoadMerged(ctx); err != nil {
+		return nil, err
+	}
+
 	// Get list of event IDs
 	eventDataStmt := sqlf.From(`default.events`).
 		Select(`id`).
-		Where(`anr.fingerprint = ?`, anrGroup.Fingerprint)
+		Where(`anr.fingerprint in ?`, anrGroup.Fingerprints)
 
 	eventDataRows, err := server.Server.ChPool.Query(ctx, eventDataStmt.String(), eventDataStmt.Args()...)
 	if err != nil {
@@ -474,28 +550,46 @@ func (a App) GetANRGroupsWithFilter(ctx context.Context, af *filter.AppFilter) (
 		Select(`file_name`).
 		Select(`line_number`).
 		Select("fingerprint").
+		Select("fingerprint_version").
 		Select("first_event_timestamp").
 		Select("created_at").
 		Select("updated_at").
-		Where("app_id = ?", a.ID)
+		Select(group.StatusExpr+" as status").
+		Select("resolved_in_version").
+		Select("resolved_in_version_code").
+		Select("muted_until").
+		Select("regressed_at").
+		Select("merged_into").
+		Where("app_id = ?", a.ID).
+		Where("merged_into is null")
 
 	defer stmt.Close()
 
+	if len(af.GroupStatuses) > 0 {
+		stmt.Where(group.StatusExpr+" = any(?)", af.GroupStatuses)
+	}
+
 	rows, _ := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
 	groups, err = pgx.CollectRows(rows, pgx.RowToStructByNameLax[group.ANRGroup])
 	if err != nil {
 		return
 	}
 
+	merged, err := group.MergedANRFingerprints(ctx, *a.ID)
+	if err != nil {
+		return
+	}
+
 	var anrGroup *group.ANRGroup
 	for i := range groups {
 		anrGroup = &groups[i]
+		anrGroup.Fingerprints = append([]string{anrGroup.Fingerprint}, merged[anrGroup.ID]...)
 
 		eventDataStmt := sqlf.
 			From("default.events").
 			Select("id").
 			Where("app_id = ?", af.AppID).
-			Where("anr.fingerprint = ?", anrGroup.Fingerprint)
+			Where("anr.fingerprint in ?", anrGroup.Fingerprints)
 
 		defer eventDataStmt.Close()
 
@@ -543,6 +637,8 @@ func (a App) GetANRGroupsWithFilter(ctx context.Context, af *filter.AppFilter) (
 			eventDataStmt.Where("attribute.network_generation").In(af.NetworkGenerations)
 		}
 
+		filterUserDefinedAttrs(eventDataStmt, af)
+
 		if af.HasTimeRange() {
 			eventDataStmt.Where("timestamp >= ? and timestamp <= ?", af.From, af.To)
 		}
@@ -667,7 +763,7 @@ func (a App) GetCrashFreeMetrics(ctx context.Context, af *filter.AppFilter, vers
 		With("t2",
 			sqlf.From("all_sessions").
 				Select("count(distinct session_id) as count_exception_selected").
-				Where("`type` = 'exception' and `exception.handled` = false").
+				Where("((`type` = 'exception' and `exception.handled` = false) or `type` = 'native_crash')").
 				Where("`attribute.app_version` in ? and `attribute.app_build` in ?", af.Versions, af.VersionCodes))
 
 	defer stmt.Close()
@@ -687,7 +783,7 @@ func (a App) GetCrashFreeMetrics(ctx context.Context, af *filter.AppFilter, vers
 					Where("attribute.app_version in ? and attribute.app_build in ?", versions.Versions(), versions.Codes())).
 			With("t4", sqlf.From("all_sessions").
 				Select("count(distinct session_id) as count_exception_unselected").
-				Where("`type` = 'exception' and `exception.handled` = false").
+				Where("((`type` = 'exception' and `exception.handled` = false) or `type` = 'native_crash')").
 				Where("`attribute.app_version` in ? and `attribute.app_build` in ?", versions.Versions(), versions.Codes())).
 			Select("round((1 - (t2.count_exception_selected / t1.total_sessions_selected)) * 100, 2) as crash_free_sessions_selected").
 			Select("round((1 - (t4.count_exception_unselected / t3.total_sessions_unselected)) * 100, 2) as crash_free_sessions_unselected").
@@ -733,7 +829,7 @@ func (a App) GetPerceivedCrashFreeMetrics(ctx context.Context, af *filter.AppFil
 	stmt := sqlf.
 		With("all_sessions",
 			sqlf.From("default.events").
-				Select("session_id, attribute.app_version, attribute.app_build, type, exception.handled, exception.foreground").
+				Select("session_id, attribute.app_version, attribute.app_build, type, exception.handled, exception.foreground, native_crash.foreground").
 				Where(`app_id = ? and timestamp >= ? and timestamp <= ?`, af.AppID, af.From, af.To)).
 		With("t1",
 			sqlf.From("all_sessions").
@@ -742,7 +838,7 @@ func (a App) GetPerceivedCrashFreeMetrics(ctx context.Context, af *filter.AppFil
 		With("t2",
 			sqlf.From("all_sessions").
 				Select("count(distinct session_id) as count_exception_selected").
-				Where("`type` = 'exception' and `exception.handled` = false and `exception.foreground` = true").
+				Where("((`type` = 'exception' and `exception.handled` = false and `exception.foreground` = true) or (`type` = 'native_crash' and `native_crash.foreground` = true))").
 				Where("`attribute.app_version` in ? and `attribute.app_build` in ?", af.Versions, af.VersionCodes))
 
 	defer stmt.Close()
@@ -762,7 +858,7 @@ func (a App) GetPerceivedCrashFreeMetrics(ctx context.Context, af *filter.AppFil
 					Where("attribute.app_version in ? and attribute.app_build in ?", versions.Versions(), versions.Codes())).
 			With("t4", sqlf.From("all_sessions").
 				Select("count(distinct session_id) as count_exception_unselected").
-				Where("`type` = 'exception' and `exception.handled` = false").
+				Where("((`type` = 'exception' and `exception.handled` = false) or `type` = 'native_crash')").
 				Where("`attribute.app_version` in ? and `attribute.app_build` in ?", versions.Versions(), versions.Codes())).
 			Select("round((1 - (t2.count_exception_selected / t1.total_sessions_selected)) * 100, 2) as crash_free_sessions_selected").
 			Select("round((1 - (t4.count_exception_unselected / t3.total_sessions_unselected)) * 100, 2) as crash_free_sessions_unselected").
@@ -1145,6 +1241,8 @@ func (a App) getJourneyEvents(ctx context.Context, af *filter.AppFilter, opts fi
 		stmt.Where("`attribute.network_generation` in ?", af.NetworkGenerations)
 	}
 
+	filterUserDefinedAttrs(stmt, af)
+
 	stmt.OrderBy(`timestamp`)
 
 	defer stmt.Close()
@@ -1249,6 +1347,8 @@ func (a *App) getWithTeam(id uuid.UUID) (*App, error) {
 	var firstVersion pgtype.Text
 	var onboarded pgtype.Bool
 	var onboardedAt pgtype.Timestamptz
+	var apiKeyId *uuid.UUID
+	var apiKeyName pgtype.Text
 	var apiKeyLastSeen pgtype.Timestamptz
 	var apiKeyCreatedAt pgtype.Timestamptz
 	var createdAt pgtype.Timestamptz
@@ -1263,6 +1363,8 @@ func (a *App) getWithTeam(id uuid.UUID) (*App, error) {
 		"apps.first_version",
 		"apps.onboarded",
 		"apps.onboarded_at",
+		"api_keys.id",
+		"api_keys.name",
 		"api_keys.key_prefix",
 		"api_keys.key_value",
 		"api_keys.checksum",
@@ -1275,8 +1377,9 @@ func (a *App) getWithTeam(id uuid.UUID) (*App, error) {
 	stmt := sqlf.PostgreSQL.
 		Select(strings.Join(cols, ",")).
 		From("public.apps").
-		LeftJoin("public.api_keys", "api_keys.app_id = apps.id").
-		Where("apps.id = ? and apps.team_id = ?", nil, nil)
+		LeftJoin(activeAPIKeyJoin, "true").
+		Where("apps.id = ? and apps.team_id = ?", nil, nil).
+		Where("apps.deleted_at is null")
 
 	defer stmt.Close()
 
@@ -1287,6 +1390,8 @@ func (a *App) getWithTeam(id uuid.UUID) (*App, error) {
 		&firstVersion,
 		&onboarded,
 		&onboardedAt,
+		&apiKeyId,
+		&apiKeyName,
 		&apiKey.keyPrefix,
 		&apiKey.keyValue,
 		&apiKey.checksum,
@@ -1334,6 +1439,15 @@ func (a *App) getWithTeam(id uuid.UUID) (*App, error) {
 		a.OnboardedAt = onboardedAt.Time
 	}
 
+	if apiKeyId != nil {
+		apiKey.id = *apiKeyId
+		apiKey.appId = id
+	}
+
+	if apiKeyName.Valid {
+		apiKey.name = apiKeyName.String
+	}
+
 	if apiKeyLastSeen.Valid {
 		apiKey.lastSeen = apiKeyLastSeen.Time
 	}
@@ -1361,7 +1475,7 @@ func (a *App) getTeam(ctx context.Context) (*Team, error) {
 	stmt := sqlf.PostgreSQL.
 		Select("team_id").
 		From("apps").
-		Where("id = ?", nil)
+		Where("id = ? and deleted_at is null", nil)
 	defer stmt.Close()
 
 	if err := server.Server.PgPool.QueryRow(ctx, stmt.String(), a.ID).Scan(&team.ID); err != nil {
@@ -1432,6 +1546,7 @@ func (a *App) GetSessionEvents(ctx context.Context, sessionId uuid.UUID) (*Sessi
 		`toString(attribute.network_type)`,
 		`toString(attribute.network_generation)`,
 		`toString(attribute.network_provider)`,
+		`user_defined_attribute`,
 		`anr.fingerprint`,
 		`anr.foreground`,
 		`anr.exceptions`,
@@ -1441,6 +1556,14 @@ func (a *App) GetSessionEvents(ctx context.Context, sessionId uuid.UUID) (*Sessi
 		`exception.foreground`,
 		`exception.exceptions`,
 		`exception.threads`,
+		`toString(native_crash.signal)`,
+		`toString(native_crash.signal_code)`,
+		`native_crash.fault_address`,
+		`native_crash.abort_message`,
+		`native_crash.fingerprint`,
+		`native_crash.foreground`,
+		`native_crash.frames`,
+		`native_crash.threads`,
 		`toString(app_exit.reason)`,
 		`toString(app_exit.importance)`,
 		`app_exit.trace`,
@@ -1553,6 +1676,20 @@ func (a *App) GetSessionEvents(ctx context.Context, sessionId uuid.UUID) (*Sessi
 		`toString(navigation.to)`,
 		`toString(navigation.from)`,
 		`toString(navigation.source)`,
+		`toString(span.trace_id)`,
+		`toString(span.span_id)`,
+		`span.parent_id`,
+		`span.name`,
+		`toString(span.kind)`,
+		`span.status`,
+		`span.status_message`,
+		`span.start_time`,
+		`span.end_time`,
+		`span_attributes`,
+		`custom.name`,
+		`custom_string_properties`,
+		`custom_number_properties`,
+		`custom_bool_properties`,
 	}
 
 	stmt := sqlf.From("default.events")
@@ -1579,6 +1716,9 @@ func (a *App) GetSessionEvents(ctx context.Context, sessionId uuid.UUID) (*Sessi
 		var exception event.Exception
 		var exceptionExceptions string
 		var exceptionThreads string
+		var nativeCrash event.NativeCrash
+		var nativeCrashFrames string
+		var nativeCrashThreads string
 		var anrExceptions string
 		var anrThreads string
 		var attachments string
@@ -1601,6 +1741,11 @@ func (a *App) GetSessionEvents(ctx context.Context, sessionId uuid.UUID) (*Sessi
 		var trimMemory event.TrimMemory
 		var cpuUsage event.CPUUsage
 		var navigation event.Navigation
+		var span event.Span
+		var custom event.Custom
+		var customStringProperties map[string]string
+		var customNumberProperties map[string]float64
+		var customBoolProperties map[string]bool
 
 		var coldLaunchDuration uint32
 		var warmLaunchDuration uint32
@@ -1643,6 +1788,7 @@ func (a *App) GetSessionEvents(ctx context.Context, sessionId uuid.UUID) (*Sessi
 			&ev.Attribute.NetworkType,
 			&ev.Attribute.NetworkGeneration,
 			&ev.Attribute.NetworkProvider,
+			&ev.Attribute.UserDefinedAttrs,
 
 			// anr
 			&anr.Fingerprint,
@@ -1656,6 +1802,14 @@ func (a *App) GetSessionEvents(ctx context.Context, sessionId uuid.UUID) (*Sessi
 			&exception.Foreground,
 			&exceptionExceptions,
 			&exceptionThreads,
+			&nativeCrash.Signal,
+			&nativeCrash.SignalCode,
+			&nativeCrash.FaultAddress,
+			&nativeCrash.AbortMessage,
+			&nativeCrash.Fingerprint,
+			&nativeCrash.Foreground,
+			&nativeCrashFrames,
+			&nativeCrashThreads,
 
 			// app exit
 			&appExit.Reason,
@@ -1804,6 +1958,24 @@ func (a *App) GetSessionEvents(ctx context.Context, sessionId uuid.UUID) (*Sessi
 			&navigation.To,
 			&navigation.From,
 			&navigation.Source,
+
+			// span
+			&span.TraceID,
+			&span.SpanID,
+			&span.ParentID,
+			&span.Name,
+			&span.Kind,
+			&span.Status,
+			&span.StatusMessage,
+			&span.StartTime,
+			&span.EndTime,
+			&span.Attributes,
+
+			// custom
+			&custom.Name,
+			&customStringProperties,
+			&customNumberProperties,
+			&customBoolProperties,
 		}
 
 		if err := rows.Scan(dest...); err != nil {
@@ -1835,6 +2007,18 @@ func (a *App) GetSessionEvents(ctx context.Context, sessionId uuid.UUID) (*Sessi
 			}
 			ev.Exception = &exception
 			session.Events = append(session.Events, ev)
+		case event.TypeNativeCrash:
+			if err := json.Unmarshal([]byte(nativeCrashFrames), &nativeCrash.Frames); err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal([]byte(nativeCrashThreads), &nativeCrash.Threads); err != nil {
+				return nil, err
+			}
+			if err := json.Unmarshal([]byte(attachments), &ev.Attachments); err != nil {
+				return nil, err
+			}
+			ev.NativeCrash = &nativeCrash
+			session.Events = append(session.Events, ev)
 		case event.TypeAppExit:
 			ev.AppExit = &appExit
 			session.Events = append(session.Events, ev)
@@ -1892,6 +2076,22 @@ func (a *App) GetSessionEvents(ctx context.Context, sessionId uuid.UUID) (*Sessi
 		case event.TypeNavigation:
 			ev.Navigation = &navigation
 			session.Events = append(session.Events, ev)
+		case event.TypeSpan:
+			ev.Span = &span
+			session.Events = append(session.Events, ev)
+		case event.TypeCustom:
+			custom.Properties = make(map[string]any)
+			for key, value := range customStringProperties {
+				custom.Properties[key] = value
+			}
+			for key, value := range customNumberProperties {
+				custom.Properties[key] = value
+			}
+			for key, value := range customBoolProperties {
+				custom.Properties[key] = value
+			}
+			ev.Custom = &custom
+			session.Events = append(session.Events, ev)
 		default:
 			continue
 		}
@@ -1927,12 +2127,13 @@ func SelectApp(ctx context.Context, id uuid.UUID) (app *App, err error) {
 
 	stmt := sqlf.PostgreSQL.
 		Select("id").
+		Select("team_id").
 		Select("onboarded").
 		Select("unique_identifier").
 		Select("platform").
 		Select("first_version").
 		From("public.apps").
-		Where("id = ?", id)
+		Where("id = ? and deleted_at is null", id)
 
 	defer stmt.Close()
 
@@ -1940,7 +2141,7 @@ func SelectApp(ctx context.Context, id uuid.UUID) (app *App, err error) {
 		app = &App{}
 	}
 
-	if err := server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&app.ID, &onboarded, &uniqueId, &platform, &firstVersion); err != nil {
+	if err := server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&app.ID, &app.TeamId, &onboarded, &uniqueId, &platform, &firstVersion); err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, nil
 		} else {
@@ -2030,39 +2231,7 @@ func GetAppJourney(c *gin.Context) {
 		ID: &id,
 	}
 
-	team, err := app.getTeam(ctx)
-	if err != nil {
-		msg := "failed to get team from app id"
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-	if team == nil {
-		msg := fmt.Sprintf("no team exists for app [%s]", app.ID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
-		return
-	}
-
-	userId := c.GetString("userId")
-	okTeam, err := PerformAuthz(userId, team.ID.String(), *ScopeTeamRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	okApp, err := PerformAuthz(userId, team.ID.String(), *ScopeAppRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	if !okTeam || !okApp {
-		msg := `you are not authorized to access this app`
-		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+	if !authzApp(c, id, *ScopeAppRead) {
 		return
 	}
 
@@ -2264,39 +2433,7 @@ func GetAppMetrics(c *gin.Context) {
 		ID: &id,
 	}
 
-	team, err := app.getTeam(ctx)
-	if err != nil {
-		msg := "failed to get team from app id"
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-	if team == nil {
-		msg := fmt.Sprintf("no team exists for app [%s]", app.ID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
-		return
-	}
-
-	userId := c.GetString("userId")
-	okTeam, err := PerformAuthz(userId, team.ID.String(), *ScopeTeamRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	okApp, err := PerformAuthz(userId, team.ID.String(), *ScopeAppRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	if !okTeam || !okApp {
-		msg := `you are not authorized to access this app`
-		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+	if !authzApp(c, id, *ScopeAppRead) {
 		return
 	}
 
@@ -2432,43 +2569,7 @@ func GetAppFilters(c *gin.Context) {
 		return
 	}
 
-	app := App{
-		ID: &id,
-	}
-
-	team, err := app.getTeam(ctx)
-	if err != nil {
-		msg := "failed to get team from app id"
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-	if team == nil {
-		msg := fmt.Sprintf("no team exists for app [%s]", app.ID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
-		return
-	}
-
-	userId := c.GetString("userId")
-	okTeam, err := PerformAuthz(userId, team.ID.String(), *ScopeTeamRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	okApp, err := PerformAuthz(userId, team.ID.String(), *ScopeAppRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	if !okTeam || !okApp {
-		msg := `you are not authorized to access this app`
-		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+	if !authzApp(c, id, *ScopeAppRead) {
 		return
 	}
 
@@ -2496,15 +2597,16 @@ func GetAppFilters(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"versions":             versions,
-		"os_versions":          osVersions,
-		"countries":            fl.Countries,
-		"network_providers":    fl.NetworkProviders,
-		"network_types":        fl.NetworkTypes,
-		"network_generations":  fl.NetworkGenerations,
-		"locales":              fl.DeviceLocales,
-		"device_manufacturers": fl.DeviceManufacturers,
-		"device_names":         fl.DeviceNames,
+		"versions":                versions,
+		"os_versions":             osVersions,
+		"countries":               fl.Countries,
+		"network_providers":       fl.NetworkProviders,
+		"network_types":           fl.NetworkTypes,
+		"network_generations":     fl.NetworkGenerations,
+		"locales":                 fl.DeviceLocales,
+		"device_manufacturers":    fl.DeviceManufacturers,
+		"device_names":            fl.DeviceNames,
+		"user_defined_attributes": fl.UserDefinedAttrs,
 	})
 }
 
@@ -2557,39 +2659,8 @@ func GetCrashOverview(c *gin.Context) {
 	app := App{
 		ID: &id,
 	}
-	team, err := app.getTeam(ctx)
-	if err != nil {
-		msg := "failed to get team from app id"
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-	if team == nil {
-		msg := fmt.Sprintf("no team exists for app [%s]", app.ID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
-		return
-	}
-
-	userId := c.GetString("userId")
-	okTeam, err := PerformAuthz(userId, team.ID.String(), *ScopeTeamRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	okApp, err := PerformAuthz(userId, team.ID.String(), *ScopeAppRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
 
-	if !okTeam || !okApp {
-		msg := `you are not authorized to access this app`
-		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+	if !authzApp(c, id, *ScopeAppRead) {
 		return
 	}
 
@@ -2679,42 +2750,7 @@ func GetCrashOverviewPlotInstances(c *gin.Context) {
 		af.SetDefaultTimeRange()
 	}
 
-	app := App{
-		ID: &id,
-	}
-	team, err := app.getTeam(ctx)
-	if err != nil {
-		msg := "failed to get team from app id"
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-	if team == nil {
-		msg := fmt.Sprintf("no team exists for app [%s]", app.ID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
-		return
-	}
-
-	userId := c.GetString("userId")
-	okTeam, err := PerformAuthz(userId, team.ID.String(), *ScopeTeamRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	okApp, err := PerformAuthz(userId, team.ID.String(), *ScopeAppRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	if !okTeam || !okApp {
-		msg := `you are not authorized to access this app`
-		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+	if !authzApp(c, id, *ScopeAppRead) {
 		return
 	}
 
@@ -2812,39 +2848,8 @@ func GetCrashDetailCrashes(c *gin.Context) {
 	app := App{
 		ID: &id,
 	}
-	team, err := app.getTeam(ctx)
-	if err != nil {
-		msg := "failed to get team from app id"
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-	if team == nil {
-		msg := fmt.Sprintf("no team exists for app [%s]", app.ID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
-		return
-	}
-
-	userId := c.GetString("userId")
-	okTeam, err := PerformAuthz(userId, team.ID.String(), *ScopeTeamRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	okApp, err := PerformAuthz(userId, team.ID.String(), *ScopeAppRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
 
-	if !okTeam || !okApp {
-		msg := `you are not authorized to access this app`
-		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+	if !authzApp(c, id, *ScopeAppRead) {
 		return
 	}
 
@@ -2957,39 +2962,8 @@ func GetCrashDetailPlotInstances(c *gin.Context) {
 	app := App{
 		ID: &id,
 	}
-	team, err := app.getTeam(ctx)
-	if err != nil {
-		msg := "failed to get team from app id"
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-	if team == nil {
-		msg := fmt.Sprintf("no team exists for app [%s]", app.ID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
-		return
-	}
-
-	userId := c.GetString("userId")
-	okTeam, err := PerformAuthz(userId, team.ID.String(), *ScopeTeamRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
 
-	okApp, err := PerformAuthz(userId, team.ID.String(), *ScopeAppRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	if !okTeam || !okApp {
-		msg := `you are not authorized to access this app`
-		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+	if !authzApp(c, id, *ScopeAppRead) {
 		return
 	}
 
@@ -3101,53 +3075,22 @@ func GetCrashDetailPlotJourney(c *gin.Context) {
 	app := App{
 		ID: &id,
 	}
-	team, err := app.getTeam(ctx)
-	if err != nil {
-		msg := "failed to get team from app id"
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-	if team == nil {
-		msg := fmt.Sprintf("no team exists for app [%s]", app.ID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+
+	if !authzApp(c, id, *ScopeAppRead) {
 		return
 	}
 
-	userId := c.GetString("userId")
-	okTeam, err := PerformAuthz(userId, team.ID.String(), *ScopeTeamRead)
+	exceptionGroup, err := app.GetExceptionGroup(ctx, crashGroupId)
 	if err != nil {
-		msg := `failed to perform authorization`
+		msg := fmt.Sprintf("failed to get exception group with id %q", crashGroupId.String())
 		fmt.Println(msg, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
 		return
 	}
 
-	okApp, err := PerformAuthz(userId, team.ID.String(), *ScopeAppRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	if !okTeam || !okApp {
-		msg := `you are not authorized to access this app`
-		c.JSON(http.StatusForbidden, gin.H{"error": msg})
-		return
-	}
-
-	exceptionGroup, err := app.GetExceptionGroup(ctx, crashGroupId)
-	if err != nil {
-		msg := fmt.Sprintf("failed to get exception group with id %q", crashGroupId.String())
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	journeyEvents, err := app.getJourneyEvents(ctx, &af, filter.JourneyOpts{
-		Exceptions: true,
-	})
+	journeyEvents, err := app.getJourneyEvents(ctx, &af, filter.JourneyOpts{
+		Exceptions: true,
+	})
 	if err != nil {
 		fmt.Println(msg, err)
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -3293,39 +3236,8 @@ func GetANROverview(c *gin.Context) {
 	app := App{
 		ID: &id,
 	}
-	team, err := app.getTeam(ctx)
-	if err != nil {
-		msg := "failed to get team from app id"
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-	if team == nil {
-		msg := fmt.Sprintf("no team exists for app [%s]", app.ID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
-		return
-	}
 
-	userId := c.GetString("userId")
-	okTeam, err := PerformAuthz(userId, team.ID.String(), *ScopeTeamRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	okApp, err := PerformAuthz(userId, team.ID.String(), *ScopeAppRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	if !okTeam || !okApp {
-		msg := `you are not authorized to access this app`
-		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+	if !authzApp(c, id, *ScopeAppRead) {
 		return
 	}
 
@@ -3412,42 +3324,7 @@ func GetANROverviewPlotInstances(c *gin.Context) {
 		af.SetDefaultTimeRange()
 	}
 
-	app := App{
-		ID: &id,
-	}
-	team, err := app.getTeam(ctx)
-	if err != nil {
-		msg := "failed to get team from app id"
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-	if team == nil {
-		msg := fmt.Sprintf("no team exists for app [%s]", app.ID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
-		return
-	}
-
-	userId := c.GetString("userId")
-	okTeam, err := PerformAuthz(userId, team.ID.String(), *ScopeTeamRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	okApp, err := PerformAuthz(userId, team.ID.String(), *ScopeAppRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	if !okTeam || !okApp {
-		msg := `you are not authorized to access this app`
-		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+	if !authzApp(c, id, *ScopeAppRead) {
 		return
 	}
 
@@ -3550,39 +3427,8 @@ func GetANRDetailANRs(c *gin.Context) {
 	app := App{
 		ID: &id,
 	}
-	team, err := app.getTeam(ctx)
-	if err != nil {
-		msg := "failed to get team from app id"
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-	if team == nil {
-		msg := fmt.Sprintf("no team exists for app [%s]", app.ID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
-		return
-	}
 
-	userId := c.GetString("userId")
-	okTeam, err := PerformAuthz(userId, team.ID.String(), *ScopeTeamRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	okApp, err := PerformAuthz(userId, team.ID.String(), *ScopeAppRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	if !okTeam || !okApp {
-		msg := `you are not authorized to access this app`
-		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+	if !authzApp(c, id, *ScopeAppRead) {
 		return
 	}
 
@@ -3689,39 +3535,8 @@ func GetANRDetailPlotInstances(c *gin.Context) {
 	app := App{
 		ID: &id,
 	}
-	team, err := app.getTeam(ctx)
-	if err != nil {
-		msg := "failed to get team from app id"
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-	if team == nil {
-		msg := fmt.Sprintf("no team exists for app [%s]", app.ID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
-		return
-	}
 
-	userId := c.GetString("userId")
-	okTeam, err := PerformAuthz(userId, team.ID.String(), *ScopeTeamRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	okApp, err := PerformAuthz(userId, team.ID.String(), *ScopeAppRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	if !okTeam || !okApp {
-		msg := `you are not authorized to access this app`
-		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+	if !authzApp(c, id, *ScopeAppRead) {
 		return
 	}
 
@@ -3836,39 +3651,8 @@ func GetANRDetailPlotJourney(c *gin.Context) {
 	app := App{
 		ID: &id,
 	}
-	team, err := app.getTeam(ctx)
-	if err != nil {
-		msg := "failed to get team from app id"
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-	if team == nil {
-		msg := fmt.Sprintf("no team exists for app [%s]", app.ID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
-		return
-	}
 
-	userId := c.GetString("userId")
-	okTeam, err := PerformAuthz(userId, team.ID.String(), *ScopeTeamRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	okApp, err := PerformAuthz(userId, team.ID.String(), *ScopeAppRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	if !okTeam || !okApp {
-		msg := `you are not authorized to access this app`
-		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+	if !authzApp(c, id, *ScopeAppRead) {
 		return
 	}
 
@@ -3985,7 +3769,7 @@ func CreateApp(c *gin.Context) {
 		return
 	}
 
-	ok, err := PerformAuthz(userId, teamId.String(), *ScopeAppAll)
+	ok, err := PerformAuthz(c, userId, teamId.String(), *ScopeAppAll)
 	if err != nil {
 		msg := `couldn't perform authorization checks`
 		fmt.Println(msg, err)
@@ -4006,6 +3790,14 @@ func CreateApp(c *gin.Context) {
 		return
 	}
 
+	app.AppName = strings.Trim(app.AppName, " ")
+
+	if err := checkName("app", app.AppName); err != nil {
+		msg := err.Error()
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
 	apiKey, err := app.add()
 
 	if err != nil {
@@ -4017,6 +3809,12 @@ func CreateApp(c *gin.Context) {
 
 	app.APIKey = apiKey
 
+	newAuditLog(c, auditAppCreated).
+		inTeam(teamId).
+		on(auditTargetApp, app.ID.String()).
+		change(nil, map[string]any{"name": app.AppName}).
+		record(c)
+
 	c.JSON(http.StatusCreated, app)
 }
 
@@ -4075,39 +3873,8 @@ func GetSessionsOverview(c *gin.Context) {
 	app := App{
 		ID: &id,
 	}
-	team, err := app.getTeam(ctx)
-	if err != nil {
-		msg := "failed to get team from app id"
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-	if team == nil {
-		msg := fmt.Sprintf("no team exists for app [%s]", app.ID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
-		return
-	}
-
-	userId := c.GetString("userId")
-	okTeam, err := PerformAuthz(userId, team.ID.String(), *ScopeTeamRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	okApp, err := PerformAuthz(userId, team.ID.String(), *ScopeAppRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
 
-	if !okTeam || !okApp {
-		msg := `you are not authorized to access this app`
-		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+	if !authzApp(c, id, *ScopeAppRead) {
 		return
 	}
 
@@ -4188,13 +3955,17 @@ func (a App) GetSessionsWithFilter(ctx context.Context, af *filter.AppFilter) (s
 	}
 
 	if af.Crash && af.ANR {
-		base.Where("((type = 'exception' AND exception.handled = false) OR type = 'anr')")
+		base.Where("((type = 'exception' AND exception.handled = false) OR type = 'native_crash' OR type = 'anr')")
 	} else if af.Crash {
-		base.Where("type = 'exception' AND exception.handled = false")
+		base.Where("((type = 'exception' AND exception.handled = false) OR type = 'native_crash')")
 	} else if af.ANR {
 		base.Where("type = 'anr'")
 	}
 
+	if af.HasCustomEvent() {
+		filterCustomEvents(base, af)
+	}
+
 	if len(af.OsNames) > 0 {
 		base.Where("attribute.os_name").In(af.OsNames)
 	}
@@ -4231,6 +4002,8 @@ func (a App) GetSessionsWithFilter(ctx context.Context, af *filter.AppFilter) (s
 		base.Where("attribute.network_generation").In(af.NetworkGenerations)
 	}
 
+	filterUserDefinedAttrs(base, af)
+
 	if af.FreeText != "" {
 		base.Where(
 			"("+
@@ -4439,42 +4212,7 @@ func GetSessionsOverviewPlot(c *gin.Context) {
 		af.SetDefaultTimeRange()
 	}
 
-	app := App{
-		ID: &id,
-	}
-	team, err := app.getTeam(ctx)
-	if err != nil {
-		msg := "failed to get team from app id"
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-	if team == nil {
-		msg := fmt.Sprintf("no team exists for app [%s]", app.ID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
-		return
-	}
-
-	userId := c.GetString("userId")
-	okTeam, err := PerformAuthz(userId, team.ID.String(), *ScopeTeamRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	okApp, err := PerformAuthz(userId, team.ID.String(), *ScopeAppRead)
-	if err != nil {
-		msg := `failed to perform authorization`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-
-	if !okTeam || !okApp {
-		msg := `you are not authorized to access this app`
-		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+	if !authzApp(c, id, *ScopeAppRead) {
 		return
 	}
 
@@ -4539,45 +4277,8 @@ func GetSession(c *gin.Context) {
 	app := &App{
 		ID: &appId,
 	}
-	team, err := app.getTeam(ctx)
-	if err != nil {
-		msg := `failed to fetch team from app`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
-		return
-	}
-	if team == nil {
-		msg := fmt.Sprintf(`no team exists for app id: %q`, app.ID)
-		fmt.Println(msg)
-		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
-		return
-	}
-
-	userId := c.GetString("userId")
-
-	ok, err := PerformAuthz(userId, team.ID.String(), *ScopeTeamRead)
-	if err != nil {
-		msg := `couldn't perform authorization checks`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-	if !ok {
-		msg := fmt.Sprintf(`you don't have permissions to read apps in team %q`, team.ID)
-		c.JSON(http.StatusForbidden, gin.H{"error": msg})
-		return
-	}
 
-	ok, err = PerformAuthz(userId, team.ID.String(), *ScopeAppRead)
-	if err != nil {
-		msg := `couldn't perform authorization checks`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-	if !ok {
-		msg := fmt.Sprintf(`you don't have permissions to read apps in team %q`, team.ID)
-		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+	if !authzApp(c, appId, *ScopeAppRead) {
 		return
 	}
 
@@ -4639,8 +4340,11 @@ func GetSession(c *gin.Context) {
 		event.TypeLowMemory,
 		event.TypeAppExit,
 		event.TypeException,
+		event.TypeNativeCrash,
 		event.TypeANR,
 		event.TypeHttp,
+		event.TypeSpan,
+		event.TypeCustom,
 	}
 
 	eventMap := session.EventsOfTypes(typeList...)
@@ -4765,6 +4469,20 @@ func GetSession(c *gin.Context) {
 		threads.Organize(event.TypeException, threadedExceptions)
 	}
 
+	nativeCrashEvents := eventMap[event.TypeNativeCrash]
+	if len(nativeCrashEvents) > 0 {
+		nativeCrashes, err := replay.ComputeNativeCrashes(c, app.ID, nativeCrashEvents)
+		if err != nil {
+			msg := fmt.Sprintf(`unable to compute native crashes for session %q for app %q`, sessionId, app.ID)
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": msg,
+			})
+			return
+		}
+		threadedNativeCrashes := replay.GroupByThreads(nativeCrashes)
+		threads.Organize(event.TypeNativeCrash, threadedNativeCrashes)
+	}
+
 	anrEvents := eventMap[event.TypeANR]
 	if len(anrEvents) > 0 {
 		anrs, err := replay.ComputeANRs(c, app.ID, anrEvents)
@@ -4786,6 +4504,20 @@ func GetSession(c *gin.Context) {
 		threads.Organize(event.TypeHttp, threadedHttpies)
 	}
 
+	spanEvents := eventMap[event.TypeSpan]
+	if len(spanEvents) > 0 {
+		spans := replay.ComputeSpans(spanEvents)
+		threadedSpans := replay.GroupByThreads(spans)
+		threads.Organize(event.TypeSpan, threadedSpans)
+	}
+
+	customEvents := eventMap[event.TypeCustom]
+	if len(customEvents) > 0 {
+		customs := replay.ComputeCustom(customEvents)
+		threadedCustoms := replay.GroupByThreads(customs)
+		threads.Organize(event.TypeCustom, threadedCustoms)
+	}
+
 	threads.Sort()
 
 	response := gin.H{
@@ -4818,6 +4550,10 @@ func GetAlertPrefs(c *gin.Context) {
 		return
 	}
 
+	if !authzApp(c, appId, *ScopeAppRead) {
+		return
+	}
+
 	alertPref, err := getAlertPref(appId, userId)
 	if err != nil {
 		msg := `unable to fetch notif prefs`
@@ -4846,6 +4582,10 @@ func UpdateAlertPrefs(c *gin.Context) {
 		return
 	}
 
+	if !authzApp(c, appId, *ScopeAppRead) {
+		return
+	}
+
 	alertPref := newAlertPref(appId, userId)
 
 	var payload AlertPrefPayload
@@ -4874,6 +4614,10 @@ func GetAppSettings(c *gin.Context) {
 		return
 	}
 
+	if !authzApp(c, appId, *ScopeAppRead) {
+		return
+	}
+
 	appSettings, err := getAppSettings(appId)
 	if err != nil {
 		msg := `unable to fetch app settings`
@@ -4886,7 +4630,6 @@ func GetAppSettings(c *gin.Context) {
 }
 
 func UpdateAppSettings(c *gin.Context) {
-	userId := c.GetString("userId")
 	appId, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		msg := `app id invalid or missing`
@@ -4899,6 +4642,12 @@ func UpdateAppSettings(c *gin.Context) {
 		ID: &appId,
 	}
 
+	if !authzApp(c, appId, *ScopeAppAll) {
+		return
+	}
+
+	// the app's team is recorded
+	// in the audit log
 	team, err := app.getTeam(c)
 	if err != nil {
 		msg := "failed to get team from app id"
@@ -4912,19 +4661,6 @@ func UpdateAppSettings(c *gin.Context) {
 		return
 	}
 
-	ok, err := PerformAuthz(userId, team.ID.String(), *ScopeAppAll)
-	if err != nil {
-		msg := `couldn't perform authorization checks`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
-		return
-	}
-	if !ok {
-		msg := fmt.Sprintf(`you don't have permissions to modify app settings in team [%s]`, team.ID.String())
-		c.JSON(http.StatusForbidden, gin.H{"error": msg})
-		return
-	}
-
 	appSettings := newAppSettings(appId)
 
 	var payload AppSettingsPayload
@@ -4935,15 +4671,32 @@ func UpdateAppSettings(c *gin.Context) {
 		return
 	}
 
+	prevSettings, err := getAppSettings(appId)
+	if err != nil {
+		msg := `unable to fetch app settings`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
 	appSettings.RetentionPeriod = payload.RetentionPeriod
 
 	appSettings.update()
 
+	newAuditLog(c, auditAppSettingsUpdated).
+		inTeam(*team.ID).
+		on(auditTargetApp, appId.String()).
+		change(map[string]any{
+			"retention_period": prevSettings.RetentionPeriod,
+		}, map[string]any{
+			"retention_period": appSettings.RetentionPeriod,
+		}).
+		record(c)
+
 	c.JSON(http.StatusOK, gin.H{"ok": "done"})
 }
 
 func RenameApp(c *gin.Context) {
-	userId := c.GetString("userId")
 	appId, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		msg := `app id invalid or missing`
@@ -4956,6 +4709,12 @@ func RenameApp(c *gin.Context) {
 		ID: &appId,
 	}
 
+	if !authzApp(c, appId, *ScopeAppAll) {
+		return
+	}
+
+	// the app's team is recorded
+	// in the audit log
 	team, err := app.getTeam(c)
 	if err != nil {
 		msg := "failed to get team from app id"
@@ -4969,16 +4728,16 @@ func RenameApp(c *gin.Context) {
 		return
 	}
 
-	ok, err := PerformAuthz(userId, team.ID.String(), *ScopeAppAll)
+	prevApp, err := NewApp(*team.ID).getWithTeam(appId)
 	if err != nil {
-		msg := `couldn't perform authorization checks`
+		msg := fmt.Sprintf("failed to fetch app: %s", appId)
 		fmt.Println(msg, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
 		return
 	}
-	if !ok {
-		msg := fmt.Sprintf(`you don't have permissions to modify app in team [%s]`, team.ID.String())
-		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+	if prevApp == nil {
+		msg := fmt.Sprintf("no app found with id: %s", appId)
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
 		return
 	}
 
@@ -4989,6 +4748,14 @@ func RenameApp(c *gin.Context) {
 		return
 	}
 
+	app.AppName = strings.Trim(app.AppName, " ")
+
+	if err := checkName("app", app.AppName); err != nil {
+		msg := err.Error()
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
 	err = app.rename()
 	if err != nil {
 		msg := `failed to rename app`
@@ -4997,5 +4764,147 @@ func RenameApp(c *gin.Context) {
 		return
 	}
 
+	newAuditLog(c, auditAppRenamed).
+		inTeam(*team.ID).
+		on(auditTargetApp, appId.String()).
+		change(map[string]any{"name": prevApp.AppName}, map[string]any{"name": app.AppName}).
+		record(c)
+
 	c.JSON(http.StatusOK, gin.H{"ok": "done"})
 }
+
+func UpdateCrashGroupStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+	appId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `app id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	crashGroupId, err := uuid.Parse(c.Param("crashGroupId"))
+	if err != nil {
+		msg := `crash group id is invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	var update group.StatusUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		msg := `failed to parse crash group status json payload`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := update.Validate(); err != nil {
+		msg := `crash group status validation failed`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg, "details": err.Error()})
+		return
+	}
+
+	app := App{
+		ID: &appId,
+	}
+
+	if !authzApp(c, appId, *ScopeAppAll) {
+		return
+	}
+
+	exceptionGroup, err := app.GetExceptionGroup(ctx, crashGroupId)
+	if err != nil {
+		msg := fmt.Sprintf(`failed to get exception group with id %q`, crashGroupId.String())
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+	if exceptionGroup == nil {
+		msg := fmt.Sprintf(`no crash group found with id %q`, crashGroupId.String())
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+		return
+	}
+
+	if err := exceptionGroup.UpdateStatus(ctx, update); err != nil {
+		msg := `failed to update crash group status`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	// omit `event_ids` field from JSON
+	// response, because these can get really huge
+	exceptionGroup.EventIDs = nil
+
+	c.JSON(http.StatusOK, exceptionGroup)
+}
+
+func UpdateANRGroupStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+	appId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `app id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	anrGroupId, err := uuid.Parse(c.Param("anrGroupId"))
+	if err != nil {
+		msg := `anr group id is invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	var update group.StatusUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		msg := `failed to parse anr group status json payload`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := update.Validate(); err != nil {
+		msg := `anr group status validation failed`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg, "details": err.Error()})
+		return
+	}
+
+	app := App{
+		ID: &appId,
+	}
+
+	if !authzApp(c, appId, *ScopeAppAll) {
+		return
+	}
+
+	anrGroup, err := app.GetANRGroup(ctx, anrGroupId)
+	if err != nil {
+		msg := fmt.Sprintf(`failed to get anr group with id %q`, anrGroupId.String())
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+	if anrGroup == nil {
+		msg := fmt.Sprintf(`no anr group found with id %q`, anrGroupId.String())
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+		return
+	}
+
+	if err := anrGroup.UpdateStatus(ctx, update); err != nil {
+		msg := `failed to update anr group status`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	// omit `event_ids` field from JSON
+	// response, because these can get really huge
+	anrGroup.EventIDs = nil
+
+	c.JSON(http.StatusOK, anrGroup)
+}
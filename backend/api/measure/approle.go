@@ -0,0 +1,463 @@
+package measure
+
+import (
+	"backend/api/chrono"
+	"backend/api/server"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/leporo/sqlf"
+)
+
+// noAppRole is the app role override
+// denying all access to an app.
+const noAppRole = "none"
+
+// AppRole represents a team member's role
+// override for an app.
+type AppRole struct {
+	userId    uuid.UUID
+	name      *string
+	email     *string
+	role      rank
+	teamRole  rank
+	createdAt time.Time
+	updatedAt time.Time
+}
+
+func (r AppRole) MarshalJSON() ([]byte, error) {
+	appRoleMap := make(map[string]any)
+
+	appRoleMap["user_id"] = r.userId
+	appRoleMap["name"] = r.name
+	appRoleMap["email"] = r.email
+	appRoleMap["role"] = appRoleString(r.role)
+	appRoleMap["team_role"] = r.teamRole.String()
+	appRoleMap["created_at"] = r.createdAt.Format(chrono.ISOFormatJS)
+	appRoleMap["updated_at"] = r.updatedAt.Format(chrono.ISOFormatJS)
+
+	return json.Marshal(appRoleMap)
+}
+
+// parseAppRole parses an app role override. The
+// 'none' override parses to the unknown rank.
+func parseAppRole(str string) (rank, bool) {
+	if str == noAppRole {
+		return unknown, true
+	}
+
+	role := roleMap[str]
+
+	return role, role.Valid()
+}
+
+// appRoleString is the inverse
+// of parseAppRole.
+func appRoleString(r rank) string {
+	if r == unknown {
+		return noAppRole
+	}
+
+	return r.String()
+}
+
+// getAppRoleOverride fetches the user's role override
+// for the app. Reports false if there is none.
+func getAppRoleOverride(ctx context.Context, appId, userId string) (role rank, ok bool, err error) {
+	var str string
+
+	stmt := sqlf.PostgreSQL.
+		Select("role").
+		From("public.app_roles").
+		Where("app_id = ? and user_id = ?", appId, userId)
+
+	defer stmt.Close()
+
+	if err = server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&str); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return unknown, false, nil
+		}
+		return
+	}
+
+	role, ok = parseAppRole(str)
+
+	return
+}
+
+// getAppRole resolves the user's role for the app
+// from the user's role in the app's team.
+func (u *User) getAppRole(ctx context.Context, appId string, teamRole rank) (rank, error) {
+	override, ok, err := getAppRoleOverride(ctx, appId, *u.ID)
+	if err != nil {
+		return unknown, err
+	}
+
+	if ok {
+		return override, nil
+	}
+
+	return teamRole, nil
+}
+
+// filterDeniedApps leaves out the apps the user
+// has no access to due to a role override.
+func filterDeniedApps(ctx context.Context, userId string, apps []App) ([]App, error) {
+	stmt := sqlf.PostgreSQL.
+		Select("app_id").
+		From("public.app_roles").
+		Where("user_id = ? and role = ?", userId, noAppRole)
+
+	defer stmt.Close()
+
+	rows, err := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return nil, err
+	}
+
+	denied, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(apps, func(a App) bool {
+		return slices.Contains(denied, *a.ID)
+	}), nil
+}
+
+// getAppRoles fetches the role overrides of
+// the app's team members for the app.
+func getAppRoles(ctx context.Context, appId uuid.UUID) (roles []AppRole, err error) {
+	stmt := sqlf.PostgreSQL.
+		Select("ar.user_id").
+		Select("u.name").
+		Select("u.email").
+		Select("ar.role").
+		Select("tm.role").
+		Select("ar.created_at").
+		Select("ar.updated_at").
+		From("public.app_roles ar").
+		Join("public.apps a", "a.id = ar.app_id").
+		Join("public.team_membership tm", "tm.team_id = a.team_id and tm.user_id = ar.user_id").
+		LeftJoin("public.users u", "u.id = ar.user_id").
+		Where("ar.app_id = ?", appId).
+		OrderBy("u.name")
+
+	defer stmt.Close()
+
+	rows, err := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var r AppRole
+		var role, teamRole string
+
+		if err = rows.Scan(&r.userId, &r.name, &r.email, &role, &teamRole, &r.createdAt, &r.updatedAt); err != nil {
+			return nil, err
+		}
+
+		r.role, _ = parseAppRole(role)
+		r.teamRole = roleMap[teamRole]
+
+		roles = append(roles, r)
+	}
+
+	err = rows.Err()
+
+	return
+}
+
+// setAppRole creates or replaces the
+// user's role override for the app.
+func setAppRole(ctx context.Context, appId, userId uuid.UUID, role rank) error {
+	now := time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		InsertInto("public.app_roles").
+		Set("app_id", appId).
+		Set("user_id", userId).
+		Set("role", appRoleString(role)).
+		Set("created_at", now).
+		Set("updated_at", now).
+		Clause("on conflict (app_id, user_id) do update set role = excluded.role, updated_at = excluded.updated_at")
+
+	defer stmt.Close()
+
+	_, err := server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return err
+}
+
+// deleteAppRole removes the user's role override
+// for the app. Reports false if there was none.
+func deleteAppRole(ctx context.Context, appId, userId uuid.UUID) (bool, error) {
+	stmt := sqlf.PostgreSQL.
+		DeleteFrom("public.app_roles").
+		Where("app_id = ? and user_id = ?", appId, userId)
+
+	defer stmt.Close()
+
+	tag, err := server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return false, err
+	}
+
+	return tag.RowsAffected() > 0, nil
+}
+
+// appRoleChange represents a request to change a
+// team member's role override for an app.
+type appRoleChange struct {
+	appId    uuid.UUID
+	teamId   uuid.UUID
+	memberId uuid.UUID
+
+	// userRole is the requesting
+	// user's role for the app.
+	userRole rank
+
+	// memberRole is the member's
+	// current role for the app.
+	memberRole rank
+
+	// override is the member's current
+	// role override, if any.
+	override *rank
+}
+
+// getAppRoleChangeFromParams parses the app & member ids
+// from the route, checks that the user can change roles
+// for the app & resolves the roles involved. Members can
+// only be changed by users having the same or a higher
+// role for the app. Writes the error response and returns
+// nil if any of it fails.
+func getAppRoleChangeFromParams(c *gin.Context) *appRoleChange {
+	ctx := c.Request.Context()
+	userId := c.GetString("userId")
+	appId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `app id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return nil
+	}
+
+	memberId, err := uuid.Parse(c.Param("memberId"))
+	if err != nil {
+		msg := `member id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return nil
+	}
+
+	app := App{
+		ID: &appId,
+	}
+
+	if !authzApp(c, appId, *ScopeTeamChangeRoleSameOrLower) {
+		return nil
+	}
+
+	team, err := app.getTeam(ctx)
+	if err != nil {
+		msg := "failed to get team from app id"
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return nil
+	}
+	if team == nil {
+		msg := fmt.Sprintf("no team exists for app [%s]", app.ID)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return nil
+	}
+
+	change := &appRoleChange{
+		appId:    appId,
+		teamId:   *team.ID,
+		memberId: memberId,
+	}
+
+	user := &User{
+		ID: &userId,
+	}
+
+	userTeamRole, err := user.getRole(team.ID.String())
+	if err != nil {
+		msg := `couldn't perform authorization checks`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return nil
+	}
+
+	if change.userRole, err = user.getAppRole(ctx, appId.String(), userTeamRole); err != nil {
+		msg := `couldn't perform authorization checks`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return nil
+	}
+
+	memberIdStr := memberId.String()
+	memberTeamRole, err := (&User{ID: &memberIdStr}).getRole(team.ID.String())
+	if err != nil {
+		msg := `couldn't perform authorization checks`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return nil
+	}
+	if memberTeamRole == unknown {
+		msg := fmt.Sprintf("no member [%s] found in team [%s]", memberId, team.ID)
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+		return nil
+	}
+
+	override, ok, err := getAppRoleOverride(ctx, appId.String(), memberIdStr)
+	if err != nil {
+		msg := `couldn't perform authorization checks`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return nil
+	}
+
+	change.memberRole = memberTeamRole
+	if ok {
+		change.override = &override
+		change.memberRole = override
+	}
+
+	if change.memberRole > change.userRole {
+		msg := fmt.Sprintf(`you don't have permissions to change roles of member [%s] for app [%s]`, memberId, appId)
+		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+		return nil
+	}
+
+	return change
+}
+
+func GetAppRoles(c *gin.Context) {
+	ctx := c.Request.Context()
+	appId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `app id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if !authzApp(c, appId, *ScopeTeamRead) {
+		return
+	}
+
+	roles, err := getAppRoles(ctx, appId)
+	if err != nil {
+		msg := `failed to fetch app roles`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	if roles == nil {
+		roles = []AppRole{}
+	}
+
+	c.JSON(http.StatusOK, roles)
+}
+
+func UpdateAppRole(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var payload struct {
+		Role string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		msg := `failed to parse payload`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	role, ok := parseAppRole(payload.Role)
+	if !ok {
+		msg := fmt.Sprintf("role [%s] is not valid", payload.Role)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	change := getAppRoleChangeFromParams(c)
+	if change == nil {
+		return
+	}
+
+	if role > change.userRole {
+		msg := fmt.Sprintf(`you don't have permissions to assign role [%s] for app [%s]`, payload.Role, change.appId)
+		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+		return
+	}
+
+	if err := setAppRole(ctx, change.appId, change.memberId, role); err != nil {
+		msg := `failed to change app role`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	var before map[string]any
+	if change.override != nil {
+		before = map[string]any{"app_role": map[string]any{"app_id": change.appId.String(), "role": appRoleString(*change.override)}}
+	}
+
+	newAuditLog(c, auditMemberAppRoleChanged).
+		inTeam(change.teamId).
+		on(auditTargetMember, change.memberId.String()).
+		change(before, map[string]any{"app_role": map[string]any{"app_id": change.appId.String(), "role": payload.Role}}).
+		record(ctx)
+
+	c.JSON(http.StatusOK, gin.H{"ok": "done"})
+}
+
+func DeleteAppRole(c *gin.Context) {
+	ctx := c.Request.Context()
+	change := getAppRoleChangeFromParams(c)
+	if change == nil {
+		return
+	}
+
+	if change.override == nil {
+		msg := fmt.Sprintf("no role override found for member [%s] in app [%s]", change.memberId, change.appId)
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+		return
+	}
+
+	deleted, err := deleteAppRole(ctx, change.appId, change.memberId)
+	if err != nil {
+		msg := `failed to remove app role`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+	if !deleted {
+		msg := fmt.Sprintf("no role override found for member [%s] in app [%s]", change.memberId, change.appId)
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+		return
+	}
+
+	newAuditLog(c, auditMemberAppRoleRemoved).
+		inTeam(change.teamId).
+		on(auditTargetMember, change.memberId.String()).
+		change(map[string]any{"app_role": map[string]any{"app_id": change.appId.String(), "role": appRoleString(*change.override)}}, nil).
+		record(ctx)
+
+	c.JSON(http.StatusOK, gin.H{"ok": "done"})
+}
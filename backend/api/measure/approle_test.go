@@ -0,0 +1,66 @@
+package measure
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestParseAppRole(t *testing.T) {
+	for _, r := range []rank{owner, admin, developer, viewer, unknown} {
+		result, ok := parseAppRole(appRoleString(r))
+		if !ok || result != r {
+			t.Errorf("Expected %v but got %v", r, result)
+		}
+	}
+
+	if appRoleString(unknown) != "none" {
+		t.Errorf("Expected %q but got %q", "none", appRoleString(unknown))
+	}
+
+	for _, str := range []string{"", "unknown", "superuser", "Viewer"} {
+		if _, ok := parseAppRole(str); ok {
+			t.Errorf("Expected %q to fail parsing", str)
+		}
+	}
+}
+
+func TestAppRoleMarshalJSON(t *testing.T) {
+	name := "Ada Lovelace"
+	r := AppRole{
+		userId:    uuid.New(),
+		name:      &name,
+		role:      unknown,
+		teamRole:  developer,
+		createdAt: time.Now(),
+		updatedAt: time.Now(),
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if result["role"] != "none" {
+		t.Errorf("Expected role %q, but got %v", "none", result["role"])
+	}
+
+	if result["team_role"] != "developer" {
+		t.Errorf("Expected team role %q, but got %v", "developer", result["team_role"])
+	}
+
+	if result["user_id"] != r.userId.String() {
+		t.Errorf("Expected user id %q, but got %v", r.userId, result["user_id"])
+	}
+
+	if result["email"] != nil {
+		t.Errorf("Expected nil email, but got %v", result["email"])
+	}
+}
@@ -0,0 +1,178 @@
+package measure
+
+import (
+	"backend/api/server"
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/leporo/sqlf"
+)
+
+// AppTransfer represents the request
+// to move an app to another team.
+type AppTransfer struct {
+	TeamID uuid.UUID `json:"team_id" binding:"required"`
+}
+
+// transfer moves the app from the source team to the
+// destination team. API keys, settings, webhooks & issue
+// groups belong to the app, so they move along. Alert
+// preferences & role overrides of users who aren't
+// members of the destination team are removed & members
+// of the destination team start with the default alert
+// preferences. Returns false if the app no longer
+// belongs to the source team.
+func (a *App) transfer(ctx context.Context, tx *pgx.Tx, from, to uuid.UUID) (ok bool, err error) {
+	now := time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.apps").
+		Set("team_id", to).
+		Set("updated_at", now).
+		Where("id = ? and team_id = ? and deleted_at is null", a.ID, from)
+
+	defer stmt.Close()
+
+	tag, err := (*tx).Exec(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	if tag.RowsAffected() == 0 {
+		return
+	}
+
+	for _, table := range []string{"public.alert_prefs", "public.app_roles"} {
+		deleteStmt := sqlf.PostgreSQL.
+			DeleteFrom(table).
+			Where("app_id = ?", a.ID).
+			Where("user_id not in (select user_id from public.team_membership where team_id = ?)", to)
+
+		_, err = (*tx).Exec(ctx, deleteStmt.String(), deleteStmt.Args()...)
+		deleteStmt.Close()
+		if err != nil {
+			return
+		}
+	}
+
+	// members only differ by user id, so
+	// the defaults are taken from a single
+	// new preference
+	pref := newAlertPref(*a.ID, uuid.Nil)
+
+	prefsQuery := "insert into public.alert_prefs(app_id, user_id, crash_rate_spike_email, anr_rate_spike_email, launch_time_spike_email, created_at, updated_at) select $1, user_id, $2, $3, $4, $5, $5 from public.team_membership where team_id = $6 on conflict (app_id, user_id) do nothing;"
+
+	if _, err = (*tx).Exec(ctx, prefsQuery, pref.AppId, pref.CrashRateSpikeEmail, pref.AnrRateSpikeEmail, pref.LaunchTimeSpikeEmail, now, to); err != nil {
+		return
+	}
+
+	return true, nil
+}
+
+func TransferApp(c *gin.Context) {
+	ctx := c.Request.Context()
+	userId := c.GetString("userId")
+	appId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `app id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	var transfer AppTransfer
+	if err := c.ShouldBindJSON(&transfer); err != nil {
+		msg := `failed to parse app transfer json payload`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	app := App{
+		ID: &appId,
+	}
+
+	if !authzApp(c, appId, *ScopeTeamAll) {
+		return
+	}
+
+	team, err := app.getTeam(ctx)
+	if err != nil {
+		msg := "failed to get team from app id"
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+	if team == nil {
+		msg := fmt.Sprintf("no team exists for app [%s]", app.ID)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if *team.ID == transfer.TeamID {
+		msg := fmt.Sprintf("app [%s] already belongs to team [%s]", appId, transfer.TeamID)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	ok, err := PerformAuthz(c, userId, transfer.TeamID.String(), *ScopeTeamAll)
+	if err != nil {
+		msg := `couldn't perform authorization checks`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+	if !ok {
+		msg := fmt.Sprintf(`you don't have permissions to transfer apps to team [%s]`, transfer.TeamID)
+		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+		return
+	}
+
+	tx, err := server.Server.PgPool.Begin(ctx)
+	if err != nil {
+		msg := `failed to transfer app`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	defer tx.Rollback(ctx)
+
+	ok, err = app.transfer(ctx, &tx, *team.ID, transfer.TeamID)
+	if err != nil {
+		msg := `failed to transfer app`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+	if !ok {
+		msg := fmt.Sprintf("app [%s] was modified by another request, try again", appId)
+		c.JSON(http.StatusConflict, gin.H{"error": msg})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		msg := `failed to transfer app`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	// record in both teams, so that
+	// each team's log tells where the
+	// app came from or went to
+	for _, teamId := range []uuid.UUID{*team.ID, transfer.TeamID} {
+		newAuditLog(c, auditAppTransferred).
+			inTeam(teamId).
+			on(auditTargetApp, appId.String()).
+			change(map[string]any{"team_id": team.ID.String()}, map[string]any{"team_id": transfer.TeamID.String()}).
+			record(ctx)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": "done"})
+}
@@ -0,0 +1,102 @@
+package measure
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func TestAppTransferConflict(t *testing.T) {
+	appId := uuid.New()
+	from := uuid.New()
+	to := uuid.New()
+	app := App{ID: &appId}
+
+	var tx pgx.Tx = &recordingTx{}
+
+	ok, err := app.transfer(context.Background(), &tx, from, to)
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	// the handler responds with
+	// 409 when not transferred
+	if ok {
+		t.Error("Expected app not to be transferred when it no longer belongs to the source team")
+	}
+
+	executions := tx.(*recordingTx).executions
+	if len(executions) != 1 {
+		t.Fatalf("Expected only the guarded update to be executed, but got %d statements", len(executions))
+	}
+
+	update := executions[0]
+	if !strings.Contains(update.sql, "team_id = $4") || update.args[3] != from {
+		t.Errorf("Expected update to be guarded by source team, but got %q with %v", update.sql, update.args)
+	}
+}
+
+func TestAppTransferPrunesAndSeedsPrefs(t *testing.T) {
+	appId := uuid.New()
+	from := uuid.New()
+	to := uuid.New()
+	app := App{ID: &appId}
+
+	var tx pgx.Tx = &recordingTx{appRows: 1}
+
+	ok, err := app.transfer(context.Background(), &tx, from, to)
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if !ok {
+		t.Fatal("Expected app to be transferred")
+	}
+
+	executions := tx.(*recordingTx).executions
+	if len(executions) != 4 {
+		t.Fatalf("Expected %d statements, but got %d", 4, len(executions))
+	}
+
+	if args := executions[0].args; args[0] != to || args[2] != app.ID || args[3] != from {
+		t.Errorf("Expected app to move from %s to %s, but got %v", from, to, args)
+	}
+
+	// preferences & role overrides of users
+	// outside the destination team are pruned
+	for i, table := range []string{"public.alert_prefs", "public.app_roles"} {
+		prune := executions[i+1]
+
+		if !strings.HasPrefix(prune.sql, "DELETE FROM "+table) {
+			t.Errorf("Expected prune of %q, but got %q", table, prune.sql)
+		}
+
+		if !strings.Contains(prune.sql, "user_id not in (select user_id from public.team_membership where team_id = $2)") {
+			t.Errorf("Expected prune of %q to keep destination team members, but got %q", table, prune.sql)
+		}
+
+		if len(prune.args) != 2 || prune.args[0] != app.ID || prune.args[1] != to {
+			t.Errorf("Expected prune of %q with app %s & team %s, but got %v", table, appId, to, prune.args)
+		}
+	}
+
+	// members of the destination team
+	// start with default preferences
+	seed := executions[3]
+
+	if !strings.HasPrefix(seed.sql, "insert into public.alert_prefs") || !strings.Contains(seed.sql, "on conflict (app_id, user_id) do nothing") {
+		t.Errorf("Expected seed of alert prefs keeping existing prefs, but got %q", seed.sql)
+	}
+
+	if !strings.Contains(seed.sql, "from public.team_membership where team_id = $6") || seed.args[5] != to {
+		t.Errorf("Expected seed for members of team %s, but got %q with %v", to, seed.sql, seed.args)
+	}
+
+	defaults := newAlertPref(appId, uuid.Nil)
+	if seed.args[0] != appId || seed.args[1] != defaults.CrashRateSpikeEmail || seed.args[2] != defaults.AnrRateSpikeEmail || seed.args[3] != defaults.LaunchTimeSpikeEmail {
+		t.Errorf("Expected seed with default preferences, but got %v", seed.args)
+	}
+}
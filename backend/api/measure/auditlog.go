@@ -0,0 +1,507 @@
+package measure
+
+import (
+	"backend/api/chrono"
+	"backend/api/server"
+	"backend/api/text"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/leporo/sqlf"
+)
+
+// defaultAuditLogsLimit is the default number
+// of audit logs returned per page.
+const defaultAuditLogsLimit = 20
+
+// maxAuditLogsLimit is the maximum number
+// of audit logs returned per page.
+const maxAuditLogsLimit = 100
+
+const (
+	// auditUserAgentMaxBytes is the maximum length
+	// of a recorded user agent in bytes.
+	auditUserAgentMaxBytes = 512
+
+	// auditTargetIDMaxBytes is the maximum length
+	// of a recorded target id in bytes.
+	auditTargetIDMaxBytes = 256
+)
+
+const (
+	auditMemberInvited        = "member.invited"
+	auditInviteCreated        = "invite.created"
+	auditInviteResent         = "invite.resent"
+	auditInviteRevoked        = "invite.revoked"
+	auditInviteAccepted       = "invite.accepted"
+	auditInviteDeclined       = "invite.declined"
+	auditMemberRemoved        = "member.removed"
+	auditMemberRoleChanged    = "member.role_changed"
+	auditMemberAppRoleChanged = "member.app_role_changed"
+	auditMemberAppRoleRemoved = "member.app_role_removed"
+	auditTeamRenamed          = "team.renamed"
+	auditTeamDeleted          = "team.deleted"
+	auditTeamQuotaUpdated     = "team.quota_updated"
+	auditAppCreated           = "app.created"
+	auditAppRenamed           = "app.renamed"
+	auditAppSettingsUpdated   = "app.settings_updated"
+	auditAppDeleted           = "app.deleted"
+	auditAppTransferred       = "app.transferred"
+	auditAppQuotaUpdated      = "app.quota_updated"
+	auditAPIKeyCreated        = "api_key.created"
+	auditAPIKeyRevoked        = "api_key.revoked"
+	auditAccessTokenCreated   = "access_token.created"
+	auditAccessTokenRevoked   = "access_token.revoked"
+	auditUserSignedIn         = "user.signed_in"
+	auditUserPasswordReset    = "user.password_reset"
+)
+
+const (
+	auditTargetMember      = "member"
+	auditTargetInvite      = "invite"
+	auditTargetTeam        = "team"
+	auditTargetApp         = "app"
+	auditTargetAPIKey      = "api_key"
+	auditTargetAccessToken = "access_token"
+	auditTargetUser        = "user"
+)
+
+// auditActions is the list of all
+// actions that are audited.
+var auditActions = []string{
+	auditMemberInvited,
+	auditInviteCreated,
+	auditInviteResent,
+	auditInviteRevoked,
+	auditInviteAccepted,
+	auditInviteDeclined,
+	auditMemberRemoved,
+	auditMemberRoleChanged,
+	auditMemberAppRoleChanged,
+	auditMemberAppRoleRemoved,
+	auditTeamRenamed,
+	auditTeamDeleted,
+	auditTeamQuotaUpdated,
+	auditAppCreated,
+	auditAppRenamed,
+	auditAppSettingsUpdated,
+	auditAppDeleted,
+	auditAppTransferred,
+	auditAppQuotaUpdated,
+	auditAPIKeyCreated,
+	auditAPIKeyRevoked,
+	auditAccessTokenCreated,
+	auditAccessTokenRevoked,
+	auditUserSignedIn,
+	auditUserPasswordReset,
+}
+
+// AuditActor represents the user
+// who performed an audited action.
+type AuditActor struct {
+	ID    uuid.UUID `json:"id"`
+	Name  *string   `json:"name"`
+	Email *string   `json:"email"`
+}
+
+// AuditLog represents a single audited action
+// performed on a team, an app or a user.
+type AuditLog struct {
+	ID         uuid.UUID       `json:"id"`
+	TeamID     *uuid.UUID      `json:"team_id"`
+	Actor      *AuditActor     `json:"actor"`
+	Action     string          `json:"action"`
+	TargetType string          `json:"target_type"`
+	TargetID   string          `json:"target_id"`
+	Before     map[string]any  `json:"before"`
+	After      map[string]any  `json:"after"`
+	IP         string          `json:"ip"`
+	UserAgent  string          `json:"user_agent"`
+	CreatedAt  *chrono.ISOTime `json:"created_at"`
+
+	// appId, when set, resolves the
+	// team from the app on record.
+	appId *uuid.UUID
+}
+
+// AuditLogQuery represents the query parameters
+// for filtering & paginating audit logs.
+type AuditLogQuery struct {
+	ActorID    string    `form:"actor_id"`
+	Actions    []string  `form:"actions"`
+	TargetType string    `form:"target_type"`
+	TargetID   string    `form:"target_id"`
+	From       time.Time `form:"from" time_format:"2006-01-02T15:04:05.000Z" time_utc:"1"`
+	To         time.Time `form:"to" time_format:"2006-01-02T15:04:05.000Z" time_utc:"1"`
+	Limit      int       `form:"limit"`
+	Offset     int       `form:"offset"`
+}
+
+// newAuditLog creates an audit log for the action
+// performed by the authenticated user of the request.
+func newAuditLog(c *gin.Context, action string) *AuditLog {
+	a := &AuditLog{
+		ID:        uuid.New(),
+		Action:    action,
+		IP:        c.ClientIP(),
+		UserAgent: truncateBytes(c.Request.UserAgent(), auditUserAgentMaxBytes),
+	}
+
+	if userId, err := uuid.Parse(c.GetString("userId")); err == nil {
+		a.by(userId)
+	}
+
+	return a
+}
+
+// by sets the user who performed the action, for
+// requests that are not authenticated yet, like
+// sign ins.
+func (a *AuditLog) by(userId uuid.UUID) *AuditLog {
+	a.Actor = &AuditActor{ID: userId}
+	return a
+}
+
+// inTeam sets the team the action
+// was performed in.
+func (a *AuditLog) inTeam(teamId uuid.UUID) *AuditLog {
+	a.TeamID = &teamId
+	return a
+}
+
+// inAppTeam sets the team the action was performed
+// in to the team owning the app.
+func (a *AuditLog) inAppTeam(appId uuid.UUID) *AuditLog {
+	a.appId = &appId
+	return a
+}
+
+// on sets the entity the action
+// was performed on.
+func (a *AuditLog) on(targetType, targetId string) *AuditLog {
+	a.TargetType = targetType
+	a.TargetID = truncateBytes(targetId, auditTargetIDMaxBytes)
+	return a
+}
+
+// change sets the fields of the target that changed
+// by the action. Unchanged fields are left out.
+func (a *AuditLog) change(before, after map[string]any) *AuditLog {
+	a.Before, a.After = diffFields(before, after)
+	return a
+}
+
+// record saves the audit log. Failing to record does
+// not fail the action, so errors are only logged.
+func (a *AuditLog) record(ctx context.Context) {
+	stmt := sqlf.PostgreSQL.
+		InsertInto("public.audit_logs").
+		Set("id", a.ID).
+		Set("action", a.Action).
+		Set("ip", a.IP).
+		Set("user_agent", a.UserAgent).
+		Set("created_at", time.Now())
+
+	defer stmt.Close()
+
+	if a.appId != nil {
+		stmt.SetExpr("team_id", "(select team_id from public.apps where id = ?)", a.appId)
+	} else {
+		stmt.Set("team_id", a.TeamID)
+	}
+
+	if a.Actor != nil {
+		stmt.Set("actor_id", a.Actor.ID)
+	}
+
+	if a.TargetType != "" {
+		stmt.Set("target_type", a.TargetType).
+			Set("target_id", a.TargetID)
+	}
+
+	if a.Before != nil {
+		stmt.Set("before", a.Before)
+	}
+
+	if a.After != nil {
+		stmt.Set("after", a.After)
+	}
+
+	if _, err := server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...); err != nil {
+		fmt.Printf("failed to record audit log %q: %v\n", a.Action, err)
+	}
+}
+
+// recordSignin records a successful sign in
+// of the user via the provider.
+func recordSignin(c *gin.Context, userId uuid.UUID, provider string) {
+	newAuditLog(c, auditUserSignedIn).
+		by(userId).
+		on(auditTargetUser, userId.String()).
+		change(nil, map[string]any{"provider": provider}).
+		record(c.Request.Context())
+}
+
+// diffFields keeps only the fields whose values differ
+// between before & after. Returns nils if nothing changed.
+func diffFields(before, after map[string]any) (b, a map[string]any) {
+	for k, v := range before {
+		if w, ok := after[k]; ok && reflect.DeepEqual(v, w) {
+			continue
+		}
+		if b == nil {
+			b = map[string]any{}
+		}
+		b[k] = v
+	}
+
+	for k, w := range after {
+		if v, ok := before[k]; ok && reflect.DeepEqual(v, w) {
+			continue
+		}
+		if a == nil {
+			a = map[string]any{}
+		}
+		a[k] = w
+	}
+
+	return
+}
+
+// truncateBytes truncates the string to at most
+// n bytes without splitting a multi-byte character.
+func truncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+
+	return s[:n]
+}
+
+// expand expands comma separated
+// actions to slice of strings.
+func (q *AuditLogQuery) expand() {
+	if len(q.Actions) > 0 {
+		q.Actions = text.SplitTrimEmpty(q.Actions[0], ",")
+	}
+}
+
+// validate validates the query & applies
+// defaults for pagination.
+func (q *AuditLogQuery) validate() error {
+	if q.ActorID != "" {
+		if _, err := uuid.Parse(q.ActorID); err != nil {
+			return fmt.Errorf("`actor_id` must be a valid uuid")
+		}
+	}
+
+	for _, action := range q.Actions {
+		if !slices.Contains(auditActions, action) {
+			return fmt.Errorf("`actions` contains unknown action %q", action)
+		}
+	}
+
+	if !q.From.IsZero() && !q.To.IsZero() && q.To.Before(q.From) {
+		return fmt.Errorf("`to` must be later time than `from`")
+	}
+
+	if q.Limit < 1 {
+		q.Limit = defaultAuditLogsLimit
+	}
+
+	if q.Limit > maxAuditLogsLimit {
+		return fmt.Errorf("`limit` cannot be more than %d", maxAuditLogsLimit)
+	}
+
+	if q.Offset < 0 {
+		return fmt.Errorf("`offset` cannot be negative")
+	}
+
+	return nil
+}
+
+// getAuditLogs fetches audit logs of the team matching
+// the query, most recent first. Actions not scoped to a
+// team, like sign ins, are included for the team's
+// members. One extra log is fetched to detect the
+// next page.
+func getAuditLogs(ctx context.Context, teamId uuid.UUID, q AuditLogQuery) (logs []AuditLog, err error) {
+	stmt := sqlf.PostgreSQL.
+		From("public.audit_logs").
+		Select("audit_logs.id").
+		Select("audit_logs.team_id").
+		Select("audit_logs.actor_id").
+		Select("users.name").
+		Select("users.email").
+		Select("audit_logs.action").
+		Select("audit_logs.target_type").
+		Select("audit_logs.target_id").
+		Select("audit_logs.before").
+		Select("audit_logs.after").
+		Select("audit_logs.ip").
+		Select("audit_logs.user_agent").
+		Select("audit_logs.created_at").
+		LeftJoin("public.users", "users.id = audit_logs.actor_id").
+		Where("(audit_logs.team_id = ? or (audit_logs.team_id is null and audit_logs.actor_id in (select user_id from public.team_membership where team_id = ?)))", teamId, teamId).
+		OrderBy("audit_logs.created_at desc", "audit_logs.id desc").
+		Limit(q.Limit + 1).
+		Offset(q.Offset)
+
+	defer stmt.Close()
+
+	if q.ActorID != "" {
+		stmt.Where("audit_logs.actor_id = ?", q.ActorID)
+	}
+
+	if len(q.Actions) > 0 {
+		stmt.Where("audit_logs.action = any(?)", q.Actions)
+	}
+
+	if q.TargetType != "" {
+		stmt.Where("audit_logs.target_type = ?", q.TargetType)
+	}
+
+	if q.TargetID != "" {
+		stmt.Where("audit_logs.target_id = ?", q.TargetID)
+	}
+
+	if !q.From.IsZero() {
+		stmt.Where("audit_logs.created_at >= ?", q.From)
+	}
+
+	if !q.To.IsZero() {
+		stmt.Where("audit_logs.created_at <= ?", q.To)
+	}
+
+	rows, err := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var log AuditLog
+		var actorId *uuid.UUID
+		var actorName, actorEmail *string
+		var targetType, targetId, ip, userAgent pgtype.Text
+		var before, after []byte
+		var createdAt chrono.ISOTime
+
+		if err = rows.Scan(&log.ID, &log.TeamID, &actorId, &actorName, &actorEmail, &log.Action, &targetType, &targetId, &before, &after, &ip, &userAgent, &createdAt); err != nil {
+			return
+		}
+
+		if actorId != nil {
+			log.Actor = &AuditActor{
+				ID:    *actorId,
+				Name:  actorName,
+				Email: actorEmail,
+			}
+		}
+
+		if before != nil {
+			if err = json.Unmarshal(before, &log.Before); err != nil {
+				return
+			}
+		}
+
+		if after != nil {
+			if err = json.Unmarshal(after, &log.After); err != nil {
+				return
+			}
+		}
+
+		log.TargetType = targetType.String
+		log.TargetID = targetId.String
+		log.IP = ip.String
+		log.UserAgent = userAgent.String
+		log.CreatedAt = &createdAt
+
+		logs = append(logs, log)
+	}
+
+	err = rows.Err()
+
+	return
+}
+
+func GetTeamAuditLogs(c *gin.Context) {
+	ctx := c.Request.Context()
+	userId := c.GetString("userId")
+	teamId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `team id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	ok, err := PerformAuthz(c, userId, teamId.String(), *ScopeAuditRead)
+	if err != nil {
+		msg := `couldn't perform authorization checks`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+	if !ok {
+		msg := fmt.Sprintf(`you don't have permissions to read audit logs of team [%s]`, teamId)
+		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+		return
+	}
+
+	var query AuditLogQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		msg := `failed to parse query parameters`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg, "details": err.Error()})
+		return
+	}
+
+	query.expand()
+
+	if err := query.validate(); err != nil {
+		msg := `audit logs request validation failed`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg, "details": err.Error()})
+		return
+	}
+
+	logs, err := getAuditLogs(ctx, teamId, query)
+	if err != nil {
+		msg := `failed to fetch audit logs`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	next := len(logs) > query.Limit
+	if next {
+		logs = logs[:query.Limit]
+	}
+
+	if logs == nil {
+		logs = []AuditLog{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": logs,
+		"meta": gin.H{
+			"next":     next,
+			"previous": query.Offset > 0,
+		},
+	})
+}
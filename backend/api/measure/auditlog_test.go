@@ -0,0 +1,111 @@
+package measure
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffFields(t *testing.T) {
+	{
+		// only changed fields are kept
+		before := map[string]any{"name": "acme", "retention_period": 90}
+		after := map[string]any{"name": "acme", "retention_period": 30}
+
+		b, a := diffFields(before, after)
+
+		expectedBefore := map[string]any{"retention_period": 90}
+		expectedAfter := map[string]any{"retention_period": 30}
+
+		if !reflect.DeepEqual(expectedBefore, b) {
+			t.Errorf("Expected %v but got %v", expectedBefore, b)
+		}
+		if !reflect.DeepEqual(expectedAfter, a) {
+			t.Errorf("Expected %v but got %v", expectedAfter, a)
+		}
+	}
+	{
+		// nothing changed
+		before := map[string]any{"role": "admin"}
+		after := map[string]any{"role": "admin"}
+
+		b, a := diffFields(before, after)
+
+		if b != nil || a != nil {
+			t.Errorf("Expected nil diff but got %v, %v", b, a)
+		}
+	}
+	{
+		// fields present only on one side are kept
+		before := map[string]any{"role": "viewer"}
+
+		b, a := diffFields(before, nil)
+
+		if !reflect.DeepEqual(before, b) {
+			t.Errorf("Expected %v but got %v", before, b)
+		}
+		if a != nil {
+			t.Errorf("Expected nil but got %v", a)
+		}
+	}
+}
+
+func TestTruncateBytes(t *testing.T) {
+	cases := map[string]struct {
+		s        string
+		n        int
+		expected string
+	}{
+		"shorter":   {"curl/8.4.0", 32, "curl/8.4.0"},
+		"longer":    {"curl/8.4.0", 4, "curl"},
+		"multibyte": {"naïve", 3, "na"},
+	}
+
+	for name, c := range cases {
+		if got := truncateBytes(c.s, c.n); got != c.expected {
+			t.Errorf("%s: expected %q but got %q", name, c.expected, got)
+		}
+	}
+}
+
+func TestAuditLogQueryValidate(t *testing.T) {
+	{
+		// defaults are applied
+		q := AuditLogQuery{}
+
+		if err := q.validate(); err != nil {
+			t.Errorf("Expected nil error but got %v", err)
+		}
+		if q.Limit != defaultAuditLogsLimit {
+			t.Errorf("Expected limit %d but got %d", defaultAuditLogsLimit, q.Limit)
+		}
+	}
+	{
+		// comma separated actions are expanded
+		q := AuditLogQuery{Actions: []string{"member.removed, member.role_changed"}}
+		q.expand()
+
+		expected := []string{auditMemberRemoved, auditMemberRoleChanged}
+		if !reflect.DeepEqual(expected, q.Actions) {
+			t.Errorf("Expected %v but got %v", expected, q.Actions)
+		}
+		if err := q.validate(); err != nil {
+			t.Errorf("Expected nil error but got %v", err)
+		}
+	}
+
+	now := time.Now()
+	invalid := map[string]AuditLogQuery{
+		"actor id": {ActorID: "not-a-uuid"},
+		"action":   {Actions: []string{"team.exploded"}},
+		"range":    {From: now, To: now.Add(-time.Hour)},
+		"limit":    {Limit: maxAuditLogsLimit + 1},
+		"offset":   {Offset: -1},
+	}
+
+	for name, q := range invalid {
+		if err := q.validate(); err == nil {
+			t.Errorf("%s: expected error but got nil", name)
+		}
+	}
+}
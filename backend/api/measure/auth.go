@@ -91,31 +91,53 @@ func ValidateAPIKey() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		key := extractToken(c)
 
-		appId, err := DecodeAPIKey(key)
+		apiKey, err := DecodeAPIKey(key)
 		if err != nil {
+			if errors.Is(err, ErrAPIKeyRevoked) {
+				msg := "api key has been revoked"
+				fmt.Println(msg)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": msg})
+				return
+			}
+			if errors.Is(err, ErrAppDeleted) {
+				msg := "app has been deleted"
+				fmt.Println(msg)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": msg})
+				return
+			}
 			fmt.Println("api key decode failed:", err)
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid api key"})
 			return
 		}
 
-		if appId == nil {
+		if apiKey == nil {
 			msg := "no app found for this api key"
 			fmt.Println(msg)
 			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": msg})
 			return
 		}
 
-		c.Set("appId", appId.String())
+		if err := apiKey.markSeen(c.Request.Context()); err != nil {
+			fmt.Println("failed to update api key last seen:", err)
+		}
+
+		c.Set("appId", apiKey.appId.String())
 
 		c.Next()
 	}
 }
 
-// ValidateAccessToken validates Measure access tokens.
+// ValidateAccessToken validates Measure access tokens
+// & personal access tokens.
 func ValidateAccessToken() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := extractToken(c)
 
+		if isAccessToken(token) {
+			validatePersonalAccessToken(c, token)
+			return
+		}
+
 		accessToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				err := fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
@@ -402,6 +424,9 @@ func SigninGitHub(c *gin.Context) {
 			return
 		}
 
+		acceptInvitesOnSignin(c, msrUser, "github")
+		recordSignin(c, userId, "github")
+
 		c.JSON(http.StatusOK, gin.H{
 			"access_token":  authSess.AccessToken,
 			"refresh_token": authSess.RefreshToken,
@@ -607,6 +632,9 @@ func SigninGoogle(c *gin.Context) {
 		return
 	}
 
+	acceptInvitesOnSignin(c, msrUser, "google")
+	recordSignin(c, userId, "google")
+
 	c.JSON(http.StatusOK, gin.H{
 		"access_token":  authSess.AccessToken,
 		"refresh_token": authSess.RefreshToken,
@@ -1,10 +1,15 @@
 package measure
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
 	"slices"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 const (
@@ -81,8 +86,27 @@ var (
 	ScopeAlertRead                 = newScope("alert", "read")
 	ScopeAppAll                    = newScope("app", "*")
 	ScopeAppRead                   = newScope("app", "read")
+	ScopeAuditRead                 = newScope("audit", "read")
 )
 
+// tokenScopesKey is the context key holding the
+// scopes of the personal access token used to
+// authenticate the request.
+const tokenScopesKey = "tokenScopes"
+
+var allScopes = []*scope{
+	ScopeBillingAll,
+	ScopeTeamAll,
+	ScopeTeamRead,
+	ScopeTeamInviteSameOrLower,
+	ScopeTeamChangeRoleSameOrLower,
+	ScopeAlertAll,
+	ScopeAlertRead,
+	ScopeAppAll,
+	ScopeAppRead,
+	ScopeAuditRead,
+}
+
 type scope struct {
 	resource string
 	perm     string
@@ -109,7 +133,7 @@ func (s scope) getRolesSameOrLower(r rank) []rank {
 
 var scopeMap = map[rank][]scope{
 	owner:     {*ScopeBillingAll, *ScopeTeamAll, *ScopeAlertAll, *ScopeAppAll},
-	admin:     {*ScopeBillingAll, *ScopeAlertAll, *ScopeAppAll, *ScopeTeamInviteSameOrLower, *ScopeTeamChangeRoleSameOrLower},
+	admin:     {*ScopeBillingAll, *ScopeAlertAll, *ScopeAppAll, *ScopeTeamInviteSameOrLower, *ScopeTeamChangeRoleSameOrLower, *ScopeAuditRead},
 	developer: {*ScopeAlertAll, *ScopeAppAll, *ScopeTeamInviteSameOrLower, *ScopeTeamChangeRoleSameOrLower},
 	viewer:    {*ScopeAlertRead, *ScopeTeamRead, *ScopeTeamInviteSameOrLower, *ScopeAppRead},
 }
@@ -125,6 +149,18 @@ func (s *scope) String() string {
 	return fmt.Sprintf("%s:%s", s.resource, s.perm)
 }
 
+// parseScope parses a scope from
+// its "resource:perm" form.
+func parseScope(str string) (scope, bool) {
+	for _, s := range allScopes {
+		if s.String() == str {
+			return *s, true
+		}
+	}
+
+	return scope{}, false
+}
+
 func newScope(resource, perm string) *scope {
 	return &scope{
 		resource: resource,
@@ -132,7 +168,11 @@ func newScope(resource, perm string) *scope {
 	}
 }
 
-func PerformAuthz(uid string, rid string, scope scope) (bool, error) {
+// PerformAuthz checks if the user's role in the team
+// grants the scope. For requests authenticated using a
+// personal access token, the token's scopes must grant
+// the scope as well.
+func PerformAuthz(ctx context.Context, uid string, rid string, scope scope) (bool, error) {
 	u := &User{
 		ID: &uid,
 	}
@@ -145,59 +185,174 @@ func PerformAuthz(uid string, rid string, scope scope) (bool, error) {
 	if role == unknown {
 		return false, errors.New("received 'unknown' role")
 	}
-	roleScope := scopeMap[role]
 
+	return authorize(ctx, role, scope), nil
+}
+
+// PerformAppAuthz checks if the user's role for the app
+// grants the scope. The user's role override for the app,
+// if any, takes the place of the user's role in the team.
+// Handlers acting on an app must use it, usually through
+// authzApp, instead of PerformAuthz, so that overrides are
+// always enforced.
+func PerformAppAuthz(ctx context.Context, uid string, tid string, aid string, scope scope) (bool, error) {
+	u := &User{
+		ID: &uid,
+	}
+
+	role, err := u.getRole(tid)
+	if err != nil {
+		return false, err
+	}
+
+	if role == unknown {
+		return false, errors.New("received 'unknown' role")
+	}
+
+	role, err = u.getAppRole(ctx, aid, role)
+	if err != nil {
+		return false, err
+	}
+
+	// an override of 'none' denies
+	// all access to the app
+	if role == unknown {
+		return false, nil
+	}
+
+	return authorize(ctx, role, scope), nil
+}
+
+// authzApp resolves the app's team & checks if the user's
+// role for the app grants the scope. Writes the error
+// response & returns false if the check fails. Handlers
+// of `/apps/:id` routes must use it.
+func authzApp(c *gin.Context, appId uuid.UUID, scope scope) bool {
+	app := App{
+		ID: &appId,
+	}
+
+	team, err := app.getTeam(c.Request.Context())
+	if err != nil {
+		msg := "failed to get team from app id"
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return false
+	}
+	if team == nil {
+		msg := fmt.Sprintf("no team exists for app [%s]", appId)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return false
+	}
+
+	ok, err := PerformAppAuthz(c, c.GetString("userId"), team.ID.String(), appId.String(), scope)
+	if err != nil {
+		msg := `couldn't perform authorization checks`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return false
+	}
+	if !ok {
+		msg := fmt.Sprintf(`you don't have the %q permission for app [%s]`, scope.String(), appId)
+		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+		return false
+	}
+
+	return true
+}
+
+// authorize reports if the role grants the scope. For
+// requests authenticated using a personal access token,
+// the token's scopes must grant the scope as well.
+func authorize(ctx context.Context, role rank, scope scope) bool {
+	if !grants(scopeMap[role], scope) {
+		return false
+	}
+
+	if scopes, ok := tokenScopes(ctx); ok {
+		return grants(scopes, scope)
+	}
+
+	return true
+}
+
+// tokenScopes returns the scopes of the personal
+// access token used to authenticate the request,
+// if any.
+func tokenScopes(ctx context.Context) ([]scope, bool) {
+	scopes, ok := ctx.Value(tokenScopesKey).([]scope)
+	return scopes, ok
+}
+
+// grants reports if the list of scopes
+// grants the scope.
+func grants(scopes []scope, scope scope) bool {
 	switch scope {
 	case *ScopeTeamRead:
-		if slices.Contains(roleScope, *ScopeTeamAll) {
-			return true, nil
+		if slices.Contains(scopes, *ScopeTeamAll) {
+			return true
 		}
-		if slices.Contains(roleScope, *ScopeTeamInviteSameOrLower) {
-			return true, nil
+		if slices.Contains(scopes, *ScopeTeamInviteSameOrLower) {
+			return true
 		}
-		if slices.Contains(roleScope, *ScopeTeamRead) {
-			return true, nil
+		if slices.Contains(scopes, *ScopeTeamRead) {
+			return true
 		}
 
-		return false, nil
+		return false
 	case *ScopeAppAll:
-		if slices.Contains(roleScope, *ScopeAppAll) {
-			return true, nil
+		if slices.Contains(scopes, *ScopeAppAll) {
+			return true
 		}
 
-		return false, nil
+		return false
 	case *ScopeAppRead:
-		if slices.Contains(roleScope, *ScopeAppAll) {
-			return true, nil
+		if slices.Contains(scopes, *ScopeAppAll) {
+			return true
 		}
-		if slices.Contains(roleScope, *ScopeAppRead) {
-			return true, nil
+		if slices.Contains(scopes, *ScopeAppRead) {
+			return true
 		}
 
-		return false, nil
+		return false
 	case *ScopeTeamInviteSameOrLower:
-		if slices.Contains(roleScope, *ScopeTeamAll) {
-			return true, nil
+		if slices.Contains(scopes, *ScopeTeamAll) {
+			return true
 		}
-		if slices.Contains(roleScope, *ScopeTeamInviteSameOrLower) {
-			return true, nil
+		if slices.Contains(scopes, *ScopeTeamInviteSameOrLower) {
+			return true
 		}
-		return false, nil
+		return false
 	case *ScopeTeamChangeRoleSameOrLower:
-		if slices.Contains(roleScope, *ScopeTeamAll) {
-			return true, nil
+		if slices.Contains(scopes, *ScopeTeamAll) {
+			return true
 		}
-		if slices.Contains(roleScope, *ScopeTeamChangeRoleSameOrLower) {
-			return true, nil
+		if slices.Contains(scopes, *ScopeTeamChangeRoleSameOrLower) {
+			return true
 		}
-		return false, nil
+		return false
 	case *ScopeTeamAll:
-		if slices.Contains(roleScope, *ScopeTeamAll) {
-			return true, nil
+		if slices.Contains(scopes, *ScopeTeamAll) {
+			return true
 		}
 
-		return false, nil
+		return false
+	case *ScopeBillingAll:
+		if slices.Contains(scopes, *ScopeBillingAll) {
+			return true
+		}
+
+		return false
+	case *ScopeAuditRead:
+		if slices.Contains(scopes, *ScopeTeamAll) {
+			return true
+		}
+		if slices.Contains(scopes, *ScopeAuditRead) {
+			return true
+		}
+
+		return false
 	default:
-		return false, nil
+		return false
 	}
 }
@@ -1,6 +1,7 @@
 package measure
 
 import (
+	"context"
 	"reflect"
 	"testing"
 )
@@ -114,3 +115,83 @@ func TestSameOrLowerRoleFromScope(t *testing.T) {
 	}
 
 }
+
+func TestGrants(t *testing.T) {
+	{
+		// app wildcard grants read
+		scopes := []scope{*ScopeAppAll}
+
+		if !grants(scopes, *ScopeAppRead) {
+			t.Errorf("Expected %v to grant %v", scopes, ScopeAppRead)
+		}
+	}
+	{
+		// app read does not grant wildcard
+		scopes := []scope{*ScopeAppRead}
+
+		if grants(scopes, *ScopeAppAll) {
+			t.Errorf("Expected %v to not grant %v", scopes, ScopeAppAll)
+		}
+	}
+	{
+		// app scopes do not grant team scopes
+		scopes := []scope{*ScopeAppAll}
+
+		if grants(scopes, *ScopeTeamRead) {
+			t.Errorf("Expected %v to not grant %v", scopes, ScopeTeamRead)
+		}
+	}
+	{
+		// every role grants app read
+		for _, r := range []rank{owner, admin, developer, viewer} {
+			if !grants(scopeMap[r], *ScopeAppRead) {
+				t.Errorf("Expected %v to grant %v", r, ScopeAppRead)
+			}
+		}
+	}
+	{
+		// only owners & admins can read audit logs
+		for _, r := range []rank{owner, admin} {
+			if !grants(scopeMap[r], *ScopeAuditRead) {
+				t.Errorf("Expected %v to grant %v", r, ScopeAuditRead)
+			}
+		}
+		for _, r := range []rank{developer, viewer} {
+			if grants(scopeMap[r], *ScopeAuditRead) {
+				t.Errorf("Expected %v to not grant %v", r, ScopeAuditRead)
+			}
+		}
+	}
+	{
+		// only owners & admins can change billing
+		for _, r := range []rank{owner, admin} {
+			if !grants(scopeMap[r], *ScopeBillingAll) {
+				t.Errorf("Expected %v to grant %v", r, ScopeBillingAll)
+			}
+			if !authorize(context.Background(), r, *ScopeBillingAll) {
+				t.Errorf("Expected %v to be authorized for %v", r, ScopeBillingAll)
+			}
+		}
+		for _, r := range []rank{developer, viewer} {
+			if grants(scopeMap[r], *ScopeBillingAll) {
+				t.Errorf("Expected %v to not grant %v", r, ScopeBillingAll)
+			}
+			if authorize(context.Background(), r, *ScopeBillingAll) {
+				t.Errorf("Expected %v to not be authorized for %v", r, ScopeBillingAll)
+			}
+		}
+	}
+}
+
+func TestParseScope(t *testing.T) {
+	for _, s := range allScopes {
+		result, ok := parseScope(s.String())
+		if !ok || result != *s {
+			t.Errorf("Expected %v but got %v", *s, result)
+		}
+	}
+
+	if _, ok := parseScope("app:write"); ok {
+		t.Error("Expected unknown scope to fail parsing")
+	}
+}
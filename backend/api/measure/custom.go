@@ -0,0 +1,239 @@
+package measure
+
+import (
+	"backend/api/event"
+	"backend/api/filter"
+	"backend/api/metrics"
+	"backend/api/server"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/leporo/sqlf"
+)
+
+// filterCustomEvents applies the custom event
+// name & property filters to a statement
+// querying events.
+//
+// A property matches if the event has the key
+// with the value, as string, number or bool.
+func filterCustomEvents(stmt *sqlf.Stmt, af *filter.AppFilter) {
+	stmt.Where("type = ?", event.TypeCustom)
+
+	if af.CustomEventName != "" {
+		stmt.Where("custom.name = ?", af.CustomEventName)
+	}
+
+	keys, values := af.CustomEventPropertyPairs()
+	for i := range keys {
+		stmt.Where(
+			"("+
+				"(mapContains(custom_string_properties, ?) AND custom_string_properties[?] = ?) OR "+
+				"(mapContains(custom_number_properties, ?) AND custom_number_properties[?] = toFloat64OrNull(?)) OR "+
+				"(mapContains(custom_bool_properties, ?) AND toString(custom_bool_properties[?]) = ?)"+
+				")",
+			keys[i], keys[i], values[i],
+			keys[i], keys[i], values[i],
+			keys[i], keys[i], values[i])
+	}
+}
+
+// GetCustomEventMetrics counts custom events &
+// the sessions having them grouped by custom
+// event name while respecting all applicable
+// app filters.
+func GetCustomEventMetrics(ctx context.Context, af *filter.AppFilter) (customMetrics []metrics.CustomEventMetric, err error) {
+	stmt := sqlf.
+		From("default.events").
+		Select("custom.name as name").
+		Select("count() as count").
+		Select("uniq(session_id) as sessions")
+
+	defer stmt.Close()
+
+	filterEvents(stmt, af)
+	filterCustomEvents(stmt, af)
+
+	if af.FreeText != "" {
+		stmt.Where("custom.name ILIKE ?", "%"+af.FreeText+"%")
+	}
+
+	stmt.
+		GroupBy("custom.name").
+		OrderBy("count desc, name")
+
+	rows, err := server.Server.ChPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	for rows.Next() {
+		var customMetric metrics.CustomEventMetric
+		if err = rows.Scan(&customMetric.Name, &customMetric.Count, &customMetric.Sessions); err != nil {
+			return
+		}
+
+		customMetrics = append(customMetrics, customMetric)
+	}
+
+	err = rows.Err()
+
+	return
+}
+
+// GetCustomEventPlotInstances queries custom event
+// instances by datetime and filters.
+func GetCustomEventPlotInstances(ctx context.Context, af *filter.AppFilter) (customInstances []event.CustomInstance, err error) {
+	if af.Timezone == "" {
+		return nil, errors.New("missing timezone filter")
+	}
+
+	stmt := sqlf.
+		From("default.events").
+		Select("formatDateTime(timestamp, '%Y-%m-%d', ?) as datetime", af.Timezone).
+		Select("concat(toString(attribute.app_version), '', '(', toString(attribute.app_build), ')') as app_version").
+		Select("count() as instances")
+
+	defer stmt.Close()
+
+	filterEvents(stmt, af)
+	filterCustomEvents(stmt, af)
+
+	stmt.
+		GroupBy("app_version, datetime").
+		OrderBy("app_version, datetime")
+
+	rows, err := server.Server.ChPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	for rows.Next() {
+		var instance event.CustomInstance
+		if err = rows.Scan(&instance.DateTime, &instance.Version, &instance.Instances); err != nil {
+			return
+		}
+
+		customInstances = append(customInstances, instance)
+	}
+
+	err = rows.Err()
+
+	return
+}
+
+// GetCustomEvents provides the count of an app's
+// custom events & of the sessions having them by
+// custom event name.
+func GetCustomEvents(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	af := filter.AppFilter{
+		AppID: id,
+		Limit: filter.DefaultPaginationLimit,
+	}
+
+	if !parseEventFilter(c, &af, `custom events request validation failed`) {
+		return
+	}
+
+	if !authzApp(c, id, *ScopeAppRead) {
+		return
+	}
+
+	customMetrics, err := GetCustomEventMetrics(ctx, &af)
+	if err != nil {
+		msg := `failed to query custom event metrics`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	if customMetrics == nil {
+		customMetrics = []metrics.CustomEventMetric{}
+	}
+
+	c.JSON(http.StatusOK, customMetrics)
+}
+
+// GetCustomEventsPlotInstances provides the daily
+// count of an app's custom events by app version.
+func GetCustomEventsPlotInstances(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	af := filter.AppFilter{
+		AppID: id,
+		Limit: filter.DefaultPaginationLimit,
+	}
+
+	if !parseEventFilter(c, &af, `custom events plot instances request validation failed`) {
+		return
+	}
+
+	if !authzApp(c, id, *ScopeAppRead) {
+		return
+	}
+
+	customInstances, err := GetCustomEventPlotInstances(ctx, &af)
+	if err != nil {
+		msg := `failed to query data for custom events plot`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	type instance struct {
+		ID   string  `json:"id"`
+		Data []gin.H `json:"data"`
+	}
+
+	lut := make(map[string]int)
+	instances := []instance{}
+
+	for i := range customInstances {
+		instance := instance{
+			ID: customInstances[i].Version,
+			Data: []gin.H{{
+				"datetime":  customInstances[i].DateTime,
+				"instances": customInstances[i].Instances,
+			}},
+		}
+
+		ndx, ok := lut[customInstances[i].Version]
+
+		if ok {
+			instances[ndx].Data = append(instances[ndx].Data, instance.Data...)
+		} else {
+			instances = append(instances, instance)
+			lut[customInstances[i].Version] = len(instances) - 1
+		}
+	}
+
+	c.JSON(http.StatusOK, instances)
+}
@@ -0,0 +1,140 @@
+package measure
+
+import (
+	"backend/api/codec"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// multipartHeadroom is the allowance in bytes for
+// multipart boundaries & part headers over the
+// maximum payload size of multipart requests.
+const multipartHeadroom = 1024 * 1024
+
+// bodyMetrics are the instruments recording
+// sizes of request bodies.
+type bodyMetrics struct {
+	// size records the size of bodies
+	// before decompression.
+	size metric.Int64Histogram
+
+	// decompressedSize records the size
+	// of bodies after decompression.
+	decompressedSize metric.Int64Histogram
+
+	// compressionRatio records the ratio of
+	// decompressed to compressed size of
+	// compressed bodies.
+	compressionRatio metric.Float64Histogram
+}
+
+// requestBodyMetrics records sizes of
+// request bodies.
+var requestBodyMetrics = newBodyMetrics(otel.Meter("request-body-meter"))
+
+// newBodyMetrics creates the instruments
+// recording sizes of request bodies.
+func newBodyMetrics(meter metric.Meter) (m bodyMetrics) {
+	var err error
+
+	// instruments are usable even
+	// when creation fails
+	m.size, err = meter.Int64Histogram(
+		"http.server.request.body.size",
+		metric.WithDescription("Size of request bodies before decompression."),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		fmt.Println("failed to create request body size histogram", err)
+	}
+
+	m.decompressedSize, err = meter.Int64Histogram(
+		"http.server.request.body.decompressed_size",
+		metric.WithDescription("Size of request bodies after decompression."),
+		metric.WithUnit("By"),
+	)
+	if err != nil {
+		fmt.Println("failed to create request body decompressed size histogram", err)
+	}
+
+	m.compressionRatio, err = meter.Float64Histogram(
+		"http.server.request.body.compression_ratio",
+		metric.WithDescription("Ratio of decompressed to compressed size of compressed request bodies."),
+		metric.WithUnit("1"),
+	)
+	if err != nil {
+		fmt.Println("failed to create request body compression ratio histogram", err)
+	}
+
+	return
+}
+
+// decodeBody replaces the request's body with one
+// decompressed as per its `Content-Encoding` while
+// being read, so that large bodies are never held
+// compressed & decompressed at once. Reading more
+// than limit bytes, before or after decompression,
+// fails.
+func decodeBody(c *gin.Context, limit int64) (*codec.Body, error) {
+	body, err := codec.NewBody(c.Request.Body, c.GetHeader("Content-Encoding"), limit)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Request.Body = body
+	c.Request.ContentLength = -1
+	c.Request.Header.Del("Content-Encoding")
+
+	return body, nil
+}
+
+// decodeBodyStatus provides the response status
+// for errors of decoding & reading request bodies.
+func decodeBodyStatus(err error) int {
+	switch {
+	case errors.Is(err, codec.ErrTooLarge), errors.Is(err, codec.ErrRatio):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, codec.ErrUnsupportedEncoding):
+		return http.StatusUnsupportedMediaType
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+// recordBodySize records the size of a request's
+// body, before & after decompression, along with
+// its compression ratio as metrics & on the
+// request's trace.
+func recordBodySize(c *gin.Context, body *codec.Body) {
+	ctx := c.Request.Context()
+
+	attrs := metric.WithAttributes(
+		attribute.String("http.route", c.FullPath()),
+		attribute.String("http.request.content_encoding", body.Encoding),
+	)
+
+	requestBodyMetrics.size.Record(ctx, body.WireSize(), attrs)
+	requestBodyMetrics.decompressedSize.Record(ctx, body.Size(), attrs)
+
+	if body.IsCompressed() {
+		requestBodyMetrics.compressionRatio.Record(ctx, body.Ratio(), attrs)
+	}
+
+	span := trace.SpanFromContext(ctx)
+
+	if span.IsRecording() {
+		span.SetAttributes(
+			attribute.String("http.request.content_encoding", body.Encoding),
+			attribute.Int64("http.request.body.size", body.WireSize()),
+			attribute.Int64("http.request.body.decompressed_size", body.Size()),
+			attribute.Float64("http.request.body.compression_ratio", body.Ratio()),
+		)
+	}
+}
@@ -0,0 +1,115 @@
+package measure
+
+import (
+	"backend/api/codec"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+func TestDecodeBodyStatus(t *testing.T) {
+	cases := map[error]int{
+		codec.ErrTooLarge:            http.StatusRequestEntityTooLarge,
+		codec.ErrRatio:               http.StatusRequestEntityTooLarge,
+		codec.ErrUnsupportedEncoding: http.StatusUnsupportedMediaType,
+		io.ErrUnexpectedEOF:          http.StatusBadRequest,
+	}
+
+	for err, expected := range cases {
+		if got := decodeBodyStatus(err); got != expected {
+			t.Errorf("Expected status %d for %v, but got %d", expected, err, got)
+		}
+	}
+}
+
+func TestRecordBodySize(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+
+	metrics := requestBodyMetrics
+	requestBodyMetrics = newBodyMetrics(provider.Meter("test"))
+	defer func() { requestBodyMetrics = metrics }()
+
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.PUT("/events", func(c *gin.Context) {
+		body, err := decodeBody(c, 1024*1024)
+		if err != nil {
+			t.Fatalf("Expected nil error, but got %v", err)
+		}
+		if _, err := io.ReadAll(c.Request.Body); err != nil {
+			t.Fatalf("Expected nil error, but got %v", err)
+		}
+		recordBodySize(c, body)
+	})
+
+	data := strings.Repeat(`{"type":"string","string":{"string":"hello"}}`, 100)
+
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	writer.Write([]byte(data))
+	writer.Close()
+	wireSize := int64(compressed.Len())
+
+	req := httptest.NewRequest(http.MethodPut, "/events", &compressed)
+	req.Header.Set("Content-Encoding", "gzip")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	req = httptest.NewRequest(http.MethodPut, "/events", strings.NewReader(data))
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	sums := make(map[string]float64)
+	counts := make(map[string]uint64)
+
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			switch data := m.Data.(type) {
+			case metricdata.Histogram[int64]:
+				for _, dp := range data.DataPoints {
+					if route, _ := dp.Attributes.Value("http.route"); route.AsString() != "/events" {
+						t.Errorf("Expected route %q, but got %q", "/events", route.AsString())
+					}
+					sums[m.Name] += float64(dp.Sum)
+					counts[m.Name] += dp.Count
+				}
+			case metricdata.Histogram[float64]:
+				for _, dp := range data.DataPoints {
+					sums[m.Name] += dp.Sum
+					counts[m.Name] += dp.Count
+				}
+			}
+		}
+	}
+
+	if got := sums["http.server.request.body.size"]; got != float64(wireSize+int64(len(data))) {
+		t.Errorf("Expected body size sum %d, but got %v", wireSize+int64(len(data)), got)
+	}
+
+	if got := sums["http.server.request.body.decompressed_size"]; got != float64(2*len(data)) {
+		t.Errorf("Expected decompressed size sum %d, but got %v", 2*len(data), got)
+	}
+
+	// only compressed bodies have
+	// a compression ratio
+	if got := counts["http.server.request.body.compression_ratio"]; got != 1 {
+		t.Errorf("Expected %d compression ratio recording, but got %d", 1, got)
+	}
+
+	if got := sums["http.server.request.body.compression_ratio"]; got != float64(len(data))/float64(wireSize) {
+		t.Errorf("Expected compression ratio %v, but got %v", float64(len(data))/float64(wireSize), got)
+	}
+}
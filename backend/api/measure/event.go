@@ -8,10 +8,13 @@ import (
 	"backend/api/inet"
 	"backend/api/server"
 	"backend/api/symbol"
+	"backend/api/webhook"
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
 	"net"
 	"net/http"
@@ -19,6 +22,8 @@ import (
 	"path/filepath"
 	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -31,12 +36,21 @@ import (
 // size of event request in bytes.
 var maxBatchSize = 20 * 1024 * 1024
 
+// proguardCache caches parsed proguard mappings
+// across event requests.
+var proguardCache *symbol.ProguardCache
+
+// proguardCacheOnce guards creation
+// of the proguard cache.
+var proguardCacheOnce sync.Once
+
 type attachment struct {
 	id       uuid.UUID
 	name     string
 	key      string
 	location string
 	header   *multipart.FileHeader
+	data     []byte
 	uploaded bool
 }
 
@@ -47,29 +61,35 @@ type eventreq struct {
 	exceptionIds           []int
 	anrIds                 []int
 	size                   int64
+	wireSize               int64
+	encoding               string
 	symbolicationAttempted int
 	events                 []event.EventField
 	attachments            map[uuid.UUID]*attachment
+	webhookEvents          []webhook.Event
+
+	// ingested contains ids of events already
+	// written by an earlier attempt
+	ingested map[uuid.UUID]bool
+
+	// replay is true when stored events
+	// are bucketed again
+	replay bool
 }
 
 // uploadAttachments prepares and uploads each attachment.
 func (e *eventreq) uploadAttachments() error {
 	for id, attachment := range e.attachments {
-		ext := filepath.Ext(attachment.header.Filename)
+		ext := filepath.Ext(attachment.name)
 		key := attachment.id.String() + ext
 
 		eventAttachment := event.Attachment{
 			ID:   id,
-			Name: attachment.header.Filename,
+			Name: attachment.name,
 			Key:  key,
 		}
 
-		file, err := attachment.header.Open()
-		if err != nil {
-			return err
-		}
-
-		eventAttachment.Reader = file
+		eventAttachment.Reader = bytes.NewReader(attachment.data)
 
 		output, err := eventAttachment.Upload()
 		if err != nil {
@@ -84,6 +104,31 @@ func (e *eventreq) uploadAttachments() error {
 	return nil
 }
 
+// readAttachments reads the contents of each
+// attachment of the payload in memory.
+func (e *eventreq) readAttachments() error {
+	for _, attachment := range e.attachments {
+		if attachment.header == nil || attachment.data != nil {
+			continue
+		}
+
+		file, err := attachment.header.Open()
+		if err != nil {
+			return err
+		}
+
+		data, err := io.ReadAll(file)
+		file.Close()
+		if err != nil {
+			return err
+		}
+
+		attachment.data = data
+	}
+
+	return nil
+}
+
 // bumpSize increases the payload size of
 // events in bytes.
 func (e *eventreq) bumpSize(n int64) {
@@ -134,18 +179,6 @@ func (e *eventreq) read(c *gin.Context, appId uuid.UUID) error {
 		e.bumpSize(int64(len(bytes)))
 		ev.AppID = appId
 
-		if ev.NeedsSymbolication() {
-			e.symbolicate[ev.ID] = i
-		}
-
-		if ev.IsUnhandledException() {
-			e.exceptionIds = append(e.exceptionIds, i)
-		}
-
-		if ev.IsANR() {
-			e.anrIds = append(e.anrIds, i)
-		}
-
 		// compute launch timings
 		if ev.IsColdLaunch() {
 			ev.ColdLaunch.Compute()
@@ -167,7 +200,7 @@ func (e *eventreq) read(c *gin.Context, appId uuid.UUID) error {
 			ev.HotLaunch.Compute()
 		}
 
-		e.events = append(e.events, ev)
+		e.push(ev)
 	}
 
 	for key, headers := range form.File {
@@ -197,6 +230,28 @@ func (e *eventreq) read(c *gin.Context, appId uuid.UUID) error {
 	return nil
 }
 
+// push appends the event to the event request and
+// indexes it for symbolication and bucketing.
+func (e *eventreq) push(ev event.EventField) {
+	i := len(e.events)
+
+	if ev.NeedsSymbolication() {
+		e.symbolicate[ev.ID] = i
+	}
+
+	// native crashes are bucketed
+	// alongside unhandled exceptions
+	if ev.IsUnhandledException() || ev.IsNativeCrash() {
+		e.exceptionIds = append(e.exceptionIds, i)
+	}
+
+	if ev.IsANR() {
+		e.anrIds = append(e.anrIds, i)
+	}
+
+	e.events = append(e.events, ev)
+}
+
 // infuseInet looks up the country code for the IP
 // and infuses the country code and IP info to each event.
 func (e *eventreq) infuseInet(rawIP string) error {
@@ -260,6 +315,8 @@ func (e eventreq) save(ctx context.Context, tx *pgx.Tx) (err error) {
 		Set(`attachment_count`, len(e.attachments)).
 		Set(`session_count`, e.sessionCount()).
 		Set(`bytes_in`, e.size).
+		Set(`bytes_in_wire`, e.wireSize).
+		Set(`content_encoding`, e.encoding).
 		Set(`symbolication_attempts_count`, e.symbolicationAttempted)
 
 	defer stmt.Close()
@@ -287,6 +344,24 @@ func (e eventreq) hasAttachments() bool {
 	return len(e.attachments) > 0
 }
 
+// isSymbolicated returns true if the event does
+// not need symbolication or was symbolicated.
+func (e eventreq) isSymbolicated(id uuid.UUID) bool {
+	_, pending := e.symbolicate[id]
+	return !pending
+}
+
+// reopens checks if the event reopens the resolved
+// group. Replayed events never reopen groups, as
+// they arrived before the group was resolved.
+func (e eventreq) reopens(lifecycle group.Lifecycle, ev event.EventField) bool {
+	if e.replay {
+		return false
+	}
+
+	return lifecycle.Regresses(ev.Attribute.AppVersion, ev.Attribute.AppBuild)
+}
+
 // getSymbolicationEvents extracts events from
 // the event request that should be symbolicated.
 func (e eventreq) getSymbolicationEvents() (events []event.EventField) {
@@ -302,7 +377,7 @@ func (e eventreq) getSymbolicationEvents() (events []event.EventField) {
 }
 
 // getUnhandledExceptions returns unhandled excpetions
-// from the event payload.
+// and native crashes from the event payload.
 func (e eventreq) getUnhandledExceptions() (events []event.EventField) {
 	if !e.hasUnhandledExceptions() {
 		return
@@ -325,90 +400,407 @@ func (e eventreq) getANRs() (events []event.EventField) {
 }
 
 // bucketUnhandledExceptions groups unhandled exceptions
-// based on similarity.
-func (e eventreq) bucketUnhandledExceptions(ctx context.Context, tx *pgx.Tx) (err error) {
+// and native crashes based on similarity.
+func (e *eventreq) bucketUnhandledExceptions(ctx context.Context, tx *pgx.Tx) (err error) {
 	events := e.getUnhandledExceptions()
 
 	app := App{
 		ID: &e.appId,
 	}
 
+	// groups caches groups matched or created
+	// in this request, as groups created inside
+	// the transaction are not visible to lookups.
+	groups := make(map[string]*group.ExceptionGroup)
+	volumes := make(map[string]*webhook.Volume)
+	var fingerprints []string
+
 	for i := range events {
-		if events[i].Exception.Fingerprint == "" {
+		fingerprint := events[i].Exception.Fingerprint
+		if events[i].IsNativeCrash() {
+			fingerprint = events[i].NativeCrash.Fingerprint
+		}
+		if fingerprint == "" {
 			msg := fmt.Sprintf("no fingerprint found for event %q, cannot bucket exception", events[i].ID)
 			fmt.Println(msg)
 			continue
 		}
 
-		matchedGroup, err := app.GetExceptionGroupByFingerprint(ctx, events[i].Exception.Fingerprint)
-		if err != nil {
-			return err
+		matchedGroup, cached := groups[fingerprint]
+		if !cached {
+			matchedGroup, err = app.GetExceptionGroupByFingerprint(ctx, fingerprint)
+			if err != nil {
+				return err
+			}
+
+			// merged groups resolve to the surviving
+			// group, which may already be cached
+			if matchedGroup != nil {
+				if survivor, ok := groups[matchedGroup.Fingerprint]; ok {
+					matchedGroup = survivor
+				}
+			}
 		}
 
 		if matchedGroup == nil {
-			exceptionGroup := group.NewExceptionGroup(events[i].AppID, events[i].Exception.GetType(), events[i].Exception.GetMessage(), events[i].Exception.GetMethodName(), events[i].Exception.GetFileName(), events[i].Exception.GetLineNumber(), events[i].Exception.Fingerprint, events[i].Timestamp)
+			exceptionGroup := newExceptionGroup(events[i])
 			if err := exceptionGroup.Insert(ctx, tx); err != nil {
 				return err
 			}
 
-			return nil
+			groups[fingerprint] = exceptionGroup
+			volumes[fingerprint] = &webhook.Volume{Current: 1}
+			fingerprints = append(fingerprints, fingerprint)
+			e.webhookEvents = append(e.webhookEvents, webhook.NewGroupEvent(e.appId, exceptionWebhookGroup(*exceptionGroup)))
+
+			continue
 		}
 
-		if !matchedGroup.EventExists(events[i].ID) {
-			if err := matchedGroup.UpdateTimeStamps(ctx, &events[i], tx); err != nil {
+		groups[fingerprint] = matchedGroup
+		groups[matchedGroup.Fingerprint] = matchedGroup
+
+		// reopen resolved groups when events
+		// arrive from newer app versions
+		if e.reopens(matchedGroup.Lifecycle, events[i]) {
+			if err := matchedGroup.Reopen(ctx, tx); err != nil {
 				return err
 			}
 		}
+
+		// volumes are tracked against the
+		// surviving group's fingerprint
+		volume, ok := volumes[matchedGroup.Fingerprint]
+		if !ok {
+			volume = &webhook.Volume{
+				Previous: matchedGroup.Count,
+				Current:  matchedGroup.Count,
+			}
+			volumes[matchedGroup.Fingerprint] = volume
+			fingerprints = append(fingerprints, matchedGroup.Fingerprint)
+		}
+
+		if matchedGroup.EventExists(events[i].ID) {
+			// event is already counted in the group
+			volume.Previous--
+			continue
+		}
+
+		volume.Current++
+
+		if err := matchedGroup.UpdateTimeStamps(ctx, &events[i], tx); err != nil {
+			return err
+		}
+	}
+
+	for _, fingerprint := range fingerprints {
+		// skip notifying for ignored
+		// or muted groups
+		if groups[fingerprint].IsSilenced() {
+			continue
+		}
+		e.webhookEvents = append(e.webhookEvents, webhook.NewVolumeEvent(e.appId, exceptionWebhookGroup(*groups[fingerprint]), *volumes[fingerprint]))
 	}
 
 	return
 }
 
+// newExceptionGroup creates an exception group from
+// the unhandled exception or native crash event.
+func newExceptionGroup(ev event.EventField) *group.ExceptionGroup {
+	if ev.IsNativeCrash() {
+		crash := ev.NativeCrash
+		return group.NewExceptionGroup(ev.AppID, crash.GetType(), crash.GetMessage(), crash.GetMethodName(), crash.GetFileName(), crash.GetLineNumber(), crash.Fingerprint, crash.FingerprintVersion, ev.Timestamp)
+	}
+
+	exception := ev.Exception
+	return group.NewExceptionGroup(ev.AppID, exception.GetType(), exception.GetMessage(), exception.GetMethodName(), exception.GetFileName(), exception.GetLineNumber(), exception.Fingerprint, exception.FingerprintVersion, ev.Timestamp)
+}
+
 // bucketANRs groups ANRs based on similarity.
-func (e eventreq) bucketANRs(ctx context.Context, tx *pgx.Tx) (err error) {
+func (e *eventreq) bucketANRs(ctx context.Context, tx *pgx.Tx) (err error) {
 	events := e.getANRs()
 
 	app := App{
 		ID: &e.appId,
 	}
 
+	// groups caches groups matched or created
+	// in this request, as groups created inside
+	// the transaction are not visible to lookups.
+	groups := make(map[string]*group.ANRGroup)
+	volumes := make(map[string]*webhook.Volume)
+	var fingerprints []string
+
 	for i := range events {
-		if events[i].ANR.Fingerprint == "" {
+		fingerprint := events[i].ANR.Fingerprint
+		if fingerprint == "" {
 			msg := fmt.Sprintf("no fingerprint found for event %q, cannot bucket ANR", events[i].ID)
 			fmt.Println(msg)
 			continue
 		}
 
-		matchedGroup, err := app.GetANRGroupByFingerprint(ctx, events[i].ANR.Fingerprint)
-		if err != nil {
-			return err
+		matchedGroup, cached := groups[fingerprint]
+		if !cached {
+			matchedGroup, err = app.GetANRGroupByFingerprint(ctx, fingerprint)
+			if err != nil {
+				return err
+			}
+
+			// merged groups resolve to the surviving
+			// group, which may already be cached
+			if matchedGroup != nil {
+				if survivor, ok := groups[matchedGroup.Fingerprint]; ok {
+					matchedGroup = survivor
+				}
+			}
 		}
 
 		if matchedGroup == nil {
-			anrGroup := group.NewANRGroup(events[i].AppID, events[i].ANR.GetType(), events[i].ANR.GetMessage(), events[i].ANR.GetMethodName(), events[i].ANR.GetFileName(), events[i].ANR.GetLineNumber(), events[i].ANR.Fingerprint, events[i].Timestamp)
+			anrGroup := group.NewANRGroup(events[i].AppID, events[i].ANR.GetType(), events[i].ANR.GetMessage(), events[i].ANR.GetMethodName(), events[i].ANR.GetFileName(), events[i].ANR.GetLineNumber(), fingerprint, events[i].ANR.FingerprintVersion, events[i].Timestamp)
 			if err := anrGroup.Insert(ctx, tx); err != nil {
 				return err
 			}
 
-			return nil
+			groups[fingerprint] = anrGroup
+			volumes[fingerprint] = &webhook.Volume{Current: 1}
+			fingerprints = append(fingerprints, fingerprint)
+			e.webhookEvents = append(e.webhookEvents, webhook.NewGroupEvent(e.appId, anrWebhookGroup(*anrGroup)))
+
+			continue
 		}
 
-		if !matchedGroup.EventExists(events[i].ID) {
-			if err := matchedGroup.UpdateTimeStamps(ctx, &events[i], tx); err != nil {
+		groups[fingerprint] = matchedGroup
+		groups[matchedGroup.Fingerprint] = matchedGroup
+
+		// reopen resolved groups when events
+		// arrive from newer app versions
+		if e.reopens(matchedGroup.Lifecycle, events[i]) {
+			if err := matchedGroup.Reopen(ctx, tx); err != nil {
 				return err
 			}
 		}
+
+		// volumes are tracked against the
+		// surviving group's fingerprint
+		volume, ok := volumes[matchedGroup.Fingerprint]
+		if !ok {
+			volume = &webhook.Volume{
+				Previous: matchedGroup.Count,
+				Current:  matchedGroup.Count,
+			}
+			volumes[matchedGroup.Fingerprint] = volume
+			fingerprints = append(fingerprints, matchedGroup.Fingerprint)
+		}
+
+		if matchedGroup.EventExists(events[i].ID) {
+			// event is already counted in the group
+			volume.Previous--
+			continue
+		}
+
+		volume.Current++
+
+		if err := matchedGroup.UpdateTimeStamps(ctx, &events[i], tx); err != nil {
+			return err
+		}
+	}
+
+	for _, fingerprint := range fingerprints {
+		// skip notifying for ignored
+		// or muted groups
+		if groups[fingerprint].IsSilenced() {
+			continue
+		}
+		e.webhookEvents = append(e.webhookEvents, webhook.NewVolumeEvent(e.appId, anrWebhookGroup(*groups[fingerprint]), *volumes[fingerprint]))
 	}
 
 	return
 }
 
+// exceptionWebhookGroup represents an exception
+// group in webhook payloads.
+func exceptionWebhookGroup(g group.ExceptionGroup) webhook.Group {
+	return webhook.Group{
+		ID:             g.ID,
+		Kind:           webhook.GroupKindCrash,
+		Type:           g.Type,
+		Message:        g.Message,
+		MethodName:     g.MethodName,
+		FileName:       g.FileName,
+		LineNumber:     g.LineNumber,
+		Fingerprint:    g.Fingerprint,
+		FirstEventTime: g.FirstEventTime,
+	}
+}
+
+// anrWebhookGroup represents an ANR group
+// in webhook payloads.
+func anrWebhookGroup(g group.ANRGroup) webhook.Group {
+	return webhook.Group{
+		ID:             g.ID,
+		Kind:           webhook.GroupKindANR,
+		Type:           g.Type,
+		Message:        g.Message,
+		MethodName:     g.MethodName,
+		FileName:       g.FileName,
+		LineNumber:     g.LineNumber,
+		Fingerprint:    g.Fingerprint,
+		FirstEventTime: g.FirstEventTime,
+	}
+}
+
 // needsSymbolication returns true if payload
 // contains events that should be symbolicated.
 func (e eventreq) needsSymbolication() bool {
 	return len(e.symbolicate) > 0
 }
 
+// newSymbolers creates the symbolicators for each
+// kind of mapping. Proguard mappings are either
+// symbolicated by the symbolicator service or in
+// process, depending on configuration.
+func newSymbolers() (symbolers []symbol.Symboler, err error) {
+	config := server.Server.Config
+
+	mappingTypes := []string{symbol.TypeProguard, symbol.TypeDsym}
+
+	if config.ProguardSymbolicator == "builtin" {
+		proguardCacheOnce.Do(func() {
+			proguardCache = symbol.NewProguardCache(config.ProguardCacheSize)
+		})
+
+		proguardSymbolicator, err := symbol.NewProguardSymbolicator(&symbol.Options{
+			Store:         server.Server.PgPool,
+			Fetch:         fetchMapping,
+			ProguardCache: proguardCache,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		symbolers = append(symbolers, proguardSymbolicator)
+		mappingTypes = []string{symbol.TypeDsym}
+	}
+
+	symbolicator, err := symbol.NewSymbolicator(&symbol.Options{
+		Origin:       os.Getenv("SYMBOLICATOR_ORIGIN"),
+		Store:        server.Server.PgPool,
+		Fetch:        fetchMapping,
+		MappingTypes: mappingTypes,
+	})
+	if err != nil {
+		return
+	}
+
+	elfSymbolicator, err := symbol.NewELFSymbolicator(&symbol.Options{
+		Store: server.Server.PgPool,
+		Fetch: fetchMapping,
+	})
+	if err != nil {
+		return
+	}
+
+	symbolers = append(symbolers, symbolicator, elfSymbolicator)
+
+	return
+}
+
+// symbolicateEvents symbolicates events that need
+// symbolication and rewrites them in place. Failed
+// batches are logged and skipped, their events are
+// left in the symbolicate cache.
+func (e *eventreq) symbolicateEvents(ctx context.Context) error {
+	symbolers, err := newSymbolers()
+	if err != nil {
+		return err
+	}
+
+	events := e.getSymbolicationEvents()
+
+	// start span to trace symbolication
+	symbolicationTracer := otel.Tracer("symbolication-tracer")
+	_, symbolicationSpan := symbolicationTracer.Start(ctx, "symbolicate-events")
+	defer symbolicationSpan.End()
+
+	for _, symboler := range symbolers {
+		batches := symboler.Batch(events)
+
+		for i := range batches {
+			// If symoblication fails for whole batch, continue. Events
+			// left unsymbolicated are picked up again once the mapping
+			// file is uploaded.
+			if err := symboler.Symbolicate(ctx, batches[i]); err != nil {
+				if errors.Is(err, symbol.ErrMappingNotFound) {
+					fmt.Println(err)
+					continue
+				}
+				msg := `failed to symbolicate batch`
+				fmt.Println(msg, err)
+				continue
+			}
+
+			// If symbolication succeeds but has errors while decoding individual frames, log them and proceed
+			if len(batches[i].Errs) > 0 {
+				for _, err := range batches[i].Errs {
+					fmt.Println("symbolication err: ", err.Error())
+				}
+			}
+
+			// rewrite symbolicated events to event request
+			for j := range batches[i].Events {
+				eventId := batches[i].Events[j].ID
+				idx, exists := e.symbolicate[eventId]
+				if !exists {
+					fmt.Printf("event id %q not found in symbolicate cache, batch index: %d, event index: %d\n", eventId, i, j)
+					continue
+				}
+				e.events[idx] = batches[i].Events[j]
+				delete(e.symbolicate, eventId)
+			}
+		}
+	}
+
+	e.bumpSymbolication()
+
+	return nil
+}
+
+// attach uploads the attachments and links the
+// uploaded location of each attachment to its event.
+func (e *eventreq) attach(ctx context.Context) error {
+	// start span to trace attachment uploads
+	uploadAttachmentsTracer := otel.Tracer("upload-attachments-tracer")
+	_, uploadAttachmentSpan := uploadAttachmentsTracer.Start(ctx, "upload-attachments")
+	defer uploadAttachmentSpan.End()
+
+	if err := e.uploadAttachments(); err != nil {
+		return err
+	}
+
+	for i := range e.events {
+		if !e.events[i].HasAttachments() {
+			continue
+		}
+
+		for j := range e.events[i].Attachments {
+			id := e.events[i].Attachments[j].ID
+			attachment, ok := e.attachments[id]
+			if !ok {
+				continue
+			}
+			if !attachment.uploaded {
+				fmt.Printf("attachment %q failed to upload for event %q, skipping\n", attachment.id, id)
+				continue
+			}
+
+			e.events[i].Attachments[j].Location = attachment.location
+			e.events[i].Attachments[j].Key = attachment.key
+		}
+	}
+
+	return nil
+}
+
 // validate validates the integrity of each event
 // and corresponding attachments.
 func (e eventreq) validate() error {
@@ -445,11 +837,29 @@ func (e eventreq) ingest(ctx context.Context) error {
 	stmt := sqlf.InsertInto(`default.events`)
 	defer stmt.Close()
 
+	// fingerprint rules are looked up only when the
+	// request has exceptions, ANRs or native crashes
+	var fingerprintRules *event.FingerprintRules
+	getRules := func() (event.FingerprintRules, error) {
+		if fingerprintRules == nil {
+			rules, err := getFingerprintRules(ctx, e.appId)
+			if err != nil {
+				return event.FingerprintRules{}, err
+			}
+			fingerprintRules = &rules.FingerprintRules
+		}
+		return *fingerprintRules, nil
+	}
+
+	rows := 0
+
 	for i := range e.events {
 		anrExceptions := "[]"
 		anrThreads := "[]"
 		exceptionExceptions := "[]"
 		exceptionThreads := "[]"
+		nativeCrashFrames := "[]"
+		nativeCrashThreads := "[]"
 		attachments := "[]"
 
 		if e.events[i].IsANR() {
@@ -463,7 +873,11 @@ func (e eventreq) ingest(ctx context.Context) error {
 				return err
 			}
 			anrThreads = string(marshalledThreads)
-			if err := e.events[i].ANR.ComputeANRFingerprint(); err != nil {
+			rules, err := getRules()
+			if err != nil {
+				return err
+			}
+			if err := e.events[i].ANR.ComputeANRFingerprint(rules); err != nil {
 				return err
 			}
 		}
@@ -479,11 +893,44 @@ func (e eventreq) ingest(ctx context.Context) error {
 				return err
 			}
 			exceptionThreads = string(marshalledThreads)
-			if err := e.events[i].Exception.ComputeExceptionFingerprint(); err != nil {
+			rules, err := getRules()
+			if err != nil {
+				return err
+			}
+			if err := e.events[i].Exception.ComputeExceptionFingerprint(rules); err != nil {
+				return err
+			}
+		}
+		if e.events[i].IsNativeCrash() {
+			marshalledFrames, err := json.Marshal(e.events[i].NativeCrash.Frames)
+			if err != nil {
+				return err
+			}
+			nativeCrashFrames = string(marshalledFrames)
+
+			marshalledThreads, err := json.Marshal(e.events[i].NativeCrash.Threads)
+			if err != nil {
+				return err
+			}
+			nativeCrashThreads = string(marshalledThreads)
+			rules, err := getRules()
+			if err != nil {
+				return err
+			}
+			if err := e.events[i].NativeCrash.ComputeNativeCrashFingerprint(rules); err != nil {
 				return err
 			}
 		}
 
+		// fingerprints are still computed for
+		// bucketing, but the row isn't written
+		// again
+		if e.ingested[e.events[i].ID] {
+			continue
+		}
+
+		rows++
+
 		if e.events[i].HasAttachments() {
 			marshalledAttachments, err := json.Marshal(e.events[i].Attachments)
 			if err != nil {
@@ -529,6 +976,7 @@ func (e eventreq) ingest(ctx context.Context) error {
 			Set(`attribute.network_type`, e.events[i].Attribute.NetworkType).
 			Set(`attribute.network_generation`, e.events[i].Attribute.NetworkGeneration).
 			Set(`attribute.network_provider`, e.events[i].Attribute.NetworkProvider).
+			Set(`user_defined_attribute`, e.events[i].Attribute.UserDefinedAttrs).
 
 			// attachments
 			Set(`attachments`, attachments)
@@ -540,14 +988,16 @@ func (e eventreq) ingest(ctx context.Context) error {
 				Set(`anr.fingerprint`, e.events[i].ANR.Fingerprint).
 				Set(`anr.exceptions`, anrExceptions).
 				Set(`anr.threads`, anrThreads).
-				Set(`anr.foreground`, e.events[i].ANR.Foreground)
+				Set(`anr.foreground`, e.events[i].ANR.Foreground).
+				Set(`anr.symbolicated`, e.isSymbolicated(e.events[i].ID))
 		} else {
 			row.
 				Set(`anr.handled`, nil).
 				Set(`anr.fingerprint`, nil).
 				Set(`anr.exceptions`, nil).
 				Set(`anr.threads`, nil).
-				Set(`anr.foreground`, nil)
+				Set(`anr.foreground`, nil).
+				Set(`anr.symbolicated`, nil)
 		}
 
 		// exception
@@ -557,14 +1007,41 @@ func (e eventreq) ingest(ctx context.Context) error {
 				Set(`exception.fingerprint`, e.events[i].Exception.Fingerprint).
 				Set(`exception.exceptions`, exceptionExceptions).
 				Set(`exception.threads`, exceptionThreads).
-				Set(`exception.foreground`, e.events[i].Exception.Foreground)
+				Set(`exception.foreground`, e.events[i].Exception.Foreground).
+				Set(`exception.symbolicated`, e.isSymbolicated(e.events[i].ID))
 		} else {
 			row.
 				Set(`exception.handled`, nil).
 				Set(`exception.fingerprint`, nil).
 				Set(`exception.exceptions`, nil).
 				Set(`exception.threads`, nil).
-				Set(`exception.foreground`, nil)
+				Set(`exception.foreground`, nil).
+				Set(`exception.symbolicated`, nil)
+		}
+
+		// native crash
+		if e.events[i].IsNativeCrash() {
+			row.
+				Set(`native_crash.signal`, e.events[i].NativeCrash.Signal).
+				Set(`native_crash.signal_code`, e.events[i].NativeCrash.SignalCode).
+				Set(`native_crash.fault_address`, e.events[i].NativeCrash.FaultAddress).
+				Set(`native_crash.abort_message`, e.events[i].NativeCrash.AbortMessage).
+				Set(`native_crash.fingerprint`, e.events[i].NativeCrash.Fingerprint).
+				Set(`native_crash.frames`, nativeCrashFrames).
+				Set(`native_crash.threads`, nativeCrashThreads).
+				Set(`native_crash.registers`, e.events[i].NativeCrash.Registers).
+				Set(`native_crash.foreground`, e.events[i].NativeCrash.Foreground)
+		} else {
+			row.
+				Set(`native_crash.signal`, nil).
+				Set(`native_crash.signal_code`, nil).
+				Set(`native_crash.fault_address`, nil).
+				Set(`native_crash.abort_message`, nil).
+				Set(`native_crash.fingerprint`, nil).
+				Set(`native_crash.frames`, nil).
+				Set(`native_crash.threads`, nil).
+				Set(`native_crash.registers`, nil).
+				Set(`native_crash.foreground`, nil)
 		}
 
 		// app exit
@@ -916,9 +1393,100 @@ func (e eventreq) ingest(ctx context.Context) error {
 				Set(`navigation.source`, nil)
 		}
 
+		// span
+		if e.events[i].IsSpan() {
+			row.
+				Set(`span.trace_id`, e.events[i].Span.TraceID).
+				Set(`span.span_id`, e.events[i].Span.SpanID).
+				Set(`span.parent_id`, e.events[i].Span.ParentID).
+				Set(`span.name`, e.events[i].Span.Name).
+				Set(`span.kind`, e.events[i].Span.Kind).
+				Set(`span.status`, e.events[i].Span.Status).
+				Set(`span.status_message`, e.events[i].Span.StatusMessage).
+				Set(`span.start_time`, e.events[i].Span.StartTime.Format(chrono.NanoTimeFormat)).
+				Set(`span.end_time`, e.events[i].Span.EndTime.Format(chrono.NanoTimeFormat)).
+				Set(`span.duration`, e.events[i].Span.Duration().Milliseconds()).
+				Set(`span_attributes`, e.events[i].Span.Attributes)
+		} else {
+			row.
+				Set(`span.trace_id`, nil).
+				Set(`span.span_id`, nil).
+				Set(`span.parent_id`, nil).
+				Set(`span.name`, nil).
+				Set(`span.kind`, nil).
+				Set(`span.status`, nil).
+				Set(`span.status_message`, nil).
+				Set(`span.start_time`, nil).
+				Set(`span.end_time`, nil).
+				Set(`span.duration`, nil).
+				Set(`span_attributes`, nil)
+		}
+
+		// custom
+		if e.events[i].IsCustom() {
+			row.
+				Set(`custom.name`, e.events[i].Custom.Name).
+				Set(`custom_string_properties`, e.events[i].Custom.StringProperties()).
+				Set(`custom_number_properties`, e.events[i].Custom.NumberProperties()).
+				Set(`custom_bool_properties`, e.events[i].Custom.BoolProperties())
+		} else {
+			row.
+				Set(`custom.name`, nil).
+				Set(`custom_string_properties`, nil).
+				Set(`custom_number_properties`, nil).
+				Set(`custom_bool_properties`, nil)
+		}
+
+	}
+
+	if rows < 1 {
+		return nil
+	}
+
+	// wait for the insert to be flushed, so that
+	// the events are visible to retries
+	return server.Server.ChPool.AsyncInsert(ctx, stmt.String(), true, stmt.Args()...)
+}
+
+// ingestedEventIds finds the events of the request
+// already written to clickhouse.
+func (e eventreq) ingestedEventIds(ctx context.Context) (ids map[uuid.UUID]bool, err error) {
+	ids = make(map[uuid.UUID]bool)
+
+	if len(e.events) < 1 {
+		return
+	}
+
+	eventIds := make([]uuid.UUID, len(e.events))
+	for i := range e.events {
+		eventIds[i] = e.events[i].ID
+	}
+
+	stmt := sqlf.From(`default.events`).
+		Select("distinct id").
+		Where("app_id = ?", e.appId).
+		Where("id in ?", eventIds)
+
+	defer stmt.Close()
+
+	rows, err := server.Server.ChPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		if err = rows.Scan(&id); err != nil {
+			return
+		}
+		ids[id] = true
 	}
 
-	return server.Server.ChPool.AsyncInsert(ctx, stmt.String(), false, stmt.Args()...)
+	err = rows.Err()
+
+	return
 }
 
 // sessionCount counts and provides the number of
@@ -944,6 +1512,9 @@ func GetExceptionsWithFilter(ctx context.Context, eventIds []uuid.UUID, af *filt
 	var exceptions string
 	var threads string
 	var attachments string
+	var nativeCrash event.NativeCrash
+	var nativeFrames string
+	var nativeThreads string
 
 	limit := af.ExtendLimit()
 	forward := af.HasPositiveLimit()
@@ -1035,6 +1606,12 @@ func GetExceptionsWithFilter(ctx context.Context, eventIds []uuid.UUID, af *filt
 			args = append(args, af.NetworkGenerations)
 		}
 
+		keys, values := af.UserDefinedAttrPairs()
+		for i := range keys {
+			countStmt.Where("mapContains(`user_defined_attribute`, ?) and `user_defined_attribute`[?] = ?", nil, nil, nil)
+			args = append(args, keys[i], keys[i], values[i])
+		}
+
 		if af.HasTimeRange() {
 			countStmt.Where("`timestamp` >= ? and `timestamp` <= ?", nil, nil)
 			args = append(args, af.From, af.To)
@@ -1089,6 +1666,14 @@ func GetExceptionsWithFilter(ctx context.Context, eventIds []uuid.UUID, af *filt
 		`exception.fingerprint`,
 		`exception.exceptions`,
 		`exception.threads`,
+		`toString(native_crash.signal)`,
+		`toString(native_crash.signal_code)`,
+		`native_crash.fault_address`,
+		`native_crash.abort_message`,
+		`native_crash.fingerprint`,
+		`native_crash.frames`,
+		`native_crash.threads`,
+		`native_crash.foreground`,
 		`attachments`,
 	}
 
@@ -1164,6 +1749,12 @@ func GetExceptionsWithFilter(ctx context.Context, eventIds []uuid.UUID, af *filt
 		args = append(args, af.NetworkGenerations)
 	}
 
+	keys, values := af.UserDefinedAttrPairs()
+	for i := range keys {
+		stmt.Where("mapContains(`user_defined_attribute`, ?) and `user_defined_attribute`[?] = ?", nil, nil, nil)
+		args = append(args, keys[i], keys[i], values[i])
+	}
+
 	if af.HasTimeRange() {
 		stmt.Where("`timestamp` >= ? and `timestamp` <= ?", nil, nil)
 		args = append(args, af.From, af.To)
@@ -1217,6 +1808,14 @@ func GetExceptionsWithFilter(ctx context.Context, eventIds []uuid.UUID, af *filt
 			&e.Exception.Fingerprint,
 			&exceptions,
 			&threads,
+			&nativeCrash.Signal,
+			&nativeCrash.SignalCode,
+			&nativeCrash.FaultAddress,
+			&nativeCrash.AbortMessage,
+			&nativeCrash.Fingerprint,
+			&nativeFrames,
+			&nativeThreads,
+			&nativeCrash.Foreground,
 			&attachments,
 		}
 
@@ -1224,11 +1823,22 @@ func GetExceptionsWithFilter(ctx context.Context, eventIds []uuid.UUID, af *filt
 			return nil, next, previous, err
 		}
 
-		if err := json.Unmarshal([]byte(exceptions), &e.Exception.Exceptions); err != nil {
-			return nil, next, previous, err
-		}
-		if err := json.Unmarshal([]byte(threads), &e.Exception.Threads); err != nil {
-			return nil, next, previous, err
+		if e.Type == event.TypeNativeCrash {
+			crash := nativeCrash
+			if err := json.Unmarshal([]byte(nativeFrames), &crash.Frames); err != nil {
+				return nil, next, previous, err
+			}
+			if err := json.Unmarshal([]byte(nativeThreads), &crash.Threads); err != nil {
+				return nil, next, previous, err
+			}
+			e.NativeCrash = &crash
+		} else {
+			if err := json.Unmarshal([]byte(exceptions), &e.Exception.Exceptions); err != nil {
+				return nil, next, previous, err
+			}
+			if err := json.Unmarshal([]byte(threads), &e.Exception.Threads); err != nil {
+				return nil, next, previous, err
+			}
 		}
 		if err := json.Unmarshal([]byte(attachments), &e.Attachments); err != nil {
 			return nil, next, previous, err
@@ -1328,6 +1938,8 @@ func GetExceptionPlotInstances(ctx context.Context, af *filter.AppFilter) (issue
 		base.Where("attribute.network_generation").In(af.NetworkGenerations)
 	}
 
+	filterUserDefinedAttrs(base, af)
+
 	if af.HasTimeRange() {
 		base.Where("timestamp >= ? and timestamp <= ?", af.From, af.To)
 	}
@@ -1337,10 +1949,10 @@ func GetExceptionPlotInstances(ctx context.Context, af *filter.AppFilter) (issue
 		From("base_exceptions").
 		Select("datetime").
 		Select("app_version").
-		Select("count(if(type = 'exception' and exception.handled = false, 1, NULL)) as total_exceptions").
+		Select("count(if((type = 'exception' and exception.handled = false) or type = 'native_crash', 1, NULL)) as total_exceptions").
 		Select("round((1 - (exception_sessions / total_sessions)) * 100, 2) as crash_free_sessions").
 		Select("count(distinct session_id) as total_sessions").
-		Select("count(distinct if(type = 'exception' and exception.handled = false, session_id, NULL)) as exception_sessions").
+		Select("count(distinct if((type = 'exception' and exception.handled = false) or type = 'native_crash', session_id, NULL)) as exception_sessions").
 		GroupBy("app_version, datetime").
 		OrderBy("app_version, datetime")
 
@@ -1470,6 +2082,12 @@ func GetANRsWithFilter(ctx context.Context, eventIds []uuid.UUID, af *filter.App
 			args = append(args, af.NetworkGenerations)
 		}
 
+		keys, values := af.UserDefinedAttrPairs()
+		for i := range keys {
+			countStmt.Where("mapContains(`user_defined_attribute`, ?) and `user_defined_attribute`[?] = ?", nil, nil, nil)
+			args = append(args, keys[i], keys[i], values[i])
+		}
+
 		if af.HasTimeRange() {
 			countStmt.Where("`timestamp` >= ? and `timestamp` <= ?", nil, nil)
 			args = append(args, af.From, af.To)
@@ -1598,6 +2216,12 @@ func GetANRsWithFilter(ctx context.Context, eventIds []uuid.UUID, af *filter.App
 		args = append(args, af.NetworkGenerations)
 	}
 
+	keys, values := af.UserDefinedAttrPairs()
+	for i := range keys {
+		stmt.Where("mapContains(`user_defined_attribute`, ?) and `user_defined_attribute`[?] = ?", nil, nil, nil)
+		args = append(args, keys[i], keys[i], values[i])
+	}
+
 	if af.HasTimeRange() {
 		stmt.Where("`timestamp` >= ? and `timestamp` <= ?", nil, nil)
 		args = append(args, af.From, af.To)
@@ -1761,6 +2385,8 @@ func GetANRPlotInstances(ctx context.Context, af *filter.AppFilter) (issueInstan
 		base.Where("attribute.network_generation").In(af.NetworkGenerations)
 	}
 
+	filterUserDefinedAttrs(base, af)
+
 	if af.HasTimeRange() {
 		base.Where("timestamp >= ? and timestamp <= ?", af.From, af.To)
 	}
@@ -1849,6 +2475,8 @@ func GetIssuesPlot(ctx context.Context, eventIds []uuid.UUID, af *filter.AppFilt
 		stmt.Where("attribute.network_generation in (?)", af.NetworkGenerations)
 	}
 
+	filterUserDefinedAttrs(stmt, af)
+
 	if len(af.Locales) > 0 {
 		stmt.Where("attribute.device_locale in (?)", af.Locales)
 	}
@@ -1906,13 +2534,17 @@ func GetSessionsPlot(ctx context.Context, af *filter.AppFilter) (sessionInstance
 	}
 
 	if af.Crash && af.ANR {
-		base.Where("((type = 'exception' AND exception.handled = false) OR type = 'anr')")
+		base.Where("((type = 'exception' AND exception.handled = false) OR type = 'native_crash' OR type = 'anr')")
 	} else if af.Crash {
-		base.Where("type = 'exception' AND exception.handled = false")
+		base.Where("((type = 'exception' AND exception.handled = false) OR type = 'native_crash')")
 	} else if af.ANR {
 		base.Where("type = 'anr'")
 	}
 
+	if af.HasCustomEvent() {
+		filterCustomEvents(base, af)
+	}
+
 	if len(af.OsNames) > 0 {
 		base.Where("attribute.os_name").In(af.OsNames)
 	}
@@ -1949,6 +2581,8 @@ func GetSessionsPlot(ctx context.Context, af *filter.AppFilter) (sessionInstance
 		base.Where("attribute.network_generation").In(af.NetworkGenerations)
 	}
 
+	filterUserDefinedAttrs(base, af)
+
 	if af.FreeText != "" {
 		base.Where(
 			"("+
@@ -2052,6 +2686,17 @@ func PutEvents(c *gin.Context) {
 		return
 	}
 
+	body, err := decodeBody(c, int64(maxBatchSize+multipartHeadroom))
+	if err != nil {
+		msg := `failed to decode events payload`
+		fmt.Println(msg, err)
+		c.JSON(decodeBodyStatus(err), gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
 	msg := `failed to parse events payload`
 	eventReq := eventreq{
 		appId:       appId,
@@ -2061,13 +2706,17 @@ func PutEvents(c *gin.Context) {
 
 	if err := eventReq.read(c, appId); err != nil {
 		fmt.Println(msg, err)
-		c.JSON(http.StatusBadRequest, gin.H{
+		c.JSON(decodeBodyStatus(err), gin.H{
 			"error":   msg,
 			"details": err.Error(),
 		})
 		return
 	}
 
+	eventReq.wireSize = body.WireSize()
+	eventReq.encoding = body.Encoding
+	recordBodySize(c, body)
+
 	if err := eventReq.validate(); err != nil {
 		msg := `failed to validate events payload`
 		fmt.Println(msg, err)
@@ -2102,169 +2751,15 @@ func PutEvents(c *gin.Context) {
 		return
 	}
 
-	if eventReq.needsSymbolication() {
-		// symbolicate
-		symbolicator, err := symbol.NewSymbolicator(&symbol.Options{
-			Origin: os.Getenv("SYMBOLICATOR_ORIGIN"),
-			Store:  server.Server.PgPool,
-		})
-		if err != nil {
-			msg := `failed to initialize symbolicator`
-			fmt.Println(msg, err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   msg,
-				"details": err.Error(),
-			})
-			return
-		}
-
-		events := eventReq.getSymbolicationEvents()
-
-		batches := symbolicator.Batch(events)
-
-		// start span to trace symbolication
-		symbolicationTracer := otel.Tracer("symbolication-tracer")
-		_, symbolicationSpan := symbolicationTracer.Start(ctx, "symbolicate-events")
-
-		for i := range batches {
-			// If symoblication fails for whole batch, continue
-			if err := symbolicator.Symbolicate(ctx, batches[i]); err != nil {
-				msg := `failed to symbolicate batch`
-				fmt.Println(msg, err)
-				continue
-			}
-
-			// If symbolication succeeds but has errors while decoding individual frames, log them and proceed
-			if len(batches[i].Errs) > 0 {
-				for _, err := range batches[i].Errs {
-					fmt.Println("symbolication err: ", err.Error())
-				}
-			}
-
-			// rewrite symbolicated events to event request
-			for j := range batches[i].Events {
-				eventId := batches[i].Events[j].ID
-				idx, exists := eventReq.symbolicate[eventId]
-				if !exists {
-					fmt.Printf("event id %q not found in symbolicate cache, batch index: %d, event index: %d\n", eventId, i, j)
-					continue
-				}
-				eventReq.events[idx] = batches[i].Events[j]
-				delete(eventReq.symbolicate, eventId)
-			}
-		}
-
-		symbolicationSpan.End()
-
-		eventReq.bumpSymbolication()
-	}
-
-	if eventReq.hasAttachments() {
-		// start span to trace attachment uploads
-		uploadAttachmentsTracer := otel.Tracer("upload-attachments-tracer")
-		_, uploadAttachmentSpan := uploadAttachmentsTracer.Start(ctx, "upload-attachments")
-
-		if err := eventReq.uploadAttachments(); err != nil {
-			msg := `failed to upload attachments`
-			fmt.Println(msg, err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": msg,
-			})
-			uploadAttachmentSpan.End()
-			return
-		}
-
-		for i := range eventReq.events {
-			if !eventReq.events[i].HasAttachments() {
-				continue
-			}
-
-			for j := range eventReq.events[i].Attachments {
-				id := eventReq.events[i].Attachments[j].ID
-				attachment, ok := eventReq.attachments[id]
-				if !ok {
-					continue
-				}
-				if !attachment.uploaded {
-					fmt.Printf("attachment %q failed to upload for event %q, skipping\n", attachment.id, id)
-					continue
-				}
-
-				eventReq.events[i].Attachments[j].Location = attachment.location
-				eventReq.events[i].Attachments[j].Key = attachment.key
-			}
-		}
-
-		uploadAttachmentSpan.End()
-	}
-
-	tx, err := server.Server.PgPool.Begin(ctx)
+	queued, err := eventReq.enqueue(ctx, app.TeamId)
 	if err != nil {
-		msg := `failed to ingest events, failed to acquire transaction`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": msg,
-		})
-		return
-	}
-
-	defer tx.Rollback(ctx)
-
-	if err := eventReq.ingest(ctx); err != nil {
-		msg := `failed to ingest events`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": msg,
-		})
-		return
-	}
-
-	// start span to trace bucketing unhandled exceptions
-	bucketUnhandledExceptionsTracer := otel.Tracer("bucket-unhandled-exceptions-tracer")
-	_, bucketUnhandledExceptionsSpan := bucketUnhandledExceptionsTracer.Start(ctx, "bucket-unhandled-exceptions")
-
-	if err := eventReq.bucketUnhandledExceptions(ctx, &tx); err != nil {
-		msg := `failed to bucket unhandled exceptions`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": msg,
-		})
-		bucketUnhandledExceptionsSpan.End()
-		return
-	}
-
-	// start span to trace bucketing ANRs
-	bucketAnrsTracer := otel.Tracer("bucket-anrs-tracer")
-	_, bucketAnrsSpan := bucketAnrsTracer.Start(ctx, "bucket-anrs-exceptions")
-
-	if err := eventReq.bucketANRs(ctx, &tx); err != nil {
-		msg := `failed to bucket anrs`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": msg,
-		})
-		bucketAnrsSpan.End()
-		return
-	}
-
-	if !app.Onboarded {
-		firstEvent := eventReq.events[0]
-		uniqueID := firstEvent.Attribute.AppUniqueID
-		platform := firstEvent.Attribute.Platform
-		version := firstEvent.Attribute.AppVersion
-
-		if err := app.Onboard(ctx, &tx, uniqueID, platform, version); err != nil {
-			msg := `failed to onboard app`
-			fmt.Println(msg, err)
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": msg,
-			})
+		var quotaErr quotaExceededError
+		if errors.As(err, &quotaErr) {
+			abortTooManyAttempts(c, quotaErr.Error(), quotaRetryAfter(time.Now()))
 			return
 		}
-	}
 
-	if err := eventReq.save(ctx, &tx); err != nil {
-		msg := `failed to save event request`
+		msg := `failed to enqueue event request`
 		fmt.Println(msg, err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": msg,
@@ -2272,18 +2767,12 @@ func PutEvents(c *gin.Context) {
 		return
 	}
 
-	if err := tx.Commit(ctx); err != nil {
-		msg := `failed to ingest events`
-		fmt.Println(msg, err)
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": msg,
+	if !queued {
+		c.JSON(http.StatusAccepted, gin.H{
+			"ok": "accepted, known event request",
 		})
-		bucketUnhandledExceptionsSpan.End()
-		bucketAnrsSpan.End()
 		return
 	}
-	bucketUnhandledExceptionsSpan.End()
-	bucketAnrsSpan.End()
 
 	c.JSON(http.StatusAccepted, gin.H{"ok": "accepted"})
 }
@@ -0,0 +1,121 @@
+package measure
+
+import (
+	"backend/api/filter"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/leporo/sqlf"
+)
+
+// filterEvents applies the app, attribute &
+// time range filters of the app filter to a
+// statement querying events.
+func filterEvents(stmt *sqlf.Stmt, af *filter.AppFilter) {
+	stmt.Where("app_id = ?", af.AppID)
+
+	if len(af.Versions) > 0 {
+		stmt.Where("attribute.app_version").In(af.Versions)
+	}
+
+	if len(af.VersionCodes) > 0 {
+		stmt.Where("attribute.app_build").In(af.VersionCodes)
+	}
+
+	if len(af.OsNames) > 0 {
+		stmt.Where("attribute.os_name").In(af.OsNames)
+	}
+
+	if len(af.OsVersions) > 0 {
+		stmt.Where("attribute.os_version").In(af.OsVersions)
+	}
+
+	if len(af.Countries) > 0 {
+		stmt.Where("inet.country_code").In(af.Countries)
+	}
+
+	if len(af.DeviceNames) > 0 {
+		stmt.Where("attribute.device_name").In(af.DeviceNames)
+	}
+
+	if len(af.DeviceManufacturers) > 0 {
+		stmt.Where("attribute.device_manufacturer").In(af.DeviceManufacturers)
+	}
+
+	if len(af.Locales) > 0 {
+		stmt.Where("attribute.device_locale").In(af.Locales)
+	}
+
+	if len(af.NetworkProviders) > 0 {
+		stmt.Where("attribute.network_provider").In(af.NetworkProviders)
+	}
+
+	if len(af.NetworkTypes) > 0 {
+		stmt.Where("attribute.network_type").In(af.NetworkTypes)
+	}
+
+	if len(af.NetworkGenerations) > 0 {
+		stmt.Where("attribute.network_generation").In(af.NetworkGenerations)
+	}
+
+	filterUserDefinedAttrs(stmt, af)
+
+	if af.HasTimeRange() {
+		stmt.Where("timestamp >= ? and timestamp <= ?", af.From, af.To)
+	}
+}
+
+// filterUserDefinedAttrs applies the user defined
+// attribute filters to a statement querying events.
+// An event matches if it has every requested key
+// with the requested value.
+func filterUserDefinedAttrs(stmt *sqlf.Stmt, af *filter.AppFilter) {
+	keys, values := af.UserDefinedAttrPairs()
+	for i := range keys {
+		stmt.Where("mapContains(user_defined_attribute, ?) and user_defined_attribute[?] = ?", keys[i], keys[i], values[i])
+	}
+}
+
+// parseEventFilter parses & validates the app filters
+// of event querying requests. Writes an error response
+// & returns false on failure.
+func parseEventFilter(c *gin.Context, af *filter.AppFilter, msg string) bool {
+	if err := c.ShouldBindQuery(af); err != nil {
+		msg := `failed to parse query parameters`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return false
+	}
+
+	af.Expand()
+
+	if err := af.Validate(); err != nil {
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return false
+	}
+
+	if len(af.Versions) > 0 || len(af.VersionCodes) > 0 {
+		if err := af.ValidateVersions(); err != nil {
+			fmt.Println(msg, err)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   msg,
+				"details": err.Error(),
+			})
+			return false
+		}
+	}
+
+	if !af.HasTimeRange() {
+		af.SetDefaultTimeRange()
+	}
+
+	return true
+}
@@ -0,0 +1,197 @@
+package measure
+
+import (
+	"backend/api/event"
+	"backend/api/server"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/leporo/sqlf"
+)
+
+// FingerprintRules represents the fingerprinting
+// rules configured for an app.
+type FingerprintRules struct {
+	AppID uuid.UUID `json:"app_id"`
+	event.FingerprintRules
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FingerprintRulesPayload represents the request
+// body to update an app's fingerprinting rules.
+type FingerprintRulesPayload struct {
+	Version            *int      `json:"version"`
+	SkipFramePrefixes  *[]string `json:"skip_frame_prefixes"`
+	InAppFramePrefixes *[]string `json:"in_app_frame_prefixes"`
+	InAppFrames        *int      `json:"in_app_frames"`
+	IncludeMessage     *bool     `json:"include_message"`
+}
+
+// newFingerprintRules creates fingerprinting
+// rules for the app with default values.
+func newFingerprintRules(appId uuid.UUID) *FingerprintRules {
+	return &FingerprintRules{
+		AppID:            appId,
+		FingerprintRules: event.DefaultFingerprintRules(),
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+	}
+}
+
+// apply applies the payload to the rules. Updating
+// rules without a version moves the app to the
+// latest fingerprinting algorithm.
+func (r *FingerprintRules) apply(payload FingerprintRulesPayload) {
+	r.Version = event.FingerprintLatest
+	if payload.Version != nil {
+		r.Version = *payload.Version
+	}
+	if payload.SkipFramePrefixes != nil {
+		r.SkipFramePrefixes = *payload.SkipFramePrefixes
+	}
+	if payload.InAppFramePrefixes != nil {
+		r.InAppFramePrefixes = *payload.InAppFramePrefixes
+	}
+	if payload.InAppFrames != nil {
+		r.InAppFrames = *payload.InAppFrames
+	}
+	if payload.IncludeMessage != nil {
+		r.IncludeMessage = *payload.IncludeMessage
+	}
+}
+
+// save upserts the fingerprinting rules.
+func (r *FingerprintRules) save(ctx context.Context) (err error) {
+	r.UpdatedAt = time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		InsertInto("public.fingerprint_rules").
+		Set("app_id", r.AppID).
+		Set("version", r.Version).
+		Set("skip_frame_prefixes", r.SkipFramePrefixes).
+		Set("in_app_frame_prefixes", r.InAppFramePrefixes).
+		Set("in_app_frames", r.InAppFrames).
+		Set("include_message", r.IncludeMessage).
+		Set("created_at", r.CreatedAt).
+		Set("updated_at", r.UpdatedAt).
+		Clause("on conflict (app_id) do update set version = excluded.version, skip_frame_prefixes = excluded.skip_frame_prefixes, in_app_frame_prefixes = excluded.in_app_frame_prefixes, in_app_frames = excluded.in_app_frames, include_message = excluded.include_message, updated_at = excluded.updated_at").
+		Returning("created_at")
+
+	defer stmt.Close()
+
+	err = server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&r.CreatedAt)
+
+	return
+}
+
+// getFingerprintRules returns the fingerprinting
+// rules of the app. Returns default rules if the
+// app has not configured any.
+func getFingerprintRules(ctx context.Context, appId uuid.UUID) (rules *FingerprintRules, err error) {
+	rules = newFingerprintRules(appId)
+
+	stmt := sqlf.PostgreSQL.
+		Select("version").
+		Select("skip_frame_prefixes").
+		Select("in_app_frame_prefixes").
+		Select("in_app_frames").
+		Select("include_message").
+		Select("created_at").
+		Select("updated_at").
+		From("public.fingerprint_rules").
+		Where("app_id = ?", appId)
+
+	defer stmt.Close()
+
+	err = server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&rules.Version, &rules.SkipFramePrefixes, &rules.InAppFramePrefixes, &rules.InAppFrames, &rules.IncludeMessage, &rules.CreatedAt, &rules.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return rules, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+func GetFingerprintRules(c *gin.Context) {
+	ctx := c.Request.Context()
+	appId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `app id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if !authzApp(c, appId, *ScopeAppRead) {
+		return
+	}
+
+	rules, err := getFingerprintRules(ctx, appId)
+	if err != nil {
+		msg := `failed to fetch fingerprint rules`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
+
+func UpdateFingerprintRules(c *gin.Context) {
+	ctx := c.Request.Context()
+	appId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `app id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	var payload FingerprintRulesPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		msg := `failed to parse fingerprint rules json payload`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if !authzApp(c, appId, *ScopeAppAll) {
+		return
+	}
+
+	rules, err := getFingerprintRules(ctx, appId)
+	if err != nil {
+		msg := `failed to fetch fingerprint rules`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	rules.apply(payload)
+
+	if err := rules.Validate(); err != nil {
+		msg := `fingerprint rules validation failed`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg, "details": err.Error()})
+		return
+	}
+
+	if err := rules.save(ctx); err != nil {
+		msg := `failed to update fingerprint rules`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	c.JSON(http.StatusOK, rules)
+}
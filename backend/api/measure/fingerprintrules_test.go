@@ -0,0 +1,85 @@
+package measure
+
+import (
+	"backend/api/event"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNewFingerprintRules(t *testing.T) {
+	appId := uuid.New()
+
+	rules := newFingerprintRules(appId)
+
+	if rules.AppID != appId {
+		t.Errorf("appId mismatch: expected %v, got %v", appId, rules.AppID)
+	}
+	if rules.Version != event.FingerprintV1 {
+		t.Errorf("Version mismatch: expected %v, got %v", event.FingerprintV1, rules.Version)
+	}
+	if rules.InAppFrames != 1 {
+		t.Errorf("InAppFrames mismatch: expected %v, got %v", 1, rules.InAppFrames)
+	}
+	if err := rules.Validate(); err != nil {
+		t.Errorf("Expected default rules to be valid, got %v", err)
+	}
+}
+
+func TestFingerprintRulesApply(t *testing.T) {
+	rules := newFingerprintRules(uuid.New())
+	prefixes := []string{"com.example.app."}
+	frames := 3
+
+	rules.apply(FingerprintRulesPayload{
+		InAppFramePrefixes: &prefixes,
+		InAppFrames:        &frames,
+	})
+
+	if rules.Version != event.FingerprintLatest {
+		t.Errorf("Version mismatch: expected %v, got %v", event.FingerprintLatest, rules.Version)
+	}
+	if !slices.Equal(rules.InAppFramePrefixes, prefixes) {
+		t.Errorf("InAppFramePrefixes mismatch: expected %v, got %v", prefixes, rules.InAppFramePrefixes)
+	}
+	if rules.InAppFrames != frames {
+		t.Errorf("InAppFrames mismatch: expected %v, got %v", frames, rules.InAppFrames)
+	}
+	if len(rules.SkipFramePrefixes) != 0 {
+		t.Errorf("SkipFramePrefixes should be untouched, got %v", rules.SkipFramePrefixes)
+	}
+
+	version := event.FingerprintV1
+	rules.apply(FingerprintRulesPayload{
+		Version: &version,
+	})
+
+	if rules.Version != event.FingerprintV1 {
+		t.Errorf("Version mismatch: expected %v, got %v", event.FingerprintV1, rules.Version)
+	}
+	if rules.InAppFrames != frames {
+		t.Errorf("InAppFrames should be retained, got %v", rules.InAppFrames)
+	}
+}
+
+func TestFingerprintRulesMarshalJSON(t *testing.T) {
+	rules := newFingerprintRules(uuid.New())
+
+	data, err := json.Marshal(rules)
+	if err != nil {
+		t.Fatalf("Failed to marshal JSON: %v", err)
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to unmarshal JSON: %v", err)
+	}
+
+	for _, key := range []string{"app_id", "version", "skip_frame_prefixes", "in_app_frame_prefixes", "in_app_frames", "include_message", "created_at", "updated_at"} {
+		if _, ok := result[key]; !ok {
+			t.Errorf("Expected key %q in JSON, got %v", key, result)
+		}
+	}
+}
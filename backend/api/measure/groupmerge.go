@@ -0,0 +1,332 @@
+package measure
+
+import (
+	"backend/api/group"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// bindMergeRequest parses and validates the merge request.
+// Writes the error response and returns false if either fails.
+func bindMergeRequest(c *gin.Context, survivorId uuid.UUID, unmerge bool) (req group.MergeRequest, ok bool) {
+	if err := c.ShouldBindJSON(&req); err != nil {
+		// unmerging without a body
+		// detaches all groups
+		if !unmerge || !errors.Is(err, io.EOF) {
+			msg := `failed to parse merge json payload`
+			fmt.Println(msg, err)
+			c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+			return
+		}
+	}
+
+	if err := req.Validate(survivorId, unmerge); err != nil {
+		msg := `merge validation failed`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg, "details": err.Error()})
+		return
+	}
+
+	ok = true
+
+	return
+}
+
+func MergeCrashGroups(c *gin.Context) {
+	ctx := c.Request.Context()
+	appId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `app id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	crashGroupId, err := uuid.Parse(c.Param("crashGroupId"))
+	if err != nil {
+		msg := `crash group id is invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	req, ok := bindMergeRequest(c, crashGroupId, false)
+	if !ok {
+		return
+	}
+
+	app := App{
+		ID: &appId,
+	}
+
+	if !authzApp(c, appId, *ScopeAppAll) {
+		return
+	}
+
+	exceptionGroup, err := app.GetExceptionGroup(ctx, crashGroupId)
+	if err != nil {
+		msg := fmt.Sprintf(`failed to get exception group with id %q`, crashGroupId.String())
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+	if exceptionGroup == nil {
+		msg := fmt.Sprintf(`no crash group found with id %q`, crashGroupId.String())
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+		return
+	}
+
+	if exceptionGroup.IsMerged() {
+		msg := fmt.Sprintf(`crash group %q is merged into another group and cannot be merged into`, crashGroupId.String())
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := exceptionGroup.Merge(ctx, req.GroupIDs); err != nil {
+		if errors.Is(err, group.ErrMergeGroupsNotFound) {
+			msg := `one or more crash groups to merge were not found`
+			c.JSON(http.StatusNotFound, gin.H{"error": msg})
+			return
+		}
+		msg := `failed to merge crash groups`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	// reload to reflect the
+	// merged fingerprint set
+	exceptionGroup, err = app.GetExceptionGroup(ctx, crashGroupId)
+	if err != nil {
+		msg := fmt.Sprintf(`failed to get exception group with id %q`, crashGroupId.String())
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	// omit `event_ids` field from JSON
+	// response, because these can get really huge
+	exceptionGroup.EventIDs = nil
+
+	c.JSON(http.StatusOK, exceptionGroup)
+}
+
+func UnmergeCrashGroups(c *gin.Context) {
+	ctx := c.Request.Context()
+	appId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `app id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	crashGroupId, err := uuid.Parse(c.Param("crashGroupId"))
+	if err != nil {
+		msg := `crash group id is invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	req, ok := bindMergeRequest(c, crashGroupId, true)
+	if !ok {
+		return
+	}
+
+	app := App{
+		ID: &appId,
+	}
+
+	if !authzApp(c, appId, *ScopeAppAll) {
+		return
+	}
+
+	exceptionGroup, err := app.GetExceptionGroup(ctx, crashGroupId)
+	if err != nil {
+		msg := fmt.Sprintf(`failed to get exception group with id %q`, crashGroupId.String())
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+	if exceptionGroup == nil {
+		msg := fmt.Sprintf(`no crash group found with id %q`, crashGroupId.String())
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+		return
+	}
+
+	if err := exceptionGroup.Unmerge(ctx, req.GroupIDs); err != nil {
+		msg := `failed to unmerge crash groups`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	// reload to reflect the
+	// merged fingerprint set
+	exceptionGroup, err = app.GetExceptionGroup(ctx, crashGroupId)
+	if err != nil {
+		msg := fmt.Sprintf(`failed to get exception group with id %q`, crashGroupId.String())
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	// omit `event_ids` field from JSON
+	// response, because these can get really huge
+	exceptionGroup.EventIDs = nil
+
+	c.JSON(http.StatusOK, exceptionGroup)
+}
+
+func MergeANRGroups(c *gin.Context) {
+	ctx := c.Request.Context()
+	appId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `app id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	anrGroupId, err := uuid.Parse(c.Param("anrGroupId"))
+	if err != nil {
+		msg := `anr group id is invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	req, ok := bindMergeRequest(c, anrGroupId, false)
+	if !ok {
+		return
+	}
+
+	app := App{
+		ID: &appId,
+	}
+
+	if !authzApp(c, appId, *ScopeAppAll) {
+		return
+	}
+
+	anrGroup, err := app.GetANRGroup(ctx, anrGroupId)
+	if err != nil {
+		msg := fmt.Sprintf(`failed to get ANR group with id %q`, anrGroupId.String())
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+	if anrGroup == nil {
+		msg := fmt.Sprintf(`no anr group found with id %q`, anrGroupId.String())
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+		return
+	}
+
+	if anrGroup.IsMerged() {
+		msg := fmt.Sprintf(`anr group %q is merged into another group and cannot be merged into`, anrGroupId.String())
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := anrGroup.Merge(ctx, req.GroupIDs); err != nil {
+		if errors.Is(err, group.ErrMergeGroupsNotFound) {
+			msg := `one or more anr groups to merge were not found`
+			c.JSON(http.StatusNotFound, gin.H{"error": msg})
+			return
+		}
+		msg := `failed to merge anr groups`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	// reload to reflect the
+	// merged fingerprint set
+	anrGroup, err = app.GetANRGroup(ctx, anrGroupId)
+	if err != nil {
+		msg := fmt.Sprintf(`failed to get ANR group with id %q`, anrGroupId.String())
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	// omit `event_ids` field from JSON
+	// response, because these can get really huge
+	anrGroup.EventIDs = nil
+
+	c.JSON(http.StatusOK, anrGroup)
+}
+
+func UnmergeANRGroups(c *gin.Context) {
+	ctx := c.Request.Context()
+	appId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `app id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	anrGroupId, err := uuid.Parse(c.Param("anrGroupId"))
+	if err != nil {
+		msg := `anr group id is invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	req, ok := bindMergeRequest(c, anrGroupId, true)
+	if !ok {
+		return
+	}
+
+	app := App{
+		ID: &appId,
+	}
+
+	if !authzApp(c, appId, *ScopeAppAll) {
+		return
+	}
+
+	anrGroup, err := app.GetANRGroup(ctx, anrGroupId)
+	if err != nil {
+		msg := fmt.Sprintf(`failed to get ANR group with id %q`, anrGroupId.String())
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+	if anrGroup == nil {
+		msg := fmt.Sprintf(`no anr group found with id %q`, anrGroupId.String())
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+		return
+	}
+
+	if err := anrGroup.Unmerge(ctx, req.GroupIDs); err != nil {
+		msg := `failed to unmerge anr groups`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	// reload to reflect the
+	// merged fingerprint set
+	anrGroup, err = app.GetANRGroup(ctx, anrGroupId)
+	if err != nil {
+		msg := fmt.Sprintf(`failed to get ANR group with id %q`, anrGroupId.String())
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	// omit `event_ids` field from JSON
+	// response, because these can get really huge
+	anrGroup.EventIDs = nil
+
+	c.JSON(http.StatusOK, anrGroup)
+}
@@ -0,0 +1,113 @@
+package measure
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func newGroupMergeRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/apps/:id/crashGroups/:crashGroupId/merge", MergeCrashGroups)
+	r.POST("/apps/:id/crashGroups/:crashGroupId/unmerge", UnmergeCrashGroups)
+	r.POST("/apps/:id/anrGroups/:anrGroupId/merge", MergeANRGroups)
+	r.POST("/apps/:id/anrGroups/:anrGroupId/unmerge", UnmergeANRGroups)
+
+	return r
+}
+
+func TestGroupMergeBadRequest(t *testing.T) {
+	r := newGroupMergeRouter()
+	appId := uuid.New()
+	groupId := uuid.New()
+	other := uuid.New()
+
+	cases := map[string]struct {
+		path string
+		body string
+	}{
+		"invalid app id": {
+			path: fmt.Sprintf("/apps/%s/crashGroups/%s/merge", "app", groupId),
+			body: fmt.Sprintf(`{"group_ids":["%s"]}`, other),
+		},
+		"invalid crash group id": {
+			path: fmt.Sprintf("/apps/%s/crashGroups/%s/merge", appId, "group"),
+			body: fmt.Sprintf(`{"group_ids":["%s"]}`, other),
+		},
+		"invalid anr group id": {
+			path: fmt.Sprintf("/apps/%s/anrGroups/%s/unmerge", appId, "group"),
+			body: fmt.Sprintf(`{"group_ids":["%s"]}`, other),
+		},
+		"malformed body": {
+			path: fmt.Sprintf("/apps/%s/anrGroups/%s/merge", appId, groupId),
+			body: `{"group_ids":`,
+		},
+		"merge without body": {
+			path: fmt.Sprintf("/apps/%s/crashGroups/%s/merge", appId, groupId),
+			body: ``,
+		},
+		"merge without group ids": {
+			path: fmt.Sprintf("/apps/%s/anrGroups/%s/merge", appId, groupId),
+			body: `{"group_ids":[]}`,
+		},
+		"merge into itself": {
+			path: fmt.Sprintf("/apps/%s/crashGroups/%s/merge", appId, groupId),
+			body: fmt.Sprintf(`{"group_ids":["%s"]}`, groupId),
+		},
+		"unmerge from itself": {
+			path: fmt.Sprintf("/apps/%s/anrGroups/%s/unmerge", appId, groupId),
+			body: fmt.Sprintf(`{"group_ids":["%s"]}`, groupId),
+		},
+		"duplicate group ids": {
+			path: fmt.Sprintf("/apps/%s/crashGroups/%s/unmerge", appId, groupId),
+			body: fmt.Sprintf(`{"group_ids":["%s","%s"]}`, other, other),
+		},
+	}
+
+	for name, tc := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(tc.body))
+		r.ServeHTTP(w, req)
+
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: Expected status %d, but got %d", name, http.StatusBadRequest, w.Code)
+		}
+
+		var body map[string]string
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: Expected nil error, but got %v", name, err)
+		}
+
+		if body["error"] == "" {
+			t.Errorf("%s: Expected an error message, but got %v", name, body)
+		}
+	}
+}
+
+func TestGroupMergeValidationDetails(t *testing.T) {
+	r := newGroupMergeRouter()
+	appId := uuid.New()
+	groupId := uuid.New()
+
+	w := httptest.NewRecorder()
+	body := fmt.Sprintf(`{"group_ids":["%s"]}`, groupId)
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/apps/%s/crashGroups/%s/merge", appId, groupId), strings.NewReader(body))
+	r.ServeHTTP(w, req)
+
+	var resp map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	expected := `"group_ids" cannot contain the group being merged into`
+	if resp["details"] != expected {
+		t.Errorf("Expected details %q, but got %q", expected, resp["details"])
+	}
+}
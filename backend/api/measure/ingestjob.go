@@ -0,0 +1,730 @@
+package measure
+
+import (
+	"backend/api/event"
+	"backend/api/server"
+	"backend/api/webhook"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/leporo/sqlf"
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	// IngestStatusQueued is the status of a job
+	// waiting to be processed.
+	IngestStatusQueued = "queued"
+
+	// IngestStatusProcessing is the status of a job
+	// being processed by a worker.
+	IngestStatusProcessing = "processing"
+
+	// IngestStatusSucceeded is the status of a job
+	// whose events were ingested.
+	IngestStatusSucceeded = "succeeded"
+
+	// IngestStatusFailed is the status of a job
+	// that failed after exhausting all attempts.
+	IngestStatusFailed = "failed"
+)
+
+// maxIngestAttempts is the maximum number of
+// times a job is attempted before giving up.
+const maxIngestAttempts = 5
+
+// baseIngestBackoff is the wait before the
+// first retry of a failed job.
+const baseIngestBackoff = 15 * time.Second
+
+// maxIngestBackoff is the maximum wait
+// between retries of a failed job.
+const maxIngestBackoff = 30 * time.Minute
+
+// ingestLease is the duration a claimed job is
+// held by a worker. Jobs of crashed workers are
+// picked up again once the lease expires.
+const ingestLease = 10 * time.Minute
+
+// ingestLeaseRenewal is the interval at which a
+// worker renews the lease of the job it runs.
+const ingestLeaseRenewal = ingestLease / 3
+
+// ingestPollInterval is the interval at which idle
+// workers look for due jobs.
+const ingestPollInterval = 2 * time.Second
+
+// maxIngestErrorChars is the maximum characters
+// of the error stored for a failed attempt.
+const maxIngestErrorChars = 512
+
+// ingestSignal wakes up an idle worker when
+// a new job is enqueued.
+var ingestSignal = make(chan struct{}, 1)
+
+// ingestJob represents an event request queued
+// for asynchronous processing.
+type ingestJob struct {
+	ID              uuid.UUID       `json:"id"`
+	AppID           uuid.UUID       `json:"-"`
+	Status          string          `json:"status"`
+	Events          json.RawMessage `json:"-"`
+	EventCount      int             `json:"event_count"`
+	AttachmentCount int             `json:"attachment_count"`
+	BytesIn         int64           `json:"-"`
+	BytesInWire     int64           `json:"-"`
+	ContentEncoding string          `json:"-"`
+	Attempts        int             `json:"attempts"`
+	Error           *string         `json:"error"`
+	IngestedAt      *time.Time      `json:"-"`
+	CompletedAt     *time.Time      `json:"completed_at"`
+	CreatedAt       time.Time       `json:"created_at"`
+	UpdatedAt       time.Time       `json:"updated_at"`
+}
+
+// ingestBackoff computes the wait before the next
+// attempt after the given number of attempts.
+func ingestBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+
+	backoff := baseIngestBackoff
+	for i := 1; i < attempts; i++ {
+		backoff = backoff * 2
+		if backoff >= maxIngestBackoff {
+			return maxIngestBackoff
+		}
+	}
+
+	return backoff
+}
+
+// enqueue durably stores the event request along
+// with its attachments for asynchronous processing.
+// Returns false if the event request was already
+// queued. Returns a quotaExceededError if the event
+// request would cross the quotas of the app or its team.
+func (e *eventreq) enqueue(ctx context.Context, teamId uuid.UUID) (queued bool, err error) {
+	if err = e.readAttachments(); err != nil {
+		return
+	}
+
+	events, err := json.Marshal(e.events)
+	if err != nil {
+		return
+	}
+
+	tx, err := server.Server.PgPool.Begin(ctx)
+	if err != nil {
+		return
+	}
+
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		InsertInto("public.ingest_jobs").
+		Set("id", e.id).
+		Set("app_id", e.appId).
+		Set("status", IngestStatusQueued).
+		Set("events", events).
+		Set("event_count", len(e.events)).
+		Set("attachment_count", len(e.attachments)).
+		Set("bytes_in", e.size).
+		Set("bytes_in_wire", e.wireSize).
+		Set("content_encoding", e.encoding).
+		Set("next_attempt_at", now).
+		Set("created_at", now).
+		Set("updated_at", now).
+		Clause("on conflict (id) do nothing", nil)
+
+	defer stmt.Close()
+
+	tag, err := tx.Exec(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	// a job for the same request
+	// already exists
+	if tag.RowsAffected() < 1 {
+		return
+	}
+
+	if err = e.reserveUsage(ctx, &tx, teamId); err != nil {
+		return
+	}
+
+	for _, attachment := range e.attachments {
+		blobStmt := sqlf.PostgreSQL.
+			InsertInto("public.ingest_job_blobs").
+			Set("id", attachment.id).
+			Set("job_id", e.id).
+			Set("name", attachment.name).
+			Set("data", attachment.data).
+			Set("created_at", now)
+
+		_, err = tx.Exec(ctx, blobStmt.String(), blobStmt.Args()...)
+		blobStmt.Close()
+		if err != nil {
+			return
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	queued = true
+
+	// wake up an idle worker, if
+	// none is waiting, skip
+	select {
+	case ingestSignal <- struct{}{}:
+	default:
+	}
+
+	return
+}
+
+// claimIngestJob claims the next due job by leasing
+// it to the caller. Returns nil if no job is due.
+func claimIngestJob(ctx context.Context) (job *ingestJob, err error) {
+	now := time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.ingest_jobs").
+		Set("status", IngestStatusProcessing).
+		SetExpr("attempts", "attempts + 1").
+		Set("next_attempt_at", now.Add(ingestLease)).
+		Set("updated_at", now).
+		Where("id = (select id from public.ingest_jobs where status in (?, ?) and next_attempt_at <= ? order by next_attempt_at limit 1 for update skip locked)", IngestStatusQueued, IngestStatusProcessing, now).
+		Returning("id").
+		Returning("app_id").
+		Returning("events").
+		Returning("bytes_in").
+		Returning("bytes_in_wire").
+		Returning("content_encoding").
+		Returning("attempts").
+		Returning("ingested_at").
+		Returning("created_at")
+
+	defer stmt.Close()
+
+	var j ingestJob
+
+	if err = server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&j.ID, &j.AppID, &j.Events, &j.BytesIn, &j.BytesInWire, &j.ContentEncoding, &j.Attempts, &j.IngestedAt, &j.CreatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return
+	}
+
+	j.Status = IngestStatusProcessing
+	job = &j
+
+	return
+}
+
+// renewLease extends the lease of a job still being
+// run. Returns false if the lease was lost to another
+// worker.
+func (j ingestJob) renewLease(ctx context.Context) (renewed bool, err error) {
+	now := time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.ingest_jobs").
+		Set("next_attempt_at", now.Add(ingestLease)).
+		Set("updated_at", now).
+		Where("id = ?", j.ID).
+		Where("status = ?", IngestStatusProcessing).
+		Where("attempts = ?", j.Attempts)
+
+	defer stmt.Close()
+
+	tag, err := server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	renewed = tag.RowsAffected() > 0
+
+	return
+}
+
+// keepLease renews the job's lease until the context
+// is done. Cancels the run if the lease is lost.
+func (j ingestJob) keepLease(ctx context.Context, cancel context.CancelFunc) {
+	ticker := time.NewTicker(ingestLeaseRenewal)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			renewed, err := j.renewLease(ctx)
+			if err != nil {
+				fmt.Printf("failed to renew lease of event request %q: %v\n", j.ID, err)
+				continue
+			}
+			if !renewed {
+				fmt.Printf("lost lease of event request %q, attempt %d\n", j.ID, j.Attempts)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// eventReq rebuilds the event request from the
+// job's events and attachment blobs.
+func (j ingestJob) eventReq(ctx context.Context) (e *eventreq, err error) {
+	e = &eventreq{
+		id:          j.ID,
+		appId:       j.AppID,
+		symbolicate: make(map[uuid.UUID]int),
+		attachments: make(map[uuid.UUID]*attachment),
+		size:        j.BytesIn,
+		wireSize:    j.BytesInWire,
+		encoding:    j.ContentEncoding,
+	}
+
+	var events []event.EventField
+	if err = json.Unmarshal(j.Events, &events); err != nil {
+		return
+	}
+
+	for i := range events {
+		e.push(events[i])
+	}
+
+	stmt := sqlf.PostgreSQL.
+		From("public.ingest_job_blobs").
+		Select("id").
+		Select("name").
+		Select("data").
+		Where("job_id = ?", j.ID)
+
+	defer stmt.Close()
+
+	rows, err := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		a := attachment{}
+		if err = rows.Scan(&a.id, &a.name, &a.data); err != nil {
+			return
+		}
+		e.attachments[a.id] = &a
+	}
+
+	err = rows.Err()
+
+	return
+}
+
+// markIngested persists the processed events and
+// records that the events were written to clickhouse,
+// so that retries don't write duplicate events.
+func (j *ingestJob) markIngested(ctx context.Context, e *eventreq) (err error) {
+	events, err := json.Marshal(e.events)
+	if err != nil {
+		return
+	}
+
+	now := time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.ingest_jobs").
+		Set("events", events).
+		Set("ingested_at", now).
+		Set("updated_at", now).
+		Where("id = ?", j.ID)
+
+	defer stmt.Close()
+
+	if _, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...); err != nil {
+		return
+	}
+
+	j.Events = events
+	j.IngestedAt = &now
+
+	return
+}
+
+// succeed marks the job as succeeded and discards
+// its events and attachment blobs.
+func (j *ingestJob) succeed(ctx context.Context, tx *pgx.Tx) (err error) {
+	now := time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.ingest_jobs").
+		Set("status", IngestStatusSucceeded).
+		Set("events", "[]").
+		Set("error", nil).
+		Set("completed_at", now).
+		Set("updated_at", now).
+		Where("id = ?", j.ID)
+
+	defer stmt.Close()
+
+	blobStmt := sqlf.PostgreSQL.
+		DeleteFrom("public.ingest_job_blobs").
+		Where("job_id = ?", j.ID)
+
+	defer blobStmt.Close()
+
+	if tx != nil {
+		if _, err = (*tx).Exec(ctx, stmt.String(), stmt.Args()...); err != nil {
+			return
+		}
+		_, err = (*tx).Exec(ctx, blobStmt.String(), blobStmt.Args()...)
+	} else {
+		if _, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...); err != nil {
+			return
+		}
+		_, err = server.Server.PgPool.Exec(ctx, blobStmt.String(), blobStmt.Args()...)
+	}
+
+	if err != nil {
+		return
+	}
+
+	j.Status = IngestStatusSucceeded
+	j.CompletedAt = &now
+
+	return
+}
+
+// fail records the failed attempt. Schedules a retry
+// with backoff or marks the job as failed once all
+// attempts are exhausted.
+func (j *ingestJob) fail(ctx context.Context, cause error) (err error) {
+	now := time.Now()
+	msg := cause.Error()
+	if len(msg) > maxIngestErrorChars {
+		msg = msg[:maxIngestErrorChars]
+	}
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.ingest_jobs").
+		Set("error", msg).
+		Set("updated_at", now)
+
+	defer stmt.Close()
+
+	if j.Attempts >= maxIngestAttempts {
+		stmt.
+			Set("status", IngestStatusFailed).
+			Set("completed_at", now)
+	} else {
+		stmt.
+			Set("status", IngestStatusQueued).
+			Set("next_attempt_at", now.Add(ingestBackoff(j.Attempts)))
+	}
+
+	stmt.Where("id = ?", j.ID)
+
+	if _, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...); err != nil {
+		return
+	}
+
+	if j.Attempts >= maxIngestAttempts {
+		j.Status = IngestStatusFailed
+		err = j.discardBlobs(ctx)
+	}
+
+	return
+}
+
+// discardBlobs deletes the job's attachment blobs.
+func (j ingestJob) discardBlobs(ctx context.Context) (err error) {
+	stmt := sqlf.PostgreSQL.
+		DeleteFrom("public.ingest_job_blobs").
+		Where("job_id = ?", j.ID)
+
+	defer stmt.Close()
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// run symbolicates, uploads attachments, ingests and
+// buckets the job's events. Steps already performed
+// by an earlier attempt are skipped.
+func (j *ingestJob) run(ctx context.Context) (err error) {
+	e, err := j.eventReq(ctx)
+	if err != nil {
+		return
+	}
+
+	seen, err := e.seen(ctx)
+	if err != nil {
+		return
+	}
+
+	// processed before the job
+	// could be marked
+	if seen {
+		return j.succeed(ctx, nil)
+	}
+
+	app, err := SelectApp(ctx, j.AppID)
+	if err != nil {
+		return
+	}
+
+	if app == nil {
+		return fmt.Errorf("no app found with id %q", j.AppID)
+	}
+
+	if j.IngestedAt == nil {
+		if e.needsSymbolication() {
+			if err = e.symbolicateEvents(ctx); err != nil {
+				return
+			}
+		}
+
+		if e.hasAttachments() {
+			if err = e.attach(ctx); err != nil {
+				return
+			}
+		}
+
+		// an earlier attempt may have written
+		// some events before failing
+		if j.Attempts > 1 {
+			if e.ingested, err = e.ingestedEventIds(ctx); err != nil {
+				return
+			}
+		}
+
+		if err = e.ingest(ctx); err != nil {
+			return
+		}
+
+		if err = j.markIngested(ctx, e); err != nil {
+			return
+		}
+	}
+
+	tx, err := server.Server.PgPool.Begin(ctx)
+	if err != nil {
+		return
+	}
+
+	defer tx.Rollback(ctx)
+
+	// start span to trace bucketing unhandled exceptions
+	bucketUnhandledExceptionsTracer := otel.Tracer("bucket-unhandled-exceptions-tracer")
+	_, bucketUnhandledExceptionsSpan := bucketUnhandledExceptionsTracer.Start(ctx, "bucket-unhandled-exceptions")
+
+	err = e.bucketUnhandledExceptions(ctx, &tx)
+	bucketUnhandledExceptionsSpan.End()
+	if err != nil {
+		return
+	}
+
+	// start span to trace bucketing ANRs
+	bucketAnrsTracer := otel.Tracer("bucket-anrs-tracer")
+	_, bucketAnrsSpan := bucketAnrsTracer.Start(ctx, "bucket-anrs-exceptions")
+
+	err = e.bucketANRs(ctx, &tx)
+	bucketAnrsSpan.End()
+	if err != nil {
+		return
+	}
+
+	if !app.Onboarded {
+		firstEvent := e.events[0]
+		uniqueID := firstEvent.Attribute.AppUniqueID
+		platform := firstEvent.Attribute.Platform
+		version := firstEvent.Attribute.AppVersion
+
+		if err = app.Onboard(ctx, &tx, uniqueID, platform, version); err != nil {
+			return
+		}
+	}
+
+	if err = e.save(ctx, &tx); err != nil {
+		return
+	}
+
+	if err = j.succeed(ctx, &tx); err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	// notify webhooks only after the groups
+	// are committed
+	if len(e.webhookEvents) > 0 {
+		go webhook.Dispatch(context.Background(), e.appId, e.webhookEvents)
+	}
+
+	return
+}
+
+// processIngestJob claims and processes the next
+// due job. Returns false if no job was due.
+func processIngestJob(ctx context.Context) (processed bool, err error) {
+	job, err := claimIngestJob(ctx)
+	if err != nil || job == nil {
+		return
+	}
+
+	processed = true
+
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go job.keepLease(runCtx, cancel)
+
+	if runErr := job.run(runCtx); runErr != nil {
+		fmt.Printf("failed to process event request %q, attempt %d: %v\n", job.ID, job.Attempts, runErr)
+		err = job.fail(ctx, runErr)
+	}
+
+	return
+}
+
+// StartIngestWorkers starts n workers that process
+// queued event requests until the context is done.
+func StartIngestWorkers(ctx context.Context, n int) {
+	for i := 0; i < n; i++ {
+		go func() {
+			ticker := time.NewTicker(ingestPollInterval)
+			defer ticker.Stop()
+
+			for {
+				processed, err := processIngestJob(ctx)
+				if err != nil {
+					fmt.Println("failed to process ingest job", err)
+				}
+
+				// drain the queue before
+				// waiting for more jobs
+				if processed {
+					continue
+				}
+
+				select {
+				case <-ctx.Done():
+					return
+				case <-ingestSignal:
+				case <-ticker.C:
+				}
+			}
+		}()
+	}
+}
+
+// GetIngestJob fetches the job of an event request
+// of an app. Returns nil if the job doesn't exist.
+func GetIngestJob(ctx context.Context, appId, id uuid.UUID) (job *ingestJob, err error) {
+	stmt := sqlf.PostgreSQL.
+		From("public.ingest_jobs").
+		Select("id").
+		Select("app_id").
+		Select("status").
+		Select("event_count").
+		Select("attachment_count").
+		Select("attempts").
+		Select("error").
+		Select("completed_at").
+		Select("created_at").
+		Select("updated_at").
+		Where("id = ?", id).
+		Where("app_id = ?", appId)
+
+	defer stmt.Close()
+
+	var j ingestJob
+
+	if err = server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&j.ID, &j.AppID, &j.Status, &j.EventCount, &j.AttachmentCount, &j.Attempts, &j.Error, &j.CompletedAt, &j.CreatedAt, &j.UpdatedAt); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return
+	}
+
+	job = &j
+
+	return
+}
+
+func GetEventRequestStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+	appId, err := uuid.Parse(c.GetString("appId"))
+	if err != nil {
+		msg := `error parsing app's uuid`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	reqId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `event request id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	job, err := GetIngestJob(ctx, appId, reqId)
+	if err != nil {
+		msg := `failed to fetch event request status`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	if job != nil {
+		c.JSON(http.StatusOK, job)
+		return
+	}
+
+	// event requests processed before
+	// jobs were introduced
+	eventReq := eventreq{
+		id:    reqId,
+		appId: appId,
+	}
+
+	seen, err := eventReq.seen(ctx)
+	if err != nil {
+		msg := `failed to fetch event request status`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	if !seen {
+		msg := fmt.Sprintf(`no event request found with id %q`, reqId)
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":     reqId,
+		"status": IngestStatusSucceeded,
+	})
+}
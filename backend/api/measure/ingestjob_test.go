@@ -0,0 +1,83 @@
+package measure
+
+import (
+	"backend/api/event"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestIngestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		0:  0,
+		1:  15 * time.Second,
+		2:  30 * time.Second,
+		4:  2 * time.Minute,
+		10: 30 * time.Minute,
+	}
+
+	for attempts, expected := range cases {
+		if got := ingestBackoff(attempts); got != expected {
+			t.Errorf("Expected backoff %v for %d attempts, but got %v", expected, attempts, got)
+		}
+	}
+}
+
+func TestEventReqPush(t *testing.T) {
+	// Setup
+	e := eventreq{
+		symbolicate: make(map[uuid.UUID]int),
+		attachments: make(map[uuid.UUID]*attachment),
+	}
+
+	exception := event.EventField{
+		ID:   uuid.New(),
+		Type: event.TypeException,
+		Exception: &event.Exception{
+			Handled: false,
+		},
+	}
+
+	anr := event.EventField{
+		ID:   uuid.New(),
+		Type: event.TypeANR,
+		ANR:  &event.ANR{},
+	}
+
+	// Act
+	e.push(event.EventField{ID: uuid.New(), Type: event.TypeString})
+	e.push(exception)
+	e.push(anr)
+
+	// Assert
+	if len(e.events) != 3 {
+		t.Errorf("Expected %d events, but got %d", 3, len(e.events))
+	}
+	if len(e.exceptionIds) != 1 || e.exceptionIds[0] != 1 {
+		t.Errorf("Expected exception ids %v, but got %v", []int{1}, e.exceptionIds)
+	}
+	if len(e.anrIds) != 1 || e.anrIds[0] != 2 {
+		t.Errorf("Expected anr ids %v, but got %v", []int{2}, e.anrIds)
+	}
+	if len(e.symbolicate) != 2 || e.symbolicate[exception.ID] != 1 || e.symbolicate[anr.ID] != 2 {
+		t.Errorf("Expected symbolicate indices for exception & anr, but got %v", e.symbolicate)
+	}
+}
+
+func TestIngestSkipsIngestedEvents(t *testing.T) {
+	id := uuid.New()
+	e := eventreq{
+		events: []event.EventField{
+			{ID: id, Type: event.TypeString},
+		},
+		ingested: map[uuid.UUID]bool{id: true},
+	}
+
+	// there's no clickhouse connection,
+	// so a write would panic
+	if err := e.ingest(context.Background()); err != nil {
+		t.Errorf("Expected nil error, but got %v", err)
+	}
+}
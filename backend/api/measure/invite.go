@@ -0,0 +1,700 @@
+package measure
+
+import (
+	"backend/api/authsession"
+	"backend/api/chrono"
+	"backend/api/cipher"
+	"backend/api/server"
+	"backend/email"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/leporo/sqlf"
+)
+
+// inviteExpiry is the duration an invite
+// can be accepted for after being sent.
+const inviteExpiry = 7 * 24 * time.Hour
+
+// inviteTokenBytes is the number of random
+// bytes in an invite token.
+const inviteTokenBytes = 32
+
+// errInvalidInvite is returned when an invite
+// token is unknown, expired or no longer pending.
+var errInvalidInvite = errors.New("invite is invalid or has expired")
+
+// Invite represents a pending invitation
+// for an email to join a team.
+type Invite struct {
+	id        uuid.UUID
+	teamId    uuid.UUID
+	email     string
+	role      rank
+	invitedBy *uuid.UUID
+	expiresAt time.Time
+	createdAt time.Time
+	updatedAt time.Time
+
+	// token is only known right after the
+	// invite is created or resent.
+	token string
+}
+
+func (i Invite) MarshalJSON() ([]byte, error) {
+	inviteMap := make(map[string]any)
+
+	inviteMap["id"] = i.id
+	inviteMap["email"] = i.email
+	inviteMap["role"] = i.role.String()
+	inviteMap["invited_by"] = i.invitedBy
+	inviteMap["expired"] = !time.Now().Before(i.expiresAt)
+	inviteMap["expires_at"] = i.expiresAt.Format(chrono.ISOFormatJS)
+	inviteMap["created_at"] = i.createdAt.Format(chrono.ISOFormatJS)
+	inviteMap["updated_at"] = i.updatedAt.Format(chrono.ISOFormatJS)
+
+	// the link is revealed only when it cannot be
+	// emailed, so that it can be shared manually
+	if i.token != "" && !smtpConfigured() {
+		inviteMap["link"] = inviteLink(server.Server.Config.SiteOrigin, i.token)
+	}
+
+	return json.Marshal(inviteMap)
+}
+
+// newInvite creates a new invite for the
+// email to join the team with the role.
+func newInvite(teamId uuid.UUID, address string, role rank, invitedBy uuid.UUID) (*Invite, error) {
+	now := time.Now()
+
+	i := &Invite{
+		id:        uuid.New(),
+		teamId:    teamId,
+		email:     address,
+		role:      role,
+		invitedBy: &invitedBy,
+		createdAt: now,
+	}
+
+	if err := i.newToken(now); err != nil {
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// newToken generates a new token for the invite
+// & extends its expiry.
+func (i *Invite) newToken(now time.Time) error {
+	bytes := make([]byte, inviteTokenBytes)
+	if _, err := rand.Read(bytes); err != nil {
+		return err
+	}
+
+	i.token = base64.RawURLEncoding.EncodeToString(bytes)
+	i.expiresAt = now.Add(inviteExpiry)
+	i.updatedAt = now
+
+	return nil
+}
+
+// hashInviteToken computes the hash of the
+// token. Only the hash is ever stored.
+func hashInviteToken(value string) (string, error) {
+	hash, err := cipher.ComputeSHA2Hash([]byte(value))
+	if err != nil {
+		return "", err
+	}
+
+	return *hash, nil
+}
+
+// insert saves the invite to database, revoking any
+// earlier pending invite of the email to the team.
+func (i *Invite) insert(ctx context.Context, tx *pgx.Tx) error {
+	hash, err := hashInviteToken(i.token)
+	if err != nil {
+		return err
+	}
+
+	revokeStmt := sqlf.PostgreSQL.
+		Update("public.invites").
+		Set("revoked_at", i.createdAt).
+		Where("team_id = ? and email = ?", i.teamId, i.email).
+		Where(pendingInvite)
+
+	defer revokeStmt.Close()
+
+	if _, err := (*tx).Exec(ctx, revokeStmt.String(), revokeStmt.Args()...); err != nil {
+		return err
+	}
+
+	stmt := sqlf.PostgreSQL.
+		InsertInto("public.invites").
+		Set("id", i.id).
+		Set("team_id", i.teamId).
+		Set("email", i.email).
+		Set("role", i.role.String()).
+		Set("token_hash", hash).
+		Set("invited_by", i.invitedBy).
+		Set("expires_at", i.expiresAt).
+		Set("created_at", i.createdAt).
+		Set("updated_at", i.updatedAt)
+
+	defer stmt.Close()
+
+	_, err = (*tx).Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return err
+}
+
+// resend replaces the invite's token, so that
+// earlier links stop working, & extends its
+// expiry.
+func (i *Invite) resend(ctx context.Context) error {
+	if err := i.newToken(time.Now()); err != nil {
+		return err
+	}
+
+	hash, err := hashInviteToken(i.token)
+	if err != nil {
+		return err
+	}
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.invites").
+		Set("token_hash", hash).
+		Set("expires_at", i.expiresAt).
+		Set("updated_at", i.updatedAt).
+		Where("id = ?", i.id)
+
+	defer stmt.Close()
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return err
+}
+
+// revoke revokes the invite, so that
+// it can no longer be accepted.
+func (i *Invite) revoke(ctx context.Context) error {
+	stmt := sqlf.PostgreSQL.
+		Update("public.invites").
+		Set("revoked_at", time.Now()).
+		Where("id = ?", i.id)
+
+	defer stmt.Close()
+
+	_, err := server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return err
+}
+
+// pendingInvite is the condition matching invites
+// not yet accepted, declined or revoked.
+const pendingInvite = "accepted_at is null and declined_at is null and revoked_at is null"
+
+// inviteCols are the columns selected
+// for reading an invite.
+var inviteCols = []string{
+	"id",
+	"team_id",
+	"email",
+	"role",
+	"invited_by",
+	"expires_at",
+	"created_at",
+	"updated_at",
+}
+
+// scanInvite scans a row selected
+// using inviteCols.
+func scanInvite(row pgx.Row) (*Invite, error) {
+	var role string
+
+	i := new(Invite)
+
+	if err := row.Scan(&i.id, &i.teamId, &i.email, &role, &i.invitedBy, &i.expiresAt, &i.createdAt, &i.updatedAt); err != nil {
+		return nil, err
+	}
+
+	i.role = roleMap[role]
+
+	return i, nil
+}
+
+// getInvites fetches the pending invites
+// of the team, most recent first.
+func getInvites(ctx context.Context, teamId uuid.UUID) (invites []Invite, err error) {
+	stmt := sqlf.PostgreSQL.Select(strings.Join(inviteCols, ",")).
+		From("public.invites").
+		Where("team_id = ?", teamId).
+		Where(pendingInvite).
+		OrderBy("updated_at desc")
+
+	defer stmt.Close()
+
+	rows, err := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		i, err := scanInvite(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		invites = append(invites, *i)
+	}
+
+	err = rows.Err()
+
+	return
+}
+
+// getInvite fetches a pending invite of the team
+// by id. Returns nil if no invite is found.
+func getInvite(ctx context.Context, teamId, inviteId uuid.UUID) (*Invite, error) {
+	stmt := sqlf.PostgreSQL.Select(strings.Join(inviteCols, ",")).
+		From("public.invites").
+		Where("id = ? and team_id = ?", inviteId, teamId).
+		Where(pendingInvite)
+
+	defer stmt.Close()
+
+	i, err := scanInvite(server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// declineInvite declines the pending invite
+// matching the token.
+func declineInvite(ctx context.Context, token string) (*Invite, error) {
+	hash, err := hashInviteToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.invites").
+		Set("declined_at", time.Now()).
+		Where("token_hash = ?", hash).
+		Where("expires_at > ?", time.Now()).
+		Where(pendingInvite).
+		Returning(strings.Join(inviteCols, ","))
+
+	defer stmt.Close()
+
+	i, err := scanInvite(server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, errInvalidInvite
+		}
+		return nil, err
+	}
+
+	return i, nil
+}
+
+// acceptInvites accepts all pending invites sent to
+// the user's email, making the user a member of each
+// inviting team. Invites are claimed as part of the
+// same transaction, so that concurrent sign ins accept
+// each invite only once.
+func acceptInvites(ctx context.Context, u *User) (accepted []Invite, err error) {
+	if u.Email == nil {
+		return
+	}
+
+	tx, err := server.Server.PgPool.Begin(ctx)
+	if err != nil {
+		return
+	}
+
+	defer tx.Rollback(ctx)
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.invites").
+		Set("accepted_at", time.Now()).
+		Where("email = ?", strings.ToLower(*u.Email)).
+		Where("expires_at > ?", time.Now()).
+		Where(pendingInvite).
+		Returning(strings.Join(inviteCols, ","))
+
+	defer stmt.Close()
+
+	rows, err := tx.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	invites, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (*Invite, error) {
+		return scanInvite(row)
+	})
+	if err != nil {
+		return
+	}
+
+	userId := uuid.MustParse(*u.ID)
+
+	for _, i := range invites {
+		role, err := u.getRole(i.teamId.String())
+		if err != nil {
+			return nil, err
+		}
+
+		// already a member, like when added
+		// directly after being invited
+		if role != unknown {
+			continue
+		}
+
+		team := &Team{ID: &i.teamId}
+		if err := team.addMember(ctx, userId, i.role, &tx); err != nil {
+			return nil, err
+		}
+
+		accepted = append(accepted, *i)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// acceptInvitesOnSignin accepts the pending invites of
+// a signing in user, if the sign in proves ownership
+// of the user's email. Passwords prove it only when
+// emails are verified. Failing to accept does not fail
+// the sign in, so errors are only logged.
+func acceptInvitesOnSignin(c *gin.Context, u *User, provider string) {
+	if provider == authsession.PasswordProvider && !smtpConfigured() {
+		return
+	}
+
+	invites, err := acceptInvites(c.Request.Context(), u)
+	if err != nil {
+		fmt.Println("failed to accept invites", err)
+	}
+
+	userId := uuid.MustParse(*u.ID)
+
+	for _, i := range invites {
+		newAuditLog(c, auditInviteAccepted).
+			by(userId).
+			inTeam(i.teamId).
+			on(auditTargetInvite, i.id.String()).
+			change(nil, map[string]any{"email": i.email, "role": i.role.String()}).
+			record(c.Request.Context())
+	}
+}
+
+// inviteLink builds the dashboard link
+// for accepting or declining an invite.
+func inviteLink(origin, token string) string {
+	return fmt.Sprintf("%s/auth/invite?%s", origin, url.Values{"token": {token}}.Encode())
+}
+
+// inviteMessage builds the email message
+// for an invite to the team.
+func inviteMessage(origin, teamName string, inviter *User, i *Invite) (msg email.Message) {
+	from := "A teammate"
+	if inviter != nil && inviter.Name != nil && *inviter.Name != "" {
+		from = *inviter.Name
+	}
+
+	msg.To = []string{i.email}
+	msg.Subject = fmt.Sprintf("You're invited to join %s on Measure", teamName)
+	msg.Body = fmt.Sprintf("Hi,\n\n%s invited you to join the %s team on Measure as %s.\n\nSign in with %s to accept, or decline the invite here:\n\n%s\n\nThis invite expires in %s. If you weren't expecting it, you can ignore this email.\n", from, teamName, i.role, i.email, inviteLink(origin, i.token), humanizeExpiry(inviteExpiry))
+
+	return
+}
+
+// sendInviteEmails emails the invites in the background,
+// if an SMTP server is configured.
+func sendInviteEmails(ctx context.Context, team *Team, inviter *User, invites []*Invite) error {
+	if !smtpConfigured() || len(invites) == 0 {
+		return nil
+	}
+
+	sender, err := newEmailSender()
+	if err != nil {
+		return err
+	}
+
+	teamName, err := team.getName(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, i := range invites {
+		sendEmail(sender, inviteMessage(server.Server.Config.SiteOrigin, teamName, inviter, i), "invite")
+	}
+
+	return nil
+}
+
+// createInvites creates invites for the invitees to
+// join the team, sent by the inviting user.
+func createInvites(ctx context.Context, teamId, invitedBy uuid.UUID, invitees []Invitee) (invites []*Invite, err error) {
+	if len(invitees) == 0 {
+		return
+	}
+
+	tx, err := server.Server.PgPool.Begin(ctx)
+	if err != nil {
+		return
+	}
+
+	defer tx.Rollback(ctx)
+
+	for _, invitee := range invitees {
+		address, err := normalizeEmail(invitee.Email)
+		if err != nil {
+			return nil, err
+		}
+
+		i, err := newInvite(teamId, address, invitee.Role, invitedBy)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := i.insert(ctx, &tx); err != nil {
+			return nil, err
+		}
+
+		invites = append(invites, i)
+	}
+
+	err = tx.Commit(ctx)
+
+	return
+}
+
+// getInviteFromParams parses the team & invite ids from
+// the route, checks that the user can manage invites of
+// the invite's role & fetches the invite. Writes the error
+// response and returns nil if any of it fails.
+func getInviteFromParams(c *gin.Context) *Invite {
+	userId := c.GetString("userId")
+	teamId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `team id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return nil
+	}
+
+	inviteId, err := uuid.Parse(c.Param("inviteId"))
+	if err != nil {
+		msg := `invite id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return nil
+	}
+
+	ok, err := PerformAuthz(c, userId, teamId.String(), *ScopeTeamInviteSameOrLower)
+	if err != nil {
+		msg := `couldn't perform authorization checks`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return nil
+	}
+	if !ok {
+		msg := fmt.Sprintf(`you don't have permissions to manage invites in team [%s]`, teamId)
+		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+		return nil
+	}
+
+	invite, err := getInvite(c, teamId, inviteId)
+	if err != nil {
+		msg := `failed to fetch invite`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return nil
+	}
+	if invite == nil {
+		msg := fmt.Sprintf(`no pending invite found with id %q`, inviteId)
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+		return nil
+	}
+
+	userRole, err := (&User{ID: &userId}).getRole(teamId.String())
+	if err != nil {
+		msg := `couldn't perform authorization checks`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return nil
+	}
+
+	if invite.role > userRole {
+		msg := fmt.Sprintf(`you don't have permissions to manage invites in team [%s]`, teamId)
+		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+		return nil
+	}
+
+	return invite
+}
+
+func GetTeamInvites(c *gin.Context) {
+	ctx := c.Request.Context()
+	userId := c.GetString("userId")
+	teamId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `team id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	ok, err := PerformAuthz(c, userId, teamId.String(), *ScopeTeamRead)
+	if err != nil {
+		msg := `couldn't perform authorization checks`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+	if !ok {
+		msg := fmt.Sprintf(`you don't have read permissions to team [%s]`, teamId)
+		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+		return
+	}
+
+	invites, err := getInvites(ctx, teamId)
+	if err != nil {
+		msg := `failed to fetch invites`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	if invites == nil {
+		invites = []Invite{}
+	}
+
+	c.JSON(http.StatusOK, invites)
+}
+
+func ResendInvite(c *gin.Context) {
+	ctx := c.Request.Context()
+	invite := getInviteFromParams(c)
+	if invite == nil {
+		return
+	}
+
+	if smtpConfigured() && !allowEmail(c, invite.email) {
+		return
+	}
+
+	if err := invite.resend(ctx); err != nil {
+		msg := `failed to resend invite`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	inviter, err := getUser(ctx, uuid.MustParse(c.GetString("userId")))
+	if err != nil {
+		fmt.Println("failed to fetch inviter", err)
+	}
+
+	if err := sendInviteEmails(ctx, &Team{ID: &invite.teamId}, inviter, []*Invite{invite}); err != nil {
+		msg := `failed to resend invite`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	newAuditLog(c, auditInviteResent).
+		inTeam(invite.teamId).
+		on(auditTargetInvite, invite.id.String()).
+		record(ctx)
+
+	c.JSON(http.StatusOK, invite)
+}
+
+func RevokeInvite(c *gin.Context) {
+	ctx := c.Request.Context()
+	invite := getInviteFromParams(c)
+	if invite == nil {
+		return
+	}
+
+	if err := invite.revoke(ctx); err != nil {
+		msg := `failed to revoke invite`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	newAuditLog(c, auditInviteRevoked).
+		inTeam(invite.teamId).
+		on(auditTargetInvite, invite.id.String()).
+		change(map[string]any{"email": invite.email, "role": invite.role.String()}, nil).
+		record(ctx)
+
+	c.JSON(http.StatusOK, gin.H{"ok": fmt.Sprintf("revoked invite [%s]", invite.id)})
+}
+
+// DeclineInvite declines an invite using the
+// token from the invite email. Needs no sign
+// in, since the invitee may not have an
+// account.
+func DeclineInvite(c *gin.Context) {
+	var payload struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if !bindToken(c, &payload) {
+		return
+	}
+
+	invite, err := declineInvite(c.Request.Context(), payload.Token)
+	if err != nil {
+		if errors.Is(err, errInvalidInvite) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		msg := "failed to decline invite"
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	newAuditLog(c, auditInviteDeclined).
+		inTeam(invite.teamId).
+		on(auditTargetInvite, invite.id.String()).
+		change(map[string]any{"email": invite.email, "role": invite.role.String()}, nil).
+		record(c.Request.Context())
+
+	c.JSON(http.StatusOK, gin.H{
+		"ok": "invite declined",
+	})
+}
@@ -0,0 +1,119 @@
+package measure
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestNewInvite(t *testing.T) {
+	before := time.Now()
+
+	invite, err := newInvite(uuid.New(), "ada@example.com", developer, uuid.New())
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if invite.token == "" {
+		t.Error("Expected token, but got empty string")
+	}
+
+	if invite.expiresAt.Before(before.Add(inviteExpiry)) {
+		t.Errorf("Expected expiry after %v, but got %v", before.Add(inviteExpiry), invite.expiresAt)
+	}
+
+	other, err := newInvite(invite.teamId, invite.email, invite.role, *invite.invitedBy)
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if other.token == invite.token {
+		t.Error("Expected unique tokens, but got duplicates")
+	}
+
+	// resending replaces the token
+	token := invite.token
+	if err := invite.newToken(time.Now()); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if invite.token == token {
+		t.Error("Expected new token, but got the same")
+	}
+}
+
+func TestInviteMessage(t *testing.T) {
+	name := "Ada Lovelace"
+	inviter := &User{Name: &name}
+
+	invite, err := newInvite(uuid.New(), "grace@example.com", admin, uuid.New())
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	msg := inviteMessage("https://measure.example.com", "Acme", inviter, invite)
+
+	if len(msg.To) != 1 || msg.To[0] != "grace@example.com" {
+		t.Errorf("Expected recipient %q, but got %v", "grace@example.com", msg.To)
+	}
+
+	if !strings.Contains(msg.Subject, "Acme") {
+		t.Errorf("Expected subject to mention team, but got %q", msg.Subject)
+	}
+
+	for _, expected := range []string{"Ada Lovelace invited you", "as admin", "Sign in with grace@example.com", "7 days"} {
+		if !strings.Contains(msg.Body, expected) {
+			t.Errorf("Expected body to contain %q, but got %q", expected, msg.Body)
+		}
+	}
+
+	prefix := "https://measure.example.com/auth/invite?"
+	start := strings.Index(msg.Body, prefix)
+	if start < 0 {
+		t.Fatalf("Expected link with prefix %q, but got %q", prefix, msg.Body)
+	}
+
+	link := msg.Body[start:]
+	link = link[:strings.Index(link, "\n")]
+
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if u.Query().Get("token") != invite.token {
+		t.Errorf("Expected token %q, but got %q", invite.token, u.Query().Get("token"))
+	}
+
+	// unknown inviters are not named
+	msg = inviteMessage("https://measure.example.com", "Acme", nil, invite)
+
+	if !strings.HasPrefix(msg.Body, "Hi,\n\nA teammate invited you") {
+		t.Errorf("Expected generic inviter, but got %q", msg.Body)
+	}
+}
+
+func TestCheckName(t *testing.T) {
+	if err := checkName("team", "Acme Inc"); err != nil {
+		t.Errorf("Expected nil error, but got %v", err)
+	}
+
+	// names end up in invite
+	// email subjects
+	invalid := []string{
+		"",
+		"Acme\r\nBcc: victim@example.com",
+		"Acme\n",
+		"Acme\x00",
+		"Acme\u0085",
+	}
+
+	for _, name := range invalid {
+		if err := checkName("team", name); err == nil {
+			t.Errorf("Expected error for %q, but got nil", name)
+		}
+	}
+}
@@ -7,15 +7,18 @@ import (
 	"io"
 	"mime/multipart"
 	"net/http"
+	"slices"
 	"time"
 
 	"backend/api/chrono"
 	"backend/api/cipher"
 	"backend/api/server"
+	"backend/api/symbol"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
@@ -42,6 +45,12 @@ type BuildMapping struct {
 // GetKey constructs a new key with extension for
 // the soon to be uploaded mapping file.
 func (bm BuildMapping) GetKey() string {
+	switch bm.MappingType {
+	case symbol.TypeDsym:
+		return fmt.Sprintf(`%s.dsym`, bm.ID)
+	case symbol.TypeElf:
+		return fmt.Sprintf(`%s.elf`, bm.ID)
+	}
 	return fmt.Sprintf(`%s.txt`, bm.ID)
 }
 
@@ -63,13 +72,51 @@ func (bm BuildMapping) Validate() (code int, err error) {
 		return
 	}
 
+	if !slices.Contains(symbol.ValidMappingTypes, bm.MappingType) {
+		err = fmt.Errorf(`"mapping_type" must be one of %q`, symbol.ValidMappingTypes)
+		return
+	}
+
 	if bm.File.Size < 1 {
 		err = errors.New(`no data in field "mapping_file"`)
+		return
 	}
 
 	if bm.File.Size > int64(server.Server.Config.MappingFileMaxSize) {
 		code = http.StatusRequestEntityTooLarge
 		err = fmt.Errorf(`%q file size exceeding %d bytes`, bm.File.Filename, server.Server.Config.MappingFileMaxSize)
+		return
+	}
+
+	if bm.MappingType == symbol.TypeProguard {
+		return
+	}
+
+	file, openErr := bm.File.Open()
+	if openErr != nil {
+		code = http.StatusInternalServerError
+		err = openErr
+		return
+	}
+	defer file.Close()
+
+	switch bm.MappingType {
+	case symbol.TypeDsym:
+		err = symbol.ValidateDsym(file)
+	case symbol.TypeElf:
+		if err = symbol.ValidateELF(file); err != nil {
+			return
+		}
+
+		ids, parseErr := symbol.ELFBuildIDs(file, bm.File.Size)
+		if parseErr != nil {
+			err = fmt.Errorf(`failed to read ELF file: %w`, parseErr)
+			return
+		}
+
+		if len(ids) == 0 {
+			err = errors.New(`ELF file does not contain any binary with a build id`)
+		}
 	}
 
 	return
@@ -136,20 +183,80 @@ func (bm BuildMapping) insert(ctx context.Context, tx pgx.Tx) error {
 func (bm BuildMapping) upsert(ctx context.Context, tx pgx.Tx) error {
 	stmt := sqlf.PostgreSQL.
 		Update(`public.build_mappings`).
-		Set(`fnv1_hash`, nil).
-		Set(`file_size`, nil).
-		Set(`last_updated`, nil).
-		Where(`id = ?`, nil)
+		Set(`fnv1_hash`, bm.ContentHash).
+		Set(`file_size`, bm.File.Size).
+		Set(`last_updated`, time.Now()).
+		Where(`id = ?`, bm.ID)
 
 	defer stmt.Close()
 
-	if _, err := tx.Exec(ctx, stmt.String(), bm.ContentHash, bm.File.Size, time.Now(), bm.ID); err != nil {
+	// point to the newly uploaded
+	// file if content has changed
+	if bm.Key != "" {
+		stmt.Set(`key`, bm.Key)
+		stmt.Set(`location`, bm.Location)
+	}
+
+	if _, err := tx.Exec(ctx, stmt.String(), stmt.Args()...); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// saveBuildIDs replaces the build ids of the ELF
+// binaries of the mapping file so that native
+// crashes can find their symbol files.
+func (bm BuildMapping) saveBuildIDs(ctx context.Context, tx pgx.Tx) error {
+	if bm.MappingType != symbol.TypeElf {
+		return nil
+	}
+
+	file, err := bm.File.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	ids, err := symbol.ELFBuildIDs(file, bm.File.Size)
+	if err != nil {
+		return err
+	}
+
+	deleteStmt := sqlf.PostgreSQL.
+		DeleteFrom(`public.build_mapping_build_ids`).
+		Where(`mapping_id = ?`, bm.ID)
+
+	defer deleteStmt.Close()
+
+	if _, err := tx.Exec(ctx, deleteStmt.String(), deleteStmt.Args()...); err != nil {
+		return err
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	stmt := sqlf.PostgreSQL.InsertInto(`public.build_mapping_build_ids`)
+	defer stmt.Close()
+
+	var args []any
+	for id, name := range ids {
+		stmt.NewRow().
+			Set(`mapping_id`, nil).
+			Set(`app_id`, nil).
+			Set(`build_id`, nil).
+			Set(`binary_name`, nil).
+			Set(`created_at`, nil)
+		args = append(args, bm.ID, bm.AppID, id, name, now)
+	}
+
+	_, err = tx.Exec(ctx, stmt.String(), args...)
+
+	return err
+}
+
 func (bm *BuildMapping) checksum() error {
 	file, err := bm.File.Open()
 	if err != nil {
@@ -176,6 +283,27 @@ func (bm *BuildMapping) upload() (*s3manager.UploadOutput, error) {
 		return nil, err
 	}
 
+	config := server.Server.Config
+	awsConfig := symbolsAWSConfig()
+
+	if bm.Key == "" {
+		bm.Key = bm.GetKey()
+	}
+
+	metadata := map[string]*string{
+		"original_file_name": aws.String(bm.File.Filename),
+		"app_id":             aws.String(bm.AppID.String()),
+		"version_name":       aws.String(bm.VersionName),
+		"version_code":       aws.String(bm.VersionCode),
+		"mapping_type":       aws.String(bm.MappingType),
+	}
+
+	return uploadToStorage(awsConfig, config.SymbolsBucket, bm.Key, file, metadata)
+}
+
+// symbolsAWSConfig creates the AWS configuration
+// for accessing the symbols bucket.
+func symbolsAWSConfig() *aws.Config {
 	config := server.Server.Config
 	awsConfig := &aws.Config{
 		Region:      aws.String(config.SymbolsBucketRegion),
@@ -190,19 +318,21 @@ func (bm *BuildMapping) upload() (*s3manager.UploadOutput, error) {
 		awsConfig.Endpoint = aws.String(config.AWSEndpoint)
 	}
 
-	if bm.Key == "" {
-		bm.Key = bm.GetKey()
-	}
+	return awsConfig
+}
 
-	metadata := map[string]*string{
-		"original_file_name": aws.String(bm.File.Filename),
-		"app_id":             aws.String(bm.AppID.String()),
-		"version_name":       aws.String(bm.VersionName),
-		"version_code":       aws.String(bm.VersionCode),
-		"mapping_type":       aws.String(bm.MappingType),
-	}
+// fetchMapping downloads the mapping file stored
+// against the key from the symbols bucket.
+func fetchMapping(ctx context.Context, key string, w io.WriterAt) error {
+	awsSession := session.Must(session.NewSession(symbolsAWSConfig()))
+	downloader := s3manager.NewDownloader(awsSession)
 
-	return uploadToStorage(awsConfig, config.SymbolsBucket, bm.Key, file, metadata)
+	_, err := downloader.DownloadWithContext(ctx, w, &s3.GetObjectInput{
+		Bucket: aws.String(server.Server.Config.SymbolsBucket),
+		Key:    aws.String(key),
+	})
+
+	return err
 }
 
 type BuildSize struct {
@@ -243,17 +373,33 @@ func PutBuild(c *gin.Context) {
 		return
 	}
 
+	body, err := decodeBody(c, int64(server.Server.Config.MappingFileMaxSize)+multipartHeadroom)
+	if err != nil {
+		msg := `failed to decode build payload`
+		fmt.Println(msg, err)
+		c.JSON(decodeBodyStatus(err), gin.H{"error": msg, "details": err.Error()})
+		return
+	}
+
 	bs := BuildSize{
 		AppID: appId,
 	}
 
 	if err := c.ShouldBindWith(&bs, binding.FormMultipart); err != nil {
+		if status := decodeBodyStatus(err); status != http.StatusBadRequest {
+			msg := `failed to decode build payload`
+			fmt.Println(msg, err)
+			c.JSON(status, gin.H{"error": msg, "details": err.Error()})
+			return
+		}
 		msg := `build info validation failed. make sure both "build_size" and "build_type" have valid values`
 		fmt.Println(msg, err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
 		return
 	}
 
+	recordBodySize(c, body)
+
 	bm := BuildMapping{
 		ID:    uuid.New(),
 		AppID: appId,
@@ -268,6 +414,7 @@ func PutBuild(c *gin.Context) {
 
 	if code, err := bm.Validate(); err != nil {
 		c.JSON(code, gin.H{"error": err.Error()})
+		return
 	}
 
 	ctx := c.Request.Context()
@@ -338,9 +485,28 @@ func PutBuild(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf(`failed to upload build info: "%s"`, bm.File.Filename)})
 			return
 		}
+		if shouldUpload {
+			if err := bm.saveBuildIDs(ctx, tx); err != nil {
+				fmt.Printf("failed to save build ids of mapping file, key: %s with error, %v\n", bm.Key, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf(`failed to upload build info: "%s"`, bm.File.Filename)})
+				return
+			}
+			if err := bm.enqueueSymbolication(ctx, tx); err != nil {
+				fmt.Printf("failed to queue symbolication of mapping file, key: %s with error, %v\n", bm.Key, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": fmt.Sprintf(`failed to upload build info: "%s"`, bm.File.Filename)})
+				return
+			}
+		}
+		if err := tx.Commit(ctx); err != nil {
+			msg := `failed to upload build info`
+			fmt.Println(msg, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+			return
+		}
 		msg := `existing build info is already up to date`
 		if shouldUpload {
 			msg = `uploaded build info`
+			notifySymbolication()
 		}
 		c.JSON(http.StatusOK, gin.H{"ok": msg})
 		return
@@ -354,6 +520,24 @@ func PutBuild(c *gin.Context) {
 		return
 	}
 
+	if err := bm.saveBuildIDs(ctx, tx); err != nil {
+		fmt.Printf("failed to save build ids of mapping file, key: %s with error, %v\n", bm.Key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf(`failed to upload mapping file: "%s"`, bm.File.Filename),
+		})
+		return
+	}
+
+	// events that arrived before the mapping
+	// file are symbolicated in the background
+	if err := bm.enqueueSymbolication(ctx, tx); err != nil {
+		fmt.Printf("failed to queue symbolication of mapping file, key: %s with error, %v\n", bm.Key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf(`failed to upload mapping file: "%s"`, bm.File.Filename),
+		})
+		return
+	}
+
 	if err := bs.Upsert(ctx, tx); err != nil {
 		msg := `failed to register app build size`
 		fmt.Println(msg, err)
@@ -368,6 +552,8 @@ func PutBuild(c *gin.Context) {
 		return
 	}
 
+	notifySymbolication()
+
 	c.JSON(http.StatusOK, gin.H{
 		"ok": `uploaded build info`,
 	})
@@ -0,0 +1,343 @@
+package measure
+
+import (
+	"backend/api/authsession"
+	"backend/api/cipher"
+	"backend/api/server"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// oidcProviderName is the name of the generic
+// OpenID Connect provider stored in auth states
+// and sessions.
+const oidcProviderName = "oidc"
+
+// errOIDCNotConfigured is returned when
+// oidc sign in is not configured.
+var errOIDCNotConfigured = errors.New("oidc sign in is not configured")
+
+var (
+	// oidcProvider is the lazily
+	// initialized oidc provider.
+	oidcProvider *authsession.OIDCProvider
+
+	// oidcProviderMu guards
+	// oidcProvider.
+	oidcProviderMu sync.Mutex
+)
+
+// getOIDCProvider returns the oidc provider, initializing
+// it on first use. Initialization is retried on the next
+// call if the issuer could not be reached.
+func getOIDCProvider(ctx context.Context) (*authsession.OIDCProvider, error) {
+	config := server.Server.Config
+
+	if config.OIDCDiscoveryURL == "" || config.OIDCClientID == "" {
+		return nil, errOIDCNotConfigured
+	}
+
+	oidcProviderMu.Lock()
+	defer oidcProviderMu.Unlock()
+
+	if oidcProvider != nil {
+		return oidcProvider, nil
+	}
+
+	provider, err := authsession.NewOIDCProvider(ctx, authsession.OIDCConfig{
+		DiscoveryURL: config.OIDCDiscoveryURL,
+		ClientID:     config.OIDCClientID,
+		ClientSecret: config.OIDCClientSecret,
+		RedirectURI:  fmt.Sprintf("%s/auth/callback/oidc", config.SiteOrigin),
+		Scopes:       config.OIDCScopes,
+		EmailClaim:   config.OIDCEmailClaim,
+		NameClaim:    config.OIDCNameClaim,
+		TrustEmail:   config.OIDCTrustEmail,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	oidcProvider = provider
+
+	return oidcProvider, nil
+}
+
+// emailDomainAllowed reports if the email's
+// domain is one of the domains.
+func emailDomainAllowed(email string, domains []string) bool {
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+
+	domain := strings.ToLower(email[at+1:])
+
+	return slices.Contains(domains, domain)
+}
+
+// autoJoinTeam makes the user a member of the configured
+// team with the configured role, if the user's email domain
+// is allowed to auto join & the user is not a member yet.
+func autoJoinTeam(ctx context.Context, u *User) (err error) {
+	config := server.Server.Config
+
+	if config.OIDCAutoJoinTeamID == "" || len(config.OIDCAutoJoinDomains) == 0 {
+		return
+	}
+
+	if u.Email == nil || !emailDomainAllowed(*u.Email, config.OIDCAutoJoinDomains) {
+		return
+	}
+
+	teamId, err := uuid.Parse(config.OIDCAutoJoinTeamID)
+	if err != nil {
+		return fmt.Errorf("invalid auto join team id %q: %w", config.OIDCAutoJoinTeamID, err)
+	}
+
+	role, ok := roleMap[config.OIDCAutoJoinRole]
+	if !ok || role == owner {
+		return fmt.Errorf("invalid auto join role %q", config.OIDCAutoJoinRole)
+	}
+
+	existing, err := u.getRole(teamId.String())
+	if err != nil {
+		return
+	}
+
+	if existing != unknown {
+		return
+	}
+
+	team := &Team{ID: &teamId}
+
+	return team.addMember(ctx, uuid.MustParse(*u.ID), role, nil)
+}
+
+// SigninOIDC signs in a user using the configured
+// generic OpenID Connect provider.
+//
+// The "init" step records the state & responds with
+// the provider's authorization url. The "code" step
+// exchanges the authorization code, verifies the id
+// token & creates an auth session.
+func SigninOIDC(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	type AuthCode struct {
+		Type  string `json:"type" binding:"required"`
+		State string `json:"state" binding:"required"`
+		Code  string `json:"code"`
+	}
+
+	authCode := AuthCode{}
+
+	msg := "failed to parse oidc auth payload"
+	if err := c.ShouldBindJSON(&authCode); err != nil {
+		fmt.Println(msg, err.Error())
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	provider, err := getOIDCProvider(ctx)
+	if err != nil {
+		if errors.Is(err, errOIDCNotConfigured) {
+			c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		msg := "failed to reach oidc provider"
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	// the nonce is derived from the state, so
+	// that it need not be stored separately
+	nonce, err := cipher.ComputeSHA2Hash([]byte(authCode.State))
+	if err != nil {
+		msg := "failed to authenticate via oidc"
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	switch authCode.Type {
+	case "init":
+		authState := authsession.AuthState{
+			OAuthProvider: oidcProviderName,
+			State:         authCode.State,
+		}
+
+		if err := authState.Save(ctx); err != nil {
+			msg := "failed to authenticate via oidc"
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":   msg,
+				"details": err.Error(),
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"ok":  "oidc init ack",
+			"url": provider.AuthURL(authCode.State, *nonce),
+		})
+		return
+	case "code":
+		if authCode.Code == "" {
+			fmt.Println(msg)
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": msg,
+			})
+			return
+		}
+
+		msg := "failed to authenticate via oidc"
+
+		authState, err := authsession.GetOAuthState(ctx, authCode.State, oidcProviderName)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error":   msg,
+				"details": err.Error(),
+			})
+			return
+		}
+
+		if authState.State != authCode.State {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": msg,
+			})
+			return
+		}
+
+		token, err := provider.Exchange(ctx, authCode.Code)
+		if err != nil {
+			fmt.Println(msg, err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   msg,
+				"details": err.Error(),
+			})
+			return
+		}
+
+		if err := authsession.RemoveOAuthState(ctx, authState.State, oidcProviderName); err != nil {
+			fmt.Printf("failed to remove oidc state: %q\n", authState.State)
+		}
+
+		claims, err := provider.VerifyIDToken(ctx, token.IDToken, *nonce)
+		if err != nil {
+			fmt.Println(msg, err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   msg,
+				"details": err.Error(),
+			})
+			return
+		}
+
+		oidcUser, err := provider.User(claims)
+		if err != nil {
+			fmt.Println(msg, err)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error":   msg,
+				"details": err.Error(),
+			})
+			return
+		}
+
+		userMeta, err := json.Marshal(oidcUser)
+		if err != nil {
+			fmt.Println(msg, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": msg,
+			})
+			return
+		}
+
+		msrUser, created, err := findOrCreateUser(ctx, oidcUser.Name, oidcUser.Email)
+		if err != nil {
+			fmt.Println(msg, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": msg,
+			})
+			return
+		}
+
+		if created {
+			trackEmail(oidcUser.Email)
+		}
+
+		// failing to auto join should not
+		// prevent the user from signing in
+		if err := autoJoinTeam(ctx, msrUser); err != nil {
+			fmt.Println("failed to auto join team", err)
+		}
+
+		userId := uuid.MustParse(*msrUser.ID)
+
+		team, err := msrUser.getOwnTeam(ctx)
+		if err != nil {
+			msg := "failed to lookup user's team"
+			fmt.Println(msg, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": msg,
+			})
+			return
+		}
+
+		authSess, err := authsession.NewAuthSession(userId, *team.ID, oidcProviderName, userMeta)
+		if err != nil {
+			fmt.Println(msg, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": msg,
+			})
+			return
+		}
+
+		if err = authSess.Save(ctx, nil); err != nil {
+			fmt.Println(msg, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": msg,
+			})
+			return
+		}
+
+		acceptInvitesOnSignin(c, msrUser, oidcProviderName)
+		recordSignin(c, userId, oidcProviderName)
+
+		c.JSON(http.StatusOK, gin.H{
+			"access_token":  authSess.AccessToken,
+			"refresh_token": authSess.RefreshToken,
+			"state":         authCode.State,
+		})
+
+		// deliberately ignore the error, because
+		// expired sessions may get cleared eventually
+		authsession.Cleanup(ctx)
+
+		return
+	default:
+		fmt.Println(msg)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error": msg,
+		})
+		return
+	}
+}
@@ -0,0 +1,30 @@
+package measure
+
+import "testing"
+
+func TestEmailDomainAllowed(t *testing.T) {
+	domains := []string{"example.com", "acme.io"}
+
+	cases := []struct {
+		email    string
+		expected bool
+	}{
+		{"ada@example.com", true},
+		{"ada@EXAMPLE.com", true},
+		{"ada@acme.io", true},
+		{"ada@sub.example.com", false},
+		{"ada@example.com.evil.io", false},
+		{"example.com", false},
+		{"", false},
+	}
+
+	for _, c := range cases {
+		if got := emailDomainAllowed(c.email, domains); got != c.expected {
+			t.Errorf("%q: expected %v, but got %v", c.email, c.expected, got)
+		}
+	}
+
+	if emailDomainAllowed("ada@example.com", nil) {
+		t.Error("Expected no domain to be allowed, but got true")
+	}
+}
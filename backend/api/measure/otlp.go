@@ -0,0 +1,255 @@
+package measure
+
+import (
+	"backend/api/codec"
+	"backend/api/otlp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+// readOTLP reads the body of an OTLP/HTTP request
+// & provides its media type. Gzip & zstd compressed
+// bodies are decompressed. Bodies larger than the
+// maximum batch size are rejected, before & after
+// decompression.
+func readOTLP(c *gin.Context) (mediaType string, data []byte, body *codec.Body, err error) {
+	mediaType, err = otlp.MediaType(c.ContentType())
+	if err != nil {
+		return
+	}
+
+	body, err = decodeBody(c, int64(maxBatchSize))
+	if err != nil {
+		return
+	}
+
+	data, err = io.ReadAll(body)
+	if err != nil {
+		return
+	}
+
+	recordBodySize(c, body)
+
+	return
+}
+
+// readOTLPStatus provides the response status
+// for errors of reading an OTLP request.
+func readOTLPStatus(err error) int {
+	if errors.Is(err, otlp.ErrUnsupportedContentType) {
+		return http.StatusUnsupportedMediaType
+	}
+
+	return decodeBodyStatus(err)
+}
+
+// writeOTLP writes the OTLP export response in
+// the media type of the request.
+func writeOTLP(c *gin.Context, mediaType string, res proto.Message) {
+	data, err := otlp.Encode(mediaType, res)
+	if err != nil {
+		msg := `failed to encode otlp response`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	c.Data(http.StatusOK, mediaType, data)
+}
+
+// ingestOTLP ingests the events converted from an
+// OTLP export request. The request id is derived from
+// the payload, so that exporters retrying the same
+// batch don't ingest it twice. Writes an error
+// response & returns false on failure.
+func ingestOTLP(ctx context.Context, c *gin.Context, appId uuid.UUID, data []byte, body *codec.Body, result otlp.Result) bool {
+	app, err := SelectApp(ctx, appId)
+	if app == nil || err != nil {
+		msg := `failed to lookup app`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return false
+	}
+
+	eventReq := eventreq{
+		id:          uuid.NewSHA1(appId, data),
+		appId:       appId,
+		symbolicate: make(map[uuid.UUID]int),
+		attachments: make(map[uuid.UUID]*attachment),
+		wireSize:    body.WireSize(),
+		encoding:    body.Encoding,
+	}
+
+	if seen, err := eventReq.seen(ctx); err != nil {
+		msg := `failed to check existing event request`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return false
+	} else if seen {
+		return true
+	}
+
+	if len(result.Events) < 1 {
+		return true
+	}
+
+	eventReq.bumpSize(int64(len(data)))
+	for i := range result.Events {
+		eventReq.push(result.Events[i])
+	}
+
+	if err := eventReq.validate(); err != nil {
+		msg := `failed to validate events payload`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return false
+	}
+
+	if err := eventReq.infuseInet(c.ClientIP()); err != nil {
+		msg := fmt.Sprintf(`failed to lookup country info for IP: %q`, c.ClientIP())
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return false
+	}
+
+	if _, err := eventReq.enqueue(ctx, app.TeamId); err != nil {
+		var quotaErr quotaExceededError
+		if errors.As(err, &quotaErr) {
+			abortTooManyAttempts(c, quotaErr.Error(), quotaRetryAfter(time.Now()))
+			return false
+		}
+
+		msg := `failed to enqueue event request`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return false
+	}
+
+	return true
+}
+
+// PostOTLPLogs ingests log records exported by
+// OpenTelemetry SDKs or collectors over OTLP/HTTP.
+func PostOTLPLogs(c *gin.Context) {
+	appId, err := uuid.Parse(c.GetString("appId"))
+	if err != nil {
+		msg := `error parsing app's uuid`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	mediaType, data, body, err := readOTLP(c)
+	if err != nil {
+		msg := `failed to read otlp logs payload`
+		fmt.Println(msg, err)
+		c.JSON(readOTLPStatus(err), gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	req, err := otlp.DecodeLogs(mediaType, data)
+	if err != nil {
+		msg := `failed to parse otlp logs payload`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result := otlp.ConvertLogs(appId, req)
+
+	if !ingestOTLP(ctx, c, appId, data, body, result) {
+		return
+	}
+
+	res := &collogspb.ExportLogsServiceResponse{}
+	if result.Rejected > 0 {
+		res.PartialSuccess = &collogspb.ExportLogsPartialSuccess{
+			RejectedLogRecords: result.Rejected,
+			ErrorMessage:       result.ErrorMessage(),
+		}
+	}
+
+	writeOTLP(c, mediaType, res)
+}
+
+// PostOTLPTraces ingests spans exported by
+// OpenTelemetry SDKs or collectors over OTLP/HTTP.
+func PostOTLPTraces(c *gin.Context) {
+	appId, err := uuid.Parse(c.GetString("appId"))
+	if err != nil {
+		msg := `error parsing app's uuid`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	mediaType, data, body, err := readOTLP(c)
+	if err != nil {
+		msg := `failed to read otlp traces payload`
+		fmt.Println(msg, err)
+		c.JSON(readOTLPStatus(err), gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	req, err := otlp.DecodeTraces(mediaType, data)
+	if err != nil {
+		msg := `failed to parse otlp traces payload`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	result := otlp.ConvertTraces(appId, req)
+
+	if !ingestOTLP(ctx, c, appId, data, body, result) {
+		return
+	}
+
+	res := &coltracepb.ExportTraceServiceResponse{}
+	if result.Rejected > 0 {
+		res.PartialSuccess = &coltracepb.ExportTracePartialSuccess{
+			RejectedSpans: result.Rejected,
+			ErrorMessage:  result.ErrorMessage(),
+		}
+	}
+
+	writeOTLP(c, mediaType, res)
+}
@@ -0,0 +1,217 @@
+package measure
+
+import (
+	"backend/api/otlp"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// newOTLPRouter creates a router serving the OTLP
+// routes. The app id is set in place of validating
+// an api key when set.
+func newOTLPRouter(appId *uuid.UUID) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+
+	auth := ValidateAPIKey()
+	if appId != nil {
+		auth = func(c *gin.Context) {
+			c.Set("appId", appId.String())
+			c.Next()
+		}
+	}
+
+	r.POST("/v1/logs", auth, PostOTLPLogs)
+	r.POST("/v1/traces", auth, PostOTLPTraces)
+
+	return r
+}
+
+func TestOTLPUnauthorized(t *testing.T) {
+	r := newOTLPRouter(nil)
+
+	cases := map[string]string{
+		"missing api key":   "",
+		"malformed api key": "Bearer msrsh_key",
+		"invalid checksum":  "Bearer msrsh_key_checksum",
+	}
+
+	for name, authorization := range cases {
+		for _, path := range []string{"/v1/logs", "/v1/traces"} {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`))
+			req.Header.Set("Content-Type", otlp.ContentTypeJSON)
+			if authorization != "" {
+				req.Header.Set("Authorization", authorization)
+			}
+			r.ServeHTTP(w, req)
+
+			if w.Code != http.StatusUnauthorized {
+				t.Errorf("%s %s: Expected status %d, but got %d", name, path, http.StatusUnauthorized, w.Code)
+			}
+		}
+	}
+}
+
+func TestOTLPMissingAppId(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/v1/logs", PostOTLPLogs)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/logs", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", otlp.ContentTypeJSON)
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Errorf("Expected status %d, but got %d", http.StatusInternalServerError, w.Code)
+	}
+}
+
+func TestOTLPBadRequest(t *testing.T) {
+	appId := uuid.New()
+	r := newOTLPRouter(&appId)
+
+	size := maxBatchSize
+	maxBatchSize = 1024
+	defer func() { maxBatchSize = size }()
+
+	cases := map[string]struct {
+		path        string
+		contentType string
+		encoding    string
+		body        []byte
+		status      int
+		error       string
+	}{
+		"unsupported content type": {
+			path:        "/v1/logs",
+			contentType: "text/plain",
+			body:        []byte(`{}`),
+			status:      http.StatusUnsupportedMediaType,
+			error:       "failed to read otlp logs payload",
+		},
+		"missing content type": {
+			path:   "/v1/traces",
+			body:   []byte(`{}`),
+			status: http.StatusUnsupportedMediaType,
+			error:  "failed to read otlp traces payload",
+		},
+		"unsupported content encoding": {
+			path:        "/v1/traces",
+			contentType: otlp.ContentTypeProtobuf,
+			encoding:    "br",
+			body:        []byte{},
+			status:      http.StatusUnsupportedMediaType,
+			error:       "failed to read otlp traces payload",
+		},
+		"corrupt gzip body": {
+			path:        "/v1/logs",
+			contentType: otlp.ContentTypeProtobuf,
+			encoding:    "gzip",
+			body:        []byte("not gzip"),
+			status:      http.StatusBadRequest,
+			error:       "failed to read otlp logs payload",
+		},
+		"body too large": {
+			path:        "/v1/logs",
+			contentType: otlp.ContentTypeJSON,
+			body:        bytes.Repeat([]byte(" "), 2048),
+			status:      http.StatusRequestEntityTooLarge,
+			error:       "failed to read otlp logs payload",
+		},
+		"malformed json logs": {
+			path:        "/v1/logs",
+			contentType: otlp.ContentTypeJSON,
+			body:        []byte(`{"resourceLogs":`),
+			status:      http.StatusBadRequest,
+			error:       "failed to parse otlp logs payload",
+		},
+		"malformed protobuf traces": {
+			path:        "/v1/traces",
+			contentType: otlp.ContentTypeProtobuf,
+			body:        []byte{0xff, 0xff, 0xff},
+			status:      http.StatusBadRequest,
+			error:       "failed to parse otlp traces payload",
+		},
+	}
+
+	for name, tc := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, tc.path, bytes.NewReader(tc.body))
+		if tc.contentType != "" {
+			req.Header.Set("Content-Type", tc.contentType)
+		}
+		if tc.encoding != "" {
+			req.Header.Set("Content-Encoding", tc.encoding)
+		}
+		r.ServeHTTP(w, req)
+
+		if w.Code != tc.status {
+			t.Errorf("%s: Expected status %d, but got %d", name, tc.status, w.Code)
+		}
+
+		var body map[string]string
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: Expected nil error, but got %v", name, err)
+		}
+
+		if body["error"] != tc.error {
+			t.Errorf("%s: Expected error %q, but got %q", name, tc.error, body["error"])
+		}
+
+		if body["details"] == "" {
+			t.Errorf("%s: Expected error details, but got none", name)
+		}
+	}
+}
+
+func TestWriteOTLP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	res := &collogspb.ExportLogsServiceResponse{
+		PartialSuccess: &collogspb.ExportLogsPartialSuccess{
+			RejectedLogRecords: 2,
+			ErrorMessage:       "rejected",
+		},
+	}
+
+	for _, mediaType := range []string{otlp.ContentTypeJSON, otlp.ContentTypeProtobuf} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+
+		writeOTLP(c, mediaType, res)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("%s: Expected status %d, but got %d", mediaType, http.StatusOK, w.Code)
+		}
+
+		if got := w.Header().Get("Content-Type"); got != mediaType {
+			t.Errorf("%s: Expected content type %q, but got %q", mediaType, mediaType, got)
+		}
+
+		decoded := &collogspb.ExportLogsServiceResponse{}
+		unmarshal := proto.Unmarshal
+		if mediaType == otlp.ContentTypeJSON {
+			unmarshal = protojson.Unmarshal
+		}
+
+		if err := unmarshal(w.Body.Bytes(), decoded); err != nil {
+			t.Fatalf("%s: Expected nil error, but got %v", mediaType, err)
+		}
+
+		if decoded.GetPartialSuccess().GetRejectedLogRecords() != 2 {
+			t.Errorf("%s: Expected %d rejected log records, but got %d", mediaType, 2, decoded.GetPartialSuccess().GetRejectedLogRecords())
+		}
+	}
+}
@@ -0,0 +1,859 @@
+package measure
+
+import (
+	"backend/api/authsession"
+	"backend/api/server"
+	"backend/email"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// emailSendTimeout is the maximum time
+// allowed for sending an email.
+const emailSendTimeout = 30 * time.Second
+
+// maxUserNameChars is the maximum number of
+// characters in a user's name. Matches the
+// users table's name column.
+const maxUserNameChars = 256
+
+// newEmailSender creates the sender used for
+// sending credential & invite emails.
+var newEmailSender = func() (email.Sender, error) {
+	config := server.Server.Config.SMTP
+	return email.NewSMTPSender(&email.Options{
+		Host:     config.Host,
+		Port:     config.Port,
+		Username: config.Username,
+		Password: config.Password,
+		From:     config.From,
+	})
+}
+
+// smtpConfigured reports if credential emails can be
+// sent. Users signing up with a password must verify
+// their email only when an SMTP server is configured,
+// since air-gapped installations may not have one.
+func smtpConfigured() bool {
+	config := server.Server.Config.SMTP
+	return config.Host != "" && config.From != ""
+}
+
+// normalizeEmail validates & normalizes
+// an email address.
+func normalizeEmail(address string) (string, error) {
+	parsed, err := mail.ParseAddress(strings.TrimSpace(address))
+	if err != nil || parsed.Name != "" {
+		return "", errors.New("email is invalid")
+	}
+
+	return strings.ToLower(parsed.Address), nil
+}
+
+// credentialLink builds a dashboard link
+// carrying a one time token.
+func credentialLink(origin, path string, query url.Values) string {
+	return fmt.Sprintf("%s%s?%s", origin, path, query.Encode())
+}
+
+// credentialMessage builds the email message for
+// a one time token, linking to the dashboard at
+// origin.
+func credentialMessage(origin string, u *User, token *authsession.OneTimeToken) (msg email.Message) {
+	msg.To = []string{*u.Email}
+	expiry := humanizeExpiry(token.ExpiresAt.Sub(token.CreatedAt))
+
+	switch token.Purpose {
+	case authsession.VerifyEmail:
+		link := credentialLink(origin, "/auth/callback/email", url.Values{"type": {"verify"}, "token": {token.Value}})
+		msg.Subject = "Verify your email for Measure"
+		msg.Body = fmt.Sprintf("Hi %s,\n\nConfirm your email to finish signing up for Measure:\n\n%s\n\nThis link expires in %s. If you didn't sign up, you can ignore this email.\n", u.firstName(), link, expiry)
+	case authsession.ResetPassword:
+		link := credentialLink(origin, "/auth/reset-password", url.Values{"token": {token.Value}})
+		msg.Subject = "Reset your Measure password"
+		msg.Body = fmt.Sprintf("Hi %s,\n\nSet a new password for your Measure account:\n\n%s\n\nThis link expires in %s. If you didn't ask to reset your password, you can ignore this email.\n", u.firstName(), link, expiry)
+	case authsession.MagicLink:
+		link := credentialLink(origin, "/auth/callback/email", url.Values{"type": {"magic"}, "token": {token.Value}})
+		msg.Subject = "Sign in to Measure"
+		msg.Body = fmt.Sprintf("Hi %s,\n\nSign in to Measure using this link:\n\n%s\n\nThis link expires in %s and can be used only once. If you didn't ask to sign in, you can ignore this email.\n", u.firstName(), link, expiry)
+	}
+
+	return
+}
+
+// humanizeExpiry formats an expiry duration
+// like "7 days", "24 hours" or "15 minutes".
+func humanizeExpiry(d time.Duration) string {
+	const day = 24 * time.Hour
+
+	unit, n := "minute", int(d.Round(time.Minute)/time.Minute)
+	if d > day && d%day == 0 {
+		unit, n = "day", int(d/day)
+	} else if d >= time.Hour && d%time.Hour == 0 {
+		unit, n = "hour", int(d/time.Hour)
+	}
+
+	if n != 1 {
+		unit += "s"
+	}
+
+	return fmt.Sprintf("%d %s", n, unit)
+}
+
+// sendCredentialEmail creates a one time token of the
+// purpose for the user & emails it in the background.
+func sendCredentialEmail(ctx context.Context, u *User, purpose authsession.TokenPurpose) (err error) {
+	sender, err := newEmailSender()
+	if err != nil {
+		return
+	}
+
+	token, err := authsession.NewOneTimeToken(uuid.MustParse(*u.ID), purpose)
+	if err != nil {
+		return
+	}
+
+	if err = token.Save(ctx); err != nil {
+		return
+	}
+
+	sendEmail(sender, credentialMessage(server.Server.Config.SiteOrigin, u, token), string(purpose))
+
+	return
+}
+
+// sendEmail sends the message in the background.
+// Failures are only logged, since the request
+// has been responded to by then.
+func sendEmail(sender email.Sender, msg email.Message, kind string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), emailSendTimeout)
+		defer cancel()
+
+		if err := sender.Send(ctx, msg); err != nil {
+			fmt.Printf("failed to send %s email: %v\n", kind, err)
+		}
+	}()
+}
+
+// abortTooManyAttempts responds with a 429 along
+// with a hint on when to retry.
+func abortTooManyAttempts(c *gin.Context, msg string, wait time.Duration) {
+	seconds := int(math.Ceil(wait.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(seconds))
+	c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+		"error":       msg,
+		"retry_after": seconds,
+	})
+}
+
+// allowEmail checks & records an email send attempt,
+// responding with a 429 when rate limited. Reports
+// whether the caller may proceed.
+func allowEmail(c *gin.Context, address string) bool {
+	ctx := c.Request.Context()
+
+	wait, err := authsession.CheckAttempts(ctx, authsession.EmailSent, address, c.ClientIP())
+	if err != nil {
+		msg := "failed to check email attempts"
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return false
+	}
+
+	if wait > 0 {
+		abortTooManyAttempts(c, "too many emails requested, try again later", wait)
+		return false
+	}
+
+	if err := authsession.RecordAttempt(ctx, authsession.EmailSent, address, c.ClientIP()); err != nil {
+		msg := "failed to record email attempt"
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return false
+	}
+
+	return true
+}
+
+// startSession creates a new auth session for the
+// user & responds with the session's tokens.
+func startSession(c *gin.Context, u *User, provider string) {
+	ctx := c.Request.Context()
+	msg := "failed to sign in"
+
+	userMeta, err := json.Marshal(map[string]string{
+		"name":  *u.Name,
+		"email": *u.Email,
+	})
+	if err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	team, err := u.getOwnTeam(ctx)
+	if err != nil {
+		msg := "failed to lookup user's team"
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	authSess, err := authsession.NewAuthSession(uuid.MustParse(*u.ID), *team.ID, provider, userMeta)
+	if err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	if err := authSess.Save(ctx, nil); err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	acceptInvitesOnSignin(c, u, provider)
+	recordSignin(c, authSess.UserID, provider)
+
+	c.JSON(http.StatusOK, gin.H{
+		"access_token":  authSess.AccessToken,
+		"refresh_token": authSess.RefreshToken,
+	})
+
+	// deliberately ignore the error, because
+	// expired sessions may get cleared eventually
+	authsession.Cleanup(ctx)
+}
+
+// requirePasswordSignin aborts with a 404 when
+// password sign in is not enabled.
+func requirePasswordSignin(c *gin.Context) bool {
+	if !server.Server.Config.PasswordSignin {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"error": "password sign in is not enabled",
+		})
+		return false
+	}
+
+	return true
+}
+
+// requireMagicLinkSignin aborts with a 404 when
+// magic link sign in is not enabled.
+func requireMagicLinkSignin(c *gin.Context) bool {
+	if !server.Server.Config.MagicLinkSignin || !smtpConfigured() {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"error": "magic link sign in is not enabled",
+		})
+		return false
+	}
+
+	return true
+}
+
+// SignupPassword signs up a new user with an email
+// & password.
+//
+// To avoid revealing which emails have an account,
+// signing up with an existing email responds the
+// same & emails a password reset link instead.
+func SignupPassword(c *gin.Context) {
+	if !requirePasswordSignin(c) {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	var payload struct {
+		Name     string `json:"name" binding:"required"`
+		Email    string `json:"email" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+
+	msg := "failed to parse sign up payload"
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	address, err := normalizeEmail(payload.Email)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	name := strings.TrimSpace(payload.Name)
+	if name == "" || utf8.RuneCountInString(name) > maxUserNameChars {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error":   msg,
+			"details": fmt.Sprintf("name must be between 1 and %d characters", maxUserNameChars),
+		})
+		return
+	}
+
+	hash, err := authsession.HashPassword(payload.Password)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	msg = "failed to sign up"
+
+	existing, err := FindUserByEmail(ctx, address)
+	if err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	verify := smtpConfigured()
+
+	if verify && !allowEmail(c, address) {
+		return
+	}
+
+	if existing != nil {
+		if !verify {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"error": "an account with this email already exists",
+			})
+			return
+		}
+
+		if err := sendCredentialEmail(ctx, existing, authsession.ResetPassword); err != nil {
+			fmt.Println(msg, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": msg,
+			})
+			return
+		}
+
+		c.JSON(http.StatusAccepted, gin.H{
+			"ok": "check your email to continue",
+		})
+		return
+	}
+
+	user := NewUser(name, address)
+
+	if verify {
+		user.ConfirmedAt = nil
+	}
+
+	tx, err := server.Server.PgPool.Begin(ctx)
+	if err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err := user.create(ctx, &tx); err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	if err := user.setPassword(ctx, hash, &tx); err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	if !verify {
+		startSession(c, user, authsession.PasswordProvider)
+		return
+	}
+
+	if err := sendCredentialEmail(ctx, user, authsession.VerifyEmail); err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"ok": "check your email to continue",
+	})
+}
+
+// SigninPassword signs in a user with their
+// email & password.
+func SigninPassword(c *gin.Context) {
+	if !requirePasswordSignin(c) {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	var payload struct {
+		Email    string `json:"email" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+
+	msg := "failed to parse sign in payload"
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	msg = "failed to sign in"
+	address := strings.ToLower(strings.TrimSpace(payload.Email))
+
+	wait, err := authsession.CheckAttempts(ctx, authsession.FailedSignin, address, c.ClientIP())
+	if err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	if wait > 0 {
+		abortTooManyAttempts(c, "too many failed sign in attempts, try again later", wait)
+		return
+	}
+
+	user, hash, err := findUserCredentials(ctx, address)
+	if err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	if !authsession.CheckPassword(hash, payload.Password) {
+		if err := authsession.RecordAttempt(ctx, authsession.FailedSignin, address, c.ClientIP()); err != nil {
+			fmt.Println("failed to record sign in attempt", err)
+		}
+
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+			"error": "invalid email or password",
+		})
+		return
+	}
+
+	if user.ConfirmedAt == nil {
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error": "email is not verified, check your email for the verification link",
+		})
+		return
+	}
+
+	if err := authsession.ClearAttempts(ctx, authsession.FailedSignin, address); err != nil {
+		fmt.Println("failed to clear sign in attempts", err)
+	}
+
+	if err := user.touchLastSignInAt(ctx); err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	startSession(c, user, authsession.PasswordProvider)
+}
+
+// bindToken parses a payload carrying a one
+// time token. Reports whether parsing succeeded.
+func bindToken(c *gin.Context, payload any) bool {
+	msg := "failed to parse token payload"
+	if err := c.ShouldBindJSON(payload); err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return false
+	}
+
+	return true
+}
+
+// consumeToken consumes the one time token & returns
+// its user. Responds with a 401 if the token is
+// unknown, expired or already used.
+func consumeToken(c *gin.Context, purpose authsession.TokenPurpose, token string) (user *User, ok bool) {
+	ctx := c.Request.Context()
+
+	userId, err := authsession.ConsumeOneTimeToken(ctx, token, purpose)
+	if err != nil {
+		if errors.Is(err, authsession.ErrInvalidOneTimeToken) {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+
+		msg := "failed to verify token"
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	user, err = getUser(ctx, userId)
+	if err != nil {
+		msg := "failed to lookup user"
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	ok = true
+
+	return
+}
+
+// VerifyEmail confirms a signed up user's email
+// & signs them in.
+func VerifyEmail(c *gin.Context) {
+	if !requirePasswordSignin(c) {
+		return
+	}
+
+	var payload struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if !bindToken(c, &payload) {
+		return
+	}
+
+	user, ok := consumeToken(c, authsession.VerifyEmail, payload.Token)
+	if !ok {
+		return
+	}
+
+	if err := user.confirm(c.Request.Context(), nil); err != nil {
+		msg := "failed to verify email"
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	startSession(c, user, authsession.PasswordProvider)
+}
+
+// ForgotPassword emails a password reset link, if an
+// account exists for the email. Responds the same either
+// way, to avoid revealing which emails have an account.
+func ForgotPassword(c *gin.Context) {
+	if !requirePasswordSignin(c) {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	var payload struct {
+		Email string `json:"email" binding:"required"`
+	}
+
+	msg := "failed to parse forgot password payload"
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	if !smtpConfigured() {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{
+			"error": "password reset requires an smtp server to be configured",
+		})
+		return
+	}
+
+	address := strings.ToLower(strings.TrimSpace(payload.Email))
+
+	if !allowEmail(c, address) {
+		return
+	}
+
+	msg = "failed to send password reset email"
+
+	user, err := FindUserByEmail(ctx, address)
+	if err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	if user != nil {
+		if err := sendCredentialEmail(ctx, user, authsession.ResetPassword); err != nil {
+			fmt.Println(msg, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": msg,
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"ok": "if an account exists for this email, a password reset link has been sent",
+	})
+}
+
+// ResetPassword sets a new password using a password
+// reset token, signs the user out of all existing
+// sessions & signs them in.
+func ResetPassword(c *gin.Context) {
+	if !requirePasswordSignin(c) {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	var payload struct {
+		Token    string `json:"token" binding:"required"`
+		Password string `json:"password" binding:"required"`
+	}
+
+	if !bindToken(c, &payload) {
+		return
+	}
+
+	msg := "failed to reset password"
+
+	// validate the new password before consuming
+	// the token, so that it can be retried
+	hash, err := authsession.HashPassword(payload.Password)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	user, ok := consumeToken(c, authsession.ResetPassword, payload.Token)
+	if !ok {
+		return
+	}
+
+	tx, err := server.Server.PgPool.Begin(ctx)
+	if err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err := resetPassword(ctx, user, hash, &tx); err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	if err := authsession.ClearAttempts(ctx, authsession.FailedSignin, *user.Email); err != nil {
+		fmt.Println("failed to clear sign in attempts", err)
+	}
+
+	userId := uuid.MustParse(*user.ID)
+
+	newAuditLog(c, auditUserPasswordReset).
+		by(userId).
+		on(auditTargetUser, userId.String()).
+		record(ctx)
+
+	startSession(c, user, authsession.PasswordProvider)
+}
+
+// resetPassword sets the user's new password, confirms
+// their email & removes all their sessions.
+func resetPassword(ctx context.Context, u *User, hash string, tx *pgx.Tx) (err error) {
+	if err = u.setPassword(ctx, hash, tx); err != nil {
+		return
+	}
+
+	// the reset link was delivered to the user's
+	// email, which proves they own it
+	if err = u.confirm(ctx, tx); err != nil {
+		return
+	}
+
+	return authsession.RemoveUserSessions(ctx, uuid.MustParse(*u.ID), tx)
+}
+
+// SendMagicLink emails a sign in link, if an account
+// exists for the email. Responds the same either way,
+// to avoid revealing which emails have an account.
+func SendMagicLink(c *gin.Context) {
+	if !requireMagicLinkSignin(c) {
+		return
+	}
+
+	ctx := c.Request.Context()
+
+	var payload struct {
+		Email string `json:"email" binding:"required"`
+	}
+
+	msg := "failed to parse magic link payload"
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	address := strings.ToLower(strings.TrimSpace(payload.Email))
+
+	if !allowEmail(c, address) {
+		return
+	}
+
+	msg = "failed to send magic link email"
+
+	user, err := FindUserByEmail(ctx, address)
+	if err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	if user != nil {
+		if err := sendCredentialEmail(ctx, user, authsession.MagicLink); err != nil {
+			fmt.Println(msg, err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+				"error": msg,
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"ok": "if an account exists for this email, a sign in link has been sent",
+	})
+}
+
+// SigninMagicLink signs in a user using a
+// magic link token.
+func SigninMagicLink(c *gin.Context) {
+	if !requireMagicLinkSignin(c) {
+		return
+	}
+
+	var payload struct {
+		Token string `json:"token" binding:"required"`
+	}
+
+	if !bindToken(c, &payload) {
+		return
+	}
+
+	user, ok := consumeToken(c, authsession.MagicLink, payload.Token)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	msg := "failed to sign in"
+
+	// the link was delivered to the user's
+	// email, which proves they own it
+	if err := user.confirm(ctx, nil); err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	if err := user.touchLastSignInAt(ctx); err != nil {
+		fmt.Println(msg, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	startSession(c, user, authsession.MagicLinkProvider)
+}
@@ -0,0 +1,100 @@
+package measure
+
+import (
+	"backend/api/authsession"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	cases := []struct {
+		input    string
+		expected string
+		valid    bool
+	}{
+		{"ada@example.com", "ada@example.com", true},
+		{"  Ada@Example.COM ", "ada@example.com", true},
+		{"Ada Lovelace <ada@example.com>", "", false},
+		{"ada", "", false},
+		{"", "", false},
+	}
+
+	for _, c := range cases {
+		got, err := normalizeEmail(c.input)
+		if c.valid && (err != nil || got != c.expected) {
+			t.Errorf("%q: expected %q, but got %q, %v", c.input, c.expected, got, err)
+		}
+		if !c.valid && err == nil {
+			t.Errorf("%q: expected error, but got nil", c.input)
+		}
+	}
+}
+
+func TestCredentialMessage(t *testing.T) {
+	id := uuid.New().String()
+	name := "Ada Lovelace"
+	address := "ada@example.com"
+	user := &User{ID: &id, Name: &name, Email: &address}
+
+	cases := map[authsession.TokenPurpose]string{
+		authsession.VerifyEmail:   "https://measure.example.com/auth/callback/email?",
+		authsession.ResetPassword: "https://measure.example.com/auth/reset-password?",
+		authsession.MagicLink:     "https://measure.example.com/auth/callback/email?",
+	}
+
+	for purpose, prefix := range cases {
+		token, err := authsession.NewOneTimeToken(uuid.MustParse(id), purpose)
+		if err != nil {
+			t.Fatalf("Expected nil error, but got %v", err)
+		}
+
+		msg := credentialMessage("https://measure.example.com", user, token)
+
+		if len(msg.To) != 1 || msg.To[0] != address {
+			t.Errorf("%s: expected recipient %q, but got %v", purpose, address, msg.To)
+		}
+
+		if msg.Subject == "" || !strings.HasPrefix(msg.Body, "Hi Ada,") {
+			t.Errorf("%s: expected subject & greeting, but got %q, %q", purpose, msg.Subject, msg.Body)
+		}
+
+		start := strings.Index(msg.Body, prefix)
+		if start < 0 {
+			t.Fatalf("%s: expected link with prefix %q, but got %q", purpose, prefix, msg.Body)
+		}
+
+		link := msg.Body[start:]
+		link = link[:strings.Index(link, "\n")]
+
+		u, err := url.Parse(link)
+		if err != nil {
+			t.Fatalf("Expected nil error, but got %v", err)
+		}
+
+		if u.Query().Get("token") != token.Value {
+			t.Errorf("%s: expected token %q in link, but got %q", purpose, token.Value, link)
+		}
+	}
+}
+
+func TestHumanizeExpiry(t *testing.T) {
+	cases := map[time.Duration]string{
+		7 * 24 * time.Hour: "7 days",
+		48 * time.Hour:     "2 days",
+		24 * time.Hour:     "24 hours",
+		time.Hour:          "1 hour",
+		15 * time.Minute:   "15 minutes",
+		90 * time.Minute:   "90 minutes",
+		time.Minute:        "1 minute",
+	}
+
+	for d, expected := range cases {
+		if got := humanizeExpiry(d); got != expected {
+			t.Errorf("%v: expected %q, but got %q", d, expected, got)
+		}
+	}
+}
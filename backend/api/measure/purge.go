@@ -0,0 +1,1080 @@
+package measure
+
+import (
+	"backend/api/chrono"
+	"backend/api/event"
+	"backend/api/server"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/leporo/sqlf"
+)
+
+const (
+	// PurgeStatusQueued is the status of a job
+	// waiting to be processed.
+	PurgeStatusQueued = "queued"
+
+	// PurgeStatusProcessing is the status of a
+	// job being processed by the worker.
+	PurgeStatusProcessing = "processing"
+
+	// PurgeStatusSucceeded is the status of a job
+	// whose data was fully purged.
+	PurgeStatusSucceeded = "succeeded"
+
+	// PurgeStatusFailed is the status of a job
+	// that failed after exhausting all attempts.
+	PurgeStatusFailed = "failed"
+)
+
+const (
+	// purgeStepJobs cancels the pending ingest
+	// & symbolication jobs of an app.
+	purgeStepJobs = "jobs"
+
+	// purgeStepAttachments removes the event
+	// attachments of an app from object storage.
+	purgeStepAttachments = "attachments"
+
+	// purgeStepEvents removes the
+	// events of an app.
+	purgeStepEvents = "events"
+
+	// purgeStepMappings removes the build mappings
+	// of an app from object storage.
+	purgeStepMappings = "mappings"
+
+	// purgeStepRecords removes the issue groups,
+	// builds, event requests & remaining records
+	// of an app.
+	purgeStepRecords = "records"
+
+	// purgeStepTeam removes the team, its
+	// members & remaining records.
+	purgeStepTeam = "team"
+)
+
+// maxPurgeAttempts is the maximum number of
+// times a job is attempted before giving up.
+const maxPurgeAttempts = 5
+
+// purgeLease is the duration a claimed job is
+// held by the worker. Jobs of crashed workers are
+// picked up again once the lease expires.
+const purgeLease = time.Hour
+
+// purgePollInterval is the interval at which
+// the idle worker looks for due jobs.
+const purgePollInterval = 30 * time.Second
+
+// basePurgeBackoff is the wait before the
+// first retry of a failed job.
+const basePurgeBackoff = time.Minute
+
+// maxPurgeBackoff is the maximum wait
+// before retrying a failed job.
+const maxPurgeBackoff = time.Hour
+
+// maxPurgeErrorChars is the maximum characters
+// of the error stored for a failed attempt.
+const maxPurgeErrorChars = 512
+
+// purgePageSize is the number of objects removed
+// from object storage at once, which is also the
+// most a single delete request accepts.
+const purgePageSize = 1000
+
+// purgeSignal wakes up the idle worker
+// when a new job is enqueued.
+var purgeSignal = make(chan struct{}, 1)
+
+// PurgeJob represents the background removal of
+// all data of a deleted team or app.
+type PurgeJob struct {
+	ID                uuid.UUID       `json:"id"`
+	TeamID            uuid.UUID       `json:"team_id"`
+	AppID             *uuid.UUID      `json:"app_id"`
+	RequestedBy       *uuid.UUID      `json:"requested_by"`
+	Status            string          `json:"status"`
+	Step              *string         `json:"step"`
+	AppsCount         int             `json:"apps_count"`
+	AppsPurged        int             `json:"apps_purged"`
+	AttachmentsPurged int64           `json:"attachments_purged"`
+	EventsPurged      int64           `json:"events_purged"`
+	MappingsPurged    int64           `json:"mappings_purged"`
+	Attempts          int             `json:"-"`
+	Error             *string         `json:"error"`
+	CompletedAt       *chrono.ISOTime `json:"completed_at"`
+	CreatedAt         *chrono.ISOTime `json:"created_at"`
+	UpdatedAt         *chrono.ISOTime `json:"updated_at"`
+}
+
+// purgeJobCols are the columns selected
+// for reading a purge job.
+var purgeJobCols = []string{
+	"id",
+	"team_id",
+	"app_id",
+	"requested_by",
+	"status",
+	"step",
+	"apps_count",
+	"apps_purged",
+	"attachments_purged",
+	"events_purged",
+	"mappings_purged",
+	"attempts",
+	"error",
+	"completed_at",
+	"created_at",
+	"updated_at",
+}
+
+// scanPurgeJob scans a row selected
+// using purgeJobCols.
+func scanPurgeJob(row pgx.Row) (*PurgeJob, error) {
+	var completedAt *time.Time
+	var createdAt, updatedAt time.Time
+
+	j := new(PurgeJob)
+
+	if err := row.Scan(&j.ID, &j.TeamID, &j.AppID, &j.RequestedBy, &j.Status, &j.Step, &j.AppsCount, &j.AppsPurged, &j.AttachmentsPurged, &j.EventsPurged, &j.MappingsPurged, &j.Attempts, &j.Error, &completedAt, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+
+	if completedAt != nil {
+		j.CompletedAt = (*chrono.ISOTime)(completedAt)
+	}
+	j.CreatedAt = (*chrono.ISOTime)(&createdAt)
+	j.UpdatedAt = (*chrono.ISOTime)(&updatedAt)
+
+	return j, nil
+}
+
+// softDelete marks the app as deleted, so that
+// it stops accepting data & disappears from the
+// dashboard right away.
+func (a *App) softDelete(ctx context.Context, tx *pgx.Tx) (err error) {
+	stmt := sqlf.PostgreSQL.
+		Update("public.apps").
+		Set("deleted_at", time.Now()).
+		Where("id = ? and deleted_at is null", a.ID)
+
+	defer stmt.Close()
+
+	_, err = (*tx).Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// softDelete marks the team & all of its apps
+// as deleted.
+func (t *Team) softDelete(ctx context.Context, tx *pgx.Tx) (err error) {
+	now := time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.teams").
+		Set("deleted_at", now).
+		Where("id = ? and deleted_at is null", t.ID)
+
+	defer stmt.Close()
+
+	if _, err = (*tx).Exec(ctx, stmt.String(), stmt.Args()...); err != nil {
+		return
+	}
+
+	appsStmt := sqlf.PostgreSQL.
+		Update("public.apps").
+		Set("deleted_at", now).
+		Where("team_id = ? and deleted_at is null", t.ID)
+
+	defer appsStmt.Close()
+
+	_, err = (*tx).Exec(ctx, appsStmt.String(), appsStmt.Args()...)
+
+	return
+}
+
+// countApps counts all apps of the team,
+// including deleted ones.
+func (t *Team) countApps(ctx context.Context, tx *pgx.Tx) (count int, err error) {
+	stmt := sqlf.PostgreSQL.
+		Select("count(*)").
+		From("public.apps").
+		Where("team_id = ?", t.ID)
+
+	defer stmt.Close()
+
+	err = (*tx).QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&count)
+
+	return
+}
+
+// countOwnedTeams counts the teams the
+// user owns, leaving out deleted ones.
+func (u *User) countOwnedTeams(ctx context.Context) (count int, err error) {
+	stmt := sqlf.PostgreSQL.
+		Select("count(*)").
+		From("public.team_membership tm").
+		Join("public.teams t", "t.id = tm.team_id").
+		Where("tm.user_id = ? and tm.role = ?", u.ID, owner.String()).
+		Where("t.deleted_at is null")
+
+	defer stmt.Close()
+
+	err = server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&count)
+
+	return
+}
+
+// enqueuePurge queues the purge of a deleted team, or
+// of a deleted app when the app id is set.
+func enqueuePurge(ctx context.Context, tx *pgx.Tx, teamId uuid.UUID, appId *uuid.UUID, requestedBy uuid.UUID, appsCount int) (job *PurgeJob, err error) {
+	now := time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		InsertInto("public.purge_jobs").
+		Set("id", uuid.New()).
+		Set("team_id", teamId).
+		Set("app_id", appId).
+		Set("requested_by", requestedBy).
+		Set("status", PurgeStatusQueued).
+		Set("apps_count", appsCount).
+		Set("next_attempt_at", now).
+		Set("created_at", now).
+		Set("updated_at", now)
+
+	for _, col := range purgeJobCols {
+		stmt.Returning(col)
+	}
+
+	defer stmt.Close()
+
+	return scanPurgeJob((*tx).QueryRow(ctx, stmt.String(), stmt.Args()...))
+}
+
+// notifyPurge wakes up the idle worker,
+// if it isn't waiting, skip.
+func notifyPurge() {
+	select {
+	case purgeSignal <- struct{}{}:
+	default:
+	}
+}
+
+// getPurgeJob fetches a purge job requested by
+// the user. Returns nil if no job is found.
+func getPurgeJob(ctx context.Context, id, requestedBy uuid.UUID) (*PurgeJob, error) {
+	stmt := sqlf.PostgreSQL.
+		From("public.purge_jobs").
+		Where("id = ? and requested_by = ?", id, requestedBy)
+
+	for _, col := range purgeJobCols {
+		stmt.Select(col)
+	}
+
+	defer stmt.Close()
+
+	j, err := scanPurgeJob(server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return j, nil
+}
+
+// claimPurgeJob claims the next due job by leasing
+// it to the caller. Returns nil if no job is due.
+func claimPurgeJob(ctx context.Context) (job *PurgeJob, err error) {
+	now := time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.purge_jobs").
+		Set("status", PurgeStatusProcessing).
+		SetExpr("attempts", "attempts + 1").
+		Set("next_attempt_at", now.Add(purgeLease)).
+		Set("updated_at", now).
+		Where("id = (select id from public.purge_jobs where status in (?, ?) and next_attempt_at <= ? order by next_attempt_at limit 1 for update skip locked)", PurgeStatusQueued, PurgeStatusProcessing, now)
+
+	for _, col := range purgeJobCols {
+		stmt.Returning(col)
+	}
+
+	defer stmt.Close()
+
+	job, err = scanPurgeJob(server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return
+}
+
+// apps provides the ids of the apps left to purge.
+// Purged apps no longer exist, so retries resume
+// from the first app not fully purged.
+func (j PurgeJob) apps(ctx context.Context) (appIds []uuid.UUID, err error) {
+	if j.AppID != nil {
+		return []uuid.UUID{*j.AppID}, nil
+	}
+
+	stmt := sqlf.PostgreSQL.
+		Select("id").
+		From("public.apps").
+		Where("team_id = ?", j.TeamID).
+		OrderBy("created_at")
+
+	defer stmt.Close()
+
+	rows, err := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	return pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+}
+
+// run purges each app of the job and then the team,
+// if the whole team was deleted. Every step can be
+// repeated, so that failed attempts start over from
+// the step that failed.
+func (j *PurgeJob) run(ctx context.Context) (err error) {
+	appIds, err := j.apps(ctx)
+	if err != nil {
+		return
+	}
+
+	for _, appId := range appIds {
+		if err = j.purgeApp(ctx, appId); err != nil {
+			return
+		}
+
+		j.AppsPurged += 1
+
+		if err = j.progress(ctx, purgeStepRecords); err != nil {
+			return
+		}
+	}
+
+	if j.AppID != nil {
+		return
+	}
+
+	if err = j.progress(ctx, purgeStepTeam); err != nil {
+		return
+	}
+
+	// removing the team cascades to its
+	// members, invites & audit logs
+	stmt := sqlf.PostgreSQL.
+		DeleteFrom("public.teams").
+		Where("id = ?", j.TeamID)
+
+	defer stmt.Close()
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// purgeApp removes all data of the app from
+// object storage, ClickHouse & Postgres.
+func (j *PurgeJob) purgeApp(ctx context.Context, appId uuid.UUID) (err error) {
+	// stop pending jobs from writing
+	// more data of the app
+	if err = j.progress(ctx, purgeStepJobs); err != nil {
+		return
+	}
+
+	for _, table := range []string{"public.ingest_jobs", "public.symbolication_jobs"} {
+		if err = purgeRows(ctx, nil, table, appId); err != nil {
+			return
+		}
+	}
+
+	if err = j.progress(ctx, purgeStepAttachments); err != nil {
+		return
+	}
+
+	if err = j.purgeAttachments(ctx, appId); err != nil {
+		return
+	}
+
+	if err = j.progress(ctx, purgeStepEvents); err != nil {
+		return
+	}
+
+	if err = j.purgeEvents(ctx, appId); err != nil {
+		return
+	}
+
+	if err = j.progress(ctx, purgeStepMappings); err != nil {
+		return
+	}
+
+	if err = j.purgeMappings(ctx, appId); err != nil {
+		return
+	}
+
+	if err = j.progress(ctx, purgeStepRecords); err != nil {
+		return
+	}
+
+	return purgeRecords(ctx, appId)
+}
+
+// purgeAttachments removes the attachments of the
+// app's events from object storage, page by page.
+func (j *PurgeJob) purgeAttachments(ctx context.Context, appId uuid.UUID) (err error) {
+	after := uuid.Nil
+
+	for {
+		stmt := sqlf.
+			From("default.events").
+			Select("id").
+			Select("attachments").
+			Where("app_id = ?", appId).
+			Where("attachments != '[]'").
+			Where("id > ?", after).
+			OrderBy("id").
+			Limit(purgePageSize)
+
+		rows, err := server.Server.ChPool.Query(ctx, stmt.String(), stmt.Args()...)
+		stmt.Close()
+		if err != nil {
+			return err
+		}
+
+		var keys []string
+		var count int
+
+		for rows.Next() {
+			var raw string
+			var attachments []event.Attachment
+
+			if err := rows.Scan(&after, &raw); err != nil {
+				rows.Close()
+				return err
+			}
+
+			count += 1
+
+			if err := json.Unmarshal([]byte(raw), &attachments); err != nil {
+				fmt.Printf("failed to unmarshal attachments of event %q: %v\n", after, err)
+				continue
+			}
+
+			for _, a := range attachments {
+				if a.Key != "" {
+					keys = append(keys, a.Key)
+				}
+			}
+		}
+
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if count == 0 {
+			return nil
+		}
+
+		if err := deleteFromStorage(ctx, attachmentsAWSConfig(), server.Server.Config.AttachmentsBucket, keys); err != nil {
+			return err
+		}
+
+		j.AttachmentsPurged += int64(len(keys))
+
+		if err := j.progress(ctx, purgeStepAttachments); err != nil {
+			return err
+		}
+	}
+}
+
+// purgeEvents removes all events of the app.
+func (j *PurgeJob) purgeEvents(ctx context.Context, appId uuid.UUID) (err error) {
+	var count uint64
+
+	countStmt := sqlf.
+		From("default.events").
+		Select("count()").
+		Where("app_id = ?", appId)
+
+	defer countStmt.Close()
+
+	if err = server.Server.ChPool.QueryRow(ctx, countStmt.String(), countStmt.Args()...).Scan(&count); err != nil {
+		return
+	}
+
+	if count == 0 {
+		return
+	}
+
+	stmt := sqlf.
+		DeleteFrom("default.events").
+		Where("app_id = ?", appId)
+
+	defer stmt.Close()
+
+	if err = server.Server.ChPool.Exec(ctx, stmt.String(), stmt.Args()...); err != nil {
+		return
+	}
+
+	j.EventsPurged += int64(count)
+
+	return
+}
+
+// purgeMappings removes the build mappings of the
+// app from object storage & then their records,
+// page by page.
+func (j *PurgeJob) purgeMappings(ctx context.Context, appId uuid.UUID) (err error) {
+	for {
+		stmt := sqlf.PostgreSQL.
+			Select("id").
+			Select("key").
+			From("public.build_mappings").
+			Where("app_id = ?", appId).
+			Limit(purgePageSize)
+
+		rows, err := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
+		stmt.Close()
+		if err != nil {
+			return err
+		}
+
+		var ids []uuid.UUID
+		var keys []string
+
+		for rows.Next() {
+			var id uuid.UUID
+			var key *string
+
+			if err := rows.Scan(&id, &key); err != nil {
+				rows.Close()
+				return err
+			}
+
+			ids = append(ids, id)
+			if key != nil && *key != "" {
+				keys = append(keys, *key)
+			}
+		}
+
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err := deleteFromStorage(ctx, symbolsAWSConfig(), server.Server.Config.SymbolsBucket, keys); err != nil {
+			return err
+		}
+
+		deleteStmt := sqlf.PostgreSQL.
+			DeleteFrom("public.build_mappings").
+			Where("id = any(?)", ids)
+
+		_, err = server.Server.PgPool.Exec(ctx, deleteStmt.String(), deleteStmt.Args()...)
+		deleteStmt.Close()
+		if err != nil {
+			return err
+		}
+
+		j.MappingsPurged += int64(len(keys))
+
+		if err := j.progress(ctx, purgeStepMappings); err != nil {
+			return err
+		}
+	}
+}
+
+// purgeRecords removes the remaining records of the
+// app & then the app itself, in a single transaction.
+func purgeRecords(ctx context.Context, appId uuid.UUID) (err error) {
+	tx, err := server.Server.PgPool.Begin(ctx)
+	if err != nil {
+		return
+	}
+
+	defer tx.Rollback(ctx)
+
+	tables := []string{
+		"public.unhandled_exception_groups",
+		"public.anr_groups",
+		"public.build_sizes",
+		"public.event_reqs",
+	}
+
+	for _, table := range tables {
+		if err = purgeRows(ctx, &tx, table, appId); err != nil {
+			return
+		}
+	}
+
+	// removing the app cascades to its api keys,
+	// settings, alerts, webhooks & remaining
+	// records
+	stmt := sqlf.PostgreSQL.
+		DeleteFrom("public.apps").
+		Where("id = ?", appId)
+
+	defer stmt.Close()
+
+	if _, err = tx.Exec(ctx, stmt.String(), stmt.Args()...); err != nil {
+		return
+	}
+
+	return tx.Commit(ctx)
+}
+
+// purgeRows removes the rows of the app
+// from the table.
+func purgeRows(ctx context.Context, tx *pgx.Tx, table string, appId uuid.UUID) (err error) {
+	stmt := sqlf.PostgreSQL.
+		DeleteFrom(table).
+		Where("app_id = ?", appId)
+
+	defer stmt.Close()
+
+	if tx != nil {
+		_, err = (*tx).Exec(ctx, stmt.String(), stmt.Args()...)
+		return
+	}
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// attachmentsAWSConfig creates the AWS configuration
+// for accessing the attachments bucket.
+func attachmentsAWSConfig() *aws.Config {
+	config := server.Server.Config
+	awsConfig := &aws.Config{
+		Region:      aws.String(config.AttachmentsBucketRegion),
+		Credentials: credentials.NewStaticCredentials(config.AttachmentsAccessKey, config.AttachmentsSecretAccessKey, ""),
+	}
+
+	// if a custom endpoint was set, then most likely,
+	// we are in local development mode and should force
+	// path style instead of S3 virtual path styles.
+	if config.AWSEndpoint != "" {
+		awsConfig.S3ForcePathStyle = aws.Bool(true)
+		awsConfig.Endpoint = aws.String(config.AWSEndpoint)
+	}
+
+	return awsConfig
+}
+
+// deleteFromStorage removes the objects of the keys
+// from the bucket, in batches the storage accepts.
+// Missing objects are not an error.
+func deleteFromStorage(ctx context.Context, awsConfig *aws.Config, bucket string, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	awsSession := session.Must(session.NewSession(awsConfig))
+	svc := s3.New(awsSession)
+
+	for start := 0; start < len(keys); start += purgePageSize {
+		end := min(start+purgePageSize, len(keys))
+
+		var objects []*s3.ObjectIdentifier
+		for _, key := range keys[start:end] {
+			objects = append(objects, &s3.ObjectIdentifier{
+				Key: aws.String(key),
+			})
+		}
+
+		output, err := svc.DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(bucket),
+			Delete: &s3.Delete{
+				Objects: objects,
+				Quiet:   aws.Bool(true),
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		if len(output.Errors) > 0 {
+			e := output.Errors[0]
+			return fmt.Errorf("failed to delete %d objects, first %q: %s", len(output.Errors), aws.StringValue(e.Key), aws.StringValue(e.Message))
+		}
+	}
+
+	return nil
+}
+
+// progress records the step being processed
+// & the amount of data purged so far.
+func (j *PurgeJob) progress(ctx context.Context, step string) (err error) {
+	j.Step = &step
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.purge_jobs").
+		Set("step", step).
+		Set("apps_purged", j.AppsPurged).
+		Set("attachments_purged", j.AttachmentsPurged).
+		Set("events_purged", j.EventsPurged).
+		Set("mappings_purged", j.MappingsPurged).
+		Set("updated_at", time.Now()).
+		Where("id = ?", j.ID)
+
+	defer stmt.Close()
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// succeed marks the job as succeeded.
+func (j PurgeJob) succeed(ctx context.Context) (err error) {
+	now := time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.purge_jobs").
+		Set("status", PurgeStatusSucceeded).
+		Set("step", nil).
+		Set("error", nil).
+		Set("completed_at", now).
+		Set("updated_at", now).
+		Where("id = ?", j.ID)
+
+	defer stmt.Close()
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// purgeBackoff computes the wait before the next
+// attempt after the given number of attempts.
+func purgeBackoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+
+	backoff := basePurgeBackoff
+	for i := 1; i < attempts; i++ {
+		backoff = backoff * 2
+		if backoff >= maxPurgeBackoff {
+			return maxPurgeBackoff
+		}
+	}
+
+	return backoff
+}
+
+// fail records the failed attempt. Schedules a retry
+// with backoff or marks the job as failed once all
+// attempts are exhausted.
+func (j PurgeJob) fail(ctx context.Context, cause error) (err error) {
+	now := time.Now()
+	msg := cause.Error()
+	if len(msg) > maxPurgeErrorChars {
+		msg = msg[:maxPurgeErrorChars]
+	}
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.purge_jobs").
+		Set("error", msg).
+		Set("updated_at", now)
+
+	defer stmt.Close()
+
+	if j.Attempts >= maxPurgeAttempts {
+		stmt.
+			Set("status", PurgeStatusFailed).
+			Set("completed_at", now)
+	} else {
+		stmt.
+			Set("status", PurgeStatusQueued).
+			Set("next_attempt_at", now.Add(purgeBackoff(j.Attempts)))
+	}
+
+	stmt.Where("id = ?", j.ID)
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// processPurgeJob claims and processes the next
+// due job. Returns false if no job was due.
+func processPurgeJob(ctx context.Context) (processed bool, err error) {
+	job, err := claimPurgeJob(ctx)
+	if err != nil || job == nil {
+		return
+	}
+
+	processed = true
+
+	if runErr := job.run(ctx); runErr != nil {
+		fmt.Printf("failed to purge team %q app %v, attempt %d: %v\n", job.TeamID, job.AppID, job.Attempts, runErr)
+		err = job.fail(ctx, runErr)
+		return
+	}
+
+	err = job.succeed(ctx)
+
+	return
+}
+
+// StartPurgeWorker starts a worker that purges
+// the data of deleted teams & apps until the
+// context is done.
+func StartPurgeWorker(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(purgePollInterval)
+		defer ticker.Stop()
+
+		for {
+			processed, err := processPurgeJob(ctx)
+			if err != nil {
+				fmt.Println("failed to process purge job", err)
+			}
+
+			// drain the queue before
+			// waiting for more jobs
+			if processed {
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-purgeSignal:
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+func DeleteTeam(c *gin.Context) {
+	ctx := c.Request.Context()
+	userId := c.GetString("userId")
+	teamId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `team id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	ok, err := PerformAuthz(c, userId, teamId.String(), *ScopeTeamAll)
+	if err != nil {
+		msg := `couldn't perform authorization checks`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+	if !ok {
+		msg := fmt.Sprintf(`you don't have permissions to delete team [%s]`, teamId)
+		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+		return
+	}
+
+	// users must own a team to
+	// create more teams
+	owned, err := (&User{ID: &userId}).countOwnedTeams(ctx)
+	if err != nil {
+		msg := `failed to delete team`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+	if owned < 2 {
+		msg := `can't delete the only team you own`
+		c.JSON(http.StatusConflict, gin.H{"error": msg})
+		return
+	}
+
+	team := &Team{
+		ID: &teamId,
+	}
+
+	tx, err := server.Server.PgPool.Begin(ctx)
+	if err != nil {
+		msg := `failed to delete team`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err := team.softDelete(ctx, &tx); err != nil {
+		msg := `failed to delete team`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	appsCount, err := team.countApps(ctx, &tx)
+	if err != nil {
+		msg := `failed to delete team`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	job, err := enqueuePurge(ctx, &tx, teamId, nil, uuid.MustParse(userId), appsCount)
+	if err != nil {
+		msg := `failed to delete team`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		msg := `failed to delete team`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	notifyPurge()
+
+	newAuditLog(c, auditTeamDeleted).
+		inTeam(teamId).
+		on(auditTargetTeam, teamId.String()).
+		record(ctx)
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+func DeleteApp(c *gin.Context) {
+	ctx := c.Request.Context()
+	userId := c.GetString("userId")
+	appId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `app id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	app := App{
+		ID: &appId,
+	}
+
+	if !authzApp(c, appId, *ScopeTeamAll) {
+		return
+	}
+
+	team, err := app.getTeam(ctx)
+	if err != nil {
+		msg := "failed to get team from app id"
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+	if team == nil {
+		msg := fmt.Sprintf("no team exists for app [%s]", app.ID)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	tx, err := server.Server.PgPool.Begin(ctx)
+	if err != nil {
+		msg := `failed to delete app`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err := app.softDelete(ctx, &tx); err != nil {
+		msg := `failed to delete app`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	job, err := enqueuePurge(ctx, &tx, *team.ID, &appId, uuid.MustParse(userId), 1)
+	if err != nil {
+		msg := `failed to delete app`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		msg := `failed to delete app`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	notifyPurge()
+
+	newAuditLog(c, auditAppDeleted).
+		inTeam(*team.ID).
+		on(auditTargetApp, appId.String()).
+		record(ctx)
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetPurge reports the progress of a team or
+// app deletion to the user who requested it.
+func GetPurge(c *gin.Context) {
+	userId, err := uuid.Parse(c.GetString("userId"))
+	if err != nil {
+		msg := `user id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `purge id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	job, err := getPurgeJob(c.Request.Context(), id, userId)
+	if err != nil {
+		msg := `failed to fetch purge`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+	if job == nil {
+		msg := fmt.Sprintf("no purge found with id %q", id)
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
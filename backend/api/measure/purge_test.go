@@ -0,0 +1,73 @@
+package measure
+
+import (
+	"backend/api/chrono"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPurgeJobMarshalJSON(t *testing.T) {
+	step := purgeStepEvents
+	now := time.Now()
+	j := PurgeJob{
+		ID:           uuid.New(),
+		TeamID:       uuid.New(),
+		Status:       PurgeStatusProcessing,
+		Step:         &step,
+		AppsCount:    2,
+		AppsPurged:   1,
+		EventsPurged: 42,
+		Attempts:     3,
+		CreatedAt:    (*chrono.ISOTime)(&now),
+		UpdatedAt:    (*chrono.ISOTime)(&now),
+	}
+
+	data, err := json.Marshal(j)
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if _, ok := result["attempts"]; ok {
+		t.Errorf("Expected attempts to be left out, but got %v", result["attempts"])
+	}
+
+	if result["step"] != "events" {
+		t.Errorf("Expected step %q, but got %v", "events", result["step"])
+	}
+
+	if result["app_id"] != nil {
+		t.Errorf("Expected nil app id, but got %v", result["app_id"])
+	}
+
+	if result["completed_at"] != nil {
+		t.Errorf("Expected nil completed at, but got %v", result["completed_at"])
+	}
+
+	if result["events_purged"] != float64(42) {
+		t.Errorf("Expected %d events purged, but got %v", 42, result["events_purged"])
+	}
+}
+
+func TestPurgeBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		0:  0,
+		1:  time.Minute,
+		2:  2 * time.Minute,
+		4:  8 * time.Minute,
+		10: time.Hour,
+	}
+
+	for attempts, expected := range cases {
+		if got := purgeBackoff(attempts); got != expected {
+			t.Errorf("Expected backoff %v for %d attempts, but got %v", expected, attempts, got)
+		}
+	}
+}
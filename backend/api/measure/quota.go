@@ -0,0 +1,710 @@
+package measure
+
+import (
+	"backend/api/server"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/leporo/sqlf"
+)
+
+const (
+	// quotaEvents is the kind of quota
+	// limiting events.
+	quotaEvents = "events"
+
+	// quotaSessions is the kind of quota
+	// limiting distinct sessions.
+	quotaSessions = "sessions"
+
+	// quotaAttachmentBytes is the kind of
+	// quota limiting attachment bytes.
+	quotaAttachmentBytes = "attachment_bytes"
+)
+
+const (
+	// quotaScopeTeam is the scope of quotas
+	// shared by all apps of a team.
+	quotaScopeTeam = "team"
+
+	// quotaScopeApp is the scope of quotas
+	// of a single app.
+	quotaScopeApp = "app"
+)
+
+// quotaWarnRatio is the share of a quota
+// used after which usage is warned about.
+const quotaWarnRatio = 0.8
+
+// Quota represents the monthly limits of a team
+// or an app. Nil limits are unlimited.
+type Quota struct {
+	MaxEvents          *int64 `json:"max_events"`
+	MaxSessions        *int64 `json:"max_sessions"`
+	MaxAttachmentBytes *int64 `json:"max_attachment_bytes"`
+}
+
+// Usage represents the amount of data
+// accepted in a calendar month.
+type Usage struct {
+	Events          int64 `json:"events"`
+	Sessions        int64 `json:"sessions"`
+	AttachmentBytes int64 `json:"attachment_bytes"`
+}
+
+// QuotaWarning represents usage nearing
+// or crossing a quota.
+type QuotaWarning struct {
+	Scope    string `json:"scope"`
+	Kind     string `json:"kind"`
+	Used     int64  `json:"used"`
+	Limit    int64  `json:"limit"`
+	Exceeded bool   `json:"exceeded"`
+}
+
+// validate validates the quota.
+func (q Quota) validate() error {
+	for kind, limit := range q.limits() {
+		if limit != nil && *limit < 0 {
+			return fmt.Errorf("%q must not be negative", "max_"+kind)
+		}
+	}
+
+	return nil
+}
+
+// empty reports if the quota has no limits.
+func (q Quota) empty() bool {
+	return q.MaxEvents == nil && q.MaxSessions == nil && q.MaxAttachmentBytes == nil
+}
+
+// limits provides the limits of the
+// quota by their kind.
+func (q Quota) limits() map[string]*int64 {
+	return map[string]*int64{
+		quotaEvents:          q.MaxEvents,
+		quotaSessions:        q.MaxSessions,
+		quotaAttachmentBytes: q.MaxAttachmentBytes,
+	}
+}
+
+// fields provides the limits for
+// recording in the audit log.
+func (q Quota) fields() map[string]any {
+	return map[string]any{
+		"max_events":           q.MaxEvents,
+		"max_sessions":         q.MaxSessions,
+		"max_attachment_bytes": q.MaxAttachmentBytes,
+	}
+}
+
+// exceeds reports the first kind of limit crossed if the
+// requested usage were added to the used amount. Kinds
+// the request doesn't add to are never crossed, so that
+// known sessions can finish once the sessions quota is
+// reached.
+func (q Quota) exceeds(used, req Usage) (kind string, ok bool) {
+	checks := []struct {
+		kind  string
+		limit *int64
+		used  int64
+		req   int64
+	}{
+		{quotaEvents, q.MaxEvents, used.Events, req.Events},
+		{quotaSessions, q.MaxSessions, used.Sessions, req.Sessions},
+		{quotaAttachmentBytes, q.MaxAttachmentBytes, used.AttachmentBytes, req.AttachmentBytes},
+	}
+
+	for _, check := range checks {
+		if check.limit == nil || check.req < 1 {
+			continue
+		}
+
+		if check.used+check.req > *check.limit {
+			return check.kind, true
+		}
+	}
+
+	return
+}
+
+// counterConditions provides the conditions under
+// which a usage counter can be raised by the requested
+// usage without crossing the quota. Kinds the request
+// doesn't add to are left out, like in exceeds.
+func (q Quota) counterConditions(req Usage) (conds []string, args []any) {
+	checks := []struct {
+		kind  string
+		limit *int64
+		req   int64
+	}{
+		{quotaEvents, q.MaxEvents, req.Events},
+		{quotaSessions, q.MaxSessions, req.Sessions},
+		{quotaAttachmentBytes, q.MaxAttachmentBytes, req.AttachmentBytes},
+	}
+
+	for _, check := range checks {
+		if check.limit == nil || check.req < 1 {
+			continue
+		}
+
+		conds = append(conds, fmt.Sprintf("usage_counters.%s + excluded.%s <= ?", check.kind, check.kind))
+		args = append(args, *check.limit)
+	}
+
+	return
+}
+
+// warnings provides warnings for each limit of the
+// quota that is nearly used up or crossed.
+func (q Quota) warnings(scope string, used Usage) (warnings []QuotaWarning) {
+	checks := []struct {
+		kind  string
+		limit *int64
+		used  int64
+	}{
+		{quotaEvents, q.MaxEvents, used.Events},
+		{quotaSessions, q.MaxSessions, used.Sessions},
+		{quotaAttachmentBytes, q.MaxAttachmentBytes, used.AttachmentBytes},
+	}
+
+	for _, check := range checks {
+		if check.limit == nil {
+			continue
+		}
+
+		if float64(check.used) < float64(*check.limit)*quotaWarnRatio {
+			continue
+		}
+
+		warnings = append(warnings, QuotaWarning{
+			Scope:    scope,
+			Kind:     check.kind,
+			Used:     check.used,
+			Limit:    *check.limit,
+			Exceeded: check.used >= *check.limit,
+		})
+	}
+
+	return
+}
+
+// usageMonth provides the first day of
+// the utc calendar month of the time.
+func usageMonth(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// quotaRetryAfter provides the wait until quotas
+// reset at the start of the next month.
+func quotaRetryAfter(now time.Time) time.Duration {
+	return usageMonth(now).AddDate(0, 1, 0).Sub(now)
+}
+
+// getQuota fetches the quota of a team, or of an app
+// when the app id is set. Returns an empty quota if
+// none is set.
+func getQuota(ctx context.Context, teamId, appId *uuid.UUID) (quota Quota, err error) {
+	stmt := sqlf.PostgreSQL.
+		Select("max_events").
+		Select("max_sessions").
+		Select("max_attachment_bytes").
+		From("public.quotas")
+
+	if appId != nil {
+		stmt.Where("app_id = ?", appId)
+	} else {
+		stmt.Where("team_id = ?", teamId)
+	}
+
+	defer stmt.Close()
+
+	if err = server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&quota.MaxEvents, &quota.MaxSessions, &quota.MaxAttachmentBytes); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Quota{}, nil
+		}
+	}
+
+	return
+}
+
+// setQuota sets the quota of a team, or of an
+// app when the app id is set.
+func setQuota(ctx context.Context, teamId, appId *uuid.UUID, quota Quota) error {
+	now := time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		InsertInto("public.quotas").
+		Set("max_events", quota.MaxEvents).
+		Set("max_sessions", quota.MaxSessions).
+		Set("max_attachment_bytes", quota.MaxAttachmentBytes).
+		Set("created_at", now).
+		Set("updated_at", now)
+
+	update := "do update set max_events = excluded.max_events, max_sessions = excluded.max_sessions, max_attachment_bytes = excluded.max_attachment_bytes, updated_at = excluded.updated_at"
+
+	if appId != nil {
+		stmt.
+			Set("app_id", appId).
+			Clause("on conflict (app_id) where app_id is not null " + update)
+	} else {
+		stmt.
+			Set("team_id", teamId).
+			Clause("on conflict (team_id) where team_id is not null " + update)
+	}
+
+	defer stmt.Close()
+
+	_, err := server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return err
+}
+
+// usageStmt builds the statement selecting the usage of
+// the month of a team's apps, or of an app when the app
+// id is set.
+func usageStmt(teamId, appId *uuid.UUID, month time.Time) *sqlf.Stmt {
+	stmt := sqlf.PostgreSQL.
+		Select("coalesce(sum(uc.events), 0)").
+		Select("coalesce(sum(uc.sessions), 0)").
+		Select("coalesce(sum(uc.attachment_bytes), 0)").
+		From("public.usage_counters uc").
+		Where("uc.month = ?", month)
+
+	if appId != nil {
+		stmt.Where("uc.app_id = ?", appId)
+	} else {
+		stmt.
+			Join("public.apps a", "a.id = uc.app_id").
+			Where("a.team_id = ? and a.deleted_at is null", teamId)
+	}
+
+	return stmt
+}
+
+// getUsage fetches the usage of the month of a team's
+// apps, or of an app when the app id is set.
+func getUsage(ctx context.Context, teamId, appId *uuid.UUID, month time.Time) (usage Usage, err error) {
+	stmt := usageStmt(teamId, appId, month)
+
+	defer stmt.Close()
+
+	err = server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&usage.Events, &usage.Sessions, &usage.AttachmentBytes)
+
+	return
+}
+
+// getUsageTx fetches the usage of the month within
+// the transaction. See getUsage.
+func getUsageTx(ctx context.Context, tx *pgx.Tx, teamId, appId *uuid.UUID, month time.Time) (usage Usage, err error) {
+	stmt := usageStmt(teamId, appId, month)
+
+	defer stmt.Close()
+
+	err = (*tx).QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&usage.Events, &usage.Sessions, &usage.AttachmentBytes)
+
+	return
+}
+
+// sessionIds provides the distinct session
+// ids of the event request.
+func (e eventreq) sessionIds() (ids []uuid.UUID) {
+	seen := make(map[uuid.UUID]struct{})
+
+	for i := range e.events {
+		id := e.events[i].SessionID
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+
+	return
+}
+
+// attachmentBytes computes the total size of
+// the event request's attachments in bytes.
+func (e eventreq) attachmentBytes() (size int64) {
+	for _, a := range e.attachments {
+		if a.data != nil {
+			size += int64(len(a.data))
+		} else if a.header != nil {
+			size += a.header.Size
+		}
+	}
+
+	return
+}
+
+// quotaExceededError is returned when accepting
+// an event request would cross a quota.
+type quotaExceededError struct {
+	scope string
+	kind  string
+}
+
+func (e quotaExceededError) Error() string {
+	return fmt.Sprintf(`monthly %s quota of the %s exceeded`, strings.ReplaceAll(e.kind, "_", " "), e.scope)
+}
+
+// lockQuota fetches the quota of a team & locks it
+// until the transaction ends. Returns an empty quota
+// if none is set.
+func lockQuota(ctx context.Context, tx *pgx.Tx, teamId uuid.UUID) (quota Quota, err error) {
+	stmt := sqlf.PostgreSQL.
+		Select("max_events").
+		Select("max_sessions").
+		Select("max_attachment_bytes").
+		From("public.quotas").
+		Where("team_id = ?", teamId).
+		Clause("for update")
+
+	defer stmt.Close()
+
+	if err = (*tx).QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&quota.MaxEvents, &quota.MaxSessions, &quota.MaxAttachmentBytes); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Quota{}, nil
+		}
+	}
+
+	return
+}
+
+// reserveUsage adds the event request's events, new
+// sessions & attachment bytes to the usage counters
+// of the app's current month. Returns a
+// quotaExceededError if the request would cross the
+// quotas of the app or its team, in which case the
+// transaction must be rolled back.
+//
+// Concurrent requests of a team with a quota queue up
+// on the lock of the team's quota, while the app's
+// quota is enforced by the conditional upsert of the
+// app's counter.
+func (e eventreq) reserveUsage(ctx context.Context, tx *pgx.Tx, teamId uuid.UUID) (err error) {
+	now := time.Now()
+	month := usageMonth(now)
+
+	teamQuota, err := lockQuota(ctx, tx, teamId)
+	if err != nil {
+		return
+	}
+
+	appQuota, err := getQuota(ctx, nil, &e.appId)
+	if err != nil {
+		return
+	}
+
+	req := Usage{
+		Events:          int64(len(e.events)),
+		AttachmentBytes: e.attachmentBytes(),
+	}
+
+	if ids := e.sessionIds(); len(ids) > 0 {
+		tag, err := (*tx).Exec(ctx, "insert into public.usage_sessions(app_id, month, session_id) select $1, $2, unnest($3::uuid[]) on conflict do nothing;", e.appId, month, ids)
+		if err != nil {
+			return err
+		}
+
+		req.Sessions = tag.RowsAffected()
+	}
+
+	if !teamQuota.empty() {
+		used, err := getUsageTx(ctx, tx, &teamId, nil, month)
+		if err != nil {
+			return err
+		}
+
+		if kind, ok := teamQuota.exceeds(used, req); ok {
+			return quotaExceededError{quotaScopeTeam, kind}
+		}
+	}
+
+	// the app's counter may not
+	// exist yet
+	if kind, ok := appQuota.exceeds(Usage{}, req); ok {
+		return quotaExceededError{quotaScopeApp, kind}
+	}
+
+	stmt := sqlf.PostgreSQL.
+		InsertInto("public.usage_counters").
+		Set("app_id", e.appId).
+		Set("month", month).
+		Set("events", req.Events).
+		Set("sessions", req.Sessions).
+		Set("attachment_bytes", req.AttachmentBytes).
+		Set("created_at", now).
+		Set("updated_at", now).
+		Clause("on conflict (app_id, month) do update set events = usage_counters.events + excluded.events, sessions = usage_counters.sessions + excluded.sessions, attachment_bytes = usage_counters.attachment_bytes + excluded.attachment_bytes, updated_at = excluded.updated_at")
+
+	if conds, args := appQuota.counterConditions(req); len(conds) > 0 {
+		stmt.Clause("where "+strings.Join(conds, " and "), args...)
+	}
+
+	defer stmt.Close()
+
+	tag, err := (*tx).Exec(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	// the counter exists, but raising
+	// it would cross the app's quota
+	if tag.RowsAffected() < 1 {
+		used, err := getUsageTx(ctx, tx, nil, &e.appId, month)
+		if err != nil {
+			return err
+		}
+
+		kind, _ := appQuota.exceeds(used, req)
+
+		return quotaExceededError{quotaScopeApp, kind}
+	}
+
+	return
+}
+
+// QuotaReport represents a quota along with
+// the usage of the current month.
+type QuotaReport struct {
+	Quota    Quota          `json:"quota"`
+	Usage    Usage          `json:"usage"`
+	Warnings []QuotaWarning `json:"warnings"`
+	ResetsAt string         `json:"resets_at"`
+}
+
+// newQuotaReport creates a report of the quota
+// & usage of the current month.
+func newQuotaReport(ctx context.Context, scope string, teamId, appId *uuid.UUID) (report *QuotaReport, err error) {
+	quota, err := getQuota(ctx, teamId, appId)
+	if err != nil {
+		return
+	}
+
+	month := usageMonth(time.Now())
+
+	usage, err := getUsage(ctx, teamId, appId, month)
+	if err != nil {
+		return
+	}
+
+	warnings := quota.warnings(scope, usage)
+	if warnings == nil {
+		warnings = []QuotaWarning{}
+	}
+
+	return &QuotaReport{
+		Quota:    quota,
+		Usage:    usage,
+		Warnings: warnings,
+		ResetsAt: month.AddDate(0, 1, 0).Format(time.RFC3339),
+	}, nil
+}
+
+func GetTeamQuota(c *gin.Context) {
+	ctx := c.Request.Context()
+	userId := c.GetString("userId")
+	teamId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `team id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	ok, err := PerformAuthz(c, userId, teamId.String(), *ScopeTeamRead)
+	if err != nil {
+		msg := `couldn't perform authorization checks`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+	if !ok {
+		msg := fmt.Sprintf(`you don't have permissions for team [%s]`, teamId)
+		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+		return
+	}
+
+	report, err := newQuotaReport(ctx, quotaScopeTeam, &teamId, nil)
+	if err != nil {
+		msg := fmt.Sprintf("failed to fetch quota for team: %s", teamId)
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func UpdateTeamQuota(c *gin.Context) {
+	ctx := c.Request.Context()
+	userId := c.GetString("userId")
+	teamId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `team id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	ok, err := PerformAuthz(c, userId, teamId.String(), *ScopeBillingAll)
+	if err != nil {
+		msg := `couldn't perform authorization checks`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+	if !ok {
+		msg := fmt.Sprintf(`you don't have permissions to change quota of team [%s]`, teamId)
+		c.JSON(http.StatusForbidden, gin.H{"error": msg})
+		return
+	}
+
+	var quota Quota
+	if err := c.ShouldBindJSON(&quota); err != nil {
+		msg := `failed to parse quota json payload`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := quota.validate(); err != nil {
+		msg := `quota validation failed`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	prevQuota, err := getQuota(ctx, &teamId, nil)
+	if err != nil {
+		msg := `failed to update quota`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	if err := setQuota(ctx, &teamId, nil, quota); err != nil {
+		msg := `failed to update quota`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	newAuditLog(c, auditTeamQuotaUpdated).
+		inTeam(teamId).
+		on(auditTargetTeam, teamId.String()).
+		change(prevQuota.fields(), quota.fields()).
+		record(ctx)
+
+	c.JSON(http.StatusOK, gin.H{"ok": "done"})
+}
+
+func GetAppQuota(c *gin.Context) {
+	ctx := c.Request.Context()
+	appId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `app id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if !authzApp(c, appId, *ScopeAppRead) {
+		return
+	}
+
+	report, err := newQuotaReport(ctx, quotaScopeApp, nil, &appId)
+	if err != nil {
+		msg := fmt.Sprintf("failed to fetch quota for app: %s", appId)
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+func UpdateAppQuota(c *gin.Context) {
+	ctx := c.Request.Context()
+	appId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `app id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	app := App{
+		ID: &appId,
+	}
+
+	if !authzApp(c, appId, *ScopeBillingAll) {
+		return
+	}
+
+	team, err := app.getTeam(ctx)
+	if err != nil {
+		msg := "failed to get team from app id"
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+	if team == nil {
+		msg := fmt.Sprintf("no team exists for app [%s]", app.ID)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	var quota Quota
+	if err := c.ShouldBindJSON(&quota); err != nil {
+		msg := `failed to parse quota json payload`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if err := quota.validate(); err != nil {
+		msg := `quota validation failed`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   msg,
+			"details": err.Error(),
+		})
+		return
+	}
+
+	prevQuota, err := getQuota(ctx, nil, &appId)
+	if err != nil {
+		msg := `failed to update quota`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	if err := setQuota(ctx, nil, &appId, quota); err != nil {
+		msg := `failed to update quota`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	newAuditLog(c, auditAppQuotaUpdated).
+		inTeam(*team.ID).
+		on(auditTargetApp, appId.String()).
+		change(prevQuota.fields(), quota.fields()).
+		record(ctx)
+
+	c.JSON(http.StatusOK, gin.H{"ok": "done"})
+}
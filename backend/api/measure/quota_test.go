@@ -0,0 +1,149 @@
+package measure
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func int64Ptr(n int64) *int64 {
+	return &n
+}
+
+func TestQuotaExceeds(t *testing.T) {
+	quota := Quota{
+		MaxEvents:   int64Ptr(100),
+		MaxSessions: int64Ptr(10),
+	}
+
+	{
+		kind, ok := quota.exceeds(Usage{Events: 90, Sessions: 5}, Usage{Events: 10, Sessions: 1})
+		if ok {
+			t.Errorf("Expected no quota to be exceeded, but got %q", kind)
+		}
+	}
+
+	{
+		kind, ok := quota.exceeds(Usage{Events: 90, Sessions: 5}, Usage{Events: 11})
+		if !ok || kind != quotaEvents {
+			t.Errorf("Expected %q quota to be exceeded, but got %q", quotaEvents, kind)
+		}
+	}
+
+	// known sessions can continue
+	{
+		kind, ok := quota.exceeds(Usage{Events: 50, Sessions: 10}, Usage{Events: 10})
+		if ok {
+			t.Errorf("Expected no quota to be exceeded, but got %q", kind)
+		}
+	}
+
+	{
+		kind, ok := quota.exceeds(Usage{Events: 50, Sessions: 10}, Usage{Events: 10, Sessions: 1})
+		if !ok || kind != quotaSessions {
+			t.Errorf("Expected %q quota to be exceeded, but got %q", quotaSessions, kind)
+		}
+	}
+
+	// unlimited attachment bytes
+	{
+		kind, ok := quota.exceeds(Usage{AttachmentBytes: 1 << 40}, Usage{Events: 1, AttachmentBytes: 1 << 30})
+		if ok {
+			t.Errorf("Expected no quota to be exceeded, but got %q", kind)
+		}
+	}
+}
+
+func TestQuotaCounterConditions(t *testing.T) {
+	quota := Quota{
+		MaxEvents:          int64Ptr(100),
+		MaxSessions:        int64Ptr(10),
+		MaxAttachmentBytes: int64Ptr(1000),
+	}
+
+	conds, args := quota.counterConditions(Usage{Events: 10, AttachmentBytes: 100})
+
+	expected := "usage_counters.events + excluded.events <= ? and usage_counters.attachment_bytes + excluded.attachment_bytes <= ?"
+	if got := strings.Join(conds, " and "); got != expected {
+		t.Errorf("Expected conditions %q, but got %q", expected, got)
+	}
+
+	if len(args) != 2 || args[0] != int64(100) || args[1] != int64(1000) {
+		t.Errorf("Expected args [100 1000], but got %v", args)
+	}
+
+	// unlimited quotas raise counters
+	// unconditionally
+	if conds, _ := (Quota{}).counterConditions(Usage{Events: 10, Sessions: 1}); len(conds) != 0 {
+		t.Errorf("Expected no conditions, but got %v", conds)
+	}
+}
+
+func TestQuotaExceededError(t *testing.T) {
+	err := quotaExceededError{quotaScopeTeam, quotaAttachmentBytes}
+
+	expected := "monthly attachment bytes quota of the team exceeded"
+	if err.Error() != expected {
+		t.Errorf("Expected error %q, but got %q", expected, err.Error())
+	}
+}
+
+func TestQuotaWarnings(t *testing.T) {
+	quota := Quota{
+		MaxEvents:          int64Ptr(100),
+		MaxSessions:        int64Ptr(10),
+		MaxAttachmentBytes: int64Ptr(1000),
+	}
+
+	warnings := quota.warnings(quotaScopeApp, Usage{Events: 79, Sessions: 8, AttachmentBytes: 1000})
+
+	if len(warnings) != 2 {
+		t.Fatalf("Expected %d warnings, but got %d", 2, len(warnings))
+	}
+
+	if warnings[0].Kind != quotaSessions || warnings[0].Exceeded {
+		t.Errorf("Expected a %q warning not exceeded, but got %+v", quotaSessions, warnings[0])
+	}
+
+	if warnings[1].Kind != quotaAttachmentBytes || !warnings[1].Exceeded {
+		t.Errorf("Expected a %q warning exceeded, but got %+v", quotaAttachmentBytes, warnings[1])
+	}
+
+	if warnings[1].Scope != quotaScopeApp || warnings[1].Limit != 1000 || warnings[1].Used != 1000 {
+		t.Errorf("Expected app warning of 1000 used out of 1000, but got %+v", warnings[1])
+	}
+
+	if warnings := (Quota{}).warnings(quotaScopeTeam, Usage{Events: 1 << 40}); warnings != nil {
+		t.Errorf("Expected no warnings, but got %+v", warnings)
+	}
+}
+
+func TestQuotaValidate(t *testing.T) {
+	if err := (Quota{MaxEvents: int64Ptr(0)}).validate(); err != nil {
+		t.Errorf("Expected nil error, but got %v", err)
+	}
+
+	if err := (Quota{MaxSessions: int64Ptr(-1)}).validate(); err == nil {
+		t.Errorf("Expected error, but got nil")
+	}
+}
+
+func TestQuotaRetryAfter(t *testing.T) {
+	now := time.Date(2024, time.February, 29, 23, 0, 0, 0, time.UTC)
+
+	if month := usageMonth(now); !month.Equal(time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected February 2024, but got %v", month)
+	}
+
+	if wait := quotaRetryAfter(now); wait != time.Hour {
+		t.Errorf("Expected %v, but got %v", time.Hour, wait)
+	}
+
+	// months are in utc
+	ist := time.FixedZone("IST", 5*60*60+30*60)
+	local := time.Date(2024, time.March, 1, 2, 0, 0, 0, ist)
+
+	if month := usageMonth(local); !month.Equal(time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected February 2024, but got %v", month)
+	}
+}
@@ -0,0 +1,237 @@
+package measure
+
+import (
+	"backend/api/event"
+	"backend/api/filter"
+	"backend/api/metrics"
+	"backend/api/server"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/leporo/sqlf"
+)
+
+// filterSpans applies the app filters to
+// a statement querying span events.
+func filterSpans(stmt *sqlf.Stmt, af *filter.AppFilter) {
+	filterEvents(stmt, af)
+
+	stmt.Where("type = ?", event.TypeSpan)
+
+	if af.SpanName != "" {
+		stmt.Where("span.name = ?", af.SpanName)
+	}
+
+	if af.FreeText != "" {
+		stmt.Where("span.name ILIKE ?", "%"+af.FreeText+"%")
+	}
+}
+
+// GetSpanMetrics computes the count & duration
+// percentiles of spans grouped by span name while
+// respecting all applicable app filters.
+func GetSpanMetrics(ctx context.Context, af *filter.AppFilter) (spanMetrics []metrics.SpanMetric, err error) {
+	stmt := sqlf.
+		From("default.events").
+		Select("span.name as name").
+		Select("count() as count").
+		Select("round(quantile(0.5)(span.duration), 2) as p50").
+		Select("round(quantile(0.9)(span.duration), 2) as p90").
+		Select("round(quantile(0.99)(span.duration), 2) as p99")
+
+	defer stmt.Close()
+
+	filterSpans(stmt, af)
+
+	stmt.
+		GroupBy("span.name").
+		OrderBy("count desc, name")
+
+	rows, err := server.Server.ChPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	for rows.Next() {
+		var spanMetric metrics.SpanMetric
+		if err = rows.Scan(&spanMetric.Name, &spanMetric.Count, &spanMetric.P50, &spanMetric.P90, &spanMetric.P99); err != nil {
+			return
+		}
+
+		spanMetrics = append(spanMetrics, spanMetric)
+	}
+
+	err = rows.Err()
+
+	return
+}
+
+// GetSpanPlotPercentiles queries duration percentiles of
+// spans of a name by datetime and filters.
+func GetSpanPlotPercentiles(ctx context.Context, af *filter.AppFilter) (spanInstances []event.SpanInstance, err error) {
+	if af.Timezone == "" {
+		return nil, errors.New("missing timezone filter")
+	}
+
+	stmt := sqlf.
+		From("default.events").
+		Select("formatDateTime(timestamp, '%Y-%m-%d', ?) as datetime", af.Timezone).
+		Select("concat(toString(attribute.app_version), '', '(', toString(attribute.app_build), ')') as app_version").
+		Select("round(quantile(0.5)(span.duration), 2) as p50").
+		Select("round(quantile(0.9)(span.duration), 2) as p90").
+		Select("round(quantile(0.99)(span.duration), 2) as p99")
+
+	defer stmt.Close()
+
+	filterSpans(stmt, af)
+
+	stmt.
+		GroupBy("app_version, datetime").
+		OrderBy("app_version, datetime")
+
+	rows, err := server.Server.ChPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	for rows.Next() {
+		var instance event.SpanInstance
+		if err = rows.Scan(&instance.DateTime, &instance.Version, &instance.P50, &instance.P90, &instance.P99); err != nil {
+			return
+		}
+
+		spanInstances = append(spanInstances, instance)
+	}
+
+	err = rows.Err()
+
+	return
+}
+
+// GetSpans provides the count & duration
+// percentiles of an app's spans by span name.
+func GetSpans(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	af := filter.AppFilter{
+		AppID: id,
+		Limit: filter.DefaultPaginationLimit,
+	}
+
+	if !parseEventFilter(c, &af, `spans request validation failed`) {
+		return
+	}
+
+	if !authzApp(c, id, *ScopeAppRead) {
+		return
+	}
+
+	spanMetrics, err := GetSpanMetrics(ctx, &af)
+	if err != nil {
+		msg := `failed to query span metrics`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	if spanMetrics == nil {
+		spanMetrics = []metrics.SpanMetric{}
+	}
+
+	c.JSON(http.StatusOK, spanMetrics)
+}
+
+// GetSpansPlotPercentiles provides the daily
+// duration percentiles of an app's spans of
+// a name, by app version.
+func GetSpansPlotPercentiles(c *gin.Context) {
+	ctx := c.Request.Context()
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	af := filter.AppFilter{
+		AppID: id,
+		Limit: filter.DefaultPaginationLimit,
+	}
+
+	msg := `spans plot percentiles request validation failed`
+
+	if !parseEventFilter(c, &af, msg) {
+		return
+	}
+
+	if af.SpanName == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   msg,
+			"details": fmt.Sprintf(`%q is required`, `span_name`),
+		})
+		return
+	}
+
+	if !authzApp(c, id, *ScopeAppRead) {
+		return
+	}
+
+	spanInstances, err := GetSpanPlotPercentiles(ctx, &af)
+	if err != nil {
+		msg := `failed to query data for spans plot`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": msg,
+		})
+		return
+	}
+
+	type instance struct {
+		ID   string  `json:"id"`
+		Data []gin.H `json:"data"`
+	}
+
+	lut := make(map[string]int)
+	instances := []instance{}
+
+	for i := range spanInstances {
+		instance := instance{
+			ID: spanInstances[i].Version,
+			Data: []gin.H{{
+				"datetime": spanInstances[i].DateTime,
+				"p50":      spanInstances[i].P50,
+				"p90":      spanInstances[i].P90,
+				"p99":      spanInstances[i].P99,
+			}},
+		}
+
+		ndx, ok := lut[spanInstances[i].Version]
+
+		if ok {
+			instances[ndx].Data = append(instances[ndx].Data, instance.Data...)
+		} else {
+			instances = append(instances, instance)
+			lut[spanInstances[i].Version] = len(instances) - 1
+		}
+	}
+
+	c.JSON(http.StatusOK, instances)
+}
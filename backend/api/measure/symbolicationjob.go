@@ -0,0 +1,699 @@
+package measure
+
+import (
+	"backend/api/event"
+	"backend/api/platform"
+	"backend/api/server"
+	"backend/api/symbol"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/leporo/sqlf"
+)
+
+const (
+	// SymbolicationStatusQueued is the status of a job
+	// waiting to be processed.
+	SymbolicationStatusQueued = "queued"
+
+	// SymbolicationStatusProcessing is the status of a
+	// job being processed by the worker.
+	SymbolicationStatusProcessing = "processing"
+
+	// SymbolicationStatusSucceeded is the status of a
+	// job whose events were symbolicated.
+	SymbolicationStatusSucceeded = "succeeded"
+
+	// SymbolicationStatusFailed is the status of a job
+	// that failed after exhausting all attempts.
+	SymbolicationStatusFailed = "failed"
+)
+
+// maxSymbolicationAttempts is the maximum number of
+// times a job is attempted before giving up.
+const maxSymbolicationAttempts = 3
+
+// symbolicationLease is the duration a claimed job is
+// held by the worker. Jobs of crashed workers are
+// picked up again once the lease expires.
+const symbolicationLease = 30 * time.Minute
+
+// symbolicationPollInterval is the interval at which
+// the idle worker looks for due jobs.
+const symbolicationPollInterval = 10 * time.Second
+
+// symbolicationPageSize is the number of events
+// symbolicated at once.
+const symbolicationPageSize = 500
+
+// symbolicationSignal wakes up the idle worker
+// when a new job is enqueued.
+var symbolicationSignal = make(chan struct{}, 1)
+
+// symbolicationJob represents the symbolication of
+// events that were ingested before the mapping file
+// of their app version was uploaded.
+type symbolicationJob struct {
+	ID           uuid.UUID
+	AppID        uuid.UUID
+	VersionName  string
+	VersionCode  string
+	MappingType  string
+	Attempts     int
+	Symbolicated int
+}
+
+// enqueueSymbolication queues symbolication of the events
+// of the mapping's app version which arrived before the
+// mapping file. Does nothing if a job for the same app
+// version is already queued.
+func (bm BuildMapping) enqueueSymbolication(ctx context.Context, tx pgx.Tx) (err error) {
+	// native crashes are matched by build
+	// id, not by app version
+	if bm.MappingType != symbol.TypeProguard && bm.MappingType != symbol.TypeDsym {
+		return
+	}
+
+	now := time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		InsertInto("public.symbolication_jobs").
+		Set("id", uuid.New()).
+		Set("app_id", bm.AppID).
+		Set("version_name", bm.VersionName).
+		Set("version_code", bm.VersionCode).
+		Set("mapping_type", bm.MappingType).
+		Set("status", SymbolicationStatusQueued).
+		Set("next_attempt_at", now).
+		Set("created_at", now).
+		Set("updated_at", now).
+		Clause("on conflict (app_id, version_name, version_code, mapping_type) where status = 'queued' do nothing", nil)
+
+	defer stmt.Close()
+
+	_, err = tx.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// notifySymbolication wakes up the idle worker,
+// if it isn't waiting, skip.
+func notifySymbolication() {
+	select {
+	case symbolicationSignal <- struct{}{}:
+	default:
+	}
+}
+
+// claimSymbolicationJob claims the next due job by
+// leasing it to the caller. Returns nil if no job
+// is due.
+func claimSymbolicationJob(ctx context.Context) (job *symbolicationJob, err error) {
+	now := time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.symbolication_jobs").
+		Set("status", SymbolicationStatusProcessing).
+		SetExpr("attempts", "attempts + 1").
+		Set("next_attempt_at", now.Add(symbolicationLease)).
+		Set("updated_at", now).
+		Where("id = (select id from public.symbolication_jobs where status in (?, ?) and next_attempt_at <= ? order by next_attempt_at limit 1 for update skip locked)", SymbolicationStatusQueued, SymbolicationStatusProcessing, now).
+		Returning("id").
+		Returning("app_id").
+		Returning("version_name").
+		Returning("version_code").
+		Returning("mapping_type").
+		Returning("attempts").
+		Returning("symbolicated_count")
+
+	defer stmt.Close()
+
+	var j symbolicationJob
+
+	if err = server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&j.ID, &j.AppID, &j.VersionName, &j.VersionCode, &j.MappingType, &j.Attempts, &j.Symbolicated); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return
+	}
+
+	job = &j
+
+	return
+}
+
+// platform provides the platform of the
+// events symbolicated by the job's mapping.
+func (j symbolicationJob) platform() string {
+	if j.MappingType == symbol.TypeDsym {
+		return platform.IOS
+	}
+	return platform.Android
+}
+
+// unsymbolicatedEvents fetches the next page of exception
+// & ANR events of the job's app version that were not
+// symbolicated, ordered by id.
+func (j symbolicationJob) unsymbolicatedEvents(ctx context.Context, after uuid.UUID) (events []event.EventField, err error) {
+	stmt := sqlf.
+		From("default.events").
+		Select("id").
+		Select("toString(type)").
+		Select("session_id").
+		Select("timestamp").
+		Select("toString(attribute.app_version)").
+		Select("toString(attribute.app_build)").
+		Select("toString(attribute.platform)").
+		Select("toString(attribute.thread_name)").
+		Select("exception.handled").
+		Select("exception.foreground").
+		Select("exception.exceptions").
+		Select("exception.threads").
+		Select("exception.fingerprint").
+		Select("anr.handled").
+		Select("anr.foreground").
+		Select("anr.exceptions").
+		Select("anr.threads").
+		Select("anr.fingerprint").
+		Where("app_id = ?", j.AppID).
+		Where("attribute.app_version = ?", j.VersionName).
+		Where("attribute.app_build = ?", j.VersionCode).
+		Where("attribute.platform = ?", j.platform()).
+		Where("((type = ? and exception.symbolicated = false) or (type = ? and anr.symbolicated = false))", event.TypeException, event.TypeANR).
+		Where("id > ?", after).
+		OrderBy("id").
+		Limit(symbolicationPageSize)
+
+	defer stmt.Close()
+
+	rows, err := server.Server.ChPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var ev event.EventField
+		var exception event.Exception
+		var anr event.ANR
+		var exceptionExceptions, exceptionThreads string
+		var anrExceptions, anrThreads string
+
+		if err = rows.Scan(
+			&ev.ID,
+			&ev.Type,
+			&ev.SessionID,
+			&ev.Timestamp,
+			&ev.Attribute.AppVersion,
+			&ev.Attribute.AppBuild,
+			&ev.Attribute.Platform,
+			&ev.Attribute.ThreadName,
+			&exception.Handled,
+			&exception.Foreground,
+			&exceptionExceptions,
+			&exceptionThreads,
+			&exception.Fingerprint,
+			&anr.Handled,
+			&anr.Foreground,
+			&anrExceptions,
+			&anrThreads,
+			&anr.Fingerprint,
+		); err != nil {
+			return
+		}
+
+		ev.AppID = j.AppID
+
+		switch ev.Type {
+		case event.TypeException:
+			if err = json.Unmarshal([]byte(exceptionExceptions), &exception.Exceptions); err != nil {
+				return
+			}
+			if err = json.Unmarshal([]byte(exceptionThreads), &exception.Threads); err != nil {
+				return
+			}
+			ev.Exception = &exception
+		case event.TypeANR:
+			if err = json.Unmarshal([]byte(anrExceptions), &anr.Exceptions); err != nil {
+				return
+			}
+			if err = json.Unmarshal([]byte(anrThreads), &anr.Threads); err != nil {
+				return
+			}
+			ev.ANR = &anr
+		}
+
+		events = append(events, ev)
+	}
+
+	err = rows.Err()
+
+	return
+}
+
+// fingerprintMove represents events of a group
+// table moving from one fingerprint to another
+// after symbolication.
+type fingerprintMove struct {
+	table string
+	from  string
+	to    string
+}
+
+// fingerprints provides the fingerprints of the
+// exception & ANR events keyed by event id.
+func fingerprints(events []event.EventField) (fingerprints map[uuid.UUID]string) {
+	fingerprints = make(map[uuid.UUID]string, len(events))
+
+	for _, ev := range events {
+		switch {
+		case ev.IsException():
+			fingerprints[ev.ID] = ev.Exception.Fingerprint
+		case ev.IsANR():
+			fingerprints[ev.ID] = ev.ANR.Fingerprint
+		}
+	}
+
+	return
+}
+
+// fingerprintMoves provides the distinct moves of the
+// symbolicated events from their previous fingerprints
+// to their new fingerprints.
+func fingerprintMoves(events []event.EventField, previous map[uuid.UUID]string) (moves []fingerprintMove) {
+	seen := make(map[fingerprintMove]struct{})
+
+	for _, ev := range events {
+		var move fingerprintMove
+
+		switch {
+		case ev.IsUnhandledException():
+			move = fingerprintMove{table: "public.unhandled_exception_groups", to: ev.Exception.Fingerprint}
+		case ev.IsANR():
+			move = fingerprintMove{table: "public.anr_groups", to: ev.ANR.Fingerprint}
+		default:
+			continue
+		}
+
+		move.from = previous[ev.ID]
+
+		if move.from == "" || move.from == move.to {
+			continue
+		}
+
+		if _, ok := seen[move]; ok {
+			continue
+		}
+
+		seen[move] = struct{}{}
+		moves = append(moves, move)
+	}
+
+	return
+}
+
+// mergeStaleGroup merges the group of the move's previous
+// fingerprint into the group of its new fingerprint, so
+// that the group left behind by symbolicated events
+// doesn't linger. Groups merged into the stale group
+// move along, so that merges never chain.
+func (j symbolicationJob) mergeStaleGroup(ctx context.Context, tx *pgx.Tx, move fingerprintMove) (err error) {
+	query := fmt.Sprintf("select stale.id, coalesce(survivor.merged_into, survivor.id) from %[1]s stale, %[1]s survivor where stale.app_id = $1 and stale.fingerprint = $2 and stale.merged_into is null and survivor.app_id = $1 and survivor.fingerprint = $3", move.table)
+
+	var staleId, survivorId uuid.UUID
+
+	if err = (*tx).QueryRow(ctx, query, j.AppID, move.from, move.to).Scan(&staleId, &survivorId); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return
+	}
+
+	if staleId == survivorId {
+		return
+	}
+
+	stmt := sqlf.PostgreSQL.
+		Update(move.table).
+		Set("merged_into", survivorId).
+		Set("updated_at", time.Now()).
+		Where("app_id = ?", j.AppID).
+		Where("(id = ? or merged_into = ?)", staleId, staleId)
+
+	defer stmt.Close()
+
+	_, err = (*tx).Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// resymbolicate symbolicates the events, recomputes their
+// fingerprints, buckets them into their groups and rewrites
+// the stored events. Groups left behind by the events are
+// merged into their new groups. Returns the number of
+// symbolicated events.
+func (j symbolicationJob) resymbolicate(ctx context.Context, events []event.EventField, rules event.FingerprintRules) (count int, err error) {
+	previous := fingerprints(events)
+
+	e := &eventreq{
+		appId:       j.AppID,
+		symbolicate: make(map[uuid.UUID]int),
+	}
+
+	for i := range events {
+		e.push(events[i])
+	}
+
+	if err = e.symbolicateEvents(ctx); err != nil {
+		return
+	}
+
+	// only events that were symbolicated
+	// are bucketed & rewritten
+	symbolicated := &eventreq{
+		appId:       j.AppID,
+		symbolicate: make(map[uuid.UUID]int),
+		replay:      true,
+	}
+
+	for _, ev := range e.events {
+		if !e.isSymbolicated(ev.ID) {
+			continue
+		}
+
+		var fingerprintErr error
+		switch {
+		case ev.IsException():
+			fingerprintErr = ev.Exception.ComputeExceptionFingerprint(rules)
+		case ev.IsANR():
+			fingerprintErr = ev.ANR.ComputeANRFingerprint(rules)
+		}
+
+		if fingerprintErr != nil {
+			fmt.Printf("failed to compute fingerprint of event %q: %v\n", ev.ID, fingerprintErr)
+			continue
+		}
+
+		symbolicated.push(ev)
+	}
+
+	if len(symbolicated.events) == 0 {
+		return
+	}
+
+	// groups are updated before the events are
+	// rewritten, so that a failed rewrite picks
+	// up the same events again on retry
+	tx, err := server.Server.PgPool.Begin(ctx)
+	if err != nil {
+		return
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err = symbolicated.bucketUnhandledExceptions(ctx, &tx); err != nil {
+		return
+	}
+
+	if err = symbolicated.bucketANRs(ctx, &tx); err != nil {
+		return
+	}
+
+	for _, move := range fingerprintMoves(symbolicated.events, previous) {
+		if err = j.mergeStaleGroup(ctx, &tx, move); err != nil {
+			return
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	if err = j.rewrite(ctx, symbolicated.events); err != nil {
+		return
+	}
+
+	count = len(symbolicated.events)
+
+	return
+}
+
+// rewriteBatch represents the rewritten columns
+// of events of a type, in the order of ids.
+type rewriteBatch struct {
+	ids          []string
+	exceptions   []string
+	threads      []string
+	fingerprints []string
+}
+
+// push adds the columns of an event.
+func (b *rewriteBatch) push(id uuid.UUID, exceptions, threads any, fingerprint string) (err error) {
+	exceptionsJSON, err := json.Marshal(exceptions)
+	if err != nil {
+		return
+	}
+
+	threadsJSON, err := json.Marshal(threads)
+	if err != nil {
+		return
+	}
+
+	b.ids = append(b.ids, id.String())
+	b.exceptions = append(b.exceptions, string(exceptionsJSON))
+	b.threads = append(b.threads, string(threadsJSON))
+	b.fingerprints = append(b.fingerprints, fingerprint)
+
+	return
+}
+
+// rewriteBatches splits the symbolicated events
+// into batches of exception & ANR columns.
+func rewriteBatches(events []event.EventField) (exceptions, anrs rewriteBatch, err error) {
+	for _, ev := range events {
+		switch {
+		case ev.IsException():
+			err = exceptions.push(ev.ID, ev.Exception.Exceptions, ev.Exception.Threads, ev.Exception.Fingerprint)
+		case ev.IsANR():
+			err = anrs.push(ev.ID, ev.ANR.Exceptions, ev.ANR.Threads, ev.ANR.Fingerprint)
+		}
+
+		if err != nil {
+			return
+		}
+	}
+
+	return
+}
+
+// rewrite replaces the stored stacktraces & fingerprints
+// of the symbolicated events and marks them as
+// symbolicated.
+func (j symbolicationJob) rewrite(ctx context.Context, events []event.EventField) (err error) {
+	exceptions, anrs, err := rewriteBatches(events)
+	if err != nil {
+		return
+	}
+
+	if len(exceptions.ids) > 0 {
+		if err = j.mutate(ctx, "exception", exceptions); err != nil {
+			return
+		}
+	}
+
+	if len(anrs.ids) > 0 {
+		err = j.mutate(ctx, "anr", anrs)
+	}
+
+	return
+}
+
+// mutation builds the mutation updating the exceptions,
+// threads & fingerprints of the events' columns of the
+// prefix along with its args.
+func (j symbolicationJob) mutation(prefix string, batch rewriteBatch) (query string, args []any) {
+	query = fmt.Sprintf("alter table default.events update "+
+		"`%[1]s.exceptions` = transform(toString(id), ?, ?, `%[1]s.exceptions`), "+
+		"`%[1]s.threads` = transform(toString(id), ?, ?, `%[1]s.threads`), "+
+		"`%[1]s.fingerprint` = toFixedString(transform(toString(id), ?, ?, toString(`%[1]s.fingerprint`)), 32), "+
+		"`%[1]s.symbolicated` = true "+
+		"where app_id = ? and has(?, toString(id))", prefix)
+
+	args = []any{batch.ids, batch.exceptions, batch.ids, batch.threads, batch.ids, batch.fingerprints, j.AppID, batch.ids}
+
+	return
+}
+
+// mutate updates the events' columns of the
+// prefix in a single mutation.
+func (j symbolicationJob) mutate(ctx context.Context, prefix string, batch rewriteBatch) error {
+	query, args := j.mutation(prefix, batch)
+
+	return server.Server.ChPool.Exec(ctx, query, args...)
+}
+
+// run symbolicates the unsymbolicated events of the
+// job's app version page by page, recording progress
+// after each page.
+func (j *symbolicationJob) run(ctx context.Context) (err error) {
+	rules, err := getFingerprintRules(ctx, j.AppID)
+	if err != nil {
+		return
+	}
+
+	after := uuid.Nil
+
+	for {
+		events, err := j.unsymbolicatedEvents(ctx, after)
+		if err != nil {
+			return err
+		}
+
+		if len(events) == 0 {
+			return nil
+		}
+
+		after = events[len(events)-1].ID
+
+		count, err := j.resymbolicate(ctx, events, rules.FingerprintRules)
+		if err != nil {
+			return err
+		}
+
+		if count == 0 {
+			continue
+		}
+
+		j.Symbolicated += count
+
+		if err := j.progress(ctx); err != nil {
+			return err
+		}
+	}
+}
+
+// progress records the number of events
+// symbolicated so far.
+func (j symbolicationJob) progress(ctx context.Context) (err error) {
+	stmt := sqlf.PostgreSQL.
+		Update("public.symbolication_jobs").
+		Set("symbolicated_count", j.Symbolicated).
+		Set("updated_at", time.Now()).
+		Where("id = ?", j.ID)
+
+	defer stmt.Close()
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// succeed marks the job as succeeded.
+func (j symbolicationJob) succeed(ctx context.Context) (err error) {
+	now := time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.symbolication_jobs").
+		Set("status", SymbolicationStatusSucceeded).
+		Set("error", nil).
+		Set("completed_at", now).
+		Set("updated_at", now).
+		Where("id = ?", j.ID)
+
+	defer stmt.Close()
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// fail records the failed attempt. Schedules a retry
+// with backoff or marks the job as failed once all
+// attempts are exhausted.
+func (j symbolicationJob) fail(ctx context.Context, cause error) (err error) {
+	now := time.Now()
+	msg := cause.Error()
+	if len(msg) > maxIngestErrorChars {
+		msg = msg[:maxIngestErrorChars]
+	}
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.symbolication_jobs").
+		Set("error", msg).
+		Set("updated_at", now)
+
+	defer stmt.Close()
+
+	if j.Attempts >= maxSymbolicationAttempts {
+		stmt.
+			Set("status", SymbolicationStatusFailed).
+			Set("completed_at", now)
+	} else {
+		stmt.
+			Set("status", SymbolicationStatusQueued).
+			Set("next_attempt_at", now.Add(ingestBackoff(j.Attempts)))
+	}
+
+	stmt.Where("id = ?", j.ID)
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// processSymbolicationJob claims and processes the
+// next due job. Returns false if no job was due.
+func processSymbolicationJob(ctx context.Context) (processed bool, err error) {
+	job, err := claimSymbolicationJob(ctx)
+	if err != nil || job == nil {
+		return
+	}
+
+	processed = true
+
+	if runErr := job.run(ctx); runErr != nil {
+		fmt.Printf("failed to symbolicate events of app %q version %q (%s), attempt %d: %v\n", job.AppID, job.VersionName, job.VersionCode, job.Attempts, runErr)
+		err = job.fail(ctx, runErr)
+		return
+	}
+
+	err = job.succeed(ctx)
+
+	return
+}
+
+// StartSymbolicationWorker starts a worker that
+// symbolicates events whose mapping files arrived
+// late until the context is done.
+func StartSymbolicationWorker(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(symbolicationPollInterval)
+		defer ticker.Stop()
+
+		for {
+			processed, err := processSymbolicationJob(ctx)
+			if err != nil {
+				fmt.Println("failed to process symbolication job", err)
+			}
+
+			// drain the queue before
+			// waiting for more jobs
+			if processed {
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-symbolicationSignal:
+			case <-ticker.C:
+			}
+		}
+	}()
+}
@@ -0,0 +1,232 @@
+package measure
+
+import (
+	"backend/api/event"
+	"backend/api/group"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+func newTestException(fingerprint string, handled bool) event.EventField {
+	return event.EventField{
+		ID:   uuid.New(),
+		Type: event.TypeException,
+		Exception: &event.Exception{
+			Handled:     handled,
+			Exceptions:  event.ExceptionUnits{{Type: "java.lang.IllegalStateException", Message: "boom"}},
+			Fingerprint: fingerprint,
+		},
+	}
+}
+
+func newTestANR(fingerprint string) event.EventField {
+	return event.EventField{
+		ID:   uuid.New(),
+		Type: event.TypeANR,
+		ANR: &event.ANR{
+			Exceptions:  event.ExceptionUnits{{Type: "sh.measure.android.anr.AndroidNotRespondingException"}},
+			Fingerprint: fingerprint,
+		},
+	}
+}
+
+func TestRewriteBatches(t *testing.T) {
+	exception := newTestException("exception-fingerprint", false)
+	anr := newTestANR("anr-fingerprint")
+
+	exceptions, anrs, err := rewriteBatches([]event.EventField{exception, anr})
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if len(exceptions.ids) != 1 || exceptions.ids[0] != exception.ID.String() {
+		t.Errorf("Expected exception batch with %q, but got %v", exception.ID, exceptions.ids)
+	}
+
+	if len(anrs.ids) != 1 || anrs.ids[0] != anr.ID.String() {
+		t.Errorf("Expected anr batch with %q, but got %v", anr.ID, anrs.ids)
+	}
+
+	if exceptions.fingerprints[0] != "exception-fingerprint" || anrs.fingerprints[0] != "anr-fingerprint" {
+		t.Errorf("Expected new fingerprints, but got %v and %v", exceptions.fingerprints, anrs.fingerprints)
+	}
+
+	var units event.ExceptionUnits
+	if err := json.Unmarshal([]byte(exceptions.exceptions[0]), &units); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if len(units) != 1 || units[0].Type != "java.lang.IllegalStateException" {
+		t.Errorf("Expected exception units to be encoded, but got %v", exceptions.exceptions[0])
+	}
+}
+
+func TestSymbolicationMutation(t *testing.T) {
+	job := symbolicationJob{AppID: uuid.New()}
+	batch := rewriteBatch{
+		ids:          []string{"a", "b"},
+		exceptions:   []string{"[1]", "[2]"},
+		threads:      []string{"[3]", "[4]"},
+		fingerprints: []string{"f1", "f2"},
+	}
+
+	query, args := job.mutation("anr", batch)
+
+	for _, column := range []string{"`anr.exceptions`", "`anr.threads`", "`anr.fingerprint`", "`anr.symbolicated` = true"} {
+		if !strings.Contains(query, column) {
+			t.Errorf("Expected mutation to update %s, but got %q", column, query)
+		}
+	}
+
+	if strings.Contains(query, "exception.") {
+		t.Errorf("Expected mutation to only update anr columns, but got %q", query)
+	}
+
+	if placeholders := strings.Count(query, "?"); placeholders != len(args) {
+		t.Fatalf("Expected %d args for %d placeholders, but got %d", placeholders, placeholders, len(args))
+	}
+
+	// each transform maps ids to the
+	// column's new values
+	pairs := [][]string{batch.exceptions, batch.threads, batch.fingerprints}
+	for i, values := range pairs {
+		if ids, ok := args[i*2].([]string); !ok || len(ids) != len(batch.ids) || ids[0] != "a" {
+			t.Errorf("Expected ids at arg %d, but got %v", i*2, args[i*2])
+		}
+		if got, ok := args[i*2+1].([]string); !ok || got[0] != values[0] || got[1] != values[1] {
+			t.Errorf("Expected %v at arg %d, but got %v", values, i*2+1, args[i*2+1])
+		}
+	}
+
+	if args[6] != job.AppID {
+		t.Errorf("Expected app id %q, but got %v", job.AppID, args[6])
+	}
+
+	if ids, ok := args[7].([]string); !ok || len(ids) != 2 {
+		t.Errorf("Expected ids to filter the mutation, but got %v", args[7])
+	}
+}
+
+func TestFingerprintMoves(t *testing.T) {
+	first := newTestException("symbolicated", false)
+	second := newTestException("symbolicated", false)
+	unchanged := newTestException("unchanged", false)
+	handled := newTestException("symbolicated", true)
+	anr := newTestANR("symbolicated-anr")
+	unknown := newTestException("symbolicated", false)
+
+	previous := map[uuid.UUID]string{
+		first.ID:     "obfuscated",
+		second.ID:    "obfuscated",
+		unchanged.ID: "unchanged",
+		handled.ID:   "obfuscated-handled",
+		anr.ID:       "obfuscated-anr",
+	}
+
+	moves := fingerprintMoves([]event.EventField{first, second, unchanged, handled, anr, unknown}, previous)
+
+	expected := []fingerprintMove{
+		{table: "public.unhandled_exception_groups", from: "obfuscated", to: "symbolicated"},
+		{table: "public.anr_groups", from: "obfuscated-anr", to: "symbolicated-anr"},
+	}
+
+	if len(moves) != len(expected) {
+		t.Fatalf("Expected %d moves, but got %d: %v", len(expected), len(moves), moves)
+	}
+
+	for i := range expected {
+		if moves[i] != expected[i] {
+			t.Errorf("Expected move %v, but got %v", expected[i], moves[i])
+		}
+	}
+
+	if got := fingerprints([]event.EventField{first, anr}); got[first.ID] != "symbolicated" || got[anr.ID] != "symbolicated-anr" {
+		t.Errorf("Expected fingerprints of events, but got %v", got)
+	}
+}
+
+func TestMergeStaleGroup(t *testing.T) {
+	job := symbolicationJob{AppID: uuid.New()}
+	move := fingerprintMove{table: "public.anr_groups", from: "obfuscated", to: "symbolicated"}
+	staleId := uuid.New()
+	survivorId := uuid.New()
+
+	var tx pgx.Tx = &recordingTx{row: []uuid.UUID{staleId, survivorId}}
+
+	if err := job.mergeStaleGroup(context.Background(), &tx, move); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	executions := tx.(*recordingTx).executions
+	if len(executions) != 2 {
+		t.Fatalf("Expected %d statements, but got %d", 2, len(executions))
+	}
+
+	lookup := executions[0]
+	if !strings.Contains(lookup.sql, "from public.anr_groups stale, public.anr_groups survivor") {
+		t.Errorf("Expected lookup of anr groups, but got %q", lookup.sql)
+	}
+
+	if lookup.args[0] != job.AppID || lookup.args[1] != "obfuscated" || lookup.args[2] != "symbolicated" {
+		t.Errorf("Expected lookup of the move's fingerprints, but got %v", lookup.args)
+	}
+
+	// the stale group & groups merged into
+	// it move to the surviving group
+	merge := executions[1]
+	if !strings.HasPrefix(merge.sql, "UPDATE public.anr_groups SET merged_into=$1") || merge.args[0] != survivorId {
+		t.Errorf("Expected merge into %q, but got %q with %v", survivorId, merge.sql, merge.args)
+	}
+
+	if merge.args[3] != staleId || merge.args[4] != staleId {
+		t.Errorf("Expected merge of stale group %q, but got %v", staleId, merge.args)
+	}
+
+	// nothing to merge when either
+	// group doesn't exist
+	tx = &recordingTx{}
+	if err := job.mergeStaleGroup(context.Background(), &tx, move); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if executions := tx.(*recordingTx).executions; len(executions) != 1 {
+		t.Errorf("Expected only the lookup, but got %d statements", len(executions))
+	}
+
+	// events already belonged to
+	// the surviving group
+	tx = &recordingTx{row: []uuid.UUID{survivorId, survivorId}}
+	if err := job.mergeStaleGroup(context.Background(), &tx, move); err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if executions := tx.(*recordingTx).executions; len(executions) != 1 {
+		t.Errorf("Expected only the lookup, but got %d statements", len(executions))
+	}
+}
+
+func TestEventReqReopens(t *testing.T) {
+	resolved := group.Lifecycle{Status: group.StatusResolved}
+	ev := newTestException("symbolicated", false)
+	ev.Attribute.AppVersion = "1.0.0"
+	ev.Attribute.AppBuild = "100"
+
+	if !(eventreq{}).reopens(resolved, ev) {
+		t.Error("Expected new event to reopen group resolved without a version")
+	}
+
+	// replayed events arrived before
+	// the group was resolved
+	if (eventreq{replay: true}).reopens(resolved, ev) {
+		t.Error("Expected replayed event not to reopen resolved group")
+	}
+
+	if (eventreq{}).reopens(group.Lifecycle{Status: group.StatusOpen}, ev) {
+		t.Error("Expected event not to reopen open group")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"slices"
 	"strings"
 	"time"
+	"unicode"
 
 	"backend/api/chrono"
 	"backend/api/server"
@@ -20,6 +21,21 @@ import (
 
 const maxInvitees = 25
 
+// checkName checks that a team or app name isn't empty
+// & has no control characters. Names end up in email
+// subjects, where line breaks would inject headers.
+func checkName(kind, name string) error {
+	if name == "" {
+		return fmt.Errorf("%s name cannot be empty", kind)
+	}
+
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return fmt.Errorf("%s name cannot contain control characters", kind)
+	}
+
+	return nil
+}
+
 type Team struct {
 	ID   *uuid.UUID `json:"id"`
 	Name *string    `json:"name"`
@@ -61,6 +77,8 @@ func (t *Team) getApps() ([]App, error) {
 		Select(`apps.first_version`, nil).
 		Select(`apps.onboarded`, nil).
 		Select(`apps.onboarded_at`, nil).
+		Select(`api_keys.id`, nil).
+		Select(`api_keys.name`, nil).
 		Select(`api_keys.key_prefix`, nil).
 		Select(`api_keys.key_value`, nil).
 		Select(`api_keys.checksum`, nil).
@@ -69,8 +87,9 @@ func (t *Team) getApps() ([]App, error) {
 		Select(`apps.created_at`, nil).
 		Select(`apps.updated_at`, nil).
 		From(`public.apps`).
-		LeftJoin(`public.api_keys`, `api_keys.app_Id = apps.id`).
+		LeftJoin(activeAPIKeyJoin, `true`).
 		Where(`apps.team_id = ?`, nil).
+		Where(`apps.deleted_at is null`).
 		OrderBy(`apps.app_name`)
 
 	defer stmt.Close()
@@ -87,12 +106,14 @@ func (t *Team) getApps() ([]App, error) {
 		var platform pgtype.Text
 		var firstVersion pgtype.Text
 		var onboardedAt pgtype.Timestamptz
+		var apiKeyId *uuid.UUID
+		var apiKeyName pgtype.Text
 		var apiKeyLastSeen pgtype.Timestamptz
 		var apiKeyCreatedAt pgtype.Timestamptz
 
 		apiKey := new(APIKey)
 
-		if err := rows.Scan(&a.ID, &a.AppName, &a.TeamId, &uniqueId, &platform, &firstVersion, &a.Onboarded, &onboardedAt, &apiKey.keyPrefix, &apiKey.keyValue, &apiKey.checksum, &apiKeyLastSeen, &apiKeyCreatedAt, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		if err := rows.Scan(&a.ID, &a.AppName, &a.TeamId, &uniqueId, &platform, &firstVersion, &a.Onboarded, &onboardedAt, &apiKeyId, &apiKeyName, &apiKey.keyPrefix, &apiKey.keyValue, &apiKey.checksum, &apiKeyLastSeen, &apiKeyCreatedAt, &a.CreatedAt, &a.UpdatedAt); err != nil {
 			return nil, err
 		}
 
@@ -118,6 +139,15 @@ func (t *Team) getApps() ([]App, error) {
 			a.OnboardedAt = onboardedAt.Time
 		}
 
+		if apiKeyId != nil {
+			apiKey.id = *apiKeyId
+			apiKey.appId = *a.ID
+		}
+
+		if apiKeyName.Valid {
+			apiKey.name = apiKeyName.String
+		}
+
 		if apiKeyLastSeen.Valid {
 			apiKey.lastSeen = apiKeyLastSeen.Time
 		}
@@ -169,6 +199,20 @@ func (t *Team) getMembers() ([]*Member, error) {
 	return members, nil
 }
 
+// getName fetches the name of the team.
+func (t *Team) getName(ctx context.Context) (name string, err error) {
+	stmt := sqlf.PostgreSQL.
+		Select("name").
+		From("public.teams").
+		Where("id = ?", t.ID)
+
+	defer stmt.Close()
+
+	err = server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&name)
+
+	return
+}
+
 func (t *Team) rename() error {
 	stmt := sqlf.PostgreSQL.Update("teams").
 		Set("name", nil).
@@ -209,6 +253,31 @@ func (t *Team) addMembers(invitees []Invitee) error {
 	return nil
 }
 
+// addMember makes the user a member of
+// the team with the role.
+func (t *Team) addMember(ctx context.Context, userId uuid.UUID, role rank, tx *pgx.Tx) (err error) {
+	now := time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		InsertInto("public.team_membership").
+		Set("team_id", t.ID).
+		Set("user_id", userId).
+		Set("role", role.String()).
+		Set("role_updated_at", now).
+		Set("created_at", now)
+
+	defer stmt.Close()
+
+	if tx != nil {
+		_, err = (*tx).Exec(ctx, stmt.String(), stmt.Args()...)
+		return
+	}
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
 func (t *Team) removeMember(memberId *uuid.UUID) error {
 	stmt := sqlf.PostgreSQL.DeleteFrom("team_membership").
 		Where("team_id = ?", nil).
@@ -223,6 +292,17 @@ func (t *Team) removeMember(memberId *uuid.UUID) error {
 		return err
 	}
 
+	// role overrides for the team's apps
+	// must not outlive the membership
+	appRolesStmt := sqlf.PostgreSQL.DeleteFrom("public.app_roles").
+		Where("user_id = ?", memberId).
+		Where("app_id in (select id from public.apps where team_id = ?)", t.ID)
+	defer appRolesStmt.Close()
+
+	if _, err := server.Server.PgPool.Exec(ctx, appRolesStmt.String(), appRolesStmt.Args()...); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -331,16 +411,20 @@ func CreateTeam(c *gin.Context) {
 		return
 	}
 
+	if newTeam.Name == nil {
+		newTeam.Name = new(string)
+	}
+
 	// trim team name value
 	*newTeam.Name = strings.Trim(*newTeam.Name, " ")
 
-	if *newTeam.Name == "" {
-		msg := "team name cannot be empty"
+	if err := checkName("team", *newTeam.Name); err != nil {
+		msg := err.Error()
 		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
 		return
 	}
 
-	ok, err := PerformAuthz(userId, ownTeam.ID.String(), *ScopeTeamAll)
+	ok, err := PerformAuthz(c, userId, ownTeam.ID.String(), *ScopeTeamAll)
 	if err != nil {
 		msg := `couldn't perform authorization checks`
 		fmt.Println(msg, err)
@@ -422,7 +506,7 @@ func GetTeamApps(c *gin.Context) {
 		return
 	}
 
-	if ok, err := PerformAuthz(userId, teamId.String(), *ScopeTeamRead); err != nil {
+	if ok, err := PerformAuthz(c, userId, teamId.String(), *ScopeTeamRead); err != nil {
 		msg := `couldn't perform authorization checks`
 		fmt.Println(msg, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
@@ -433,7 +517,7 @@ func GetTeamApps(c *gin.Context) {
 		return
 	}
 
-	if ok, err := PerformAuthz(userId, teamId.String(), *ScopeAppRead); err != nil {
+	if ok, err := PerformAuthz(c, userId, teamId.String(), *ScopeAppRead); err != nil {
 		msg := `couldn't perform authorization checks`
 		fmt.Println(msg, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
@@ -455,6 +539,14 @@ func GetTeamApps(c *gin.Context) {
 		return
 	}
 
+	apps, err = filterDeniedApps(c, userId, apps)
+	if err != nil {
+		msg := fmt.Sprintf("error occurred while querying apps list for team: %s", teamId)
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
 	if len(apps) < 1 {
 		msg := fmt.Sprintf("no apps exists under team: %s", teamId)
 		c.JSON(http.StatusNotFound, gin.H{"error": msg})
@@ -481,7 +573,7 @@ func GetTeamApp(c *gin.Context) {
 		return
 	}
 
-	if ok, err := PerformAuthz(userId, teamId.String(), *ScopeTeamRead); err != nil {
+	if ok, err := PerformAuthz(c, userId, teamId.String(), *ScopeTeamRead); err != nil {
 		msg := `couldn't perform authorization checks`
 		fmt.Println(msg, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
@@ -492,13 +584,13 @@ func GetTeamApp(c *gin.Context) {
 		return
 	}
 
-	if ok, err := PerformAuthz(userId, teamId.String(), *ScopeAppRead); err != nil {
+	if ok, err := PerformAppAuthz(c, userId, teamId.String(), appId.String(), *ScopeAppRead); err != nil {
 		msg := `couldn't perform authorization checks`
 		fmt.Println(msg, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
 		return
 	} else if !ok {
-		msg := fmt.Sprintf(`you don't have permissions to read apps in team [%s]`, teamId)
+		msg := fmt.Sprintf(`you don't have permissions to read app [%s]`, appId)
 		c.JSON(http.StatusForbidden, gin.H{"error": msg})
 		return
 	}
@@ -557,7 +649,25 @@ func InviteMembers(c *gin.Context) {
 		return
 	}
 
-	ok, err := PerformAuthz(userId, teamId.String(), *ScopeTeamInviteSameOrLower)
+	for _, invitee := range invitees {
+		if _, err := normalizeEmail(invitee.Email); err != nil {
+			msg := fmt.Sprintf("invitee email '%s' is invalid", invitee.Email)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": msg,
+			})
+			return
+		}
+
+		if !invitee.Role.Valid() {
+			msg := fmt.Sprintf("role of invitee '%s' is not valid", invitee.Email)
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": msg,
+			})
+			return
+		}
+	}
+
+	ok, err := PerformAuthz(c, userId, teamId.String(), *ScopeTeamInviteSameOrLower)
 	if err != nil {
 		// FIXME: improve error handling, this is quite brittle way of
 		// doing errors. not ideal.
@@ -642,8 +752,7 @@ func InviteMembers(c *gin.Context) {
 		return
 	}
 
-	// ignoring new invitees for now
-	existingUsers, _, err := GetUsersByInvitees(invitees)
+	existingUsers, newUsers, err := GetUsersByInvitees(invitees)
 	if err != nil {
 		msg := `failed to invite`
 		fmt.Println(msg, err)
@@ -653,21 +762,36 @@ func InviteMembers(c *gin.Context) {
 		return
 	}
 
-	if len(existingUsers) < 1 {
-		inviteeEmails := []string{}
-		for i := range invitees {
-			inviteeEmails = append(inviteeEmails, invitees[i].Email)
+	// existing users join right away, everyone
+	// else joins on accepting the invite
+	if len(existingUsers) > 0 {
+		if err := team.addMembers(existingUsers); err != nil {
+			msg := `failed to invite existing users`
+			fmt.Println(msg, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": msg,
+			})
+			return
 		}
-		emails := strings.Join(inviteeEmails, ", ")
-		msg := fmt.Sprintf("no matching users found for %s", emails)
-		c.JSON(http.StatusNotFound, gin.H{
+	}
+
+	invites, err := createInvites(c, teamId, uuid.MustParse(userId), newUsers)
+	if err != nil {
+		msg := `failed to invite new users`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": msg,
 		})
 		return
 	}
 
-	if err := team.addMembers(existingUsers); err != nil {
-		msg := `failed to invite existing users`
+	inviter, err := getUser(c, uuid.MustParse(userId))
+	if err != nil {
+		fmt.Println("failed to fetch inviter", err)
+	}
+
+	if err := sendInviteEmails(c, &team, inviter, invites); err != nil {
+		msg := `failed to send invite emails`
 		fmt.Println(msg, err)
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": msg,
@@ -678,11 +802,38 @@ func InviteMembers(c *gin.Context) {
 	invitedEmails := []string{}
 	for i := range existingUsers {
 		invitedEmails = append(invitedEmails, existingUsers[i].Email)
+
+		newAuditLog(c, auditMemberInvited).
+			inTeam(teamId).
+			on(auditTargetMember, existingUsers[i].ID.String()).
+			change(nil, map[string]any{
+				"email": existingUsers[i].Email,
+				"role":  existingUsers[i].Role.String(),
+			}).
+			record(c)
+	}
+
+	for _, invite := range invites {
+		invitedEmails = append(invitedEmails, invite.email)
+
+		newAuditLog(c, auditInviteCreated).
+			inTeam(teamId).
+			on(auditTargetInvite, invite.id.String()).
+			change(nil, map[string]any{
+				"email": invite.email,
+				"role":  invite.role.String(),
+			}).
+			record(c)
 	}
 	emails := strings.Join(invitedEmails, ", ")
 
+	if invites == nil {
+		invites = []*Invite{}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
-		"ok": fmt.Sprintf("invited %s", emails),
+		"ok":      fmt.Sprintf("invited %s", emails),
+		"invites": invites,
 	})
 }
 
@@ -709,7 +860,7 @@ func RenameTeam(c *gin.Context) {
 		return
 	}
 
-	ok, err := PerformAuthz(userId, teamId.String(), *ScopeTeamAll)
+	ok, err := PerformAuthz(c, userId, teamId.String(), *ScopeTeamAll)
 	if err != nil {
 		msg := `couldn't perform authorization checks`
 		fmt.Println(msg, err)
@@ -725,6 +876,14 @@ func RenameTeam(c *gin.Context) {
 	var team = new(Team)
 	team.ID = &teamId
 
+	prevName, err := team.getName(c)
+	if err != nil {
+		msg := "failed to rename team"
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
 	if err := c.ShouldBindJSON(&team); err != nil {
 		msg := "failed to parse invite payload"
 		fmt.Println(msg, err)
@@ -732,6 +891,18 @@ func RenameTeam(c *gin.Context) {
 		return
 	}
 
+	if team.Name == nil {
+		team.Name = new(string)
+	}
+
+	*team.Name = strings.Trim(*team.Name, " ")
+
+	if err := checkName("team", *team.Name); err != nil {
+		msg := err.Error()
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
 	if err := team.rename(); err != nil {
 		msg := "failed to rename team"
 		fmt.Println(msg, err)
@@ -739,6 +910,12 @@ func RenameTeam(c *gin.Context) {
 		return
 	}
 
+	newAuditLog(c, auditTeamRenamed).
+		inTeam(teamId).
+		on(auditTargetTeam, teamId.String()).
+		change(map[string]any{"name": prevName}, map[string]any{"name": *team.Name}).
+		record(c)
+
 	c.JSON(http.StatusOK, gin.H{"ok": "team was renamed"})
 }
 
@@ -771,7 +948,7 @@ func GetAuthzRoles(c *gin.Context) {
 		return
 	}
 
-	ok, err := PerformAuthz(userId, teamId.String(), *ScopeTeamRead)
+	ok, err := PerformAuthz(c, userId, teamId.String(), *ScopeTeamRead)
 	if err != nil {
 		msg := `couldn't perform authorization checks`
 		fmt.Println(msg, err)
@@ -847,7 +1024,7 @@ func GetTeamMembers(c *gin.Context) {
 		return
 	}
 
-	ok, err := PerformAuthz(userId, teamId.String(), *ScopeTeamRead)
+	ok, err := PerformAuthz(c, userId, teamId.String(), *ScopeTeamRead)
 	if err != nil {
 		msg := `couldn't perform authorization checks`
 		fmt.Println(msg, err)
@@ -912,7 +1089,7 @@ func RemoveTeamMember(c *gin.Context) {
 		return
 	}
 
-	ok, err := PerformAuthz(userId, teamId.String(), *ScopeTeamChangeRoleSameOrLower)
+	ok, err := PerformAuthz(c, userId, teamId.String(), *ScopeTeamChangeRoleSameOrLower)
 	if err != nil {
 		msg := `couldn't perform authorization checks`
 		fmt.Println(msg, err)
@@ -929,6 +1106,15 @@ func RemoveTeamMember(c *gin.Context) {
 		ID: &teamId,
 	}
 
+	memberIdStr := memberId.String()
+	memberRole, err := (&User{ID: &memberIdStr}).getRole(teamId.String())
+	if err != nil {
+		msg := fmt.Sprintf("couldn't remove member [%s]", memberId)
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
 	if err = team.removeMember(&memberId); err != nil {
 		msg := fmt.Sprintf("couldn't remove member [%s]", memberId)
 		fmt.Println(msg, err)
@@ -936,6 +1122,12 @@ func RemoveTeamMember(c *gin.Context) {
 		return
 	}
 
+	newAuditLog(c, auditMemberRemoved).
+		inTeam(teamId).
+		on(auditTargetMember, memberIdStr).
+		change(map[string]any{"role": memberRole.String()}, nil).
+		record(c)
+
 	c.JSON(http.StatusOK, gin.H{"ok": fmt.Sprintf("removed member [%s] from team [%s]", memberId, teamId)})
 }
 
@@ -976,7 +1168,7 @@ func ChangeMemberRole(c *gin.Context) {
 		return
 	}
 
-	ok, err := PerformAuthz(userId, teamId.String(), *ScopeTeamChangeRoleSameOrLower)
+	ok, err := PerformAuthz(c, userId, teamId.String(), *ScopeTeamChangeRoleSameOrLower)
 	if err != nil {
 		msg := `couldn't perform authorization checks`
 		fmt.Println(msg, err)
@@ -1010,6 +1202,15 @@ func ChangeMemberRole(c *gin.Context) {
 		ID: &teamId,
 	}
 
+	memberIdStr := memberId.String()
+	prevRole, err := (&User{ID: &memberIdStr}).getRole(teamId.String())
+	if err != nil {
+		msg := `failed to change role`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
 	if err := team.changeRole(&memberId, roleMap[*member.Role]); err != nil {
 		msg := `failed to change role`
 		fmt.Println(msg, err)
@@ -1017,5 +1218,11 @@ func ChangeMemberRole(c *gin.Context) {
 		return
 	}
 
+	newAuditLog(c, auditMemberRoleChanged).
+		inTeam(teamId).
+		on(auditTargetMember, memberIdStr).
+		change(map[string]any{"role": prevRole.String()}, map[string]any{"role": *member.Role}).
+		record(c)
+
 	c.JSON(http.StatusOK, gin.H{"ok": "done"})
 }
@@ -0,0 +1,65 @@
+package measure
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// execution is a statement executed
+// in a transaction.
+type execution struct {
+	sql  string
+	args []any
+}
+
+// recordingTx is a transaction that records
+// executed statements. Updates of apps affect
+// appRows rows & queried rows scan row's values.
+type recordingTx struct {
+	pgx.Tx
+	appRows    int
+	row        []uuid.UUID
+	executions []execution
+}
+
+// uuidRow is a row of uuid values. Scans
+// pgx.ErrNoRows if there are no values.
+type uuidRow []uuid.UUID
+
+func (r uuidRow) Scan(dest ...any) error {
+	if len(r) == 0 {
+		return pgx.ErrNoRows
+	}
+
+	for i := range dest {
+		*dest[i].(*uuid.UUID) = r[i]
+	}
+
+	return nil
+}
+
+func (tx *recordingTx) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	tx.executions = append(tx.executions, execution{sql: sql, args: slices.Clone(args)})
+
+	return uuidRow(tx.row)
+}
+
+func (tx *recordingTx) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	// statements are closed & their args
+	// reset after the transfer
+	tx.executions = append(tx.executions, execution{sql: sql, args: slices.Clone(args)})
+
+	if strings.HasPrefix(sql, "UPDATE public.apps") {
+		if tx.appRows == 0 {
+			return pgconn.NewCommandTag("UPDATE 0"), nil
+		}
+		return pgconn.NewCommandTag("UPDATE 1"), nil
+	}
+
+	return pgconn.NewCommandTag("DELETE 0"), nil
+}
@@ -12,9 +12,12 @@ import (
 )
 
 type AppUsage struct {
-	AppId           string            `json:"app_id"`
-	AppName         string            `json:"app_name"`
-	MonthlyAppUsage []MonthlyAppUsage `json:"monthly_app_usage"`
+	AppId             string            `json:"app_id"`
+	AppName           string            `json:"app_name"`
+	MonthlyAppUsage   []MonthlyAppUsage `json:"monthly_app_usage"`
+	Quota             Quota             `json:"quota"`
+	CurrentMonthUsage Usage             `json:"current_month_usage"`
+	QuotaWarnings     []QuotaWarning    `json:"quota_warnings"`
 }
 
 type MonthlyAppUsage struct {
@@ -33,7 +36,7 @@ func GetUsage(c *gin.Context) {
 		return
 	}
 
-	if ok, err := PerformAuthz(userId, teamId.String(), *ScopeTeamRead); err != nil {
+	if ok, err := PerformAuthz(c, userId, teamId.String(), *ScopeTeamRead); err != nil {
 		msg := `couldn't perform authorization checks`
 		fmt.Println(msg, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
@@ -44,7 +47,7 @@ func GetUsage(c *gin.Context) {
 		return
 	}
 
-	if ok, err := PerformAuthz(userId, teamId.String(), *ScopeAppRead); err != nil {
+	if ok, err := PerformAuthz(c, userId, teamId.String(), *ScopeAppRead); err != nil {
 		msg := `couldn't perform authorization checks`
 		fmt.Println(msg, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
@@ -66,6 +69,14 @@ func GetUsage(c *gin.Context) {
 		return
 	}
 
+	apps, err = filterDeniedApps(c, userId, apps)
+	if err != nil {
+		msg := fmt.Sprintf("error occurred while querying apps list for team: %s", teamId)
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
 	if len(apps) < 1 {
 		msg := fmt.Sprintf("no apps exists under team: %s", teamId)
 		c.JSON(http.StatusNotFound, gin.H{"error": msg})
@@ -99,14 +110,61 @@ func GetUsage(c *gin.Context) {
 		return
 	}
 
+	defer rows.Close()
+
 	appUsageMap := make(map[string]*AppUsage)
 
+	month := usageMonth(time.Now())
+
+	teamQuota, err := getQuota(c, &teamId, nil)
+	if err != nil {
+		msg := fmt.Sprintf("error occurred while querying quota for team: %s", teamId)
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	teamUsage, err := getUsage(c, &teamId, nil, month)
+	if err != nil {
+		msg := fmt.Sprintf("error occurred while querying usage counters for team: %s", teamId)
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	// Warnings of the team's quota apply to every app
+	teamWarnings := teamQuota.warnings(quotaScopeTeam, teamUsage)
+
 	// Initialize appUsageMap with all apps
 	for _, app := range apps {
+		appQuota, err := getQuota(c, nil, app.ID)
+		if err != nil {
+			msg := fmt.Sprintf("error occurred while querying quota for app: %s", app.ID)
+			fmt.Println(msg, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+			return
+		}
+
+		appUsage, err := getUsage(c, nil, app.ID, month)
+		if err != nil {
+			msg := fmt.Sprintf("error occurred while querying usage counters for app: %s", app.ID)
+			fmt.Println(msg, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+			return
+		}
+
+		warnings := append(appQuota.warnings(quotaScopeApp, appUsage), teamWarnings...)
+		if warnings == nil {
+			warnings = []QuotaWarning{}
+		}
+
 		appUsageMap[app.ID.String()] = &AppUsage{
-			AppId:           app.ID.String(),
-			AppName:         app.AppName,
-			MonthlyAppUsage: make([]MonthlyAppUsage, 0, 3),
+			AppId:             app.ID.String(),
+			AppName:           app.AppName,
+			MonthlyAppUsage:   make([]MonthlyAppUsage, 0, 3),
+			Quota:             appQuota,
+			CurrentMonthUsage: appUsage,
+			QuotaWarnings:     warnings,
 		}
 	}
 
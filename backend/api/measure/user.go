@@ -62,7 +62,8 @@ func (u *User) getTeams() ([]map[string]string, error) {
 		Select("team_membership.team_id, team_membership.role, teams.name").
 		From("public.team_membership").
 		LeftJoin("public.teams", "public.team_membership.team_id = teams.id").
-		Where("public.team_membership.user_id = ?", nil)
+		Where("public.team_membership.user_id = ?", nil).
+		Where("teams.deleted_at is null")
 
 	defer stmt.Close()
 
@@ -99,7 +100,8 @@ func (u *User) getOwnTeam(ctx context.Context) (team *Team, err error) {
 		Select("teams.id, teams.name").
 		From("public.teams").
 		LeftJoin("public.team_membership", "public.teams.id = public.team_membership.team_id and public.team_membership.role = 'owner'").
-		Where("public.team_membership.user_id = ?", u.ID)
+		Where("public.team_membership.user_id = ?", u.ID).
+		Where("public.teams.deleted_at is null")
 
 	defer stmt.Close()
 
@@ -118,6 +120,7 @@ func (u *User) getRole(teamId string) (rank, error) {
 	stmt := sqlf.PostgreSQL.
 		Select("role").
 		From("public.team_membership").
+		Join("public.teams", "public.teams.id = public.team_membership.team_id and public.teams.deleted_at is null").
 		Where("user_id::uuid = ? and team_id::uuid = ?", nil, nil)
 
 	defer stmt.Close()
@@ -284,3 +287,161 @@ func NewUser(name, email string) (user *User) {
 
 	return
 }
+
+// findOrCreateUser finds a user by email, creating the
+// user along with their own team if they don't exist yet.
+// Reports whether a new user was created.
+func findOrCreateUser(ctx context.Context, name, email string) (user *User, created bool, err error) {
+	user, err = FindUserByEmail(ctx, email)
+	if err != nil {
+		return
+	}
+
+	if user != nil {
+		err = user.touchLastSignInAt(ctx)
+		return
+	}
+
+	user = NewUser(name, email)
+
+	tx, err := server.Server.PgPool.Begin(ctx)
+	if err != nil {
+		return
+	}
+
+	defer tx.Rollback(ctx)
+
+	if err = user.create(ctx, &tx); err != nil {
+		return
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return
+	}
+
+	created = true
+
+	return
+}
+
+// create saves a new user along with
+// their own team.
+func (u *User) create(ctx context.Context, tx *pgx.Tx) (err error) {
+	if err = u.save(ctx, tx); err != nil {
+		return
+	}
+
+	teamName := fmt.Sprintf("%s's team", u.firstName())
+	team := &Team{
+		Name: &teamName,
+	}
+
+	return team.create(ctx, u, tx)
+}
+
+// getUser finds a user from their id.
+func getUser(ctx context.Context, id uuid.UUID) (*User, error) {
+	stmt := sqlf.PostgreSQL.
+		From("public.users").
+		Select("id").
+		Select("name").
+		Select("email").
+		Select("confirmed_at").
+		Where("id = ?", id)
+
+	defer stmt.Close()
+
+	var user User
+
+	if err := server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&user.ID, &user.Name, &user.Email, &user.ConfirmedAt); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// findUserCredentials finds a user along with their
+// password hash from their email. The hash is empty
+// if the user has never set a password.
+func findUserCredentials(ctx context.Context, email string) (user *User, hash string, err error) {
+	stmt := sqlf.PostgreSQL.
+		From("public.users").
+		Select("id").
+		Select("name").
+		Select("email").
+		Select("confirmed_at").
+		Select("coalesce(password_hash, '')").
+		Where("email = ?", email)
+
+	defer stmt.Close()
+
+	var u User
+
+	if err = server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&u.ID, &u.Name, &u.Email, &u.ConfirmedAt, &hash); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			err = nil
+		}
+		return
+	}
+
+	user = &u
+
+	return
+}
+
+// setPassword sets the password hash
+// of the user.
+func (u *User) setPassword(ctx context.Context, hash string, tx *pgx.Tx) (err error) {
+	now := time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.users").
+		Set("password_hash", hash).
+		Set("password_updated_at", now).
+		Set("updated_at", now).
+		Where("id = ?", u.ID)
+
+	defer stmt.Close()
+
+	if tx != nil {
+		_, err = (*tx).Exec(ctx, stmt.String(), stmt.Args()...)
+		return
+	}
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// confirm marks the user's email as confirmed,
+// if not confirmed already.
+func (u *User) confirm(ctx context.Context, tx *pgx.Tx) (err error) {
+	if u.ConfirmedAt != nil {
+		return
+	}
+
+	now := time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.users").
+		Set("confirmed_at", now).
+		Set("updated_at", now).
+		Where("id = ?", u.ID).
+		Where("confirmed_at is null")
+
+	defer stmt.Close()
+
+	if tx != nil {
+		_, err = (*tx).Exec(ctx, stmt.String(), stmt.Args()...)
+	} else {
+		_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+	}
+
+	if err != nil {
+		return
+	}
+
+	u.ConfirmedAt = &now
+
+	return
+}
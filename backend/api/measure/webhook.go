@@ -0,0 +1,315 @@
+package measure
+
+import (
+	"backend/api/webhook"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxWebhooksPerApp is the maximum number of
+// webhooks an app can have.
+const maxWebhooksPerApp = 10
+
+// defaultDeliveriesLimit is the default number
+// of deliveries returned per page.
+const defaultDeliveriesLimit = 20
+
+// maxDeliveriesLimit is the maximum number
+// of deliveries returned per page.
+const maxDeliveriesLimit = 100
+
+type WebhookPayload struct {
+	URL             string   `json:"url" binding:"required"`
+	Events          []string `json:"events" binding:"required"`
+	VolumeThreshold int      `json:"volume_threshold"`
+}
+
+type WebhookUpdatePayload struct {
+	URL             *string   `json:"url"`
+	Events          *[]string `json:"events"`
+	VolumeThreshold *int      `json:"volume_threshold"`
+	Active          *bool     `json:"active"`
+}
+
+type WebhookDeliveriesQuery struct {
+	Limit  int `form:"limit"`
+	Offset int `form:"offset"`
+}
+
+// getWebhookFromParams parses the app and webhook ids
+// from the route and fetches the webhook. Writes the
+// error response and returns nil if either fails.
+func getWebhookFromParams(c *gin.Context, scope scope) *webhook.Webhook {
+	appId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `app id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return nil
+	}
+
+	webhookId, err := uuid.Parse(c.Param("webhookId"))
+	if err != nil {
+		msg := `webhook id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return nil
+	}
+
+	if !authzApp(c, appId, scope) {
+		return nil
+	}
+
+	w, err := webhook.GetWebhook(c.Request.Context(), appId, webhookId)
+	if err != nil {
+		msg := `failed to fetch webhook`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return nil
+	}
+	if w == nil {
+		msg := fmt.Sprintf(`no webhook found with id %q`, webhookId)
+		c.JSON(http.StatusNotFound, gin.H{"error": msg})
+		return nil
+	}
+
+	return w
+}
+
+func GetWebhooks(c *gin.Context) {
+	ctx := c.Request.Context()
+	appId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `app id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if !authzApp(c, appId, *ScopeAppRead) {
+		return
+	}
+
+	webhooks, err := webhook.GetWebhooks(ctx, appId)
+	if err != nil {
+		msg := `failed to fetch webhooks`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	for i := range webhooks {
+		webhooks[i].Mask()
+	}
+
+	if webhooks == nil {
+		webhooks = []webhook.Webhook{}
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+func CreateWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+	userId := c.GetString("userId")
+	appId, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		msg := `app id invalid or missing`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if !authzApp(c, appId, *ScopeAppAll) {
+		return
+	}
+
+	var payload WebhookPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		msg := `failed to parse webhook json payload`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	existing, err := webhook.GetWebhooks(ctx, appId)
+	if err != nil {
+		msg := `failed to fetch webhooks`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	if len(existing) >= maxWebhooksPerApp {
+		msg := fmt.Sprintf(`app cannot have more than %d webhooks`, maxWebhooksPerApp)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	w, err := webhook.NewWebhook(appId, uuid.MustParse(userId))
+	if err != nil {
+		msg := `failed to create webhook`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	w.URL = payload.URL
+	w.Events = payload.Events
+	w.VolumeThreshold = payload.VolumeThreshold
+
+	if err := w.Validate(ctx); err != nil {
+		msg := `webhook validation failed`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg, "details": err.Error()})
+		return
+	}
+
+	if err := w.Insert(ctx); err != nil {
+		msg := `failed to create webhook`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	// secret is only revealed once
+	// at the time of creation
+	c.JSON(http.StatusCreated, w)
+}
+
+func UpdateWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+	w := getWebhookFromParams(c, *ScopeAppAll)
+	if w == nil {
+		return
+	}
+
+	var payload WebhookUpdatePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		msg := `failed to parse webhook json payload`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if payload.URL != nil {
+		w.URL = *payload.URL
+	}
+	if payload.Events != nil {
+		w.Events = *payload.Events
+	}
+	if payload.VolumeThreshold != nil {
+		w.VolumeThreshold = *payload.VolumeThreshold
+	}
+	if payload.Active != nil {
+		w.Active = *payload.Active
+	}
+
+	if err := w.Validate(ctx); err != nil {
+		msg := `webhook validation failed`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg, "details": err.Error()})
+		return
+	}
+
+	if err := w.Update(ctx); err != nil {
+		msg := `failed to update webhook`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	w.Mask()
+
+	c.JSON(http.StatusOK, w)
+}
+
+func DeleteWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+	w := getWebhookFromParams(c, *ScopeAppAll)
+	if w == nil {
+		return
+	}
+
+	if err := w.Delete(ctx); err != nil {
+		msg := `failed to delete webhook`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ok": "done"})
+}
+
+func GetWebhookDeliveries(c *gin.Context) {
+	ctx := c.Request.Context()
+	w := getWebhookFromParams(c, *ScopeAppRead)
+	if w == nil {
+		return
+	}
+
+	var query WebhookDeliveriesQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		msg := `failed to parse query parameters`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": msg})
+		return
+	}
+
+	if query.Limit < 1 {
+		query.Limit = defaultDeliveriesLimit
+	}
+	if query.Limit > maxDeliveriesLimit {
+		query.Limit = maxDeliveriesLimit
+	}
+	if query.Offset < 0 {
+		query.Offset = 0
+	}
+
+	// fetch one extra to detect next page
+	deliveries, err := webhook.GetDeliveries(ctx, w.ID, query.Limit+1, query.Offset)
+	if err != nil {
+		msg := `failed to fetch webhook deliveries`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	next := len(deliveries) > query.Limit
+	if next {
+		deliveries = deliveries[:query.Limit]
+	}
+
+	if deliveries == nil {
+		deliveries = []webhook.Delivery{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": deliveries,
+		"meta": gin.H{
+			"next":     next,
+			"previous": query.Offset > 0,
+		},
+	})
+}
+
+func TestWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+	w := getWebhookFromParams(c, *ScopeAppAll)
+	if w == nil {
+		return
+	}
+
+	delivery, err := webhook.SendTest(ctx, *w)
+	if err != nil {
+		msg := `failed to send test delivery`
+		fmt.Println(msg, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
@@ -66,6 +66,26 @@ type LaunchMetric struct {
 	HotNaN        bool    `json:"hot_nan"`
 }
 
+// SpanMetric represents compute result of the
+// duration percentiles of an app's spans of
+// the same name.
+type SpanMetric struct {
+	Name  string  `json:"name"`
+	Count uint64  `json:"count"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P99   float64 `json:"p99"`
+}
+
+// CustomEventMetric represents compute result of
+// the count of an app's custom events of the same
+// name & of the sessions having them.
+type CustomEventMetric struct {
+	Name     string `json:"name"`
+	Count    uint64 `json:"count"`
+	Sessions uint64 `json:"sessions"`
+}
+
 // SetNaNs sets the NaN bit if adoption
 // value is NaN.
 func (sa *SessionAdoption) SetNaNs() {
@@ -0,0 +1,148 @@
+package otlp
+
+import (
+	"backend/api/event"
+	"backend/api/platform"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// OTel semantic convention attribute keys
+// mapped onto event attributes.
+const (
+	keyInstallationID     = "app.installation.id"
+	keyDeviceID           = "device.id"
+	keyServiceName        = "service.name"
+	keyServiceVersion     = "service.version"
+	keyServiceInstanceID  = "service.instance.id"
+	keyAppBuildID         = "app.build_id"
+	keySDKVersion         = "telemetry.sdk.version"
+	keyOSName             = "os.name"
+	keyOSVersion          = "os.version"
+	keyDeviceModelID      = "device.model.identifier"
+	keyDeviceModelName    = "device.model.name"
+	keyDeviceManufacturer = "device.manufacturer"
+	keyEndUserID          = "enduser.id"
+	keyThreadName         = "thread.name"
+	keySessionID          = "session.id"
+	keyNetworkType        = "network.connection.type"
+	keyNetworkSubtype     = "network.connection.subtype"
+	keyNetworkCarrier     = "network.carrier.name"
+)
+
+// networkTypes maps OTel network connection
+// types to event network types.
+var networkTypes = map[string]string{
+	"wifi":        event.NetworkTypeWifi,
+	"cell":        event.NetworkTypeCellular,
+	"unavailable": event.NetworkTypeNoNetwork,
+	"unknown":     event.NetworkTypeUnknown,
+}
+
+// networkGenerations maps OTel network connection
+// subtypes to event network generations.
+var networkGenerations = map[string]string{
+	"gprs":           event.NetworkGeneration2G,
+	"edge":           event.NetworkGeneration2G,
+	"cdma":           event.NetworkGeneration2G,
+	"cdma2000_1xrtt": event.NetworkGeneration2G,
+	"iden":           event.NetworkGeneration2G,
+	"gsm":            event.NetworkGeneration2G,
+	"umts":           event.NetworkGeneration3G,
+	"evdo_0":         event.NetworkGeneration3G,
+	"evdo_a":         event.NetworkGeneration3G,
+	"evdo_b":         event.NetworkGeneration3G,
+	"hsdpa":          event.NetworkGeneration3G,
+	"hsupa":          event.NetworkGeneration3G,
+	"hspa":           event.NetworkGeneration3G,
+	"hspap":          event.NetworkGeneration3G,
+	"ehrpd":          event.NetworkGeneration3G,
+	"td_scdma":       event.NetworkGeneration3G,
+	"lte":            event.NetworkGeneration4G,
+	"lte_ca":         event.NetworkGeneration4G,
+	"iwlan":          event.NetworkGeneration4G,
+	"nr":             event.NetworkGeneration5G,
+	"nrnsa":          event.NetworkGeneration5G,
+}
+
+// toAttribute maps OTel resource & record
+// attributes onto event attributes.
+func toAttribute(attrs attributes) (attribute event.Attribute, err error) {
+	installationId := attrs.str(keyInstallationID, keyDeviceID)
+	if installationId == "" {
+		err = fmt.Errorf("resource must contain one of %q or %q attributes", keyInstallationID, keyDeviceID)
+		return
+	}
+
+	appVersion := attrs.str(keyServiceVersion)
+	if appVersion == "" {
+		err = fmt.Errorf("resource must contain %q attribute", keyServiceVersion)
+		return
+	}
+
+	appUniqueId := attrs.str(keyServiceName)
+	if appUniqueId == "" {
+		err = fmt.Errorf("resource must contain %q attribute", keyServiceName)
+		return
+	}
+
+	osName := attrs.str(keyOSName)
+	var os string
+	switch strings.ToLower(osName) {
+	case "android":
+		os = platform.Android
+	case "ios", "ipados":
+		os = platform.IOS
+	default:
+		err = fmt.Errorf("resource attribute %q must be one of %q or %q", keyOSName, "Android", "iOS")
+		return
+	}
+
+	networkType, ok := networkTypes[attrs.str(keyNetworkType)]
+	if !ok {
+		networkType = event.NetworkTypeUnknown
+	}
+
+	networkGeneration, ok := networkGenerations[attrs.str(keyNetworkSubtype)]
+	if !ok {
+		networkGeneration = event.NetworkGenerationUnknown
+	}
+
+	attribute = event.Attribute{
+		InstallationID:     toUUID(installationId),
+		AppVersion:         appVersion,
+		AppBuild:           attrs.str(keyAppBuildID, keyServiceVersion),
+		AppUniqueID:        appUniqueId,
+		MeasureSDKVersion:  attrs.str(keySDKVersion),
+		Platform:           os,
+		ThreadName:         attrs.str(keyThreadName),
+		UserID:             attrs.str(keyEndUserID),
+		DeviceName:         attrs.str(keyDeviceModelName),
+		DeviceModel:        attrs.str(keyDeviceModelID),
+		DeviceManufacturer: attrs.str(keyDeviceManufacturer),
+		OSName:             osName,
+		OSVersion:          attrs.str(keyOSVersion),
+		NetworkType:        networkType,
+		NetworkGeneration:  networkGeneration,
+		NetworkProvider:    attrs.str(keyNetworkCarrier),
+	}
+
+	err = attribute.Validate()
+
+	return
+}
+
+// toSessionID provides the session of a record. The
+// `session.id` attribute is preferred, then the
+// service instance, which OTel SDKs generate once
+// per process. As a last resort, all records of an
+// installation fall in a single session.
+func toSessionID(attrs attributes, attribute event.Attribute) uuid.UUID {
+	if id := attrs.str(keySessionID, keyServiceInstanceID); id != "" {
+		return toUUID(id)
+	}
+
+	return uuid.NewSHA1(namespace, attribute.InstallationID[:])
+}
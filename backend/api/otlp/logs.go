@@ -0,0 +1,118 @@
+package otlp
+
+import (
+	"backend/api/event"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+)
+
+// severityTexts maps common OTel severity
+// texts to log levels.
+var severityTexts = map[string]string{
+	"trace":    "debug",
+	"debug":    "debug",
+	"info":     "info",
+	"warn":     "warning",
+	"warning":  "warning",
+	"error":    "error",
+	"fatal":    "fatal",
+	"critical": "fatal",
+}
+
+// ConvertLogs converts the log records of an OTLP
+// logs export request to events. Records with
+// exception attributes become exception events,
+// the rest become string events.
+func ConvertLogs(appId uuid.UUID, req *collogspb.ExportLogsServiceRequest) (result Result) {
+	for _, resourceLogs := range req.GetResourceLogs() {
+		resource := resourceLogs.GetResource().GetAttributes()
+		for _, scopeLogs := range resourceLogs.GetScopeLogs() {
+			for _, record := range scopeLogs.GetLogRecords() {
+				ev, err := toLogEvent(appId, newAttributes(resource, record.GetAttributes()), record)
+				if err != nil {
+					result.reject(1, err)
+					continue
+				}
+				result.Events = append(result.Events, ev)
+			}
+		}
+	}
+
+	return
+}
+
+// toLogEvent converts a log record
+// to an event.
+func toLogEvent(appId uuid.UUID, attrs attributes, record *logspb.LogRecord) (ev event.EventField, err error) {
+	attribute, err := toAttribute(attrs)
+	if err != nil {
+		return
+	}
+
+	timestamp := unixNano(record.GetTimeUnixNano())
+	if timestamp.IsZero() {
+		timestamp = unixNano(record.GetObservedTimeUnixNano())
+	}
+
+	if timestamp.IsZero() {
+		err = errors.New("log record must contain one of time or observed time")
+		return
+	}
+
+	ev = event.EventField{
+		ID:        uuid.New(),
+		AppID:     appId,
+		SessionID: toSessionID(attrs, attribute),
+		Timestamp: timestamp,
+		Attribute: attribute,
+	}
+
+	severity := record.GetSeverityNumber()
+
+	if isException(attrs) {
+		// fatal logs & exceptions escaping their
+		// scope are reported by crash handlers
+		handled := !attrs.flag(keyExceptionEscaped) && severity < logspb.SeverityNumber_SEVERITY_NUMBER_FATAL
+		ev.Type = event.TypeException
+		ev.Exception = toException(attrs, handled)
+	} else {
+		ev.Type = event.TypeString
+		ev.LogString = &event.LogString{
+			SeverityText: toSeverityText(severity, record.GetSeverityText()),
+			String:       stringify(record.GetBody()),
+		}
+	}
+
+	err = ev.Validate()
+
+	return
+}
+
+// toSeverityText provides the log level
+// from the severity number, falling back
+// to the severity text.
+func toSeverityText(number logspb.SeverityNumber, text string) string {
+	switch {
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_FATAL:
+		return "fatal"
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_ERROR:
+		return "error"
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_WARN:
+		return "warning"
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_INFO:
+		return "info"
+	case number >= logspb.SeverityNumber_SEVERITY_NUMBER_TRACE:
+		return "debug"
+	}
+
+	text = strings.ToLower(text)
+	if severity, ok := severityTexts[text]; ok {
+		return severity
+	}
+
+	return text
+}
@@ -0,0 +1,349 @@
+package otlp
+
+import (
+	"backend/api/event"
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ContentTypeProtobuf & ContentTypeJSON are the
+// content types supported by OTLP/HTTP.
+const (
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeJSON     = "application/json"
+)
+
+// ErrUnsupportedContentType is returned when the
+// payload is neither binary protobuf nor JSON.
+var ErrUnsupportedContentType = errors.New("unsupported content type, must be one of application/x-protobuf or application/json")
+
+// namespace is the namespace for deriving
+// stable UUIDs from OTel identifiers that
+// aren't UUIDs themselves.
+var namespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://measure.sh/otlp"))
+
+// idKeys are the JSON keys of trace & span
+// ids, which OTLP/JSON encodes as hex instead
+// of the base64 used by protobuf JSON.
+var idKeys = []string{"traceId", "spanId", "parentSpanId", "trace_id", "span_id", "parent_span_id"}
+
+// Result is the outcome of converting an
+// OTLP export request.
+type Result struct {
+	// Events are the converted log records,
+	// spans & span events.
+	Events []event.EventField
+
+	// Rejected is the count of log records or
+	// spans that could not be converted.
+	Rejected int64
+
+	// Errors are the distinct reasons of
+	// rejection.
+	Errors []string
+}
+
+// reject records a rejected item with its
+// reason.
+func (r *Result) reject(n int, err error) {
+	if n < 1 {
+		return
+	}
+
+	r.Rejected += int64(n)
+
+	msg := err.Error()
+	for _, e := range r.Errors {
+		if e == msg {
+			return
+		}
+	}
+
+	r.Errors = append(r.Errors, msg)
+}
+
+// ErrorMessage provides a human readable summary
+// of the rejections, empty if nothing was rejected.
+func (r Result) ErrorMessage() string {
+	if r.Rejected == 0 {
+		return ""
+	}
+
+	return strings.Join(r.Errors, "; ")
+}
+
+// MediaType provides the OTLP content type
+// from a Content-Type header value.
+func MediaType(contentType string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", ErrUnsupportedContentType
+	}
+
+	switch mediaType {
+	case ContentTypeProtobuf, ContentTypeJSON:
+		return mediaType, nil
+	default:
+		return "", ErrUnsupportedContentType
+	}
+}
+
+// DecodeLogs decodes an OTLP logs export request.
+func DecodeLogs(mediaType string, data []byte) (req *collogspb.ExportLogsServiceRequest, err error) {
+	req = &collogspb.ExportLogsServiceRequest{}
+	err = decode(mediaType, data, req)
+	return
+}
+
+// DecodeTraces decodes an OTLP traces export request.
+func DecodeTraces(mediaType string, data []byte) (req *coltracepb.ExportTraceServiceRequest, err error) {
+	req = &coltracepb.ExportTraceServiceRequest{}
+	err = decode(mediaType, data, req)
+	return
+}
+
+// Encode encodes an OTLP export response in the
+// same content type as the request.
+func Encode(mediaType string, m proto.Message) ([]byte, error) {
+	switch mediaType {
+	case ContentTypeProtobuf:
+		return proto.Marshal(m)
+	case ContentTypeJSON:
+		return protojson.Marshal(m)
+	default:
+		return nil, ErrUnsupportedContentType
+	}
+}
+
+// decode unmarshals binary protobuf or
+// OTLP/JSON into the message.
+func decode(mediaType string, data []byte, m proto.Message) error {
+	switch mediaType {
+	case ContentTypeProtobuf:
+		return proto.Unmarshal(data, m)
+	case ContentTypeJSON:
+		data, err := hexToBase64(data)
+		if err != nil {
+			return err
+		}
+		return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(data, m)
+	default:
+		return ErrUnsupportedContentType
+	}
+}
+
+// hexToBase64 rewrites the hex encoded trace
+// & span ids of an OTLP/JSON payload to base64,
+// so that it can be read as protobuf JSON.
+func hexToBase64(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var payload any
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, err
+	}
+
+	if err := rewriteIds(payload); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(payload)
+}
+
+// rewriteIds walks the JSON value rewriting
+// ids in place.
+func rewriteIds(v any) error {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, value := range v {
+			id, ok := value.(string)
+			if ok && isIdKey(key) {
+				b, err := hex.DecodeString(id)
+				if err != nil {
+					return fmt.Errorf("%q must be hex encoded", key)
+				}
+				v[key] = base64.StdEncoding.EncodeToString(b)
+				continue
+			}
+			if err := rewriteIds(value); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for i := range v {
+			if err := rewriteIds(v[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// isIdKey returns true if the JSON
+// key holds a trace or span id.
+func isIdKey(key string) bool {
+	for _, k := range idKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// attributes indexes key values by key.
+type attributes map[string]*commonpb.AnyValue
+
+// newAttributes indexes the key values. Later
+// keys take precedence over earlier ones.
+func newAttributes(kvs ...[]*commonpb.KeyValue) attributes {
+	attrs := attributes{}
+	for _, list := range kvs {
+		for _, kv := range list {
+			if kv == nil || kv.Value == nil {
+				continue
+			}
+			attrs[kv.Key] = kv.Value
+		}
+	}
+	return attrs
+}
+
+// has returns true if the key is present.
+func (a attributes) has(key string) bool {
+	_, ok := a[key]
+	return ok
+}
+
+// str provides the first non-empty value
+// of the keys as a string.
+func (a attributes) str(keys ...string) string {
+	for _, key := range keys {
+		if v, ok := a[key]; ok {
+			if s := stringify(v); s != "" {
+				return s
+			}
+		}
+	}
+	return ""
+}
+
+// flag provides the value of the key
+// as a boolean.
+func (a attributes) flag(key string) bool {
+	v, ok := a[key]
+	if !ok {
+		return false
+	}
+
+	if b, ok := v.Value.(*commonpb.AnyValue_BoolValue); ok {
+		return b.BoolValue
+	}
+
+	b, _ := strconv.ParseBool(stringify(v))
+	return b
+}
+
+// toMap stringifies all the attributes.
+func (a attributes) toMap() map[string]string {
+	if len(a) == 0 {
+		return nil
+	}
+
+	m := make(map[string]string, len(a))
+	for key, value := range a {
+		m[key] = stringify(value)
+	}
+	return m
+}
+
+// stringify provides a string representation
+// of an OTel value. Arrays & maps are encoded
+// as JSON.
+func stringify(v *commonpb.AnyValue) string {
+	switch v := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(v.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(v.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(v.DoubleValue, 'f', -1, 64)
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(v.BytesValue)
+	case *commonpb.AnyValue_ArrayValue, *commonpb.AnyValue_KvlistValue:
+		b, err := json.Marshal(native(&commonpb.AnyValue{Value: v}))
+		if err != nil {
+			return ""
+		}
+		return string(b)
+	default:
+		return ""
+	}
+}
+
+// native converts an OTel value to
+// its Go counterpart.
+func native(v *commonpb.AnyValue) any {
+	switch v := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return v.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return v.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return v.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return v.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return v.BytesValue
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]any, 0, len(v.ArrayValue.GetValues()))
+		for _, value := range v.ArrayValue.GetValues() {
+			values = append(values, native(value))
+		}
+		return values
+	case *commonpb.AnyValue_KvlistValue:
+		values := make(map[string]any, len(v.KvlistValue.GetValues()))
+		for _, kv := range v.KvlistValue.GetValues() {
+			values[kv.Key] = native(kv.Value)
+		}
+		return values
+	default:
+		return nil
+	}
+}
+
+// toUUID parses the value as a UUID or
+// derives a stable UUID from it.
+func toUUID(value string) uuid.UUID {
+	if id, err := uuid.Parse(value); err == nil {
+		return id
+	}
+	return uuid.NewSHA1(namespace, []byte(value))
+}
+
+// unixNano converts nanoseconds since epoch
+// to time. Zero is the zero time.
+func unixNano(ns uint64) time.Time {
+	if ns == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, int64(ns)).UTC()
+}
@@ -0,0 +1,279 @@
+package otlp
+
+import (
+	"backend/api/event"
+	"backend/api/platform"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func stringValue(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
+
+func keyValues(kvs ...string) (list []*commonpb.KeyValue) {
+	for i := 0; i+1 < len(kvs); i += 2 {
+		list = append(list, &commonpb.KeyValue{Key: kvs[i], Value: stringValue(kvs[i+1])})
+	}
+	return
+}
+
+var androidResource = keyValues(
+	"service.name", "sh.measure.sample",
+	"service.version", "1.2.0",
+	"service.instance.id", "627cc493-f310-47de-96bd-71410b7dec09",
+	"app.installation.id", "00a3c8b1-3a3b-4bb5-9d4c-6d1e0f8f3c2e",
+	"os.name", "Android",
+	"os.version", "14",
+	"device.manufacturer", "Google",
+	"telemetry.sdk.version", "1.41.0",
+	"network.connection.type", "cell",
+	"network.connection.subtype", "lte",
+)
+
+func TestMediaType(t *testing.T) {
+	if mediaType, err := MediaType("application/json; charset=utf-8"); err != nil || mediaType != ContentTypeJSON {
+		t.Errorf("Expected %q, but got %q and %v", ContentTypeJSON, mediaType, err)
+	}
+
+	if _, err := MediaType("multipart/form-data"); err != ErrUnsupportedContentType {
+		t.Errorf("Expected %v, but got %v", ErrUnsupportedContentType, err)
+	}
+}
+
+func TestConvertLogsJSON(t *testing.T) {
+	payload := `{
+  "resourceLogs": [
+    {
+      "resource": {
+        "attributes": [
+          { "key": "service.name", "value": { "stringValue": "sh.measure.sample" } },
+          { "key": "service.version", "value": { "stringValue": "1.2.0" } },
+          { "key": "app.build_id", "value": { "stringValue": "120" } },
+          { "key": "device.id", "value": { "stringValue": "a7b3f6c1" } },
+          { "key": "os.name", "value": { "stringValue": "iOS" } }
+        ]
+      },
+      "scopeLogs": [
+        {
+          "logRecords": [
+            {
+              "timeUnixNano": "1729000000000000000",
+              "severityNumber": 13,
+              "severityText": "WARN",
+              "traceId": "5b8efff798038103d269b633813fc60c",
+              "spanId": "eee19b7ec3c1b174",
+              "body": { "stringValue": "low disk space" },
+              "attributes": [
+                { "key": "session.id", "value": { "stringValue": "8f1a1c1e-68fb-4f4a-9a38-5c5e2f0c8b31" } },
+                { "key": "thread.name", "value": { "stringValue": "io" } }
+              ]
+            },
+            {
+              "observedTimeUnixNano": "1729000001000000000",
+              "severityNumber": 21,
+              "attributes": [
+                { "key": "exception.type", "value": { "stringValue": "NSInvalidArgumentException" } },
+                { "key": "exception.message", "value": { "stringValue": "unrecognized selector" } }
+              ]
+            },
+            {
+              "severityText": "info",
+              "body": { "stringValue": "no timestamp" }
+            }
+          ]
+        }
+      ]
+    },
+    {
+      "resource": {
+        "attributes": [
+          { "key": "service.name", "value": { "stringValue": "backend" } },
+          { "key": "service.version", "value": { "stringValue": "1.0.0" } },
+          { "key": "device.id", "value": { "stringValue": "host-1" } },
+          { "key": "os.name", "value": { "stringValue": "Linux" } }
+        ]
+      },
+      "scopeLogs": [
+        {
+          "logRecords": [
+            { "timeUnixNano": "1729000000000000000", "body": { "stringValue": "from a server" } }
+          ]
+        }
+      ]
+    }
+  ]
+}`
+
+	req, err := DecodeLogs(ContentTypeJSON, []byte(payload))
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if traceId := req.ResourceLogs[0].ScopeLogs[0].LogRecords[0].TraceId; len(traceId) != 16 {
+		t.Errorf("Expected hex trace id to decode to %d bytes, but got %d", 16, len(traceId))
+	}
+
+	appId := uuid.New()
+	result := ConvertLogs(appId, req)
+
+	if len(result.Events) != 2 {
+		t.Fatalf("Expected %d events, but got %d", 2, len(result.Events))
+	}
+
+	if result.Rejected != 2 || len(result.Errors) != 2 {
+		t.Errorf("Expected %d rejections with %d errors, but got %d with %v", 2, 2, result.Rejected, result.Errors)
+	}
+
+	log := result.Events[0]
+
+	if log.Type != event.TypeString || log.LogString.String != "low disk space" || log.LogString.SeverityText != "warning" {
+		t.Errorf("Expected a warning string event, but got %+v", log.LogString)
+	}
+
+	if log.AppID != appId || log.SessionID.String() != "8f1a1c1e-68fb-4f4a-9a38-5c5e2f0c8b31" {
+		t.Errorf("Expected app & session ids to be mapped, but got %v and %v", log.AppID, log.SessionID)
+	}
+
+	if !log.Timestamp.Equal(time.Unix(1729000000, 0)) {
+		t.Errorf("Expected timestamp %v, but got %v", time.Unix(1729000000, 0), log.Timestamp)
+	}
+
+	attribute := log.Attribute
+
+	if attribute.Platform != platform.IOS || attribute.AppBuild != "120" || attribute.ThreadName != "io" {
+		t.Errorf("Expected attributes to be mapped, but got %+v", attribute)
+	}
+
+	if attribute.InstallationID != toUUID("a7b3f6c1") {
+		t.Errorf("Expected installation id derived from device id, but got %v", attribute.InstallationID)
+	}
+
+	crash := result.Events[1]
+
+	if crash.Type != event.TypeException || crash.Exception.Handled {
+		t.Fatalf("Expected an unhandled exception, but got %+v", crash.Exception)
+	}
+
+	if crash.Exception.GetType() != "NSInvalidArgumentException" {
+		t.Errorf("Expected exception type %q, but got %q", "NSInvalidArgumentException", crash.Exception.GetType())
+	}
+
+	if !crash.Timestamp.Equal(time.Unix(1729000001, 0)) {
+		t.Errorf("Expected observed timestamp %v, but got %v", time.Unix(1729000001, 0), crash.Timestamp)
+	}
+
+	// records without a session fall back
+	// to the installation
+	if crash.SessionID != uuid.NewSHA1(namespace, attribute.InstallationID[:]) {
+		t.Errorf("Expected session derived from installation, but got %v", crash.SessionID)
+	}
+}
+
+func TestConvertTracesProtobuf(t *testing.T) {
+	start := time.Date(2024, time.October, 16, 9, 0, 0, 0, time.UTC)
+	traceId := []byte{0x5b, 0x8e, 0xff, 0xf7, 0x98, 0x03, 0x81, 0x03, 0xd2, 0x69, 0xb6, 0x33, 0x81, 0x3f, 0xc6, 0x0c}
+
+	in := &coltracepb.ExportTraceServiceRequest{
+		ResourceSpans: []*tracepb.ResourceSpans{
+			{
+				Resource: &resourcepb.Resource{Attributes: androidResource},
+				ScopeSpans: []*tracepb.ScopeSpans{
+					{
+						Spans: []*tracepb.Span{
+							{
+								TraceId:           traceId,
+								SpanId:            []byte{0xee, 0xe1, 0x9b, 0x7e, 0xc3, 0xc1, 0xb1, 0x74},
+								ParentSpanId:      []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+								Name:              "checkout",
+								Kind:              tracepb.Span_SPAN_KIND_CLIENT,
+								StartTimeUnixNano: uint64(start.UnixNano()),
+								EndTimeUnixNano:   uint64(start.Add(250 * time.Millisecond).UnixNano()),
+								Attributes: append(keyValues("http.method", "POST"), &commonpb.KeyValue{
+									Key:   "http.status_code",
+									Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 500}},
+								}),
+								Status: &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: "server error"},
+								Events: []*tracepb.Span_Event{
+									{
+										Name:         "exception",
+										TimeUnixNano: uint64(start.Add(time.Millisecond).UnixNano()),
+										Attributes: keyValues(
+											"exception.type", "java.io.IOException",
+											"exception.stacktrace", "java.io.IOException: timeout\n\tat okhttp3.Call.execute(Call.kt:12)",
+										),
+									},
+									{
+										Name: "retry",
+									},
+								},
+							},
+							{
+								TraceId: make([]byte, 16),
+								SpanId:  []byte{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08},
+								Name:    "invalid",
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	data, err := proto.Marshal(in)
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	req, err := DecodeTraces(ContentTypeProtobuf, data)
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	result := ConvertTraces(uuid.New(), req)
+
+	if len(result.Events) != 2 || result.Rejected != 1 {
+		t.Fatalf("Expected %d events & %d rejection, but got %d & %d", 2, 1, len(result.Events), result.Rejected)
+	}
+
+	spanEvent := result.Events[0]
+
+	if spanEvent.Type != event.TypeSpan || !spanEvent.Timestamp.Equal(start) {
+		t.Errorf("Expected span event at %v, but got %q at %v", start, spanEvent.Type, spanEvent.Timestamp)
+	}
+
+	span := spanEvent.Span
+
+	if span.TraceID != "5b8efff798038103d269b633813fc60c" || span.ParentID != "0102030405060708" {
+		t.Errorf("Expected hex encoded ids, but got %q and %q", span.TraceID, span.ParentID)
+	}
+
+	if span.Kind != event.SpanKindClient || span.Status != event.SpanStatusError || span.Duration() != 250*time.Millisecond {
+		t.Errorf("Expected client span with error of 250ms, but got %+v", span)
+	}
+
+	if span.Attributes["http.status_code"] != "500" {
+		t.Errorf("Expected stringified status code %q, but got %q", "500", span.Attributes["http.status_code"])
+	}
+
+	if spanEvent.Attribute.NetworkType != event.NetworkTypeCellular || spanEvent.Attribute.NetworkGeneration != event.NetworkGeneration4G {
+		t.Errorf("Expected cellular 4g network, but got %q %q", spanEvent.Attribute.NetworkType, spanEvent.Attribute.NetworkGeneration)
+	}
+
+	exception := result.Events[1]
+
+	if !exception.Exception.Handled || exception.SessionID != spanEvent.SessionID {
+		t.Errorf("Expected handled exception in the span's session, but got %+v", exception)
+	}
+
+	if exception.Exception.GetTitle() != "java.io.IOException: timeout" || exception.Exception.GetMethodName() != "execute" {
+		t.Errorf("Expected exception parsed from stacktrace, but got %q", exception.Exception.Stacktrace())
+	}
+}
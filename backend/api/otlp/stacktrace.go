@@ -0,0 +1,176 @@
+package otlp
+
+import (
+	"backend/api/event"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// OTel semantic convention attribute keys
+// of exceptions.
+const (
+	keyExceptionType       = "exception.type"
+	keyExceptionMessage    = "exception.message"
+	keyExceptionStacktrace = "exception.stacktrace"
+	keyExceptionEscaped    = "exception.escaped"
+)
+
+// unknownFrame stands in for the frames of
+// exceptions reported without a stacktrace
+// that could be parsed, as exceptions must
+// have at least one frame.
+var unknownFrame = event.Frame{
+	MethodName: "unknown",
+	FileName:   "unknown",
+}
+
+// isException returns true if the attributes
+// describe an exception.
+func isException(attrs attributes) bool {
+	return attrs.has(keyExceptionType) || attrs.has(keyExceptionStacktrace)
+}
+
+// toException maps OTel exception attributes
+// onto an exception.
+func toException(attrs attributes, handled bool) *event.Exception {
+	units := parseStacktrace(attrs.str(keyExceptionStacktrace))
+	if len(units) == 0 {
+		units = append(units, event.ExceptionUnit{})
+	}
+
+	thrown := &units[len(units)-1]
+
+	if exType := attrs.str(keyExceptionType); exType != "" {
+		thrown.Type = exType
+	}
+
+	if thrown.Type == "" {
+		thrown.Type = "unknown"
+	}
+
+	if message := attrs.str(keyExceptionMessage); message != "" && thrown.Message == "" {
+		thrown.Message = message
+	}
+
+	if len(thrown.Frames) == 0 {
+		thrown.Frames = event.Frames{unknownFrame}
+	}
+
+	threadName := attrs.str(keyThreadName)
+	if threadName == "" {
+		threadName = "main"
+	}
+
+	return &event.Exception{
+		Handled:    handled,
+		Exceptions: units,
+		Threads: event.Threads{
+			{
+				Name:   threadName,
+				Frames: thrown.Frames,
+			},
+		},
+	}
+}
+
+// parseStacktrace parses a JVM style stacktrace into
+// exception units. Units are ordered from the root
+// cause to the thrown exception, the same as the
+// SDKs report them. Suppressed exceptions are left
+// out.
+func parseStacktrace(stacktrace string) (units event.ExceptionUnits) {
+	suppressed := false
+
+	for _, raw := range strings.Split(stacktrace, "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" {
+			continue
+		}
+
+		if title, ok := strings.CutPrefix(line, "Caused by:"); ok {
+			// causes of suppressed exceptions
+			// are indented
+			if suppressed && raw != strings.TrimLeft(raw, " \t") {
+				continue
+			}
+			suppressed = false
+			units = append(units, parseTitle(title))
+			continue
+		}
+
+		if strings.HasPrefix(line, "Suppressed:") {
+			suppressed = true
+			continue
+		}
+
+		if suppressed || strings.HasPrefix(line, "...") {
+			continue
+		}
+
+		if frame, ok := strings.CutPrefix(line, "at "); ok {
+			if len(units) == 0 {
+				units = append(units, event.ExceptionUnit{})
+			}
+			i := len(units) - 1
+			units[i].Frames = append(units[i].Frames, parseFrame(frame))
+			continue
+		}
+
+		if len(units) == 0 {
+			units = append(units, parseTitle(line))
+			continue
+		}
+
+		// lines before the first frame continue
+		// a multi-line message
+		i := len(units) - 1
+		if len(units[i].Frames) == 0 {
+			units[i].Message += "\n" + line
+		}
+	}
+
+	slices.Reverse(units)
+
+	return
+}
+
+// parseTitle parses the type & message of an
+// exception from a line like `type: message`.
+func parseTitle(title string) event.ExceptionUnit {
+	exType, message, _ := strings.Cut(strings.TrimSpace(title), event.GenericPrefix)
+
+	return event.ExceptionUnit{
+		Type:    strings.TrimSpace(exType),
+		Message: message,
+	}
+}
+
+// parseFrame parses a JVM stack frame like
+// `module/class.method(file:line)`.
+func parseFrame(frame string) (f event.Frame) {
+	code, location, _ := strings.Cut(frame, "(")
+	location = strings.TrimSuffix(location, ")")
+
+	if i := strings.LastIndex(code, "/"); i != -1 {
+		f.ModuleName = strings.TrimRight(code[:i], "/")
+		code = code[i+1:]
+	}
+
+	if i := strings.LastIndex(code, "."); i != -1 {
+		f.ClassName = code[:i]
+		f.MethodName = code[i+1:]
+	} else {
+		f.MethodName = code
+	}
+
+	f.FileName = location
+	if i := strings.LastIndex(location, ":"); i != -1 {
+		if line, err := strconv.Atoi(location[i+1:]); err == nil {
+			f.FileName = location[:i]
+			f.LineNum = line
+		}
+	}
+
+	return
+}
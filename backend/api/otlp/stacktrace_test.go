@@ -0,0 +1,103 @@
+package otlp
+
+import (
+	"backend/api/event"
+	"os"
+	"testing"
+)
+
+func TestParseStacktraceRoundTrip(t *testing.T) {
+	bytes, err := os.ReadFile("../event/exception_stacktrace_one.txt")
+	if err != nil {
+		panic(err)
+	}
+
+	expected := string(bytes)
+	exception := event.Exception{
+		Exceptions: parseStacktrace(expected),
+	}
+
+	if len(exception.Exceptions) != 1 {
+		t.Fatalf("Expected %d exception, but got %d", 1, len(exception.Exceptions))
+	}
+
+	if got := exception.Stacktrace(); expected != got {
+		t.Errorf("Expected %q stacktrace, but got %q", expected, got)
+	}
+}
+
+func TestParseStacktraceNested(t *testing.T) {
+	stacktrace := `java.lang.RuntimeException: Unable to start activity
+	at android.app.ActivityThread.performLaunchActivity(ActivityThread.java:3645)
+	at java.base/java.lang.Thread.run(Thread.java:833)
+	Suppressed: java.io.IOException: close failed
+		at sh.measure.sample.Stream.close(Stream.kt:10)
+		Caused by: java.lang.IllegalStateException: closed
+			at sh.measure.sample.Stream.check(Stream.kt:20)
+Caused by: java.lang.NullPointerException: Attempt to invoke virtual method
+on a null object reference
+	at sh.measure.sample.MainActivity.onCreate(MainActivity.kt:42)
+	at android.app.Activity.performCreate(Native Method)
+	... 12 more`
+
+	units := parseStacktrace(stacktrace)
+
+	if len(units) != 2 {
+		t.Fatalf("Expected %d exceptions, but got %d", 2, len(units))
+	}
+
+	cause := units[0]
+	thrown := units[1]
+
+	if thrown.Type != "java.lang.RuntimeException" || thrown.Message != "Unable to start activity" {
+		t.Errorf("Expected thrown RuntimeException, but got %+v", thrown)
+	}
+
+	if len(thrown.Frames) != 2 {
+		t.Fatalf("Expected %d frames, but got %d", 2, len(thrown.Frames))
+	}
+
+	expectedFrame := event.Frame{
+		ModuleName: "java.base",
+		ClassName:  "java.lang.Thread",
+		MethodName: "run",
+		FileName:   "Thread.java",
+		LineNum:    833,
+	}
+
+	if thrown.Frames[1] != expectedFrame {
+		t.Errorf("Expected %+v frame, but got %+v", expectedFrame, thrown.Frames[1])
+	}
+
+	expectedMessage := "Attempt to invoke virtual method\non a null object reference"
+	if cause.Type != "java.lang.NullPointerException" || cause.Message != expectedMessage {
+		t.Errorf("Expected NullPointerException cause, but got %+v", cause)
+	}
+
+	if len(cause.Frames) != 2 {
+		t.Fatalf("Expected %d frames, but got %d", 2, len(cause.Frames))
+	}
+
+	if cause.Frames[1].FileName != "Native Method" || cause.Frames[1].LineNum != 0 {
+		t.Errorf("Expected native method frame, but got %+v", cause.Frames[1])
+	}
+}
+
+func TestToExceptionWithoutStacktrace(t *testing.T) {
+	exception := toException(attributes{
+		keyExceptionType:    stringValue("java.lang.IllegalStateException"),
+		keyExceptionMessage: stringValue("boom"),
+	}, true)
+
+	if exception.GetTitle() != "java.lang.IllegalStateException: boom" {
+		t.Errorf("Expected title %q, but got %q", "java.lang.IllegalStateException: boom", exception.GetTitle())
+	}
+
+	if exception.GetFileName() != unknownFrame.FileName {
+		t.Errorf("Expected file name %q, but got %q", unknownFrame.FileName, exception.GetFileName())
+	}
+
+	if len(exception.Threads) != 1 || exception.Threads[0].Name != "main" {
+		t.Errorf("Expected a single main thread, but got %+v", exception.Threads)
+	}
+}
@@ -0,0 +1,122 @@
+package otlp
+
+import (
+	"backend/api/event"
+	"encoding/hex"
+
+	"github.com/google/uuid"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+)
+
+// exceptionEventName is the name of span
+// events that record exceptions.
+const exceptionEventName = "exception"
+
+// spanKinds maps OTel span kinds
+// to span kinds.
+var spanKinds = map[tracepb.Span_SpanKind]string{
+	tracepb.Span_SPAN_KIND_INTERNAL: event.SpanKindInternal,
+	tracepb.Span_SPAN_KIND_SERVER:   event.SpanKindServer,
+	tracepb.Span_SPAN_KIND_CLIENT:   event.SpanKindClient,
+	tracepb.Span_SPAN_KIND_PRODUCER: event.SpanKindProducer,
+	tracepb.Span_SPAN_KIND_CONSUMER: event.SpanKindConsumer,
+}
+
+// ConvertTraces converts the spans of an OTLP
+// traces export request to span events. Exception
+// events of spans become exception events. Other
+// span events are left out.
+func ConvertTraces(appId uuid.UUID, req *coltracepb.ExportTraceServiceRequest) (result Result) {
+	for _, resourceSpans := range req.GetResourceSpans() {
+		resource := resourceSpans.GetResource().GetAttributes()
+		for _, scopeSpans := range resourceSpans.GetScopeSpans() {
+			for _, span := range scopeSpans.GetSpans() {
+				attrs := newAttributes(resource, span.GetAttributes())
+				s, err := toSpan(appId, attrs, span)
+				if err != nil {
+					result.reject(1, err)
+					continue
+				}
+				result.Events = append(result.Events, s)
+
+				for _, spanEvent := range span.GetEvents() {
+					if spanEvent.GetName() != exceptionEventName {
+						continue
+					}
+					ev, err := toExceptionEvent(s, newAttributes(resource, span.GetAttributes(), spanEvent.GetAttributes()), spanEvent)
+					if err != nil {
+						continue
+					}
+					result.Events = append(result.Events, ev)
+				}
+			}
+		}
+	}
+
+	return
+}
+
+// toSpan converts an OTel span to
+// a span event.
+func toSpan(appId uuid.UUID, attrs attributes, span *tracepb.Span) (ev event.EventField, err error) {
+	attribute, err := toAttribute(attrs)
+	if err != nil {
+		return
+	}
+
+	startTime := unixNano(span.GetStartTimeUnixNano())
+
+	ev = event.EventField{
+		ID:        uuid.New(),
+		AppID:     appId,
+		SessionID: toSessionID(attrs, attribute),
+		Timestamp: startTime,
+		Type:      event.TypeSpan,
+		Attribute: attribute,
+		Span: &event.Span{
+			TraceID:       hex.EncodeToString(span.GetTraceId()),
+			SpanID:        hex.EncodeToString(span.GetSpanId()),
+			ParentID:      hex.EncodeToString(span.GetParentSpanId()),
+			Name:          span.GetName(),
+			Kind:          spanKinds[span.GetKind()],
+			Status:        uint8(span.GetStatus().GetCode()),
+			StatusMessage: span.GetStatus().GetMessage(),
+			StartTime:     startTime,
+			EndTime:       unixNano(span.GetEndTimeUnixNano()),
+			Attributes:    newAttributes(span.GetAttributes()).toMap(),
+		},
+	}
+
+	err = ev.Validate()
+
+	return
+}
+
+// toExceptionEvent converts an exception
+// event of a span to an event.
+func toExceptionEvent(s event.EventField, attrs attributes, spanEvent *tracepb.Span_Event) (ev event.EventField, err error) {
+	timestamp := unixNano(spanEvent.GetTimeUnixNano())
+	if timestamp.IsZero() {
+		timestamp = s.Span.EndTime
+	}
+
+	attribute, err := toAttribute(attrs)
+	if err != nil {
+		return
+	}
+
+	ev = event.EventField{
+		ID:        uuid.New(),
+		AppID:     s.AppID,
+		SessionID: s.SessionID,
+		Timestamp: timestamp,
+		Type:      event.TypeException,
+		Attribute: attribute,
+		Exception: toException(attrs, !attrs.flag(keyExceptionEscaped)),
+	}
+
+	err = ev.Validate()
+
+	return
+}
@@ -78,9 +78,11 @@ func ComputeExceptions(ctx context.Context, appId *uuid.UUID, events []event.Eve
 		var groupId *uuid.UUID
 
 		if !event.Exception.Handled {
+			// merged groups resolve to
+			// their surviving group
 			stmt := sqlf.PostgreSQL.
 				From("public.unhandled_exception_groups").
-				Select("id").
+				Select("coalesce(merged_into, id)").
 				Where("app_id = ?", appId).
 				Where("fingerprint = ?", event.Exception.Fingerprint)
 
@@ -117,6 +119,51 @@ func ComputeExceptions(ctx context.Context, appId *uuid.UUID, events []event.Eve
 	return result, nil
 }
 
+// ComputeNativeCrashes computes native crashes
+// for session replay.
+func ComputeNativeCrashes(ctx context.Context, appId *uuid.UUID, events []event.EventField) (result []ThreadGrouper, err error) {
+	for _, event := range events {
+
+		var groupId *uuid.UUID
+
+		// native crashes are bucketed alongside
+		// unhandled exceptions
+		stmt := sqlf.PostgreSQL.
+			From("public.unhandled_exception_groups").
+			Select("coalesce(merged_into, id)").
+			Where("app_id = ?", appId).
+			Where("fingerprint = ?", event.NativeCrash.Fingerprint)
+
+		defer stmt.Close()
+
+		row := server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...)
+
+		if err := row.Scan(&groupId); err != nil {
+			return nil, err
+		}
+
+		nativeCrash := Exception{
+			event.Type,
+			event.UserTriggered,
+			groupId,
+			event.NativeCrash.GetType(),
+			event.NativeCrash.GetMessage(),
+			event.NativeCrash.GetMethodName(),
+			event.NativeCrash.GetFileName(),
+			event.NativeCrash.GetLineNumber(),
+			event.Attribute.ThreadName,
+			false,
+			event.NativeCrash.Stacktrace(),
+			event.NativeCrash.Foreground,
+			event.Timestamp,
+			event.Attachments,
+		}
+		result = append(result, nativeCrash)
+	}
+
+	return result, nil
+}
+
 // ComputeANR computes anrs
 // for session replay.
 func ComputeANRs(ctx context.Context, appId *uuid.UUID, events []event.EventField) (result []ThreadGrouper, err error) {
@@ -124,9 +171,11 @@ func ComputeANRs(ctx context.Context, appId *uuid.UUID, events []event.EventFiel
 
 		var groupId *uuid.UUID
 
+		// merged groups resolve to
+		// their surviving group
 		stmt := sqlf.PostgreSQL.
 			From("public.anr_groups").
-			Select("id").
+			Select("coalesce(merged_into, id)").
 			Where("app_id = ?", appId).
 			Where("fingerprint = ?", event.ANR.Fingerprint)
 
@@ -0,0 +1,43 @@
+package replay
+
+import (
+	"backend/api/event"
+	"time"
+)
+
+// Custom represents custom events
+// suitable for session replay.
+type Custom struct {
+	EventType  string `json:"event_type"`
+	ThreadName string `json:"thread_name"`
+	*event.Custom
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// GetThreadName provides the name of the thread
+// where the custom event took place.
+func (c Custom) GetThreadName() string {
+	return c.ThreadName
+}
+
+// GetTimestamp provides the timestamp of
+// the custom event.
+func (c Custom) GetTimestamp() time.Time {
+	return c.Timestamp
+}
+
+// ComputeCustom computes the custom
+// events for session replay.
+func ComputeCustom(events []event.EventField) (result []ThreadGrouper) {
+	for _, event := range events {
+		custom := Custom{
+			event.Type,
+			event.Attribute.ThreadName,
+			event.Custom,
+			event.Timestamp,
+		}
+		result = append(result, custom)
+	}
+
+	return
+}
@@ -17,6 +17,8 @@ breakdown of each.
 - **navigation** - operations related to app's navigation events
 - **launch** - operations related to app's start timings and events
 - **exit** - operations related to app's stop timings and events
+- **span** - operations related to app's timed operations like traces
+- **custom** - operations related to app's own product events
 
 Additionally, this package also contains glue code to massage the
 shape of session replay objects.
@@ -0,0 +1,45 @@
+package replay
+
+import (
+	"backend/api/event"
+	"time"
+)
+
+// Span represents span events
+// suitable for session replay.
+type Span struct {
+	EventType  string `json:"event_type"`
+	ThreadName string `json:"thread_name"`
+	*event.Span
+	Duration  time.Duration `json:"duration"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// GetThreadName provides the name of the thread
+// where the span was started.
+func (s Span) GetThreadName() string {
+	return s.ThreadName
+}
+
+// GetTimestamp provides the timestamp of
+// the span event.
+func (s Span) GetTimestamp() time.Time {
+	return s.Timestamp
+}
+
+// ComputeSpans computes the span
+// events for session replay.
+func ComputeSpans(events []event.EventField) (result []ThreadGrouper) {
+	for _, event := range events {
+		span := Span{
+			event.Type,
+			event.Attribute.ThreadName,
+			event.Span,
+			time.Duration(event.Span.Duration().Milliseconds()),
+			event.Timestamp,
+		}
+		result = append(result, span)
+	}
+
+	return
+}
@@ -13,8 +13,10 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/resource"
 	"go.opentelemetry.io/otel/sdk/trace"
 	"google.golang.org/grpc/credentials"
@@ -38,6 +40,23 @@ type ClickhouseConfig struct {
 	DSN string
 }
 
+type SMTPConfig struct {
+	/* hostname of the smtp server */
+	Host string
+
+	/* port of the smtp server */
+	Port string
+
+	/* username for smtp authentication */
+	Username string
+
+	/* password for smtp authentication */
+	Password string
+
+	/* sender email address */
+	From string
+}
+
 type ServerConfig struct {
 	PG                         PostgresConfig
 	CH                         ClickhouseConfig
@@ -57,9 +76,25 @@ type ServerConfig struct {
 	OAuthGitHubKey             string
 	OAuthGitHubSecret          string
 	OAuthGoogleKey             string
+	OIDCDiscoveryURL           string
+	OIDCClientID               string
+	OIDCClientSecret           string
+	OIDCScopes                 []string
+	OIDCEmailClaim             string
+	OIDCNameClaim              string
+	OIDCTrustEmail             bool
+	OIDCAutoJoinDomains        []string
+	OIDCAutoJoinTeamID         string
+	OIDCAutoJoinRole           string
+	PasswordSignin             bool
+	MagicLinkSignin            bool
 	AccessTokenSecret          []byte
 	RefreshTokenSecret         []byte
 	OtelServiceName            string
+	IngestWorkers              int
+	ProguardSymbolicator       string
+	ProguardCacheSize          int
+	SMTP                       SMTPConfig
 }
 
 func NewConfig() *ServerConfig {
@@ -139,6 +174,73 @@ func NewConfig() *ServerConfig {
 		log.Println("OAUTH_GOOGLE_KEY env var is not set, dashboard authn won't work")
 	}
 
+	oidcDiscoveryURL := os.Getenv("OIDC_DISCOVERY_URL")
+	if oidcDiscoveryURL == "" {
+		log.Println("OIDC_DISCOVERY_URL env var is not set, oidc sign in won't work")
+	}
+
+	oidcClientID := os.Getenv("OIDC_CLIENT_ID")
+	if oidcDiscoveryURL != "" && oidcClientID == "" {
+		log.Println("OIDC_CLIENT_ID env var is not set, oidc sign in won't work")
+	}
+
+	oidcClientSecret := os.Getenv("OIDC_CLIENT_SECRET")
+	if oidcDiscoveryURL != "" && oidcClientSecret == "" {
+		log.Println("OIDC_CLIENT_SECRET env var is not set, oidc sign in won't work")
+	}
+
+	oidcScopes := strings.Fields(os.Getenv("OIDC_SCOPES"))
+	if oidcDiscoveryURL != "" && len(oidcScopes) == 0 {
+		log.Println("using default value of OIDC_SCOPES")
+		oidcScopes = []string{"openid", "email", "profile"}
+	}
+
+	oidcEmailClaim := os.Getenv("OIDC_EMAIL_CLAIM")
+	if oidcDiscoveryURL != "" && oidcEmailClaim == "" {
+		log.Println("using default value of OIDC_EMAIL_CLAIM")
+		oidcEmailClaim = "email"
+	}
+
+	oidcNameClaim := os.Getenv("OIDC_NAME_CLAIM")
+	if oidcDiscoveryURL != "" && oidcNameClaim == "" {
+		log.Println("using default value of OIDC_NAME_CLAIM")
+		oidcNameClaim = "name"
+	}
+
+	oidcTrustEmail := os.Getenv("OIDC_TRUST_EMAIL") == "true"
+
+	var oidcAutoJoinDomains []string
+	for _, domain := range strings.Split(os.Getenv("OIDC_AUTO_JOIN_DOMAINS"), ",") {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain != "" {
+			oidcAutoJoinDomains = append(oidcAutoJoinDomains, domain)
+		}
+	}
+
+	oidcAutoJoinTeamID := os.Getenv("OIDC_AUTO_JOIN_TEAM_ID")
+	if len(oidcAutoJoinDomains) > 0 && oidcAutoJoinTeamID == "" {
+		log.Println("OIDC_AUTO_JOIN_TEAM_ID env var is not set, oidc auto join won't work")
+	}
+
+	oidcAutoJoinRole := os.Getenv("OIDC_AUTO_JOIN_ROLE")
+	if len(oidcAutoJoinDomains) > 0 && oidcAutoJoinRole == "" {
+		log.Println("using default value of OIDC_AUTO_JOIN_ROLE")
+		oidcAutoJoinRole = "viewer"
+	}
+
+	passwordSignin := os.Getenv("AUTH_PASSWORD_ENABLED") == "true"
+	magicLinkSignin := os.Getenv("AUTH_MAGIC_LINK_ENABLED") == "true"
+
+	smtpHost := os.Getenv("SMTP_HOST")
+	if smtpHost == "" && (passwordSignin || magicLinkSignin) {
+		log.Println("SMTP_HOST env var not set, verification, password reset & magic link emails won't work")
+	}
+
+	smtpFrom := os.Getenv("SMTP_FROM_EMAIL")
+	if smtpFrom == "" && (passwordSignin || magicLinkSignin) {
+		log.Println("SMTP_FROM_EMAIL env var not set, verification, password reset & magic link emails won't work")
+	}
+
 	atSecret := os.Getenv("SESSION_ACCESS_SECRET")
 	if atSecret == "" {
 		log.Println("SESSION_ACCESS_SECRET env var is not set, dashboard authn won't work")
@@ -164,6 +266,24 @@ func NewConfig() *ServerConfig {
 		log.Println("OTEL_SERVICE_NAME env var is not set, o11y will not work")
 	}
 
+	ingestWorkers, err := strconv.Atoi(os.Getenv("INGEST_WORKERS"))
+	if err != nil || ingestWorkers < 1 {
+		log.Println("using default value of INGEST_WORKERS")
+		ingestWorkers = 4
+	}
+
+	proguardSymbolicator := os.Getenv("PROGUARD_SYMBOLICATOR")
+	if proguardSymbolicator != "service" && proguardSymbolicator != "builtin" {
+		log.Println("using default value of PROGUARD_SYMBOLICATOR")
+		proguardSymbolicator = "service"
+	}
+
+	proguardCacheSize, err := strconv.Atoi(os.Getenv("PROGUARD_CACHE_SIZE"))
+	if err != nil || proguardCacheSize < 1 {
+		log.Println("using default value of PROGUARD_CACHE_SIZE")
+		proguardCacheSize = 16
+	}
+
 	endpoint := os.Getenv("AWS_ENDPOINT_URL")
 
 	return &ServerConfig{
@@ -189,9 +309,31 @@ func NewConfig() *ServerConfig {
 		OAuthGitHubKey:             oauthGitHubKey,
 		OAuthGitHubSecret:          oauthGitHubSecret,
 		OAuthGoogleKey:             oauthGoogleKey,
+		OIDCDiscoveryURL:           oidcDiscoveryURL,
+		OIDCClientID:               oidcClientID,
+		OIDCClientSecret:           oidcClientSecret,
+		OIDCScopes:                 oidcScopes,
+		OIDCEmailClaim:             oidcEmailClaim,
+		OIDCNameClaim:              oidcNameClaim,
+		OIDCTrustEmail:             oidcTrustEmail,
+		OIDCAutoJoinDomains:        oidcAutoJoinDomains,
+		OIDCAutoJoinTeamID:         oidcAutoJoinTeamID,
+		OIDCAutoJoinRole:           oidcAutoJoinRole,
+		PasswordSignin:             passwordSignin,
+		MagicLinkSignin:            magicLinkSignin,
 		AccessTokenSecret:          []byte(atSecret),
 		RefreshTokenSecret:         []byte(rtSecret),
 		OtelServiceName:            otelServiceName,
+		IngestWorkers:              ingestWorkers,
+		ProguardSymbolicator:       proguardSymbolicator,
+		ProguardCacheSize:          proguardCacheSize,
+		SMTP: SMTPConfig{
+			Host:     smtpHost,
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     smtpFrom,
+		},
 	}
 }
 
@@ -267,3 +409,46 @@ func (sc ServerConfig) InitTracer() func(context.Context) error {
 
 	return exporter.Shutdown
 }
+
+func (sc ServerConfig) InitMeter() func(context.Context) error {
+	otelCollectorURL := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+	otelInsecureMode := os.Getenv("OTEL_INSECURE_MODE")
+	otelServiceName := sc.OtelServiceName
+
+	var secureOption otlpmetricgrpc.Option
+
+	if strings.ToLower(otelInsecureMode) == "false" || otelInsecureMode == "0" || strings.ToLower(otelInsecureMode) == "f" {
+		secureOption = otlpmetricgrpc.WithTLSCredentials(credentials.NewClientTLSFromCert(nil, ""))
+	} else {
+		secureOption = otlpmetricgrpc.WithInsecure()
+	}
+
+	exporter, err := otlpmetricgrpc.New(
+		context.Background(),
+		secureOption,
+		otlpmetricgrpc.WithEndpoint(otelCollectorURL),
+	)
+
+	if err != nil {
+		log.Fatalf("Failed to create metric exporter: %v", err)
+	}
+	resources, err := resource.New(
+		context.Background(),
+		resource.WithAttributes(
+			attribute.String("service.name", otelServiceName),
+			attribute.String("library.language", "go"),
+		),
+	)
+	if err != nil {
+		log.Fatalf("Could not set resources: %v", err)
+	}
+
+	provider := metric.NewMeterProvider(
+		metric.WithReader(metric.NewPeriodicReader(exporter)),
+		metric.WithResource(resources),
+	)
+
+	otel.SetMeterProvider(provider)
+
+	return provider.Shutdown
+}
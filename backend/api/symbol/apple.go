@@ -0,0 +1,283 @@
+package symbol
+
+import (
+	"archive/zip"
+	"backend/api/event"
+	"bytes"
+	"context"
+	"debug/macho"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// TypeDsym represents the "dsym" type
+// of mapping symbolication.
+const TypeDsym = "dsym"
+
+// dwarfDir is the directory inside a dSYM bundle
+// containing the debug information binaries.
+const dwarfDir = ".dSYM/Contents/Resources/DWARF/"
+
+// appImage is the key of the debug image of
+// the app's binary when the dSYM is uploaded
+// as a single Mach-O binary.
+const appImage = ""
+
+// maxZipEntrySize is the maximum allowed size in
+// bytes of a zip archive's entry after decompression.
+const maxZipEntrySize = 1024 * 1024 * 1024
+
+// ErrZipEntryTooLarge is returned when an entry
+// of a zip archive decompresses to more than
+// the maximum allowed size.
+var ErrZipEntryTooLarge = errors.New("zip entry exceeds maximum allowed size")
+
+// zipMagic is the magic number of
+// zip archives.
+var zipMagic = []byte("PK\x03\x04")
+
+// machoMagics are the magic numbers of thin and
+// fat Mach-O binaries in either byte order.
+var machoMagics = [][]byte{
+	{0xfe, 0xed, 0xfa, 0xce},
+	{0xce, 0xfa, 0xed, 0xfe},
+	{0xfe, 0xed, 0xfa, 0xcf},
+	{0xcf, 0xfa, 0xed, 0xfe},
+	{0xca, 0xfe, 0xba, 0xbe},
+}
+
+// ValidateDsym validates that the contents are either a
+// zip archive of one or more dSYM bundles or a single
+// Mach-O debug information binary.
+func ValidateDsym(r io.Reader) error {
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return errors.New(`dSYM file is too small`)
+	}
+
+	if isZip(magic) || isMachO(magic) {
+		return nil
+	}
+
+	return errors.New(`dSYM file must be a zip archive of dSYM bundles or a Mach-O binary`)
+}
+
+// newDebugImage creates a debug image from a thin or fat
+// Mach-O binary. The arm64 slice is preferred for fat
+// binaries as it is the architecture of all supported
+// Apple devices.
+func newDebugImage(name string, r io.ReaderAt) (image *debugImage, err error) {
+	f, err := macho.NewFile(r)
+	if err != nil {
+		fat, fatErr := macho.NewFatFile(r)
+		if fatErr != nil {
+			return nil, err
+		}
+
+		if len(fat.Arches) == 0 {
+			return nil, fmt.Errorf(`no architectures found in %q`, name)
+		}
+
+		f = fat.Arches[0].File
+		for _, arch := range fat.Arches {
+			if arch.Cpu == macho.CpuArm64 {
+				f = arch.File
+				break
+			}
+		}
+
+		err = nil
+	}
+
+	image = &debugImage{
+		name: name,
+	}
+
+	if text := f.Segment("__TEXT"); text != nil {
+		image.textAddr = text.Addr
+	}
+
+	// binaries without debug information can still
+	// be symbolicated using the symbol table
+	if data, err := f.DWARF(); err == nil {
+		image.dwarf = data
+	}
+
+	if f.Symtab != nil {
+		for _, sym := range f.Symtab.Syms {
+			// skip debugging entries and symbols
+			// not defined in a section
+			if sym.Type&0xe0 != 0 || sym.Type&0x0e != 0x0e {
+				continue
+			}
+			image.symbols = append(image.symbols, imageSymbol{
+				name: strings.TrimPrefix(sym.Name, "_"),
+				addr: sym.Value,
+			})
+		}
+	}
+
+	sort.Slice(image.symbols, func(i, j int) bool {
+		return image.symbols[i].addr < image.symbols[j].addr
+	})
+
+	return
+}
+
+// openDebugImages opens the debug images of the named binaries
+// from the dSYM contents. The contents are either a zip archive
+// of dSYM bundles or a single Mach-O binary. A single binary is
+// keyed by appImage as it only carries the app's own symbols.
+func openDebugImages(r io.ReaderAt, size int64, names []string) (images map[string]*debugImage, err error) {
+	images = make(map[string]*debugImage)
+
+	magic := make([]byte, 4)
+	if _, err = r.ReadAt(magic, 0); err != nil {
+		return
+	}
+
+	if !isZip(magic) {
+		image, err := newDebugImage(appImage, r)
+		if err != nil {
+			return nil, err
+		}
+		images[appImage] = image
+		return images, nil
+	}
+
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return
+	}
+
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() || !strings.Contains(file.Name, dwarfDir) {
+			continue
+		}
+
+		name := path.Base(file.Name)
+		if _, exists := images[name]; exists || !slices.Contains(names, name) {
+			continue
+		}
+
+		data, err := readZipEntry(file, maxZipEntrySize)
+		if err != nil {
+			return nil, err
+		}
+
+		image, err := newDebugImage(name, bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+
+		images[name] = image
+	}
+
+	return
+}
+
+// binaryNames returns the unique binary names
+// of the native frames in the batch.
+func (b SymbolBatch) binaryNames() (names []string) {
+	seen := make(map[string]struct{})
+
+	b.eachNativeFrame(func(frame *event.Frame) {
+		if _, ok := seen[frame.BinaryName]; ok {
+			return
+		}
+		seen[frame.BinaryName] = struct{}{}
+		names = append(names, frame.BinaryName)
+	})
+
+	sort.Strings(names)
+
+	return
+}
+
+// symbolicateFrames resolves the native frames of the
+// batch using the debug images. Frames of binaries not
+// present in the dSYM are left untouched.
+func (b *SymbolBatch) symbolicateFrames(images map[string]*debugImage) {
+	b.resolveFrames(func(frame *event.Frame) *debugImage {
+		image, ok := images[frame.BinaryName]
+		if !ok && frame.InApp {
+			image = images[appImage]
+		}
+		return image
+	})
+}
+
+// symbolicateApple symbolicates the native frames of
+// the batch using the dSYM stored against the key.
+func (s Symbolicator) symbolicateApple(ctx context.Context, batch SymbolBatch, key string) error {
+	if s.opts.Fetch == nil {
+		return errors.New(`failed to symbolicate, no fetcher configured for dSYM files`)
+	}
+
+	names := batch.binaryNames()
+	if len(names) == 0 {
+		return nil
+	}
+
+	return fetchFile(ctx, s.opts.Fetch, key, func(r io.ReaderAt, size int64) error {
+		images, err := openDebugImages(r, size, names)
+		if err != nil {
+			return err
+		}
+
+		batch.symbolicateFrames(images)
+
+		return nil
+	})
+}
+
+// readZipEntry reads the decompressed contents of
+// the zip archive's entry. Entries declaring or
+// decompressing to more than limit bytes fail
+// with ErrZipEntryTooLarge.
+func readZipEntry(file *zip.File, limit uint64) (data []byte, err error) {
+	if file.UncompressedSize64 > limit {
+		return nil, fmt.Errorf("%w: %s", ErrZipEntryTooLarge, file.Name)
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return
+	}
+	defer rc.Close()
+
+	// the declared size can't be trusted, so
+	// reading is bounded regardless
+	data, err = io.ReadAll(io.LimitReader(rc, int64(limit)+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if uint64(len(data)) > limit {
+		return nil, fmt.Errorf("%w: %s", ErrZipEntryTooLarge, file.Name)
+	}
+
+	return
+}
+
+// isZip returns true if the magic number
+// belongs to a zip archive.
+func isZip(magic []byte) bool {
+	return bytes.Equal(magic, zipMagic)
+}
+
+// isMachO returns true if the magic number
+// belongs to a Mach-O binary.
+func isMachO(magic []byte) bool {
+	for _, m := range machoMagics {
+		if bytes.Equal(magic, m) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,221 @@
+package symbol
+
+import (
+	"archive/zip"
+	"backend/api/event"
+	"backend/api/platform"
+	"bytes"
+	"compress/flate"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func newTestImage() *debugImage {
+	return &debugImage{
+		name:     "DemoApp",
+		textAddr: 0x100000000,
+		symbols: []imageSymbol{
+			{name: "main", addr: 0x100004000},
+			{name: "$s7DemoApp14ViewControllerC5crashyyF", addr: 0x100008000},
+			{name: "$s7DemoApp14ViewControllerC11viewDidLoadyyF", addr: 0x100008400},
+		},
+	}
+}
+
+// newTestArchive creates a zip archive with a
+// single deflated entry of data. The entry's
+// header declares size as its uncompressed size.
+func newTestArchive(t *testing.T, data []byte, size uint64) []byte {
+	var compressed bytes.Buffer
+	deflater, _ := flate.NewWriter(&compressed, flate.BestCompression)
+	deflater.Write(data)
+	deflater.Close()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	header := &zip.FileHeader{
+		Name:               "DemoApp.dSYM/Contents/Resources/DWARF/DemoApp",
+		Method:             zip.Deflate,
+		CompressedSize64:   uint64(compressed.Len()),
+		UncompressedSize64: size,
+	}
+	entry, err := w.CreateRaw(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry.Write(compressed.Bytes())
+	w.Close()
+
+	return buf.Bytes()
+}
+
+// newTestZip provides the single entry of
+// an archive from newTestArchive.
+func newTestZip(t *testing.T, data []byte, size uint64) *zip.File {
+	archive := newTestArchive(t, data, size)
+	r, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return r.File[0]
+}
+
+func TestReadZipEntry(t *testing.T) {
+	data := make([]byte, 4096)
+
+	got, err := readZipEntry(newTestZip(t, data, uint64(len(data))), 8192)
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	if len(got) != len(data) {
+		t.Errorf("Expected %d bytes, but got %d", len(data), len(got))
+	}
+
+	if _, err := readZipEntry(newTestZip(t, data, uint64(len(data))), 1024); !errors.Is(err, ErrZipEntryTooLarge) {
+		t.Errorf("Expected %v, but got %v", ErrZipEntryTooLarge, err)
+	}
+
+	// entry declaring less than it
+	// decompresses to
+	if _, err := readZipEntry(newTestZip(t, data, 512), 1024); err == nil {
+		t.Error("Expected error for entry exceeding its declared size, but got nil")
+	}
+}
+
+func TestValidateDsym(t *testing.T) {
+	valid := [][]byte{
+		[]byte("PK\x03\x04rest of the archive"),
+		{0xcf, 0xfa, 0xed, 0xfe, 0x0c, 0x00, 0x00, 0x01},
+		{0xca, 0xfe, 0xba, 0xbe, 0x00, 0x00, 0x00, 0x02},
+	}
+
+	for _, v := range valid {
+		if err := ValidateDsym(bytes.NewReader(v)); err != nil {
+			t.Errorf("Expected nil error for %x, but got %v", v, err)
+		}
+	}
+
+	invalid := [][]byte{
+		[]byte("PK"),
+		[]byte("com.example.MainActivity -> a:"),
+	}
+
+	for _, v := range invalid {
+		if err := ValidateDsym(bytes.NewReader(v)); err == nil {
+			t.Errorf("Expected error for %q, but got nil", v)
+		}
+	}
+}
+
+func TestDebugImageLookup(t *testing.T) {
+	image := newTestImage()
+
+	info, ok := image.lookup(0x8010)
+	if !ok {
+		t.Fatal("Expected symbol to be found")
+	}
+
+	expected := "$s7DemoApp14ViewControllerC5crashyyF"
+	if info.function != expected {
+		t.Errorf("Expected %q, but got %q", expected, info.function)
+	}
+
+	if _, ok := image.lookup(0x10); ok {
+		t.Error("Expected no symbol before the first symbol's address")
+	}
+}
+
+func TestSymbolicateFrames(t *testing.T) {
+	frames := event.Frames{
+		{FrameIndex: 0, BinaryName: "DemoApp", BinaryAddress: "100f14000", Offset: "32784", InApp: true},
+		{FrameIndex: 1, BinaryName: "DemoApp", BinaryAddress: "100f14000", Offset: "33792", InApp: true},
+		{FrameIndex: 2, BinaryName: "UIKitCore", BinaryAddress: "-[UIApplication sendAction:to:from:forEvent:]", Offset: "96"},
+	}
+
+	batch := SymbolBatch{
+		Events: []event.EventField{
+			{
+				Type: event.TypeException,
+				Exception: &event.Exception{
+					Exceptions: event.ExceptionUnits{{Type: "EXC_BAD_ACCESS", Frames: frames}},
+				},
+			},
+		},
+	}
+
+	if names := batch.binaryNames(); len(names) != 2 || names[0] != "DemoApp" || names[1] != "UIKitCore" {
+		t.Errorf("Expected binary names [DemoApp UIKitCore], but got %v", names)
+	}
+
+	batch.symbolicateFrames(map[string]*debugImage{appImage: newTestImage()})
+
+	got := batch.Events[0].Exception.Exceptions[0].Frames
+
+	if got[0].MethodName != "$s7DemoApp14ViewControllerC5crashyyF" {
+		t.Errorf("Expected crashing frame to be symbolicated, but got %q", got[0].MethodName)
+	}
+
+	// return address at the start of the next function
+	// must resolve to the calling function
+	if got[1].MethodName != "$s7DemoApp14ViewControllerC5crashyyF" {
+		t.Errorf("Expected calling frame to be symbolicated, but got %q", got[1].MethodName)
+	}
+
+	if got[2].MethodName != "" {
+		t.Errorf("Expected system frame to be untouched, but got %q", got[2].MethodName)
+	}
+
+	if len(batch.Errs) > 0 {
+		t.Errorf("Expected no errors, but got %v", batch.Errs)
+	}
+}
+
+func TestEventBatchingByPlatform(t *testing.T) {
+	appId, _ := uuid.Parse("06b6d6bf-99d1-4536-8f94-1cea038cf207")
+	android := event.EventField{
+		AppID: appId,
+		Type:  event.TypeException,
+		Attribute: event.Attribute{
+			AppVersion: "1.0.0",
+			AppBuild:   "1000",
+			Platform:   platform.Android,
+		},
+	}
+
+	ios := event.EventField{
+		AppID: appId,
+		Type:  event.TypeException,
+		Attribute: event.Attribute{
+			AppVersion: "1.0.0",
+			AppBuild:   "1000",
+			Platform:   platform.IOS,
+		},
+	}
+
+	store, _ := pgxpool.New(context.Background(), "")
+
+	symbolicator, _ := NewSymbolicator(&Options{
+		Origin: "http://example.com",
+		Store:  store,
+	})
+
+	batches := symbolicator.Batch([]event.EventField{android, ios})
+
+	if len(batches) != 2 {
+		t.Fatalf("Expected %d batches, got %d", 2, len(batches))
+	}
+
+	if batches[0].mappingKeyID.mappingType != TypeDsym {
+		t.Errorf("Expected %q, but got %q", TypeDsym, batches[0].mappingKeyID.mappingType)
+	}
+
+	if batches[1].mappingKeyID.mappingType != TypeProguard {
+		t.Errorf("Expected %q, but got %q", TypeProguard, batches[1].mappingKeyID.mappingType)
+	}
+}
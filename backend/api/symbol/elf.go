@@ -0,0 +1,411 @@
+package symbol
+
+import (
+	"archive/zip"
+	"backend/api/event"
+	"bytes"
+	"context"
+	"debug/elf"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/leporo/sqlf"
+)
+
+// TypeElf represents the "elf" type
+// of mapping symbolication.
+const TypeElf = "elf"
+
+// buildIDNoteName is the owner name of the
+// GNU build id note.
+const buildIDNoteName = "GNU\x00"
+
+// ntGNUBuildID is the type of the
+// GNU build id note.
+const ntGNUBuildID = 3
+
+// elfMagic is the magic number of
+// ELF binaries.
+var elfMagic = []byte("\x7fELF")
+
+// ValidateELF validates that the contents are either a
+// zip archive of one or more ELF shared libraries or a
+// single ELF shared library.
+func ValidateELF(r io.Reader) error {
+	magic := make([]byte, 4)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return errors.New(`ELF file is too small`)
+	}
+
+	if isZip(magic) || isELF(magic) {
+		return nil
+	}
+
+	return errors.New(`ELF file must be a zip archive of shared libraries or an ELF binary`)
+}
+
+// ELFBuildIDs extracts the build ids of the ELF binaries
+// from the contents. The contents are either a zip archive
+// of ELF binaries or a single ELF binary. Build ids are
+// mapped to the name of their binary.
+func ELFBuildIDs(r io.ReaderAt, size int64) (ids map[string]string, err error) {
+	ids = make(map[string]string)
+
+	err = eachELF(r, size, func(name string, f *elf.File) error {
+		if id := elfBuildID(f); id != "" {
+			ids[id] = name
+		}
+		return nil
+	})
+
+	return
+}
+
+// ELFSymbolicator offers symbolication of native
+// crashes using ELF symbol files matched by the
+// build ids of the crashing binaries.
+type ELFSymbolicator struct {
+	opts *Options
+}
+
+// NewELFSymbolicator creates a new instance of
+// ELFSymbolicator.
+func NewELFSymbolicator(opts *Options) (symbolicator *ELFSymbolicator, err error) {
+	if opts.Store == nil {
+		err = fmt.Errorf(`%q must not be nil`, `Store`)
+		return
+	}
+	if opts.Fetch == nil {
+		err = fmt.Errorf(`%q must not be nil`, `Fetch`)
+		return
+	}
+	if opts.Table == "" {
+		opts.Table = `public.build_mappings`
+	}
+	if opts.BuildIDTable == "" {
+		opts.BuildIDTable = `public.build_mapping_build_ids`
+	}
+	symbolicator = &ELFSymbolicator{
+		opts: opts,
+	}
+	return
+}
+
+// Batch creates groups of native crash events for
+// each app. Symbol files are matched by build id
+// so events are not batched by app version.
+func (s ELFSymbolicator) Batch(events []event.EventField) (batches []SymbolBatch) {
+	return batchEvents(events, func(ev event.EventField) (key MappingKeyID, ok bool) {
+		if !ev.IsNativeCrash() {
+			return
+		}
+
+		key = MappingKeyID{
+			appId:       ev.AppID,
+			mappingType: TypeElf,
+		}
+
+		return key, true
+	})
+}
+
+// GetKeys fetches the keys of the symbol files
+// containing the build ids from the backing store.
+// Keys are mapped to the build ids they contain.
+func (s ELFSymbolicator) GetKeys(ctx context.Context, appId uuid.UUID, buildIds []string) (keys map[string][]string, err error) {
+	stmt := sqlf.PostgreSQL.
+		Select("ids.build_id").
+		Select("mappings.key").
+		From(s.opts.BuildIDTable+" ids").
+		Join(s.opts.Table+" mappings", "mappings.id = ids.mapping_id").
+		Where("ids.app_id = ?", appId).
+		Where("ids.build_id = any(?)", buildIds).
+		OrderBy("mappings.last_updated desc")
+
+	defer stmt.Close()
+
+	rows, err := s.opts.Store.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	keys = make(map[string][]string)
+	seen := make(map[string]struct{})
+
+	for rows.Next() {
+		var buildId, key string
+		if err = rows.Scan(&buildId, &key); err != nil {
+			return
+		}
+
+		// the same binary may be uploaded with more
+		// than one build, prefer the latest upload
+		if _, ok := seen[buildId]; ok {
+			continue
+		}
+		seen[buildId] = struct{}{}
+
+		keys[key] = append(keys[key], buildId)
+	}
+
+	err = rows.Err()
+
+	return
+}
+
+// Symbolicate symbolicates the native frames of the
+// batch using the ELF symbol files matching the build
+// ids of the frames' binaries.
+func (s ELFSymbolicator) Symbolicate(ctx context.Context, batch SymbolBatch) error {
+	buildIds := batch.buildIDs()
+	if len(buildIds) == 0 {
+		return nil
+	}
+
+	keys, err := s.GetKeys(ctx, batch.mappingKeyID.appId, buildIds)
+	if err != nil {
+		return err
+	}
+
+	// symbol files may be uploaded later
+	if len(keys) == 0 {
+		return ErrMappingNotFound
+	}
+
+	sortedKeys := []string{}
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	slices.Sort(sortedKeys)
+
+	images := make(map[string]*debugImage)
+
+	for _, key := range sortedKeys {
+		if err := fetchFile(ctx, s.opts.Fetch, key, func(r io.ReaderAt, size int64) error {
+			return openELFImages(r, size, keys[key], images)
+		}); err != nil {
+			return err
+		}
+	}
+
+	batch.resolveFrames(imageByBuildID(images))
+
+	return nil
+}
+
+// imageByBuildID returns a resolver of the debug
+// image of a frame from images keyed by build id.
+func imageByBuildID(images map[string]*debugImage) func(frame *event.Frame) *debugImage {
+	return func(frame *event.Frame) *debugImage {
+		return images[strings.ToLower(frame.BuildID)]
+	}
+}
+
+// buildIDs returns the unique build ids
+// of the native frames in the batch.
+func (b SymbolBatch) buildIDs() (ids []string) {
+	seen := make(map[string]struct{})
+
+	b.eachNativeFrame(func(frame *event.Frame) {
+		id := strings.ToLower(frame.BuildID)
+		if id == "" {
+			return
+		}
+		if _, ok := seen[id]; ok {
+			return
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	})
+
+	sort.Strings(ids)
+
+	return
+}
+
+// openELFImages opens the debug images of the ELF binaries
+// from the contents whose build ids are wanted and adds
+// them to images keyed by build id.
+func openELFImages(r io.ReaderAt, size int64, buildIds []string, images map[string]*debugImage) error {
+	return eachELF(r, size, func(name string, f *elf.File) error {
+		id := elfBuildID(f)
+		if _, exists := images[id]; exists || !slices.Contains(buildIds, id) {
+			return nil
+		}
+
+		images[id] = newELFImage(name, f)
+
+		return nil
+	})
+}
+
+// newELFImage creates a debug image from an ELF binary.
+// Offsets of native crash frames are relative to the
+// binary's virtual addresses, so the image is considered
+// loaded at zero.
+func newELFImage(name string, f *elf.File) (image *debugImage) {
+	image = &debugImage{
+		name: name,
+	}
+
+	// binaries without debug information can still
+	// be symbolicated using the symbol tables
+	if data, err := f.DWARF(); err == nil {
+		image.dwarf = data
+	}
+
+	// stripped binaries only carry
+	// the dynamic symbol table
+	symbols, _ := f.Symbols()
+	dynamic, _ := f.DynamicSymbols()
+
+	for _, sym := range append(symbols, dynamic...) {
+		if elf.ST_TYPE(sym.Info) != elf.STT_FUNC || sym.Value == 0 {
+			continue
+		}
+		image.symbols = append(image.symbols, imageSymbol{
+			name: sym.Name,
+			addr: sym.Value,
+		})
+	}
+
+	sort.Slice(image.symbols, func(i, j int) bool {
+		return image.symbols[i].addr < image.symbols[j].addr
+	})
+
+	return
+}
+
+// eachELF calls fn for every ELF binary of the contents.
+// The contents are either a zip archive of ELF binaries
+// or a single ELF binary. Binaries are named after their
+// file name in the archive, or their soname otherwise.
+func eachELF(r io.ReaderAt, size int64, fn func(name string, f *elf.File) error) (err error) {
+	magic := make([]byte, 4)
+	if _, err = r.ReadAt(magic, 0); err != nil {
+		return
+	}
+
+	if !isZip(magic) {
+		f, err := elf.NewFile(r)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		name := ""
+		if sonames, err := f.DynString(elf.DT_SONAME); err == nil && len(sonames) > 0 {
+			name = sonames[0]
+		}
+
+		return fn(name, f)
+	}
+
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return
+	}
+
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		data, err := readZipEntry(file, maxZipEntrySize)
+		if err != nil {
+			return err
+		}
+
+		// archives may contain other files
+		// alongside the shared libraries
+		if len(data) < len(elfMagic) || !isELF(data[:len(elfMagic)]) {
+			continue
+		}
+
+		f, err := elf.NewFile(bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+
+		err = fn(path.Base(file.Name), f)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return
+}
+
+// elfBuildID returns the GNU build id of the ELF
+// binary in hex. Returns an empty string if the
+// binary does not carry a build id.
+func elfBuildID(f *elf.File) string {
+	for _, section := range f.Sections {
+		if section.Type != elf.SHT_NOTE {
+			continue
+		}
+
+		data, err := section.Data()
+		if err != nil {
+			continue
+		}
+
+		if id := parseBuildIDNote(data, f.ByteOrder); id != "" {
+			return id
+		}
+	}
+
+	return ""
+}
+
+// parseBuildIDNote parses the GNU build id from
+// the contents of an ELF note section.
+func parseBuildIDNote(data []byte, order binary.ByteOrder) string {
+	// note names & descriptors are
+	// padded to 4 byte boundaries
+	align := func(n int) int {
+		return (n + 3) &^ 3
+	}
+
+	for len(data) >= 12 {
+		nameSize := int(order.Uint32(data[0:4]))
+		descSize := int(order.Uint32(data[4:8]))
+		noteType := order.Uint32(data[8:12])
+		data = data[12:]
+
+		if nameSize > len(data) {
+			return ""
+		}
+		name := data[:nameSize]
+		data = data[min(align(nameSize), len(data)):]
+
+		if descSize > len(data) {
+			return ""
+		}
+		desc := data[:descSize]
+		data = data[min(align(descSize), len(data)):]
+
+		if noteType == ntGNUBuildID && string(name) == buildIDNoteName {
+			return hex.EncodeToString(desc)
+		}
+	}
+
+	return ""
+}
+
+// isELF returns true if the magic number
+// belongs to an ELF binary.
+func isELF(magic []byte) bool {
+	return bytes.Equal(magic, elfMagic)
+}
@@ -0,0 +1,209 @@
+package symbol
+
+import (
+	"backend/api/event"
+	"backend/api/platform"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func newTestNote(order binary.ByteOrder, name string, noteType uint32, desc []byte) []byte {
+	var b bytes.Buffer
+
+	pad := func() {
+		for b.Len()%4 != 0 {
+			b.WriteByte(0)
+		}
+	}
+
+	binary.Write(&b, order, uint32(len(name)))
+	binary.Write(&b, order, uint32(len(desc)))
+	binary.Write(&b, order, noteType)
+	b.WriteString(name)
+	pad()
+	b.Write(desc)
+	pad()
+
+	return b.Bytes()
+}
+
+func TestValidateELF(t *testing.T) {
+	valid := [][]byte{
+		[]byte("PK\x03\x04rest of the archive"),
+		[]byte("\x7fELF\x02\x01\x01\x00"),
+	}
+
+	for _, v := range valid {
+		if err := ValidateELF(bytes.NewReader(v)); err != nil {
+			t.Errorf("Expected nil error for %x, but got %v", v, err)
+		}
+	}
+
+	invalid := [][]byte{
+		[]byte("\x7fEL"),
+		{0xcf, 0xfa, 0xed, 0xfe, 0x0c, 0x00, 0x00, 0x01},
+	}
+
+	for _, v := range invalid {
+		if err := ValidateELF(bytes.NewReader(v)); err == nil {
+			t.Errorf("Expected error for %q, but got nil", v)
+		}
+	}
+}
+
+func TestELFBuildIDsZipEntryTooLarge(t *testing.T) {
+	archive := newTestArchive(t, elfMagic, maxZipEntrySize+1)
+
+	if _, err := ELFBuildIDs(bytes.NewReader(archive), int64(len(archive))); !errors.Is(err, ErrZipEntryTooLarge) {
+		t.Errorf("Expected %v, but got %v", ErrZipEntryTooLarge, err)
+	}
+}
+
+func TestParseBuildIDNote(t *testing.T) {
+	order := binary.LittleEndian
+	buildId := []byte{0xa3, 0xf1, 0xc2, 0x9d, 0x00, 0x4e, 0x11, 0x7b, 0x52, 0x6c, 0x0f, 0xe8, 0x31, 0x5a, 0x90, 0x77, 0xd4, 0x18, 0x6e, 0x02}
+
+	// build id preceded by an unrelated note
+	var data []byte
+	data = append(data, newTestNote(order, "Android\x00", 1, []byte{0x1e, 0x00, 0x00, 0x00})...)
+	data = append(data, newTestNote(order, buildIDNoteName, ntGNUBuildID, buildId)...)
+
+	expected := "a3f1c29d004e117b526c0fe8315a9077d4186e02"
+	if got := parseBuildIDNote(data, order); got != expected {
+		t.Errorf("Expected %q, but got %q", expected, got)
+	}
+
+	bigEndian := newTestNote(binary.BigEndian, buildIDNoteName, ntGNUBuildID, buildId)
+	if got := parseBuildIDNote(bigEndian, binary.BigEndian); got != expected {
+		t.Errorf("Expected %q, but got %q", expected, got)
+	}
+
+	goNote := newTestNote(order, "Go\x00\x00", 4, []byte("go build id"))
+	if got := parseBuildIDNote(goNote, order); got != "" {
+		t.Errorf("Expected no build id, but got %q", got)
+	}
+
+	truncated := newTestNote(order, buildIDNoteName, ntGNUBuildID, buildId)[:20]
+	if got := parseBuildIDNote(truncated, order); got != "" {
+		t.Errorf("Expected no build id for truncated note, but got %q", got)
+	}
+}
+
+func TestELFEventBatching(t *testing.T) {
+	appId, _ := uuid.Parse("06b6d6bf-99d1-4536-8f94-1cea038cf207")
+	exception := event.EventField{
+		AppID: appId,
+		Type:  event.TypeException,
+		Attribute: event.Attribute{
+			AppVersion: "1.0.0",
+			AppBuild:   "1000",
+			Platform:   platform.Android,
+		},
+	}
+
+	nativeCrash := func(version string) event.EventField {
+		return event.EventField{
+			AppID: appId,
+			Type:  event.TypeNativeCrash,
+			Attribute: event.Attribute{
+				AppVersion: version,
+				AppBuild:   "1000",
+				Platform:   platform.Android,
+			},
+			NativeCrash: &event.NativeCrash{Signal: "SIGSEGV"},
+		}
+	}
+
+	events := []event.EventField{exception, nativeCrash("1.0.0"), nativeCrash("1.1.0")}
+
+	store, _ := pgxpool.New(context.Background(), "")
+
+	symbolicator, _ := NewSymbolicator(&Options{
+		Origin: "http://example.com",
+		Store:  store,
+	})
+
+	batches := symbolicator.Batch(events)
+	if len(batches) != 1 || len(batches[0].Events) != 1 || batches[0].Events[0].IsNativeCrash() {
+		t.Errorf("Expected native crashes to be left out of mapping batches, got %+v", batches)
+	}
+
+	elfSymbolicator, err := NewELFSymbolicator(&Options{
+		Store: store,
+		Fetch: func(ctx context.Context, key string, w io.WriterAt) error { return nil },
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	batches = elfSymbolicator.Batch(events)
+	if len(batches) != 1 {
+		t.Fatalf("Expected %d batches, got %d", 1, len(batches))
+	}
+
+	if batches[0].mappingKeyID.mappingType != TypeElf {
+		t.Errorf("Expected %q, but got %q", TypeElf, batches[0].mappingKeyID.mappingType)
+	}
+
+	if len(batches[0].Events) != 2 {
+		t.Errorf("Expected native crashes of all versions in one batch, got %d events", len(batches[0].Events))
+	}
+
+	if _, err := NewELFSymbolicator(&Options{Store: store}); err == nil {
+		t.Error("Expected error for missing fetcher, but got nil")
+	}
+}
+
+func TestResolveFramesByBuildID(t *testing.T) {
+	image := &debugImage{
+		name: "libnative.so",
+		symbols: []imageSymbol{
+			{name: "Java_com_example_app_NativeLib_crash", addr: 0xb800},
+			{name: "crash", addr: 0xb890},
+			{name: "helper", addr: 0xb900},
+		},
+	}
+
+	batch := SymbolBatch{
+		Events: []event.EventField{
+			{
+				Type: event.TypeNativeCrash,
+				NativeCrash: &event.NativeCrash{
+					Signal: "SIGSEGV",
+					Frames: event.Frames{
+						{FrameIndex: 0, BinaryName: "/data/app/lib/arm64/libnative.so", Offset: "47268", BuildID: "A3F1C2"},
+						{FrameIndex: 1, BinaryName: "/data/app/lib/arm64/libnative.so", Offset: "47248", BuildID: "a3f1c2"},
+						{FrameIndex: 2, BinaryName: "/apex/com.android.runtime/lib64/bionic/libc.so", Offset: "563412", BuildID: "ffee"},
+					},
+				},
+			},
+		},
+	}
+
+	if ids := batch.buildIDs(); len(ids) != 2 || ids[0] != "a3f1c2" || ids[1] != "ffee" {
+		t.Errorf("Expected build ids [a3f1c2 ffee], but got %v", ids)
+	}
+
+	batch.resolveFrames(imageByBuildID(map[string]*debugImage{"a3f1c2": image}))
+
+	got := batch.Events[0].NativeCrash.Frames
+
+	if got[0].MethodName != "crash" {
+		t.Errorf("Expected crashing frame to be symbolicated, but got %q", got[0].MethodName)
+	}
+
+	if got[1].MethodName != "Java_com_example_app_NativeLib_crash" {
+		t.Errorf("Expected calling frame to be symbolicated, but got %q", got[1].MethodName)
+	}
+
+	if got[2].MethodName != "" {
+		t.Errorf("Expected system frame to be untouched, but got %q", got[2].MethodName)
+	}
+}
@@ -0,0 +1,247 @@
+package symbol
+
+import (
+	"backend/api/event"
+	"context"
+	"debug/dwarf"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+)
+
+// imageSymbol represents a symbol from
+// the symbol table of a binary image.
+type imageSymbol struct {
+	name string
+	addr uint64
+}
+
+// symbolInfo represents the resolved
+// symbol of an instruction address.
+type symbolInfo struct {
+	function string
+	file     string
+	line     int
+}
+
+// debugImage represents the debug information
+// of a binary image from a dSYM or an ELF
+// symbol file.
+type debugImage struct {
+	// name is the name of the binary image.
+	name string
+
+	// textAddr is the virtual address the image
+	// is loaded at, like the address of the
+	// __TEXT segment for Mach-O binaries.
+	textAddr uint64
+
+	// dwarf is the image's DWARF debug
+	// information.
+	dwarf *dwarf.Data
+
+	// symbols is the image's symbol table
+	// sorted by address.
+	symbols []imageSymbol
+}
+
+// lookup resolves the symbol of an instruction at
+// the offset from the image's load address.
+func (d debugImage) lookup(offset uint64) (info symbolInfo, ok bool) {
+	pc := d.textAddr + offset
+
+	if d.dwarf != nil {
+		info, ok = d.lookupDWARF(pc)
+		if ok && info.function != "" {
+			return
+		}
+	}
+
+	i := sort.Search(len(d.symbols), func(i int) bool {
+		return d.symbols[i].addr > pc
+	})
+
+	if i == 0 {
+		return
+	}
+
+	info.function = d.symbols[i-1].name
+
+	return info, true
+}
+
+// lookupDWARF resolves the function, file and line
+// of the address using the DWARF debug information.
+func (d debugImage) lookupDWARF(pc uint64) (info symbolInfo, ok bool) {
+	r := d.dwarf.Reader()
+	cu, err := r.SeekPC(pc)
+	if err != nil {
+		return
+	}
+
+	if lr, err := d.dwarf.LineReader(cu); err == nil && lr != nil {
+		var entry dwarf.LineEntry
+		if err := lr.SeekPC(pc, &entry); err == nil && entry.File != nil {
+			info.file = path.Base(entry.File.Name)
+			info.line = entry.Line
+			ok = true
+		}
+	}
+
+	for {
+		entry, err := r.Next()
+		if err != nil || entry == nil || entry.Tag == dwarf.TagCompileUnit {
+			break
+		}
+
+		if entry.Tag != dwarf.TagSubprogram {
+			continue
+		}
+
+		ranges, err := d.dwarf.Ranges(entry)
+		if err != nil {
+			continue
+		}
+
+		for _, rng := range ranges {
+			if pc >= rng[0] && pc < rng[1] {
+				info.function = d.subprogramName(entry)
+				return info, true
+			}
+		}
+	}
+
+	return
+}
+
+// subprogramName returns the name of the subprogram
+// following its specification if the name is only
+// present on the declaration.
+func (d debugImage) subprogramName(entry *dwarf.Entry) string {
+	if name, ok := entry.Val(dwarf.AttrName).(string); ok {
+		return name
+	}
+
+	for _, attr := range []dwarf.Attr{dwarf.AttrSpecification, dwarf.AttrAbstractOrigin} {
+		offset, ok := entry.Val(attr).(dwarf.Offset)
+		if !ok {
+			continue
+		}
+
+		r := d.dwarf.Reader()
+		r.Seek(offset)
+		decl, err := r.Next()
+		if err != nil || decl == nil {
+			continue
+		}
+
+		if name, ok := decl.Val(dwarf.AttrName).(string); ok {
+			return name
+		}
+	}
+
+	if name, ok := entry.Val(dwarf.AttrLinkageName).(string); ok {
+		return name
+	}
+
+	return ""
+}
+
+// eachNativeFrame calls fn for every native frame
+// of the exceptions, native crashes and their
+// threads in the batch.
+func (b SymbolBatch) eachNativeFrame(fn func(frame *event.Frame)) {
+	visit := func(frames event.Frames) {
+		for i := range frames {
+			if frames[i].IsNative() {
+				fn(&frames[i])
+			}
+		}
+	}
+
+	for _, evt := range b.Events {
+		if evt.IsException() {
+			for _, exc := range evt.Exception.Exceptions {
+				visit(exc.Frames)
+			}
+
+			for _, thrd := range evt.Exception.Threads {
+				visit(thrd.Frames)
+			}
+		}
+
+		if evt.IsNativeCrash() {
+			visit(evt.NativeCrash.Frames)
+
+			for _, thrd := range evt.NativeCrash.Threads {
+				visit(thrd.Frames)
+			}
+		}
+	}
+}
+
+// resolveFrames resolves the native frames of the
+// batch using the debug image returned by imageOf.
+// Frames without a debug image are left untouched.
+func (b *SymbolBatch) resolveFrames(imageOf func(frame *event.Frame) *debugImage) {
+	var errs []error
+
+	b.eachNativeFrame(func(frame *event.Frame) {
+		image := imageOf(frame)
+		if image == nil {
+			return
+		}
+
+		offset, ok := frame.ImageOffset()
+		if !ok {
+			return
+		}
+
+		// return addresses point to the instruction
+		// after the call, so look up the call itself
+		// for all frames but the crashing one
+		if frame.FrameIndex > 0 && offset > 0 {
+			offset--
+		}
+
+		info, ok := image.lookup(offset)
+		if !ok {
+			errs = append(errs, fmt.Errorf(`no symbol found in %q for offset %d`, frame.BinaryName, offset))
+			return
+		}
+
+		frame.MethodName = info.function
+		frame.FileName = info.file
+		frame.LineNum = info.line
+	})
+
+	if len(errs) > 0 {
+		b.Errs = errs
+	}
+}
+
+// fetchFile fetches the mapping file stored against the
+// key to a temporary file and calls fn with its contents.
+// The temporary file is removed once fn returns.
+func fetchFile(ctx context.Context, fetch func(ctx context.Context, key string, w io.WriterAt) error, key string, fn func(r io.ReaderAt, size int64) error) (err error) {
+	file, err := os.CreateTemp("", "mapping-*")
+	if err != nil {
+		return
+	}
+
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	if err = fetch(ctx, key, file); err != nil {
+		return
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		return
+	}
+
+	return fn(file, stat.Size())
+}
@@ -0,0 +1,615 @@
+package symbol
+
+import (
+	"backend/api/event"
+	"bufio"
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/leporo/sqlf"
+)
+
+// maxProguardLineBytes is the maximum size
+// of a single line of a proguard mapping.
+const maxProguardLineBytes = 1 << 20
+
+// proguardMethodPattern matches method lines of a
+// proguard mapping, like
+//
+//	1:3:void com.example.Foo.bar(int):10:12 -> a
+var proguardMethodPattern = regexp.MustCompile(`^(?:(\d+):(\d+):)?\S+ ([^\s(]+)\([^)]*\)(?::(\d+)(?::(\d+))?)? -> (\S+)$`)
+
+// proguardClassNamePattern matches class names
+// occurring in free text, like stacktraces.
+var proguardClassNamePattern = regexp.MustCompile(`[\w$]+(?:\.[\w$]+)+`)
+
+// ProguardMapping represents a parsed proguard
+// or R8 mapping file.
+type ProguardMapping struct {
+	// classes are the mapped classes keyed
+	// by their obfuscated name.
+	classes map[string]*proguardClass
+
+	// sourceFiles are the source files of the
+	// mapped classes keyed by their original
+	// name.
+	sourceFiles map[string]string
+}
+
+// proguardClass represents a mapped class.
+type proguardClass struct {
+	// original is the original name
+	// of the class.
+	original string
+
+	// methods are the mapped methods keyed
+	// by their obfuscated name. Members are
+	// in the order of the mapping file.
+	methods map[string][]proguardMember
+}
+
+// proguardMember represents a single method
+// line of a mapped class.
+type proguardMember struct {
+	// obfStart is the start of the
+	// obfuscated line range.
+	obfStart int
+
+	// obfEnd is the end of the
+	// obfuscated line range.
+	obfEnd int
+
+	// hasRange is true if the member
+	// has an obfuscated line range.
+	hasRange bool
+
+	// class is the original class of the
+	// method if it was inlined from another
+	// class.
+	class string
+
+	// name is the original name
+	// of the method.
+	name string
+
+	// origStart is the start of the
+	// original line range.
+	origStart int
+
+	// origEnd is the end of the
+	// original line range.
+	origEnd int
+}
+
+// ParseProguard parses a proguard or R8 mapping file.
+// Fields are skipped as stacktraces only refer to
+// classes & methods.
+func ParseProguard(r io.Reader) (mapping *ProguardMapping, err error) {
+	mapping = &ProguardMapping{
+		classes:     make(map[string]*proguardClass),
+		sourceFiles: make(map[string]string),
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxProguardLineBytes)
+
+	var class *proguardClass
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "" {
+			continue
+		}
+
+		// R8 writes metadata as json in comments,
+		// only the source file is of interest
+		if strings.HasPrefix(trimmed, "#") {
+			if class != nil {
+				mapping.parseMetadata(class, strings.TrimSpace(trimmed[1:]))
+			}
+			continue
+		}
+
+		// class lines are not indented
+		if line[0] != ' ' && line[0] != '\t' {
+			original, obfuscated, found := strings.Cut(strings.TrimSuffix(trimmed, ":"), " -> ")
+			if !found {
+				class = nil
+				continue
+			}
+
+			class = &proguardClass{
+				original: original,
+				methods:  make(map[string][]proguardMember),
+			}
+			mapping.classes[obfuscated] = class
+			continue
+		}
+
+		if class == nil {
+			continue
+		}
+
+		matches := proguardMethodPattern.FindStringSubmatch(trimmed)
+		if matches == nil {
+			continue
+		}
+
+		member := proguardMember{
+			hasRange:  matches[1] != "",
+			obfStart:  atoi(matches[1]),
+			obfEnd:    atoi(matches[2]),
+			origStart: atoi(matches[4]),
+			origEnd:   atoi(matches[5]),
+			name:      matches[3],
+		}
+
+		// methods inlined from other classes
+		// are qualified with their class
+		if i := strings.LastIndex(member.name, "."); i > 0 {
+			member.class = member.name[:i]
+			member.name = member.name[i+1:]
+		}
+
+		obfuscated := matches[6]
+		class.methods[obfuscated] = append(class.methods[obfuscated], member)
+	}
+
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return
+}
+
+// parseMetadata parses the R8 metadata
+// of a class.
+func (m *ProguardMapping) parseMetadata(class *proguardClass, comment string) {
+	if !strings.HasPrefix(comment, "{") {
+		return
+	}
+
+	var metadata struct {
+		ID       string `json:"id"`
+		FileName string `json:"fileName"`
+	}
+
+	if err := json.Unmarshal([]byte(comment), &metadata); err != nil {
+		return
+	}
+
+	if metadata.ID == "sourceFile" && metadata.FileName != "" {
+		m.sourceFiles[class.original] = metadata.FileName
+	}
+}
+
+// retraceFragments deobfuscates the values of
+// the fragments. Frames may expand to more than
+// one frame if methods were inlined.
+func (m ProguardMapping) retraceFragments(frags []Fragment) (retraced []Fragment, errs []error) {
+	for _, frag := range frags {
+		values := []string{}
+
+		for _, value := range frag.Values {
+			switch {
+			case strings.HasPrefix(value, event.FramePrefix):
+				frames, err := m.retraceFrameValue(value)
+				if err != nil {
+					errs = append(errs, err)
+				}
+				values = append(values, frames...)
+			case strings.HasPrefix(value, event.GenericPrefix):
+				values = append(values, event.GenericPrefix+m.retraceText(strings.TrimPrefix(value, event.GenericPrefix)))
+			default:
+				values = append(values, value)
+			}
+		}
+
+		retraced = append(retraced, Fragment{
+			ID:     frag.ID,
+			Values: values,
+		})
+	}
+
+	return
+}
+
+// retraceFrameValue deobfuscates a single
+// serialized frame.
+func (m ProguardMapping) retraceFrameValue(value string) (values []string, err error) {
+	// frames without a class cannot
+	// be deobfuscated
+	if !strings.Contains(value, ".") {
+		return []string{value}, nil
+	}
+
+	frame, err := UnmarshalRetraceFrame(value, event.FramePrefix)
+	if err != nil {
+		return []string{value}, err
+	}
+
+	for _, f := range m.retraceFrame(frame) {
+		values = append(values, MarshalRetraceFrame(event.Frame{
+			ClassName:  f.ClassName,
+			MethodName: f.MethodName,
+			FileName:   f.FileName,
+			LineNum:    f.LineNum,
+		}, event.FramePrefix))
+	}
+
+	return
+}
+
+// retraceFrame deobfuscates a single frame. Returns the
+// frames of all the methods inlined at the frame's line,
+// innermost first.
+func (m ProguardMapping) retraceFrame(frame RetraceFrame) (frames []RetraceFrame) {
+	class, ok := m.classes[frame.ClassName]
+	if !ok {
+		return []RetraceFrame{frame}
+	}
+
+	members := class.match(frame.MethodName, frame.LineNum)
+
+	// method not mapped, the class
+	// may still be deobfuscated
+	if len(members) == 0 {
+		return []RetraceFrame{{
+			ClassName:  class.original,
+			MethodName: frame.MethodName,
+			FileName:   m.fileName(class.original, frame.FileName),
+			LineNum:    frame.LineNum,
+		}}
+	}
+
+	for _, member := range members {
+		className := class.original
+		if member.class != "" {
+			className = member.class
+		}
+
+		frames = append(frames, RetraceFrame{
+			ClassName:  className,
+			MethodName: member.name,
+			FileName:   m.fileName(className, frame.FileName),
+			LineNum:    member.originalLine(frame.LineNum),
+		})
+	}
+
+	return
+}
+
+// retraceText deobfuscates class names and
+// frames occurring in free text, like the
+// exception type or a whole stacktrace.
+func (m ProguardMapping) retraceText(text string) string {
+	lines := strings.Split(text, "\n")
+	retraced := make([]string, 0, len(lines))
+
+	for _, line := range lines {
+		if strings.HasPrefix(line, event.FramePrefix) {
+			frames, _ := m.retraceFrameValue(line)
+			retraced = append(retraced, frames...)
+			continue
+		}
+
+		// an obfuscated class name without
+		// package, like a bare exception type
+		if class, ok := m.classes[line]; ok {
+			retraced = append(retraced, class.original)
+			continue
+		}
+
+		retraced = append(retraced, proguardClassNamePattern.ReplaceAllStringFunc(line, func(name string) string {
+			if class, ok := m.classes[name]; ok {
+				return class.original
+			}
+			return name
+		}))
+	}
+
+	return strings.Join(retraced, "\n")
+}
+
+// fileName provides the source file name of the class.
+// Falls back to the outermost class' name when the
+// mapping does not record the source file. Frames
+// without a file name are left as is.
+func (m ProguardMapping) fileName(className, obfFileName string) string {
+	if obfFileName == "" {
+		return ""
+	}
+
+	if sourceFile, ok := m.sourceFiles[className]; ok {
+		return sourceFile
+	}
+
+	name := className[strings.LastIndex(className, ".")+1:]
+	name, _, _ = strings.Cut(name, "$")
+
+	ext := ".java"
+	if strings.HasSuffix(obfFileName, ".kt") {
+		ext = ".kt"
+	}
+
+	return name + ext
+}
+
+// match returns the members of the obfuscated method
+// mapped at the line. Prefers members whose line range
+// contains the line and falls back to members without
+// a line range. Ambiguous matches resolve to the first
+// member.
+func (c proguardClass) match(method string, line int) (matched []proguardMember) {
+	members := c.methods[method]
+	if len(members) == 0 {
+		return
+	}
+
+	if line > 0 {
+		for _, member := range members {
+			if member.hasRange && member.obfStart <= line && line <= member.obfEnd {
+				matched = append(matched, member)
+			}
+		}
+	}
+
+	if len(matched) > 0 {
+		return
+	}
+
+	for _, member := range members {
+		if !member.hasRange {
+			matched = append(matched, member)
+		}
+	}
+
+	if len(matched) > 0 {
+		return matched[:1]
+	}
+
+	return members[:1]
+}
+
+// originalLine maps the obfuscated line
+// to the original line of the member.
+func (p proguardMember) originalLine(line int) int {
+	// members without an original range
+	// keep their obfuscated lines
+	if p.origStart == 0 {
+		return line
+	}
+
+	if !p.hasRange || line < p.obfStart || line > p.obfEnd {
+		return p.origStart
+	}
+
+	// ranges of equal span map
+	// line by line
+	if p.origEnd-p.origStart == p.obfEnd-p.obfStart {
+		return p.origStart + line - p.obfStart
+	}
+
+	return p.origStart
+}
+
+// atoi parses a decimal number, returning
+// zero for empty or invalid input.
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// ProguardCache is a least recently used cache of
+// parsed proguard mappings safe for concurrent use.
+type ProguardCache struct {
+	mu    sync.Mutex
+	size  int
+	ll    *list.List
+	items map[MappingKeyID]*list.Element
+}
+
+// proguardCacheEntry represents a cached mapping
+// along with the checksum of its mapping file.
+type proguardCacheEntry struct {
+	key      MappingKeyID
+	checksum string
+	mapping  *ProguardMapping
+}
+
+// NewProguardCache creates a new ProguardCache
+// holding at most size mappings.
+func NewProguardCache(size int) *ProguardCache {
+	if size < 1 {
+		size = 1
+	}
+
+	return &ProguardCache{
+		size:  size,
+		ll:    list.New(),
+		items: make(map[MappingKeyID]*list.Element),
+	}
+}
+
+// get returns the cached mapping for the key. Mappings
+// of a replaced mapping file are considered stale.
+func (c *ProguardCache) get(key MappingKeyID, checksum string) (mapping *ProguardMapping, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return
+	}
+
+	entry := elem.Value.(*proguardCacheEntry)
+	if entry.checksum != checksum {
+		c.ll.Remove(elem)
+		delete(c.items, key)
+		return nil, false
+	}
+
+	c.ll.MoveToFront(elem)
+
+	return entry.mapping, true
+}
+
+// put caches the mapping for the key and evicts
+// the least recently used mapping if full.
+func (c *ProguardCache) put(key MappingKeyID, checksum string, mapping *ProguardMapping) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.items[key]; ok {
+		elem.Value = &proguardCacheEntry{key, checksum, mapping}
+		c.ll.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&proguardCacheEntry{key, checksum, mapping})
+
+	if c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*proguardCacheEntry).key)
+	}
+}
+
+// ProguardSymbolicator offers in process symbolication
+// of Android events using proguard or R8 mappings.
+type ProguardSymbolicator struct {
+	opts *Options
+}
+
+// NewProguardSymbolicator creates a new instance
+// of ProguardSymbolicator.
+func NewProguardSymbolicator(opts *Options) (symbolicator *ProguardSymbolicator, err error) {
+	if opts.Store == nil {
+		err = fmt.Errorf(`%q must not be nil`, `Store`)
+		return
+	}
+	if opts.Fetch == nil {
+		err = fmt.Errorf(`%q must not be nil`, `Fetch`)
+		return
+	}
+	if opts.Table == "" {
+		opts.Table = `public.build_mappings`
+	}
+	if opts.ProguardCache == nil {
+		opts.ProguardCache = NewProguardCache(1)
+	}
+	symbolicator = &ProguardSymbolicator{
+		opts: opts,
+	}
+	return
+}
+
+// Batch creates groups of events needing proguard
+// mappings based on the event's attribute values.
+func (s ProguardSymbolicator) Batch(events []event.EventField) (batches []SymbolBatch) {
+	return batchEvents(events, func(ev event.EventField) (key MappingKeyID, ok bool) {
+		if ev.IsNativeCrash() || mappingTypeOf(ev) != TypeProguard {
+			return
+		}
+
+		key = MappingKeyID{
+			appId:       ev.AppID,
+			versionName: ev.Attribute.AppVersion,
+			versionCode: ev.Attribute.AppBuild,
+			mappingType: TypeProguard,
+		}
+
+		return key, true
+	})
+}
+
+// GetKey fetches the mapping key and the checksum
+// of the mapping file from the backing store.
+func (s ProguardSymbolicator) GetKey(ctx context.Context, batch SymbolBatch) (key, checksum string, err error) {
+	stmt := sqlf.PostgreSQL.
+		Select("key").
+		Select("fnv1_hash").
+		From(s.opts.Table).
+		Where("app_id = ?", batch.mappingKeyID.appId).
+		Where("version_name = ?", batch.mappingKeyID.versionName).
+		Where("version_code = ?", batch.mappingKeyID.versionCode).
+		Where("mapping_type = ?", batch.mappingKeyID.mappingType)
+
+	defer stmt.Close()
+
+	if err := s.opts.Store.QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&key, &checksum); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", "", nil
+		}
+		return "", "", err
+	}
+
+	return
+}
+
+// Symbolicate symbolicates the batch using the parsed
+// proguard mapping and saves the errors in the batch
+// if any.
+func (s ProguardSymbolicator) Symbolicate(ctx context.Context, batch SymbolBatch) error {
+	key, checksum, err := s.GetKey(ctx, batch)
+	if err != nil {
+		return err
+	}
+
+	// mapping file may be uploaded later
+	if key == "" {
+		return ErrMappingNotFound
+	}
+
+	batch.encode()
+
+	if !batch.hasFrags() {
+		return errors.New(`failed to symbolicate, batch does not contain any symbolication fragments`)
+	}
+
+	mapping, err := s.mapping(ctx, batch.mappingKeyID, key, checksum)
+	if err != nil {
+		return err
+	}
+
+	frags, errs := mapping.retraceFragments(batch.frags)
+
+	for _, err := range errs {
+		fmt.Println("retrace err: ", err.Error())
+	}
+
+	batch.decode(frags)
+
+	return nil
+}
+
+// mapping returns the parsed mapping from the cache
+// or fetches and parses the mapping file.
+func (s ProguardSymbolicator) mapping(ctx context.Context, id MappingKeyID, key, checksum string) (mapping *ProguardMapping, err error) {
+	if mapping, ok := s.opts.ProguardCache.get(id, checksum); ok {
+		return mapping, nil
+	}
+
+	err = fetchFile(ctx, s.opts.Fetch, key, func(r io.ReaderAt, size int64) (err error) {
+		mapping, err = ParseProguard(io.NewSectionReader(r, 0, size))
+		return
+	})
+	if err != nil {
+		return
+	}
+
+	s.opts.ProguardCache.put(id, checksum, mapping)
+
+	return
+}
@@ -0,0 +1,280 @@
+package symbol
+
+import (
+	"backend/api/event"
+	"backend/api/platform"
+	"context"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const testProguardMapping = `# compiler: R8
+# compiler_version: 8.5.35
+# pg_map_id: 5c1e0a2
+com.example.app.MainActivity -> com.example.app.a:
+# {"id":"sourceFile","fileName":"MainActivity.kt"}
+    android.widget.Button button -> a
+    1:4:void onCreate(android.os.Bundle):21:24 -> onCreate
+    5:5:void com.example.app.Checkout.validate(int):40:40 -> onCreate
+    5:5:void onCreate(android.os.Bundle):25 -> onCreate
+    6:8:void onCreate(android.os.Bundle):27:29 -> onCreate
+    void onClick(android.view.View) -> b
+com.example.app.Checkout -> com.example.app.b:
+    1:1:void validate(int):38:38 -> a
+    2:3:void validate(int):40 -> a
+com.example.app.CheckoutException -> b4:
+    void <init>(java.lang.String) -> <init>
+`
+
+func parseTestMapping(t *testing.T) *ProguardMapping {
+	t.Helper()
+
+	mapping, err := ParseProguard(strings.NewReader(testProguardMapping))
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	return mapping
+}
+
+func TestParseProguard(t *testing.T) {
+	mapping := parseTestMapping(t)
+
+	if len(mapping.classes) != 3 {
+		t.Fatalf("Expected %d classes, but got %d", 3, len(mapping.classes))
+	}
+
+	class := mapping.classes["com.example.app.a"]
+	if class.original != "com.example.app.MainActivity" {
+		t.Errorf("Expected %q, but got %q", "com.example.app.MainActivity", class.original)
+	}
+
+	if len(class.methods["onCreate"]) != 4 {
+		t.Errorf("Expected %d members, but got %d", 4, len(class.methods["onCreate"]))
+	}
+
+	inlined := class.methods["onCreate"][1]
+	if inlined.class != "com.example.app.Checkout" || inlined.name != "validate" {
+		t.Errorf("Expected inlined member of %q, but got %+v", "com.example.app.Checkout", inlined)
+	}
+
+	if got := mapping.sourceFiles["com.example.app.MainActivity"]; got != "MainActivity.kt" {
+		t.Errorf("Expected source file %q, but got %q", "MainActivity.kt", got)
+	}
+}
+
+func TestRetraceFrame(t *testing.T) {
+	mapping := parseTestMapping(t)
+
+	cases := []struct {
+		frame    RetraceFrame
+		expected []RetraceFrame
+	}{
+		{
+			RetraceFrame{ClassName: "com.example.app.a", MethodName: "onCreate", FileName: "SourceFile", LineNum: 3},
+			[]RetraceFrame{
+				{ClassName: "com.example.app.MainActivity", MethodName: "onCreate", FileName: "MainActivity.kt", LineNum: 23},
+			},
+		},
+		{
+			RetraceFrame{ClassName: "com.example.app.a", MethodName: "onCreate", FileName: "SourceFile", LineNum: 5},
+			[]RetraceFrame{
+				{ClassName: "com.example.app.Checkout", MethodName: "validate", FileName: "Checkout.java", LineNum: 40},
+				{ClassName: "com.example.app.MainActivity", MethodName: "onCreate", FileName: "MainActivity.kt", LineNum: 25},
+			},
+		},
+		{
+			RetraceFrame{ClassName: "com.example.app.a", MethodName: "b", FileName: "SourceFile", LineNum: 12},
+			[]RetraceFrame{
+				{ClassName: "com.example.app.MainActivity", MethodName: "onClick", FileName: "MainActivity.kt", LineNum: 12},
+			},
+		},
+		{
+			RetraceFrame{ClassName: "com.example.app.b", MethodName: "a", FileName: "SourceFile", LineNum: 3},
+			[]RetraceFrame{
+				{ClassName: "com.example.app.Checkout", MethodName: "validate", FileName: "Checkout.java", LineNum: 40},
+			},
+		},
+		{
+			RetraceFrame{ClassName: "com.example.app.b", MethodName: "z", FileName: "SourceFile", LineNum: 7},
+			[]RetraceFrame{
+				{ClassName: "com.example.app.Checkout", MethodName: "z", FileName: "Checkout.java", LineNum: 7},
+			},
+		},
+		{
+			RetraceFrame{ClassName: "android.app.Activity", MethodName: "performCreate", FileName: "Activity.java", LineNum: 8595},
+			[]RetraceFrame{
+				{ClassName: "android.app.Activity", MethodName: "performCreate", FileName: "Activity.java", LineNum: 8595},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		got := mapping.retraceFrame(c.frame)
+		if !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("Expected %+v, but got %+v", c.expected, got)
+		}
+	}
+}
+
+func TestRetraceText(t *testing.T) {
+	mapping := parseTestMapping(t)
+
+	if got := mapping.retraceText("b4"); got != "com.example.app.CheckoutException" {
+		t.Errorf("Expected %q, but got %q", "com.example.app.CheckoutException", got)
+	}
+
+	trace := strings.Join([]string{
+		"java.lang.IllegalStateException: com.example.app.b failed",
+		event.FramePrefix + "com.example.app.a.onCreate(SourceFile:5)",
+		event.FramePrefix + "android.app.Activity.performCreate(Activity.java:8595)",
+	}, "\n")
+
+	expected := strings.Join([]string{
+		"java.lang.IllegalStateException: com.example.app.Checkout failed",
+		event.FramePrefix + "com.example.app.Checkout.validate(Checkout.java:40)",
+		event.FramePrefix + "com.example.app.MainActivity.onCreate(MainActivity.kt:25)",
+		event.FramePrefix + "android.app.Activity.performCreate(Activity.java:8595)",
+	}, "\n")
+
+	if got := mapping.retraceText(trace); got != expected {
+		t.Errorf("Expected %q, but got %q", expected, got)
+	}
+}
+
+func TestRetraceBatch(t *testing.T) {
+	mapping := parseTestMapping(t)
+
+	batch := SymbolBatch{
+		Events: []event.EventField{
+			{
+				Type: event.TypeException,
+				Exception: &event.Exception{
+					Exceptions: event.ExceptionUnits{
+						{
+							Type: "b4",
+							Frames: event.Frames{
+								{ClassName: "com.example.app.a", MethodName: "onCreate", FileName: "SourceFile", LineNum: 5},
+								{ClassName: "android.app.Activity", MethodName: "performCreate", FileName: "Activity.java", LineNum: 8595},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	batch.encode()
+
+	frags, errs := mapping.retraceFragments(batch.frags)
+	if len(errs) > 0 {
+		t.Fatalf("Expected no errors, but got %v", errs)
+	}
+
+	batch.decode(frags)
+
+	exception := batch.Events[0].Exception.Exceptions[0]
+
+	if exception.Type != "com.example.app.CheckoutException" {
+		t.Errorf("Expected %q, but got %q", "com.example.app.CheckoutException", exception.Type)
+	}
+
+	if len(exception.Frames) != 3 {
+		t.Fatalf("Expected inlined frames to expand to %d frames, but got %d", 3, len(exception.Frames))
+	}
+
+	if exception.Frames[1].ClassName != "com.example.app.MainActivity" || exception.Frames[1].LineNum != 25 {
+		t.Errorf("Expected outer frame of inlined method, but got %+v", exception.Frames[1])
+	}
+}
+
+func TestProguardCache(t *testing.T) {
+	appId := uuid.New()
+	key := func(version string) MappingKeyID {
+		return MappingKeyID{appId: appId, versionName: version, versionCode: "1", mappingType: TypeProguard}
+	}
+
+	cache := NewProguardCache(2)
+	first, second, third := &ProguardMapping{}, &ProguardMapping{}, &ProguardMapping{}
+
+	cache.put(key("1.0.0"), "c1", first)
+	cache.put(key("1.1.0"), "c2", second)
+
+	// touch the first so that
+	// the second gets evicted
+	if got, ok := cache.get(key("1.0.0"), "c1"); !ok || got != first {
+		t.Error("Expected cached mapping")
+	}
+
+	cache.put(key("1.2.0"), "c3", third)
+
+	if _, ok := cache.get(key("1.1.0"), "c2"); ok {
+		t.Error("Expected least recently used mapping to be evicted")
+	}
+
+	if _, ok := cache.get(key("1.0.0"), "c1"); !ok {
+		t.Error("Expected recently used mapping to be cached")
+	}
+
+	// a replaced mapping file
+	// invalidates the entry
+	if _, ok := cache.get(key("1.2.0"), "c4"); ok {
+		t.Error("Expected stale mapping to be a miss")
+	}
+}
+
+func TestProguardEventBatching(t *testing.T) {
+	appId := uuid.New()
+	newEvent := func(p string) event.EventField {
+		return event.EventField{
+			AppID: appId,
+			Type:  event.TypeException,
+			Attribute: event.Attribute{
+				AppVersion: "1.0.0",
+				AppBuild:   "1000",
+				Platform:   p,
+			},
+		}
+	}
+
+	events := []event.EventField{newEvent(platform.Android), newEvent(platform.IOS)}
+
+	store, _ := pgxpool.New(context.Background(), "")
+	fetch := func(ctx context.Context, key string, w io.WriterAt) error { return nil }
+
+	proguardSymbolicator, err := NewProguardSymbolicator(&Options{
+		Store: store,
+		Fetch: fetch,
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error, but got %v", err)
+	}
+
+	batches := proguardSymbolicator.Batch(events)
+	if len(batches) != 1 || batches[0].mappingKeyID.mappingType != TypeProguard {
+		t.Fatalf("Expected a single proguard batch, but got %+v", batches)
+	}
+
+	symbolicator, err := NewSymbolicator(&Options{
+		Store:        store,
+		Fetch:        fetch,
+		MappingTypes: []string{TypeDsym},
+	})
+	if err != nil {
+		t.Fatalf("Expected nil error without origin, but got %v", err)
+	}
+
+	batches = symbolicator.Batch(events)
+	if len(batches) != 1 || batches[0].mappingKeyID.mappingType != TypeDsym {
+		t.Errorf("Expected a single dSYM batch, but got %+v", batches)
+	}
+
+	if _, err := NewSymbolicator(&Options{Store: store}); err == nil {
+		t.Error("Expected error for missing origin, but got nil")
+	}
+}
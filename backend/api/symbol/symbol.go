@@ -2,11 +2,13 @@ package symbol
 
 import (
 	"backend/api/event"
+	"backend/api/platform"
 	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"slices"
 	"strings"
@@ -21,6 +23,19 @@ import (
 // type of mapping symbolication.
 const TypeProguard = "proguard"
 
+// ValidMappingTypes defines allowed
+// mapping types.
+var ValidMappingTypes = []string{
+	TypeProguard,
+	TypeDsym,
+	TypeElf,
+}
+
+// ErrMappingNotFound is returned when no mapping file
+// exists for the batch. Events of the batch are left
+// unsymbolicated.
+var ErrMappingNotFound = errors.New("no mapping file found for event batch")
+
 // Symboler describes the interface for symbolication.
 type Symboler interface {
 	Batch(events []event.EventField) (batches []SymbolBatch)
@@ -77,11 +92,33 @@ type Options struct {
 	// Table is the name of the table storing build
 	// mappings.
 	Table string
+
+	// Fetch writes the contents of the mapping file
+	// stored against the key. Required to symbolicate
+	// with mappings resolved in process, like dSYMs.
+	Fetch func(ctx context.Context, key string, w io.WriterAt) error
+
+	// BuildIDTable is the name of the table storing
+	// build ids of ELF symbol files.
+	BuildIDTable string
+
+	// MappingTypes are the mapping types the Symbolicator
+	// handles. Defaults to proguard & dSYM mappings.
+	MappingTypes []string
+
+	// ProguardCache caches parsed proguard mappings
+	// across instances of ProguardSymbolicator.
+	ProguardCache *ProguardCache
 }
 
 // NewSymbolicator creates a new instance of Symbolicator.
 func NewSymbolicator(opts *Options) (symbolicator *Symbolicator, err error) {
-	if opts.Origin == "" {
+	if len(opts.MappingTypes) == 0 {
+		opts.MappingTypes = []string{TypeProguard, TypeDsym}
+	}
+	// only proguard mappings are symbolicated
+	// by the symbolicator service
+	if opts.Origin == "" && slices.Contains(opts.MappingTypes, TypeProguard) {
 		err = fmt.Errorf(`%q must not be empty`, `Origin`)
 		return
 	}
@@ -99,16 +136,37 @@ func NewSymbolicator(opts *Options) (symbolicator *Symbolicator, err error) {
 }
 
 // Batch creates groups of events based on the event's attribute
-// values.
+// values. Native crashes are left out as they are symbolicated
+// by the ELFSymbolicator, so are events of mapping types not
+// handled by the Symbolicator.
 func (s Symbolicator) Batch(events []event.EventField) (batches []SymbolBatch) {
+	return batchEvents(events, func(ev event.EventField) (key MappingKeyID, ok bool) {
+		mappingType := mappingTypeOf(ev)
+		if ev.IsNativeCrash() || !slices.Contains(s.opts.MappingTypes, mappingType) {
+			return
+		}
+
+		key = MappingKeyID{
+			appId:       ev.AppID,
+			versionName: ev.Attribute.AppVersion,
+			versionCode: ev.Attribute.AppBuild,
+			mappingType: mappingType,
+		}
+
+		return key, true
+	})
+}
+
+// batchEvents groups events by the mapping key id returned
+// by keyOf. Events without a key are left out. Batches are
+// sorted by their key.
+func batchEvents(events []event.EventField, keyOf func(ev event.EventField) (key MappingKeyID, ok bool)) (batches []SymbolBatch) {
 	keys := make(map[string]SymbolBatch)
 
 	for i := range events {
-		key := MappingKeyID{
-			appId:       events[i].AppID,
-			versionName: events[i].Attribute.AppVersion,
-			versionCode: events[i].Attribute.AppBuild,
-			mappingType: TypeProguard,
+		key, ok := keyOf(events[i])
+		if !ok {
+			continue
 		}
 
 		batch, exists := keys[key.String()]
@@ -172,10 +230,13 @@ func (s Symbolicator) Symbolicate(ctx context.Context, batch SymbolBatch) error
 		return err
 	}
 
-	// in case no mapping file is found, just log and proceed
+	// mapping file may be uploaded later
 	if key == "" {
-		fmt.Println("no mapping file found for event batch")
-		return nil
+		return ErrMappingNotFound
+	}
+
+	if batch.mappingKeyID.mappingType == TypeDsym {
+		return s.symbolicateApple(ctx, batch, key)
 	}
 
 	batch.encode()
@@ -546,6 +607,16 @@ func (b *SymbolBatch) decode(frags []Fragment) {
 	}
 }
 
+// mappingTypeOf returns the type of mapping
+// needed to symbolicate the event based on
+// the event's platform.
+func mappingTypeOf(ev event.EventField) string {
+	if ev.Attribute.Platform == platform.IOS {
+		return TypeDsym
+	}
+	return TypeProguard
+}
+
 // hasFrags returns true if the batch
 // contains symbolication fragments.
 func (b SymbolBatch) hasFrags() bool {
@@ -0,0 +1,530 @@
+package webhook
+
+import (
+	"backend/api/server"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leporo/sqlf"
+)
+
+// SignatureHeader is the header containing
+// the signature of the payload.
+const SignatureHeader = "Measure-Signature"
+
+// EventHeader is the header containing
+// the event type of the payload.
+const EventHeader = "Measure-Event"
+
+// DeliveryHeader is the header containing
+// the unique id of the delivery.
+const DeliveryHeader = "Measure-Delivery"
+
+const (
+	// StatusPending represents a delivery
+	// awaiting an attempt.
+	StatusPending = "pending"
+
+	// StatusSucceeded represents a delivery
+	// acknowledged by the endpoint.
+	StatusSucceeded = "succeeded"
+
+	// StatusFailed represents a delivery that
+	// exhausted all attempts.
+	StatusFailed = "failed"
+)
+
+// MaxAttempts is the maximum number of
+// attempts made for a delivery.
+const MaxAttempts = 6
+
+// baseBackoff is the delay before the
+// first retry.
+const baseBackoff = 30 * time.Second
+
+// maxBackoff is the maximum delay
+// between retries.
+const maxBackoff = 6 * time.Hour
+
+// maxErrorChars is the maximum characters
+// of an error stored in the delivery log.
+const maxErrorChars = 512
+
+// retryInterval is the interval at which due
+// deliveries are retried.
+const retryInterval = 30 * time.Second
+
+// retryBatchSize is the maximum deliveries
+// retried per interval.
+const retryBatchSize = 50
+
+// retryLease is the duration for which claimed
+// deliveries are not picked up again.
+const retryLease = 5 * time.Minute
+
+// client is the http client used for
+// deliveries.
+var client = &http.Client{
+	Timeout:   10 * time.Second,
+	Transport: newTransport(),
+}
+
+// newTransport creates the transport used for
+// deliveries. Connections are only made to public
+// addresses, as hosts may resolve differently
+// than when the webhook was validated.
+func newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	// a proxy would be dialed in
+	// place of the webhook's host
+	transport.Proxy = nil
+
+	dialer := &net.Dialer{
+		Timeout: 5 * time.Second,
+		Control: dialControl,
+	}
+	transport.DialContext = dialer.DialContext
+
+	return transport
+}
+
+// dialControl rejects connections to addresses
+// that are not publicly routable.
+func dialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+
+	if !isPublic(addr) {
+		return fmt.Errorf("%w: %s", errPrivateAddress, addr)
+	}
+
+	return nil
+}
+
+// Delivery represents an attempted or pending
+// delivery of a payload to a webhook.
+type Delivery struct {
+	ID            uuid.UUID       `json:"id"`
+	WebhookID     uuid.UUID       `json:"webhook_id"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Status        string          `json:"status"`
+	Attempts      int             `json:"attempts"`
+	ResponseCode  *int            `json:"response_code"`
+	Error         *string         `json:"error"`
+	NextAttemptAt *time.Time      `json:"next_attempt_at"`
+	DeliveredAt   *time.Time      `json:"delivered_at"`
+	CreatedAt     time.Time       `json:"created_at"`
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// Sign computes the signature header value of the
+// body. The signature is a hex encoded HMAC-SHA256
+// of "<timestamp>.<body>" keyed by the secret.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// Backoff computes the delay before the next
+// attempt after `attempts` failed attempts.
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		return 0
+	}
+
+	delay := baseBackoff
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+
+	return delay
+}
+
+// newDelivery creates a new pending delivery of
+// the payload for the webhook.
+func newDelivery(webhookId uuid.UUID, event string, appId uuid.UUID, data any) (delivery *Delivery, err error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return
+	}
+
+	payload, err := json.Marshal(Payload{
+		ID:        id,
+		Event:     event,
+		AppID:     appId,
+		Timestamp: time.Now().UTC(),
+		Data:      data,
+	})
+	if err != nil {
+		return
+	}
+
+	delivery = &Delivery{
+		ID:        id,
+		WebhookID: webhookId,
+		Event:     event,
+		Payload:   payload,
+		Status:    StatusPending,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	return
+}
+
+// insert inserts the delivery.
+func (d Delivery) insert(ctx context.Context) (err error) {
+	stmt := sqlf.PostgreSQL.
+		InsertInto("public.webhook_deliveries").
+		Set("id", d.ID).
+		Set("webhook_id", d.WebhookID).
+		Set("event", d.Event).
+		Set("payload", string(d.Payload)).
+		Set("status", d.Status).
+		Set("attempts", d.Attempts).
+		Set("created_at", d.CreatedAt).
+		Set("updated_at", d.UpdatedAt)
+
+	defer stmt.Close()
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// update persists the outcome of the
+// last attempt.
+func (d Delivery) update(ctx context.Context) (err error) {
+	stmt := sqlf.PostgreSQL.
+		Update("public.webhook_deliveries").
+		Set("status", d.Status).
+		Set("attempts", d.Attempts).
+		Set("response_code", d.ResponseCode).
+		Set("error", d.Error).
+		Set("next_attempt_at", d.NextAttemptAt).
+		Set("delivered_at", d.DeliveredAt).
+		Set("updated_at", d.UpdatedAt).
+		Where("id = ?", d.ID)
+
+	defer stmt.Close()
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// attempt posts the signed payload to the webhook
+// and records the outcome.
+func (d *Delivery) attempt(ctx context.Context, webhook Webhook, retry bool) (err error) {
+	code, sendErr := send(ctx, webhook, *d)
+	d.record(time.Now(), code, sendErr, retry)
+
+	return d.update(ctx)
+}
+
+// record records the outcome of an attempt. If
+// retry is true, failed attempts are scheduled
+// for retry with exponential backoff until
+// attempts are exhausted, otherwise they fail
+// right away.
+func (d *Delivery) record(now time.Time, code int, sendErr error, retry bool) {
+	d.Attempts++
+	d.UpdatedAt = now
+	d.ResponseCode = nil
+	d.Error = nil
+
+	if code != 0 {
+		d.ResponseCode = &code
+	}
+
+	if sendErr == nil {
+		d.Status = StatusSucceeded
+		d.DeliveredAt = &now
+		d.NextAttemptAt = nil
+		return
+	}
+
+	msg := sendErr.Error()
+	if len(msg) > maxErrorChars {
+		msg = msg[:maxErrorChars]
+	}
+	d.Error = &msg
+
+	if !retry || d.Attempts >= MaxAttempts {
+		d.Status = StatusFailed
+		d.NextAttemptAt = nil
+	} else {
+		next := now.Add(Backoff(d.Attempts))
+		d.Status = StatusPending
+		d.NextAttemptAt = &next
+	}
+}
+
+// send posts the signed payload to the webhook's
+// url. Any non-2xx response is considered a failure.
+func send(ctx context.Context, webhook Webhook, delivery Delivery) (code int, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "measure-webhook")
+	req.Header.Set(EventHeader, delivery.Event)
+	req.Header.Set(DeliveryHeader, delivery.ID.String())
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, time.Now().Unix(), delivery.Payload))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return
+	}
+
+	defer resp.Body.Close()
+
+	// drain a bounded part of the body
+	// to allow connection reuse
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	code = resp.StatusCode
+
+	if code < 200 || code > 299 {
+		err = fmt.Errorf("endpoint responded with status %d", code)
+	}
+
+	return
+}
+
+// Dispatch creates deliveries for all active webhooks
+// subscribed to the events and makes the first
+// delivery attempt. Failed deliveries are retried
+// by the retrier.
+func Dispatch(ctx context.Context, appId uuid.UUID, events []Event) {
+	if len(events) < 1 {
+		return
+	}
+
+	webhooks, err := getActiveWebhooks(ctx, appId)
+	if err != nil {
+		fmt.Println("failed to fetch webhooks", err)
+		return
+	}
+
+	for _, webhook := range webhooks {
+		for _, event := range events {
+			if !webhook.Subscribes(event.Type) {
+				continue
+			}
+
+			data := payloadData{
+				Group: event.Group,
+			}
+
+			if event.Type == EventGroupVolumeThreshold {
+				if event.Volume == nil || !event.Volume.Crosses(webhook.VolumeThreshold) {
+					continue
+				}
+				data.Threshold = webhook.VolumeThreshold
+				data.Volume = event.Volume
+			}
+
+			delivery, err := newDelivery(webhook.ID, event.Type, appId, data)
+			if err != nil {
+				fmt.Println("failed to create webhook delivery", err)
+				continue
+			}
+
+			if err := delivery.insert(ctx); err != nil {
+				fmt.Println("failed to save webhook delivery", err)
+				continue
+			}
+
+			if err := delivery.attempt(ctx, webhook, true); err != nil {
+				fmt.Println("failed to update webhook delivery", err)
+			}
+		}
+	}
+}
+
+// SendTest makes a single delivery attempt of a
+// test payload to the webhook and returns the
+// logged delivery. Test deliveries are never
+// retried.
+func SendTest(ctx context.Context, webhook Webhook) (delivery *Delivery, err error) {
+	delivery, err = newDelivery(webhook.ID, EventTest, webhook.AppID, map[string]string{
+		"message": "this is a test delivery from measure",
+	})
+	if err != nil {
+		return
+	}
+
+	if err = delivery.insert(ctx); err != nil {
+		return
+	}
+
+	if err = delivery.attempt(ctx, webhook, false); err != nil {
+		return
+	}
+
+	return
+}
+
+// GetDeliveries fetches the delivery log of a webhook
+// in reverse chronological order.
+func GetDeliveries(ctx context.Context, webhookId uuid.UUID, limit, offset int) (deliveries []Delivery, err error) {
+	stmt := sqlf.PostgreSQL.
+		From("public.webhook_deliveries").
+		Select("id").
+		Select("webhook_id").
+		Select("event").
+		Select("payload").
+		Select("status").
+		Select("attempts").
+		Select("response_code").
+		Select("error").
+		Select("next_attempt_at").
+		Select("delivered_at").
+		Select("created_at").
+		Select("updated_at").
+		Where("webhook_id = ?", webhookId).
+		OrderBy("created_at desc").
+		Limit(limit).
+		Offset(offset)
+
+	defer stmt.Close()
+
+	rows, err := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var d Delivery
+		if err = rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Status, &d.Attempts, &d.ResponseCode, &d.Error, &d.NextAttemptAt, &d.DeliveredAt, &d.CreatedAt, &d.UpdatedAt); err != nil {
+			return
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	err = rows.Err()
+
+	return
+}
+
+// retryDue claims pending deliveries whose next attempt
+// is due and retries them. Claimed deliveries are leased
+// by pushing their next attempt forward, so that
+// concurrent retriers don't pick the same deliveries.
+func retryDue(ctx context.Context) (err error) {
+	now := time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.webhook_deliveries").
+		Set("next_attempt_at", now.Add(retryLease)).
+		Where("id in (select d.id from public.webhook_deliveries d join public.webhooks w on w.id = d.webhook_id where d.status = ? and d.next_attempt_at <= ? and w.active = ? order by d.next_attempt_at limit ? for update of d skip locked)", StatusPending, now, true, retryBatchSize).
+		Returning("id").
+		Returning("webhook_id").
+		Returning("event").
+		Returning("payload").
+		Returning("attempts").
+		Returning("created_at")
+
+	defer stmt.Close()
+
+	rows, err := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	var deliveries []Delivery
+
+	for rows.Next() {
+		var d Delivery
+		if err = rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Payload, &d.Attempts, &d.CreatedAt); err != nil {
+			rows.Close()
+			return
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return
+	}
+
+	webhooks := make(map[uuid.UUID]*Webhook)
+
+	for i := range deliveries {
+		webhook, ok := webhooks[deliveries[i].WebhookID]
+		if !ok {
+			webhook, err = getWebhookByID(ctx, deliveries[i].WebhookID)
+			if err != nil {
+				return
+			}
+			webhooks[deliveries[i].WebhookID] = webhook
+		}
+
+		// webhook was deleted after
+		// claiming the delivery
+		if webhook == nil {
+			continue
+		}
+
+		if err := deliveries[i].attempt(ctx, *webhook, true); err != nil {
+			fmt.Println("failed to update webhook delivery", err)
+		}
+	}
+
+	return
+}
+
+// StartRetrier periodically retries failed
+// deliveries until the context is done.
+func StartRetrier(ctx context.Context) {
+	ticker := time.NewTicker(retryInterval)
+
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := retryDue(ctx); err != nil {
+					fmt.Println("failed to retry webhook deliveries", err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,112 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// GroupKindCrash represents a crash group.
+const GroupKindCrash = "crash"
+
+// GroupKindANR represents an ANR group.
+const GroupKindANR = "anr"
+
+// Group represents a crash or ANR group
+// in webhook payloads.
+type Group struct {
+	ID             uuid.UUID `json:"id"`
+	Kind           string    `json:"kind"`
+	Type           string    `json:"type"`
+	Message        string    `json:"message"`
+	MethodName     string    `json:"method_name"`
+	FileName       string    `json:"file_name"`
+	LineNumber     int       `json:"line_number"`
+	Fingerprint    string    `json:"fingerprint"`
+	FirstEventTime time.Time `json:"first_event_timestamp"`
+}
+
+// Volume represents the change in number of
+// events of a group.
+type Volume struct {
+	// Previous is the number of events in the
+	// group before the events were bucketed.
+	Previous int `json:"previous_count"`
+
+	// Current is the number of events in the
+	// group after the events were bucketed.
+	Current int `json:"count"`
+}
+
+// Crosses returns true if the volume crossed
+// the threshold.
+func (v Volume) Crosses(threshold int) bool {
+	if threshold < 1 {
+		return false
+	}
+	return v.Previous < threshold && v.Current >= threshold
+}
+
+// Event represents an occurrence of interest
+// for webhooks of an app.
+type Event struct {
+	// Type is the type of the event.
+	Type string
+
+	// AppID is the id of the app.
+	AppID uuid.UUID
+
+	// Group is the crash or ANR group
+	// of the event.
+	Group Group
+
+	// Volume is the change in number of
+	// events of the group. Only present for
+	// volume threshold events.
+	Volume *Volume
+}
+
+// NewGroupEvent creates an event for a
+// newly created group.
+func NewGroupEvent(appId uuid.UUID, group Group) Event {
+	eventType := EventCrashGroupNew
+	if group.Kind == GroupKindANR {
+		eventType = EventANRGroupNew
+	}
+
+	return Event{
+		Type:  eventType,
+		AppID: appId,
+		Group: group,
+	}
+}
+
+// NewVolumeEvent creates a volume threshold event
+// candidate for a group. The event is only delivered
+// to webhooks whose threshold was crossed.
+func NewVolumeEvent(appId uuid.UUID, group Group, volume Volume) Event {
+	return Event{
+		Type:   EventGroupVolumeThreshold,
+		AppID:  appId,
+		Group:  group,
+		Volume: &volume,
+	}
+}
+
+// Payload represents the json body of
+// a webhook delivery.
+type Payload struct {
+	ID        uuid.UUID `json:"id"`
+	Event     string    `json:"event"`
+	AppID     uuid.UUID `json:"app_id"`
+	Timestamp time.Time `json:"timestamp"`
+	Data      any       `json:"data"`
+}
+
+// payloadData represents the data of
+// group event payloads.
+type payloadData struct {
+	Group     Group `json:"group"`
+	Threshold int   `json:"threshold,omitempty"`
+	*Volume
+}
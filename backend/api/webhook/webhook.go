@@ -0,0 +1,418 @@
+package webhook
+
+import (
+	"backend/api/server"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/leporo/sqlf"
+)
+
+// EventCrashGroupNew is delivered when a new
+// crash group is created.
+const EventCrashGroupNew = "crash_group.new"
+
+// EventANRGroupNew is delivered when a new
+// ANR group is created.
+const EventANRGroupNew = "anr_group.new"
+
+// EventGroupVolumeThreshold is delivered when the
+// number of events in a crash or ANR group crosses
+// the webhook's volume threshold.
+const EventGroupVolumeThreshold = "group.volume_threshold_crossed"
+
+// EventTest is delivered when a test delivery
+// is requested.
+const EventTest = "test"
+
+// secretPrefix is the prefix of webhook secrets.
+const secretPrefix = "whsec_"
+
+// maxURLChars is the maximum characters allowed
+// in a webhook url.
+const maxURLChars = 2048
+
+// sharedAddressSpace is the carrier-grade NAT
+// range, not routable on the public internet.
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// IPv6 ranges that carry an embedded IPv4 address
+// the traffic may end up being translated or
+// tunnelled to.
+var (
+	nat64      = netip.MustParsePrefix("64:ff9b::/96")
+	nat64Local = netip.MustParsePrefix("64:ff9b:1::/48")
+	sixToFour  = netip.MustParsePrefix("2002::/16")
+	teredo     = netip.MustParsePrefix("2001::/32")
+)
+
+// errPrivateAddress is returned for webhook hosts
+// resolving to loopback, private, link-local or
+// otherwise non-public addresses.
+var errPrivateAddress = errors.New("address is not publicly routable")
+
+// lookupAddrs resolves a host to its addresses.
+var lookupAddrs = net.DefaultResolver.LookupNetIP
+
+// ValidEvents defines the event types a
+// webhook can subscribe to.
+var ValidEvents = []string{
+	EventCrashGroupNew,
+	EventANRGroupNew,
+	EventGroupVolumeThreshold,
+}
+
+// Webhook represents a per-app endpoint receiving
+// signed event payloads.
+type Webhook struct {
+	ID              uuid.UUID `json:"id"`
+	AppID           uuid.UUID `json:"app_id"`
+	URL             string    `json:"url"`
+	Secret          string    `json:"secret,omitempty"`
+	Events          []string  `json:"events"`
+	VolumeThreshold int       `json:"volume_threshold"`
+	Active          bool      `json:"active"`
+	CreatedBy       uuid.UUID `json:"created_by"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// NewWebhook creates a new webhook with a
+// freshly generated secret.
+func NewWebhook(appId, userId uuid.UUID) (webhook *Webhook, err error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return
+	}
+
+	bytes := make([]byte, 32)
+	if _, err = rand.Read(bytes); err != nil {
+		return
+	}
+
+	webhook = &Webhook{
+		ID:        id,
+		AppID:     appId,
+		Secret:    secretPrefix + hex.EncodeToString(bytes),
+		Active:    true,
+		CreatedBy: userId,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	return
+}
+
+// Validate validates the webhook's url, events
+// and volume threshold. The url's host must only
+// resolve to public addresses, so that webhooks
+// can't reach internal services.
+func (w Webhook) Validate(ctx context.Context) error {
+	if w.URL == "" {
+		return errors.New(`"url" must not be empty`)
+	}
+
+	if len(w.URL) > maxURLChars {
+		return fmt.Errorf(`"url" exceeds maximum allowed characters of %d`, maxURLChars)
+	}
+
+	u, err := url.ParseRequestURI(w.URL)
+	if err != nil || u.Host == "" {
+		return errors.New(`"url" must be a valid absolute url`)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New(`"url" must use http or https scheme`)
+	}
+
+	if err := checkHost(ctx, u.Hostname()); err != nil {
+		return fmt.Errorf(`"url" host is not allowed: %w`, err)
+	}
+
+	if len(w.Events) < 1 {
+		return errors.New(`"events" must contain at least 1 event`)
+	}
+
+	for _, event := range w.Events {
+		if !slices.Contains(ValidEvents, event) {
+			return fmt.Errorf(`"events" contains invalid event %q`, event)
+		}
+	}
+
+	if w.VolumeThreshold < 0 {
+		return errors.New(`"volume_threshold" must not be negative`)
+	}
+
+	if w.Subscribes(EventGroupVolumeThreshold) && w.VolumeThreshold < 1 {
+		return fmt.Errorf(`"volume_threshold" must be greater than 0 to subscribe to %q`, EventGroupVolumeThreshold)
+	}
+
+	return nil
+}
+
+// isPublic checks if the address is a
+// publicly routable unicast address.
+func isPublic(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() || sharedAddressSpace.Contains(addr) {
+		return false
+	}
+
+	// local-use NAT64 prefixes are specific to
+	// the network, so the translated address
+	// can't be known.
+	if nat64Local.Contains(addr) {
+		return false
+	}
+
+	for _, v4 := range embeddedIPv4(addr) {
+		if !isPublic(v4) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// embeddedIPv4 extracts the IPv4 addresses embedded
+// in NAT64, 6to4 & Teredo IPv6 addresses.
+func embeddedIPv4(addr netip.Addr) (addrs []netip.Addr) {
+	if !addr.Is6() {
+		return
+	}
+
+	b := addr.As16()
+
+	switch {
+	case nat64.Contains(addr):
+		addrs = append(addrs, netip.AddrFrom4([4]byte(b[12:16])))
+	case sixToFour.Contains(addr):
+		addrs = append(addrs, netip.AddrFrom4([4]byte(b[2:6])))
+	case teredo.Contains(addr):
+		// the client address is stored
+		// with all of its bits inverted.
+		client := [4]byte{^b[12], ^b[13], ^b[14], ^b[15]}
+		addrs = append(addrs, netip.AddrFrom4([4]byte(b[4:8])), netip.AddrFrom4(client))
+	}
+
+	return
+}
+
+// checkHost checks that the host is, or only
+// resolves to, public addresses.
+func checkHost(ctx context.Context, host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !isPublic(addr) {
+			return fmt.Errorf("%w: %s", errPrivateAddress, addr)
+		}
+		return nil
+	}
+
+	addrs, err := lookupAddrs(ctx, "ip", host)
+	if err != nil {
+		return fmt.Errorf("failed to resolve %q: %w", host, err)
+	}
+
+	for _, addr := range addrs {
+		if !isPublic(addr) {
+			return fmt.Errorf("%w: %s resolves to %s", errPrivateAddress, host, addr)
+		}
+	}
+
+	return nil
+}
+
+// Subscribes returns true if the webhook is
+// subscribed to the event.
+func (w Webhook) Subscribes(event string) bool {
+	return slices.Contains(w.Events, event)
+}
+
+// Mask hides the webhook's secret.
+func (w *Webhook) Mask() {
+	w.Secret = ""
+}
+
+// Insert inserts a new webhook.
+func (w Webhook) Insert(ctx context.Context) (err error) {
+	stmt := sqlf.PostgreSQL.
+		InsertInto("public.webhooks").
+		Set("id", w.ID).
+		Set("app_id", w.AppID).
+		Set("url", w.URL).
+		Set("secret", w.Secret).
+		Set("events", w.Events).
+		Set("volume_threshold", w.VolumeThreshold).
+		Set("active", w.Active).
+		Set("created_by", w.CreatedBy).
+		Set("created_at", w.CreatedAt).
+		Set("updated_at", w.UpdatedAt)
+
+	defer stmt.Close()
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// Update updates the webhook's url, events,
+// volume threshold and active state.
+func (w *Webhook) Update(ctx context.Context) (err error) {
+	w.UpdatedAt = time.Now()
+
+	stmt := sqlf.PostgreSQL.
+		Update("public.webhooks").
+		Set("url", w.URL).
+		Set("events", w.Events).
+		Set("volume_threshold", w.VolumeThreshold).
+		Set("active", w.Active).
+		Set("updated_at", w.UpdatedAt).
+		Where("id = ?", w.ID).
+		Where("app_id = ?", w.AppID)
+
+	defer stmt.Close()
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// Delete deletes the webhook along with
+// its delivery log.
+func (w Webhook) Delete(ctx context.Context) (err error) {
+	stmt := sqlf.PostgreSQL.
+		DeleteFrom("public.webhooks").
+		Where("id = ?", w.ID).
+		Where("app_id = ?", w.AppID)
+
+	defer stmt.Close()
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// selectWebhooks builds the select statement
+// for webhooks.
+func selectWebhooks() *sqlf.Stmt {
+	return sqlf.PostgreSQL.
+		From("public.webhooks").
+		Select("id").
+		Select("app_id").
+		Select("url").
+		Select("secret").
+		Select("events").
+		Select("volume_threshold").
+		Select("active").
+		Select("coalesce(created_by, '00000000-0000-0000-0000-000000000000'::uuid)").
+		Select("created_at").
+		Select("updated_at")
+}
+
+// scanWebhooks scans all rows into webhooks.
+func scanWebhooks(rows pgx.Rows) (webhooks []Webhook, err error) {
+	defer rows.Close()
+
+	for rows.Next() {
+		var w Webhook
+		if err = rows.Scan(&w.ID, &w.AppID, &w.URL, &w.Secret, &w.Events, &w.VolumeThreshold, &w.Active, &w.CreatedBy, &w.CreatedAt, &w.UpdatedAt); err != nil {
+			return
+		}
+		webhooks = append(webhooks, w)
+	}
+
+	err = rows.Err()
+
+	return
+}
+
+// GetWebhook fetches a webhook of an app. Returns
+// nil if the webhook doesn't exist.
+func GetWebhook(ctx context.Context, appId, webhookId uuid.UUID) (webhook *Webhook, err error) {
+	stmt := selectWebhooks().
+		Where("app_id = ?", appId).
+		Where("id = ?", webhookId)
+
+	defer stmt.Close()
+
+	rows, err := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	webhooks, err := scanWebhooks(rows)
+	if err != nil || len(webhooks) < 1 {
+		return
+	}
+
+	webhook = &webhooks[0]
+
+	return
+}
+
+// getWebhookByID fetches a webhook by its id.
+// Returns nil if the webhook doesn't exist.
+func getWebhookByID(ctx context.Context, webhookId uuid.UUID) (webhook *Webhook, err error) {
+	stmt := selectWebhooks().
+		Where("id = ?", webhookId)
+
+	defer stmt.Close()
+
+	rows, err := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	webhooks, err := scanWebhooks(rows)
+	if err != nil || len(webhooks) < 1 {
+		return
+	}
+
+	webhook = &webhooks[0]
+
+	return
+}
+
+// GetWebhooks fetches all webhooks of an app.
+func GetWebhooks(ctx context.Context, appId uuid.UUID) (webhooks []Webhook, err error) {
+	stmt := selectWebhooks().
+		Where("app_id = ?", appId).
+		OrderBy("created_at desc")
+
+	defer stmt.Close()
+
+	rows, err := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	return scanWebhooks(rows)
+}
+
+// getActiveWebhooks fetches all active webhooks
+// of an app.
+func getActiveWebhooks(ctx context.Context, appId uuid.UUID) (webhooks []Webhook, err error) {
+	stmt := selectWebhooks().
+		Where("app_id = ?", appId).
+		Where("active = ?", true)
+
+	defer stmt.Close()
+
+	rows, err := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	return scanWebhooks(rows)
+}
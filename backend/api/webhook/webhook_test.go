@@ -0,0 +1,207 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+)
+
+// stubLookup resolves hosts from a fixed
+// table for the duration of the test.
+func stubLookup(t *testing.T, hosts map[string]string) {
+	lookup := lookupAddrs
+	t.Cleanup(func() { lookupAddrs = lookup })
+
+	lookupAddrs = func(_ context.Context, _, host string) ([]netip.Addr, error) {
+		addr, ok := hosts[host]
+		if !ok {
+			return nil, fmt.Errorf("no such host %q", host)
+		}
+		return []netip.Addr{netip.MustParseAddr(addr)}, nil
+	}
+}
+
+func TestSign(t *testing.T) {
+	expected := "t=1700000000,v1=85876387ad9d6be57a04653bc0729da757049f58afb10ba6cac3bedaecf4fda3"
+	got := Sign("whsec_test", 1700000000, []byte(`{"ok":true}`))
+
+	if got != expected {
+		t.Errorf("Expected signature %q, but got %q", expected, got)
+	}
+
+	if Sign("whsec_test", 1700000000, []byte(`{"ok":true}`)) != got {
+		t.Error("Expected signature to be deterministic")
+	}
+
+	if Sign("whsec_other", 1700000000, []byte(`{"ok":true}`)) == got {
+		t.Error("Expected signature to differ for different secrets")
+	}
+
+	if Sign("whsec_test", 1700000001, []byte(`{"ok":true}`)) == got {
+		t.Error("Expected signature to differ for different timestamps")
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	cases := map[int]time.Duration{
+		0:  0,
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		5:  8 * time.Minute,
+		20: 6 * time.Hour,
+	}
+
+	for attempts, expected := range cases {
+		if got := Backoff(attempts); got != expected {
+			t.Errorf("Expected backoff %v for %d attempts, but got %v", expected, attempts, got)
+		}
+	}
+}
+
+func TestDeliveryRecord(t *testing.T) {
+	now := time.Now()
+	sendErr := errors.New("endpoint responded with status 500")
+
+	succeeded := Delivery{Status: StatusPending}
+	succeeded.record(now, 200, nil, true)
+	if succeeded.Status != StatusSucceeded || succeeded.Attempts != 1 || succeeded.DeliveredAt == nil {
+		t.Errorf("Expected succeeded delivery after 1 attempt, but got %+v", succeeded)
+	}
+
+	retried := Delivery{Status: StatusPending}
+	retried.record(now, 500, sendErr, true)
+	if retried.Status != StatusPending || retried.NextAttemptAt == nil {
+		t.Errorf("Expected pending delivery with next attempt, but got %+v", retried)
+	}
+
+	exhausted := Delivery{Status: StatusPending, Attempts: MaxAttempts - 1}
+	exhausted.record(now, 500, sendErr, true)
+	if exhausted.Status != StatusFailed || exhausted.NextAttemptAt != nil {
+		t.Errorf("Expected failed delivery without next attempt, but got %+v", exhausted)
+	}
+
+	test := Delivery{Status: StatusPending}
+	test.record(now, 0, sendErr, false)
+	if test.Status != StatusFailed || test.NextAttemptAt != nil || test.ResponseCode != nil {
+		t.Errorf("Expected failed delivery without next attempt, but got %+v", test)
+	}
+
+	if test.Attempts != 1 {
+		t.Errorf("Expected %d attempts, but got %d", 1, test.Attempts)
+	}
+}
+
+func TestVolumeCrosses(t *testing.T) {
+	cases := []struct {
+		volume    Volume
+		threshold int
+		expected  bool
+	}{
+		{Volume{Previous: 9, Current: 10}, 10, true},
+		{Volume{Previous: 0, Current: 25}, 10, true},
+		{Volume{Previous: 10, Current: 11}, 10, false},
+		{Volume{Previous: 5, Current: 9}, 10, false},
+		{Volume{Previous: 0, Current: 5}, 0, false},
+	}
+
+	for _, c := range cases {
+		if got := c.volume.Crosses(c.threshold); got != c.expected {
+			t.Errorf("Expected %v for %+v with threshold %d, but got %v", c.expected, c.volume, c.threshold, got)
+		}
+	}
+}
+
+func TestWebhookValidate(t *testing.T) {
+	stubLookup(t, map[string]string{
+		"example.com":          "93.184.215.14",
+		"internal.example.com": "10.0.0.5",
+		"metadata.example.com": "169.254.169.254",
+	})
+
+	ctx := context.Background()
+
+	valid := []Webhook{
+		{URL: "https://example.com/hooks/measure", Events: []string{EventCrashGroupNew, EventANRGroupNew}},
+		{URL: "https://93.184.215.14/hooks", Events: []string{EventCrashGroupNew}},
+		{URL: "https://[64:ff9b::5db8:d70e]/hooks", Events: []string{EventCrashGroupNew}},
+		{URL: "https://[2002:5db8:d70e::1]/hooks", Events: []string{EventCrashGroupNew}},
+	}
+
+	for _, w := range valid {
+		if err := w.Validate(ctx); err != nil {
+			t.Errorf("Expected nil error for %q, but got %v", w.URL, err)
+		}
+	}
+
+	invalid := []Webhook{
+		{URL: "", Events: []string{EventCrashGroupNew}},
+		{URL: "example.com/hooks", Events: []string{EventCrashGroupNew}},
+		{URL: "ftp://example.com/hooks", Events: []string{EventCrashGroupNew}},
+		{URL: "https://example.com/hooks", Events: []string{}},
+		{URL: "https://example.com/hooks", Events: []string{"unknown"}},
+		{URL: "https://example.com/hooks", Events: []string{EventGroupVolumeThreshold}},
+		{URL: "https://example.com/hooks", Events: []string{EventCrashGroupNew}, VolumeThreshold: -1},
+		{URL: "https://unknown.example.com/hooks", Events: []string{EventCrashGroupNew}},
+	}
+
+	for _, w := range invalid {
+		if err := w.Validate(ctx); err == nil {
+			t.Errorf("Expected error for %+v, but got nil", w)
+		}
+	}
+
+	private := []string{
+		"http://internal.example.com/hooks",
+		"http://metadata.example.com/latest/meta-data",
+		"http://127.0.0.1:8080/hooks",
+		"http://10.1.2.3/hooks",
+		"http://172.16.0.1/hooks",
+		"http://192.168.1.1/hooks",
+		"http://100.64.0.1/hooks",
+		"http://169.254.169.254/latest/meta-data",
+		"http://0.0.0.0/hooks",
+		"http://[::1]/hooks",
+		"http://[fd00::1]/hooks",
+		"http://[fe80::1]/hooks",
+		"http://[::ffff:10.0.0.1]/hooks",
+		"http://[64:ff9b::a9fe:a9fe]/latest/meta-data",
+		"http://[64:ff9b::7f00:1]/hooks",
+		"http://[64:ff9b:1::5db8:d70e]/hooks",
+		"http://[2002:a00:1::1]/hooks",
+		"http://[2002:7f00:1::1]/hooks",
+		"http://[2001:0:5db8:d70e::f5ff:fffe]/hooks",
+		"http://[2001:0:a00:1::a2ff:fffe]/hooks",
+	}
+
+	for _, url := range private {
+		w := Webhook{URL: url, Events: []string{EventCrashGroupNew}}
+		if err := w.Validate(ctx); !errors.Is(err, errPrivateAddress) {
+			t.Errorf("Expected %v for %q, but got %v", errPrivateAddress, url, err)
+		}
+	}
+}
+
+func TestSendPrivateAddress(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer srv.Close()
+
+	w := Webhook{URL: srv.URL, Secret: "whsec_test"}
+	d := Delivery{Event: EventTest, Payload: []byte(`{"ok":true}`)}
+
+	if _, err := send(context.Background(), w, d); !errors.Is(err, errPrivateAddress) {
+		t.Errorf("Expected %v, but got %v", errPrivateAddress, err)
+	}
+
+	if requests != 0 {
+		t.Errorf("Expected no requests to reach the server, but got %d", requests)
+	}
+}
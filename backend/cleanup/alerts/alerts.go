@@ -0,0 +1,497 @@
+package alerts
+
+import (
+	"backend/cleanup/server"
+	"backend/email"
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/leporo/sqlf"
+)
+
+const (
+	// TypeCrashRateSpike represents a spike in
+	// the crash rate of an app.
+	TypeCrashRateSpike = "crash_rate_spike"
+
+	// TypeAnrRateSpike represents a spike in
+	// the ANR rate of an app.
+	TypeAnrRateSpike = "anr_rate_spike"
+
+	// TypeLaunchTimeSpike represents a spike in
+	// the cold launch p95 of an app.
+	TypeLaunchTimeSpike = "launch_time_spike"
+)
+
+// maxColdLaunchDuration is the upper bound of cold
+// launch durations considered. Mirrors the filter
+// applied by the launch metrics in the api.
+const maxColdLaunchDuration = 30000
+
+// prefColumns maps each alert type to the
+// alert pref column that toggles its emails.
+var prefColumns = map[string]string{
+	TypeCrashRateSpike:  "crash_rate_spike_email",
+	TypeAnrRateSpike:    "anr_rate_spike_email",
+	TypeLaunchTimeSpike: "launch_time_spike_email",
+}
+
+// App represents an app evaluated for alerts.
+type App struct {
+	ID     uuid.UUID
+	TeamID uuid.UUID
+	Name   string
+}
+
+// Window represents the evaluated window and
+// the baseline window preceding it.
+type Window struct {
+	BaselineFrom time.Time
+	From         time.Time
+	To           time.Time
+}
+
+// Metric represents the value of a metric in
+// the evaluated window and in the baseline
+// window.
+type Metric struct {
+	Current  float64
+	Baseline float64
+	Sessions uint64
+}
+
+// Alert represents a detected spike.
+type Alert struct {
+	ID       uuid.UUID
+	App      App
+	Type     string
+	Metric   Metric
+	Delta    float64
+	Window   Window
+	Notified int
+}
+
+// Evaluator evaluates all apps for metric spikes
+// and notifies users via email.
+type Evaluator struct {
+	sender email.Sender
+	config server.AlertConfig
+}
+
+// NewEvaluator creates a new Evaluator.
+func NewEvaluator(sender email.Sender, config server.AlertConfig) *Evaluator {
+	return &Evaluator{
+		sender: sender,
+		config: config,
+	}
+}
+
+// EvaluateAlerts evaluates alerts for all onboarded
+// apps using the configured SMTP sender.
+func EvaluateAlerts(ctx context.Context) {
+	config := server.Server.Config
+	sender, err := email.NewSMTPSender(&email.Options{
+		Host:     config.SMTP.Host,
+		Port:     config.SMTP.Port,
+		Username: config.SMTP.Username,
+		Password: config.SMTP.Password,
+		From:     config.SMTP.From,
+	})
+	if err != nil {
+		fmt.Printf("Skipping alert evaluation, failed to create email sender: %v\n", err)
+		return
+	}
+
+	NewEvaluator(sender, config.Alert).Run(ctx, time.Now().UTC())
+}
+
+// Run evaluates all onboarded apps for spikes
+// in the window ending at `now`.
+func (e Evaluator) Run(ctx context.Context, now time.Time) {
+	window := Window{
+		BaselineFrom: now.Add(-e.config.Window - e.config.Baseline),
+		From:         now.Add(-e.config.Window),
+		To:           now,
+	}
+
+	apps, err := fetchApps(ctx)
+	if err != nil {
+		fmt.Printf("Failed to fetch apps for alert evaluation: %v\n", err)
+		return
+	}
+
+	for _, app := range apps {
+		alerts, err := e.evaluate(ctx, app, window)
+		if err != nil {
+			fmt.Printf("Failed to evaluate alerts for app_id: %v, err: %v\n", app.ID, err)
+			continue
+		}
+
+		for i := range alerts {
+			if err := e.notify(ctx, &alerts[i]); err != nil {
+				fmt.Printf("Failed to notify %s alert for app_id: %v, err: %v\n", alerts[i].Type, app.ID, err)
+				continue
+			}
+		}
+	}
+}
+
+// evaluate computes all metrics of an app and
+// returns alerts for the metrics that spiked.
+func (e Evaluator) evaluate(ctx context.Context, app App, window Window) (alerts []Alert, err error) {
+	crash, err := crashRate(ctx, app.ID, window)
+	if err != nil {
+		return
+	}
+
+	anr, err := anrRate(ctx, app.ID, window)
+	if err != nil {
+		return
+	}
+
+	launch, err := coldLaunchP95(ctx, app.ID, window)
+	if err != nil {
+		return
+	}
+
+	metrics := []struct {
+		kind   string
+		metric Metric
+	}{
+		{TypeCrashRateSpike, crash},
+		{TypeAnrRateSpike, anr},
+		{TypeLaunchTimeSpike, launch},
+	}
+
+	for _, m := range metrics {
+		delta, spiked := IsSpike(m.metric, e.config.Threshold, e.config.MinSessions)
+		if !spiked {
+			continue
+		}
+
+		alerts = append(alerts, Alert{
+			App:    app,
+			Type:   m.kind,
+			Metric: m.metric,
+			Delta:  delta,
+			Window: window,
+		})
+	}
+
+	return
+}
+
+// notify emails the alert to all users who opted in,
+// unless an alert of the same type was already sent
+// for the app within the cooldown period.
+func (e Evaluator) notify(ctx context.Context, alert *Alert) (err error) {
+	duplicate, err := isDuplicate(ctx, *alert, e.config.Cooldown)
+	if err != nil {
+		return
+	}
+
+	if duplicate {
+		fmt.Printf("Skipping duplicate %s alert for app_id: %v\n", alert.Type, alert.App.ID)
+		return
+	}
+
+	recipients, err := fetchRecipients(ctx, *alert)
+	if err != nil {
+		return
+	}
+
+	if len(recipients) > 0 {
+		if err = e.sender.Send(ctx, compose(*alert, recipients)); err != nil {
+			return
+		}
+	}
+
+	alert.Notified = len(recipients)
+
+	return alert.save(ctx)
+}
+
+// IsSpike computes the ratio of the current value
+// to the baseline value and reports whether it
+// crosses the threshold. Metrics with fewer sessions
+// than `minSessions` in the evaluated window never
+// spike.
+func IsSpike(m Metric, threshold float64, minSessions uint64) (delta float64, spiked bool) {
+	if m.Sessions < minSessions {
+		return
+	}
+
+	if math.IsNaN(m.Current) || math.IsNaN(m.Baseline) || m.Current <= 0 {
+		return
+	}
+
+	// a metric that was absent in the baseline
+	// and is present now is considered a spike
+	if m.Baseline <= 0 {
+		return math.Inf(1), true
+	}
+
+	delta = math.Round(m.Current/m.Baseline*100) / 100
+	spiked = delta >= threshold
+
+	return
+}
+
+// compose builds the alert email message.
+func compose(alert Alert, recipients []string) email.Message {
+	var subject string
+	var page string
+	var b strings.Builder
+
+	switch alert.Type {
+	case TypeCrashRateSpike:
+		subject = fmt.Sprintf("Crash rate spike in %s", alert.App.Name)
+		page = "crashes"
+		fmt.Fprintf(&b, "Crash rate of %s is %.2f%%, up from a baseline of %.2f%%.\n", alert.App.Name, alert.Metric.Current, alert.Metric.Baseline)
+	case TypeAnrRateSpike:
+		subject = fmt.Sprintf("ANR rate spike in %s", alert.App.Name)
+		page = "anrs"
+		fmt.Fprintf(&b, "ANR rate of %s is %.2f%%, up from a baseline of %.2f%%.\n", alert.App.Name, alert.Metric.Current, alert.Metric.Baseline)
+	case TypeLaunchTimeSpike:
+		subject = fmt.Sprintf("Launch time spike in %s", alert.App.Name)
+		page = "overview"
+		fmt.Fprintf(&b, "Cold launch p95 of %s is %.2fms, up from a baseline of %.2fms.\n", alert.App.Name, alert.Metric.Current, alert.Metric.Baseline)
+	}
+
+	fmt.Fprintf(&b, "\nEvaluated window: %s to %s\n", alert.Window.From.Format(time.RFC3339), alert.Window.To.Format(time.RFC3339))
+	fmt.Fprintf(&b, "Sessions in window: %d\n", alert.Metric.Sessions)
+
+	if origin := server.Server.Config.SiteOrigin; origin != "" {
+		fmt.Fprintf(&b, "\nView in dashboard: %s/%s/%s\n", strings.TrimSuffix(origin, "/"), alert.App.TeamID, page)
+	}
+
+	b.WriteString("\nYou are receiving this email because alerts are enabled for this app. You can change your alert preferences from the dashboard.\n")
+
+	return email.Message{
+		To:      recipients,
+		Subject: subject,
+		Body:    b.String(),
+	}
+}
+
+// save persists the alert.
+func (a *Alert) save(ctx context.Context) (err error) {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return
+	}
+
+	a.ID = id
+
+	stmt := sqlf.PostgreSQL.
+		InsertInto("public.alerts").
+		Set("id", a.ID).
+		Set("app_id", a.App.ID).
+		Set("type", a.Type).
+		Set("current_value", a.Metric.Current).
+		Set("baseline_value", a.Metric.Baseline).
+		Set("delta", finite(a.Delta)).
+		Set("recipient_count", a.Notified).
+		Set("window_start", a.Window.From).
+		Set("window_end", a.Window.To)
+
+	defer stmt.Close()
+
+	_, err = server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+
+	return
+}
+
+// isDuplicate reports whether an alert of the same
+// type was already created for the app within the
+// cooldown period.
+func isDuplicate(ctx context.Context, alert Alert, cooldown time.Duration) (duplicate bool, err error) {
+	stmt := sqlf.PostgreSQL.
+		Select("count(*)").
+		From("public.alerts").
+		Where("app_id = ?", alert.App.ID).
+		Where("type = ?", alert.Type).
+		Where("created_at >= ?", alert.Window.To.Add(-cooldown))
+
+	defer stmt.Close()
+
+	var count int
+	if err = server.Server.PgPool.QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&count); err != nil {
+		return
+	}
+
+	duplicate = count > 0
+
+	return
+}
+
+// fetchApps fetches all onboarded apps.
+func fetchApps(ctx context.Context) (apps []App, err error) {
+	stmt := sqlf.PostgreSQL.
+		From("public.apps").
+		Select("id").
+		Select("team_id").
+		Select("coalesce(app_name, '')").
+		Where("onboarded = ?", true)
+
+	defer stmt.Close()
+
+	rows, err := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var app App
+		if err = rows.Scan(&app.ID, &app.TeamID, &app.Name); err != nil {
+			return
+		}
+		apps = append(apps, app)
+	}
+
+	err = rows.Err()
+
+	return
+}
+
+// fetchRecipients fetches email addresses of all team
+// members of the app who opted in for the alert type.
+func fetchRecipients(ctx context.Context, alert Alert) (recipients []string, err error) {
+	column, ok := prefColumns[alert.Type]
+	if !ok {
+		err = fmt.Errorf("unknown alert type %q", alert.Type)
+		return
+	}
+
+	stmt := sqlf.PostgreSQL.
+		From("public.alert_prefs ap").
+		Select("u.email").
+		Join("public.users u", "u.id = ap.user_id").
+		Join("public.team_membership tm", "tm.user_id = ap.user_id").
+		Where("ap.app_id = ?", alert.App.ID).
+		Where("tm.team_id = ?", alert.App.TeamID).
+		Where(fmt.Sprintf("ap.%s = ?", column), true)
+
+	defer stmt.Close()
+
+	rows, err := server.Server.PgPool.Query(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		return
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var recipient string
+		if err = rows.Scan(&recipient); err != nil {
+			return
+		}
+		recipients = append(recipients, recipient)
+	}
+
+	err = rows.Err()
+
+	return
+}
+
+// crashRate computes the percentage of sessions with
+// unhandled exceptions in the evaluated window and in
+// the baseline window.
+func crashRate(ctx context.Context, appId uuid.UUID, window Window) (metric Metric, err error) {
+	return sessionRate(ctx, appId, window, "`type` = 'exception' and `exception.handled` = false")
+}
+
+// anrRate computes the percentage of sessions with
+// ANRs in the evaluated window and in the baseline
+// window.
+func anrRate(ctx context.Context, appId uuid.UUID, window Window) (metric Metric, err error) {
+	return sessionRate(ctx, appId, window, "`type` = 'anr'")
+}
+
+// sessionRate computes the percentage of sessions matching
+// the condition in the evaluated window and in the baseline
+// window. Follows the same computation as crash free &
+// ANR free session metrics.
+func sessionRate(ctx context.Context, appId uuid.UUID, window Window, cond string) (metric Metric, err error) {
+	stmt := sqlf.
+		With("all_sessions",
+			sqlf.From("default.events").
+				Select("session_id, timestamp, type, exception.handled").
+				Where("app_id = ? and timestamp >= ? and timestamp <= ?", appId, window.BaselineFrom, window.To)).
+		With("t1",
+			sqlf.From("all_sessions").
+				Select("count(distinct session_id) as total_sessions_current").
+				Where("timestamp >= ?", window.From)).
+		With("t2",
+			sqlf.From("all_sessions").
+				Select("count(distinct session_id) as count_current").
+				Where(cond).
+				Where("timestamp >= ?", window.From)).
+		With("t3",
+			sqlf.From("all_sessions").
+				Select("count(distinct session_id) as total_sessions_baseline").
+				Where("timestamp < ?", window.From)).
+		With("t4",
+			sqlf.From("all_sessions").
+				Select("count(distinct session_id) as count_baseline").
+				Where(cond).
+				Where("timestamp < ?", window.From)).
+		Select("t1.total_sessions_current").
+		Select("round((t2.count_current / t1.total_sessions_current) * 100, 2) as rate_current").
+		Select("round((t4.count_baseline / t3.total_sessions_baseline) * 100, 2) as rate_baseline").
+		From("t1, t2, t3, t4")
+
+	defer stmt.Close()
+
+	err = server.Server.ChPool.QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&metric.Sessions, &metric.Current, &metric.Baseline)
+
+	return
+}
+
+// coldLaunchP95 computes the p95 of cold launch durations
+// in the evaluated window and in the baseline window.
+func coldLaunchP95(ctx context.Context, appId uuid.UUID, window Window) (metric Metric, err error) {
+	stmt := sqlf.
+		With("timings",
+			sqlf.From("default.events").
+				Select("session_id, timestamp, cold_launch.duration").
+				Where("app_id = ?", appId).
+				Where("timestamp >= ? and timestamp <= ?", window.BaselineFrom, window.To).
+				Where("type = 'cold_launch'").
+				Where("cold_launch.duration > 0 and cold_launch.duration <= ?", maxColdLaunchDuration)).
+		With("cold_current",
+			sqlf.From("timings").
+				Select("count(distinct session_id) as sessions").
+				Select("round(quantile(0.95)(cold_launch.duration), 2) as cold_launch").
+				Where("timestamp >= ?", window.From)).
+		With("cold_baseline",
+			sqlf.From("timings").
+				Select("round(quantile(0.95)(cold_launch.duration), 2) as cold_launch").
+				Where("timestamp < ?", window.From)).
+		Select("cold_current.sessions").
+		Select("cold_current.cold_launch").
+		Select("cold_baseline.cold_launch").
+		From("cold_current, cold_baseline")
+
+	defer stmt.Close()
+
+	err = server.Server.ChPool.QueryRow(ctx, stmt.String(), stmt.Args()...).Scan(&metric.Sessions, &metric.Current, &metric.Baseline)
+
+	return
+}
+
+// finite replaces infinite values with zero
+// as postgres accepts infinite doubles but
+// they are not meaningful for consumers.
+func finite(v float64) float64 {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		return 0
+	}
+	return v
+}
@@ -0,0 +1,34 @@
+package alerts
+
+import (
+	"math"
+	"testing"
+)
+
+func TestIsSpike(t *testing.T) {
+	cases := []struct {
+		name        string
+		metric      Metric
+		expected    bool
+		expectDelta float64
+	}{
+		{"below threshold", Metric{Current: 1.2, Baseline: 1, Sessions: 500}, false, 1.2},
+		{"at threshold", Metric{Current: 1.5, Baseline: 1, Sessions: 500}, true, 1.5},
+		{"above threshold", Metric{Current: 4, Baseline: 1, Sessions: 500}, true, 4},
+		{"too few sessions", Metric{Current: 4, Baseline: 1, Sessions: 10}, false, 0},
+		{"nan current", Metric{Current: math.NaN(), Baseline: 1, Sessions: 500}, false, 0},
+		{"nan baseline", Metric{Current: 2, Baseline: math.NaN(), Sessions: 500}, false, 0},
+		{"zero current", Metric{Current: 0, Baseline: 1, Sessions: 500}, false, 0},
+		{"zero baseline", Metric{Current: 2, Baseline: 0, Sessions: 500}, true, math.Inf(1)},
+	}
+
+	for _, c := range cases {
+		delta, spiked := IsSpike(c.metric, 1.5, 100)
+		if spiked != c.expected {
+			t.Errorf("%s: expected spike %v, but got %v", c.name, c.expected, spiked)
+		}
+		if delta != c.expectDelta {
+			t.Errorf("%s: expected delta %v, but got %v", c.name, c.expectDelta, delta)
+		}
+	}
+}
@@ -199,3 +199,25 @@ func deleteAttachments(ctx context.Context, staleData StaleData) (err error) {
 
 	return
 }
+
+// DeleteStaleUsageSessions deletes sessions counted towards
+// usage quotas of past months. Sessions are only counted
+// towards the current month, so older ones are not needed.
+func DeleteStaleUsageSessions(ctx context.Context) {
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	stmt := sqlf.PostgreSQL.
+		DeleteFrom("public.usage_sessions").
+		Where("month < ?", month)
+
+	defer stmt.Close()
+
+	tag, err := server.Server.PgPool.Exec(ctx, stmt.String(), stmt.Args()...)
+	if err != nil {
+		fmt.Printf("Failed to delete stale usage sessions: %v\n", err)
+		return
+	}
+
+	fmt.Printf("Deleted %v stale usage sessions\n", tag.RowsAffected())
+}
@@ -80,3 +80,7 @@ require (
 	google.golang.org/protobuf v1.34.2 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+require backend/email v0.0.0
+
+replace backend/email => ../email
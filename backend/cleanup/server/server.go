@@ -4,6 +4,8 @@ import (
 	"context"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
@@ -39,6 +41,43 @@ type ServerConfig struct {
 	AWSEndpoint                string
 	AttachmentOrigin           string
 	OtelServiceName            string
+	SiteOrigin                 string
+	SMTP                       SMTPConfig
+	Alert                      AlertConfig
+}
+
+type SMTPConfig struct {
+	/* hostname of the smtp server */
+	Host string
+
+	/* port of the smtp server */
+	Port string
+
+	/* username for smtp authentication */
+	Username string
+
+	/* password for smtp authentication */
+	Password string
+
+	/* sender email address */
+	From string
+}
+
+type AlertConfig struct {
+	/* duration of the recent window to evaluate */
+	Window time.Duration
+
+	/* duration of the baseline window preceding the evaluated window */
+	Baseline time.Duration
+
+	/* ratio over baseline at which a metric is considered a spike */
+	Threshold float64
+
+	/* minimum sessions in the evaluated window to consider alerting */
+	MinSessions uint64
+
+	/* duration during which an alert of the same type won't be resent */
+	Cooldown time.Duration
 }
 
 func NewConfig() *ServerConfig {
@@ -85,6 +124,45 @@ func NewConfig() *ServerConfig {
 
 	endpoint := os.Getenv("AWS_ENDPOINT_URL")
 
+	siteOrigin := os.Getenv("SITE_ORIGIN")
+	if siteOrigin == "" {
+		log.Println("SITE_ORIGIN env var not set, alert emails won't contain dashboard links")
+	}
+
+	smtpHost := os.Getenv("SMTP_HOST")
+	if smtpHost == "" {
+		log.Println("SMTP_HOST env var not set, alert emails won't work")
+	}
+
+	smtpFrom := os.Getenv("SMTP_FROM_EMAIL")
+	if smtpFrom == "" {
+		log.Println("SMTP_FROM_EMAIL env var not set, alert emails won't work")
+	}
+
+	alertWindow := parseDuration("ALERT_WINDOW", time.Hour)
+	alertBaseline := parseDuration("ALERT_BASELINE", 7*24*time.Hour)
+	alertCooldown := parseDuration("ALERT_COOLDOWN", 24*time.Hour)
+
+	alertThreshold := 1.5
+	if val := os.Getenv("ALERT_SPIKE_THRESHOLD"); val != "" {
+		threshold, err := strconv.ParseFloat(val, 64)
+		if err != nil || threshold <= 1 {
+			log.Printf("ALERT_SPIKE_THRESHOLD env var is invalid, using default of %v\n", alertThreshold)
+		} else {
+			alertThreshold = threshold
+		}
+	}
+
+	var alertMinSessions uint64 = 100
+	if val := os.Getenv("ALERT_MIN_SESSIONS"); val != "" {
+		minSessions, err := strconv.ParseUint(val, 10, 64)
+		if err != nil {
+			log.Printf("ALERT_MIN_SESSIONS env var is invalid, using default of %d\n", alertMinSessions)
+		} else {
+			alertMinSessions = minSessions
+		}
+	}
+
 	return &ServerConfig{
 		PG: PostgresConfig{
 			DSN: postgresDSN,
@@ -99,7 +177,40 @@ func NewConfig() *ServerConfig {
 		AWSEndpoint:                endpoint,
 		AttachmentOrigin:           attachmentOrigin,
 		OtelServiceName:            otelServiceName,
+		SiteOrigin:                 siteOrigin,
+		SMTP: SMTPConfig{
+			Host:     smtpHost,
+			Port:     os.Getenv("SMTP_PORT"),
+			Username: os.Getenv("SMTP_USER"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+			From:     smtpFrom,
+		},
+		Alert: AlertConfig{
+			Window:      alertWindow,
+			Baseline:    alertBaseline,
+			Threshold:   alertThreshold,
+			MinSessions: alertMinSessions,
+			Cooldown:    alertCooldown,
+		},
+	}
+}
+
+// parseDuration reads a duration from the env
+// var and falls back to the default if the env
+// var is not set or is invalid.
+func parseDuration(name string, fallback time.Duration) time.Duration {
+	val := os.Getenv(name)
+	if val == "" {
+		return fallback
 	}
+
+	d, err := time.ParseDuration(val)
+	if err != nil || d <= 0 {
+		log.Printf("%s env var is invalid, using default of %v\n", name, fallback)
+		return fallback
+	}
+
+	return d
 }
 
 func Init(config *ServerConfig) {
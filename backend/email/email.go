@@ -0,0 +1,187 @@
+// Package email sends plain text emails over SMTP.
+// Shared by the api & cleanup services.
+package email
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Message represents a plain text email
+// message.
+type Message struct {
+	// To is the list of recipient email
+	// addresses.
+	To []string
+
+	// Subject is the subject line of the
+	// email.
+	Subject string
+
+	// Body is the plain text body of the
+	// email.
+	Body string
+}
+
+// ErrInvalidHeader is returned when a message's
+// subject contains line breaks, which would
+// otherwise inject headers into the message.
+var ErrInvalidHeader = errors.New("email subject must not contain line breaks")
+
+// Sender describes the interface for
+// sending emails.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// SMTPSender sends emails using a SMTP
+// server.
+type SMTPSender struct {
+	opts *Options
+
+	// rootCAs is the set of root certificate
+	// authorities used to verify the SMTP
+	// server. System roots are used if nil.
+	rootCAs *x509.CertPool
+}
+
+// Options represents the configuration options
+// for configuring the SMTPSender.
+type Options struct {
+	// Host is the hostname of the SMTP server.
+	Host string
+
+	// Port is the port of the SMTP server.
+	Port string
+
+	// Username is the username used for
+	// authenticating with the SMTP server.
+	// Authentication is skipped if empty.
+	Username string
+
+	// Password is the password used for
+	// authenticating with the SMTP server.
+	Password string
+
+	// From is the sender's email address.
+	From string
+
+	// Timeout is the maximum time allowed
+	// for connecting to the SMTP server.
+	Timeout time.Duration
+}
+
+// NewSMTPSender creates a new instance of SMTPSender.
+func NewSMTPSender(opts *Options) (sender *SMTPSender, err error) {
+	if opts.Host == "" {
+		err = fmt.Errorf(`%q must not be empty`, `Host`)
+		return
+	}
+	if opts.From == "" {
+		err = fmt.Errorf(`%q must not be empty`, `From`)
+		return
+	}
+	if opts.Port == "" {
+		opts.Port = "587"
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	sender = &SMTPSender{
+		opts: opts,
+	}
+	return
+}
+
+// Send sends the message to all its recipients.
+func (s SMTPSender) Send(ctx context.Context, msg Message) (err error) {
+	if len(msg.To) < 1 {
+		return errors.New(`message must have at least one recipient`)
+	}
+
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return ErrInvalidHeader
+	}
+
+	addr := net.JoinHostPort(s.opts.Host, s.opts.Port)
+	dialer := &net.Dialer{Timeout: s.opts.Timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return
+	}
+
+	client, err := smtp.NewClient(conn, s.opts.Host)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		config := &tls.Config{
+			ServerName: s.opts.Host,
+			RootCAs:    s.rootCAs,
+		}
+		if err = client.StartTLS(config); err != nil {
+			return
+		}
+	}
+
+	if s.opts.Username != "" {
+		auth := smtp.PlainAuth("", s.opts.Username, s.opts.Password, s.opts.Host)
+		if err = client.Auth(auth); err != nil {
+			return
+		}
+	}
+
+	if err = client.Mail(s.opts.From); err != nil {
+		return
+	}
+
+	for _, to := range msg.To {
+		if err = client.Rcpt(to); err != nil {
+			return
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return
+	}
+
+	if _, err = w.Write(s.compose(msg)); err != nil {
+		return
+	}
+
+	if err = w.Close(); err != nil {
+		return
+	}
+
+	return client.Quit()
+}
+
+// compose builds the raw RFC 5322 message
+// along with its headers. Non ASCII subjects
+// are encoded as per RFC 2047.
+func (s SMTPSender) compose(msg Message) []byte {
+	var b strings.Builder
+
+	b.WriteString("From: " + s.opts.From + "\r\n")
+	b.WriteString("To: " + strings.Join(msg.To, ", ") + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().UTC().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+
+	return []byte(b.String())
+}
@@ -0,0 +1,322 @@
+package email
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// stubSMTP is a minimal SMTP server that
+// records the envelope and data of the
+// last received message. Offers STARTTLS
+// when configured with TLS.
+type stubSMTP struct {
+	ln     net.Listener
+	tls    *tls.Config
+	secure bool
+	auth   string
+	from   string
+	rcpt   []string
+	data   string
+	done   chan struct{}
+}
+
+func newStubSMTP(t *testing.T, config *tls.Config) *stubSMTP {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &stubSMTP{ln: ln, tls: config, done: make(chan struct{})}
+	go s.serve()
+	return s
+}
+
+// newTestCert provides a certificate valid for
+// 127.0.0.1 along with a pool trusting it.
+func newTestCert(t *testing.T) (tls.Certificate, *x509.CertPool) {
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	return srv.TLS.Certificates[0], pool
+}
+
+func (s *stubSMTP) serve() {
+	defer close(s.done)
+	conn, err := s.ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	write := func(line string) {
+		conn.Write([]byte(line + "\r\n"))
+	}
+
+	write("220 stub ready")
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		cmd := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+			if s.tls != nil && !s.secure {
+				write("250-stub")
+				write("250 STARTTLS")
+				continue
+			}
+			if s.secure {
+				write("250-stub")
+				write("250 AUTH PLAIN")
+				continue
+			}
+			write("250 stub")
+		case strings.HasPrefix(cmd, "AUTH PLAIN "):
+			decoded, err := base64.StdEncoding.DecodeString(line[len("AUTH PLAIN "):])
+			if err != nil {
+				write("501 bad credentials")
+				continue
+			}
+			s.auth = string(decoded)
+			write("235 ok")
+		case cmd == "STARTTLS":
+			write("220 ready")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			r = bufio.NewReader(conn)
+			s.secure = true
+		case strings.HasPrefix(cmd, "MAIL FROM:"):
+			s.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+			write("250 ok")
+		case strings.HasPrefix(cmd, "RCPT TO:"):
+			s.rcpt = append(s.rcpt, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			write("250 ok")
+		case cmd == "DATA":
+			write("354 go ahead")
+			var b strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				b.WriteString(l)
+			}
+			s.data = b.String()
+			write("250 ok")
+		case cmd == "QUIT":
+			write("221 bye")
+			return
+		default:
+			write("250 ok")
+		}
+	}
+}
+
+func TestSMTPSenderSend(t *testing.T) {
+	stub := newStubSMTP(t, nil)
+	defer stub.ln.Close()
+
+	host, port, _ := net.SplitHostPort(stub.ln.Addr().String())
+	sender, err := NewSMTPSender(&Options{
+		Host: host,
+		Port: port,
+		From: "noreply@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := Message{
+		To:      []string{"alice@example.com", "bob@example.com"},
+		Subject: "Verify your email",
+		Body:    "line one\nline two",
+	}
+
+	if err := sender.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	<-stub.done
+
+	if stub.from != "noreply@example.com" {
+		t.Errorf("Expected %q from, but got %q", "noreply@example.com", stub.from)
+	}
+
+	if len(stub.rcpt) != 2 {
+		t.Fatalf("Expected %d recipients, but got %d", 2, len(stub.rcpt))
+	}
+
+	if !strings.Contains(stub.data, "Subject: Verify your email\r\n") {
+		t.Errorf("Expected subject header in data, but got %q", stub.data)
+	}
+
+	if !strings.Contains(stub.data, "line one\r\nline two") {
+		t.Errorf("Expected body in data, but got %q", stub.data)
+	}
+}
+
+func TestSMTPSenderStartTLSAuth(t *testing.T) {
+	cert, pool := newTestCert(t)
+	stub := newStubSMTP(t, &tls.Config{Certificates: []tls.Certificate{cert}})
+	defer stub.ln.Close()
+
+	host, port, _ := net.SplitHostPort(stub.ln.Addr().String())
+	sender, err := NewSMTPSender(&Options{
+		Host:     host,
+		Port:     port,
+		Username: "measure",
+		Password: "secret",
+		From:     "noreply@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender.rootCAs = pool
+
+	msg := Message{
+		To:      []string{"alice@example.com"},
+		Subject: "Sign in to Measure",
+		Body:    "https://measure.example.com/auth/magic?token=abc",
+	}
+
+	if err := sender.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	<-stub.done
+
+	if !stub.secure {
+		t.Error("Expected message to be sent after STARTTLS, but it was sent in plain text")
+	}
+
+	if stub.auth != "\x00measure\x00secret" {
+		t.Errorf("Expected %q credentials, but got %q", "\x00measure\x00secret", stub.auth)
+	}
+
+	if !strings.Contains(stub.data, "token=abc") {
+		t.Errorf("Expected magic link in data, but got %q", stub.data)
+	}
+}
+
+func TestSMTPSenderStartTLSUntrusted(t *testing.T) {
+	cert, _ := newTestCert(t)
+	stub := newStubSMTP(t, &tls.Config{Certificates: []tls.Certificate{cert}})
+	defer stub.ln.Close()
+
+	host, port, _ := net.SplitHostPort(stub.ln.Addr().String())
+	sender, err := NewSMTPSender(&Options{
+		Host:     host,
+		Port:     port,
+		Username: "measure",
+		Password: "secret",
+		From:     "noreply@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := Message{
+		To:      []string{"alice@example.com"},
+		Subject: "Reset your password",
+		Body:    "https://measure.example.com/auth/reset?token=abc",
+	}
+
+	if err := sender.Send(context.Background(), msg); err == nil {
+		t.Error("Expected error for untrusted certificate, but got nil")
+	}
+
+	<-stub.done
+
+	if stub.auth != "" || stub.data != "" {
+		t.Error("Expected no credentials or data to be sent to an untrusted server")
+	}
+}
+
+func TestSMTPSenderNoRecipients(t *testing.T) {
+	sender, err := NewSMTPSender(&Options{
+		Host: "127.0.0.1",
+		From: "noreply@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sender.Send(context.Background(), Message{}); err == nil {
+		t.Error("Expected error for message without recipients, but got nil")
+	}
+}
+
+func TestNewSMTPSenderValidation(t *testing.T) {
+	if _, err := NewSMTPSender(&Options{From: "noreply@example.com"}); err == nil {
+		t.Error("Expected error for empty host, but got nil")
+	}
+
+	if _, err := NewSMTPSender(&Options{Host: "127.0.0.1"}); err == nil {
+		t.Error("Expected error for empty from, but got nil")
+	}
+}
+
+func TestSMTPSenderHeaderInjection(t *testing.T) {
+	sender, err := NewSMTPSender(&Options{
+		Host: "127.0.0.1",
+		From: "noreply@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, subject := range []string{
+		"You're invited to join x\r\nBcc: victim@example.com on Measure",
+		"Crash rate spike in x\nBcc: victim@example.com",
+		"Crash rate spike in x\r",
+	} {
+		msg := Message{
+			To:      []string{"alice@example.com"},
+			Subject: subject,
+		}
+
+		if err := sender.Send(context.Background(), msg); !errors.Is(err, ErrInvalidHeader) {
+			t.Errorf("Expected %v for %q, but got %v", ErrInvalidHeader, subject, err)
+		}
+	}
+}
+
+func TestSMTPSenderComposeSubject(t *testing.T) {
+	sender, err := NewSMTPSender(&Options{
+		Host: "127.0.0.1",
+		From: "noreply@example.com",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := string(sender.compose(Message{
+		To:      []string{"alice@example.com"},
+		Subject: "Crash rate spike in Café",
+	}))
+
+	expected := "Subject: =?utf-8?q?Crash_rate_spike_in_Caf=C3=A9?=\r\n"
+	if !strings.Contains(data, expected) {
+		t.Errorf("Expected %q in data, but got %q", expected, data)
+	}
+}
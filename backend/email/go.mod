@@ -0,0 +1,3 @@
+module backend/email
+
+go 1.22
@@ -0,0 +1,51 @@
+import { decodeJWT } from '@/app/utils/auth/auth'
+import { NextResponse } from 'next/server'
+
+export const dynamic = 'force-dynamic'
+
+const origin = process?.env?.NEXT_PUBLIC_SITE_URL
+const apiOrigin = process?.env?.API_BASE_URL
+
+const paths: { [type: string]: string } = {
+  verify: "/auth/password/verify",
+  magic: "/auth/magic/verify",
+}
+
+export async function GET(request: Request) {
+  const { searchParams } = new URL(request.url)
+  const type = searchParams.get("type") ?? ""
+  const token = searchParams.get("token")
+  const errRedirectUrl = `${origin}/auth/login?error=This link is invalid or has expired`
+  const path = paths[type]
+
+  if (!path) {
+    console.log(`email login failure: unknown type ${type}`)
+    return NextResponse.redirect(errRedirectUrl, { status: 302 })
+  }
+
+  if (!token) {
+    console.log("email login failure: no token")
+    return NextResponse.redirect(errRedirectUrl, { status: 302 })
+  }
+
+  const res = await fetch(`${apiOrigin}${path}`, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json'
+    },
+    body: JSON.stringify({ token })
+  });
+
+  if (!res.ok) {
+    console.log(`email login failure: post ${path} returned ${res.status}`)
+    return NextResponse.redirect(errRedirectUrl, { status: 302 });
+  }
+
+  const session = await res.json();
+  const { payload } = decodeJWT(session.access_token);
+
+  const redirectURL = new URL(`${origin}/${payload["team"]}/overview`);
+  redirectURL.hash = `access_token=${session.access_token}&refresh_token=${session.refresh_token}`;
+
+  return NextResponse.redirect(redirectURL, { status: 302 });
+}
@@ -0,0 +1,43 @@
+'use client';
+
+import { useState } from "react";
+import Link from "next/link";
+import { createMeasureClient } from "@/app/utils/auth/measure-client";
+
+export default function Invite({ searchParams }: { searchParams: { [key: string]: string | string[] | undefined } }) {
+  const token = typeof searchParams["token"] === "string" ? searchParams["token"] : ""
+  const [loading, setLoading] = useState(false)
+  const [declined, setDeclined] = useState(false)
+  const [error, setError] = useState("")
+
+  const decline = async () => {
+    setError("")
+    setLoading(true)
+    const client = createMeasureClient(process.env.NEXT_PUBLIC_API_BASE_URL)
+    const { error } = await client.declineInvite(token)
+    setLoading(false)
+
+    if (error) {
+      setError(error.message)
+      return
+    }
+
+    setDeclined(true)
+  }
+
+  return (
+    <div className="min-h-screen flex flex-col items-center justify-center px-4 sm:px-6 lg:px-8">
+      <div className="w-full space-y-6" style={{ width: "400px" }}>
+        <p className="font-display text-xl text-center">You&apos;re invited to join a team on Measure</p>
+        {!token && <p className="text-center font-display text-red-600">This link is invalid or has expired</p>}
+        {token && !declined && <>
+          <p className="text-center font-body">Sign in with the email this invite was sent to and you will join the team right away.</p>
+          <Link href="/auth/login" className="flex justify-center hover:bg-yellow-200 active:bg-yellow-300 focus-visible:bg-yellow-200 border border-black rounded-md font-display text-black transition-colors duration-100 py-2 px-4 w-full">Sign in to accept</Link>
+          <button type="button" onClick={decline} className="justify-center hover:bg-yellow-200 active:bg-yellow-300 focus-visible:bg-yellow-200 border border-black rounded-md font-display text-black transition-colors duration-100 py-2 px-4 w-full disabled:opacity-50" disabled={loading}>Decline invite</button>
+        </>}
+        {declined && <p className="text-center font-display">The invite was declined</p>}
+        {error && <p className="text-center font-display text-red-600">{error}</p>}
+      </div>
+    </div>
+  )
+}
@@ -0,0 +1,21 @@
+"use client";
+
+import { createMeasureClient } from "@/app/utils/auth/measure-client";
+
+async function doOIDCLogin() {
+  const client = createMeasureClient(process.env.NEXT_PUBLIC_API_BASE_URL)
+  const { url, error } = await client.oidcSignin('');
+
+  if (error) {
+    console.error(`failed to login using single sign on`, error)
+    return
+  }
+
+  if (url) {
+    window.location.assign(url);
+  }
+}
+
+export default function OIDCSignIn() {
+  return <button className="justify-center hover:bg-yellow-200 active:bg-yellow-300 focus-visible:bg-yellow-200 border border-black rounded-md font-display text-black transition-colors duration-100 py-2 px-4 w-full" onClick={() => doOIDCLogin()}><span>Sign in with SSO</span></button>
+}
@@ -6,6 +6,8 @@ import { useState, useEffect } from "react";
 import Messages from "./messages"
 import GoogleSignIn from "./google-sign-in"
 import GitHubSignIn from "./github-sign-in"
+import OIDCSignIn from "./oidc-sign-in"
+import PasswordSignIn from "./password-sign-in"
 import { auth, logout, getSession, decodeJWT } from "@/app/utils/auth/auth"
 import Script from "next/script"
 
@@ -51,6 +53,12 @@ export default function Login({ searchParams }: { searchParams: { [key: string]:
       <div className="my-6 place-content-end" style={{ width: "400px" }}>
         {!loggedIn && initial && <GitHubSignIn />}
       </div>
+      {process.env.NEXT_PUBLIC_OIDC_ENABLED === "true" && <div className="mb-6 place-content-end" style={{ width: "400px" }}>
+        {!loggedIn && initial && <OIDCSignIn />}
+      </div>}
+      {process.env.NEXT_PUBLIC_PASSWORD_ENABLED === "true" && <div className="mb-6 place-content-end" style={{ width: "400px" }}>
+        {!loggedIn && initial && <PasswordSignIn magicLink={process.env.NEXT_PUBLIC_MAGIC_LINK_ENABLED === "true"} />}
+      </div>}
       <Messages />
     </div>
   )
@@ -0,0 +1,89 @@
+"use client";
+
+import { useState } from "react";
+import { createMeasureClient } from "@/app/utils/auth/measure-client";
+import { redirectWithSession } from "./session-redirect";
+
+type Mode = "signin" | "signup" | "forgot" | "magic"
+
+const inputClassName = "w-full border border-black rounded-md font-body py-2 px-4 mb-3"
+const buttonClassName = "justify-center hover:bg-yellow-200 active:bg-yellow-300 focus-visible:bg-yellow-200 border border-black rounded-md font-display text-black transition-colors duration-100 py-2 px-4 w-full disabled:opacity-50"
+const linkClassName = "underline text-blue-500 hover:text-blue-700 font-display text-sm"
+
+const submitLabels: { [mode in Mode]: string } = {
+  signin: "Sign in with email",
+  signup: "Create account",
+  forgot: "Send reset link",
+  magic: "Email me a sign in link",
+}
+
+export default function PasswordSignIn({ magicLink }: { magicLink: boolean }) {
+  const [mode, setMode] = useState<Mode>("signin")
+  const [name, setName] = useState("")
+  const [email, setEmail] = useState("")
+  const [password, setPassword] = useState("")
+  const [loading, setLoading] = useState(false)
+  const [error, setError] = useState("")
+  const [message, setMessage] = useState("")
+
+  const switchMode = (next: Mode) => {
+    setMode(next)
+    setError("")
+    setMessage("")
+  }
+
+  const submit = async (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault()
+    setLoading(true)
+    setError("")
+    setMessage("")
+
+    const client = createMeasureClient(process.env.NEXT_PUBLIC_API_BASE_URL)
+    let result
+    switch (mode) {
+      case "signin":
+        result = await client.passwordSignin(email, password)
+        break
+      case "signup":
+        result = await client.passwordSignup(name, email, password)
+        break
+      case "forgot":
+        result = await client.forgotPassword(email)
+        break
+      case "magic":
+        result = await client.sendMagicLink(email)
+        break
+    }
+
+    setLoading(false)
+
+    if (result.error) {
+      setError(result.error.message)
+      return
+    }
+
+    if (result.session) {
+      redirectWithSession(result.session)
+      return
+    }
+
+    setMessage(result.message ?? "")
+  }
+
+  return (
+    <form onSubmit={submit}>
+      {mode === "signup" && <input className={inputClassName} type="text" placeholder="Name" autoComplete="name" required value={name} onChange={(e) => setName(e.target.value)} />}
+      <input className={inputClassName} type="email" placeholder="Email" autoComplete="email" required value={email} onChange={(e) => setEmail(e.target.value)} />
+      {(mode === "signin" || mode === "signup") && <input className={inputClassName} type="password" placeholder="Password" autoComplete={mode === "signup" ? "new-password" : "current-password"} minLength={8} required value={password} onChange={(e) => setPassword(e.target.value)} />}
+      <button type="submit" className={buttonClassName} disabled={loading}>{submitLabels[mode]}</button>
+      <div className="flex flex-wrap justify-between gap-2 mt-3">
+        {mode !== "signin" && <button type="button" className={linkClassName} onClick={() => switchMode("signin")}>Sign in with password</button>}
+        {mode !== "signup" && <button type="button" className={linkClassName} onClick={() => switchMode("signup")}>Create an account</button>}
+        {mode === "signin" && <button type="button" className={linkClassName} onClick={() => switchMode("forgot")}>Forgot password?</button>}
+        {magicLink && mode !== "magic" && <button type="button" className={linkClassName} onClick={() => switchMode("magic")}>Email me a link</button>}
+      </div>
+      {error && <p className="mt-4 text-center font-display text-red-600">{error}</p>}
+      {message && <p className="mt-4 text-center font-sans">{message}</p>}
+    </form>
+  )
+}
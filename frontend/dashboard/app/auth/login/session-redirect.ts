@@ -0,0 +1,14 @@
+import { decodeJWT } from "@/app/utils/auth/auth";
+
+/**
+ * Redirect to the user's team overview, handing over
+ * the session the same way sign in callbacks do.
+ *
+ * @param session access & refresh token pair
+ */
+export function redirectWithSession(session: { access_token: string, refresh_token: string }) {
+  const { payload } = decodeJWT(session.access_token)
+  const url = new URL(`/${payload["team"]}/overview`, window.location.origin)
+  url.hash = `access_token=${session.access_token}&refresh_token=${session.refresh_token}`
+  window.location.assign(url)
+}
@@ -0,0 +1,56 @@
+'use client';
+
+import { useState } from "react";
+import Link from "next/link";
+import { createMeasureClient } from "@/app/utils/auth/measure-client";
+import { redirectWithSession } from "../login/session-redirect";
+
+export default function ResetPassword({ searchParams }: { searchParams: { [key: string]: string | string[] | undefined } }) {
+  const token = typeof searchParams["token"] === "string" ? searchParams["token"] : ""
+  const [password, setPassword] = useState("")
+  const [confirm, setConfirm] = useState("")
+  const [loading, setLoading] = useState(false)
+  const [error, setError] = useState("")
+
+  const submit = async (e: React.FormEvent<HTMLFormElement>) => {
+    e.preventDefault()
+    setError("")
+
+    if (password !== confirm) {
+      setError("Passwords do not match")
+      return
+    }
+
+    setLoading(true)
+    const client = createMeasureClient(process.env.NEXT_PUBLIC_API_BASE_URL)
+    const { session, error } = await client.resetPassword(token, password)
+    setLoading(false)
+
+    if (error) {
+      setError(error.message)
+      return
+    }
+
+    if (session) {
+      redirectWithSession(session)
+    }
+  }
+
+  return (
+    <div className="min-h-screen flex flex-col items-center justify-center px-4 sm:px-6 lg:px-8">
+      <div className="w-full space-y-6" style={{ width: "400px" }}>
+        <p className="font-display text-xl text-center">Set a new password</p>
+        {!token && <p className="text-center font-display text-red-600">This link is invalid or has expired</p>}
+        {token && <form onSubmit={submit}>
+          <input className="w-full border border-black rounded-md font-body py-2 px-4 mb-3" type="password" placeholder="New password" autoComplete="new-password" minLength={8} required value={password} onChange={(e) => setPassword(e.target.value)} />
+          <input className="w-full border border-black rounded-md font-body py-2 px-4 mb-3" type="password" placeholder="Confirm new password" autoComplete="new-password" minLength={8} required value={confirm} onChange={(e) => setConfirm(e.target.value)} />
+          <button type="submit" className="justify-center hover:bg-yellow-200 active:bg-yellow-300 focus-visible:bg-yellow-200 border border-black rounded-md font-display text-black transition-colors duration-100 py-2 px-4 w-full disabled:opacity-50" disabled={loading}>Set password</button>
+        </form>}
+        {error && <p className="text-center font-display text-red-600">{error}</p>}
+        <div className="text-center">
+          <Link href="/auth/login" className="underline text-blue-500 hover:text-blue-700">Go back to login</Link>
+        </div>
+      </div>
+    </div>
+  )
+}
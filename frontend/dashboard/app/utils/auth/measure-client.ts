@@ -66,6 +66,61 @@ class MeasureClient {
     return { url, error };
   }
 
+  async oidcSignin(next: URL | string): Promise<{ url?: URL, error: Error | undefined }> {
+    const state = encodeOAuthState(next);
+
+    const path = '/auth/oidc';
+    const body = JSON.stringify({ type: "init", state });
+    const res = await this.#request(path, 'POST', { body });
+    const json = await res.json();
+
+    if (!res.ok) {
+      return { error: new Error(`${res.status}:${json?.error}`) };
+    }
+
+    return { url: new URL(json.url), error: undefined };
+  }
+
+  async #credentials(path: string, payload: Record<string, string>): Promise<{ session?: { access_token: string, refresh_token: string }, message?: string, error?: Error }> {
+    const body = JSON.stringify(payload);
+    const res = await this.#request(path, 'POST', { body });
+    const json = await res.json();
+
+    if (!res.ok) {
+      return { error: new Error(json?.details ?? json?.error ?? `request failed with ${res.status}`) };
+    }
+
+    if (json?.access_token) {
+      return { session: json };
+    }
+
+    return { message: json?.ok };
+  }
+
+  passwordSignup(name: string, email: string, password: string) {
+    return this.#credentials('/auth/password/signup', { name, email, password });
+  }
+
+  passwordSignin(email: string, password: string) {
+    return this.#credentials('/auth/password/signin', { email, password });
+  }
+
+  forgotPassword(email: string) {
+    return this.#credentials('/auth/password/forgot', { email });
+  }
+
+  resetPassword(token: string, password: string) {
+    return this.#credentials('/auth/password/reset', { token, password });
+  }
+
+  sendMagicLink(email: string) {
+    return this.#credentials('/auth/magic', { email });
+  }
+
+  declineInvite(token: string) {
+    return this.#credentials('/auth/invites/decline', { token });
+  }
+
   signout(refreshToken: string) {
     return this.#request("/auth/signout", "DELETE", {
       headers: new Headers({
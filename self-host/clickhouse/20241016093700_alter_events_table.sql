@@ -0,0 +1,23 @@
+-- migrate:up
+alter table events
+add column if not exists `native_crash.signal` LowCardinality(FixedString(32)) after `exception.foreground`, comment column `native_crash.signal` 'name of the signal that terminated the process',
+add column if not exists `native_crash.signal_code` LowCardinality(FixedString(32)) after `native_crash.signal`, comment column `native_crash.signal_code` 'name of the code of the signal',
+add column if not exists `native_crash.fault_address` String after `native_crash.signal_code`, comment column `native_crash.fault_address` 'memory address in hex that caused the fault',
+add column if not exists `native_crash.abort_message` String after `native_crash.fault_address`, comment column `native_crash.abort_message` 'message set by the app before aborting',
+add column if not exists `native_crash.fingerprint` FixedString(32) after `native_crash.abort_message`, comment column `native_crash.fingerprint` 'fingerprint for native crash similarity classification',
+add column if not exists `native_crash.frames` String after `native_crash.fingerprint`, comment column `native_crash.frames` 'frames of the crashing thread',
+add column if not exists `native_crash.threads` String after `native_crash.frames`, comment column `native_crash.threads` 'native crash thread data',
+add column if not exists `native_crash.registers` Map(String, String) after `native_crash.threads`, comment column `native_crash.registers` 'register values of the crashing thread',
+add column if not exists `native_crash.foreground` Bool after `native_crash.registers`, comment column `native_crash.foreground` 'true if the native crash was perceived by end user';
+
+-- migrate:down
+alter table events
+drop column if exists `native_crash.signal`,
+drop column if exists `native_crash.signal_code`,
+drop column if exists `native_crash.fault_address`,
+drop column if exists `native_crash.abort_message`,
+drop column if exists `native_crash.fingerprint`,
+drop column if exists `native_crash.frames`,
+drop column if exists `native_crash.threads`,
+drop column if exists `native_crash.registers`,
+drop column if exists `native_crash.foreground`;
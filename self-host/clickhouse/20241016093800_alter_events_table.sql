@@ -0,0 +1,9 @@
+-- migrate:up
+alter table events
+add column if not exists `anr.symbolicated` Bool default true after `anr.foreground`, comment column `anr.symbolicated` 'true if the anr was symbolicated or did not need symbolication, existing anrs are assumed symbolicated',
+add column if not exists `exception.symbolicated` Bool default true after `exception.foreground`, comment column `exception.symbolicated` 'true if the exception was symbolicated or did not need symbolication, existing exceptions are assumed symbolicated';
+
+-- migrate:down
+alter table events
+drop column if exists `anr.symbolicated`,
+drop column if exists `exception.symbolicated`;
@@ -0,0 +1,27 @@
+-- migrate:up
+alter table events
+add column if not exists `span.trace_id` FixedString(32) after `navigation.source`, comment column `span.trace_id` 'hex encoded trace id',
+add column if not exists `span.span_id` FixedString(16) after `span.trace_id`, comment column `span.span_id` 'hex encoded span id',
+add column if not exists `span.parent_id` String after `span.span_id`, comment column `span.parent_id` 'hex encoded span id of the parent span, empty for root spans',
+add column if not exists `span.name` LowCardinality(String) after `span.parent_id`, comment column `span.name` 'name of the span',
+add column if not exists `span.kind` LowCardinality(FixedString(16)) after `span.name`, comment column `span.kind` 'kind of the span, like internal, client or server',
+add column if not exists `span.status` UInt8 after `span.kind`, comment column `span.status` 'status of the span, either - 0 (unset), 1 (ok), 2 (error)',
+add column if not exists `span.status_message` String after `span.status`, comment column `span.status_message` 'description of the span status',
+add column if not exists `span.start_time` DateTime64(9, 'UTC') after `span.status_message`, comment column `span.start_time` 'span start timestamp',
+add column if not exists `span.end_time` DateTime64(9, 'UTC') after `span.start_time`, comment column `span.end_time` 'span end timestamp',
+add column if not exists `span.duration` UInt64 after `span.end_time`, comment column `span.duration` 'computed span duration, in msec',
+add column if not exists `span_attributes` Map(String, String) after `span.duration`, comment column `span_attributes` 'span attributes as received, stringified';
+
+-- migrate:down
+alter table events
+drop column if exists `span.trace_id`,
+drop column if exists `span.span_id`,
+drop column if exists `span.parent_id`,
+drop column if exists `span.name`,
+drop column if exists `span.kind`,
+drop column if exists `span.status`,
+drop column if exists `span.status_message`,
+drop column if exists `span.start_time`,
+drop column if exists `span.end_time`,
+drop column if exists `span.duration`,
+drop column if exists `span_attributes`;
@@ -0,0 +1,13 @@
+-- migrate:up
+alter table events
+add column if not exists `custom.name` LowCardinality(String) after `span_attributes`, comment column `custom.name` 'name of the custom event',
+add column if not exists `custom_string_properties` Map(String, String) after `custom.name`, comment column `custom_string_properties` 'custom event properties having string values',
+add column if not exists `custom_number_properties` Map(String, Float64) after `custom_string_properties`, comment column `custom_number_properties` 'custom event properties having number values',
+add column if not exists `custom_bool_properties` Map(String, Bool) after `custom_number_properties`, comment column `custom_bool_properties` 'custom event properties having bool values';
+
+-- migrate:down
+alter table events
+drop column if exists `custom.name`,
+drop column if exists `custom_string_properties`,
+drop column if exists `custom_number_properties`,
+drop column if exists `custom_bool_properties`;
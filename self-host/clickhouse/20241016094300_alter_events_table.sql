@@ -0,0 +1,7 @@
+-- migrate:up
+alter table events
+add column if not exists `user_defined_attribute` Map(LowCardinality(String), String) after `attribute.network_provider`, comment column `user_defined_attribute` 'user defined attributes as key/value pairs';
+
+-- migrate:down
+alter table events
+drop column if exists `user_defined_attribute`;
@@ -40,16 +40,28 @@ CREATE TABLE default.events
     `attribute.network_type` LowCardinality(FixedString(16)) COMMENT 'either - wifi, cellular, vpn, unknown, no_network',
     `attribute.network_generation` LowCardinality(FixedString(8)) COMMENT 'either - 2g, 3g, 4g, 5g, unknown',
     `attribute.network_provider` FixedString(64) COMMENT 'name of the network service provider',
+    `user_defined_attribute` Map(LowCardinality(String), String) COMMENT 'user defined attributes as key/value pairs',
     `anr.handled` Bool COMMENT 'anr was handled by the application code',
     `anr.fingerprint` FixedString(32) COMMENT 'fingerprint for anr similarity classification',
     `anr.exceptions` String COMMENT 'anr exception data',
     `anr.threads` String COMMENT 'anr thread data',
     `anr.foreground` Bool COMMENT 'true if the anr was perceived by end user',
+    `anr.symbolicated` Bool DEFAULT true COMMENT 'true if the anr was symbolicated or did not need symbolication, existing anrs are assumed symbolicated',
     `exception.handled` Bool COMMENT 'exception was handled by application code',
     `exception.fingerprint` FixedString(32) COMMENT 'fingerprint for exception similarity classification',
     `exception.exceptions` String COMMENT 'exception data',
     `exception.threads` String COMMENT 'exception thread data',
     `exception.foreground` Bool COMMENT 'true if the exception was perceived by end user',
+    `exception.symbolicated` Bool DEFAULT true COMMENT 'true if the exception was symbolicated or did not need symbolication, existing exceptions are assumed symbolicated',
+    `native_crash.signal` LowCardinality(FixedString(32)) COMMENT 'name of the signal that terminated the process',
+    `native_crash.signal_code` LowCardinality(FixedString(32)) COMMENT 'name of the code of the signal',
+    `native_crash.fault_address` String COMMENT 'memory address in hex that caused the fault',
+    `native_crash.abort_message` String COMMENT 'message set by the app before aborting',
+    `native_crash.fingerprint` FixedString(32) COMMENT 'fingerprint for native crash similarity classification',
+    `native_crash.frames` String COMMENT 'frames of the crashing thread',
+    `native_crash.threads` String COMMENT 'native crash thread data',
+    `native_crash.registers` Map(String, String) COMMENT 'register values of the crashing thread',
+    `native_crash.foreground` Bool COMMENT 'true if the native crash was perceived by end user',
     `app_exit.reason` LowCardinality(FixedString(64)) COMMENT 'reason for app exit',
     `app_exit.importance` LowCardinality(FixedString(32)) COMMENT 'importance of process that it used to have before death',
     `app_exit.trace` String COMMENT 'modified trace given by ApplicationExitInfo to help debug anrs.',
@@ -157,6 +169,21 @@ CREATE TABLE default.events
     `navigation.to` FixedString(128) COMMENT 'destination page or screen where the navigation led to',
     `navigation.from` FixedString(128) COMMENT 'source page or screen from where the navigation was triggered',
     `navigation.source` FixedString(128) COMMENT 'how the event was collected example a library or framework name',
+    `span.trace_id` FixedString(32) COMMENT 'hex encoded trace id',
+    `span.span_id` FixedString(16) COMMENT 'hex encoded span id',
+    `span.parent_id` String COMMENT 'hex encoded span id of the parent span, empty for root spans',
+    `span.name` LowCardinality(String) COMMENT 'name of the span',
+    `span.kind` LowCardinality(FixedString(16)) COMMENT 'kind of the span, like internal, client or server',
+    `span.status` UInt8 COMMENT 'status of the span, either - 0 (unset), 1 (ok), 2 (error)',
+    `span.status_message` String COMMENT 'description of the span status',
+    `span.start_time` DateTime64(9, 'UTC') COMMENT 'span start timestamp',
+    `span.end_time` DateTime64(9, 'UTC') COMMENT 'span end timestamp',
+    `span.duration` UInt64 COMMENT 'computed span duration, in msec',
+    `span_attributes` Map(String, String) COMMENT 'span attributes as received, stringified',
+    `custom.name` LowCardinality(String) COMMENT 'name of the custom event',
+    `custom_string_properties` Map(String, String) COMMENT 'custom event properties having string values',
+    `custom_number_properties` Map(String, Float64) COMMENT 'custom event properties having number values',
+    `custom_bool_properties` Map(String, Bool) COMMENT 'custom event properties having bool values',
     `attachments` String COMMENT 'attachment metadata'
 )
 ENGINE = MergeTree
@@ -182,4 +209,9 @@ SETTINGS index_granularity = 8192;
 --
 
 INSERT INTO schema_migrations (version) VALUES
-    ('20231117020810');
+    ('20231117020810'),
+    ('20241016093700'),
+    ('20241016093800'),
+    ('20241016093900'),
+    ('20241016094200'),
+    ('20241016094300');
@@ -204,6 +204,11 @@ ATTACHMENTS_SECRET_ACCESS_KEY=minio123
 
 SYMBOLICATOR_ORIGIN=http://symbolicator-android:8181
 
+# Set to "builtin" to symbolicate proguard
+# mappings without the symbolicator service
+PROGUARD_SYMBOLICATOR=service
+PROGUARD_CACHE_SIZE=16
+
 NEXT_PUBLIC_SITE_URL=http://localhost:3000
 NEXT_PUBLIC_API_BASE_URL=http://localhost:8080
 API_BASE_URL=http://api:8080
@@ -215,9 +220,48 @@ API_BASE_URL=http://api:8080
 OAUTH_GOOGLE_KEY=$OAUTH_GOOGLE_KEY
 OAUTH_GITHUB_KEY=$OAUTH_GITHUB_KEY
 OAUTH_GITHUB_SECRET=$OAUTH_GITHUB_SECRET
+
+# Generic OpenID Connect sign in. Leave
+# OIDC_DISCOVERY_URL empty to disable.
+OIDC_DISCOVERY_URL=
+OIDC_CLIENT_ID=
+OIDC_CLIENT_SECRET=
+OIDC_SCOPES="openid email profile"
+OIDC_EMAIL_CLAIM=email
+OIDC_NAME_CLAIM=name
+
+# Set to true only if the provider omits the
+# email_verified claim & verifies all emails
+OIDC_TRUST_EMAIL=false
+
+# Users signing in via OIDC with an email in
+# one of these comma separated domains join
+# the team with the role below
+OIDC_AUTO_JOIN_DOMAINS=
+OIDC_AUTO_JOIN_TEAM_ID=
+OIDC_AUTO_JOIN_ROLE=viewer
+
+# Email & password sign in, for installs that
+# can't reach GitHub or Google. Email verification,
+# password reset & magic links need SMTP below.
+AUTH_PASSWORD_ENABLED=false
+AUTH_MAGIC_LINK_ENABLED=false
+
 SESSION_ACCESS_SECRET=super-secret-for-jwt-token-with-at-least-32-characters
 SESSION_REFRESH_SECRET=super-secret-for-jwt-token-with-at-least-32-characters
 
+#########
+# Email #
+#########
+
+# Alert, verification, password reset & magic
+# link emails won't work without these
+SMTP_HOST=
+SMTP_PORT=587
+SMTP_USER=
+SMTP_PASSWORD=
+SMTP_FROM_EMAIL=
+
 ########
 # OTEL #
 ########
@@ -252,6 +296,15 @@ NEXT_PUBLIC_SITE_URL=http://localhost:3000
 NEXT_PUBLIC_OAUTH_GOOGLE_KEY=$OAUTH_GOOGLE_KEY
 NEXT_PUBLIC_OAUTH_GITHUB_KEY=$OAUTH_GITHUB_KEY
 
+# Set to "true" once OIDC_* variables are
+# configured in the api's environment
+NEXT_PUBLIC_OIDC_ENABLED=false
+
+# Set to "true" to match AUTH_PASSWORD_ENABLED
+# & AUTH_MAGIC_LINK_ENABLED of the api
+NEXT_PUBLIC_PASSWORD_ENABLED=false
+NEXT_PUBLIC_MAGIC_LINK_ENABLED=false
+
 ###############
 # MEASURE API #
 ###############
@@ -318,6 +371,11 @@ ATTACHMENTS_SECRET_ACCESS_KEY=$ATTACHMENTS_SECRET_ACCESS_KEY
 
 SYMBOLICATOR_ORIGIN=http://symbolicator-android:8181
 
+# Set to "builtin" to symbolicate proguard
+# mappings without the symbolicator service
+PROGUARD_SYMBOLICATOR=service
+PROGUARD_CACHE_SIZE=16
+
 NEXT_PUBLIC_SITE_URL=$NEXT_PUBLIC_SITE_URL
 NEXT_PUBLIC_API_BASE_URL=$NEXT_PUBLIC_API_BASE_URL
 API_BASE_URL=http://api:8080
@@ -329,9 +387,48 @@ API_BASE_URL=http://api:8080
 OAUTH_GOOGLE_KEY=$OAUTH_GOOGLE_KEY
 OAUTH_GITHUB_KEY=$OAUTH_GITHUB_KEY
 OAUTH_GITHUB_SECRET=$OAUTH_GITHUB_SECRET
+
+# Generic OpenID Connect sign in. Leave
+# OIDC_DISCOVERY_URL empty to disable.
+OIDC_DISCOVERY_URL=
+OIDC_CLIENT_ID=
+OIDC_CLIENT_SECRET=
+OIDC_SCOPES="openid email profile"
+OIDC_EMAIL_CLAIM=email
+OIDC_NAME_CLAIM=name
+
+# Set to true only if the provider omits the
+# email_verified claim & verifies all emails
+OIDC_TRUST_EMAIL=false
+
+# Users signing in via OIDC with an email in
+# one of these comma separated domains join
+# the team with the role below
+OIDC_AUTO_JOIN_DOMAINS=
+OIDC_AUTO_JOIN_TEAM_ID=
+OIDC_AUTO_JOIN_ROLE=viewer
+
+# Email & password sign in, for installs that
+# can't reach GitHub or Google. Email verification,
+# password reset & magic links need SMTP below.
+AUTH_PASSWORD_ENABLED=false
+AUTH_MAGIC_LINK_ENABLED=false
+
 SESSION_ACCESS_SECRET=$SESSION_ACCESS_SECRET
 SESSION_REFRESH_SECRET=$SESSION_REFRESH_SECRET
 
+#########
+# Email #
+#########
+
+# Alert, verification, password reset & magic
+# link emails won't work without these
+SMTP_HOST=
+SMTP_PORT=587
+SMTP_USER=
+SMTP_PASSWORD=
+SMTP_FROM_EMAIL=
+
 ########
 # OTEL #
 ########
@@ -366,6 +463,15 @@ NEXT_PUBLIC_SITE_URL=$NEXT_PUBLIC_SITE_URL
 NEXT_PUBLIC_OAUTH_GOOGLE_KEY=$OAUTH_GOOGLE_KEY
 NEXT_PUBLIC_OAUTH_GITHUB_KEY=$OAUTH_GITHUB_KEY
 
+# Set to "true" once OIDC_* variables are
+# configured in the api's environment
+NEXT_PUBLIC_OIDC_ENABLED=false
+
+# Set to "true" to match AUTH_PASSWORD_ENABLED
+# & AUTH_MAGIC_LINK_ENABLED of the api
+NEXT_PUBLIC_PASSWORD_ENABLED=false
+NEXT_PUBLIC_MAGIC_LINK_ENABLED=false
+
 ###############
 # MEASURE API #
 ###############
@@ -0,0 +1,29 @@
+-- migrate:up
+create table if not exists public.alerts (
+    id uuid primary key not null,
+    app_id uuid not null references public.apps(id) on delete cascade,
+    type text not null,
+    current_value double precision not null,
+    baseline_value double precision not null,
+    delta double precision not null,
+    recipient_count int not null default 0,
+    window_start timestamptz not null,
+    window_end timestamptz not null,
+    created_at timestamptz not null default now()
+);
+
+create index if not exists alerts_app_id_type_created_at_idx on public.alerts (app_id, type, created_at);
+
+comment on column public.alerts.id is 'sortable unique id (uuidv7) for each alert';
+comment on column public.alerts.app_id is 'linked app id';
+comment on column public.alerts.type is 'type of the alert, one of crash_rate_spike, anr_rate_spike or launch_time_spike';
+comment on column public.alerts.current_value is 'value of the metric in the evaluated window';
+comment on column public.alerts.baseline_value is 'value of the metric in the baseline window';
+comment on column public.alerts.delta is 'ratio of current value to baseline value';
+comment on column public.alerts.recipient_count is 'number of users the alert was emailed to';
+comment on column public.alerts.window_start is 'utc timestamp of the start of the evaluated window';
+comment on column public.alerts.window_end is 'utc timestamp of the end of the evaluated window';
+comment on column public.alerts.created_at is 'utc timestamp at the time of record creation';
+
+-- migrate:down
+drop table if exists public.alerts;
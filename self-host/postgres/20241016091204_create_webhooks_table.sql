@@ -0,0 +1,29 @@
+-- migrate:up
+create table if not exists public.webhooks (
+    id uuid primary key not null,
+    app_id uuid not null references public.apps(id) on delete cascade,
+    url text not null,
+    secret text not null,
+    events text[] not null,
+    volume_threshold int not null default 0,
+    active boolean not null default true,
+    created_by uuid references public.users(id) on delete set null,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now()
+);
+
+create index if not exists webhooks_app_id_idx on public.webhooks (app_id);
+
+comment on column public.webhooks.id is 'sortable unique id (uuidv7) for each webhook';
+comment on column public.webhooks.app_id is 'linked app id';
+comment on column public.webhooks.url is 'endpoint url receiving webhook payloads';
+comment on column public.webhooks.secret is 'secret used to compute hmac signature of payloads';
+comment on column public.webhooks.events is 'list of subscribed event types';
+comment on column public.webhooks.volume_threshold is 'number of events in a group at which volume threshold event is delivered, 0 disables';
+comment on column public.webhooks.active is 'if true, payloads are delivered to the webhook';
+comment on column public.webhooks.created_by is 'id of user who created the webhook';
+comment on column public.webhooks.created_at is 'utc timestamp at the time of record creation';
+comment on column public.webhooks.updated_at is 'utc timestamp at the time of record update';
+
+-- migrate:down
+drop table if exists public.webhooks;
@@ -0,0 +1,34 @@
+-- migrate:up
+create table if not exists public.webhook_deliveries (
+    id uuid primary key not null,
+    webhook_id uuid not null references public.webhooks(id) on delete cascade,
+    event text not null,
+    payload jsonb not null,
+    status text not null check (status in ('pending', 'succeeded', 'failed')),
+    attempts int not null default 0,
+    response_code int,
+    error text,
+    next_attempt_at timestamptz,
+    delivered_at timestamptz,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now()
+);
+
+create index if not exists webhook_deliveries_webhook_id_created_at_idx on public.webhook_deliveries (webhook_id, created_at desc);
+create index if not exists webhook_deliveries_status_next_attempt_at_idx on public.webhook_deliveries (status, next_attempt_at);
+
+comment on column public.webhook_deliveries.id is 'sortable unique id (uuidv7) for each delivery';
+comment on column public.webhook_deliveries.webhook_id is 'linked webhook id';
+comment on column public.webhook_deliveries.event is 'type of the delivered event';
+comment on column public.webhook_deliveries.payload is 'json payload of the delivery';
+comment on column public.webhook_deliveries.status is 'status of the delivery, one of pending, succeeded or failed';
+comment on column public.webhook_deliveries.attempts is 'number of delivery attempts made';
+comment on column public.webhook_deliveries.response_code is 'http status code of the last attempt';
+comment on column public.webhook_deliveries.error is 'error of the last failed attempt';
+comment on column public.webhook_deliveries.next_attempt_at is 'utc timestamp of the next retry attempt';
+comment on column public.webhook_deliveries.delivered_at is 'utc timestamp of the successful delivery';
+comment on column public.webhook_deliveries.created_at is 'utc timestamp at the time of record creation';
+comment on column public.webhook_deliveries.updated_at is 'utc timestamp at the time of record update';
+
+-- migrate:down
+drop table if exists public.webhook_deliveries;
@@ -0,0 +1,37 @@
+-- migrate:up
+create table if not exists public.ingest_jobs (
+    id uuid primary key not null,
+    app_id uuid not null references public.apps(id) on delete cascade,
+    status text not null check (status in ('queued', 'processing', 'succeeded', 'failed')),
+    events jsonb not null,
+    event_count int not null default 0,
+    attachment_count int not null default 0,
+    bytes_in bigint not null default 0,
+    attempts int not null default 0,
+    error text,
+    next_attempt_at timestamptz not null default now(),
+    ingested_at timestamptz,
+    completed_at timestamptz,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now()
+);
+
+create index if not exists ingest_jobs_status_next_attempt_at_idx on public.ingest_jobs (status, next_attempt_at);
+
+comment on column public.ingest_jobs.id is 'id of the event request, same as the msr-req-id header';
+comment on column public.ingest_jobs.app_id is 'id of the associated app';
+comment on column public.ingest_jobs.status is 'status of the job, one of queued, processing, succeeded or failed';
+comment on column public.ingest_jobs.events is 'validated events of the event request, cleared once the job succeeds';
+comment on column public.ingest_jobs.event_count is 'number of events in the event request';
+comment on column public.ingest_jobs.attachment_count is 'number of attachments in the event request';
+comment on column public.ingest_jobs.bytes_in is 'total payload size of the request';
+comment on column public.ingest_jobs.attempts is 'number of processing attempts made';
+comment on column public.ingest_jobs.error is 'error of the last failed attempt';
+comment on column public.ingest_jobs.next_attempt_at is 'utc timestamp after which the job can be picked up by a worker';
+comment on column public.ingest_jobs.ingested_at is 'utc timestamp at the time the events were written to clickhouse, retries skip ingestion once set';
+comment on column public.ingest_jobs.completed_at is 'utc timestamp at the time the job succeeded or finally failed';
+comment on column public.ingest_jobs.created_at is 'utc timestamp at the time of record creation';
+comment on column public.ingest_jobs.updated_at is 'utc timestamp at the time of record update';
+
+-- migrate:down
+drop table if exists public.ingest_jobs;
@@ -0,0 +1,18 @@
+-- migrate:up
+create table if not exists public.ingest_job_blobs (
+    id uuid not null,
+    job_id uuid not null references public.ingest_jobs(id) on delete cascade,
+    name text not null,
+    data bytea not null,
+    created_at timestamptz not null default now(),
+    primary key (job_id, id)
+);
+
+comment on column public.ingest_job_blobs.id is 'id of the attachment blob';
+comment on column public.ingest_job_blobs.job_id is 'linked ingest job id';
+comment on column public.ingest_job_blobs.name is 'original file name of the blob';
+comment on column public.ingest_job_blobs.data is 'raw bytes of the blob, removed once the job completes';
+comment on column public.ingest_job_blobs.created_at is 'utc timestamp at the time of record creation';
+
+-- migrate:down
+drop table if exists public.ingest_job_blobs;
@@ -0,0 +1,21 @@
+-- migrate:up
+alter table if exists public.anr_groups
+add column if not exists status text not null default 'open' check (status in ('open', 'resolved', 'ignored', 'muted')),
+add column if not exists resolved_in_version text,
+add column if not exists resolved_in_version_code text,
+add column if not exists muted_until timestamptz,
+add column if not exists regressed_at timestamptz;
+
+comment on column public.anr_groups.status is 'triage status of the group, one of open, resolved, ignored or muted';
+comment on column public.anr_groups.resolved_in_version is 'app version name the group was resolved in';
+comment on column public.anr_groups.resolved_in_version_code is 'app version code the group was resolved in';
+comment on column public.anr_groups.muted_until is 'utc timestamp until which the group is muted';
+comment on column public.anr_groups.regressed_at is 'utc timestamp of the last time the resolved group was reopened by a regression';
+
+-- migrate:down
+alter table if exists public.anr_groups
+drop column if exists status,
+drop column if exists resolved_in_version,
+drop column if exists resolved_in_version_code,
+drop column if exists muted_until,
+drop column if exists regressed_at;
@@ -0,0 +1,23 @@
+-- migrate:up
+create table if not exists public.fingerprint_rules (
+    app_id uuid primary key not null references public.apps(id) on delete cascade,
+    version int not null default 1,
+    skip_frame_prefixes text[] not null default '{}',
+    in_app_frame_prefixes text[] not null default '{}',
+    in_app_frames int not null default 1,
+    include_message boolean not null default false,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now()
+);
+
+comment on column public.fingerprint_rules.app_id is 'linked app id';
+comment on column public.fingerprint_rules.version is 'version of the fingerprinting algorithm';
+comment on column public.fingerprint_rules.skip_frame_prefixes is 'frames whose class and method start with any of these prefixes are excluded from fingerprints';
+comment on column public.fingerprint_rules.in_app_frame_prefixes is 'frames whose class and method start with any of these prefixes are preferred for fingerprints';
+comment on column public.fingerprint_rules.in_app_frames is 'number of frames that contribute to fingerprints';
+comment on column public.fingerprint_rules.include_message is 'if true, message template with variable parts stripped contributes to fingerprints';
+comment on column public.fingerprint_rules.created_at is 'utc timestamp at the time of record creation';
+comment on column public.fingerprint_rules.updated_at is 'utc timestamp at the time of record update';
+
+-- migrate:down
+drop table if exists public.fingerprint_rules;
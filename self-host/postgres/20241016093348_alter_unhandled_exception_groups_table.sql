@@ -0,0 +1,9 @@
+-- migrate:up
+alter table if exists public.unhandled_exception_groups
+add column if not exists fingerprint_version int not null default 1;
+
+comment on column public.unhandled_exception_groups.fingerprint_version is 'version of the fingerprinting algorithm that computed the fingerprint';
+
+-- migrate:down
+alter table if exists public.unhandled_exception_groups
+drop column if exists fingerprint_version;
@@ -0,0 +1,13 @@
+-- migrate:up
+alter table if exists public.unhandled_exception_groups
+add column if not exists merged_into uuid references public.unhandled_exception_groups(id) on delete set null;
+
+create index if not exists unhandled_exception_groups_merged_into_idx on public.unhandled_exception_groups (merged_into);
+
+comment on column public.unhandled_exception_groups.merged_into is 'id of the group this group was merged into, events of this group belong to the surviving group';
+
+-- migrate:down
+drop index if exists public.unhandled_exception_groups_merged_into_idx;
+
+alter table if exists public.unhandled_exception_groups
+drop column if exists merged_into;
@@ -0,0 +1,13 @@
+-- migrate:up
+alter table if exists public.anr_groups
+add column if not exists merged_into uuid references public.anr_groups(id) on delete set null;
+
+create index if not exists anr_groups_merged_into_idx on public.anr_groups (merged_into);
+
+comment on column public.anr_groups.merged_into is 'id of the group this group was merged into, events of this group belong to the surviving group';
+
+-- migrate:down
+drop index if exists public.anr_groups_merged_into_idx;
+
+alter table if exists public.anr_groups
+drop column if exists merged_into;
@@ -0,0 +1,20 @@
+-- migrate:up
+create table if not exists public.build_mapping_build_ids (
+    mapping_id uuid not null references public.build_mappings(id) on delete cascade,
+    app_id uuid not null references public.apps(id) on delete cascade,
+    build_id varchar(128) not null,
+    binary_name varchar(256) not null default '',
+    created_at timestamptz not null default now(),
+    primary key (mapping_id, build_id)
+);
+
+comment on column public.build_mapping_build_ids.mapping_id is 'linked build mapping id';
+comment on column public.build_mapping_build_ids.app_id is 'linked app id';
+comment on column public.build_mapping_build_ids.build_id is 'gnu build id of the binary in hex';
+comment on column public.build_mapping_build_ids.binary_name is 'name of the binary carrying the build id';
+comment on column public.build_mapping_build_ids.created_at is 'utc timestamp at the time of record creation';
+
+create index if not exists build_mapping_build_ids_app_id_build_id_idx on public.build_mapping_build_ids (app_id, build_id);
+
+-- migrate:down
+drop table if exists public.build_mapping_build_ids;
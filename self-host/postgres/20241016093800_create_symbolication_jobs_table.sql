@@ -0,0 +1,37 @@
+-- migrate:up
+create table if not exists public.symbolication_jobs (
+    id uuid primary key not null,
+    app_id uuid not null references public.apps(id) on delete cascade,
+    version_name varchar(256) not null,
+    version_code varchar(256) not null,
+    mapping_type varchar(32) not null,
+    status text not null check (status in ('queued', 'processing', 'succeeded', 'failed')),
+    symbolicated_count int not null default 0,
+    attempts int not null default 0,
+    error text,
+    next_attempt_at timestamptz not null default now(),
+    completed_at timestamptz,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now()
+);
+
+create index if not exists symbolication_jobs_status_next_attempt_at_idx on public.symbolication_jobs (status, next_attempt_at);
+
+create unique index if not exists symbolication_jobs_queued_version_idx on public.symbolication_jobs (app_id, version_name, version_code, mapping_type) where status = 'queued';
+
+comment on column public.symbolication_jobs.id is 'unique id for each symbolication job';
+comment on column public.symbolication_jobs.app_id is 'id of the associated app';
+comment on column public.symbolication_jobs.version_name is 'app version of the events to symbolicate';
+comment on column public.symbolication_jobs.version_code is 'app build of the events to symbolicate';
+comment on column public.symbolication_jobs.mapping_type is 'type of the uploaded mapping file';
+comment on column public.symbolication_jobs.status is 'status of the job, one of queued, processing, succeeded or failed';
+comment on column public.symbolication_jobs.symbolicated_count is 'number of events symbolicated so far';
+comment on column public.symbolication_jobs.attempts is 'number of processing attempts made';
+comment on column public.symbolication_jobs.error is 'error of the last failed attempt';
+comment on column public.symbolication_jobs.next_attempt_at is 'utc timestamp after which the job can be picked up by the worker';
+comment on column public.symbolication_jobs.completed_at is 'utc timestamp at the time the job succeeded or finally failed';
+comment on column public.symbolication_jobs.created_at is 'utc timestamp at the time of record creation';
+comment on column public.symbolication_jobs.updated_at is 'utc timestamp at the time of record update';
+
+-- migrate:down
+drop table if exists public.symbolication_jobs;
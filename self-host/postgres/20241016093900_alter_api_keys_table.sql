@@ -0,0 +1,26 @@
+-- migrate:up
+alter table if exists public.api_keys
+add column if not exists name varchar(256),
+add column if not exists revoked_at timestamptz,
+add column if not exists expires_at timestamptz,
+add column if not exists created_by uuid references public.users(id) on delete set null;
+
+create unique index if not exists api_keys_key_value_idx on public.api_keys (key_value);
+
+create index if not exists api_keys_app_id_created_at_idx on public.api_keys (app_id, created_at);
+
+comment on column public.api_keys.name is 'user provided label for the key';
+comment on column public.api_keys.revoked_at is 'utc timestamp at the time of key revocation';
+comment on column public.api_keys.expires_at is 'utc timestamp after which a revoked key stops being accepted, allows an overlap window during rotation';
+comment on column public.api_keys.created_by is 'id of the user who minted the key';
+
+-- migrate:down
+drop index if exists public.api_keys_app_id_created_at_idx;
+
+drop index if exists public.api_keys_key_value_idx;
+
+alter table if exists public.api_keys
+drop column if exists name,
+drop column if exists revoked_at,
+drop column if exists expires_at,
+drop column if exists created_by;
@@ -0,0 +1,31 @@
+-- migrate:up
+create table if not exists public.personal_access_tokens (
+    id uuid primary key not null,
+    user_id uuid not null references public.users(id) on delete cascade,
+    name varchar(256) not null,
+    hint varchar(16) not null,
+    token_hash varchar(64) not null,
+    scopes text[] not null,
+    expires_at timestamptz not null,
+    last_used_at timestamptz,
+    revoked_at timestamptz,
+    created_at timestamptz not null default now()
+);
+
+create unique index if not exists personal_access_tokens_token_hash_idx on public.personal_access_tokens (token_hash);
+
+create index if not exists personal_access_tokens_user_id_created_at_idx on public.personal_access_tokens (user_id, created_at);
+
+comment on column public.personal_access_tokens.id is 'unique id for each personal access token';
+comment on column public.personal_access_tokens.user_id is 'id of the user the token acts on behalf of';
+comment on column public.personal_access_tokens.name is 'user provided label for the token';
+comment on column public.personal_access_tokens.hint is 'first few characters of the token value to help identify it';
+comment on column public.personal_access_tokens.token_hash is 'sha256 hash of the token value';
+comment on column public.personal_access_tokens.scopes is 'list of scopes granted to the token';
+comment on column public.personal_access_tokens.expires_at is 'utc timestamp after which the token stops being accepted';
+comment on column public.personal_access_tokens.last_used_at is 'utc timestamp at the time of last token usage seen';
+comment on column public.personal_access_tokens.revoked_at is 'utc timestamp at the time of token revocation';
+comment on column public.personal_access_tokens.created_at is 'utc timestamp at the time of token creation';
+
+-- migrate:down
+drop table if exists public.personal_access_tokens;
@@ -0,0 +1,12 @@
+-- migrate:up
+alter table if exists public.users
+add column if not exists password_hash varchar(72),
+add column if not exists password_updated_at timestamptz;
+
+comment on column public.users.password_hash is 'bcrypt hash of the user''s password, null if the user never set one';
+comment on column public.users.password_updated_at is 'utc timestamp at the time of last password change';
+
+-- migrate:down
+alter table if exists public.users
+drop column if exists password_hash,
+drop column if exists password_updated_at;
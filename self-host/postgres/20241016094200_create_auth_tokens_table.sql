@@ -0,0 +1,25 @@
+-- migrate:up
+create table if not exists public.auth_tokens (
+    id uuid primary key not null,
+    user_id uuid not null references public.users(id) on delete cascade,
+    purpose varchar(32) not null,
+    token_hash varchar(64) not null,
+    expires_at timestamptz not null,
+    used_at timestamptz,
+    created_at timestamptz not null default now()
+);
+
+create unique index if not exists auth_tokens_token_hash_idx on public.auth_tokens (token_hash);
+
+create index if not exists auth_tokens_user_id_purpose_idx on public.auth_tokens (user_id, purpose);
+
+comment on column public.auth_tokens.id is 'unique id for each one time token';
+comment on column public.auth_tokens.user_id is 'id of the user the token was issued to';
+comment on column public.auth_tokens.purpose is 'what the token can be used for, one of verify_email, reset_password or magic_link';
+comment on column public.auth_tokens.token_hash is 'sha256 hash of the token value';
+comment on column public.auth_tokens.expires_at is 'utc timestamp after which the token stops being accepted';
+comment on column public.auth_tokens.used_at is 'utc timestamp at the time the token was used or invalidated';
+comment on column public.auth_tokens.created_at is 'utc timestamp at the time of token creation';
+
+-- migrate:down
+drop table if exists public.auth_tokens;
@@ -0,0 +1,21 @@
+-- migrate:up
+create table if not exists public.auth_attempts (
+    id uuid primary key not null,
+    kind varchar(32) not null,
+    email varchar(256) not null,
+    ip varchar(64) not null,
+    created_at timestamptz not null default now()
+);
+
+create index if not exists auth_attempts_kind_email_created_at_idx on public.auth_attempts (kind, email, created_at);
+
+create index if not exists auth_attempts_kind_ip_created_at_idx on public.auth_attempts (kind, ip, created_at);
+
+comment on column public.auth_attempts.id is 'unique id for each attempt';
+comment on column public.auth_attempts.kind is 'kind of rate limited attempt, one of failed_signin or email_sent';
+comment on column public.auth_attempts.email is 'lowercased email the attempt was made for';
+comment on column public.auth_attempts.ip is 'ip address of the client making the attempt';
+comment on column public.auth_attempts.created_at is 'utc timestamp at the time of the attempt';
+
+-- migrate:down
+drop table if exists public.auth_attempts;
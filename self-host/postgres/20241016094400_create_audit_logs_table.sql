@@ -0,0 +1,33 @@
+-- migrate:up
+create table if not exists public.audit_logs (
+    id uuid primary key not null,
+    team_id uuid references public.teams(id) on delete cascade,
+    actor_id uuid references public.users(id) on delete set null,
+    action varchar(64) not null,
+    target_type varchar(32),
+    target_id varchar(256),
+    before jsonb,
+    after jsonb,
+    ip varchar(64),
+    user_agent text,
+    created_at timestamptz not null default now()
+);
+
+create index if not exists audit_logs_team_id_created_at_idx on public.audit_logs (team_id, created_at desc, id desc);
+
+create index if not exists audit_logs_actor_id_created_at_idx on public.audit_logs (actor_id, created_at desc, id desc);
+
+comment on column public.audit_logs.id is 'unique id for each audit log entry';
+comment on column public.audit_logs.team_id is 'id of the team the action was performed in, null for actions not scoped to a team';
+comment on column public.audit_logs.actor_id is 'id of the user who performed the action';
+comment on column public.audit_logs.action is 'name of the action performed, like member.role_changed';
+comment on column public.audit_logs.target_type is 'type of the entity acted upon, like member, team or app';
+comment on column public.audit_logs.target_id is 'id of the entity acted upon';
+comment on column public.audit_logs.before is 'changed fields of the target before the action';
+comment on column public.audit_logs.after is 'changed fields of the target after the action';
+comment on column public.audit_logs.ip is 'ip address of the client performing the action';
+comment on column public.audit_logs.user_agent is 'user agent of the client performing the action';
+comment on column public.audit_logs.created_at is 'utc timestamp at the time of the action';
+
+-- migrate:down
+drop table if exists public.audit_logs;
@@ -0,0 +1,37 @@
+-- migrate:up
+create table if not exists public.invites (
+    id uuid primary key not null,
+    team_id uuid not null references public.teams(id) on delete cascade,
+    email varchar(256) not null,
+    role varchar(32) not null,
+    token_hash varchar(64) not null,
+    invited_by uuid references public.users(id) on delete set null,
+    expires_at timestamptz not null,
+    accepted_at timestamptz,
+    declined_at timestamptz,
+    revoked_at timestamptz,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now()
+);
+
+create unique index if not exists invites_token_hash_idx on public.invites (token_hash);
+
+create index if not exists invites_team_id_email_idx on public.invites (team_id, email);
+
+create index if not exists invites_email_idx on public.invites (email);
+
+comment on column public.invites.id is 'unique id for each invite';
+comment on column public.invites.team_id is 'id of the team the invitee is invited to';
+comment on column public.invites.email is 'lowercased email of the invitee';
+comment on column public.invites.role is 'role the invitee joins the team with';
+comment on column public.invites.token_hash is 'sha256 hash of the invite token';
+comment on column public.invites.invited_by is 'id of the user who sent the invite';
+comment on column public.invites.expires_at is 'utc timestamp after which the invite cannot be accepted';
+comment on column public.invites.accepted_at is 'utc timestamp at the time the invite was accepted';
+comment on column public.invites.declined_at is 'utc timestamp at the time the invite was declined';
+comment on column public.invites.revoked_at is 'utc timestamp at the time the invite was revoked';
+comment on column public.invites.created_at is 'utc timestamp at the time of invite creation';
+comment on column public.invites.updated_at is 'utc timestamp at the time the invite was last sent';
+
+-- migrate:down
+drop table if exists public.invites;
@@ -0,0 +1,20 @@
+-- migrate:up
+create table if not exists public.app_roles (
+    app_id uuid not null references public.apps(id) on delete cascade,
+    user_id uuid not null references public.users(id) on delete cascade,
+    role varchar(32) not null,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    primary key (app_id, user_id)
+);
+
+create index if not exists app_roles_user_id_idx on public.app_roles (user_id);
+
+comment on column public.app_roles.app_id is 'id of the app the role applies to';
+comment on column public.app_roles.user_id is 'id of the team member the role applies to';
+comment on column public.app_roles.role is 'role of the member for the app, overriding their team role. none denies all access to the app';
+comment on column public.app_roles.created_at is 'utc timestamp at the time of role creation';
+comment on column public.app_roles.updated_at is 'utc timestamp at the time of last role change';
+
+-- migrate:down
+drop table if exists public.app_roles;
@@ -0,0 +1,12 @@
+-- migrate:up
+alter table if exists public.teams add column if not exists deleted_at timestamptz;
+
+alter table if exists public.apps add column if not exists deleted_at timestamptz;
+
+comment on column public.teams.deleted_at is 'utc timestamp at the time the team was deleted, its data is purged in the background';
+comment on column public.apps.deleted_at is 'utc timestamp at the time the app was deleted, its data is purged in the background';
+
+-- migrate:down
+alter table if exists public.apps drop column if exists deleted_at;
+
+alter table if exists public.teams drop column if exists deleted_at;
@@ -0,0 +1,45 @@
+-- migrate:up
+create table if not exists public.purge_jobs (
+    id uuid primary key not null,
+    team_id uuid not null,
+    app_id uuid,
+    requested_by uuid references public.users(id) on delete set null,
+    status text not null check (status in ('queued', 'processing', 'succeeded', 'failed')),
+    step varchar(32),
+    apps_count int not null default 0,
+    apps_purged int not null default 0,
+    attachments_purged bigint not null default 0,
+    events_purged bigint not null default 0,
+    mappings_purged bigint not null default 0,
+    attempts int not null default 0,
+    error text,
+    next_attempt_at timestamptz not null default now(),
+    completed_at timestamptz,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now()
+);
+
+create index if not exists purge_jobs_status_next_attempt_at_idx on public.purge_jobs (status, next_attempt_at);
+
+create index if not exists purge_jobs_team_id_app_id_idx on public.purge_jobs (team_id, app_id);
+
+comment on column public.purge_jobs.id is 'unique id for each purge job';
+comment on column public.purge_jobs.team_id is 'id of the deleted team, or of the team of the deleted app. not a foreign key, the team row is removed by the purge';
+comment on column public.purge_jobs.app_id is 'id of the deleted app, null when the whole team was deleted. not a foreign key, the app row is removed by the purge';
+comment on column public.purge_jobs.requested_by is 'id of the user who deleted the team or app';
+comment on column public.purge_jobs.status is 'status of the job, one of queued, processing, succeeded or failed';
+comment on column public.purge_jobs.step is 'step of the purge being processed';
+comment on column public.purge_jobs.apps_count is 'number of apps to purge';
+comment on column public.purge_jobs.apps_purged is 'number of apps purged so far';
+comment on column public.purge_jobs.attachments_purged is 'number of attachments removed from object storage so far';
+comment on column public.purge_jobs.events_purged is 'number of events removed so far';
+comment on column public.purge_jobs.mappings_purged is 'number of build mappings removed from object storage so far';
+comment on column public.purge_jobs.attempts is 'number of processing attempts made';
+comment on column public.purge_jobs.error is 'error of the last failed attempt';
+comment on column public.purge_jobs.next_attempt_at is 'utc timestamp after which the job can be picked up by the worker';
+comment on column public.purge_jobs.completed_at is 'utc timestamp at the time the job succeeded or finally failed';
+comment on column public.purge_jobs.created_at is 'utc timestamp at the time of record creation';
+comment on column public.purge_jobs.updated_at is 'utc timestamp at the time of record update';
+
+-- migrate:down
+drop table if exists public.purge_jobs;
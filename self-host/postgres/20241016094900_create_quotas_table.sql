@@ -0,0 +1,28 @@
+-- migrate:up
+create table if not exists public.quotas (
+    id uuid primary key not null default gen_random_uuid(),
+    team_id uuid references public.teams(id) on delete cascade,
+    app_id uuid references public.apps(id) on delete cascade,
+    max_events bigint check (max_events >= 0),
+    max_sessions bigint check (max_sessions >= 0),
+    max_attachment_bytes bigint check (max_attachment_bytes >= 0),
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    check ((team_id is null) <> (app_id is null))
+);
+
+create unique index if not exists quotas_team_id_idx on public.quotas (team_id) where team_id is not null;
+
+create unique index if not exists quotas_app_id_idx on public.quotas (app_id) where app_id is not null;
+
+comment on column public.quotas.id is 'unique id for each quota';
+comment on column public.quotas.team_id is 'linked team id, set for quotas shared by all apps of a team';
+comment on column public.quotas.app_id is 'linked app id, set for quotas of a single app';
+comment on column public.quotas.max_events is 'maximum events accepted in a calendar month, null for unlimited';
+comment on column public.quotas.max_sessions is 'maximum sessions accepted in a calendar month, null for unlimited';
+comment on column public.quotas.max_attachment_bytes is 'maximum attachment bytes accepted in a calendar month, null for unlimited';
+comment on column public.quotas.created_at is 'utc timestamp at the time of record creation';
+comment on column public.quotas.updated_at is 'utc timestamp at the time of record update';
+
+-- migrate:down
+drop table if exists public.quotas;
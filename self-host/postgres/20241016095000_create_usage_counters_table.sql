@@ -0,0 +1,34 @@
+-- migrate:up
+create table if not exists public.usage_counters (
+    app_id uuid not null references public.apps(id) on delete cascade,
+    month date not null,
+    events bigint not null default 0,
+    sessions bigint not null default 0,
+    attachment_bytes bigint not null default 0,
+    created_at timestamptz not null default now(),
+    updated_at timestamptz not null default now(),
+    primary key (app_id, month)
+);
+
+comment on column public.usage_counters.app_id is 'linked app id';
+comment on column public.usage_counters.month is 'first day of the utc calendar month counted';
+comment on column public.usage_counters.events is 'number of events accepted in the month';
+comment on column public.usage_counters.sessions is 'number of distinct sessions accepted in the month';
+comment on column public.usage_counters.attachment_bytes is 'size of attachments accepted in the month in bytes';
+comment on column public.usage_counters.created_at is 'utc timestamp at the time of record creation';
+comment on column public.usage_counters.updated_at is 'utc timestamp at the time of record update';
+
+create table if not exists public.usage_sessions (
+    app_id uuid not null references public.apps(id) on delete cascade,
+    month date not null,
+    session_id uuid not null,
+    primary key (app_id, month, session_id)
+);
+
+comment on column public.usage_sessions.app_id is 'linked app id';
+comment on column public.usage_sessions.month is 'first day of the utc calendar month the session was counted in';
+comment on column public.usage_sessions.session_id is 'id of the session counted';
+
+-- migrate:down
+drop table if exists public.usage_sessions;
+drop table if exists public.usage_counters;
@@ -0,0 +1,22 @@
+-- migrate:up
+alter table if exists public.event_reqs
+add column if not exists bytes_in_wire int,
+add column if not exists content_encoding varchar(16);
+
+alter table if exists public.ingest_jobs
+add column if not exists bytes_in_wire bigint not null default 0,
+add column if not exists content_encoding varchar(16) not null default 'identity';
+
+comment on column public.event_reqs.bytes_in_wire is 'size of the request body as received, before decompression';
+comment on column public.event_reqs.content_encoding is 'content encoding of the request body, one of identity, gzip or zstd';
+comment on column public.ingest_jobs.bytes_in_wire is 'size of the request body as received, before decompression';
+comment on column public.ingest_jobs.content_encoding is 'content encoding of the request body, one of identity, gzip or zstd';
+
+-- migrate:down
+alter table if exists public.ingest_jobs
+drop column if exists bytes_in_wire,
+drop column if exists content_encoding;
+
+alter table if exists public.event_reqs
+drop column if exists bytes_in_wire,
+drop column if exists content_encoding;
@@ -107,6 +107,94 @@ COMMENT ON COLUMN public.alert_prefs.created_at IS 'utc timestamp at the time of
 COMMENT ON COLUMN public.alert_prefs.updated_at IS 'utc timestamp at the time of record update';
 
 
+--
+-- Name: alerts; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.alerts (
+    id uuid NOT NULL,
+    app_id uuid NOT NULL,
+    type text NOT NULL,
+    current_value double precision NOT NULL,
+    baseline_value double precision NOT NULL,
+    delta double precision NOT NULL,
+    recipient_count integer DEFAULT 0 NOT NULL,
+    window_start timestamp with time zone NOT NULL,
+    window_end timestamp with time zone NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: COLUMN alerts.id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.alerts.id IS 'sortable unique id (uuidv7) for each alert';
+
+
+--
+-- Name: COLUMN alerts.app_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.alerts.app_id IS 'linked app id';
+
+
+--
+-- Name: COLUMN alerts.type; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.alerts.type IS 'type of the alert, one of crash_rate_spike, anr_rate_spike or launch_time_spike';
+
+
+--
+-- Name: COLUMN alerts.current_value; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.alerts.current_value IS 'value of the metric in the evaluated window';
+
+
+--
+-- Name: COLUMN alerts.baseline_value; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.alerts.baseline_value IS 'value of the metric in the baseline window';
+
+
+--
+-- Name: COLUMN alerts.delta; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.alerts.delta IS 'ratio of current value to baseline value';
+
+
+--
+-- Name: COLUMN alerts.recipient_count; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.alerts.recipient_count IS 'number of users the alert was emailed to';
+
+
+--
+-- Name: COLUMN alerts.window_start; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.alerts.window_start IS 'utc timestamp of the start of the evaluated window';
+
+
+--
+-- Name: COLUMN alerts.window_end; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.alerts.window_end IS 'utc timestamp of the end of the evaluated window';
+
+
+--
+-- Name: COLUMN alerts.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.alerts.created_at IS 'utc timestamp at the time of record creation';
+
+
 --
 -- Name: anr_groups; Type: TABLE; Schema: public; Owner: -
 --
@@ -123,7 +211,15 @@ CREATE TABLE public.anr_groups (
     event_ids uuid[] NOT NULL,
     first_event_timestamp timestamp with time zone NOT NULL,
     created_at timestamp with time zone DEFAULT now() NOT NULL,
-    updated_at timestamp with time zone DEFAULT now() NOT NULL
+    updated_at timestamp with time zone DEFAULT now() NOT NULL,
+    status text DEFAULT 'open'::text NOT NULL,
+    resolved_in_version text,
+    resolved_in_version_code text,
+    muted_until timestamp with time zone,
+    regressed_at timestamp with time zone,
+    fingerprint_version integer DEFAULT 1 NOT NULL,
+    merged_into uuid,
+    CONSTRAINT anr_groups_status_check CHECK ((status = ANY (ARRAY['open'::text, 'resolved'::text, 'ignored'::text, 'muted'::text])))
 );
 
 
@@ -211,6 +307,55 @@ COMMENT ON COLUMN public.anr_groups.created_at IS 'utc timestamp at the time of
 COMMENT ON COLUMN public.anr_groups.updated_at IS 'utc timestamp at the time of record updation';
 
 
+--
+-- Name: COLUMN anr_groups.status; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.anr_groups.status IS 'triage status of the group, one of open, resolved, ignored or muted';
+
+
+--
+-- Name: COLUMN anr_groups.resolved_in_version; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.anr_groups.resolved_in_version IS 'app version name the group was resolved in';
+
+
+--
+-- Name: COLUMN anr_groups.resolved_in_version_code; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.anr_groups.resolved_in_version_code IS 'app version code the group was resolved in';
+
+
+--
+-- Name: COLUMN anr_groups.muted_until; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.anr_groups.muted_until IS 'utc timestamp until which the group is muted';
+
+
+--
+-- Name: COLUMN anr_groups.regressed_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.anr_groups.regressed_at IS 'utc timestamp of the last time the resolved group was reopened by a regression';
+
+
+--
+-- Name: COLUMN anr_groups.fingerprint_version; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.anr_groups.fingerprint_version IS 'version of the fingerprinting algorithm that computed the fingerprint';
+
+
+--
+-- Name: COLUMN anr_groups.merged_into; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.anr_groups.merged_into IS 'id of the group this group was merged into, events of this group belong to the surviving group';
+
+
 --
 -- Name: api_keys; Type: TABLE; Schema: public; Owner: -
 --
@@ -223,7 +368,11 @@ CREATE TABLE public.api_keys (
     checksum character varying(16) NOT NULL,
     revoked boolean DEFAULT false,
     last_seen timestamp with time zone,
-    created_at timestamp with time zone DEFAULT now() NOT NULL
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    name character varying(256),
+    revoked_at timestamp with time zone,
+    expires_at timestamp with time zone,
+    created_by uuid
 );
 
 
@@ -283,6 +432,82 @@ COMMENT ON COLUMN public.api_keys.last_seen IS 'utc timestamp at the time of las
 COMMENT ON COLUMN public.api_keys.created_at IS 'utc timestamp at the time of api key creation';
 
 
+--
+-- Name: COLUMN api_keys.name; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.api_keys.name IS 'user provided label for the key';
+
+
+--
+-- Name: COLUMN api_keys.revoked_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.api_keys.revoked_at IS 'utc timestamp at the time of key revocation';
+
+
+--
+-- Name: COLUMN api_keys.expires_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.api_keys.expires_at IS 'utc timestamp after which a revoked key stops being accepted, allows an overlap window during rotation';
+
+
+--
+-- Name: COLUMN api_keys.created_by; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.api_keys.created_by IS 'id of the user who minted the key';
+
+
+--
+-- Name: app_roles; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.app_roles (
+    app_id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    role character varying(32) NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: COLUMN app_roles.app_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.app_roles.app_id IS 'id of the app the role applies to';
+
+
+--
+-- Name: COLUMN app_roles.user_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.app_roles.user_id IS 'id of the team member the role applies to';
+
+
+--
+-- Name: COLUMN app_roles.role; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.app_roles.role IS 'role of the member for the app, overriding their team role. none denies all access to the app';
+
+
+--
+-- Name: COLUMN app_roles.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.app_roles.created_at IS 'utc timestamp at the time of role creation';
+
+
+--
+-- Name: COLUMN app_roles.updated_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.app_roles.updated_at IS 'utc timestamp at the time of last role change';
+
+
 --
 -- Name: app_settings; Type: TABLE; Schema: public; Owner: -
 --
@@ -338,6 +563,7 @@ CREATE TABLE public.apps (
     onboarded_at timestamp with time zone,
     created_at timestamp with time zone DEFAULT now() NOT NULL,
     updated_at timestamp with time zone NOT NULL,
+    deleted_at timestamp with time zone,
     CONSTRAINT apps_platform_check CHECK (((platform)::text = ANY ((ARRAY['ios'::character varying, 'android'::character varying, 'flutter'::character varying, 'react-native'::character varying, 'unity'::character varying])::text[])))
 );
 
@@ -413,200 +639,463 @@ COMMENT ON COLUMN public.apps.updated_at IS 'utc timestamp at the time of app re
 
 
 --
--- Name: auth_sessions; Type: TABLE; Schema: public; Owner: -
+-- Name: COLUMN apps.deleted_at; Type: COMMENT; Schema: public; Owner: -
 --
 
-CREATE TABLE public.auth_sessions (
+COMMENT ON COLUMN public.apps.deleted_at IS 'utc timestamp at the time the app was deleted, its data is purged in the background';
+
+
+--
+-- Name: audit_logs; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.audit_logs (
     id uuid NOT NULL,
-    user_id uuid NOT NULL,
-    oauth_provider text,
-    user_metadata jsonb,
-    at_expiry_at timestamp with time zone NOT NULL,
-    rt_expiry_at timestamp with time zone NOT NULL,
+    team_id uuid,
+    actor_id uuid,
+    action character varying(64) NOT NULL,
+    target_type character varying(32),
+    target_id character varying(256),
+    before jsonb,
+    after jsonb,
+    ip character varying(64),
+    user_agent text,
     created_at timestamp with time zone DEFAULT now() NOT NULL
 );
 
 
 --
--- Name: COLUMN auth_sessions.id; Type: COMMENT; Schema: public; Owner: -
+-- Name: COLUMN audit_logs.id; Type: COMMENT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.auth_sessions.id IS 'unique id of auth session';
+COMMENT ON COLUMN public.audit_logs.id IS 'unique id for each audit log entry';
 
 
 --
--- Name: COLUMN auth_sessions.user_id; Type: COMMENT; Schema: public; Owner: -
+-- Name: COLUMN audit_logs.team_id; Type: COMMENT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.auth_sessions.user_id IS 'user id of the session holder';
+COMMENT ON COLUMN public.audit_logs.team_id IS 'id of the team the action was performed in, null for actions not scoped to a team';
 
 
 --
--- Name: COLUMN auth_sessions.oauth_provider; Type: COMMENT; Schema: public; Owner: -
+-- Name: COLUMN audit_logs.actor_id; Type: COMMENT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.auth_sessions.oauth_provider IS 'name of the oauth provider';
+COMMENT ON COLUMN public.audit_logs.actor_id IS 'id of the user who performed the action';
 
 
 --
--- Name: COLUMN auth_sessions.user_metadata; Type: COMMENT; Schema: public; Owner: -
+-- Name: COLUMN audit_logs.action; Type: COMMENT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.auth_sessions.user_metadata IS 'arbitrary metadata associated with the user';
+COMMENT ON COLUMN public.audit_logs.action IS 'name of the action performed, like member.role_changed';
 
 
 --
--- Name: COLUMN auth_sessions.at_expiry_at; Type: COMMENT; Schema: public; Owner: -
+-- Name: COLUMN audit_logs.target_type; Type: COMMENT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.auth_sessions.at_expiry_at IS 'expiry time of access token';
+COMMENT ON COLUMN public.audit_logs.target_type IS 'type of the entity acted upon, like member, team or app';
 
 
 --
--- Name: COLUMN auth_sessions.rt_expiry_at; Type: COMMENT; Schema: public; Owner: -
+-- Name: COLUMN audit_logs.target_id; Type: COMMENT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.auth_sessions.rt_expiry_at IS 'expiry time of refresh token';
+COMMENT ON COLUMN public.audit_logs.target_id IS 'id of the entity acted upon';
 
 
 --
--- Name: COLUMN auth_sessions.created_at; Type: COMMENT; Schema: public; Owner: -
+-- Name: COLUMN audit_logs.before; Type: COMMENT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.auth_sessions.created_at IS 'utc timestamp at the time of record creation';
+COMMENT ON COLUMN public.audit_logs.before IS 'changed fields of the target before the action';
 
 
 --
--- Name: auth_states; Type: TABLE; Schema: public; Owner: -
+-- Name: COLUMN audit_logs.after; Type: COMMENT; Schema: public; Owner: -
 --
 
-CREATE TABLE public.auth_states (
-    id uuid NOT NULL,
-    state text NOT NULL,
-    oauth_provider text NOT NULL,
-    created_at timestamp with time zone DEFAULT now() NOT NULL,
-    updated_at timestamp with time zone DEFAULT now() NOT NULL
-);
+COMMENT ON COLUMN public.audit_logs.after IS 'changed fields of the target after the action';
 
 
 --
--- Name: COLUMN auth_states.id; Type: COMMENT; Schema: public; Owner: -
+-- Name: COLUMN audit_logs.ip; Type: COMMENT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.auth_states.id IS 'unique id of auth state';
+COMMENT ON COLUMN public.audit_logs.ip IS 'ip address of the client performing the action';
 
 
 --
--- Name: COLUMN auth_states.state; Type: COMMENT; Schema: public; Owner: -
+-- Name: COLUMN audit_logs.user_agent; Type: COMMENT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.auth_states.state IS 'oauth state nonce';
+COMMENT ON COLUMN public.audit_logs.user_agent IS 'user agent of the client performing the action';
 
 
 --
--- Name: COLUMN auth_states.oauth_provider; Type: COMMENT; Schema: public; Owner: -
+-- Name: COLUMN audit_logs.created_at; Type: COMMENT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.auth_states.oauth_provider IS 'name of the social oauth provider';
+COMMENT ON COLUMN public.audit_logs.created_at IS 'utc timestamp at the time of the action';
 
 
 --
--- Name: COLUMN auth_states.created_at; Type: COMMENT; Schema: public; Owner: -
+-- Name: auth_attempts; Type: TABLE; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.auth_states.created_at IS 'utc timestamp at the time of record creation';
+CREATE TABLE public.auth_attempts (
+    id uuid NOT NULL,
+    kind character varying(32) NOT NULL,
+    email character varying(256) NOT NULL,
+    ip character varying(64) NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
 
 
 --
--- Name: COLUMN auth_states.updated_at; Type: COMMENT; Schema: public; Owner: -
+-- Name: COLUMN auth_attempts.id; Type: COMMENT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.auth_states.updated_at IS 'utc timestamp at the time of record updation';
+COMMENT ON COLUMN public.auth_attempts.id IS 'unique id for each attempt';
 
 
 --
--- Name: build_mappings; Type: TABLE; Schema: public; Owner: -
+-- Name: COLUMN auth_attempts.kind; Type: COMMENT; Schema: public; Owner: -
 --
 
-CREATE TABLE public.build_mappings (
-    id uuid NOT NULL,
-    app_id uuid,
-    version_name character varying(256) NOT NULL,
-    version_code character varying(256) NOT NULL,
-    mapping_type character varying(32) NOT NULL,
-    key character varying(256) NOT NULL,
-    location character varying NOT NULL,
-    fnv1_hash character varying(34) NOT NULL,
-    file_size integer DEFAULT 0,
-    last_updated timestamp with time zone NOT NULL
-);
+COMMENT ON COLUMN public.auth_attempts.kind IS 'kind of rate limited attempt, one of failed_signin or email_sent';
 
 
 --
--- Name: COLUMN build_mappings.id; Type: COMMENT; Schema: public; Owner: -
+-- Name: COLUMN auth_attempts.email; Type: COMMENT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.build_mappings.id IS 'unique id for each mapping file';
+COMMENT ON COLUMN public.auth_attempts.email IS 'lowercased email the attempt was made for';
 
 
 --
--- Name: COLUMN build_mappings.app_id; Type: COMMENT; Schema: public; Owner: -
+-- Name: COLUMN auth_attempts.ip; Type: COMMENT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.build_mappings.app_id IS 'linked app id';
+COMMENT ON COLUMN public.auth_attempts.ip IS 'ip address of the client making the attempt';
 
 
 --
--- Name: COLUMN build_mappings.version_name; Type: COMMENT; Schema: public; Owner: -
+-- Name: COLUMN auth_attempts.created_at; Type: COMMENT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.build_mappings.version_name IS 'user visible version number of the app';
+COMMENT ON COLUMN public.auth_attempts.created_at IS 'utc timestamp at the time of the attempt';
 
 
 --
--- Name: COLUMN build_mappings.version_code; Type: COMMENT; Schema: public; Owner: -
+-- Name: auth_sessions; Type: TABLE; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.build_mappings.version_code IS 'incremental build number of the app';
+CREATE TABLE public.auth_sessions (
+    id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    oauth_provider text,
+    user_metadata jsonb,
+    at_expiry_at timestamp with time zone NOT NULL,
+    rt_expiry_at timestamp with time zone NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
 
 
 --
--- Name: COLUMN build_mappings.mapping_type; Type: COMMENT; Schema: public; Owner: -
+-- Name: COLUMN auth_sessions.id; Type: COMMENT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.build_mappings.mapping_type IS 'type of the mapping file, like proguard etc';
+COMMENT ON COLUMN public.auth_sessions.id IS 'unique id of auth session';
 
 
 --
--- Name: COLUMN build_mappings.key; Type: COMMENT; Schema: public; Owner: -
+-- Name: COLUMN auth_sessions.user_id; Type: COMMENT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.build_mappings.key IS 'key of the mapping file stored in remote object store';
+COMMENT ON COLUMN public.auth_sessions.user_id IS 'user id of the session holder';
 
 
 --
--- Name: COLUMN build_mappings.location; Type: COMMENT; Schema: public; Owner: -
+-- Name: COLUMN auth_sessions.oauth_provider; Type: COMMENT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.build_mappings.location IS 'url of the mapping file stored in remote object store';
+COMMENT ON COLUMN public.auth_sessions.oauth_provider IS 'name of the oauth provider';
 
 
 --
--- Name: COLUMN build_mappings.fnv1_hash; Type: COMMENT; Schema: public; Owner: -
+-- Name: COLUMN auth_sessions.user_metadata; Type: COMMENT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.build_mappings.fnv1_hash IS '64 bit fnv1 hash of the mapping file bytes';
+COMMENT ON COLUMN public.auth_sessions.user_metadata IS 'arbitrary metadata associated with the user';
 
 
 --
--- Name: COLUMN build_mappings.file_size; Type: COMMENT; Schema: public; Owner: -
+-- Name: COLUMN auth_sessions.at_expiry_at; Type: COMMENT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.build_mappings.file_size IS 'size of mapping file in bytes';
+COMMENT ON COLUMN public.auth_sessions.at_expiry_at IS 'expiry time of access token';
 
 
 --
--- Name: COLUMN build_mappings.last_updated; Type: COMMENT; Schema: public; Owner: -
+-- Name: COLUMN auth_sessions.rt_expiry_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.auth_sessions.rt_expiry_at IS 'expiry time of refresh token';
+
+
+--
+-- Name: COLUMN auth_sessions.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.auth_sessions.created_at IS 'utc timestamp at the time of record creation';
+
+
+--
+-- Name: auth_states; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.auth_states (
+    id uuid NOT NULL,
+    state text NOT NULL,
+    oauth_provider text NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: COLUMN auth_states.id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.auth_states.id IS 'unique id of auth state';
+
+
+--
+-- Name: COLUMN auth_states.state; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.auth_states.state IS 'oauth state nonce';
+
+
+--
+-- Name: COLUMN auth_states.oauth_provider; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.auth_states.oauth_provider IS 'name of the social oauth provider';
+
+
+--
+-- Name: COLUMN auth_states.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.auth_states.created_at IS 'utc timestamp at the time of record creation';
+
+
+--
+-- Name: COLUMN auth_states.updated_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.auth_states.updated_at IS 'utc timestamp at the time of record updation';
+
+
+--
+-- Name: auth_tokens; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.auth_tokens (
+    id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    purpose character varying(32) NOT NULL,
+    token_hash character varying(64) NOT NULL,
+    expires_at timestamp with time zone NOT NULL,
+    used_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: COLUMN auth_tokens.id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.auth_tokens.id IS 'unique id for each one time token';
+
+
+--
+-- Name: COLUMN auth_tokens.user_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.auth_tokens.user_id IS 'id of the user the token was issued to';
+
+
+--
+-- Name: COLUMN auth_tokens.purpose; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.auth_tokens.purpose IS 'what the token can be used for, one of verify_email, reset_password or magic_link';
+
+
+--
+-- Name: COLUMN auth_tokens.token_hash; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.auth_tokens.token_hash IS 'sha256 hash of the token value';
+
+
+--
+-- Name: COLUMN auth_tokens.expires_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.auth_tokens.expires_at IS 'utc timestamp after which the token stops being accepted';
+
+
+--
+-- Name: COLUMN auth_tokens.used_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.auth_tokens.used_at IS 'utc timestamp at the time the token was used or invalidated';
+
+
+--
+-- Name: COLUMN auth_tokens.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.auth_tokens.created_at IS 'utc timestamp at the time of token creation';
+
+
+--
+-- Name: build_mapping_build_ids; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.build_mapping_build_ids (
+    mapping_id uuid NOT NULL,
+    app_id uuid NOT NULL,
+    build_id character varying(128) NOT NULL,
+    binary_name character varying(256) DEFAULT ''::character varying NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: COLUMN build_mapping_build_ids.mapping_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.build_mapping_build_ids.mapping_id IS 'linked build mapping id';
+
+
+--
+-- Name: COLUMN build_mapping_build_ids.app_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.build_mapping_build_ids.app_id IS 'linked app id';
+
+
+--
+-- Name: COLUMN build_mapping_build_ids.build_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.build_mapping_build_ids.build_id IS 'gnu build id of the binary in hex';
+
+
+--
+-- Name: COLUMN build_mapping_build_ids.binary_name; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.build_mapping_build_ids.binary_name IS 'name of the binary carrying the build id';
+
+
+--
+-- Name: COLUMN build_mapping_build_ids.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.build_mapping_build_ids.created_at IS 'utc timestamp at the time of record creation';
+
+
+--
+-- Name: build_mappings; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.build_mappings (
+    id uuid NOT NULL,
+    app_id uuid,
+    version_name character varying(256) NOT NULL,
+    version_code character varying(256) NOT NULL,
+    mapping_type character varying(32) NOT NULL,
+    key character varying(256) NOT NULL,
+    location character varying NOT NULL,
+    fnv1_hash character varying(34) NOT NULL,
+    file_size integer DEFAULT 0,
+    last_updated timestamp with time zone NOT NULL
+);
+
+
+--
+-- Name: COLUMN build_mappings.id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.build_mappings.id IS 'unique id for each mapping file';
+
+
+--
+-- Name: COLUMN build_mappings.app_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.build_mappings.app_id IS 'linked app id';
+
+
+--
+-- Name: COLUMN build_mappings.version_name; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.build_mappings.version_name IS 'user visible version number of the app';
+
+
+--
+-- Name: COLUMN build_mappings.version_code; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.build_mappings.version_code IS 'incremental build number of the app';
+
+
+--
+-- Name: COLUMN build_mappings.mapping_type; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.build_mappings.mapping_type IS 'type of the mapping file, like proguard etc';
+
+
+--
+-- Name: COLUMN build_mappings.key; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.build_mappings.key IS 'key of the mapping file stored in remote object store';
+
+
+--
+-- Name: COLUMN build_mappings.location; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.build_mappings.location IS 'url of the mapping file stored in remote object store';
+
+
+--
+-- Name: COLUMN build_mappings.fnv1_hash; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.build_mappings.fnv1_hash IS '64 bit fnv1 hash of the mapping file bytes';
+
+
+--
+-- Name: COLUMN build_mappings.file_size; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.build_mappings.file_size IS 'size of mapping file in bytes';
+
+
+--
+-- Name: COLUMN build_mappings.last_updated; Type: COMMENT; Schema: public; Owner: -
 --
 
 COMMENT ON COLUMN public.build_mappings.last_updated IS 'utc timestamp at the time of mapping file upload';
@@ -689,7 +1178,9 @@ CREATE TABLE public.event_reqs (
     session_count integer DEFAULT 0,
     bytes_in integer DEFAULT 0,
     symbolication_attempts_count integer DEFAULT 0,
-    created_at timestamp with time zone DEFAULT now() NOT NULL
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    bytes_in_wire integer,
+    content_encoding character varying(16)
 );
 
 
@@ -732,575 +1223,2290 @@ COMMENT ON COLUMN public.event_reqs.session_count IS 'number of sessions in the
 -- Name: COLUMN event_reqs.bytes_in; Type: COMMENT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.event_reqs.bytes_in IS 'total payload size of the request';
+COMMENT ON COLUMN public.event_reqs.bytes_in IS 'total payload size of the request';
+
+
+--
+-- Name: COLUMN event_reqs.symbolication_attempts_count; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.event_reqs.symbolication_attempts_count IS 'number of times symbolication was attempted for this event request';
+
+
+--
+-- Name: COLUMN event_reqs.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.event_reqs.created_at IS 'utc timestamp at the time of record creation';
+
+
+--
+-- Name: COLUMN event_reqs.bytes_in_wire; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.event_reqs.bytes_in_wire IS 'size of the request body as received, before decompression';
+
+
+--
+-- Name: COLUMN event_reqs.content_encoding; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.event_reqs.content_encoding IS 'content encoding of the request body, one of identity, gzip or zstd';
+
+
+--
+-- Name: fingerprint_rules; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.fingerprint_rules (
+    app_id uuid NOT NULL,
+    version integer DEFAULT 1 NOT NULL,
+    skip_frame_prefixes text[] DEFAULT '{}'::text[] NOT NULL,
+    in_app_frame_prefixes text[] DEFAULT '{}'::text[] NOT NULL,
+    in_app_frames integer DEFAULT 1 NOT NULL,
+    include_message boolean DEFAULT false NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: COLUMN fingerprint_rules.app_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.fingerprint_rules.app_id IS 'linked app id';
+
+
+--
+-- Name: COLUMN fingerprint_rules.version; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.fingerprint_rules.version IS 'version of the fingerprinting algorithm';
+
+
+--
+-- Name: COLUMN fingerprint_rules.skip_frame_prefixes; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.fingerprint_rules.skip_frame_prefixes IS 'frames whose class and method start with any of these prefixes are excluded from fingerprints';
+
+
+--
+-- Name: COLUMN fingerprint_rules.in_app_frame_prefixes; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.fingerprint_rules.in_app_frame_prefixes IS 'frames whose class and method start with any of these prefixes are preferred for fingerprints';
+
+
+--
+-- Name: COLUMN fingerprint_rules.in_app_frames; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.fingerprint_rules.in_app_frames IS 'number of frames that contribute to fingerprints';
+
+
+--
+-- Name: COLUMN fingerprint_rules.include_message; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.fingerprint_rules.include_message IS 'if true, message template with variable parts stripped contributes to fingerprints';
+
+
+--
+-- Name: COLUMN fingerprint_rules.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.fingerprint_rules.created_at IS 'utc timestamp at the time of record creation';
+
+
+--
+-- Name: COLUMN fingerprint_rules.updated_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.fingerprint_rules.updated_at IS 'utc timestamp at the time of record update';
+
+
+--
+-- Name: ingest_job_blobs; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.ingest_job_blobs (
+    id uuid NOT NULL,
+    job_id uuid NOT NULL,
+    name text NOT NULL,
+    data bytea NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: COLUMN ingest_job_blobs.id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.ingest_job_blobs.id IS 'id of the attachment blob';
+
+
+--
+-- Name: COLUMN ingest_job_blobs.job_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.ingest_job_blobs.job_id IS 'linked ingest job id';
+
+
+--
+-- Name: COLUMN ingest_job_blobs.name; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.ingest_job_blobs.name IS 'original file name of the blob';
+
+
+--
+-- Name: COLUMN ingest_job_blobs.data; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.ingest_job_blobs.data IS 'raw bytes of the blob, removed once the job completes';
+
+
+--
+-- Name: COLUMN ingest_job_blobs.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.ingest_job_blobs.created_at IS 'utc timestamp at the time of record creation';
+
+
+--
+-- Name: ingest_jobs; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.ingest_jobs (
+    id uuid NOT NULL,
+    app_id uuid NOT NULL,
+    status text NOT NULL,
+    events jsonb NOT NULL,
+    event_count integer DEFAULT 0 NOT NULL,
+    attachment_count integer DEFAULT 0 NOT NULL,
+    bytes_in bigint DEFAULT 0 NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    error text,
+    next_attempt_at timestamp with time zone DEFAULT now() NOT NULL,
+    ingested_at timestamp with time zone,
+    completed_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL,
+    bytes_in_wire bigint DEFAULT 0 NOT NULL,
+    content_encoding character varying(16) DEFAULT 'identity'::character varying NOT NULL,
+    CONSTRAINT ingest_jobs_status_check CHECK ((status = ANY (ARRAY['queued'::text, 'processing'::text, 'succeeded'::text, 'failed'::text])))
+);
+
+
+--
+-- Name: COLUMN ingest_jobs.id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.ingest_jobs.id IS 'id of the event request, same as the msr-req-id header';
+
+
+--
+-- Name: COLUMN ingest_jobs.app_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.ingest_jobs.app_id IS 'id of the associated app';
+
+
+--
+-- Name: COLUMN ingest_jobs.status; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.ingest_jobs.status IS 'status of the job, one of queued, processing, succeeded or failed';
+
+
+--
+-- Name: COLUMN ingest_jobs.events; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.ingest_jobs.events IS 'validated events of the event request, cleared once the job succeeds';
+
+
+--
+-- Name: COLUMN ingest_jobs.event_count; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.ingest_jobs.event_count IS 'number of events in the event request';
+
+
+--
+-- Name: COLUMN ingest_jobs.attachment_count; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.ingest_jobs.attachment_count IS 'number of attachments in the event request';
+
+
+--
+-- Name: COLUMN ingest_jobs.bytes_in; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.ingest_jobs.bytes_in IS 'total payload size of the request';
+
+
+--
+-- Name: COLUMN ingest_jobs.attempts; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.ingest_jobs.attempts IS 'number of processing attempts made';
+
+
+--
+-- Name: COLUMN ingest_jobs.error; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.ingest_jobs.error IS 'error of the last failed attempt';
+
+
+--
+-- Name: COLUMN ingest_jobs.next_attempt_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.ingest_jobs.next_attempt_at IS 'utc timestamp after which the job can be picked up by a worker';
+
+
+--
+-- Name: COLUMN ingest_jobs.ingested_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.ingest_jobs.ingested_at IS 'utc timestamp at the time the events were written to clickhouse, retries skip ingestion once set';
+
+
+--
+-- Name: COLUMN ingest_jobs.completed_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.ingest_jobs.completed_at IS 'utc timestamp at the time the job succeeded or finally failed';
+
+
+--
+-- Name: COLUMN ingest_jobs.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.ingest_jobs.created_at IS 'utc timestamp at the time of record creation';
+
+
+--
+-- Name: COLUMN ingest_jobs.updated_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.ingest_jobs.updated_at IS 'utc timestamp at the time of record update';
+
+
+--
+-- Name: COLUMN ingest_jobs.bytes_in_wire; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.ingest_jobs.bytes_in_wire IS 'size of the request body as received, before decompression';
+
+
+--
+-- Name: COLUMN ingest_jobs.content_encoding; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.ingest_jobs.content_encoding IS 'content encoding of the request body, one of identity, gzip or zstd';
+
+
+--
+-- Name: invites; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.invites (
+    id uuid NOT NULL,
+    team_id uuid NOT NULL,
+    email character varying(256) NOT NULL,
+    role character varying(32) NOT NULL,
+    token_hash character varying(64) NOT NULL,
+    invited_by uuid,
+    expires_at timestamp with time zone NOT NULL,
+    accepted_at timestamp with time zone,
+    declined_at timestamp with time zone,
+    revoked_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: COLUMN invites.id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.invites.id IS 'unique id for each invite';
+
+
+--
+-- Name: COLUMN invites.team_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.invites.team_id IS 'id of the team the invitee is invited to';
+
+
+--
+-- Name: COLUMN invites.email; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.invites.email IS 'lowercased email of the invitee';
+
+
+--
+-- Name: COLUMN invites.role; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.invites.role IS 'role the invitee joins the team with';
+
+
+--
+-- Name: COLUMN invites.token_hash; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.invites.token_hash IS 'sha256 hash of the invite token';
+
+
+--
+-- Name: COLUMN invites.invited_by; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.invites.invited_by IS 'id of the user who sent the invite';
+
+
+--
+-- Name: COLUMN invites.expires_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.invites.expires_at IS 'utc timestamp after which the invite cannot be accepted';
+
+
+--
+-- Name: COLUMN invites.accepted_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.invites.accepted_at IS 'utc timestamp at the time the invite was accepted';
+
+
+--
+-- Name: COLUMN invites.declined_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.invites.declined_at IS 'utc timestamp at the time the invite was declined';
+
+
+--
+-- Name: COLUMN invites.revoked_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.invites.revoked_at IS 'utc timestamp at the time the invite was revoked';
+
+
+--
+-- Name: COLUMN invites.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.invites.created_at IS 'utc timestamp at the time of invite creation';
+
+
+--
+-- Name: COLUMN invites.updated_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.invites.updated_at IS 'utc timestamp at the time the invite was last sent';
+
+
+--
+-- Name: personal_access_tokens; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.personal_access_tokens (
+    id uuid NOT NULL,
+    user_id uuid NOT NULL,
+    name character varying(256) NOT NULL,
+    hint character varying(16) NOT NULL,
+    token_hash character varying(64) NOT NULL,
+    scopes text[] NOT NULL,
+    expires_at timestamp with time zone NOT NULL,
+    last_used_at timestamp with time zone,
+    revoked_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: COLUMN personal_access_tokens.id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.personal_access_tokens.id IS 'unique id for each personal access token';
+
+
+--
+-- Name: COLUMN personal_access_tokens.user_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.personal_access_tokens.user_id IS 'id of the user the token acts on behalf of';
+
+
+--
+-- Name: COLUMN personal_access_tokens.name; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.personal_access_tokens.name IS 'user provided label for the token';
+
+
+--
+-- Name: COLUMN personal_access_tokens.hint; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.personal_access_tokens.hint IS 'first few characters of the token value to help identify it';
+
+
+--
+-- Name: COLUMN personal_access_tokens.token_hash; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.personal_access_tokens.token_hash IS 'sha256 hash of the token value';
+
+
+--
+-- Name: COLUMN personal_access_tokens.scopes; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.personal_access_tokens.scopes IS 'list of scopes granted to the token';
+
+
+--
+-- Name: COLUMN personal_access_tokens.expires_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.personal_access_tokens.expires_at IS 'utc timestamp after which the token stops being accepted';
+
+
+--
+-- Name: COLUMN personal_access_tokens.last_used_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.personal_access_tokens.last_used_at IS 'utc timestamp at the time of last token usage seen';
+
+
+--
+-- Name: COLUMN personal_access_tokens.revoked_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.personal_access_tokens.revoked_at IS 'utc timestamp at the time of token revocation';
+
+
+--
+-- Name: COLUMN personal_access_tokens.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.personal_access_tokens.created_at IS 'utc timestamp at the time of token creation';
+
+
+--
+-- Name: purge_jobs; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.purge_jobs (
+    id uuid NOT NULL,
+    team_id uuid NOT NULL,
+    app_id uuid,
+    requested_by uuid,
+    status text NOT NULL,
+    step character varying(32),
+    apps_count integer DEFAULT 0 NOT NULL,
+    apps_purged integer DEFAULT 0 NOT NULL,
+    attachments_purged bigint DEFAULT 0 NOT NULL,
+    events_purged bigint DEFAULT 0 NOT NULL,
+    mappings_purged bigint DEFAULT 0 NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    error text,
+    next_attempt_at timestamp with time zone DEFAULT now() NOT NULL,
+    completed_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT purge_jobs_status_check CHECK ((status = ANY (ARRAY['queued'::text, 'processing'::text, 'succeeded'::text, 'failed'::text])))
+);
+
+
+--
+-- Name: COLUMN purge_jobs.id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.purge_jobs.id IS 'unique id for each purge job';
+
+
+--
+-- Name: COLUMN purge_jobs.team_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.purge_jobs.team_id IS 'id of the deleted team, or of the team of the deleted app. not a foreign key, the team row is removed by the purge';
+
+
+--
+-- Name: COLUMN purge_jobs.app_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.purge_jobs.app_id IS 'id of the deleted app, null when the whole team was deleted. not a foreign key, the app row is removed by the purge';
+
+
+--
+-- Name: COLUMN purge_jobs.requested_by; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.purge_jobs.requested_by IS 'id of the user who deleted the team or app';
+
+
+--
+-- Name: COLUMN purge_jobs.status; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.purge_jobs.status IS 'status of the job, one of queued, processing, succeeded or failed';
+
+
+--
+-- Name: COLUMN purge_jobs.step; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.purge_jobs.step IS 'step of the purge being processed';
+
+
+--
+-- Name: COLUMN purge_jobs.apps_count; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.purge_jobs.apps_count IS 'number of apps to purge';
+
+
+--
+-- Name: COLUMN purge_jobs.apps_purged; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.purge_jobs.apps_purged IS 'number of apps purged so far';
+
+
+--
+-- Name: COLUMN purge_jobs.attachments_purged; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.purge_jobs.attachments_purged IS 'number of attachments removed from object storage so far';
+
+
+--
+-- Name: COLUMN purge_jobs.events_purged; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.purge_jobs.events_purged IS 'number of events removed so far';
+
+
+--
+-- Name: COLUMN purge_jobs.mappings_purged; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.purge_jobs.mappings_purged IS 'number of build mappings removed from object storage so far';
+
+
+--
+-- Name: COLUMN purge_jobs.attempts; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.purge_jobs.attempts IS 'number of processing attempts made';
+
+
+--
+-- Name: COLUMN purge_jobs.error; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.purge_jobs.error IS 'error of the last failed attempt';
+
+
+--
+-- Name: COLUMN purge_jobs.next_attempt_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.purge_jobs.next_attempt_at IS 'utc timestamp after which the job can be picked up by the worker';
+
+
+--
+-- Name: COLUMN purge_jobs.completed_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.purge_jobs.completed_at IS 'utc timestamp at the time the job succeeded or finally failed';
+
+
+--
+-- Name: COLUMN purge_jobs.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.purge_jobs.created_at IS 'utc timestamp at the time of record creation';
+
+
+--
+-- Name: COLUMN purge_jobs.updated_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.purge_jobs.updated_at IS 'utc timestamp at the time of record update';
+
+
+--
+-- Name: quotas; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.quotas (
+    id uuid DEFAULT gen_random_uuid() NOT NULL,
+    team_id uuid,
+    app_id uuid,
+    max_events bigint,
+    max_sessions bigint,
+    max_attachment_bytes bigint,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT quotas_check CHECK (((team_id IS NULL) <> (app_id IS NULL))),
+    CONSTRAINT quotas_max_attachment_bytes_check CHECK ((max_attachment_bytes >= 0)),
+    CONSTRAINT quotas_max_events_check CHECK ((max_events >= 0)),
+    CONSTRAINT quotas_max_sessions_check CHECK ((max_sessions >= 0))
+);
+
+
+--
+-- Name: COLUMN quotas.id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.quotas.id IS 'unique id for each quota';
+
+
+--
+-- Name: COLUMN quotas.team_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.quotas.team_id IS 'linked team id, set for quotas shared by all apps of a team';
+
+
+--
+-- Name: COLUMN quotas.app_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.quotas.app_id IS 'linked app id, set for quotas of a single app';
+
+
+--
+-- Name: COLUMN quotas.max_events; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.quotas.max_events IS 'maximum events accepted in a calendar month, null for unlimited';
+
+
+--
+-- Name: COLUMN quotas.max_sessions; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.quotas.max_sessions IS 'maximum sessions accepted in a calendar month, null for unlimited';
+
+
+--
+-- Name: COLUMN quotas.max_attachment_bytes; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.quotas.max_attachment_bytes IS 'maximum attachment bytes accepted in a calendar month, null for unlimited';
+
+
+--
+-- Name: COLUMN quotas.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.quotas.created_at IS 'utc timestamp at the time of record creation';
+
+
+--
+-- Name: COLUMN quotas.updated_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.quotas.updated_at IS 'utc timestamp at the time of record update';
+
+
+--
+-- Name: roles; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.roles (
+    name character varying(256) NOT NULL,
+    scopes text[],
+    CONSTRAINT roles_name_check CHECK (((name)::text = ANY ((ARRAY['owner'::character varying, 'admin'::character varying, 'developer'::character varying, 'viewer'::character varying])::text[])))
+);
+
+
+--
+-- Name: COLUMN roles.name; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.roles.name IS 'unique role name';
+
+
+--
+-- Name: COLUMN roles.scopes; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.roles.scopes IS 'valid scopes for this role';
+
+
+--
+-- Name: symbolication_jobs; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.symbolication_jobs (
+    id uuid NOT NULL,
+    app_id uuid NOT NULL,
+    version_name character varying(256) NOT NULL,
+    version_code character varying(256) NOT NULL,
+    mapping_type character varying(32) NOT NULL,
+    status text NOT NULL,
+    symbolicated_count integer DEFAULT 0 NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    error text,
+    next_attempt_at timestamp with time zone DEFAULT now() NOT NULL,
+    completed_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT symbolication_jobs_status_check CHECK ((status = ANY (ARRAY['queued'::text, 'processing'::text, 'succeeded'::text, 'failed'::text])))
+);
+
+
+--
+-- Name: COLUMN symbolication_jobs.id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.symbolication_jobs.id IS 'unique id for each symbolication job';
+
+
+--
+-- Name: COLUMN symbolication_jobs.app_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.symbolication_jobs.app_id IS 'id of the associated app';
+
+
+--
+-- Name: COLUMN symbolication_jobs.version_name; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.symbolication_jobs.version_name IS 'app version of the events to symbolicate';
+
+
+--
+-- Name: COLUMN symbolication_jobs.version_code; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.symbolication_jobs.version_code IS 'app build of the events to symbolicate';
+
+
+--
+-- Name: COLUMN symbolication_jobs.mapping_type; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.symbolication_jobs.mapping_type IS 'type of the uploaded mapping file';
+
+
+--
+-- Name: COLUMN symbolication_jobs.status; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.symbolication_jobs.status IS 'status of the job, one of queued, processing, succeeded or failed';
+
+
+--
+-- Name: COLUMN symbolication_jobs.symbolicated_count; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.symbolication_jobs.symbolicated_count IS 'number of events symbolicated so far';
+
+
+--
+-- Name: COLUMN symbolication_jobs.attempts; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.symbolication_jobs.attempts IS 'number of processing attempts made';
+
+
+--
+-- Name: COLUMN symbolication_jobs.error; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.symbolication_jobs.error IS 'error of the last failed attempt';
+
+
+--
+-- Name: COLUMN symbolication_jobs.next_attempt_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.symbolication_jobs.next_attempt_at IS 'utc timestamp after which the job can be picked up by the worker';
+
+
+--
+-- Name: COLUMN symbolication_jobs.completed_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.symbolication_jobs.completed_at IS 'utc timestamp at the time the job succeeded or finally failed';
+
+
+--
+-- Name: COLUMN symbolication_jobs.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.symbolication_jobs.created_at IS 'utc timestamp at the time of record creation';
+
+
+--
+-- Name: COLUMN symbolication_jobs.updated_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.symbolication_jobs.updated_at IS 'utc timestamp at the time of record update';
+
+
+--
+-- Name: team_membership; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.team_membership (
+    id uuid DEFAULT gen_random_uuid() NOT NULL,
+    team_id uuid,
+    user_id uuid,
+    role character varying(256),
+    role_updated_at timestamp with time zone NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
+
+
+--
+-- Name: COLUMN team_membership.id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.team_membership.id IS 'unique id for each team membership';
+
+
+--
+-- Name: COLUMN team_membership.team_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.team_membership.team_id IS 'team id to which user is a member of';
+
+
+--
+-- Name: COLUMN team_membership.user_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.team_membership.user_id IS 'user id of user having membership of team';
+
+
+--
+-- Name: COLUMN team_membership.role; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.team_membership.role IS 'role of the invitee';
+
+
+--
+-- Name: COLUMN team_membership.role_updated_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.team_membership.role_updated_at IS 'utc timestamp at the time of role change';
+
+
+--
+-- Name: COLUMN team_membership.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.team_membership.created_at IS 'utc timestamp at the time of team membership';
+
+
+--
+-- Name: teams; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.teams (
+    id uuid DEFAULT gen_random_uuid() NOT NULL,
+    name character varying(256) NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone NOT NULL,
+    deleted_at timestamp with time zone
+);
+
+
+--
+-- Name: COLUMN teams.id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.teams.id IS 'unique id for each team';
+
+
+--
+-- Name: COLUMN teams.name; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.teams.name IS 'name of the team';
+
+
+--
+-- Name: COLUMN teams.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.teams.created_at IS 'utc timestamp at the time of team creation';
+
+
+--
+-- Name: COLUMN teams.updated_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.teams.updated_at IS 'utc timestmap at the time of team name update';
+
+
+--
+-- Name: COLUMN teams.deleted_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.teams.deleted_at IS 'utc timestamp at the time the team was deleted, its data is purged in the background';
+
+
+--
+-- Name: unhandled_exception_groups; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.unhandled_exception_groups (
+    id uuid NOT NULL,
+    app_id uuid,
+    type text NOT NULL,
+    message text NOT NULL,
+    method_name text NOT NULL,
+    file_name text NOT NULL,
+    line_number integer NOT NULL,
+    fingerprint character varying(32) NOT NULL,
+    event_ids uuid[] NOT NULL,
+    first_event_timestamp timestamp with time zone NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL,
+    status text DEFAULT 'open'::text NOT NULL,
+    resolved_in_version text,
+    resolved_in_version_code text,
+    muted_until timestamp with time zone,
+    regressed_at timestamp with time zone,
+    fingerprint_version integer DEFAULT 1 NOT NULL,
+    merged_into uuid,
+    CONSTRAINT unhandled_exception_groups_status_check CHECK ((status = ANY (ARRAY['open'::text, 'resolved'::text, 'ignored'::text, 'muted'::text])))
+);
+
+
+--
+-- Name: COLUMN unhandled_exception_groups.id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.unhandled_exception_groups.id IS 'sortable unique id (uuidv7) for each unhandled exception group';
+
+
+--
+-- Name: COLUMN unhandled_exception_groups.app_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.unhandled_exception_groups.app_id IS 'linked app id';
+
+
+--
+-- Name: COLUMN unhandled_exception_groups.type; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.unhandled_exception_groups.type IS 'type of the exception';
+
+
+--
+-- Name: COLUMN unhandled_exception_groups.message; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.unhandled_exception_groups.message IS 'message of the exception';
+
+
+--
+-- Name: COLUMN unhandled_exception_groups.method_name; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.unhandled_exception_groups.method_name IS 'method name where the exception occured';
+
+
+--
+-- Name: COLUMN unhandled_exception_groups.file_name; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.unhandled_exception_groups.file_name IS 'file name where the exception occured';
+
+
+--
+-- Name: COLUMN unhandled_exception_groups.line_number; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.unhandled_exception_groups.line_number IS 'line number where the exception occured';
+
+
+--
+-- Name: COLUMN unhandled_exception_groups.fingerprint; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.unhandled_exception_groups.fingerprint IS 'fingerprint of the unhandled exception';
+
+
+--
+-- Name: COLUMN unhandled_exception_groups.event_ids; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.unhandled_exception_groups.event_ids IS 'list of associated event ids';
+
+
+--
+-- Name: COLUMN unhandled_exception_groups.first_event_timestamp; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.unhandled_exception_groups.first_event_timestamp IS 'utc timestamp of the oldest event in the group';
+
+
+--
+-- Name: COLUMN unhandled_exception_groups.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.unhandled_exception_groups.created_at IS 'utc timestamp at the time of record creation';
+
+
+--
+-- Name: COLUMN unhandled_exception_groups.updated_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.unhandled_exception_groups.updated_at IS 'utc timestamp at the time of record updation';
+
+
+--
+-- Name: COLUMN unhandled_exception_groups.status; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.unhandled_exception_groups.status IS 'triage status of the group, one of open, resolved, ignored or muted';
+
+
+--
+-- Name: COLUMN unhandled_exception_groups.resolved_in_version; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.unhandled_exception_groups.resolved_in_version IS 'app version name the group was resolved in';
+
+
+--
+-- Name: COLUMN unhandled_exception_groups.resolved_in_version_code; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.unhandled_exception_groups.resolved_in_version_code IS 'app version code the group was resolved in';
+
+
+--
+-- Name: COLUMN unhandled_exception_groups.muted_until; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.unhandled_exception_groups.muted_until IS 'utc timestamp until which the group is muted';
+
+
+--
+-- Name: COLUMN unhandled_exception_groups.regressed_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.unhandled_exception_groups.regressed_at IS 'utc timestamp of the last time the resolved group was reopened by a regression';
+
+
+--
+-- Name: COLUMN unhandled_exception_groups.fingerprint_version; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.unhandled_exception_groups.fingerprint_version IS 'version of the fingerprinting algorithm that computed the fingerprint';
+
+
+--
+-- Name: COLUMN unhandled_exception_groups.merged_into; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.unhandled_exception_groups.merged_into IS 'id of the group this group was merged into, events of this group belong to the surviving group';
+
+
+--
+-- Name: usage_counters; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.usage_counters (
+    app_id uuid NOT NULL,
+    month date NOT NULL,
+    events bigint DEFAULT 0 NOT NULL,
+    sessions bigint DEFAULT 0 NOT NULL,
+    attachment_bytes bigint DEFAULT 0 NOT NULL,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: COLUMN usage_counters.app_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.usage_counters.app_id IS 'linked app id';
+
+
+--
+-- Name: COLUMN usage_counters.month; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.usage_counters.month IS 'first day of the utc calendar month counted';
+
+
+--
+-- Name: COLUMN usage_counters.events; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.usage_counters.events IS 'number of events accepted in the month';
+
+
+--
+-- Name: COLUMN usage_counters.sessions; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.usage_counters.sessions IS 'number of distinct sessions accepted in the month';
+
+
+--
+-- Name: COLUMN usage_counters.attachment_bytes; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.usage_counters.attachment_bytes IS 'size of attachments accepted in the month in bytes';
+
+
+--
+-- Name: COLUMN usage_counters.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.usage_counters.created_at IS 'utc timestamp at the time of record creation';
+
+
+--
+-- Name: COLUMN usage_counters.updated_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.usage_counters.updated_at IS 'utc timestamp at the time of record update';
+
+
+--
+-- Name: usage_sessions; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.usage_sessions (
+    app_id uuid NOT NULL,
+    month date NOT NULL,
+    session_id uuid NOT NULL
+);
+
+
+--
+-- Name: COLUMN usage_sessions.app_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.usage_sessions.app_id IS 'linked app id';
+
+
+--
+-- Name: COLUMN usage_sessions.month; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.usage_sessions.month IS 'first day of the utc calendar month the session was counted in';
+
+
+--
+-- Name: COLUMN usage_sessions.session_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.usage_sessions.session_id IS 'id of the session counted';
+
+
+--
+-- Name: users; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.users (
+    id uuid NOT NULL,
+    name character varying(256),
+    email character varying(256) NOT NULL,
+    invited_by_user_id uuid,
+    invited_to_team_id uuid,
+    invited_as_role character varying(256),
+    confirmed_at timestamp with time zone,
+    last_sign_in_at timestamp with time zone NOT NULL,
+    created_at timestamp with time zone NOT NULL,
+    updated_at timestamp with time zone NOT NULL,
+    password_hash character varying(72),
+    password_updated_at timestamp with time zone
+);
+
+
+--
+-- Name: COLUMN users.id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.users.id IS 'unique id for each user';
+
+
+--
+-- Name: COLUMN users.name; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.users.name IS 'name of the user';
+
+
+--
+-- Name: COLUMN users.invited_by_user_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.users.invited_by_user_id IS 'id of user who invited this user';
+
+
+--
+-- Name: COLUMN users.invited_to_team_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.users.invited_to_team_id IS 'id of team to which this user was invited';
+
+
+--
+-- Name: COLUMN users.invited_as_role; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.users.invited_as_role IS 'role as which this user was invited';
+
+
+--
+-- Name: COLUMN users.confirmed_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.users.confirmed_at IS 'utc timestamp at which user was confirmed';
+
+
+--
+-- Name: COLUMN users.last_sign_in_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.users.last_sign_in_at IS 'utc timestamp at the time of last user sign in';
+
+
+--
+-- Name: COLUMN users.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.users.created_at IS 'utc timestamp at the time of user creation';
+
+
+--
+-- Name: COLUMN users.updated_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.users.updated_at IS 'utc timestmap at the time of user update';
+
+
+--
+-- Name: COLUMN users.password_hash; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.users.password_hash IS 'bcrypt hash of the user''s password, null if the user never set one';
+
+
+--
+-- Name: COLUMN users.password_updated_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.users.password_updated_at IS 'utc timestamp at the time of last password change';
+
+
+--
+-- Name: webhook_deliveries; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.webhook_deliveries (
+    id uuid NOT NULL,
+    webhook_id uuid NOT NULL,
+    event text NOT NULL,
+    payload jsonb NOT NULL,
+    status text NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    response_code integer,
+    error text,
+    next_attempt_at timestamp with time zone,
+    delivered_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL,
+    CONSTRAINT webhook_deliveries_status_check CHECK ((status = ANY (ARRAY['pending'::text, 'succeeded'::text, 'failed'::text])))
+);
+
+
+--
+-- Name: COLUMN webhook_deliveries.id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhook_deliveries.id IS 'sortable unique id (uuidv7) for each delivery';
+
+
+--
+-- Name: COLUMN webhook_deliveries.webhook_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhook_deliveries.webhook_id IS 'linked webhook id';
+
+
+--
+-- Name: COLUMN webhook_deliveries.event; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhook_deliveries.event IS 'type of the delivered event';
+
+
+--
+-- Name: COLUMN webhook_deliveries.payload; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhook_deliveries.payload IS 'json payload of the delivery';
+
+
+--
+-- Name: COLUMN webhook_deliveries.status; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhook_deliveries.status IS 'status of the delivery, one of pending, succeeded or failed';
+
+
+--
+-- Name: COLUMN webhook_deliveries.attempts; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhook_deliveries.attempts IS 'number of delivery attempts made';
+
+
+--
+-- Name: COLUMN webhook_deliveries.response_code; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhook_deliveries.response_code IS 'http status code of the last attempt';
+
+
+--
+-- Name: COLUMN webhook_deliveries.error; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhook_deliveries.error IS 'error of the last failed attempt';
+
+
+--
+-- Name: COLUMN webhook_deliveries.next_attempt_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhook_deliveries.next_attempt_at IS 'utc timestamp of the next retry attempt';
+
+
+--
+-- Name: COLUMN webhook_deliveries.delivered_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhook_deliveries.delivered_at IS 'utc timestamp of the successful delivery';
+
+
+--
+-- Name: COLUMN webhook_deliveries.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhook_deliveries.created_at IS 'utc timestamp at the time of record creation';
+
+
+--
+-- Name: COLUMN webhook_deliveries.updated_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhook_deliveries.updated_at IS 'utc timestamp at the time of record update';
+
+
+--
+-- Name: webhooks; Type: TABLE; Schema: public; Owner: -
+--
+
+CREATE TABLE public.webhooks (
+    id uuid NOT NULL,
+    app_id uuid NOT NULL,
+    url text NOT NULL,
+    secret text NOT NULL,
+    events text[] NOT NULL,
+    volume_threshold integer DEFAULT 0 NOT NULL,
+    active boolean DEFAULT true NOT NULL,
+    created_by uuid,
+    created_at timestamp with time zone DEFAULT now() NOT NULL,
+    updated_at timestamp with time zone DEFAULT now() NOT NULL
+);
+
+
+--
+-- Name: COLUMN webhooks.id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhooks.id IS 'sortable unique id (uuidv7) for each webhook';
+
+
+--
+-- Name: COLUMN webhooks.app_id; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhooks.app_id IS 'linked app id';
+
+
+--
+-- Name: COLUMN webhooks.url; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhooks.url IS 'endpoint url receiving webhook payloads';
+
+
+--
+-- Name: COLUMN webhooks.secret; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhooks.secret IS 'secret used to compute hmac signature of payloads';
+
+
+--
+-- Name: COLUMN webhooks.events; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhooks.events IS 'list of subscribed event types';
+
+
+--
+-- Name: COLUMN webhooks.volume_threshold; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhooks.volume_threshold IS 'number of events in a group at which volume threshold event is delivered, 0 disables';
+
+
+--
+-- Name: COLUMN webhooks.active; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhooks.active IS 'if true, payloads are delivered to the webhook';
+
+
+--
+-- Name: COLUMN webhooks.created_by; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhooks.created_by IS 'id of user who created the webhook';
+
+
+--
+-- Name: COLUMN webhooks.created_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhooks.created_at IS 'utc timestamp at the time of record creation';
+
+
+--
+-- Name: COLUMN webhooks.updated_at; Type: COMMENT; Schema: public; Owner: -
+--
+
+COMMENT ON COLUMN public.webhooks.updated_at IS 'utc timestamp at the time of record update';
+
+
+--
+-- Name: alert_prefs alert_prefs_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.alert_prefs
+    ADD CONSTRAINT alert_prefs_pkey PRIMARY KEY (app_id, user_id);
+
+
+--
+-- Name: alerts alerts_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.alerts
+    ADD CONSTRAINT alerts_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: anr_groups anr_groups_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.anr_groups
+    ADD CONSTRAINT anr_groups_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: api_keys api_keys_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.api_keys
+    ADD CONSTRAINT api_keys_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: app_roles app_roles_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.app_roles
+    ADD CONSTRAINT app_roles_pkey PRIMARY KEY (app_id, user_id);
+
+
+--
+-- Name: app_settings app_settings_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.app_settings
+    ADD CONSTRAINT app_settings_pkey PRIMARY KEY (app_id);
+
+
+--
+-- Name: apps apps_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.apps
+    ADD CONSTRAINT apps_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: audit_logs audit_logs_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.audit_logs
+    ADD CONSTRAINT audit_logs_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: auth_attempts auth_attempts_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.auth_attempts
+    ADD CONSTRAINT auth_attempts_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: auth_sessions auth_sessions_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.auth_sessions
+    ADD CONSTRAINT auth_sessions_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: auth_states auth_states_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.auth_states
+    ADD CONSTRAINT auth_states_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: auth_tokens auth_tokens_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.auth_tokens
+    ADD CONSTRAINT auth_tokens_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: build_mapping_build_ids build_mapping_build_ids_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.build_mapping_build_ids
+    ADD CONSTRAINT build_mapping_build_ids_pkey PRIMARY KEY (mapping_id, build_id);
+
+
+--
+-- Name: build_mappings build_mappings_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.build_mappings
+    ADD CONSTRAINT build_mappings_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: build_sizes build_sizes_app_id_version_name_version_code_build_type_key; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.build_sizes
+    ADD CONSTRAINT build_sizes_app_id_version_name_version_code_build_type_key UNIQUE (app_id, version_name, version_code, build_type);
+
+
+--
+-- Name: build_sizes build_sizes_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.build_sizes
+    ADD CONSTRAINT build_sizes_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: event_reqs event_reqs_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.event_reqs
+    ADD CONSTRAINT event_reqs_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: fingerprint_rules fingerprint_rules_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.fingerprint_rules
+    ADD CONSTRAINT fingerprint_rules_pkey PRIMARY KEY (app_id);
+
+
+--
+-- Name: ingest_job_blobs ingest_job_blobs_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ingest_job_blobs
+    ADD CONSTRAINT ingest_job_blobs_pkey PRIMARY KEY (job_id, id);
+
+
+--
+-- Name: ingest_jobs ingest_jobs_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.ingest_jobs
+    ADD CONSTRAINT ingest_jobs_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: invites invites_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.invites
+    ADD CONSTRAINT invites_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: personal_access_tokens personal_access_tokens_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.personal_access_tokens
+    ADD CONSTRAINT personal_access_tokens_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: purge_jobs purge_jobs_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.purge_jobs
+    ADD CONSTRAINT purge_jobs_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: quotas quotas_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.quotas
+    ADD CONSTRAINT quotas_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: roles roles_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.roles
+    ADD CONSTRAINT roles_pkey PRIMARY KEY (name);
+
+
+--
+-- Name: schema_migrations schema_migrations_pkey; Type: CONSTRAINT; Schema: dbmate; Owner: -
+--
+
+ALTER TABLE ONLY dbmate.schema_migrations
+    ADD CONSTRAINT schema_migrations_pkey PRIMARY KEY (version);
+
+
+--
+-- Name: symbolication_jobs symbolication_jobs_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.symbolication_jobs
+    ADD CONSTRAINT symbolication_jobs_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: team_membership team_membership_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.team_membership
+    ADD CONSTRAINT team_membership_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: teams teams_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.teams
+    ADD CONSTRAINT teams_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: unhandled_exception_groups unhandled_exception_groups_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.unhandled_exception_groups
+    ADD CONSTRAINT unhandled_exception_groups_pkey PRIMARY KEY (id);
+
+
+--
+-- Name: usage_counters usage_counters_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.usage_counters
+    ADD CONSTRAINT usage_counters_pkey PRIMARY KEY (app_id, month);
+
+
+--
+-- Name: usage_sessions usage_sessions_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+--
+
+ALTER TABLE ONLY public.usage_sessions
+    ADD CONSTRAINT usage_sessions_pkey PRIMARY KEY (app_id, month, session_id);
 
 
 --
--- Name: COLUMN event_reqs.symbolication_attempts_count; Type: COMMENT; Schema: public; Owner: -
+-- Name: users users_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.event_reqs.symbolication_attempts_count IS 'number of times symbolication was attempted for this event request';
+ALTER TABLE ONLY public.users
+    ADD CONSTRAINT users_pkey PRIMARY KEY (id);
 
 
 --
--- Name: COLUMN event_reqs.created_at; Type: COMMENT; Schema: public; Owner: -
+-- Name: webhook_deliveries webhook_deliveries_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.event_reqs.created_at IS 'utc timestamp at the time of record creation';
+ALTER TABLE ONLY public.webhook_deliveries
+    ADD CONSTRAINT webhook_deliveries_pkey PRIMARY KEY (id);
 
 
 --
--- Name: roles; Type: TABLE; Schema: public; Owner: -
+-- Name: webhooks webhooks_pkey; Type: CONSTRAINT; Schema: public; Owner: -
 --
 
-CREATE TABLE public.roles (
-    name character varying(256) NOT NULL,
-    scopes text[],
-    CONSTRAINT roles_name_check CHECK (((name)::text = ANY ((ARRAY['owner'::character varying, 'admin'::character varying, 'developer'::character varying, 'viewer'::character varying])::text[])))
-);
+ALTER TABLE ONLY public.webhooks
+    ADD CONSTRAINT webhooks_pkey PRIMARY KEY (id);
 
 
 --
--- Name: COLUMN roles.name; Type: COMMENT; Schema: public; Owner: -
+-- Name: alerts_app_id_type_created_at_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.roles.name IS 'unique role name';
+CREATE INDEX alerts_app_id_type_created_at_idx ON public.alerts USING btree (app_id, type, created_at);
 
 
 --
--- Name: COLUMN roles.scopes; Type: COMMENT; Schema: public; Owner: -
+-- Name: anr_groups_merged_into_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.roles.scopes IS 'valid scopes for this role';
+CREATE INDEX anr_groups_merged_into_idx ON public.anr_groups USING btree (merged_into);
 
 
 --
--- Name: team_membership; Type: TABLE; Schema: public; Owner: -
+-- Name: api_keys_app_id_created_at_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-CREATE TABLE public.team_membership (
-    id uuid DEFAULT gen_random_uuid() NOT NULL,
-    team_id uuid,
-    user_id uuid,
-    role character varying(256),
-    role_updated_at timestamp with time zone NOT NULL,
-    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL
-);
+CREATE INDEX api_keys_app_id_created_at_idx ON public.api_keys USING btree (app_id, created_at);
 
 
 --
--- Name: COLUMN team_membership.id; Type: COMMENT; Schema: public; Owner: -
+-- Name: api_keys_key_value_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.team_membership.id IS 'unique id for each team membership';
+CREATE UNIQUE INDEX api_keys_key_value_idx ON public.api_keys USING btree (key_value);
 
 
 --
--- Name: COLUMN team_membership.team_id; Type: COMMENT; Schema: public; Owner: -
+-- Name: app_roles_user_id_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.team_membership.team_id IS 'team id to which user is a member of';
+CREATE INDEX app_roles_user_id_idx ON public.app_roles USING btree (user_id);
 
 
 --
--- Name: COLUMN team_membership.user_id; Type: COMMENT; Schema: public; Owner: -
+-- Name: audit_logs_actor_id_created_at_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.team_membership.user_id IS 'user id of user having membership of team';
+CREATE INDEX audit_logs_actor_id_created_at_idx ON public.audit_logs USING btree (actor_id, created_at DESC, id DESC);
 
 
 --
--- Name: COLUMN team_membership.role; Type: COMMENT; Schema: public; Owner: -
+-- Name: audit_logs_team_id_created_at_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.team_membership.role IS 'role of the invitee';
+CREATE INDEX audit_logs_team_id_created_at_idx ON public.audit_logs USING btree (team_id, created_at DESC, id DESC);
 
 
 --
--- Name: COLUMN team_membership.role_updated_at; Type: COMMENT; Schema: public; Owner: -
+-- Name: auth_attempts_kind_email_created_at_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.team_membership.role_updated_at IS 'utc timestamp at the time of role change';
+CREATE INDEX auth_attempts_kind_email_created_at_idx ON public.auth_attempts USING btree (kind, email, created_at);
 
 
 --
--- Name: COLUMN team_membership.created_at; Type: COMMENT; Schema: public; Owner: -
+-- Name: auth_attempts_kind_ip_created_at_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.team_membership.created_at IS 'utc timestamp at the time of team membership';
+CREATE INDEX auth_attempts_kind_ip_created_at_idx ON public.auth_attempts USING btree (kind, ip, created_at);
 
 
 --
--- Name: teams; Type: TABLE; Schema: public; Owner: -
+-- Name: auth_tokens_token_hash_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-CREATE TABLE public.teams (
-    id uuid DEFAULT gen_random_uuid() NOT NULL,
-    name character varying(256) NOT NULL,
-    created_at timestamp with time zone DEFAULT now() NOT NULL,
-    updated_at timestamp with time zone NOT NULL
-);
+CREATE UNIQUE INDEX auth_tokens_token_hash_idx ON public.auth_tokens USING btree (token_hash);
 
 
 --
--- Name: COLUMN teams.id; Type: COMMENT; Schema: public; Owner: -
+-- Name: auth_tokens_user_id_purpose_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.teams.id IS 'unique id for each team';
+CREATE INDEX auth_tokens_user_id_purpose_idx ON public.auth_tokens USING btree (user_id, purpose);
 
 
 --
--- Name: COLUMN teams.name; Type: COMMENT; Schema: public; Owner: -
+-- Name: build_mapping_build_ids_app_id_build_id_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.teams.name IS 'name of the team';
+CREATE INDEX build_mapping_build_ids_app_id_build_id_idx ON public.build_mapping_build_ids USING btree (app_id, build_id);
 
 
 --
--- Name: COLUMN teams.created_at; Type: COMMENT; Schema: public; Owner: -
+-- Name: ingest_jobs_status_next_attempt_at_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.teams.created_at IS 'utc timestamp at the time of team creation';
+CREATE INDEX ingest_jobs_status_next_attempt_at_idx ON public.ingest_jobs USING btree (status, next_attempt_at);
 
 
 --
--- Name: COLUMN teams.updated_at; Type: COMMENT; Schema: public; Owner: -
+-- Name: invites_email_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.teams.updated_at IS 'utc timestmap at the time of team name update';
+CREATE INDEX invites_email_idx ON public.invites USING btree (email);
 
 
 --
--- Name: unhandled_exception_groups; Type: TABLE; Schema: public; Owner: -
+-- Name: invites_team_id_email_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-CREATE TABLE public.unhandled_exception_groups (
-    id uuid NOT NULL,
-    app_id uuid,
-    type text NOT NULL,
-    message text NOT NULL,
-    method_name text NOT NULL,
-    file_name text NOT NULL,
-    line_number integer NOT NULL,
-    fingerprint character varying(32) NOT NULL,
-    event_ids uuid[] NOT NULL,
-    first_event_timestamp timestamp with time zone NOT NULL,
-    created_at timestamp with time zone DEFAULT now() NOT NULL,
-    updated_at timestamp with time zone DEFAULT now() NOT NULL
-);
+CREATE INDEX invites_team_id_email_idx ON public.invites USING btree (team_id, email);
 
 
 --
--- Name: COLUMN unhandled_exception_groups.id; Type: COMMENT; Schema: public; Owner: -
+-- Name: invites_token_hash_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.unhandled_exception_groups.id IS 'sortable unique id (uuidv7) for each unhandled exception group';
+CREATE UNIQUE INDEX invites_token_hash_idx ON public.invites USING btree (token_hash);
 
 
 --
--- Name: COLUMN unhandled_exception_groups.app_id; Type: COMMENT; Schema: public; Owner: -
+-- Name: personal_access_tokens_token_hash_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.unhandled_exception_groups.app_id IS 'linked app id';
+CREATE UNIQUE INDEX personal_access_tokens_token_hash_idx ON public.personal_access_tokens USING btree (token_hash);
 
 
 --
--- Name: COLUMN unhandled_exception_groups.type; Type: COMMENT; Schema: public; Owner: -
+-- Name: personal_access_tokens_user_id_created_at_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.unhandled_exception_groups.type IS 'type of the exception';
+CREATE INDEX personal_access_tokens_user_id_created_at_idx ON public.personal_access_tokens USING btree (user_id, created_at);
 
 
 --
--- Name: COLUMN unhandled_exception_groups.message; Type: COMMENT; Schema: public; Owner: -
+-- Name: purge_jobs_status_next_attempt_at_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.unhandled_exception_groups.message IS 'message of the exception';
+CREATE INDEX purge_jobs_status_next_attempt_at_idx ON public.purge_jobs USING btree (status, next_attempt_at);
 
 
 --
--- Name: COLUMN unhandled_exception_groups.method_name; Type: COMMENT; Schema: public; Owner: -
+-- Name: purge_jobs_team_id_app_id_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.unhandled_exception_groups.method_name IS 'method name where the exception occured';
+CREATE INDEX purge_jobs_team_id_app_id_idx ON public.purge_jobs USING btree (team_id, app_id);
 
 
 --
--- Name: COLUMN unhandled_exception_groups.file_name; Type: COMMENT; Schema: public; Owner: -
+-- Name: quotas_app_id_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.unhandled_exception_groups.file_name IS 'file name where the exception occured';
+CREATE UNIQUE INDEX quotas_app_id_idx ON public.quotas USING btree (app_id) WHERE (app_id IS NOT NULL);
 
 
 --
--- Name: COLUMN unhandled_exception_groups.line_number; Type: COMMENT; Schema: public; Owner: -
+-- Name: quotas_team_id_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.unhandled_exception_groups.line_number IS 'line number where the exception occured';
+CREATE UNIQUE INDEX quotas_team_id_idx ON public.quotas USING btree (team_id) WHERE (team_id IS NOT NULL);
 
 
 --
--- Name: COLUMN unhandled_exception_groups.fingerprint; Type: COMMENT; Schema: public; Owner: -
+-- Name: symbolication_jobs_queued_version_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.unhandled_exception_groups.fingerprint IS 'fingerprint of the unhandled exception';
+CREATE UNIQUE INDEX symbolication_jobs_queued_version_idx ON public.symbolication_jobs USING btree (app_id, version_name, version_code, mapping_type) WHERE (status = 'queued'::text);
 
 
 --
--- Name: COLUMN unhandled_exception_groups.event_ids; Type: COMMENT; Schema: public; Owner: -
+-- Name: symbolication_jobs_status_next_attempt_at_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.unhandled_exception_groups.event_ids IS 'list of associated event ids';
+CREATE INDEX symbolication_jobs_status_next_attempt_at_idx ON public.symbolication_jobs USING btree (status, next_attempt_at);
 
 
 --
--- Name: COLUMN unhandled_exception_groups.first_event_timestamp; Type: COMMENT; Schema: public; Owner: -
+-- Name: unhandled_exception_groups_merged_into_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.unhandled_exception_groups.first_event_timestamp IS 'utc timestamp of the oldest event in the group';
+CREATE INDEX unhandled_exception_groups_merged_into_idx ON public.unhandled_exception_groups USING btree (merged_into);
 
 
 --
--- Name: COLUMN unhandled_exception_groups.created_at; Type: COMMENT; Schema: public; Owner: -
+-- Name: webhook_deliveries_status_next_attempt_at_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.unhandled_exception_groups.created_at IS 'utc timestamp at the time of record creation';
+CREATE INDEX webhook_deliveries_status_next_attempt_at_idx ON public.webhook_deliveries USING btree (status, next_attempt_at);
 
 
 --
--- Name: COLUMN unhandled_exception_groups.updated_at; Type: COMMENT; Schema: public; Owner: -
+-- Name: webhook_deliveries_webhook_id_created_at_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.unhandled_exception_groups.updated_at IS 'utc timestamp at the time of record updation';
+CREATE INDEX webhook_deliveries_webhook_id_created_at_idx ON public.webhook_deliveries USING btree (webhook_id, created_at DESC);
 
 
 --
--- Name: users; Type: TABLE; Schema: public; Owner: -
+-- Name: webhooks_app_id_idx; Type: INDEX; Schema: public; Owner: -
 --
 
-CREATE TABLE public.users (
-    id uuid NOT NULL,
-    name character varying(256),
-    email character varying(256) NOT NULL,
-    invited_by_user_id uuid,
-    invited_to_team_id uuid,
-    invited_as_role character varying(256),
-    confirmed_at timestamp with time zone,
-    last_sign_in_at timestamp with time zone NOT NULL,
-    created_at timestamp with time zone NOT NULL,
-    updated_at timestamp with time zone NOT NULL
-);
+CREATE INDEX webhooks_app_id_idx ON public.webhooks USING btree (app_id);
 
 
 --
--- Name: COLUMN users.id; Type: COMMENT; Schema: public; Owner: -
+-- Name: alert_prefs alert_prefs_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.users.id IS 'unique id for each user';
+ALTER TABLE ONLY public.alert_prefs
+    ADD CONSTRAINT alert_prefs_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
 
 
 --
--- Name: COLUMN users.name; Type: COMMENT; Schema: public; Owner: -
+-- Name: alert_prefs alert_prefs_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.users.name IS 'name of the user';
+ALTER TABLE ONLY public.alert_prefs
+    ADD CONSTRAINT alert_prefs_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
 
 
 --
--- Name: COLUMN users.invited_by_user_id; Type: COMMENT; Schema: public; Owner: -
+-- Name: alerts alerts_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.users.invited_by_user_id IS 'id of user who invited this user';
+ALTER TABLE ONLY public.alerts
+    ADD CONSTRAINT alerts_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
 
 
 --
--- Name: COLUMN users.invited_to_team_id; Type: COMMENT; Schema: public; Owner: -
+-- Name: anr_groups anr_groups_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.users.invited_to_team_id IS 'id of team to which this user was invited';
+ALTER TABLE ONLY public.anr_groups
+    ADD CONSTRAINT anr_groups_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
 
 
 --
--- Name: COLUMN users.invited_as_role; Type: COMMENT; Schema: public; Owner: -
+-- Name: anr_groups anr_groups_merged_into_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.users.invited_as_role IS 'role as which this user was invited';
+ALTER TABLE ONLY public.anr_groups
+    ADD CONSTRAINT anr_groups_merged_into_fkey FOREIGN KEY (merged_into) REFERENCES public.anr_groups(id) ON DELETE SET NULL;
 
 
 --
--- Name: COLUMN users.confirmed_at; Type: COMMENT; Schema: public; Owner: -
+-- Name: api_keys api_keys_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.users.confirmed_at IS 'utc timestamp at which user was confirmed';
+ALTER TABLE ONLY public.api_keys
+    ADD CONSTRAINT api_keys_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE DEFERRABLE;
 
 
 --
--- Name: COLUMN users.last_sign_in_at; Type: COMMENT; Schema: public; Owner: -
+-- Name: api_keys api_keys_created_by_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.users.last_sign_in_at IS 'utc timestamp at the time of last user sign in';
+ALTER TABLE ONLY public.api_keys
+    ADD CONSTRAINT api_keys_created_by_fkey FOREIGN KEY (created_by) REFERENCES public.users(id) ON DELETE SET NULL;
 
 
 --
--- Name: COLUMN users.created_at; Type: COMMENT; Schema: public; Owner: -
+-- Name: app_roles app_roles_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.users.created_at IS 'utc timestamp at the time of user creation';
+ALTER TABLE ONLY public.app_roles
+    ADD CONSTRAINT app_roles_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
 
 
 --
--- Name: COLUMN users.updated_at; Type: COMMENT; Schema: public; Owner: -
+-- Name: app_roles app_roles_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-COMMENT ON COLUMN public.users.updated_at IS 'utc timestmap at the time of user update';
+ALTER TABLE ONLY public.app_roles
+    ADD CONSTRAINT app_roles_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
 
 
 --
--- Name: schema_migrations schema_migrations_pkey; Type: CONSTRAINT; Schema: dbmate; Owner: -
+-- Name: app_settings app_settings_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY dbmate.schema_migrations
-    ADD CONSTRAINT schema_migrations_pkey PRIMARY KEY (version);
+ALTER TABLE ONLY public.app_settings
+    ADD CONSTRAINT app_settings_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
 
 
 --
--- Name: alert_prefs alert_prefs_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: apps apps_team_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.alert_prefs
-    ADD CONSTRAINT alert_prefs_pkey PRIMARY KEY (app_id, user_id);
+ALTER TABLE ONLY public.apps
+    ADD CONSTRAINT apps_team_id_fkey FOREIGN KEY (team_id) REFERENCES public.teams(id) ON DELETE CASCADE;
 
 
 --
--- Name: anr_groups anr_groups_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: audit_logs audit_logs_actor_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.anr_groups
-    ADD CONSTRAINT anr_groups_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.audit_logs
+    ADD CONSTRAINT audit_logs_actor_id_fkey FOREIGN KEY (actor_id) REFERENCES public.users(id) ON DELETE SET NULL;
 
 
 --
--- Name: api_keys api_keys_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: audit_logs audit_logs_team_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.api_keys
-    ADD CONSTRAINT api_keys_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.audit_logs
+    ADD CONSTRAINT audit_logs_team_id_fkey FOREIGN KEY (team_id) REFERENCES public.teams(id) ON DELETE CASCADE;
 
 
 --
--- Name: app_settings app_settings_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: auth_sessions auth_sessions_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.app_settings
-    ADD CONSTRAINT app_settings_pkey PRIMARY KEY (app_id);
+ALTER TABLE ONLY public.auth_sessions
+    ADD CONSTRAINT auth_sessions_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
 
 
 --
--- Name: apps apps_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: auth_tokens auth_tokens_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.apps
-    ADD CONSTRAINT apps_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.auth_tokens
+    ADD CONSTRAINT auth_tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
 
 
 --
--- Name: auth_sessions auth_sessions_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: build_mapping_build_ids build_mapping_build_ids_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.auth_sessions
-    ADD CONSTRAINT auth_sessions_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.build_mapping_build_ids
+    ADD CONSTRAINT build_mapping_build_ids_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
 
 
 --
--- Name: auth_states auth_states_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: build_mapping_build_ids build_mapping_build_ids_mapping_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.auth_states
-    ADD CONSTRAINT auth_states_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.build_mapping_build_ids
+    ADD CONSTRAINT build_mapping_build_ids_mapping_id_fkey FOREIGN KEY (mapping_id) REFERENCES public.build_mappings(id) ON DELETE CASCADE;
 
 
 --
--- Name: build_mappings build_mappings_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: build_mappings build_mappings_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
 ALTER TABLE ONLY public.build_mappings
-    ADD CONSTRAINT build_mappings_pkey PRIMARY KEY (id);
+    ADD CONSTRAINT build_mappings_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
 
 
 --
--- Name: build_sizes build_sizes_app_id_version_name_version_code_build_type_key; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: build_sizes build_sizes_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
 ALTER TABLE ONLY public.build_sizes
-    ADD CONSTRAINT build_sizes_app_id_version_name_version_code_build_type_key UNIQUE (app_id, version_name, version_code, build_type);
+    ADD CONSTRAINT build_sizes_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
 
 
 --
--- Name: build_sizes build_sizes_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: event_reqs event_reqs_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.build_sizes
-    ADD CONSTRAINT build_sizes_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.event_reqs
+    ADD CONSTRAINT event_reqs_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
 
 
 --
--- Name: event_reqs event_reqs_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: fingerprint_rules fingerprint_rules_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.event_reqs
-    ADD CONSTRAINT event_reqs_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.fingerprint_rules
+    ADD CONSTRAINT fingerprint_rules_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
 
 
 --
--- Name: roles roles_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: ingest_job_blobs ingest_job_blobs_job_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.roles
-    ADD CONSTRAINT roles_pkey PRIMARY KEY (name);
+ALTER TABLE ONLY public.ingest_job_blobs
+    ADD CONSTRAINT ingest_job_blobs_job_id_fkey FOREIGN KEY (job_id) REFERENCES public.ingest_jobs(id) ON DELETE CASCADE;
 
 
 --
--- Name: team_membership team_membership_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: ingest_jobs ingest_jobs_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.team_membership
-    ADD CONSTRAINT team_membership_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.ingest_jobs
+    ADD CONSTRAINT ingest_jobs_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
 
 
 --
--- Name: teams teams_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: invites invites_invited_by_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.teams
-    ADD CONSTRAINT teams_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.invites
+    ADD CONSTRAINT invites_invited_by_fkey FOREIGN KEY (invited_by) REFERENCES public.users(id) ON DELETE SET NULL;
 
 
 --
--- Name: unhandled_exception_groups unhandled_exception_groups_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: invites invites_team_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.unhandled_exception_groups
-    ADD CONSTRAINT unhandled_exception_groups_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.invites
+    ADD CONSTRAINT invites_team_id_fkey FOREIGN KEY (team_id) REFERENCES public.teams(id) ON DELETE CASCADE;
 
 
 --
--- Name: users users_pkey; Type: CONSTRAINT; Schema: public; Owner: -
+-- Name: personal_access_tokens personal_access_tokens_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.users
-    ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+ALTER TABLE ONLY public.personal_access_tokens
+    ADD CONSTRAINT personal_access_tokens_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
 
 
 --
--- Name: alert_prefs alert_prefs_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+-- Name: purge_jobs purge_jobs_requested_by_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.alert_prefs
-    ADD CONSTRAINT alert_prefs_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
+ALTER TABLE ONLY public.purge_jobs
+    ADD CONSTRAINT purge_jobs_requested_by_fkey FOREIGN KEY (requested_by) REFERENCES public.users(id) ON DELETE SET NULL;
 
 
 --
--- Name: alert_prefs alert_prefs_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+-- Name: quotas quotas_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.alert_prefs
-    ADD CONSTRAINT alert_prefs_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+ALTER TABLE ONLY public.quotas
+    ADD CONSTRAINT quotas_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
 
 
 --
--- Name: anr_groups anr_groups_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+-- Name: quotas quotas_team_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.anr_groups
-    ADD CONSTRAINT anr_groups_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
+ALTER TABLE ONLY public.quotas
+    ADD CONSTRAINT quotas_team_id_fkey FOREIGN KEY (team_id) REFERENCES public.teams(id) ON DELETE CASCADE;
 
 
 --
--- Name: api_keys api_keys_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+-- Name: symbolication_jobs symbolication_jobs_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.api_keys
-    ADD CONSTRAINT api_keys_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE DEFERRABLE;
+ALTER TABLE ONLY public.symbolication_jobs
+    ADD CONSTRAINT symbolication_jobs_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
 
 
 --
--- Name: app_settings app_settings_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+-- Name: team_membership team_membership_role_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.app_settings
-    ADD CONSTRAINT app_settings_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
+ALTER TABLE ONLY public.team_membership
+    ADD CONSTRAINT team_membership_role_fkey FOREIGN KEY (role) REFERENCES public.roles(name) ON DELETE CASCADE;
 
 
 --
--- Name: apps apps_team_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+-- Name: team_membership team_membership_team_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.apps
-    ADD CONSTRAINT apps_team_id_fkey FOREIGN KEY (team_id) REFERENCES public.teams(id) ON DELETE CASCADE;
+ALTER TABLE ONLY public.team_membership
+    ADD CONSTRAINT team_membership_team_id_fkey FOREIGN KEY (team_id) REFERENCES public.teams(id) ON DELETE CASCADE;
 
 
 --
--- Name: auth_sessions auth_sessions_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+-- Name: team_membership team_membership_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.auth_sessions
-    ADD CONSTRAINT auth_sessions_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+ALTER TABLE ONLY public.team_membership
+    ADD CONSTRAINT team_membership_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
 
 
 --
--- Name: build_mappings build_mappings_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+-- Name: unhandled_exception_groups unhandled_exception_groups_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.build_mappings
-    ADD CONSTRAINT build_mappings_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
+ALTER TABLE ONLY public.unhandled_exception_groups
+    ADD CONSTRAINT unhandled_exception_groups_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
 
 
 --
--- Name: build_sizes build_sizes_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+-- Name: unhandled_exception_groups unhandled_exception_groups_merged_into_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.build_sizes
-    ADD CONSTRAINT build_sizes_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
+ALTER TABLE ONLY public.unhandled_exception_groups
+    ADD CONSTRAINT unhandled_exception_groups_merged_into_fkey FOREIGN KEY (merged_into) REFERENCES public.unhandled_exception_groups(id) ON DELETE SET NULL;
 
 
 --
--- Name: event_reqs event_reqs_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+-- Name: usage_counters usage_counters_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.event_reqs
-    ADD CONSTRAINT event_reqs_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
+ALTER TABLE ONLY public.usage_counters
+    ADD CONSTRAINT usage_counters_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
 
 
 --
--- Name: team_membership team_membership_role_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+-- Name: usage_sessions usage_sessions_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.team_membership
-    ADD CONSTRAINT team_membership_role_fkey FOREIGN KEY (role) REFERENCES public.roles(name) ON DELETE CASCADE;
+ALTER TABLE ONLY public.usage_sessions
+    ADD CONSTRAINT usage_sessions_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
 
 
 --
--- Name: team_membership team_membership_team_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+-- Name: webhook_deliveries webhook_deliveries_webhook_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.team_membership
-    ADD CONSTRAINT team_membership_team_id_fkey FOREIGN KEY (team_id) REFERENCES public.teams(id) ON DELETE CASCADE;
+ALTER TABLE ONLY public.webhook_deliveries
+    ADD CONSTRAINT webhook_deliveries_webhook_id_fkey FOREIGN KEY (webhook_id) REFERENCES public.webhooks(id) ON DELETE CASCADE;
 
 
 --
--- Name: team_membership team_membership_user_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+-- Name: webhooks webhooks_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.team_membership
-    ADD CONSTRAINT team_membership_user_id_fkey FOREIGN KEY (user_id) REFERENCES public.users(id) ON DELETE CASCADE;
+ALTER TABLE ONLY public.webhooks
+    ADD CONSTRAINT webhooks_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
 
 
 --
--- Name: unhandled_exception_groups unhandled_exception_groups_app_id_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
+-- Name: webhooks webhooks_created_by_fkey; Type: FK CONSTRAINT; Schema: public; Owner: -
 --
 
-ALTER TABLE ONLY public.unhandled_exception_groups
-    ADD CONSTRAINT unhandled_exception_groups_app_id_fkey FOREIGN KEY (app_id) REFERENCES public.apps(id) ON DELETE CASCADE;
+ALTER TABLE ONLY public.webhooks
+    ADD CONSTRAINT webhooks_created_by_fkey FOREIGN KEY (created_by) REFERENCES public.users(id) ON DELETE SET NULL;
 
 
 --
@@ -1327,4 +3533,31 @@ INSERT INTO dbmate.schema_migrations (version) VALUES
     ('20240502060117'),
     ('20240703152041'),
     ('20240704051355'),
-    ('20240708104127');
+    ('20240708104127'),
+    ('20241016090512'),
+    ('20241016091204'),
+    ('20241016091318'),
+    ('20241016092437'),
+    ('20241016092512'),
+    ('20241016093120'),
+    ('20241016093204'),
+    ('20241016093305'),
+    ('20241016093348'),
+    ('20241016093412'),
+    ('20241016093527'),
+    ('20241016093551'),
+    ('20241016093700'),
+    ('20241016093800'),
+    ('20241016093900'),
+    ('20241016094000'),
+    ('20241016094100'),
+    ('20241016094200'),
+    ('20241016094300'),
+    ('20241016094400'),
+    ('20241016094500'),
+    ('20241016094600'),
+    ('20241016094700'),
+    ('20241016094800'),
+    ('20241016094900'),
+    ('20241016095000'),
+    ('20241016095100');